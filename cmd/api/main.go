@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -26,7 +30,10 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/cleanup"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/cmdaudit"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
@@ -40,7 +47,9 @@ import (
 	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/redisx"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/storage"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/telemetry"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/tenant"
 )
 
 func loadDotEnv() {
@@ -115,6 +124,7 @@ func main() {
 		auditSink = store
 	}
 	cmdRunner := cmdaudit.NewRunner(sanitizer, auditSink)
+	cmdRunner.Command = services.LimitedCommand
 
 	// Metrics + GPU + rate limiter
 	metricsReg := metrics.New()
@@ -123,6 +133,10 @@ func main() {
 
 	// Existing services
 	jobManager := services.NewJobManager()
+	if err := services.CheckSandbox(cfg); err != nil {
+		log.Fatalf("sandbox: %v", err)
+	}
+	services.UseProcessLimits(cfg)
 	converter := services.NewConverter(cfg)
 	inspector := services.NewMediaInspector(cfg.CommandTimeout)
 	analysisQueue := services.NewAnalysisQueue(cfg, inspector)
@@ -137,10 +151,53 @@ func main() {
 	s3Client := newS3Client(cfg)
 	faceDetectionStore := services.NewFaceDetectionStore(30 * time.Minute)
 	conversionHandler := handlers.NewConversionHandler(jobManager, converter, cfg, inspector, analysisQueue, transcription, s3Client, faceDetectionStore)
+	atRest, err := atrest.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("storage encryption: %v", err)
+	}
+	conversionHandler.SetAtRestSealer(atRest)
+	var virusScanner *services.ClamdScanner
+	if cfg.ClamdAddress != "" {
+		virusScanner = services.NewClamdScanner(cfg.ClamdAddress, cfg.ClamdTimeout)
+		if err := virusScanner.Ping(context.Background()); err != nil {
+			logging.Warn("virus scanning: clamd is unreachable; uploads fail until it is", "address", cfg.ClamdAddress, "error", err.Error())
+		}
+		conversionHandler.SetVirusScanner(virusScanner)
+	}
+	jobStorage, err := storage.New(cfg, s3Client)
+	if err != nil {
+		log.Fatalf("job storage: %v", err)
+	}
+	if jobStorage.Remote() && atRest != nil {
+		// Presigned downloads would hand out the sealed bytes; use the
+		// bucket's server-side encryption instead.
+		log.Fatalf("job storage: encryption at rest is only supported with STORAGE_BACKEND=local")
+	}
+	conversionHandler.SetStorage(jobStorage)
+	usage, err := services.NewUsageTracker(cfg.UsageFile)
+	if err != nil {
+		log.Fatalf("usage: %v", err)
+	}
+	jobManager.TrackUsage(usage)
+	// Jobs are restored once the handler can rerun the ones a restart
+	// interrupted.
+	interrupted, err := jobManager.UsePersistentStore(cfg.JobStoreDir, cfg.JobRetention)
+	if err != nil {
+		log.Fatalf("job store: %v", err)
+	}
+	conversionHandler.RecoverInterruptedJobs(interrupted)
+	tenants, err := tenant.Load(cfg.TenantPoliciesFile)
+	if err != nil {
+		log.Fatalf("tenant policies: %v", err)
+	}
+	if tenants.Len() > 0 {
+		logging.Info("tenant policies loaded", "tenants", tenants.Len())
+	}
 	// Content Studio gets its own handler because it persists projects/assets in
 	// Postgres (the conversion handler is stateless). It shares the jobManager so
 	// ingest/export progress flows through the same /api/job/:jobId machinery.
 	studioHandler := handlers.NewStudioHandler(jobManager, cfg, inspector, s3Client, pool, transcription)
+	studioHandler.SetVirusScanner(virusScanner)
 	// AI Video Restoration gets a dedicated handler too: its pipeline is the
 	// first consumer of the GPU lease manager and the command-audit runner.
 	videoRestoreHandler := handlers.NewVideoRestoreHandler(jobManager, cfg, s3Client, gpuMgr, store, cmdRunner)
@@ -148,11 +205,13 @@ func main() {
 	// restoration. No S3 dependency (images are small, uploaded directly); it
 	// shares the GPU lease manager + command-audit runner.
 	imageRestoreHandler := handlers.NewImageRestoreHandler(jobManager, cfg, inspector, gpuMgr, store, cmdRunner)
+	imageRestoreHandler.SetVirusScanner(virusScanner)
 	// AI Document Scan — scanned printed documents AND handwritten notes →
 	// searchable PDF + optional structured DOCX. PUBLIC (no Firebase), mounted on
 	// the plain /api group like the conversion routes; shares the GPU lease
 	// manager + command-audit runner.
 	documentScanHandler := handlers.NewDocumentScanHandler(jobManager, cfg, inspector, gpuMgr, store, cmdRunner)
+	documentScanHandler.SetVirusScanner(virusScanner)
 	// Double Raven partner portal — Postgres-backed document API (GET /api/dr/docs
 	// [, /:slug]) plus the in-portal "Create Doc" editor (draft create/update/
 	// publish + media asset presign/complete/delete). Shares the same pgx pool as
@@ -188,6 +247,10 @@ func main() {
 		drDesktopPresign = s3.NewPresignClient(s3Client)
 	}
 	drDesktopHandler := handlers.NewDrDesktopHandler(cfg, drDesktopPresign)
+	// Operator endpoints (/api/admin/*) — 404 unless ADMIN_API_TOKEN is set.
+	adminHandler := handlers.NewAdminHandler(cfg, jobManager)
+	adminHandler.SetWorkers(converter, analysisQueue)
+	adminHandler.SetAtRestSealer(atRest)
 
 	// Future auth seam (default OFF): when RESTORE_REQUIRE_FIREBASE_AUTH is
 	// set, /api/video-restore/* verifies Firebase ID tokens. Init failure
@@ -212,6 +275,17 @@ func main() {
 	} else {
 		drVerifier = verifier
 	}
+	// OIDC bearer tokens (OIDC_ISSUER). Init failure leaves the verifier nil
+	// and bearer requests get 503 instead of being treated as anonymous.
+	var oidcVerifier *middleware.OIDCVerifier
+	if cfg.OIDCIssuer != "" {
+		verifier, err := middleware.NewOIDCVerifier(ctx, cfg.OIDCIssuer, cfg.OIDCJWKSURL, cfg.OIDCAudience, cfg.OIDCSubjectClaim, logging)
+		if err != nil {
+			logging.Error("oidc auth init failed; bearer tokens will be rejected (503)", "error", err.Error())
+		} else {
+			oidcVerifier = verifier
+		}
+	}
 	if len(cfg.DRAllowedEmails) == 0 {
 		logging.Warn("DR_ALLOWED_EMAILS is empty; any verified Firebase user of the project can access /api/dr/* — set DR_ALLOWED_EMAILS to restrict the Double Raven portal")
 	}
@@ -219,6 +293,7 @@ func main() {
 	// Cleanup worker
 	if cfg.CleanupEnabled {
 		worker := cleanup.NewWorker(cfg, store, metricsReg, logging, jobManager)
+		adminHandler.SetCleanupWorker(worker)
 		go worker.Run(ctx)
 	}
 
@@ -245,7 +320,7 @@ func main() {
 	// Periodic active-jobs gauge update.
 	go pollActiveJobs(ctx, jobManager, metricsReg)
 
-	router := setupRouter(cfg, conversionHandler, studioHandler, videoRestoreHandler, imageRestoreHandler, documentScanHandler, restoreAuthVerifier, drVerifier, drDocsHandler, drCommentsHandler, drFeedbackHandler, drChatLabHandler, drTasksHandler, drDesktopHandler, store, enricher, limiter, metricsReg)
+	router := setupRouter(cfg, conversionHandler, studioHandler, videoRestoreHandler, imageRestoreHandler, documentScanHandler, restoreAuthVerifier, drVerifier, oidcVerifier, drDocsHandler, drCommentsHandler, drFeedbackHandler, drChatLabHandler, drTasksHandler, drDesktopHandler, adminHandler, store, enricher, limiter, metricsReg, tenants)
 
 	server := &http.Server{
 		Addr:              ":" + cfg.Port,
@@ -262,9 +337,28 @@ func main() {
 		logging.Warn("pprof endpoints mounted on the main router — do NOT expose this in production")
 	}
 
+	useTLS, certManager, err := configureTLS(cfg, server)
+	if err != nil {
+		log.Fatalf("tls: %v", err)
+	}
+	var redirectServer *http.Server
+	if cfg.HTTPRedirectAddr != "" {
+		if !useTLS {
+			log.Fatalf("tls: HTTP_REDIRECT_ADDR needs TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAINS")
+		}
+		redirectServer = startRedirectServer(cfg, certManager, logging)
+	}
+
 	go func() {
-		logging.Info("media-manipulator-api listening", "addr", server.Addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.Info("media-manipulator-api listening", "addr", server.Addr, "tls", useTLS)
+		var err error
+		if useTLS {
+			// With autocert the certificates come from server.TLSConfig.
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("server: %v", err)
 		}
 	}()
@@ -279,6 +373,10 @@ func main() {
 	if adminServer != nil {
 		_ = adminServer.Shutdown(shutdownCtx)
 	}
+	if redirectServer != nil {
+		_ = redirectServer.Shutdown(shutdownCtx)
+	}
+	jobManager.FlushStore()
 }
 
 func newS3Client(cfg *config.Config) *s3.Client {
@@ -290,31 +388,31 @@ func newS3Client(cfg *config.Config) *s3.Client {
 		if cfg.S3Endpoint != "" {
 			options.BaseEndpoint = aws.String(cfg.S3Endpoint)
 		}
+		options.UsePathStyle = cfg.S3UsePathStyle
 	})
 }
 
-func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandler, studioHandler *handlers.StudioHandler, videoRestoreHandler *handlers.VideoRestoreHandler, imageRestoreHandler *handlers.ImageRestoreHandler, documentScanHandler *handlers.DocumentScanHandler, restoreAuthVerifier middleware.TokenVerifier, drVerifier middleware.ClaimsVerifier, drDocsHandler *handlers.DrDocsHandler, drCommentsHandler *handlers.DrCommentsHandler, drFeedbackHandler *handlers.DrFeedbackHandler, drChatLabHandler *handlers.DrChatLabHandler, drTasksHandler *handlers.DrTasksHandler, drDesktopHandler *handlers.DrDesktopHandler, store *telemetry.Store, enricher *geo.Enricher, limiter *limits.Limiter, m *metrics.Registry) *gin.Engine {
+func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandler, studioHandler *handlers.StudioHandler, videoRestoreHandler *handlers.VideoRestoreHandler, imageRestoreHandler *handlers.ImageRestoreHandler, documentScanHandler *handlers.DocumentScanHandler, restoreAuthVerifier middleware.TokenVerifier, drVerifier middleware.ClaimsVerifier, oidcVerifier *middleware.OIDCVerifier, drDocsHandler *handlers.DrDocsHandler, drCommentsHandler *handlers.DrCommentsHandler, drFeedbackHandler *handlers.DrFeedbackHandler, drChatLabHandler *handlers.DrChatLabHandler, drTasksHandler *handlers.DrTasksHandler, drDesktopHandler *handlers.DrDesktopHandler, adminHandler *handlers.AdminHandler, store *telemetry.Store, enricher *geo.Enricher, limiter *limits.Limiter, m *metrics.Registry, tenants *tenant.Registry) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
 	_ = router.SetTrustedProxies([]string{"127.0.0.1", "::1"})
 
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{
-		"https://media-manipulator.com",
-		"https://www.media-manipulator.com",
-		// Restricted AI Video Restoration deployment (Firebase-gated when
-		// RESTORE_REQUIRE_FIREBASE_AUTH is enabled).
-		"https://dr.media-manipulator.com",
-		"http://localhost:3000",
-		// Standalone Double Raven portal — Electron desktop app (fixed local port).
-		"http://localhost:41999",
-		// Standalone Double Raven portal — web deployment on Vercel.
-		"https://drportal.wintrow.dev",
+	// CORS_ALLOWED_ORIGINS; entries like "https://*.example.com" match any
+	// subdomain (see Config.AllowsOrigin). The list is still handed to cors
+	// so Validate checks it.
+	if slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.CORSAllowedOrigins
+		corsConfig.AllowOriginFunc = cfg.AllowsOrigin
 	}
 	// PUT is required by the Content Studio project save (PUT /api/studio/projects/:id);
 	// PATCH/DELETE are allowed too so the editor's CRUD surface doesn't trip CORS.
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	// HEAD is how tus clients ask where to resume an upload.
+	corsConfig.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	corsConfig.AllowHeaders = []string{
 		"Origin",
 		"Content-Type",
@@ -323,24 +421,62 @@ func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandl
 		"X-Requested-With",
 		"X-MM-Visitor-ID",
 		"X-MM-Session-ID",
+		"X-API-Key",
 		// Range lets the Content Studio preview proxy be scrubbed cross-origin
 		// from a <video crossorigin="anonymous"> element (needed for Web Audio).
 		"Range",
+		// tus resumable uploads (/api/tus).
+		"Tus-Resumable",
+		"Upload-Length",
+		"Upload-Offset",
+		"Upload-Metadata",
 	}
 	corsConfig.AllowCredentials = false
 	// Expose the byte-range response headers so cross-origin <video> seeking and
 	// the Content Studio proxy passthrough work.
-	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Disposition", "Content-Range", "Accept-Ranges", "X-MM-Request-ID"}
+	// tus clients read the upload URL, offsets and the finished upload's job
+	// ID from response headers. Clients pace themselves by the rate and
+	// concurrency limit headers.
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Disposition", "Content-Range", "Accept-Ranges", "X-MM-Request-ID", "Content-Language",
+		"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires", "X-MM-Job-ID",
+		"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Concurrent-Jobs-Limit", "X-Concurrent-Jobs-Remaining"}
+	if err := corsConfig.Validate(); err != nil {
+		log.Fatalf("CORS_ALLOWED_ORIGINS: %v", err)
+	}
 	router.Use(cors.New(corsConfig))
 	router.Use(middleware.RequestContext())
+	router.Use(middleware.RequestLog())
 	router.Use(middleware.AccessLog(store, enricher))
 	router.Use(m.Middleware())
+	router.Use(middleware.Localize())
+	router.Use(middleware.APIKey(tenants))
+	// Per-client token bucket, in process so it holds without Redis.
+	router.Use(middleware.ClientRateLimit(limits.NewBuckets(), cfg.ClientRateLimitRPS, cfg.ClientRateLimitBurst))
 	// Global per-IP rate limit guard.
 	router.Use(limiter.GlobalIPRPS())
 
-	router.GET("/healthz", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "media_manipulator_api"})
-	})
+	// ?deep=true also runs the media tools and checks the work directories,
+	// answering 503 when any check fails.
+	healthChecker := services.NewHealthChecker(cfg)
+	health := func(c *gin.Context) {
+		body := gin.H{
+			"status":  "healthy",
+			"service": "media_manipulator_api",
+			"queue":   conversionHandler.QueueStatus(),
+		}
+		if deep, _ := strconv.ParseBool(c.Query("deep")); !deep {
+			c.JSON(http.StatusOK, body)
+			return
+		}
+		report := healthChecker.Check(c.Request.Context())
+		body["status"], body["checks"] = report.Status, report
+		status := http.StatusOK
+		if !report.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, body)
+	}
+	router.GET("/healthz", health)
 
 	if cfg.MetricsEnabled {
 		router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(m.Reg, promhttp.HandlerOpts{})))
@@ -348,18 +484,24 @@ func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandl
 
 	api := router.Group("/api")
 	{
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "media_manipulator_api"})
-		})
+		api.GET("/health", health)
+		// Routes whose jobs can belong to a user: OIDC bearer tokens are
+		// verified here (a pass-through while OIDC_ISSUER is unset), and a
+		// user's jobs are hidden from everyone else. The admin, restore and
+		// DR groups below keep their own bearer schemes.
+		userGroup := api.Group("")
+		userGroup.Use(middleware.BearerUser(cfg.OIDCIssuer != "", oidcVerifier, cfg.OIDCRequired))
+		userGroup.Use(conversionHandler.RequireJobOwner())
 		// Conversion routes — register first so per-route rate limits can
 		// be layered onto specific groups.
-		handlers.RegisterConversionRoutes(api, conversionHandler)
+		handlers.RegisterConversionRoutes(userGroup, conversionHandler)
 		// Specialized tool endpoints that don't fit cleanly into the
 		// single-file /upload contract (caption translator takes .srt/.vtt
-		// text files; stitch-audio-to-video takes multi-file multipart).
-		handlers.RegisterToolRoutes(api, conversionHandler)
+		// text files; stitch-audio-to-video and image-sequence-to-video take
+		// multi-file multipart).
+		handlers.RegisterToolRoutes(userGroup, conversionHandler)
 		// Content Studio (browser NLE) endpoints — projects/assets/export.
-		handlers.RegisterStudioRoutes(api, studioHandler)
+		handlers.RegisterStudioRoutes(userGroup, studioHandler)
 		// AI Video Restoration (multi-model comparison pipeline). The group
 		// carries the Firebase auth seam — a pass-through no-op while
 		// RESTORE_REQUIRE_FIREBASE_AUTH is off (the default).
@@ -370,8 +512,8 @@ func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandl
 		// RESTORE_REQUIRE_FIREBASE_AUTH gates it identically.
 		handlers.RegisterImageRestoreRoutes(restoreGroup, imageRestoreHandler)
 		// AI Document Scan is PUBLIC (like the conversion/tool routes) — mounted
-		// on the plain /api group, NOT behind the Firebase-gated restoreGroup.
-		handlers.RegisterDocumentScanRoutes(api, documentScanHandler)
+		// on userGroup, NOT behind the Firebase-gated restoreGroup.
+		handlers.RegisterDocumentScanRoutes(userGroup, documentScanHandler)
 
 		// Double Raven partner portal document API. ALWAYS Firebase-gated and
 		// fail-closed via RequireDoubleRavenAuth (nil verifier -> 503; bad/missing
@@ -405,6 +547,12 @@ func setupRouter(cfg *config.Config, conversionHandler *handlers.ConversionHandl
 
 		telemetryHandler := handlers.NewTelemetryHandler(store, enricher)
 		telemetryHandler.Register(api)
+
+		// Operator endpoints (self-test suite, stats). Bearer ADMIN_API_TOKEN; the
+		// group 404s when no token is configured.
+		adminGroup := api.Group("/admin")
+		adminGroup.Use(middleware.RequireAdminToken(cfg.AdminAPIToken))
+		handlers.RegisterAdminRoutes(adminGroup, adminHandler)
 	}
 
 	// Per-route limiters: we attach extra-strict limits via a second
@@ -444,6 +592,9 @@ func routeLimitDispatcher(cfg *config.Config, limiter *limits.Limiter, guard fun
 		{path: "/api/upload", routeKey: "upload", tool: "upload", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
 		{path: "/api/video-upload/presign", routeKey: "video_upload_presign", tool: "video_upload", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
 		{path: "/api/video-upload/complete", routeKey: "video_upload_complete", tool: "video_upload", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		// Only creating a tus upload spends from the bucket; its PATCHes are
+		// pieces of the same upload.
+		{path: "/api/tus", routeKey: "tus_upload", tool: "upload", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
 		{path: "/api/video-transcode/start", routeKey: "video_transcode_start", tool: "video_transcode", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
 		// AI Video Restoration is GPU- and disk-hungry (up to six models per
 		// job) — it gets its own, much tighter bucket.
@@ -451,12 +602,42 @@ func routeLimitDispatcher(cfg *config.Config, limiter *limits.Limiter, guard fun
 		{path: "/api/image-restore/start", routeKey: "image_restore_start", tool: "image_restore", sessionLimit: cfg.ImageRestoreRateLimitPerSessionPerHour, ipLimit: cfg.ImageRestoreRateLimitPerIPPerHour},
 		{path: "/api/document-scan/start", routeKey: "document_scan_start", tool: "document_scan", sessionLimit: cfg.DocumentScanRateLimitPerSessionPerHour, ipLimit: cfg.DocumentScanRateLimitPerIPPerHour},
 		{path: "/api/video-transcode/probe", routeKey: "video_transcode_probe", tool: "video_transcode", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Waveforms and loudness/silence analysis run synchronously from an
+		// upload — upload bucket.
+		{path: "/api/waveform", routeKey: "waveform", tool: "waveform", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		{path: "/api/analyze/loudness", routeKey: "analyze_loudness", tool: "loudness", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		{path: "/api/analyze/silence", routeKey: "analyze_silence", tool: "silence", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		{path: "/api/analyze/image-hash", routeKey: "analyze_image_hash", tool: "image_hash", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		{path: "/api/analyze/image-compare", routeKey: "analyze_image_compare", tool: "image_hash", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
 		{path: "/api/ai/faces/detect", routeKey: "ai_faces_detect", tool: "ai_faces", sessionLimit: cfg.RateLimitAnalysisPerSessionPerHour, ipLimit: cfg.RateLimitAnalysisPerIPPerHour},
 		// Caption translator runs the local Ollama LLM — treat it like analysis
 		// usage (the model competes for GPU time with whisper).
 		{path: "/api/tools/caption-translator", routeKey: "tools_caption_translator", tool: "caption_translator", sessionLimit: cfg.RateLimitAnalysisPerSessionPerHour, ipLimit: cfg.RateLimitAnalysisPerIPPerHour},
 		// Stitch-audio-to-video uploads + transcodes — share the upload bucket.
 		{path: "/api/tools/stitch-audio-to-video", routeKey: "tools_stitch_audio_to_video", tool: "stitch_audio_to_video", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		// Image-sequence-to-video renders a slideshow/timelapse — encode-bound
+		// like a transcode.
+		{path: "/api/tools/image-sequence-to-video", routeKey: "tools_image_sequence_to_video", tool: "image_sequence_to_video", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Live record holds an ingest connection open for the whole capture
+		// window and then transcodes, so it spends from the transcode bucket.
+		{path: "/api/tools/live-record", routeKey: "tools_live_record", tool: "live_record", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// URL imports download a whole video and then transcode it.
+		{path: "/api/tools/url-import", routeKey: "tools_url_import", tool: "url_import", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Webpage screenshots boot a whole browser per job; budget them like
+		// a transcode.
+		{path: "/api/tools/webpage-screenshot", routeKey: "tools_webpage_screenshot", tool: "webpage_screenshot", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Document thumbnails are an upload plus one cheap render.
+		{path: "/api/tools/document-thumbnail", routeKey: "tools_document_thumbnail", tool: "document_thumbnail", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		// A batch is many image conversions in one request — transcode bucket.
+		{path: "/api/tools/batch-images", routeKey: "tools_batch_images", tool: "batch_images", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Grid composition decodes every input and encodes one large frame.
+		{path: "/api/tools/video-grid", routeKey: "tools_video_grid", tool: "video_grid", sessionLimit: cfg.RateLimitTranscodesPerSessionPerHour, ipLimit: cfg.RateLimitTranscodesPerIPPerHour},
+		// Audio joins are multi-file uploads plus one audio encode.
+		{path: "/api/tools/audio-join", routeKey: "tools_audio_join", tool: "audio_join", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		// QR generation is one small render; decoding is an upload plus one
+		// zbarimg pass.
+		{path: "/api/tools/qr-code", routeKey: "tools_qr_code", tool: "qr_code", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
+		{path: "/api/tools/qr-decode", routeKey: "tools_qr_decode", tool: "qr_code", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
 		// Content Studio: source ingest shares the upload bucket; the EDL export
 		// (NVENC transcode) shares the transcode bucket.
 		{path: "/api/studio/assets/presign", routeKey: "studio_assets_presign", tool: "studio_upload", sessionLimit: cfg.RateLimitUploadsPerSessionPerHour, ipLimit: cfg.RateLimitUploadsPerIPPerHour},
@@ -552,6 +733,71 @@ func startAdminServer(cfg *config.Config, logging *slog.Logger) *http.Server {
 	return admin
 }
 
+// configureTLS prepares server for HTTPS when TLS_CERT_FILE/TLS_KEY_FILE
+// or TLS_AUTOCERT_DOMAINS is set, and reports whether it should serve TLS.
+// The autocert manager, when certificates come from ACME, also has to
+// answer HTTP-01 challenges on the redirect listener.
+func configureTLS(cfg *config.Config, server *http.Server) (bool, *autocert.Manager, error) {
+	files := cfg.TLSCertFile != "" || cfg.TLSKeyFile != ""
+	switch {
+	case files && len(cfg.TLSAutocertDomains) > 0:
+		return false, nil, errors.New("set either TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAINS, not both")
+	case files:
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return false, nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		return true, nil, nil
+	case len(cfg.TLSAutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		if cfg.TLSACMEDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.TLSACMEDirectoryURL}
+		}
+		// TLSConfig also answers TLS-ALPN-01 challenges on the HTTPS port.
+		server.TLSConfig = manager.TLSConfig()
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+		return true, manager, nil
+	}
+	return false, nil, nil
+}
+
+// startRedirectServer starts the plain HTTP listener on
+// cfg.HTTPRedirectAddr. It answers ACME HTTP-01 challenges when manager is
+// set and sends everything else to the same URL over HTTPS.
+func startRedirectServer(cfg *config.Config, manager *autocert.Manager, logging *slog.Logger) *http.Server {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if cfg.Port != "443" {
+			host = net.JoinHostPort(host, cfg.Port)
+		}
+		// 308 keeps the method and body of an upload POSTed over HTTP.
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+	if manager != nil {
+		handler = manager.HTTPHandler(handler)
+	}
+	redirect := &http.Server{
+		Addr:              cfg.HTTPRedirectAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	logging.Info("http redirect listening", "addr", redirect.Addr)
+	go func() {
+		if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("redirect server: %v", err)
+		}
+	}()
+	return redirect
+}
+
 func createDirs(cfg *config.Config) {
 	for _, dir := range []string{cfg.UploadDir, cfg.OutputDir, cfg.TempDir} {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -0,0 +1,431 @@
+// Package atrest encrypts job files on disk with AES-256-GCM so uploads and
+// outputs sitting in UPLOAD_DIR / OUTPUT_DIR are unreadable to anyone with
+// access to the volume but not the key.
+//
+// Files are sealed in fixed-size chunks so multi-GB videos can be encrypted
+// and streamed back without holding them in memory. Layout:
+//
+//	magic "MMATREST" | chunk size (uint32 BE) | nonce prefix (8 random bytes)
+//	chunk 0 ciphertext+tag | chunk 1 ciphertext+tag | ...
+//
+// Each chunk's nonce is the prefix followed by its big-endian index, and the
+// header plus a final-chunk flag are authenticated as additional data, so
+// reordering, truncating or splicing chunks fails to decrypt.
+package atrest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+const (
+	magic          = "MMATREST"
+	headerSize     = len(magic) + 4 + 8
+	tagSize        = 16
+	chunkSize      = 64 * 1024
+	maxChunkSize   = 16 * 1024 * 1024
+	keyCommandWait = 30 * time.Second
+)
+
+// ErrCorrupt is returned when a sealed file fails authentication.
+var ErrCorrupt = errors.New("encrypted file is corrupt or was sealed with a different key")
+
+// Sealer encrypts files with one AES-256 key and decrypts them with that
+// key or any of the previous ones, so a key can be rotated without losing
+// the files already sealed.
+type Sealer struct {
+	aead     cipher.AEAD
+	previous []cipher.AEAD
+}
+
+// NewSealer builds a Sealer from a raw 32-byte key, plus retired keys that
+// are only used to decrypt.
+func NewSealer(key []byte, previous ...[]byte) (*Sealer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sealer{aead: aead}
+	for _, old := range previous {
+		aead, err := newAEAD(old)
+		if err != nil {
+			return nil, fmt.Errorf("previous %w", err)
+		}
+		s.previous = append(s.previous, aead)
+	}
+	return s, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("storage encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// FromConfig loads the key from the configured source and returns nil (and
+// no error) when encryption at rest is not configured.
+func FromConfig(cfg *config.Config) (*Sealer, error) {
+	key, err := loadKey(cfg)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	var previous [][]byte
+	for i, encoded := range cfg.StorageEncryptionPreviousKeys {
+		old, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("STORAGE_ENCRYPTION_PREVIOUS_KEYS entry %d is not valid base64: %w", i+1, err)
+		}
+		previous = append(previous, old)
+	}
+	return NewSealer(key, previous...)
+}
+
+// loadKey resolves the base64 key from, in order, STORAGE_ENCRYPTION_KEY,
+// STORAGE_ENCRYPTION_KEY_FILE (a mounted secret) or the stdout of
+// STORAGE_ENCRYPTION_KEY_COMMAND (e.g. `aws kms decrypt ... --output text
+// --query Plaintext`, which prints the data key as base64).
+func loadKey(cfg *config.Config) ([]byte, error) {
+	var encoded, source string
+	switch {
+	case strings.TrimSpace(cfg.StorageEncryptionKey) != "":
+		encoded, source = cfg.StorageEncryptionKey, "STORAGE_ENCRYPTION_KEY"
+	case strings.TrimSpace(cfg.StorageEncryptionKeyFile) != "":
+		data, err := os.ReadFile(cfg.StorageEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read storage encryption key file: %w", err)
+		}
+		encoded, source = string(data), "STORAGE_ENCRYPTION_KEY_FILE"
+	case strings.TrimSpace(cfg.StorageEncryptionKeyCommand) != "":
+		fields := strings.Fields(cfg.StorageEncryptionKeyCommand)
+		ctx, cancel := context.WithTimeout(context.Background(), keyCommandWait)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("storage encryption key command failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+		}
+		encoded, source = string(out), "STORAGE_ENCRYPTION_KEY_COMMAND"
+	default:
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64: %w", source, err)
+	}
+	return key, nil
+}
+
+// IsSealed reports whether path starts with the sealed-file header.
+func IsSealed(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return false
+	}
+	return string(buf) == magic
+}
+
+// SealFile encrypts path in place. The ciphertext is written next to it and
+// renamed over the original, so a crash never leaves a half-sealed file.
+// Already-sealed files are left alone.
+func (s *Sealer) SealFile(path string) error {
+	if IsSealed(path) {
+		return nil
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".sealing-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if err := s.seal(bufio.NewWriter(tmp), in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	// Keep the original mtime so the cleanup worker's retention clock is
+	// not reset by sealing.
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func (s *Sealer) seal(w *bufio.Writer, r io.Reader) error {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], chunkSize)
+	if _, err := rand.Read(header[len(magic)+4:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	br := bufio.NewReaderSize(r, chunkSize)
+	plain := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+tagSize)
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(br, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		final := n < chunkSize
+		if !final {
+			if _, perr := br.Peek(1); perr == io.EOF {
+				final = true
+			}
+		}
+		sealed = s.aead.Seal(sealed[:0], chunkNonce(header, index), plain[:n], chunkAAD(header, final))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return w.Flush()
+		}
+	}
+}
+
+// plainSize returns the decrypted length of a sealed file.
+func plainSize(sealedSize int64, chunk uint32) int64 {
+	body := sealedSize - int64(headerSize)
+	if body < tagSize {
+		return 0
+	}
+	stride := int64(chunk) + tagSize
+	chunks := (body + stride - 1) / stride
+	return body - chunks*tagSize
+}
+
+// Open returns a reader over the decrypted contents of a sealed file and its
+// plaintext length. Authentication failures surface as ErrCorrupt from Read.
+// The reader is seekable, so downloads can serve byte ranges: a seek only
+// decrypts the chunk it lands in.
+func (s *Sealer) Open(path string) (io.ReadSeekCloser, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:len(magic)]) != magic {
+		f.Close()
+		return nil, 0, fmt.Errorf("%s is not an encrypted file", filepath.Base(path))
+	}
+	size := binary.BigEndian.Uint32(header[len(magic):])
+	if size == 0 || size > maxChunkSize {
+		f.Close()
+		return nil, 0, ErrCorrupt
+	}
+	plain := plainSize(info.Size(), size)
+	return &openReader{
+		s:      s,
+		f:      f,
+		br:     bufio.NewReaderSize(f, int(size)+tagSize),
+		header: header,
+		chunk:  make([]byte, int(size)+tagSize),
+		size:   plain,
+	}, plain, nil
+}
+
+// DecryptFile writes the plaintext of the sealed file src to dst.
+func (s *Sealer) DecryptFile(src, dst string) error {
+	r, _, err := s.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+type openReader struct {
+	s      *Sealer
+	aead   cipher.AEAD
+	f      *os.File
+	br     *bufio.Reader
+	header []byte
+	chunk  []byte
+	plain  []byte
+	index  uint32
+	done   bool
+	size   int64
+	pos    int64
+}
+
+func (r *openReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Seek moves to a plaintext offset by decrypting the chunk that holds it.
+func (r *openReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.pos + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("atrest: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("atrest: negative position")
+	}
+	if abs == r.pos {
+		return abs, nil
+	}
+	r.plain = nil
+	if abs >= r.size {
+		r.done = true
+		r.pos = abs
+		return abs, nil
+	}
+	plainChunk := int64(len(r.chunk) - tagSize)
+	index := abs / plainChunk
+	if _, err := r.f.Seek(int64(headerSize)+index*int64(len(r.chunk)), io.SeekStart); err != nil {
+		return 0, err
+	}
+	r.br.Reset(r.f)
+	r.index = uint32(index)
+	r.done = false
+	if err := r.next(); err != nil {
+		return 0, err
+	}
+	r.plain = r.plain[abs-index*plainChunk:]
+	r.pos = abs
+	return abs, nil
+}
+
+func (r *openReader) next() error {
+	n, err := io.ReadFull(r.br, r.chunk)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			// The previous chunk was full-size but not flagged final:
+			// the file was truncated on a chunk boundary.
+			return ErrCorrupt
+		}
+		return err
+	}
+	final := n < len(r.chunk)
+	if !final {
+		if _, perr := r.br.Peek(1); perr == io.EOF {
+			final = true
+		}
+	}
+	nonce, aad := chunkNonce(r.header, r.index), chunkAAD(r.header, final)
+	if r.aead == nil {
+		// The first chunk read tells which key sealed the file.
+		plain, err := r.identify(nonce, r.chunk[:n], aad)
+		if err != nil {
+			return err
+		}
+		r.plain = plain
+	} else {
+		plain, err := r.aead.Open(r.chunk[:0], nonce, r.chunk[:n], aad)
+		if err != nil {
+			return ErrCorrupt
+		}
+		r.plain = plain
+	}
+	r.index++
+	r.done = final
+	return nil
+}
+
+func (r *openReader) Close() error { return r.f.Close() }
+
+// identify decrypts a chunk with whichever key authenticates it and
+// remembers that key for the rest of the file. The chunk is decrypted into
+// a new buffer, because a failed Open clears its output and ciphertext
+// would be lost for the next key.
+func (r *openReader) identify(nonce, ciphertext, aad []byte) ([]byte, error) {
+	for _, aead := range append([]cipher.AEAD{r.s.aead}, r.s.previous...) {
+		if plain, err := aead.Open(nil, nonce, ciphertext, aad); err == nil {
+			r.aead = aead
+			return plain, nil
+		}
+	}
+	return nil, ErrCorrupt
+}
+
+func chunkNonce(header []byte, index uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[len(magic)+4:])
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+func chunkAAD(header []byte, final bool) []byte {
+	aad := make([]byte, len(header)+1)
+	copy(aad, header)
+	if final {
+		aad[len(header)] = 1
+	}
+	return aad
+}
@@ -0,0 +1,201 @@
+package atrest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func testSealer(t *testing.T) *Sealer {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSealer(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	s := testSealer(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+		path := filepath.Join(t.TempDir(), "out.bin")
+		if err := os.WriteFile(path, plain, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SealFile(path); err != nil {
+			t.Fatalf("size %d: SealFile: %v", size, err)
+		}
+		if !IsSealed(path) {
+			t.Fatalf("size %d: file not sealed", size)
+		}
+		r, n, err := s.Open(path)
+		if err != nil {
+			t.Fatalf("size %d: Open: %v", size, err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("size %d: read: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) || n != int64(size) {
+			t.Fatalf("size %d: round trip mismatch (len %d, reported %d)", size, len(got), n)
+		}
+	}
+}
+
+func TestOpenSeek(t *testing.T) {
+	s := testSealer(t)
+	plain := make([]byte, 3*chunkSize+17)
+	_, _ = rand.Read(plain)
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(path, plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SealFile(path); err != nil {
+		t.Fatal(err)
+	}
+	r, n, err := s.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, off := range []int64{chunkSize + 5, 10, 3 * chunkSize, n - 1, 0} {
+		if got, err := r.Seek(off, io.SeekStart); err != nil || got != off {
+			t.Fatalf("Seek(%d) = %d, %v", off, got, err)
+		}
+		buf := make([]byte, 100)
+		k, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("read at %d: %v", off, err)
+		}
+		if want := plain[off:min(off+100, n)]; !bytes.Equal(buf[:k], want) {
+			t.Fatalf("read at %d: got %d bytes that don't match", off, k)
+		}
+	}
+	if end, err := r.Seek(0, io.SeekEnd); err != nil || end != n {
+		t.Fatalf("Seek to end = %d, %v; want %d", end, err, n)
+	}
+	if k, err := r.Read(make([]byte, 1)); k != 0 || err != io.EOF {
+		t.Fatalf("read at end = %d, %v; want EOF", k, err)
+	}
+}
+
+func TestOpenRejectsTamperingAndTruncation(t *testing.T) {
+	s := testSealer(t)
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 2*chunkSize+10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SealFile(path); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := os.ReadFile(path)
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[headerSize+5] ^= 1
+	truncated := sealed[:headerSize+2*(chunkSize+tagSize)]
+	for name, data := range map[string][]byte{"flipped": flipped, "truncated": truncated} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		r, _, err := s.Open(path)
+		if err != nil {
+			t.Fatalf("%s: Open: %v", name, err)
+		}
+		_, err = io.ReadAll(r)
+		r.Close()
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: read error = %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	oldSealer, err := NewSealer(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, 2*chunkSize+5)
+	_, _ = rand.Read(plain)
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(path, plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := oldSealer.SealFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewSealer(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, err := rotated.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Seeking first makes a middle chunk the one that picks the key.
+	if _, err := r.Seek(chunkSize+1, io.SeekStart); err != nil {
+		t.Fatalf("seek with previous key: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, plain[chunkSize+1:]) {
+		t.Fatalf("read with previous key: %v", err)
+	}
+
+	withoutOld, err := NewSealer(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, err = withoutOld.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(r)
+	r.Close()
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("read without previous key: err = %v, want ErrCorrupt", err)
+	}
+	if _, err := NewSealer(newKey, []byte("short")); err == nil {
+		t.Fatal("expected an error for a short previous key")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	if s, err := FromConfig(&config.Config{}); s != nil || err != nil {
+		t.Fatalf("no key configured: got %v, %v", s, err)
+	}
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := FromConfig(&config.Config{StorageEncryptionKeyFile: keyFile}); s == nil || err != nil {
+		t.Fatalf("key file: got %v, %v", s, err)
+	}
+	short := base64.StdEncoding.EncodeToString([]byte("too short"))
+	if _, err := FromConfig(&config.Config{StorageEncryptionKey: short}); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+	if s, err := FromConfig(&config.Config{StorageEncryptionKey: key, StorageEncryptionPreviousKeys: []string{key}}); s == nil || err != nil {
+		t.Fatalf("previous keys: got %v, %v", s, err)
+	}
+	if _, err := FromConfig(&config.Config{StorageEncryptionKey: key, StorageEncryptionPreviousKeys: []string{"not base64!"}}); err == nil {
+		t.Fatal("expected an error for an invalid previous key")
+	}
+}
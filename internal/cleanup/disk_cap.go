@@ -0,0 +1,148 @@
+package cleanup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/telemetry"
+)
+
+// diskUsage totals the working directories. A trash directory outside
+// OutputDir is counted on its own; one inside it is already included.
+func (w *Worker) diskUsage() int64 {
+	roots := []string{w.Cfg.UploadDir, w.Cfg.OutputDir, w.Cfg.TempDir}
+	if TrashEnabled(w.Cfg) && !isWithin(TrashDir(w.Cfg), w.Cfg.OutputDir) {
+		roots = append(roots, TrashDir(w.Cfg))
+	}
+	var total int64
+	for _, root := range roots {
+		if strings.TrimSpace(root) == "" {
+			continue
+		}
+		_, bytes, _ := DirSize(root)
+		total += bytes
+	}
+	return total
+}
+
+// evictionCandidate is everything stored for one job id across the upload,
+// output and trash directories.
+type evictionCandidate struct {
+	name    string
+	paths   []string
+	bytes   int64
+	files   int64
+	modTime time.Time
+}
+
+// enforceDiskCap removes whole jobs, least recently written first, until
+// the working directories fit in CleanupMaxDiskBytes. It runs after the age
+// sweeps, so it only evicts what retention alone didn't free. Active jobs
+// are skipped, and evicted outputs bypass the trash since moving them there
+// would free nothing. It returns the usage it leaves behind.
+func (w *Worker) enforceDiskCap(ctx context.Context, active map[string]struct{}, capPaths int) (int64, int64, int64, []telemetry.CleanupPath, int64, error) {
+	usage := w.diskUsage()
+	limit := w.Cfg.CleanupMaxDiskBytes
+	if limit <= 0 || usage <= limit {
+		return 0, 0, 0, nil, usage, nil
+	}
+
+	roots := []string{w.Cfg.UploadDir, w.Cfg.OutputDir}
+	if TrashEnabled(w.Cfg) {
+		roots = append(roots, TrashDir(w.Cfg))
+	}
+	byName := map[string]*evictionCandidate{}
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil || strings.TrimSpace(root) == "" {
+			continue
+		}
+		entries, err := os.ReadDir(absRoot)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if _, busy := active[name]; busy {
+				continue
+			}
+			full := filepath.Join(absRoot, name)
+			if !isWithin(full, absRoot) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			c := byName[name]
+			if c == nil {
+				c = &evictionCandidate{name: name}
+				byName[name] = c
+			}
+			files, bytes, _ := DirSize(full)
+			c.paths = append(c.paths, full)
+			c.files += files
+			c.bytes += bytes
+			if info.ModTime().After(c.modTime) {
+				c.modTime = info.ModTime()
+			}
+		}
+	}
+	candidates := make([]*evictionCandidate, 0, len(byName))
+	for _, c := range byName {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].modTime.Before(candidates[j].modTime) })
+
+	var (
+		files int64
+		dirs  int64
+		bytes int64
+		paths []telemetry.CleanupPath
+	)
+	now := time.Now()
+	for _, c := range candidates {
+		if usage <= limit {
+			break
+		}
+		if ctx.Err() != nil {
+			return files, dirs, bytes, paths, usage, ctx.Err()
+		}
+		status := "disk_cap"
+		if w.Cfg.CleanupDryRun {
+			status = "dry_run"
+		} else {
+			for _, path := range c.paths {
+				if err := os.RemoveAll(path); err != nil {
+					w.Logger.Warn("cleanup remove failed", "path", c.name, "err", err.Error())
+					status = err.Error()
+					if w.Metrics != nil {
+						w.Metrics.CleanupError()
+					}
+				}
+			}
+		}
+		usage -= c.bytes
+		files += c.files
+		dirs += int64(len(c.paths))
+		bytes += c.bytes
+		if len(paths) < capPaths {
+			paths = append(paths, telemetry.CleanupPath{
+				PathRedacted: c.name,
+				PathType:     "dir",
+				AgeSeconds:   int(now.Sub(c.modTime) / time.Second),
+				SizeBytes:    c.bytes,
+				DeletedAt:    time.Now().UTC(),
+				ErrorMessage: status,
+			})
+		}
+	}
+	return files, dirs, bytes, paths, usage, nil
+}
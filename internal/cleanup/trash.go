@@ -0,0 +1,143 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/telemetry"
+)
+
+// Soft delete keeps expired outputs recoverable for a grace period so an
+// over-eager OUTPUT_RETENTION_SECONDS doesn't destroy a customer's only copy.
+// Trashed entries keep their job-id name; their mtime is reset on the way in
+// so the grace period counts from the move, not from the original write.
+
+var (
+	// ErrTrashDisabled is returned by the trash helpers when soft delete is off.
+	ErrTrashDisabled = errors.New("soft delete is not enabled")
+	// ErrRestoreConflict means the output directory already has that job.
+	ErrRestoreConflict = errors.New("output already exists")
+)
+
+// TrashEnabled reports whether expired outputs go to the trash.
+func TrashEnabled(cfg *config.Config) bool {
+	return cfg != nil && cfg.CleanupTrashRetention > 0
+}
+
+// TrashDir is the configured trash directory, defaulting to a hidden
+// directory inside OutputDir. Keeping it on the same filesystem makes the
+// move a rename, and the sweeper already skips dot entries.
+func TrashDir(cfg *config.Config) string {
+	if dir := strings.TrimSpace(cfg.CleanupTrashDir); dir != "" {
+		return dir
+	}
+	return filepath.Join(cfg.OutputDir, ".trash")
+}
+
+// TrashEntry describes one recoverable output.
+type TrashEntry struct {
+	Name      string    `json:"name"`
+	TrashedAt time.Time `json:"trashedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+	Files     int64     `json:"files"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// ListTrash returns the trashed outputs, oldest first.
+func ListTrash(cfg *config.Config) ([]TrashEntry, error) {
+	if !TrashEnabled(cfg) {
+		return nil, ErrTrashDisabled
+	}
+	dir := TrashDir(cfg)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []TrashEntry{}, nil
+		}
+		return nil, err
+	}
+	out := make([]TrashEntry, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entry := TrashEntry{
+			Name:      e.Name(),
+			TrashedAt: info.ModTime().UTC(),
+			PurgeAt:   info.ModTime().Add(cfg.CleanupTrashRetention).UTC(),
+		}
+		if e.IsDir() {
+			entry.Files, entry.Bytes, _ = DirSize(filepath.Join(dir, e.Name()))
+		} else {
+			entry.Files, entry.Bytes = 1, info.Size()
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TrashedAt.Before(out[j].TrashedAt) })
+	return out, nil
+}
+
+// RestoreFromTrash moves a trashed output back into OutputDir. Its mtime is
+// reset so it gets a full OUTPUT_RETENTION_SECONDS before the next sweep.
+func RestoreFromTrash(cfg *config.Config, name string) error {
+	if !TrashEnabled(cfg) {
+		return ErrTrashDisabled
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid trash entry name %q: %w", name, os.ErrNotExist)
+	}
+	src := filepath.Join(TrashDir(cfg), name)
+	if _, err := os.Lstat(src); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return os.ErrNotExist
+		}
+		return err
+	}
+	dst := filepath.Join(cfg.OutputDir, name)
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("restore %q: %w", name, ErrRestoreConflict)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(dst, now, now)
+}
+
+// moveToTrash moves full (an entry of the output root) into the trash.
+func moveToTrash(trashDir, full string) error {
+	if err := os.MkdirAll(trashDir, 0o755); err != nil {
+		return err
+	}
+	dst := filepath.Join(trashDir, filepath.Base(full))
+	// A same-named entry can only be left over from an earlier run of the
+	// same job id; the newer copy wins.
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	if err := os.Rename(full, dst); err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(dst, now, now)
+}
+
+// purgeTrash permanently removes trash entries past the grace period.
+func (w *Worker) purgeTrash(ctx context.Context, capPaths int) (int64, int64, int64, []telemetry.CleanupPath, error) {
+	dir := TrashDir(w.Cfg)
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, 0, 0, nil, nil
+		}
+		return 0, 0, 0, nil, err
+	}
+	return w.sweepRoot(ctx, dir, w.Cfg.CleanupTrashRetention, false, nil, capPaths, false)
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -27,6 +28,12 @@ type ActiveJobs interface {
 	ActiveJobIDs() map[string]struct{}
 }
 
+// JobRecords is implemented by an ActiveJobs that also keeps job records,
+// so finished ones can be purged along with their files.
+type JobRecords interface {
+	CleanupOldJobs(maxAge time.Duration) int
+}
+
 // Worker periodically scans the configured directories.
 type Worker struct {
 	Cfg     *config.Config
@@ -36,6 +43,73 @@ type Worker struct {
 	Active  ActiveJobs
 
 	stop atomic.Bool
+
+	mu      sync.Mutex
+	lastRun *RunSummary
+	nextRun time.Time
+}
+
+// RunSummary is the outcome of one sweep.
+type RunSummary struct {
+	StartedAt    time.Time `json:"startedAt"`
+	CompletedAt  time.Time `json:"completedAt"`
+	Status       string    `json:"status"`
+	DeletedFiles int64     `json:"deletedFiles"`
+	DeletedDirs  int64     `json:"deletedDirs"`
+	DeletedBytes int64     `json:"deletedBytes"`
+	JobsPurged   int       `json:"jobsPurged"`
+	DiskBytes    int64     `json:"diskBytes"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Status is the sweeper's configuration, last run and next scheduled run.
+type Status struct {
+	Enabled                bool        `json:"enabled"`
+	DryRun                 bool        `json:"dryRun"`
+	IntervalSeconds        int64       `json:"intervalSeconds"`
+	UploadRetentionSeconds int64       `json:"uploadRetentionSeconds"`
+	OutputRetentionSeconds int64       `json:"outputRetentionSeconds"`
+	TempRetentionSeconds   int64       `json:"tempRetentionSeconds"`
+	JobRetentionSeconds    int64       `json:"jobRetentionSeconds"`
+	MaxDiskBytes           int64       `json:"maxDiskBytes,omitempty"`
+	LastRun                *RunSummary `json:"lastRun,omitempty"`
+	NextRunAt              *time.Time  `json:"nextRunAt,omitempty"`
+}
+
+// Status reports the sweeper's schedule. A nil Worker (cleanup disabled)
+// reports only the configuration.
+func (w *Worker) Status(cfg *config.Config) Status {
+	st := Status{
+		Enabled:                cfg.CleanupEnabled,
+		DryRun:                 cfg.CleanupDryRun,
+		IntervalSeconds:        int64(interval(cfg) / time.Second),
+		UploadRetentionSeconds: int64(cfg.UploadRetention / time.Second),
+		OutputRetentionSeconds: int64(cfg.OutputRetention / time.Second),
+		TempRetentionSeconds:   int64(cfg.TempRetention / time.Second),
+		JobRetentionSeconds:    int64(cfg.JobRetention / time.Second),
+		MaxDiskBytes:           cfg.CleanupMaxDiskBytes,
+	}
+	if w == nil {
+		return st
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lastRun != nil {
+		last := *w.lastRun
+		st.LastRun = &last
+	}
+	if !w.nextRun.IsZero() {
+		next := w.nextRun
+		st.NextRunAt = &next
+	}
+	return st
+}
+
+func interval(cfg *config.Config) time.Duration {
+	if cfg.CleanupInterval <= 0 {
+		return 15 * time.Minute
+	}
+	return cfg.CleanupInterval
 }
 
 // NewWorker constructs a Worker. Active may be nil — in that case nothing is
@@ -53,24 +127,29 @@ func (w *Worker) Run(ctx context.Context) {
 		w.Logger.Info("cleanup worker disabled via config")
 		return
 	}
-	interval := w.Cfg.CleanupInterval
-	if interval <= 0 {
-		interval = 15 * time.Minute
-	}
-	t := time.NewTicker(interval)
+	every := interval(w.Cfg)
+	t := time.NewTicker(every)
 	defer t.Stop()
 	// Run once immediately.
 	w.tick(ctx)
+	w.scheduleNext(time.Now().Add(every))
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-t.C:
 			w.tick(ctx)
+			w.scheduleNext(time.Now().Add(every))
 		}
 	}
 }
 
+func (w *Worker) scheduleNext(at time.Time) {
+	w.mu.Lock()
+	w.nextRun = at.UTC()
+	w.mu.Unlock()
+}
+
 // tick runs a single sweep.
 func (w *Worker) tick(ctx context.Context) {
 	if w.stop.Load() {
@@ -93,13 +172,14 @@ func (w *Worker) tick(ctx context.Context) {
 	}
 
 	type sweepSpec struct {
-		root      string
-		retention time.Duration
-		jobAware  bool
+		root       string
+		retention  time.Duration
+		jobAware   bool
+		softDelete bool
 	}
 	sweeps := []sweepSpec{
 		{root: w.Cfg.UploadDir, retention: w.Cfg.UploadRetention, jobAware: true},
-		{root: w.Cfg.OutputDir, retention: w.Cfg.OutputRetention, jobAware: true},
+		{root: w.Cfg.OutputDir, retention: w.Cfg.OutputRetention, jobAware: true, softDelete: TrashEnabled(w.Cfg)},
 		{root: w.Cfg.TempDir, retention: w.Cfg.TempRetention, jobAware: false},
 	}
 
@@ -119,7 +199,32 @@ func (w *Worker) tick(ctx context.Context) {
 		if strings.TrimSpace(sp.root) == "" || sp.retention <= 0 {
 			continue
 		}
-		f, d, b, p, err := w.sweepRoot(ctx, sp.root, sp.retention, sp.jobAware, active, maxPaths-len(paths))
+		f, d, b, p, err := w.sweepRoot(ctx, sp.root, sp.retention, sp.jobAware, active, maxPaths-len(paths), sp.softDelete)
+		if !sp.softDelete {
+			files += f
+			dirs += d
+			bytes += b
+		}
+		paths = append(paths, p...)
+		if err != nil {
+			run.Status = "error"
+			if errMsg == "" {
+				errMsg = err.Error()
+			} else {
+				errMsg += "; " + err.Error()
+			}
+			if w.Metrics != nil {
+				w.Metrics.CleanupError()
+			}
+		}
+		if len(paths) >= maxPaths {
+			break
+		}
+	}
+	// Trashed outputs only count as deleted once the grace period is over
+	// and they are purged here.
+	if TrashEnabled(w.Cfg) && len(paths) < maxPaths {
+		f, d, b, p, err := w.purgeTrash(ctx, maxPaths-len(paths))
 		files += f
 		dirs += d
 		bytes += b
@@ -135,11 +240,28 @@ func (w *Worker) tick(ctx context.Context) {
 				w.Metrics.CleanupError()
 			}
 		}
-		if len(paths) >= maxPaths {
-			break
+	}
+
+	f, d, b, p, usage, err := w.enforceDiskCap(ctx, active, maxPaths-len(paths))
+	files += f
+	dirs += d
+	bytes += b
+	paths = append(paths, p...)
+	if err != nil {
+		run.Status = "error"
+		if errMsg == "" {
+			errMsg = err.Error()
+		} else {
+			errMsg += "; " + err.Error()
 		}
 	}
 
+	// Finished job records are kept for JOB_RETENTION_SECONDS.
+	jobsPurged := 0
+	if records, ok := w.Active.(JobRecords); ok && w.Cfg.JobRetention > 0 && !w.Cfg.CleanupDryRun {
+		jobsPurged = records.CleanupOldJobs(w.Cfg.JobRetention)
+	}
+
 	run.DeletedFiles = files
 	run.DeletedDirs = dirs
 	run.DeletedBytes = bytes
@@ -148,7 +270,21 @@ func (w *Worker) tick(ctx context.Context) {
 	run.CompletedAt = &completed
 	logger.Info("cleanup tick complete",
 		"deletedFiles", files, "deletedDirs", dirs,
-		"deletedBytes", bytes, "errors", errMsg)
+		"deletedBytes", bytes, "jobsPurged", jobsPurged,
+		"diskBytes", usage, "errors", errMsg)
+	w.mu.Lock()
+	w.lastRun = &RunSummary{
+		StartedAt:    started,
+		CompletedAt:  completed,
+		Status:       run.Status,
+		DeletedFiles: files,
+		DeletedDirs:  dirs,
+		DeletedBytes: bytes,
+		JobsPurged:   jobsPurged,
+		DiskBytes:    usage,
+		Error:        errMsg,
+	}
+	w.mu.Unlock()
 	if w.Metrics != nil {
 		w.Metrics.CleanupDeleted(files, bytes)
 	}
@@ -164,8 +300,9 @@ func (w *Worker) tick(ctx context.Context) {
 }
 
 // sweepRoot walks root and deletes anything older than retention, with
-// safety checks for the configured directories.
-func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Duration, jobAware bool, active map[string]struct{}, capPaths int) (int64, int64, int64, []telemetry.CleanupPath, error) {
+// safety checks for the configured directories. With softDelete the expired
+// entries are moved to the trash instead of being removed.
+func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Duration, jobAware bool, active map[string]struct{}, capPaths int, softDelete bool) (int64, int64, int64, []telemetry.CleanupPath, error) {
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return 0, 0, 0, nil, err
@@ -180,6 +317,12 @@ func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Dura
 	if !stat.IsDir() {
 		return 0, 0, 0, nil, errors.New("cleanup root is not a directory: " + absRoot)
 	}
+	var trashDir string
+	if softDelete {
+		if trashDir, err = filepath.Abs(TrashDir(w.Cfg)); err != nil {
+			return 0, 0, 0, nil, err
+		}
+	}
 	now := time.Now()
 	var (
 		files int64
@@ -204,6 +347,11 @@ func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Dura
 			continue
 		}
 		full := filepath.Join(absRoot, name)
+		// A non-hidden trash dir configured inside the root must not be
+		// swept into itself.
+		if softDelete && full == trashDir {
+			continue
+		}
 		// Guard against symlink escapes.
 		if !isWithin(full, absRoot) {
 			w.Logger.Warn("cleanup skipping path outside root", "path", "<root>/"+name)
@@ -226,7 +374,7 @@ func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Dura
 		pathType := "file"
 		if e.IsDir() {
 			pathType = "dir"
-			sub, subBytes, subErr := dirSize(full)
+			sub, subBytes, subErr := DirSize(full)
 			size = subBytes
 			dirs++
 			files += sub
@@ -252,9 +400,12 @@ func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Dura
 			continue
 		}
 		var removeErr error
-		if e.IsDir() {
+		switch {
+		case softDelete:
+			removeErr = moveToTrash(trashDir, full)
+		case e.IsDir():
 			removeErr = os.RemoveAll(full)
-		} else {
+		default:
 			removeErr = os.Remove(full)
 		}
 		if removeErr != nil {
@@ -275,13 +426,17 @@ func (w *Worker) sweepRoot(ctx context.Context, root string, retention time.Dura
 			continue
 		}
 		if len(paths) < capPaths {
-			paths = append(paths, telemetry.CleanupPath{
+			cp := telemetry.CleanupPath{
 				PathRedacted: filepath.Base(absRoot) + "/" + name,
 				PathType:     pathType,
 				AgeSeconds:   int(age / time.Second),
 				SizeBytes:    size,
 				DeletedAt:    time.Now().UTC(),
-			})
+			}
+			if softDelete {
+				cp.ErrorMessage = "moved_to_trash"
+			}
+			paths = append(paths, cp)
 		}
 	}
 	return files, dirs, bytes, paths, nil
@@ -310,8 +465,9 @@ func isWithin(candidate, root string) bool {
 	return true
 }
 
-// dirSize totals up files and bytes in a directory tree.
-func dirSize(root string) (int64, int64, error) {
+// DirSize totals up files and bytes in a directory tree. Unreadable entries
+// are skipped rather than failing the walk.
+func DirSize(root string) (int64, int64, error) {
 	var (
 		files int64
 		bytes int64
@@ -1,8 +1,14 @@
 package cleanup
 
 import (
+	"context"
+	"errors"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
 )
 
 func TestIsWithin(t *testing.T) {
@@ -22,3 +28,88 @@ func TestIsWithin(t *testing.T) {
 		}
 	}
 }
+
+func TestTrashRoundTrip(t *testing.T) {
+	cfg := &config.Config{OutputDir: t.TempDir(), CleanupTrashRetention: time.Hour}
+	jobDir := filepath.Join(cfg.OutputDir, "job-1")
+	if err := os.MkdirAll(jobDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(jobDir, "out.mp4"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := moveToTrash(TrashDir(cfg), jobDir); err != nil {
+		t.Fatalf("moveToTrash: %v", err)
+	}
+	entries, err := ListTrash(cfg)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "job-1" || entries[0].Bytes != 4 {
+		t.Fatalf("ListTrash = %+v, want one 4-byte job-1 entry", entries)
+	}
+
+	for _, bad := range []string{"", "..", ".trash", "../job-1"} {
+		if err := RestoreFromTrash(cfg, bad); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("RestoreFromTrash(%q) = %v, want ErrNotExist", bad, err)
+		}
+	}
+	if err := RestoreFromTrash(cfg, "job-1"); err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(jobDir, "out.mp4")); err != nil {
+		t.Fatalf("restored file missing: %v", err)
+	}
+	if err := RestoreFromTrash(cfg, "job-1"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("second restore = %v, want ErrNotExist", err)
+	}
+
+	cfg.CleanupTrashRetention = 0
+	if _, err := ListTrash(cfg); !errors.Is(err, ErrTrashDisabled) {
+		t.Errorf("ListTrash with trash disabled = %v, want ErrTrashDisabled", err)
+	}
+}
+
+func TestEnforceDiskCap(t *testing.T) {
+	cfg := &config.Config{UploadDir: t.TempDir(), OutputDir: t.TempDir(), TempDir: t.TempDir(), CleanupMaxDiskBytes: 250}
+	write := func(root, job string, size int, age time.Duration) {
+		dir := filepath.Join(root, job)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "f"), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		when := time.Now().Add(-age)
+		if err := os.Chtimes(dir, when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(cfg.UploadDir, "oldest", 50, 3*time.Hour)
+	write(cfg.OutputDir, "oldest", 50, 3*time.Hour)
+	write(cfg.OutputDir, "active", 100, 2*time.Hour)
+	write(cfg.OutputDir, "older", 100, time.Hour)
+	write(cfg.OutputDir, "newest", 100, 0)
+
+	w := NewWorker(cfg, nil, nil, nil, nil)
+	_, dirs, bytes, _, usage, err := w.enforceDiskCap(context.Background(), map[string]struct{}{"active": {}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 400 bytes against a 250 cap: "oldest" (both dirs) and then "older"
+	// go; "active" is skipped although it is older than "older".
+	if dirs != 3 || bytes != 200 || usage != 200 {
+		t.Fatalf("evicted %d dirs, %d bytes, left %d", dirs, bytes, usage)
+	}
+	for _, gone := range []string{filepath.Join(cfg.UploadDir, "oldest"), filepath.Join(cfg.OutputDir, "oldest"), filepath.Join(cfg.OutputDir, "older")} {
+		if _, err := os.Stat(gone); !os.IsNotExist(err) {
+			t.Errorf("%s not evicted", gone)
+		}
+	}
+	for _, kept := range []string{"active", "newest"} {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, kept)); err != nil {
+			t.Errorf("%s evicted", kept)
+		}
+	}
+}
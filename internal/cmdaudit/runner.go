@@ -92,6 +92,9 @@ type Result struct {
 type Runner struct {
 	Sanitizer *PathSanitizer
 	Sink      AuditSink
+	// Command builds the process for a spec; nil is exec.CommandContext.
+	// The server sets it to run specs under its sandbox and limits.
+	Command func(ctx context.Context, name string, args ...string) *exec.Cmd
 }
 
 // NewRunner builds a Runner. A nil sink is treated as NopSink.
@@ -128,7 +131,11 @@ func (r *Runner) Run(ctx context.Context, spec Spec) (Result, error) {
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(cmdCtx, spec.Executable, spec.Args...)
+	command := r.Command
+	if command == nil {
+		command = exec.CommandContext
+	}
+	cmd := command(cmdCtx, spec.Executable, spec.Args...)
 	cmd.Dir = spec.WorkingDir
 	cmd.Stdin = spec.Stdin
 	if len(spec.ExtraEnv) > 0 {
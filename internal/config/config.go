@@ -18,6 +18,9 @@ type Config struct {
 	MaxVideoUpload     int64
 	CommandTimeout     time.Duration
 	AnalysisWorkers    int
+	ChunkEncodeWorkers int
+	ProbeConcurrency   int
+	ProbeMemoryLimit   int64
 	AWSRegion          string
 	S3Bucket           string
 	S3Endpoint         string
@@ -106,6 +109,74 @@ type Config struct {
 	TempRetention              time.Duration
 	CleanupDryRun              bool
 	CleanupAuditMaxPathsPerRun int
+	// Soft delete: when CleanupTrashRetention > 0, expired output dirs are
+	// moved to CleanupTrashDir (default <OutputDir>/.trash) instead of being
+	// removed, and purged once they have sat there for the grace period.
+	CleanupTrashRetention time.Duration
+	CleanupTrashDir       string
+	// JobRetention is how long finished job records are kept.
+	// CleanupMaxDiskBytes, when > 0, caps the upload, output, temp and trash
+	// directories together: past it the oldest jobs' files are removed.
+	JobRetention        time.Duration
+	CleanupMaxDiskBytes int64
+
+	// Encryption at rest: a base64 AES-256 key from the env, a mounted
+	// secret file, or a command that prints it (e.g. a KMS decrypt of a
+	// wrapped data key). When any is set, every file of an upload or
+	// /api/tools job (original, output and sidecars) is sealed once the job
+	// finishes and decrypted when served. Studio, transcode, restoration and
+	// document-scan jobs stay plaintext.
+	// StorageEncryptionPreviousKeys are retired base64 keys that still
+	// decrypt files sealed before a rotation; new files use the current key.
+	StorageEncryptionKey          string
+	StorageEncryptionKeyFile      string
+	StorageEncryptionKeyCommand   string
+	StorageEncryptionPreviousKeys []string
+
+	// Upload filename policy. UploadAllowedExtensions, when set, is the only
+	// final extensions accepted; UploadDeniedExtensions is rejected anywhere
+	// in the name, so "clip.php.mp4" fails as well as "setup.exe". Both are
+	// lowercase and without the dot. Content sniffing still decides the type.
+	UploadAllowedExtensions []string
+	UploadDeniedExtensions  []string
+
+	// CORSAllowedOrigins are the browser origins the API answers
+	// cross-origin requests from. An entry may hold one "*", as in
+	// "https://*.example.com" for every subdomain; "*" alone allows any
+	// origin.
+	CORSAllowedOrigins []string
+
+	// Format policy for conversions, enforced by package validation. Formats
+	// are names such as "mp4", "flv" or "jpg": an allow list, when set, is
+	// the only formats accepted and a deny list is always refused. Input
+	// formats are what the content sniffs as, output formats the requested
+	// "format". MaxResolutionWidth x MaxResolutionHeight (MAX_RESOLUTION,
+	// "3840x2160") caps inputs and requested output sizes in either
+	// orientation; 0 disables it.
+	AllowedInputFormats  []string
+	DeniedInputFormats   []string
+	AllowedOutputFormats []string
+	DeniedOutputFormats  []string
+	MaxResolutionWidth   int
+	MaxResolutionHeight  int
+
+	// TenantPoliciesFile is a JSON file of per-API-key integrator policies
+	// (see package tenant). Empty disables API keys entirely.
+	TenantPoliciesFile string
+
+	// TLS. With TLSCertFile and TLSKeyFile the API serves HTTPS on Port
+	// with that certificate. With TLSAutocertDomains it gets certificates
+	// for those names from an ACME CA (Let's Encrypt unless
+	// TLSACMEDirectoryURL says otherwise), kept in TLSAutocertCacheDir.
+	// HTTPRedirectAddr, when set, is a plain HTTP listener that answers the
+	// ACME HTTP-01 challenge and redirects everything else to HTTPS.
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomains  []string
+	TLSAutocertEmail    string
+	TLSAutocertCacheDir string
+	TLSACMEDirectoryURL string
+	HTTPRedirectAddr    string
 
 	// Observability
 	MetricsEnabled     bool
@@ -113,6 +184,9 @@ type Config struct {
 	AdminDebugBindAddr string
 	LogLevel           string
 	LogFormat          string
+	// AdminAPIToken gates the /api/admin/* operator endpoints (self-test,
+	// stats). Empty disables the group entirely — it returns 404.
+	AdminAPIToken string
 
 	// Rate limiting
 	RateLimitEnabled                     bool
@@ -336,10 +410,156 @@ type Config struct {
 	DRDesktopMacArm64Key string
 	DRDesktopMacIntelKey string
 	DRDesktopWindowsKey  string
+
+	// Download offload. When DownloadAccelMode is set, /api/download/:jobId
+	// only authorizes the request and hands the actual byte-pushing to the
+	// fronting web server, so multi-GB results don't pin a Gin goroutine:
+	//   - "x-accel-redirect": nginx. DownloadAccelLocation is an `internal`
+	//     location aliased to OUTPUT_DIR (e.g. /_protected_outputs/).
+	//   - "x-sendfile": Apache mod_xsendfile / lighttpd; sends the absolute path.
+	// Empty (the default) serves the file from Go as before.
+	DownloadAccelMode     string
+	DownloadAccelLocation string
+
+	// Live capture (/api/tools/live-record). Off by default: it makes the
+	// server dial arbitrary RTMP/SRT/HLS URLs. LiveRecordMaxSeconds caps one
+	// capture window; LiveRecordAllowPrivateHosts lifts the SSRF guard for
+	// deployments that ingest from an internal media server, and allows HLS,
+	// whose segment URIs the guard can't check.
+	LiveRecordEnabled           bool
+	LiveRecordMaxSeconds        int
+	LiveRecordAllowPrivateHosts bool
+
+	// Webpage screenshots (/api/tools/webpage-screenshot) run a headless
+	// Chrome/Chromium. Off by default for the same SSRF reasons as live
+	// capture. ScreenshotChromePath overrides the browser lookup on PATH.
+	ScreenshotEnabled           bool
+	ScreenshotChromePath        string
+	ScreenshotAllowPrivateHosts bool
+
+	// Document thumbnails (/api/tools/document-thumbnail). PDFs always work
+	// when poppler is installed; office formats need LibreOffice and are
+	// opt-in via DocumentThumbnailOfficeEnabled.
+	DocumentThumbnailOfficeEnabled bool
+	LibreOfficePath                string
+
+	// Platform URL import (/api/tools/url-import) downloads YouTube, Vimeo
+	// and SoundCloud links with yt-dlp. Off by default: it makes the server
+	// fetch caller-chosen URLs. Only hosts in MediaURLImportHosts (and their
+	// subdomains) are accepted; MediaURLImportMaxSeconds caps the media length.
+	MediaURLImportEnabled    bool
+	MediaURLImportHosts      []string
+	MediaURLImportMaxSeconds int
+	YtDlpPath                string
+
+	// Job file storage. StorageBackend "local" (the default) leaves originals
+	// and outputs in UPLOAD_DIR / OUTPUT_DIR; "s3" moves them to
+	// StorageS3Bucket under StorageS3Prefix once a job is done and serves
+	// downloads through presigned URLs. Files over StorageMultipartPartSize
+	// go up as multipart uploads. S3UsePathStyle is needed by most MinIO
+	// deployments (AWS_S3_ENDPOINT points the client at them).
+	StorageBackend           string
+	StorageS3Bucket          string
+	StorageS3Prefix          string
+	StorageMultipartPartSize int64
+	S3UsePathStyle           bool
+
+	// KeepOriginals keeps a job's original upload once the job is done so
+	// it can be downloaded again from /api/job/:jobId/original (until
+	// UploadRetention expires it). False deletes it as soon as the job
+	// finishes.
+	KeepOriginals bool
+
+	// JobRetryMax is how many times a conversion that failed for a transient
+	// reason (a full or failing disk, no free file descriptors) is retried
+	// automatically; 0 disables it. Retries wait JobRetryBackoff, doubling
+	// each time up to JobRetryMaxBackoff.
+	JobRetryMax        int
+	JobRetryBackoff    time.Duration
+	JobRetryMaxBackoff time.Duration
+
+	// JobStoreDir holds a JSON record of every job so job status survives a
+	// restart; jobs interrupted by the restart are rerun or failed on startup.
+	JobStoreDir string
+
+	// ResultCacheEnabled makes an upload of a file already converted with
+	// the same options return the earlier job instead of converting again.
+	ResultCacheEnabled bool
+
+	// Per-job limits. JobTimeout, when > 0, bounds a whole job, a
+	// conversion including its retries; CommandTimeout still bounds each
+	// command in it. Restorations only have their model timeouts. Every
+	// command run on an upload is started with nice(JobNice) and prlimit's
+	// address-space (JobMemoryLimitBytes) and CPU-time (JobCPULimitSeconds)
+	// limits; 0 leaves each unset.
+	JobTimeout          time.Duration
+	JobNice             int
+	JobMemoryLimitBytes int64
+	JobCPULimitSeconds  int
+
+	// Sandbox ("firejail" or "bwrap"; "" for none) confines every command
+	// the services run on uploads, not only a conversion's: no network
+	// (except for live recording, URL import and page screenshots, which
+	// fetch their input), and nothing writable outside UploadDir,
+	// OutputDir, TempDir and the system temp dir. SandboxUID and SandboxGID,
+	// when > 0, run them as that user, which needs the server to run as
+	// root.
+	Sandbox    string
+	SandboxUID int
+	SandboxGID int
+
+	// MaxQueueDepth, when > 0, makes /api/upload answer 429 while that many
+	// jobs are pending or processing, asking clients to come back after
+	// QueueRetryAfter.
+	MaxQueueDepth   int
+	QueueRetryAfter time.Duration
+
+	// OIDC bearer tokens. When OIDCIssuer is set, JWTs it signed (keys from
+	// OIDCJWKSURL, or its discovery document) authenticate users of the
+	// conversion routes, and OIDCSubjectClaim becomes the owner of their
+	// jobs. OIDCAudience, when set, must be in the token's aud.
+	// OIDCRequired rejects requests with neither a token nor an API key.
+	OIDCIssuer       string
+	OIDCJWKSURL      string
+	OIDCAudience     string
+	OIDCSubjectClaim string
+	OIDCRequired     bool
+
+	// Per-client limits, kept in process so they hold without Redis. A
+	// client is its API key's tenant, or its IP when it has no key.
+	// ClientRateLimitRPS refills a token bucket of ClientRateLimitBurst
+	// requests (0 disables it); MaxConcurrentJobsPerClient caps the
+	// client's pending and processing jobs (0 disables it). Tenant
+	// policies can override each.
+	ClientRateLimitRPS         float64
+	ClientRateLimitBurst       int
+	MaxConcurrentJobsPerClient int
+
+	// UsageFile records each API key tenant's monthly usage (jobs, input
+	// bytes, encode seconds) for GET /api/usage and tenant quotas. Empty
+	// keeps usage in memory only.
+	UsageFile string
+
+	// ClamdAddress points at a ClamAV daemon ("/run/clamav/clamd.ctl",
+	// "unix:<path>" or "host:3310"). When set, conversion inputs are
+	// streamed to it before they are converted and infected jobs never run.
+	// ClamdTimeout bounds each scan.
+	ClamdAddress string
+	ClamdTimeout time.Duration
+
+	// HealthMinFreeDiskBytes is the free space below which the deep health
+	// check (/api/health?deep=true) reports a work directory as low on disk.
+	HealthMinFreeDiskBytes int64
 }
 
 func Load() *Config {
 	maxFileSize := getEnvInt64("MAX_FILE_SIZE_BYTES", 10000*1024*1024)
+	s3Bucket := getEnv("S3_BUCKET", "media-manipulator")
+	maxResolution := parseResolution(getEnv("MAX_RESOLUTION", ""))
+	sandboxKind := strings.ToLower(getEnv("SANDBOX", "none"))
+	if sandboxKind == "none" {
+		sandboxKind = ""
+	}
 	return &Config{
 		Port:               DefaultPort,
 		UploadDir:          getEnv("UPLOAD_DIR", "uploads"),
@@ -349,8 +569,11 @@ func Load() *Config {
 		MaxVideoUpload:     getEnvInt64("MAX_VIDEO_UPLOAD_SIZE_BYTES", maxFileSize),
 		CommandTimeout:     time.Duration(getEnvInt("COMMAND_TIMEOUT_SECONDS", 6*60*60)) * time.Second,
 		AnalysisWorkers:    getEnvInt("ANALYSIS_WORKERS", 1),
+		ChunkEncodeWorkers: getEnvInt("CHUNK_ENCODE_WORKERS", 4),
+		ProbeConcurrency:   getEnvInt("PROBE_CONCURRENCY", 4),
+		ProbeMemoryLimit:   getEnvInt64("PROBE_MEMORY_LIMIT_BYTES", 32*1024*1024),
 		AWSRegion:          getEnv("AWS_REGION", "us-west-2"),
-		S3Bucket:           getEnv("S3_BUCKET", "media-manipulator"),
+		S3Bucket:           s3Bucket,
 		S3Endpoint:         getEnv("AWS_S3_ENDPOINT", ""),
 		S3PresignTTL:       time.Duration(getEnvInt("S3_PRESIGN_TTL_SECONDS", 15*60)) * time.Second,
 		S3ResultPresignTTL: time.Duration(getEnvInt("S3_RESULT_PRESIGN_TTL_SECONDS", 30*60)) * time.Second,
@@ -415,6 +638,39 @@ func Load() *Config {
 		TempRetention:              time.Duration(getEnvInt("TEMP_RETENTION_SECONDS", 3600)) * time.Second,
 		CleanupDryRun:              getEnvBool("CLEANUP_DRY_RUN", false),
 		CleanupAuditMaxPathsPerRun: getEnvInt("CLEANUP_AUDIT_MAX_PATHS_PER_RUN", 1000),
+		CleanupTrashRetention:      time.Duration(getEnvIntDefault("CLEANUP_TRASH_RETENTION_SECONDS", 0)) * time.Second,
+		CleanupTrashDir:            getEnv("CLEANUP_TRASH_DIR", ""),
+		JobRetention:               time.Duration(getEnvInt("JOB_RETENTION_SECONDS", 7*86400)) * time.Second,
+		CleanupMaxDiskBytes:        getEnvInt64("CLEANUP_MAX_DISK_BYTES", 0),
+
+		// Encryption at rest
+		StorageEncryptionKey:          getEnv("STORAGE_ENCRYPTION_KEY", ""),
+		StorageEncryptionKeyFile:      getEnv("STORAGE_ENCRYPTION_KEY_FILE", ""),
+		StorageEncryptionKeyCommand:   getEnv("STORAGE_ENCRYPTION_KEY_COMMAND", ""),
+		StorageEncryptionPreviousKeys: splitCSV(getEnv("STORAGE_ENCRYPTION_PREVIOUS_KEYS", "")),
+
+		// Upload filename policy
+		UploadAllowedExtensions: splitExtensions(getEnv("UPLOAD_ALLOWED_EXTENSIONS", "")),
+		UploadDeniedExtensions:  splitExtensions(getEnv("UPLOAD_DENIED_EXTENSIONS", defaultDeniedUploadExtensions)),
+
+		CORSAllowedOrigins: splitCSV(getEnv("CORS_ALLOWED_ORIGINS", defaultCORSOrigins)),
+
+		AllowedInputFormats:  splitExtensions(getEnv("ALLOWED_INPUT_FORMATS", "")),
+		DeniedInputFormats:   splitExtensions(getEnv("DENIED_INPUT_FORMATS", "")),
+		AllowedOutputFormats: splitExtensions(getEnv("ALLOWED_OUTPUT_FORMATS", "")),
+		DeniedOutputFormats:  splitExtensions(getEnv("DENIED_OUTPUT_FORMATS", "")),
+		MaxResolutionWidth:   maxResolution[0],
+		MaxResolutionHeight:  maxResolution[1],
+
+		TenantPoliciesFile: getEnv("TENANT_POLICIES_FILE", ""),
+
+		TLSCertFile:         getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:          getEnv("TLS_KEY_FILE", ""),
+		TLSAutocertDomains:  splitCSVLower(getEnv("TLS_AUTOCERT_DOMAINS", "")),
+		TLSAutocertEmail:    getEnv("TLS_AUTOCERT_EMAIL", ""),
+		TLSAutocertCacheDir: getEnv("TLS_AUTOCERT_CACHE_DIR", "autocert"),
+		TLSACMEDirectoryURL: getEnv("TLS_ACME_DIRECTORY_URL", ""),
+		HTTPRedirectAddr:    getEnv("HTTP_REDIRECT_ADDR", ""),
 
 		// Observability
 		MetricsEnabled:     getEnvBool("METRICS_ENABLED", true),
@@ -422,6 +678,7 @@ func Load() *Config {
 		AdminDebugBindAddr: getEnv("ADMIN_DEBUG_BIND_ADDR", ""),
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
 		LogFormat:          getEnv("LOG_FORMAT", "json"),
+		AdminAPIToken:      strings.TrimSpace(getEnv("ADMIN_API_TOKEN", "")),
 
 		// Rate limiting
 		RateLimitEnabled:                     getEnvBool("RATE_LIMIT_ENABLED", true),
@@ -607,6 +864,70 @@ func Load() *Config {
 		DRDesktopMacArm64Key: getEnv("DR_DESKTOP_MAC_ARM64_KEY", "double-raven/desktop/mac/apple/Double Raven Portal-0.1.0-arm64.dmg"),
 		DRDesktopMacIntelKey: getEnv("DR_DESKTOP_MAC_INTEL_KEY", "double-raven/desktop/mac/intel/Double Raven Portal-0.1.0.dmg"),
 		DRDesktopWindowsKey:  getEnv("DR_DESKTOP_WINDOWS_KEY", "double-raven/desktop/windows/Double Raven Portal Setup 0.1.0.exe"),
+
+		DownloadAccelMode:     strings.ToLower(strings.TrimSpace(getEnv("DOWNLOAD_ACCEL_MODE", ""))),
+		DownloadAccelLocation: getEnv("DOWNLOAD_ACCEL_LOCATION", "/_protected_outputs/"),
+
+		LiveRecordEnabled:           getEnvBool("LIVE_RECORD_ENABLED", false),
+		LiveRecordMaxSeconds:        getEnvInt("LIVE_RECORD_MAX_SECONDS", 600),
+		LiveRecordAllowPrivateHosts: getEnvBool("LIVE_RECORD_ALLOW_PRIVATE_HOSTS", false),
+
+		ScreenshotEnabled:           getEnvBool("SCREENSHOT_ENABLED", false),
+		ScreenshotChromePath:        strings.TrimSpace(getEnv("SCREENSHOT_CHROME_PATH", "")),
+		ScreenshotAllowPrivateHosts: getEnvBool("SCREENSHOT_ALLOW_PRIVATE_HOSTS", false),
+
+		DocumentThumbnailOfficeEnabled: getEnvBool("DOCUMENT_THUMBNAIL_OFFICE_ENABLED", false),
+		LibreOfficePath:                getEnv("LIBREOFFICE_PATH", "soffice"),
+
+		MediaURLImportEnabled:    getEnvBool("MEDIA_URL_IMPORT_ENABLED", false),
+		MediaURLImportHosts:      splitCSVLower(getEnv("MEDIA_URL_IMPORT_HOSTS", "youtube.com,youtu.be,vimeo.com,soundcloud.com")),
+		MediaURLImportMaxSeconds: getEnvInt("MEDIA_URL_IMPORT_MAX_SECONDS", 3600),
+		YtDlpPath:                getEnv("YTDLP_PATH", "yt-dlp"),
+
+		StorageBackend:           strings.ToLower(getEnv("STORAGE_BACKEND", "local")),
+		StorageS3Bucket:          getEnv("STORAGE_S3_BUCKET", s3Bucket),
+		StorageS3Prefix:          getEnv("STORAGE_S3_PREFIX", "jobs"),
+		StorageMultipartPartSize: getEnvInt64("STORAGE_MULTIPART_PART_BYTES", 64*1024*1024),
+		S3UsePathStyle:           getEnvBool("S3_USE_PATH_STYLE", false),
+
+		KeepOriginals: getEnvBool("KEEP_ORIGINALS", true),
+
+		JobRetryMax:        max(0, getEnvIntDefault("JOB_RETRY_MAX", 2)),
+		JobRetryBackoff:    time.Duration(getEnvInt("JOB_RETRY_BACKOFF_SECONDS", 5)) * time.Second,
+		JobRetryMaxBackoff: time.Duration(getEnvInt("JOB_RETRY_MAX_BACKOFF_SECONDS", 120)) * time.Second,
+
+		JobStoreDir: getEnv("JOB_STORE_DIR", "jobs"),
+
+		ResultCacheEnabled: getEnvBool("RESULT_CACHE_ENABLED", false),
+
+		JobTimeout:          time.Duration(getEnvIntDefault("JOB_TIMEOUT_SECONDS", 0)) * time.Second,
+		JobNice:             min(19, max(0, getEnvIntDefault("JOB_NICE", 0))),
+		JobMemoryLimitBytes: getEnvInt64("JOB_MEMORY_LIMIT_BYTES", 0),
+		JobCPULimitSeconds:  max(0, getEnvIntDefault("JOB_CPU_LIMIT_SECONDS", 0)),
+
+		Sandbox:    sandboxKind,
+		SandboxUID: max(0, getEnvIntDefault("SANDBOX_UID", 0)),
+		SandboxGID: max(0, getEnvIntDefault("SANDBOX_GID", 0)),
+
+		MaxQueueDepth:   max(0, getEnvIntDefault("MAX_QUEUE_DEPTH", 0)),
+		QueueRetryAfter: time.Duration(getEnvInt("QUEUE_RETRY_AFTER_SECONDS", 30)) * time.Second,
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCJWKSURL:      getEnv("OIDC_JWKS_URL", ""),
+		OIDCAudience:     getEnv("OIDC_AUDIENCE", ""),
+		OIDCSubjectClaim: getEnv("OIDC_SUBJECT_CLAIM", "sub"),
+		OIDCRequired:     getEnvBool("OIDC_REQUIRED", false),
+
+		ClientRateLimitRPS:         getEnvFloat("CLIENT_RATE_LIMIT_RPS", 0),
+		ClientRateLimitBurst:       getEnvInt("CLIENT_RATE_LIMIT_BURST", 30),
+		MaxConcurrentJobsPerClient: max(0, getEnvIntDefault("MAX_CONCURRENT_JOBS_PER_CLIENT", 0)),
+
+		UsageFile: getEnv("USAGE_FILE", "usage.json"),
+
+		ClamdAddress: getEnv("CLAMD_ADDRESS", ""),
+		ClamdTimeout: time.Duration(getEnvInt("CLAMD_TIMEOUT_SECONDS", 300)) * time.Second,
+
+		HealthMinFreeDiskBytes: getEnvInt64("HEALTH_MIN_FREE_DISK_BYTES", 1<<30),
 	}
 }
 
@@ -690,6 +1011,79 @@ func splitCSV(raw string) []string {
 	return out
 }
 
+// defaultDeniedUploadExtensions are executables, scripts and server-side
+// templates: nothing this service converts, and the usual payloads of a
+// double-extension upload.
+const defaultDeniedUploadExtensions = "exe,dll,bat,cmd,scr,msi,ps1,vbs,jar,sh,php,phtml,phar,asp,aspx,jsp,cgi,py,htaccess"
+
+// defaultCORSOrigins are the hosted frontends (media-manipulator.com, the
+// restricted restoration deployment and the Double Raven portal on the web
+// and in its Electron app) plus the local dev server.
+const defaultCORSOrigins = "https://media-manipulator.com,https://www.media-manipulator.com,https://dr.media-manipulator.com," +
+	"http://localhost:3000,http://localhost:41999,https://drportal.wintrow.dev"
+
+// AllowsOrigin reports whether CORSAllowedOrigins lets a browser at origin
+// call the API. The "*" of a wildcard entry stands for one or more
+// subdomain labels only: "https://*.example.com" matches
+// "https://a.example.com" but not "https://example.com.evil.com",
+// "https://evilexample.com", another scheme or an explicit port.
+func (c *Config) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.CORSAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(allowed, "*")
+		if !ok || len(origin) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		if isSubdomain(origin[len(prefix) : len(origin)-len(suffix)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubdomain reports whether s is made of dot-separated hostname labels.
+func isSubdomain(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// splitExtensions is splitCSVLower with any leading dots removed, so ".MP4"
+// and "mp4" configure the same extension.
+func splitExtensions(raw string) []string {
+	parts := splitCSVLower(raw)
+	for i, p := range parts {
+		parts[i] = strings.TrimLeft(p, ".")
+	}
+	return parts
+}
+
+// parseResolution reads "WIDTHxHEIGHT" such as "3840x2160". Anything else,
+// including an empty value, is {0, 0}: no limit.
+func parseResolution(raw string) [2]int {
+	w, h, ok := strings.Cut(strings.ToLower(raw), "x")
+	if !ok {
+		return [2]int{}
+	}
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return [2]int{}
+	}
+	return [2]int{width, height}
+}
+
 // splitCSVLower is splitCSV plus ASCII-lowercasing. Used for case-insensitive
 // allowlists such as DR_ALLOWED_EMAILS so membership checks are a plain compare.
 func splitCSVLower(raw string) []string {
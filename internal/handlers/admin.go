@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/cleanup"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// AdminHandler serves the operator-only /api/admin/* endpoints. The group is
+// mounted behind middleware.RequireAdminToken in main.
+type AdminHandler struct {
+	cfg        *config.Config
+	selfTest   *services.SelfTestService
+	jobManager *services.JobManager
+	cleanup    *cleanup.Worker
+	converter  *services.Converter
+	analysis   *services.AnalysisQueue
+	atRest     *atrest.Sealer
+}
+
+func NewAdminHandler(cfg *config.Config, jobManager *services.JobManager) *AdminHandler {
+	return &AdminHandler{cfg: cfg, selfTest: services.NewSelfTestService(cfg), jobManager: jobManager}
+}
+
+// SetCleanupWorker lets GET /api/admin/stats report the cleanup schedule.
+func (h *AdminHandler) SetCleanupWorker(w *cleanup.Worker) {
+	h.cleanup = w
+}
+
+// SetWorkers lets GET /api/admin/stats report worker utilization.
+func (h *AdminHandler) SetWorkers(converter *services.Converter, analysis *services.AnalysisQueue) {
+	h.converter, h.analysis = converter, analysis
+}
+
+// SetAtRestSealer lets the admin endpoints read job files sealed at rest.
+func (h *AdminHandler) SetAtRestSealer(s *atrest.Sealer) {
+	h.atRest = s
+}
+
+// RegisterAdminRoutes mounts the admin endpoints on an already-guarded group.
+func RegisterAdminRoutes(r gin.IRouter, h *AdminHandler) {
+	r.POST("/selftest", h.RunSelfTest)
+	r.GET("/stats", h.GetStats)
+	r.GET("/jobs/active", h.ListActiveJobs)
+	r.GET("/jobs/:jobId/log", h.GetJobLog)
+	r.GET("/failures", h.ListFailures)
+	r.GET("/queue", h.GetQueue)
+	r.POST("/queue/pause", h.PauseQueue)
+	r.POST("/queue/resume", h.ResumeQueue)
+	r.GET("/trash", h.ListTrash)
+	r.POST("/trash/:jobId/restore", h.RestoreTrash)
+}
+
+// selfTestTimeout bounds a whole suite run; each case has its own, tighter
+// bound inside the service.
+const selfTestTimeout = 10 * time.Minute
+
+// RunSelfTest runs the built-in conversion suite synchronously and returns
+// the per-capability report. The response is 200 when every case passed and
+// 503 otherwise, so it can double as a provisioning gate in deploy scripts.
+func (h *AdminHandler) RunSelfTest(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), selfTestTimeout)
+	defer cancel()
+	report, err := h.selfTest.Run(ctx)
+	if err != nil {
+		logger.WithRequest(c).Error("self-test failed to run", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run self-test"})
+		return
+	}
+	status := http.StatusOK
+	if !report.Passed {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// StorageUsage is the on-disk footprint of one working directory.
+type StorageUsage struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Files int64  `json:"files"`
+	Bytes int64  `json:"bytes"`
+	Error string `json:"error,omitempty"`
+}
+
+// WorkerUtilization is how busy the server's workers are. Conversions run
+// without a fixed pool, so they are reported against the CPU count.
+type WorkerUtilization struct {
+	ConversionsRunning int                  `json:"conversionsRunning"`
+	CPUs               int                  `json:"cpus"`
+	Analysis           *services.WorkerPool `json:"analysis,omitempty"`
+}
+
+// AdminStatsResponse is the body of GET /api/admin/stats.
+type AdminStatsResponse struct {
+	services.JobStats
+	Queue   services.QueueState `json:"queue"`
+	Workers WorkerUtilization   `json:"workers"`
+	Storage []StorageUsage      `json:"storage"`
+	Cleanup cleanup.Status      `json:"cleanup"`
+}
+
+// GetStats returns aggregate job counts, throughput, per-type durations,
+// queue depth and state, worker utilization, storage usage and the cleanup
+// schedule — enough for a basic ops dashboard without going through
+// Prometheus.
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	resp := AdminStatsResponse{Workers: WorkerUtilization{CPUs: runtime.NumCPU()}}
+	if h.jobManager != nil {
+		resp.JobStats = h.jobManager.Stats(time.Now())
+		resp.Queue = h.jobManager.QueueState()
+	}
+	if h.converter != nil {
+		resp.Workers.ConversionsRunning = h.converter.RunningJobs()
+	}
+	if h.analysis != nil {
+		pool := h.analysis.Utilization()
+		resp.Workers.Analysis = &pool
+	}
+	for _, dir := range []struct{ name, path string }{
+		{"uploads", h.cfg.UploadDir},
+		{"outputs", h.cfg.OutputDir},
+		{"temp", h.cfg.TempDir},
+	} {
+		usage := StorageUsage{Name: dir.name, Path: dir.path}
+		files, bytes, err := cleanup.DirSize(dir.path)
+		usage.Files, usage.Bytes = files, bytes
+		if err != nil {
+			usage.Error = err.Error()
+		}
+		resp.Storage = append(resp.Storage, usage)
+	}
+	resp.Cleanup = h.cleanup.Status(h.cfg)
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListActiveJobs lists the pending and processing jobs, oldest first.
+func (h *AdminHandler) ListActiveJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": h.jobManager.ActiveJobs()})
+}
+
+// GetJobLog serves any job's diagnostic log.
+func (h *AdminHandler) GetJobLog(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if _, err := h.jobManager.GetJob(jobID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	serveDiagnosticLog(c, h.atRest, h.cfg.OutputDir, jobID)
+}
+
+// Bounds of the limit query parameter of GET /api/admin/failures.
+const (
+	defaultFailureLimit = 20
+	maxFailureLimit     = 200
+)
+
+// ListFailures lists the most recent failed jobs with their errors and the
+// stderr of the command that failed, newest first.
+func (h *AdminHandler) ListFailures(c *gin.Context) {
+	limit := defaultFailureLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, maxFailureLimit)
+	}
+	c.JSON(http.StatusOK, gin.H{"failures": h.jobManager.RecentFailures(limit)})
+}
+
+// GetQueue reports whether the queue is paused, with its depth.
+func (h *AdminHandler) GetQueue(c *gin.Context) {
+	c.JSON(http.StatusOK, h.queueResponse(h.jobManager.QueueState()))
+}
+
+// PauseQueue holds new jobs in pending until the queue is resumed.
+// Jobs already running finish normally.
+func (h *AdminHandler) PauseQueue(c *gin.Context) {
+	state := h.jobManager.PauseQueue(time.Now())
+	logger.WithRequest(c).Warn("job queue paused")
+	c.JSON(http.StatusOK, h.queueResponse(state))
+}
+
+// ResumeQueue starts the conversions held while the queue was paused.
+func (h *AdminHandler) ResumeQueue(c *gin.Context) {
+	state := h.jobManager.ResumeQueue()
+	logger.WithRequest(c).Info("job queue resumed")
+	c.JSON(http.StatusOK, h.queueResponse(state))
+}
+
+// AdminQueueResponse is the body of the /api/admin/queue endpoints.
+type AdminQueueResponse struct {
+	services.QueueState
+	Depth services.JobQueueDepth `json:"depth"`
+}
+
+func (h *AdminHandler) queueResponse(state services.QueueState) AdminQueueResponse {
+	return AdminQueueResponse{QueueState: state, Depth: h.jobManager.Stats(time.Now()).QueueDepth}
+}
+
+// ListTrash lists soft-deleted outputs that can still be restored, with the
+// time each one will be purged.
+func (h *AdminHandler) ListTrash(c *gin.Context) {
+	entries, err := cleanup.ListTrash(h.cfg)
+	if errors.Is(err, cleanup.ErrTrashDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.WithRequest(c).Error("failed to list trash", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list trash"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"retentionSeconds": int64(h.cfg.CleanupTrashRetention / time.Second), "entries": entries})
+}
+
+// RestoreTrash moves a soft-deleted output back into place so
+// /api/download/:jobId serves it again.
+func (h *AdminHandler) RestoreTrash(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	err := cleanup.RestoreFromTrash(h.cfg, jobID)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"restored": jobID})
+	case errors.Is(err, cleanup.ErrTrashDisabled), errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "no trashed output for that job"})
+	case errors.Is(err, cleanup.ErrRestoreConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "job output already exists"})
+	default:
+		logger.WithRequest(c).Error("failed to restore trashed output", "jobId", jobID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore output"})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// SetAtRestSealer enables encryption at rest for /api/upload and /api/tools
+// jobs. A nil sealer (no key configured) leaves files in plaintext.
+func (h *ConversionHandler) SetAtRestSealer(s *atrest.Sealer) {
+	h.atRest = s
+}
+
+// sealJobFiles encrypts every file the job left in its upload and output
+// directories once the pipeline is done with them: the original, the output
+// and the sidecars next to it (metadata.json, reports, diagnostics.json,
+// transcripts). A failure is logged loudly but not surfaced to the client:
+// the job result is still valid, the file just stays readable.
+func (h *ConversionHandler) sealJobFiles(job *models.ConversionJob, outputDir string) {
+	if h.atRest == nil {
+		return
+	}
+	for _, dir := range []string{filepath.Join(h.cfg.UploadDir, job.ID), outputDir} {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if err := h.atRest.SealFile(path); err != nil {
+				jobLogger(job).Error("at-rest encryption failed", "file", filepath.Base(path), "error", err)
+			}
+			return nil
+		})
+	}
+}
+
+// startToolJob dispatches a /api/tools job and seals its files once it is
+// done.
+func (h *ConversionHandler) startToolJob(job *models.ConversionJob, run func()) {
+	h.jobManager.Dispatch(func() {
+		defer h.sealJobFiles(job, filepath.Join(h.cfg.OutputDir, job.ID))
+		if !h.scanJobUploads(job) {
+			return
+		}
+		run()
+	})
+}
+
+// readJobFile reads a small job file such as a JSON sidecar, decrypting it
+// when it is sealed at rest.
+func readJobFile(sealer *atrest.Sealer, path string) ([]byte, error) {
+	if !atrest.IsSealed(path) {
+		return os.ReadFile(path)
+	}
+	if sealer == nil {
+		return nil, errors.New("file is encrypted but no storage encryption key is configured")
+	}
+	r, _, err := sealer.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// sendSealedFile serves the decrypted contents of a sealed output, with
+// range support. The accel offload modes are bypassed because nginx/Apache
+// would hand out the ciphertext.
+func (h *ConversionHandler) sendSealedFile(c *gin.Context, filePath string) {
+	if h.atRest == nil {
+		logger.WithRequest(c).Error("file is encrypted but no storage encryption key is configured", "file", filePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "File is encrypted and cannot be decrypted"})
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	r, size, err := h.atRest.Open(filePath)
+	if err != nil {
+		logger.WithRequest(c).Error("failed to open encrypted file", "file", filePath, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer r.Close()
+	serveContent(c, filePath, info.ModTime(), size, &corruptionLogger{ReadSeeker: r, path: filePath, log: logger.WithRequest(c)})
+}
+
+// corruptionLogger logs a sealed file failing to decrypt mid-download. By
+// then the headers are out, so all that's left is cutting the body short.
+type corruptionLogger struct {
+	io.ReadSeeker
+	path   string
+	log    *slog.Logger
+	logged bool
+}
+
+func (l *corruptionLogger) Read(p []byte) (int, error) {
+	n, err := l.ReadSeeker.Read(p)
+	if err != nil && errors.Is(err, atrest.ErrCorrupt) && !l.logged {
+		l.logged = true
+		l.log.Error("file failed to decrypt", "file", l.path, "error", err)
+	}
+	return n, err
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestSealJobFilesSealsSidecars(t *testing.T) {
+	sealer, err := atrest.NewSealer(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{UploadDir: t.TempDir(), OutputDir: t.TempDir()}
+	h := &ConversionHandler{cfg: cfg, atRest: sealer}
+	job := &models.ConversionJob{ID: "job-1"}
+	outputDir := filepath.Join(cfg.OutputDir, job.ID)
+	files := []string{
+		filepath.Join(cfg.UploadDir, job.ID, "original_in.mp4"),
+		filepath.Join(outputDir, "converted.mp4"),
+		filepath.Join(outputDir, "metadata.json"),
+		filepath.Join(outputDir, services.DiagnosticLogFile),
+		filepath.Join(outputDir, "transcript", "transcript.json"),
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte(`{"ok":true}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.sealJobFiles(job, outputDir)
+	for _, f := range files {
+		if !atrest.IsSealed(f) {
+			t.Errorf("%s was left in plaintext", filepath.Base(f))
+		}
+	}
+	data, err := readJobFile(sealer, files[3])
+	if err != nil || string(data) != `{"ok":true}` {
+		t.Fatalf("readJobFile = %q, %v", data, err)
+	}
+	if _, err := readJobFile(nil, files[3]); err == nil {
+		t.Fatal("readJobFile without a key should fail on a sealed file")
+	}
+}
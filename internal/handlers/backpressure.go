@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// rejectWhenQueueFull answers 429 with Retry-After when MAX_QUEUE_DEPTH
+// jobs are already pending or processing, before the upload is read. The
+// check isn't atomic with creating the job, so a burst can overshoot the
+// limit by a few jobs. It reports whether the request was rejected.
+func (h *ConversionHandler) rejectWhenQueueFull(c *gin.Context) bool {
+	if h.cfg.MaxQueueDepth <= 0 {
+		return false
+	}
+	depth := h.jobManager.ActiveCount()
+	if depth < h.cfg.MaxQueueDepth {
+		return false
+	}
+	retryAfter := max(1, int(h.cfg.QueueRetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":             "The server is busy with other jobs. Please try again shortly.",
+		"retryAfterSeconds": retryAfter,
+		"queueDepth":        depth,
+	})
+	return true
+}
+
+// rejectWhenClientBusy answers 429 when the caller already has its
+// MAX_CONCURRENT_JOBS_PER_CLIENT (or its tenant's maxConcurrentJobs) jobs
+// pending or processing, before the upload is read. createClientJob checks
+// again when the job is created. It reports whether the request was
+// rejected.
+func (h *ConversionHandler) rejectWhenClientBusy(c *gin.Context) bool {
+	active, limit, busy := clientBusy(c, h.jobManager, h.cfg)
+	if !busy {
+		return false
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":             errClientBusy.Error(),
+		"retryAfterSeconds": max(1, int(h.cfg.QueueRetryAfter.Seconds())),
+		"activeJobs":        active,
+		"maxConcurrentJobs": limit,
+	})
+	return true
+}
+
+var errClientBusy = errors.New("You already have the maximum number of jobs running. Please wait for one to finish.")
+
+// clientBusy reports whether the caller is at its concurrent job limit,
+// setting Retry-After when it is. While a limit applies,
+// X-Concurrent-Jobs-Limit and X-Concurrent-Jobs-Remaining report it.
+func clientBusy(c *gin.Context, jm *services.JobManager, cfg *config.Config) (active, limit int, busy bool) {
+	limit = cfg.MaxConcurrentJobsPerClient
+	if t := middleware.Tenant(c); t != nil && t.Policy.MaxConcurrentJobs > 0 {
+		limit = t.Policy.MaxConcurrentJobs
+	}
+	client := middleware.ClientKey(c)
+	if limit <= 0 || client == "" {
+		return 0, limit, false
+	}
+	active = jm.ActiveCountFor(client)
+	c.Header("X-Concurrent-Jobs-Limit", strconv.Itoa(limit))
+	c.Header("X-Concurrent-Jobs-Remaining", strconv.Itoa(max(0, limit-active)))
+	if active < limit {
+		return active, limit, false
+	}
+	c.Header("Retry-After", strconv.Itoa(max(1, int(cfg.QueueRetryAfter.Seconds()))))
+	return active, limit, true
+}
+
+// createClientJob creates a job owned by the caller and counted against its
+// client. Every handler that starts a job goes through it, so the
+// concurrent job limit and the tenant's quota hold whichever endpoint the
+// job came from. Like rejectWhenQueueFull the checks race other requests
+// creating jobs. The returned status is the HTTP status to answer with on
+// error.
+func createClientJob(c *gin.Context, jm *services.JobManager, cfg *config.Config, originalFile models.OriginalFileInfo, options map[string]interface{}) (*models.ConversionJob, int, error) {
+	if _, _, busy := clientBusy(c, jm, cfg); busy {
+		return nil, http.StatusTooManyRequests, errClientBusy
+	}
+	if err := checkQuota(c, jm, originalFile.Size); err != nil {
+		return nil, http.StatusTooManyRequests, err
+	}
+	job := jm.CreateOwnedJob(middleware.User(c), originalFile, options)
+	_ = jm.SetClient(job.ID, middleware.ClientKey(c))
+	_ = jm.SetRequestID(job.ID, logger.RequestID(c))
+	return job, http.StatusOK, nil
+}
+
+// QueueStatus is the queue block of the health endpoints: the depth
+// MAX_QUEUE_DEPTH is compared against, and the limit (0 when off).
+func (h *ConversionHandler) QueueStatus() gin.H {
+	return gin.H{"depth": h.jobManager.ActiveCount(), "maxDepth": h.cfg.MaxQueueDepth}
+}
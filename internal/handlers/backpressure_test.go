@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestRejectWhenQueueFull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm, cfg: &config.Config{MaxQueueDepth: 2, QueueRetryAfter: 45 * time.Second}}
+	reject := func() (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+		return h.rejectWhenQueueFull(c), w
+	}
+
+	first := jm.CreateJob(models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	jm.CreateJob(models.OriginalFileInfo{Name: "b.mp4"}, nil)
+	rejected, w := reject()
+	if !rejected || w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "45" {
+		t.Fatalf("full queue: rejected=%v code=%d Retry-After=%q", rejected, w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Finished jobs don't count towards the depth.
+	_ = jm.UpdateJobStatus(first.ID, models.StatusCompleted)
+	if rejected, _ := reject(); rejected {
+		t.Fatal("rejected with room in the queue")
+	}
+	if got := h.QueueStatus()["depth"]; got != 1 {
+		t.Fatalf("QueueStatus depth = %v, want 1", got)
+	}
+
+	h.cfg.MaxQueueDepth = 0
+	jm.CreateJob(models.OriginalFileInfo{Name: "c.mp4"}, nil)
+	if rejected, _ := reject(); rejected {
+		t.Fatal("rejected with MAX_QUEUE_DEPTH unset")
+	}
+}
+
+func TestRejectWhenClientBusy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm, cfg: &config.Config{MaxConcurrentJobsPerClient: 1, QueueRetryAfter: 10 * time.Second}}
+	reject := func(ip string) (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+		c.Request.RemoteAddr = ip + ":1234"
+		return h.rejectWhenClientBusy(c), w
+	}
+
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	_ = jm.SetClient(job.ID, "ip:203.0.113.7")
+	rejected, w := reject("203.0.113.7")
+	if !rejected || w.Code != http.StatusTooManyRequests || w.Header().Get("X-Concurrent-Jobs-Remaining") != "0" {
+		t.Fatalf("busy client: rejected=%v code=%d headers=%v", rejected, w.Code, w.Header())
+	}
+	if rejected, w := reject("198.51.100.1"); rejected || w.Header().Get("X-Concurrent-Jobs-Remaining") != "1" {
+		t.Fatalf("other client: rejected=%v headers=%v", rejected, w.Header())
+	}
+	_ = jm.UpdateJobStatus(job.ID, models.StatusCompleted)
+	if rejected, _ := reject("203.0.113.7"); rejected {
+		t.Fatal("rejected once the job finished")
+	}
+}
+
+func TestCreateClientJobEnforcesLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := services.NewJobManager()
+	cfg := &config.Config{MaxConcurrentJobsPerClient: 1, QueueRetryAfter: 10 * time.Second}
+	create := func() (*models.ConversionJob, int, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/tools/batch-images", nil)
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		job, status, _ := createClientJob(c, jm, cfg, models.OriginalFileInfo{Name: "a.png"}, map[string]interface{}{"mode": "batch_images"})
+		return job, status, w
+	}
+
+	job, status, _ := create()
+	if job == nil || status != http.StatusOK || job.Client != "ip:203.0.113.7" {
+		t.Fatalf("first job = %+v, status %d", job, status)
+	}
+	if again, status, w := create(); again != nil || status != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "10" {
+		t.Fatalf("second job = %+v, status %d, headers %v", again, status, w.Header())
+	}
+	_ = jm.UpdateJobStatus(job.ID, models.StatusCompleted)
+	if again, _, _ := create(); again == nil {
+		t.Fatal("refused once the first job finished")
+	}
+}
@@ -1,24 +1,31 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/i18n"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/storage"
 )
 
 type ConversionHandler struct {
@@ -32,10 +39,28 @@ type ConversionHandler struct {
 	specializedTools   *services.SpecializedToolsService
 	captionTranslator  *services.CaptionTranslatorService
 	stitchAudioTool    *services.StitchAudioToVideoService
+	imageSequenceTool  *services.ImageSequenceToVideoService
+	liveRecord         *services.LiveRecordService
+	mediaURL           *services.MediaURLService
+	webpageScreenshot  *services.WebpageScreenshotService
+	documentThumbnail  *services.DocumentThumbnailService
+	batchImages        *services.BatchImageService
+	videoGrid          *services.VideoGridService
+	audioJoin          *services.AudioJoinService
 	s3Client           *s3.Client
 	s3Presign          *s3.PresignClient
 	faceDetectionStore *services.FaceDetectionStore
 	aiService          *services.AIService
+	atRest             *atrest.Sealer
+	virusScanner       *services.ClamdScanner
+	// storage keeps finished jobs' originals and outputs.
+	storage storage.Backend
+	// tusUploads holds resumable uploads in progress.
+	tusUploads *services.TusStore
+	// probeSlots bounds concurrent /api/details probes.
+	probeSlots chan struct{}
+	// previewMu serializes preview proxy builds.
+	previewMu sync.Mutex
 }
 
 func NewConversionHandler(jobManager *services.JobManager, converter *services.Converter, cfg *config.Config, inspector *services.MediaInspector, analysisJobs *services.AnalysisQueue, transcription *services.TranscriptionService, s3Client *s3.Client, faceDetectionStore *services.FaceDetectionStore) *ConversionHandler {
@@ -60,6 +85,24 @@ func NewConversionHandler(jobManager *services.JobManager, converter *services.C
 	specializedTools := services.NewSpecializedToolsService(cfg, jobManager)
 	captionTranslator := services.NewCaptionTranslatorService(cfg, jobManager)
 	stitchAudioTool := services.NewStitchAudioToVideoService(cfg, jobManager)
+	imageSequenceTool := services.NewImageSequenceToVideoService(cfg, jobManager)
+	liveRecord := services.NewLiveRecordService(cfg, jobManager)
+	mediaURL := services.NewMediaURLService(cfg, jobManager)
+	webpageScreenshot := services.NewWebpageScreenshotService(cfg, jobManager)
+	documentThumbnail := services.NewDocumentThumbnailService(cfg, jobManager)
+	batchImages := services.NewBatchImageService(cfg, jobManager, converter)
+	videoGrid := services.NewVideoGridService(cfg, jobManager)
+	audioJoin := services.NewAudioJoinService(cfg, jobManager)
+	probeConcurrency := 4
+	if cfg != nil && cfg.ProbeConcurrency > 0 {
+		probeConcurrency = cfg.ProbeConcurrency
+	}
+	var tusUploads *services.TusStore
+	var jobStorage storage.Backend
+	if cfg != nil {
+		tusUploads = services.NewTusStore(cfg.UploadDir, cfg.UploadRetention)
+		jobStorage = &storage.Local{UploadDir: cfg.UploadDir, OutputDir: cfg.OutputDir}
+	}
 	return &ConversionHandler{
 		jobManager:         jobManager,
 		converter:          converter,
@@ -71,23 +114,55 @@ func NewConversionHandler(jobManager *services.JobManager, converter *services.C
 		specializedTools:   specializedTools,
 		captionTranslator:  captionTranslator,
 		stitchAudioTool:    stitchAudioTool,
+		imageSequenceTool:  imageSequenceTool,
+		liveRecord:         liveRecord,
+		mediaURL:           mediaURL,
+		webpageScreenshot:  webpageScreenshot,
+		documentThumbnail:  documentThumbnail,
+		batchImages:        batchImages,
+		videoGrid:          videoGrid,
+		audioJoin:          audioJoin,
 		s3Client:           s3Client,
 		s3Presign:          presign,
 		faceDetectionStore: faceDetectionStore,
 		aiService:          ai,
+		storage:            jobStorage,
+		tusUploads:         tusUploads,
+		probeSlots:         make(chan struct{}, probeConcurrency),
 	}
 }
 
 func RegisterConversionRoutes(r gin.IRouter, h *ConversionHandler) {
 	r.POST("/details", h.IdentifyFile)
+	r.POST("/waveform", h.Waveform)
+	r.POST("/analyze/loudness", h.AnalyzeLoudness)
+	r.POST("/analyze/silence", h.AnalyzeSilence)
+	r.POST("/analyze/image-hash", h.ImageHash)
+	r.POST("/analyze/image-compare", h.CompareImages)
 	r.POST("/upload", h.UploadFile)
 	r.POST("/video-upload/presign", h.PresignVideoUpload)
 	r.POST("/video-upload/complete", h.CompleteVideoUpload)
+	r.OPTIONS("/tus", h.TusOptions)
+	r.POST("/tus", h.TusCreate)
+	r.HEAD("/tus/:uploadId", h.TusHead)
+	r.PATCH("/tus/:uploadId", h.TusPatch)
+	r.DELETE("/tus/:uploadId", h.TusDelete)
+	r.GET("/jobs", h.ListJobs)
+	r.GET("/usage", h.GetUsage)
 	r.GET("/job/:jobId", h.GetJobStatus)
+	r.GET("/job/:jobId/diff", h.GetJobDiff)
+	r.GET("/job/:jobId/original", h.DownloadOriginal)
+	r.DELETE("/job/:jobId", h.DeleteJob)
+	r.DELETE("/job/:jobId/files", h.DeleteJobFiles)
+	r.POST("/job/:jobId/retry", h.RetryJob)
 	r.GET("/job/:jobId/events", h.StreamJobEvents)
+	r.GET("/job/:jobId/output-stream", h.StreamJobOutput)
+	r.GET("/job/:jobId/log", h.GetJobLog)
 	r.GET("/download/:jobId", h.DownloadFile)
+	r.GET("/preview/:jobId", h.PreviewFile)
 	r.GET("/transcript/:jobId", h.GetTranscriptResult)
 	r.GET("/analysis/:jobId", h.GetAnalysisResult)
+	r.GET("/report/:jobId", h.GetConversionReport)
 
 	// Lightweight preview/helper endpoint that detects faces and stashes the
 	// boxes server-side. The final conversion still goes through /upload.
@@ -106,18 +181,51 @@ func (h *ConversionHandler) IdentifyFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("identify_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
-	if err := h.saveUploadedFile(file, tempPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
 		return
 	}
-	defer func() { _ = os.Remove(tempPath) }()
+	defer release()
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
 	defer cancel()
 
-	fileType, mimeType := h.inspector.DetectFile(ctx, tempPath, fileHeader.GetHeader("Content-Type"))
-	metadata, err := h.inspector.ProbeFile(ctx, tempPath, fileType)
+	// Small files are probed from memory over stdin. Anything larger, or a
+	// file a tool can't read from a pipe, is written to temp/ as before.
+	var (
+		fileType models.FileType
+		mimeType string
+		metadata *services.MediaMetadata
+		data     []byte
+	)
+	if fileHeader.Size <= h.cfg.ProbeMemoryLimit {
+		if data, err = io.ReadAll(io.LimitReader(file, h.cfg.ProbeMemoryLimit+1)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		fileType, mimeType = h.inspector.DetectBytes(data, fileHeader.Filename, fileHeader.GetHeader("Content-Type"))
+		if m, ok := h.inspector.ProbeBytes(ctx, data, fileType); ok {
+			metadata = m
+		}
+	}
+	if metadata == nil {
+		var src io.Reader = file
+		if data != nil {
+			src = bytes.NewReader(data)
+		}
+		tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("identify_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+		if err := h.saveUploadedFile(src, tempPath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+			return
+		}
+		defer func() { _ = os.Remove(tempPath) }()
+		if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+			c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+			return
+		}
+		fileType, mimeType = h.inspector.DetectFile(ctx, tempPath, fileHeader.GetHeader("Content-Type"))
+		metadata, err = h.inspector.ProbeFile(ctx, tempPath, fileType)
+	}
 	if err != nil && metadata == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to identify file: %v", err)})
 		return
@@ -133,8 +241,11 @@ func (h *ConversionHandler) IdentifyFile(c *gin.Context) {
 		MimeType:      mimeType,
 		Details:       metadata.Details,
 		ImageMetadata: metadata.ImageMetadata,
+		AudioTracks:   metadata.AudioTracks,
+		AudioTags:     metadata.AudioTags,
 		Tool:          metadata.Tool,
 		RawOutput:     metadata.Raw,
+		Identify:      metadata.Identify,
 	}
 	if metadata.Error != "" {
 		response.Details["probe_error"] = metadata.Error
@@ -142,7 +253,28 @@ func (h *ConversionHandler) IdentifyFile(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// acquireProbeSlot waits for one of the PROBE_CONCURRENCY slots shared by
+// the synchronous analysis endpoints. Probes of large videos are I/O- and
+// CPU-heavy; queueing beyond the limit keeps a burst of them from thrashing
+// the disk. ok is false (and the response written) when the client gave up
+// while waiting.
+func (h *ConversionHandler) acquireProbeSlot(c *gin.Context) (release func(), ok bool) {
+	if h.probeSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case h.probeSlots <- struct{}{}:
+		return func() { <-h.probeSlots }, true
+	case <-c.Request.Context().Done():
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Request cancelled while waiting to be processed"})
+		return nil, false
+	}
+}
+
 func (h *ConversionHandler) UploadFile(c *gin.Context) {
+	if h.rejectWhenQueueFull(c) || h.rejectWhenClientBusy(c) {
+		return
+	}
 	file, fileHeader, err := h.multipartFile(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -150,7 +282,7 @@ func (h *ConversionHandler) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	options, err := parseOptions(c.Request.FormValue("options"))
+	options, delivery, err := parseUploadOptions(c.Request.FormValue("options"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -162,57 +294,136 @@ func (h *ConversionHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
+	job, status, err := h.startUploadJob(c, incomingPath, fileHeader.Filename, fileHeader.Size, fileHeader.GetHeader("Content-Type"), options, delivery)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+// parseUploadOptions reads the /api/upload options JSON and checks the parts
+// that can be validated before the file arrives. Delivery key material is
+// replaced by its redacted form in the returned options so it stays out of
+// the job record served by /api/job.
+func parseUploadOptions(raw string) (map[string]interface{}, *services.DeliveryEncryption, error) {
+	options, err := parseOptions(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := services.ConversionReportFormat(options); err != nil {
+		return nil, nil, err
+	}
+	delivery, err := services.ParseDeliveryEncryption(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if delivery != nil {
+		options["deliveryEncryption"] = delivery.Redacted()
+	}
+	return options, delivery, nil
+}
+
+// startUploadJob turns a fully received upload at incomingPath into a
+// conversion job: it detects the file type, applies the API key's policy,
+// moves the file into the job's upload directory and starts the conversion.
+// incomingPath is removed on failure. The returned status is the HTTP status
+// to answer with on error.
+func (h *ConversionHandler) startUploadJob(c *gin.Context, incomingPath, fileName string, size int64, contentType string, options map[string]interface{}, delivery *services.DeliveryEncryption) (*models.ConversionJob, int, error) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
 	defer cancel()
-	fileType, mimeType := h.inspector.DetectFile(ctx, incomingPath, fileHeader.GetHeader("Content-Type"))
+	fileType, mimeType := h.inspector.DetectFile(ctx, incomingPath, contentType)
 	if fileType == models.FileTypeUnknown {
 		_ = os.Remove(incomingPath)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported file type"})
-		return
+		return nil, http.StatusBadRequest, fmt.Errorf("Unsupported file type")
+	}
+	if err := services.CheckDeclaredType(fileType, contentType, fileName); err != nil {
+		_ = os.Remove(incomingPath)
+		return nil, http.StatusBadRequest, err
+	}
+	// An API key's policy is merged in once the file type is known, so the
+	// converter only ever sees options the tenant is allowed.
+	if t := middleware.Tenant(c); t != nil {
+		if err := t.Policy.Apply(fileType, options); err != nil {
+			_ = os.Remove(incomingPath)
+			return nil, http.StatusForbidden, err
+		}
+	}
+	if err := h.checkFormatPolicy(mimeType, fileName, options); err != nil {
+		_ = os.Remove(incomingPath)
+		return nil, rejectionStatus(err, http.StatusBadRequest), err
+	}
+	// The probe runs before the job exists so oversized inputs are refused
+	// outright.
+	metadata, probeErr := h.inspector.ProbeFile(ctx, incomingPath, fileType)
+	if err := h.checkInputResolution(metadata); err != nil {
+		_ = os.Remove(incomingPath)
+		return nil, rejectionStatus(err, http.StatusBadRequest), err
 	}
 
-	originalFile := models.OriginalFileInfo{Name: fileHeader.Filename, Size: fileHeader.Size, Type: mimeType}
-	job := h.jobManager.CreateJob(originalFile, options)
+	originalFile := models.OriginalFileInfo{Name: fileName, Size: size, Type: mimeType}
+	if delivery != nil {
+		probe := &models.ConversionJob{Options: options}
+		if isTranscribeMode(probe) || specializedMode(probe) != "" {
+			_ = os.Remove(incomingPath)
+			return nil, http.StatusBadRequest, fmt.Errorf("deliveryEncryption is only supported for standard conversions")
+		}
+	}
+	owner := middleware.User(c)
+	cached, cacheKey := h.cachedJob(owner, incomingPath, options, delivery)
+	if cached != nil {
+		_ = os.Remove(incomingPath)
+		return cached, http.StatusOK, nil
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		_ = os.Remove(incomingPath)
+		return nil, status, err
+	}
+	if cacheKey != "" {
+		_ = h.jobManager.SetCacheKey(job.ID, cacheKey)
+	}
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
 	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
 	if err := os.MkdirAll(jobUploadDir, 0755); err != nil {
+		_ = os.Remove(incomingPath)
 		h.jobManager.UpdateJobError(job.ID, "Failed to create upload directory")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare upload"})
-		return
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to prepare upload")
 	}
 	if err := os.MkdirAll(jobOutputDir, 0755); err != nil {
+		_ = os.Remove(incomingPath)
 		h.jobManager.UpdateJobError(job.ID, "Failed to create output directory")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare output"})
-		return
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to prepare output")
 	}
 
-	uploadPath := filepath.Join(jobUploadDir, "original_"+safeFilename(fileHeader.Filename))
+	uploadPath := filepath.Join(jobUploadDir, "original_"+safeFilename(fileName))
 	if err := os.Rename(incomingPath, uploadPath); err != nil {
+		_ = os.Remove(incomingPath)
 		h.jobManager.UpdateJobError(job.ID, "Failed to finalize uploaded file")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize upload"})
-		return
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to finalize upload")
 	}
 
-	metadata, probeErr := h.inspector.ProbeFile(ctx, uploadPath, fileType)
 	if probeErr != nil {
-		log.Printf("metadata probe failed for job %s: %v", job.ID, probeErr)
+		jobLogger(job).Warn("metadata probe failed", "error", probeErr)
 	}
 	if metadata == nil {
 		metadata = &services.MediaMetadata{FileType: fileType, MimeType: mimeType, Details: map[string]any{}, Error: stringOrErr(probeErr)}
 	}
 	if err := services.WriteMetadata(filepath.Join(jobOutputDir, "metadata.json"), metadata); err != nil {
-		log.Printf("failed to write metadata for job %s: %v", job.ID, err)
+		jobLogger(job).Error("failed to write metadata", "error", err)
 	}
 
 	// Skip background analysis for PDFs — the analysis queue targets image,
-	// video, and audio media and has nothing to do with documents.
-	if !isTranscribeMode(job) && specializedMode(job) == "" && fileType != models.FileTypeDocument {
+	// video, and audio media and has nothing to do with documents. It is also
+	// skipped when encrypting at rest: it reads the upload on its own schedule
+	// and would race the sealing, and it ships frames to the AI models. With
+	// virus scanning on it would open the upload before the scan does.
+	if !isTranscribeMode(job) && specializedMode(job) == "" && fileType != models.FileTypeDocument && h.atRest == nil && h.virusScanner == nil {
 		h.analysisJobs.Enqueue(services.AnalysisJob{JobID: job.ID, InputPath: uploadPath, OutputDir: jobOutputDir, FileType: fileType, MimeType: mimeType})
 	}
-	go h.processConversion(job, uploadPath, jobOutputDir)
-
-	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+	h.jobManager.Dispatch(func() { h.processConversion(job, uploadPath, jobOutputDir, delivery) })
+	return job, http.StatusOK, nil
 }
 
 func (h *ConversionHandler) GetJobStatus(c *gin.Context) {
@@ -226,7 +437,23 @@ func (h *ConversionHandler) GetJobStatus(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
 		return
 	}
-	c.JSON(http.StatusOK, job)
+	c.JSON(http.StatusOK, localizedJob(c, job))
+}
+
+// localizedJob returns job with its error message in the request's
+// language. The job manager hands out shared pointers, so a translated
+// message goes on a shallow copy.
+func localizedJob(c *gin.Context, job *models.ConversionJob) *models.ConversionJob {
+	if job == nil || job.Error == "" {
+		return job
+	}
+	msg := i18n.Localize(middleware.Language(c), job.Error)
+	if msg == job.Error {
+		return job
+	}
+	copied := *job
+	copied.Error = msg
+	return &copied
 }
 
 func (h *ConversionHandler) DownloadFile(c *gin.Context) {
@@ -247,12 +474,16 @@ func (h *ConversionHandler) DownloadFile(c *gin.Context) {
 
 	outputPath := h.outputPath(job, filepath.Join(h.cfg.OutputDir, job.ID))
 	if _, err := os.Stat(outputPath); err != nil {
+		if h.redirectToStoredOutput(c, job, outputPath, h.getOutputFilename(job)) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Converted file not found"})
 		return
 	}
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", h.getOutputFilename(job)))
-	c.Header("Content-Type", "application/octet-stream")
-	c.File(outputPath)
+	fileName := h.getOutputFilename(job)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Content-Type", outputContentType(fileName))
+	h.sendOutputFile(c, outputPath)
 }
 
 func (h *ConversionHandler) GetTranscriptResult(c *gin.Context) {
@@ -266,7 +497,7 @@ func (h *ConversionHandler) GetTranscriptResult(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transcript result not found"})
 		return
 	}
-	c.File(resultPath)
+	h.sendLocalFile(c, resultPath)
 }
 
 func (h *ConversionHandler) GetAnalysisResult(c *gin.Context) {
@@ -280,14 +511,33 @@ func (h *ConversionHandler) GetAnalysisResult(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Analysis result not yet available"})
 		return
 	}
-	c.File(resultPath)
+	h.sendLocalFile(c, resultPath)
+}
+
+// processConversion runs a job to completion. delivery, when set, encrypts
+// the converted file with the client's key; only standard conversions
+// accept it.
+func (h *ConversionHandler) processConversion(job *models.ConversionJob, inputPath string, outputDir string, delivery *services.DeliveryEncryption) {
+	h.runConversion(job, inputPath, outputDir, delivery, models.AttemptInitial)
 }
 
-func (h *ConversionHandler) processConversion(job *models.ConversionJob, inputPath string, outputDir string) {
+// runConversion is processConversion for a run started by trigger, one of
+// the models.Attempt* constants.
+func (h *ConversionHandler) runConversion(job *models.ConversionJob, inputPath string, outputDir string, delivery *services.DeliveryEncryption, trigger string) {
+	if !h.scanInput(job, inputPath) {
+		return
+	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
-		log.Printf("failed to update job %s status: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job status", "error", err)
 		return
 	}
+	_ = h.jobManager.StartAttempt(job.ID, trigger)
+	// Deferred calls run last-in first-out: an unwanted original is gone
+	// before anything else, and files are sealed before they are handed to
+	// storage.
+	defer h.persistJobFiles(job, inputPath, outputDir)
+	defer h.sealJobFiles(job, outputDir)
+	defer h.discardOriginal(job, inputPath)
 	if isTranscribeMode(job) {
 		h.processTranscription(job, inputPath, outputDir)
 		return
@@ -297,18 +547,57 @@ func (h *ConversionHandler) processConversion(job *models.ConversionJob, inputPa
 		return
 	}
 	outputPath := h.outputPath(job, outputDir)
-	if err := h.converter.ConvertFile(job, inputPath, outputPath); err != nil {
-		log.Printf("conversion failed for job %s: %v", job.ID, err)
+	if delivery != nil {
+		// Convert to the plaintext name; only the encrypted file is kept.
+		outputPath = strings.TrimSuffix(outputPath, delivery.Suffix())
+	}
+	reportFormat, _ := services.ConversionReportFormat(job.Options)
+	// JOB_TIMEOUT_SECONDS covers every attempt, retries included.
+	endJob := h.converter.BeginJob(job)
+	var attempts []services.RecordedAttempt
+	err := h.withRetries(job, outputPath, func() error {
+		h.converter.BeginCommandLog(job.ID)
+		err := h.converter.ConvertFile(job, inputPath, outputPath)
+		attempts = append(attempts, services.RecordedAttempt{Number: h.currentAttempt(job.ID), Commands: h.converter.TakeCommandLog(job.ID)})
+		return err
+	})
+	endJob()
+	h.writeDiagnosticLog(job, inputPath, outputPath, outputDir, attempts, err)
+	var commands []services.RecordedCommand
+	if len(attempts) > 0 {
+		commands = attempts[len(attempts)-1].Commands
+	}
+	if err != nil {
+		jobLogger(job).Warn("conversion failed", "error", err)
+		_ = os.Remove(outputPath)
 		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
 		return
 	}
+	if reportFormat != "" {
+		h.writeConversionReport(job, inputPath, outputPath, outputDir, reportFormat, commands)
+	}
+	if delivery != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+		err := services.EncryptForDelivery(ctx, delivery, outputPath, outputPath+delivery.Suffix())
+		cancel()
+		_ = os.Remove(outputPath)
+		if err != nil {
+			jobLogger(job).Error("delivery encryption failed", "error", err)
+			_ = os.Remove(outputPath + delivery.Suffix())
+			_ = h.jobManager.UpdateJobError(job.ID, "Failed to encrypt output for delivery")
+			return
+		}
+		// Only the size of the encrypted file is reported.
+		outputPath += delivery.Suffix()
+	}
+	h.recordResultMetadata(job, outputPath)
 	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
-		log.Printf("failed to update job %s result: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job result", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, "Failed to update job result")
 		return
 	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
-		log.Printf("failed to mark job %s completed: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job completed", "error", err)
 	}
 }
 
@@ -327,20 +616,25 @@ func (h *ConversionHandler) processTranscription(job *models.ConversionJob, inpu
 	opts := services.TranscribeOptions{Format: format, Language: language}
 	outputPath := h.outputPath(job, outputDir)
 
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
-	if _, err := h.transcription.Transcribe(ctx, job, inputPath, outputPath, opts); err != nil {
-		log.Printf("transcription failed for job %s: %v", job.ID, err)
+	err := h.withRetries(job, outputPath, func() error {
+		_, err := h.transcription.Transcribe(ctx, job, inputPath, outputPath, opts)
+		return err
+	})
+	if err != nil {
+		jobLogger(job).Warn("transcription failed", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
 		return
 	}
+	h.recordResultMetadata(job, outputPath)
 	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
-		log.Printf("failed to update job %s result: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job result", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, "Failed to update job result")
 		return
 	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
-		log.Printf("failed to mark job %s completed: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job completed", "error", err)
 	}
 }
 
@@ -350,23 +644,45 @@ func (h *ConversionHandler) processSpecializedTool(job *models.ConversionJob, mo
 		return
 	}
 	outputPath := h.outputPath(job, outputDir)
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
-	if err := h.specializedTools.Run(ctx, job, mode, inputPath, outputPath); err != nil {
-		log.Printf("specialized tool %s failed for job %s: %v", mode, job.ID, err)
+	err := h.withRetries(job, outputPath, func() error {
+		return h.specializedTools.Run(ctx, job, mode, inputPath, outputPath)
+	})
+	if err != nil {
+		jobLogger(job).Warn("specialized tool failed", "mode", mode, "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
 		return
 	}
+	h.recordResultMetadata(job, outputPath)
 	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
-		log.Printf("failed to update job %s result: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job result", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, "Failed to update job result")
 		return
 	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
-		log.Printf("failed to mark job %s completed: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job completed", "error", err)
 	}
 }
 
+// jobCommandContext is the context for the commands of a job that isn't a
+// conversion: services.JobContext, with each bounded by CommandTimeout as
+// well.
+func jobCommandContext(cfg *config.Config, job *models.ConversionJob) (context.Context, context.CancelFunc) {
+	jobCtx, cancelJob := services.JobContext(cfg, job)
+	ctx, cancel := context.WithTimeout(jobCtx, cfg.CommandTimeout)
+	return ctx, func() {
+		cancel()
+		cancelJob()
+	}
+}
+
+// jobLogger is the logger for a job's background processing. It carries
+// the request ID of the upload, so a failure can be traced to its request.
+func jobLogger(job *models.ConversionJob) *slog.Logger {
+	return logger.FromContext(logger.WithRequestID(logger.WithJob(context.Background(), job.ID), job.RequestID))
+}
+
 func isTranscribeMode(job *models.ConversionJob) bool {
 	if job == nil || job.Options == nil {
 		return false
@@ -403,6 +719,10 @@ func (h *ConversionHandler) multipartFile(c *gin.Context) (io.ReadCloser, *multi
 	if err != nil {
 		return nil, nil, fmt.Errorf("no file provided")
 	}
+	if err := checkUploadName(h.cfg, fileHeader.Filename); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
 	return file, &multipartFileHeader{Filename: fileHeader.Filename, Size: fileHeader.Size, Header: fileHeader.Header}, nil
 }
 
@@ -414,17 +734,26 @@ type multipartFileHeader struct {
 
 func (h multipartFileHeader) GetHeader(key string) string { return http.Header(h.Header).Get(key) }
 
+// saveUploadedFile writes an upload to path, which must be a fresh file
+// under UploadDir or TempDir. O_EXCL refuses to write through a pre-existing
+// file or symlink, and a partial file is removed on error.
 func (h *ConversionHandler) saveUploadedFile(file io.Reader, path string) error {
+	if !pathWithin(path, h.cfg.UploadDir, h.cfg.TempDir) {
+		return fmt.Errorf("refusing to save upload outside the upload directories: %s", path)
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	out, err := os.Create(path)
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-	_, err = io.Copy(out, file)
-	return err
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return out.Close()
 }
 
 func (h *ConversionHandler) getOutputExtension(job *models.ConversionJob) string {
@@ -446,11 +775,41 @@ func (h *ConversionHandler) getOutputExtension(job *models.ConversionJob) string
 	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "stitch_audio_to_video") {
 		return ".mp4"
 	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "image_sequence_to_video") {
+		return ".mp4"
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "webpage_screenshot") {
+		if format, _ := job.Options["format"].(string); strings.TrimSpace(format) != "" {
+			return "." + strings.ToLower(strings.TrimSpace(format))
+		}
+		return ".png"
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "document_thumbnail") {
+		if format, _ := job.Options["format"].(string); strings.TrimSpace(format) != "" {
+			return "." + strings.ToLower(strings.TrimSpace(format))
+		}
+		return ".png"
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "batch_images") {
+		return ".zip"
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "video_grid") {
+		return ".mp4"
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "audio_join") {
+		if format, _ := job.Options["format"].(string); strings.TrimSpace(format) != "" {
+			return "." + strings.ToLower(strings.TrimSpace(format))
+		}
+		return ".mp3"
+	}
 	switch models.GetFileType(job.OriginalFile.Type) {
 	case models.FileTypeImage:
 		if ext := aiImageExtension(job); ext != "" {
 			return ext
 		}
+		if hasImageSizes(job) {
+			return ".zip"
+		}
 		if format, ok := job.Options["format"].(string); ok && format != "" {
 			return "." + strings.TrimPrefix(format, ".")
 		}
@@ -468,6 +827,10 @@ func (h *ConversionHandler) getOutputExtension(job *models.ConversionJob) string
 			return ext
 		}
 		if format, ok := job.Options["format"].(string); ok && format != "" {
+			// ALAC has no file type of its own; it ships in an MP4 container.
+			if format == "alac" {
+				return ".m4a"
+			}
 			return "." + strings.TrimPrefix(format, ".")
 		}
 		return ".mp3"
@@ -478,6 +841,13 @@ func (h *ConversionHandler) getOutputExtension(job *models.ConversionJob) string
 	}
 }
 
+// hasImageSizes reports whether an image job asked for a multi-size set,
+// which is delivered as one .zip.
+func hasImageSizes(job *models.ConversionJob) bool {
+	sizes, _ := job.Options["sizes"].([]interface{})
+	return len(sizes) > 0
+}
+
 // pdfOutputExtension is the deterministic output extension for the PDF -> image
 // pathway. It must mirror parsePDFRenderOptions in the services package: an
 // "all" page selection produces a .zip of per-page images, while "first"
@@ -634,17 +1004,35 @@ func (h *ConversionHandler) getOutputFilename(job *models.ConversionJob) string
 	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "stitch_audio_to_video") {
 		return fmt.Sprintf("%s_stitched%s", name, h.getOutputExtension(job))
 	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "image_sequence_to_video") {
+		return fmt.Sprintf("%s_slideshow%s", name, h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "webpage_screenshot") {
+		return fmt.Sprintf("%s_screenshot%s", name, h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "document_thumbnail") {
+		return fmt.Sprintf("%s_thumbnail%s", name, h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "batch_images") {
+		return fmt.Sprintf("%s_batch%s", name, h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "video_grid") {
+		return fmt.Sprintf("%s_grid%s", name, h.getOutputExtension(job))
+	}
 	if isImageRestoreMode(job) {
 		return fmt.Sprintf("%s_restoration_results.tar.gz", name)
 	}
+	if models.GetFileType(job.OriginalFile.Type) == models.FileTypeImage && hasImageSizes(job) {
+		return fmt.Sprintf("%s_sizes.zip%s", name, services.DeliveryEncryptionSuffix(job.Options))
+	}
 	if models.GetFileType(job.OriginalFile.Type) == models.FileTypeDocument {
 		ext := h.getOutputExtension(job)
 		if ext == ".zip" {
-			return fmt.Sprintf("%s_pages.zip", name)
+			return fmt.Sprintf("%s_pages.zip%s", name, services.DeliveryEncryptionSuffix(job.Options))
 		}
-		return fmt.Sprintf("%s_converted%s", name, ext)
+		return fmt.Sprintf("%s_converted%s%s", name, ext, services.DeliveryEncryptionSuffix(job.Options))
 	}
-	return fmt.Sprintf("%s_converted%s", name, h.getOutputExtension(job))
+	return fmt.Sprintf("%s_converted%s%s", name, h.getOutputExtension(job), services.DeliveryEncryptionSuffix(job.Options))
 }
 
 func (h *ConversionHandler) outputPath(job *models.ConversionJob, outputDir string) string {
@@ -667,13 +1055,31 @@ func (h *ConversionHandler) outputPath(job *models.ConversionJob, outputDir stri
 	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "stitch_audio_to_video") {
 		return filepath.Join(outputDir, "stitched"+h.getOutputExtension(job))
 	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "image_sequence_to_video") {
+		return filepath.Join(outputDir, "slideshow"+h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "webpage_screenshot") {
+		return filepath.Join(outputDir, "screenshot"+h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "document_thumbnail") {
+		return filepath.Join(outputDir, "thumbnail"+h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "batch_images") {
+		return filepath.Join(outputDir, "batch"+h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "video_grid") {
+		return filepath.Join(outputDir, "grid"+h.getOutputExtension(job))
+	}
+	if mode, _ := job.Options["mode"].(string); strings.EqualFold(strings.TrimSpace(mode), "audio_join") {
+		return filepath.Join(outputDir, "joined"+h.getOutputExtension(job))
+	}
 	// AI Image Restoration packages a single results tarball that the
 	// image-restore pipeline leaves in the job output dir; the shared
 	// /api/download/:jobId endpoint serves it.
 	if isImageRestoreMode(job) {
 		return filepath.Join(outputDir, "image_restoration_results.tar.gz")
 	}
-	return filepath.Join(outputDir, "converted"+h.getOutputExtension(job))
+	return filepath.Join(outputDir, "converted"+h.getOutputExtension(job)+services.DeliveryEncryptionSuffix(job.Options))
 }
 
 // isImageRestoreMode reports whether a job is an AI Image Restoration job.
@@ -699,15 +1105,6 @@ func parseOptions(optionsStr string) (map[string]interface{}, error) {
 	return options, nil
 }
 
-func safeFilename(name string) string {
-	name = filepath.Base(strings.TrimSpace(name))
-	name = strings.NewReplacer("/", "_", "\\", "_", "\x00", "", "\n", "_", "\r", "_").Replace(name)
-	if name == "" || name == "." {
-		return "upload"
-	}
-	return name
-}
-
 func stringOrErr(err error) string {
 	if err == nil {
 		return ""
@@ -743,6 +1140,10 @@ func (h *ConversionHandler) DetectFaces(c *gin.Context) {
 		return
 	}
 	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
 	defer cancel()
@@ -755,14 +1156,14 @@ func (h *ConversionHandler) DetectFaces(c *gin.Context) {
 
 	sha, err := hashFile(tempPath)
 	if err != nil {
-		log.Printf("face detect hash failed: %v", err)
+		logger.WithRequest(c).Error("face detect hash failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash uploaded image"})
 		return
 	}
 
 	resp, err := h.aiService.DetectFaces(ctx, tempPath)
 	if err != nil {
-		log.Printf("face detect failed: %v", err)
+		logger.WithRequest(c).Error("face detect failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Face detection failed"})
 		return
 	}
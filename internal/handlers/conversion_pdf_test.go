@@ -58,3 +58,17 @@ func TestImageToPDFOutputExtension(t *testing.T) {
 		t.Fatalf("image->pdf filename = %q, want photo_converted.pdf", got)
 	}
 }
+
+func TestImageSizesOutputName(t *testing.T) {
+	h := &ConversionHandler{}
+	job := &models.ConversionJob{
+		OriginalFile: models.OriginalFileInfo{Name: "photo.jpg", Type: "image/jpeg"},
+		Options:      map[string]interface{}{"format": "webp", "sizes": []interface{}{64.0, 256.0}},
+	}
+	if got := h.getOutputExtension(job); got != ".zip" {
+		t.Fatalf("getOutputExtension = %q, want .zip", got)
+	}
+	if got := h.getOutputFilename(job); got != "photo_sizes.zip" {
+		t.Fatalf("getOutputFilename = %q, want photo_sizes.zip", got)
+	}
+}
@@ -30,6 +30,8 @@ type DocumentScanHandler struct {
 	cfg        *config.Config
 	inspector  *services.MediaInspector
 	svc        *services.DocumentScanService
+	// virusScanner, when set, checks each page before it is read.
+	virusScanner *services.ClamdScanner
 }
 
 // NewDocumentScanHandler wires the document-scan service.
@@ -42,6 +44,11 @@ func NewDocumentScanHandler(jobManager *services.JobManager, cfg *config.Config,
 	}
 }
 
+// SetVirusScanner turns on virus scanning of the uploaded pages.
+func (h *DocumentScanHandler) SetVirusScanner(s *services.ClamdScanner) {
+	h.virusScanner = s
+}
+
 // RegisterDocumentScanRoutes mounts the document-scan endpoints.
 func RegisterDocumentScanRoutes(r gin.IRouter, h *DocumentScanHandler) {
 	r.GET("/document-scan/capabilities", h.GetCapabilities)
@@ -131,6 +138,12 @@ func (h *DocumentScanHandler) Start(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No images provided"})
 		return
 	}
+	for _, name := range fieldNames {
+		if err := checkUploadNames(h.cfg, form.File[name]); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	if err := services.ValidateDocumentScanCounts(len(fieldNames), h.cfg); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -198,7 +211,11 @@ func (h *DocumentScanHandler) Start(c *gin.Context) {
 		"secondOpinion": secondOpinion,
 		"summarize":     opts.Summarize,
 	}
-	job := h.jobManager.CreateJob(originalFile, options)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "document_scan")
 
 	srcDir := filepath.Join(h.cfg.UploadDir, job.ID, "src")
@@ -231,6 +248,11 @@ func (h *DocumentScanHandler) Start(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save an uploaded image"})
 			return
 		}
+		if scanErr := scanUpload(c, h.virusScanner, dst); scanErr != nil {
+			scanErr.record(h.jobManager, job)
+			c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+			return
+		}
 		fileType, _ := h.inspector.DetectFile(ctx, dst, fileHeader.Header.Get("Content-Type"))
 		if fileType != models.FileTypeImage {
 			_ = h.jobManager.UpdateJobError(job.ID, "One of the uploaded files is not a supported image")
@@ -261,7 +283,11 @@ func (h *DocumentScanHandler) Start(c *gin.Context) {
 	}
 	_ = h.jobManager.ReplaceStages(job.ID, h.svc.BuildStages(req), "queued")
 
-	go h.svc.Process(context.Background(), req)
+	h.jobManager.Dispatch(func() {
+		ctx, cancel := services.JobContext(h.cfg, job)
+		defer cancel()
+		h.svc.Process(ctx, req)
+	})
 
 	c.JSON(http.StatusAccepted, models.DocumentScanStartResponse{JobID: job.ID})
 }
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+)
+
+const (
+	downloadAccelNginx    = "x-accel-redirect"
+	downloadAccelSendfile = "x-sendfile"
+)
+
+// sendOutputFile serves a finished artifact from OutputDir. With
+// DOWNLOAD_ACCEL_MODE configured it answers with an empty body plus the
+// offload header and lets nginx/Apache stream the bytes (they honor the
+// Content-Disposition / Content-Type we already set, and serve ranges
+// themselves). Anything outside OutputDir, or an unrecognized mode, falls
+// back to serving from Go, and encrypted-at-rest files are always decrypted
+// by Go. Go serves byte ranges too, so browsers can seek in videos.
+func (h *ConversionHandler) sendOutputFile(c *gin.Context, filePath string) {
+	if atrest.IsSealed(filePath) {
+		h.sendSealedFile(c, filePath)
+		return
+	}
+	switch h.cfg.DownloadAccelMode {
+	case downloadAccelNginx:
+		if uri, ok := accelRedirectURI(h.cfg.OutputDir, h.cfg.DownloadAccelLocation, filePath); ok {
+			c.Header("X-Accel-Redirect", uri)
+			c.Status(http.StatusOK)
+			return
+		}
+	case downloadAccelSendfile:
+		if abs, err := filepath.Abs(filePath); err == nil {
+			c.Header("X-Sendfile", abs)
+			c.Status(http.StatusOK)
+			return
+		}
+	}
+	h.sendLocalFile(c, filePath)
+}
+
+// sendLocalFile serves a job file from Go, decrypting it if it is sealed at
+// rest.
+func (h *ConversionHandler) sendLocalFile(c *gin.Context, filePath string) {
+	if atrest.IsSealed(filePath) {
+		h.sendSealedFile(c, filePath)
+		return
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	serveContent(c, filePath, info.ModTime(), info.Size(), f)
+}
+
+// serveContent answers with http.ServeContent, which handles Range and
+// If-Range requests and conditional GETs. The ETag is derived from the
+// file's size and modification time; a re-run output gets a new one.
+func serveContent(c *gin.Context, filePath string, modTime time.Time, size int64, content io.ReadSeeker) {
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), size))
+	http.ServeContent(c.Writer, c.Request, filepath.Base(filePath), modTime, content)
+}
+
+// outputContentType is the MIME type a download is served with, from the
+// output file's extension.
+func outputContentType(fileName string) string {
+	if ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// accelRedirectURI maps a path under outputDir onto the nginx internal
+// location. Each segment is escaped so job/file names with spaces or '?'
+// survive the header, and paths that escape outputDir are refused.
+func accelRedirectURI(outputDir, location, filePath string) (string, bool) {
+	rel, err := filepath.Rel(outputDir, filePath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	location = "/" + strings.Trim(strings.TrimSpace(location), "/")
+	return path.Join(location, strings.Join(segments, "/")), true
+}
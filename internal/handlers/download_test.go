@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func TestAccelRedirectURI(t *testing.T) {
+	cases := []struct {
+		file string
+		want string
+		ok   bool
+	}{
+		{"/data/outputs/job-1/converted.mp4", "/_protected_outputs/job-1/converted.mp4", true},
+		{"/data/outputs/job-1/my file?.mp4", "/_protected_outputs/job-1/my%20file%3F.mp4", true},
+		{"/data/uploads/job-1/original.mp4", "", false},
+		{"/data/outputs", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := accelRedirectURI("/data/outputs", "_protected_outputs/", tc.file)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("accelRedirectURI(%q) = %q, %v; want %q, %v", tc.file, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSendOutputFile_Modes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	outDir := t.TempDir()
+	file := filepath.Join(outDir, "job-1", "converted.mp4")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(mode string) *httptest.ResponseRecorder {
+		h := &ConversionHandler{cfg: &config.Config{OutputDir: outDir, DownloadAccelMode: mode, DownloadAccelLocation: "/_protected_outputs/"}}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/download/job-1", nil)
+		h.sendOutputFile(c, file)
+		return w
+	}
+
+	if w := serve(""); w.Body.String() != "payload" {
+		t.Errorf("default mode should stream the file, got %q", w.Body.String())
+	}
+	w := serve(downloadAccelNginx)
+	if got := w.Header().Get("X-Accel-Redirect"); got != "/_protected_outputs/job-1/converted.mp4" {
+		t.Errorf("X-Accel-Redirect = %q", got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("offloaded response should have an empty body, got %d bytes", w.Body.Len())
+	}
+	if w := serve(downloadAccelSendfile); w.Header().Get("X-Sendfile") != file {
+		t.Errorf("X-Sendfile = %q, want %q", w.Header().Get("X-Sendfile"), file)
+	}
+}
+
+func TestSendOutputFile_DecryptsSealedFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	outDir := t.TempDir()
+	file := filepath.Join(outDir, "job-1", "converted.mp4")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := atrest.NewSealer(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sealer.SealFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accel offload must be bypassed: the web server would send ciphertext.
+	h := &ConversionHandler{cfg: &config.Config{OutputDir: outDir, DownloadAccelMode: downloadAccelNginx}, atRest: sealer}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/download/job-1", nil)
+	h.sendOutputFile(c, file)
+	if w.Body.String() != "payload" || w.Header().Get("X-Accel-Redirect") != "" {
+		t.Errorf("sealed download = %q (accel %q), want decrypted payload", w.Body.String(), w.Header().Get("X-Accel-Redirect"))
+	}
+	if got := w.Header().Get("Content-Length"); got != "7" {
+		t.Errorf("Content-Length = %q, want 7", got)
+	}
+}
+
+func TestSendOutputFile_ServesRanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	outDir := t.TempDir()
+	file := filepath.Join(outDir, "job-1", "converted.mp4")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := atrest.NewSealer(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string) {
+		h := &ConversionHandler{cfg: &config.Config{OutputDir: outDir}, atRest: sealer}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/download/job-1", nil)
+		c.Request.Header.Set("Range", "bytes=2-5")
+		h.sendOutputFile(c, file)
+		if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+			t.Errorf("%s: status %d body %q, want 206 \"2345\"", name, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+			t.Errorf("%s: Content-Range = %q", name, got)
+		}
+		if w.Header().Get("ETag") == "" || w.Header().Get("Last-Modified") == "" {
+			t.Errorf("%s: missing ETag/Last-Modified", name)
+		}
+	}
+	check("plain")
+	if err := sealer.SealFile(file); err != nil {
+		t.Fatal(err)
+	}
+	check("sealed")
+}
+
+func TestOutputContentType(t *testing.T) {
+	for name, want := range map[string]string{"clip.mp4": "video/mp4", "song.MP3": "audio/mpeg", "x.unknownext": "application/octet-stream"} {
+		if got := outputContentType(name); got != want {
+			t.Errorf("outputContentType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/validation"
+)
+
+// checkFormatPolicy applies the operator's format policy to an upload about
+// to become a conversion job: its sniffed input format, the requested
+// output format, and the requested output size (width/height, or each of
+// an image's sizes).
+func (h *ConversionHandler) checkFormatPolicy(mimeType, fileName string, options map[string]interface{}) error {
+	if err := validation.CheckInputFormat(h.cfg, services.InputFormat(mimeType, fileName)); err != nil {
+		return err
+	}
+	format, _ := options["format"].(string)
+	if err := validation.CheckOutputFormat(h.cfg, format); err != nil {
+		return err
+	}
+	width, height := optionPixels(options["width"]), optionPixels(options["height"])
+	if err := validation.CheckResolution(h.cfg, width, height, true); err != nil {
+		return err
+	}
+	sizes, _ := options["sizes"].([]interface{})
+	for _, size := range sizes {
+		if err := validation.CheckResolution(h.cfg, optionPixels(size), 0, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkInputResolution applies MAX_RESOLUTION to a probed upload. Files
+// whose size couldn't be probed pass.
+func (h *ConversionHandler) checkInputResolution(metadata *services.MediaMetadata) error {
+	width, height := metadata.Dimensions()
+	return validation.CheckResolution(h.cfg, width, height, false)
+}
+
+// rejectionStatus is the HTTP status of a validation.Rejection, or
+// fallback for other errors.
+func rejectionStatus(err error, fallback int) int {
+	var rej *validation.Rejection
+	if errors.As(err, &rej) && rej.HTTPStatus != 0 {
+		return rej.HTTPStatus
+	}
+	return fallback
+}
+
+func optionPixels(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
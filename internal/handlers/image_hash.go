@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// ImageHash returns the pHash and dHash of an uploaded image, for dedupe
+// tooling that stores the hashes and compares them itself.
+func (h *ConversionHandler) ImageHash(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form"})
+		return
+	}
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	hashes, status, err := h.hashUploadedImage(ctx, c, "file")
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hashes)
+}
+
+// CompareImages scores how alike two uploaded images (fileA and fileB) are.
+// The optional threshold form field is the largest pHash distance, 0–64,
+// reported as a duplicate.
+func (h *ConversionHandler) CompareImages(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 2*h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(2 * h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form"})
+		return
+	}
+	threshold := services.DefaultDuplicateThreshold
+	if raw := strings.TrimSpace(c.Request.FormValue("threshold")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > 64 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 64"})
+			return
+		}
+		threshold = v
+	}
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	a, status, err := h.hashUploadedImage(ctx, c, "fileA")
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	b, status, err := h.hashUploadedImage(ctx, c, "fileB")
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, services.CompareImageHashes(*a, *b, threshold))
+}
+
+// hashUploadedImage hashes the image in multipart field, returning the HTTP
+// status to answer with on error.
+func (h *ConversionHandler) hashUploadedImage(ctx context.Context, c *gin.Context, field string) (*services.ImageHashes, int, error) {
+	file, fileHeader, err := c.Request.FormFile(field)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("no %s provided", field)
+	}
+	defer file.Close()
+	if err := checkUploadName(h.cfg, fileHeader.Filename); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("image_hash_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+	if err := h.saveUploadedFile(file, tempPath); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to save temporary file")
+	}
+	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		return nil, scanErr.status(), scanErr
+	}
+
+	if fileType, _ := h.inspector.DetectFile(ctx, tempPath, fileHeader.Header.Get("Content-Type")); fileType != models.FileTypeImage {
+		return nil, http.StatusBadRequest, fmt.Errorf("%s is not an image", field)
+	}
+	hashes, err := services.ComputeImageHashes(ctx, tempPath)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to hash %s: %v", field, err)
+	}
+	return hashes, http.StatusOK, nil
+}
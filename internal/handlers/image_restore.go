@@ -32,6 +32,8 @@ type ImageRestoreHandler struct {
 	cfg          *config.Config
 	inspector    *services.MediaInspector
 	imageRestore *services.ImageRestoreService
+	// virusScanner, when set, checks the upload before it is read.
+	virusScanner *services.ClamdScanner
 }
 
 // NewImageRestoreHandler wires the image-restoration service. Unlike video
@@ -45,6 +47,11 @@ func NewImageRestoreHandler(jobManager *services.JobManager, cfg *config.Config,
 	}
 }
 
+// SetVirusScanner turns on virus scanning of the uploaded images.
+func (h *ImageRestoreHandler) SetVirusScanner(s *services.ClamdScanner) {
+	h.virusScanner = s
+}
+
 // RegisterImageRestoreRoutes mounts the image-restoration endpoints.
 func RegisterImageRestoreRoutes(r gin.IRouter, h *ImageRestoreHandler) {
 	r.GET("/image-restore/capabilities", h.GetImageRestoreCapabilities)
@@ -79,6 +86,10 @@ func (h *ImageRestoreHandler) StartImageRestore(c *gin.Context) {
 		return
 	}
 	defer file.Close()
+	if err := checkUploadName(h.cfg, fileHeader.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	var opts models.ImageRestoreOptions
 	if raw := strings.TrimSpace(c.Request.FormValue("options")); raw != "" {
@@ -141,6 +152,11 @@ func (h *ImageRestoreHandler) StartImageRestore(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save the uploaded image"})
 		return
 	}
+	if scanErr := scanUpload(c, h.virusScanner, incomingPath); scanErr != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
 	defer cancel()
@@ -162,7 +178,12 @@ func (h *ImageRestoreHandler) StartImageRestore(c *gin.Context) {
 		"chain":      opts.Chain,
 		"scale":      opts.Scale,
 	}
-	job := h.jobManager.CreateJob(originalFile, options)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "image_restore")
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
@@ -204,7 +225,7 @@ func (h *ImageRestoreHandler) StartImageRestore(c *gin.Context) {
 	}
 	_ = h.jobManager.ReplaceStages(job.ID, h.imageRestore.PlanStages(req), "queued")
 
-	go h.imageRestore.Process(context.Background(), req)
+	h.jobManager.Dispatch(func() { h.imageRestore.Process(context.Background(), req) })
 
 	c.JSON(http.StatusAccepted, models.ImageRestoreStartResponse{JobID: job.ID})
 }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/cleanup"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// DeleteJobFiles removes a finished job's original, output and any other
+// artifacts (reports, previews, trashed copies, remote storage objects)
+// right away instead of waiting for the retention sweep. The job record
+// stays so clients polling it see its final status.
+func (h *ConversionHandler) DeleteJobFiles(c *gin.Context) {
+	job, ok := h.deletableJob(c)
+	if !ok {
+		return
+	}
+	if err := h.removeJobFiles(c.Request.Context(), job); err != nil {
+		logger.WithRequest(c).Error("failed to remove job files", "jobId", job.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete job files"})
+		return
+	}
+	// With its files gone the job can't serve identical uploads anymore.
+	_ = h.jobManager.SetCacheKey(job.ID, "")
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// DeleteJob removes a finished job's files and then the job itself.
+func (h *ConversionHandler) DeleteJob(c *gin.Context) {
+	job, ok := h.deletableJob(c)
+	if !ok {
+		return
+	}
+	if err := h.removeJobFiles(c.Request.Context(), job); err != nil {
+		logger.WithRequest(c).Error("failed to remove job files", "jobId", job.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete job files"})
+		return
+	}
+	_ = h.jobManager.DeleteJob(job.ID)
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// deletableJob looks up the job in the path. Jobs still running are
+// refused: the pipeline would write their files again.
+func (h *ConversionHandler) deletableJob(c *gin.Context) (*models.ConversionJob, bool) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return nil, false
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return nil, false
+	}
+	if !job.Status.Finished() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is still processing"})
+		return nil, false
+	}
+	return job, true
+}
+
+// removeJobFiles deletes everything stored for the job. The ID comes from
+// the job manager, so it is always a plain UUID and safe to join.
+func (h *ConversionHandler) removeJobFiles(ctx context.Context, job *models.ConversionJob) error {
+	dirs := []string{
+		filepath.Join(h.cfg.UploadDir, job.ID),
+		filepath.Join(h.cfg.OutputDir, job.ID),
+		filepath.Join(cleanup.TrashDir(h.cfg), job.ID),
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	if h.storage != nil && h.storage.Remote() {
+		ctx, cancel := context.WithTimeout(ctx, h.cfg.CommandTimeout)
+		defer cancel()
+		return h.storage.DeleteJob(ctx, job.ID)
+	}
+	return nil
+}
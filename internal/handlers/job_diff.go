@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/storage"
+)
+
+// GetJobDiff probes a completed job's original upload and its output and
+// returns what changed between them.
+func (h *ConversionHandler) GetJobDiff(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.Status != models.StatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job not completed"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+	workDir, err := os.MkdirTemp(h.cfg.TempDir, "diff-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare comparison"})
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	// Files moved to remote storage are fetched into workDir.
+	outputPath := h.outputPath(job, filepath.Join(h.cfg.OutputDir, job.ID))
+	if _, err := os.Stat(outputPath); err != nil {
+		if outputPath, err = h.fetchStoredJobFile(ctx, storage.AreaOutputs, job.ID, outputPath, workDir, "output"); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Converted file not found"})
+			return
+		}
+	}
+	originalPath, ok := h.originalPath(job)
+	if !ok {
+		if originalPath, err = h.fetchStoredJobFile(ctx, storage.AreaUploads, job.ID, h.storedOriginalPath(job), workDir, "input"); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Original file is no longer available"})
+			return
+		}
+	}
+
+	inputPath, err := h.plaintextCopy(originalPath, workDir, "input")
+	if err == nil {
+		outputPath, err = h.plaintextCopy(outputPath, workDir, "output")
+	}
+	if err != nil {
+		log.Printf("diff: failed to decrypt files for job %s: %v", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read job files"})
+		return
+	}
+
+	diff, err := services.BuildConversionDiff(ctx, h.inspector, job, inputPath, outputPath)
+	if err != nil {
+		log.Printf("diff: job %s: %v", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare input and output"})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// plaintextCopy returns path itself, or for a file sealed at rest the path
+// of a decrypted copy in workDir. The copy keeps the extension so the
+// probes can still fall back on it.
+func (h *ConversionHandler) plaintextCopy(path, workDir, name string) (string, error) {
+	if !atrest.IsSealed(path) {
+		return path, nil
+	}
+	if h.atRest == nil {
+		return "", os.ErrPermission
+	}
+	dst := filepath.Join(workDir, name+filepath.Ext(path))
+	if err := h.atRest.DecryptFile(path, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
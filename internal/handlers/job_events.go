@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
 )
 
 // StreamJobEvents serves Server-Sent Events for a single job. The client gets
@@ -48,7 +47,7 @@ func (h *ConversionHandler) StreamJobEvents(c *gin.Context) {
 	// Send the current snapshot immediately so the client renders without
 	// waiting for the next pipeline tick.
 	if snap, err := h.jobManager.GetJob(jobID); err == nil {
-		_ = writeSSEEvent(c.Writer, "update", snap)
+		_ = writeSSEEvent(c.Writer, "update", localizedJob(c, snap))
 		c.Writer.Flush()
 	}
 
@@ -72,13 +71,13 @@ func (h *ConversionHandler) StreamJobEvents(c *gin.Context) {
 			if snapshot == nil {
 				continue
 			}
-			if err := writeSSEEvent(c.Writer, "update", snapshot); err != nil {
+			if err := writeSSEEvent(c.Writer, "update", localizedJob(c, snapshot)); err != nil {
 				return
 			}
 			c.Writer.Flush()
 			// Once the job hits a terminal state, push one final snapshot and
 			// close the stream. The client treats stream close as "done".
-			if snapshot.Status == models.StatusCompleted || snapshot.Status == models.StatusFailed {
+			if snapshot.Status.Finished() {
 				return
 			}
 		}
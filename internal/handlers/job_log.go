@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// writeDiagnosticLog stores the commands and stderr of a conversion's
+// attempts next to its output. Like the report, a failure to write it never
+// fails the job.
+func (h *ConversionHandler) writeDiagnosticLog(job *models.ConversionJob, inputPath, outputPath, outputDir string, attempts []services.RecordedAttempt, convErr error) {
+	diagnostics := services.BuildDiagnosticLog(services.DiagnosticLogInput{
+		Job:        job,
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Attempts:   attempts,
+		Err:        convErr,
+		Redact:     h.reportRedactions(job, outputDir),
+	})
+	if err := services.WriteDiagnosticLog(outputDir, diagnostics); err != nil {
+		jobLogger(job).Error("failed to write diagnostic log", "error", err)
+	}
+}
+
+// currentAttempt is the number of the job's latest attempt.
+func (h *ConversionHandler) currentAttempt(jobID string) int {
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil || len(job.Attempts) == 0 {
+		return 0
+	}
+	return job.Attempts[len(job.Attempts)-1].Number
+}
+
+// GetJobLog serves a conversion's diagnostic log: every command it ran, with
+// the stderr each printed. The log names server paths and tools, so only
+// the job's signed-in owner may read it; jobs without an owner have no
+// public log. Everyone else gets 404, as RequireJobOwner answers. Operators
+// read any job's log at /api/admin/jobs/:jobId/log.
+func (h *ConversionHandler) GetJobLog(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.Owner == "" || job.Owner != middleware.User(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	serveDiagnosticLog(c, h.atRest, h.cfg.OutputDir, jobID)
+}
+
+// serveDiagnosticLog answers with the job's diagnostics.json, decrypted when
+// it is sealed at rest.
+func serveDiagnosticLog(c *gin.Context, sealer *atrest.Sealer, outputDir, jobID string) {
+	logPath := filepath.Join(outputDir, jobID, services.DiagnosticLogFile)
+	data, err := readJobFile(sealer, logPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagnostic log not available"})
+		return
+	}
+	if err != nil {
+		logger.WithRequest(c).Error("failed to read diagnostic log", "jobId", jobID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestGetJobLogAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := services.NewJobManager()
+	cfg := &config.Config{OutputDir: t.TempDir(), AdminAPIToken: "admin-secret"}
+	h := &ConversionHandler{jobManager: jm, cfg: cfg}
+	owned := jm.CreateOwnedJob("user-1", models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	anonymous := jm.CreateJob(models.OriginalFileInfo{Name: "b.mp4"}, nil)
+	for _, job := range []*models.ConversionJob{owned, anonymous} {
+		dir := filepath.Join(cfg.OutputDir, job.ID)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, services.DiagnosticLogFile), []byte(`{"attempts":[]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(user, token, jobID string) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if user != "" {
+				c.Set(middleware.UserContextKey, user)
+			}
+		})
+		r.GET("/api/job/:jobId/log", h.GetJobLog)
+		req := httptest.NewRequest(http.MethodGet, "/api/job/"+jobID+"/log", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cases := []struct {
+		name, user, token, jobID string
+		want                     int
+	}{
+		{"owner", "user-1", "", owned.ID, http.StatusOK},
+		{"other user", "user-2", "", owned.ID, http.StatusNotFound},
+		{"anonymous", "", "", owned.ID, http.StatusNotFound},
+		{"unowned job", "", "", anonymous.ID, http.StatusNotFound},
+		{"unowned job, signed in", "user-2", "", anonymous.ID, http.StatusNotFound},
+		// Operators use /api/admin/jobs/:jobId/log.
+		{"admin token", "", "admin-secret", anonymous.ID, http.StatusNotFound},
+		{"admin token, owned job", "", "admin-secret", owned.ID, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if got := get(tc.user, tc.token, tc.jobID); got != tc.want {
+			t.Errorf("%s: %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJobLogRoutesWithOIDC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","alg":"RS256","use":"sig","n":%q,"e":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	keys := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(jwks))
+	}))
+	defer keys.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	verifier, err := middleware.NewOIDCVerifier(ctx, "https://id.example.com", keys.URL, "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userToken := func(sub string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": "https://id.example.com",
+			"sub": sub,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	jm := services.NewJobManager()
+	cfg := &config.Config{OutputDir: t.TempDir(), AdminAPIToken: "admin-secret"}
+	job := jm.CreateOwnedJob("user-1", models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	dir := filepath.Join(cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, services.DiagnosticLogFile), []byte(`{"attempts":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The route groups as setupRouter builds them.
+	h := &ConversionHandler{jobManager: jm, cfg: cfg}
+	r := gin.New()
+	api := r.Group("/api")
+	userGroup := api.Group("")
+	userGroup.Use(middleware.BearerUser(true, verifier, false))
+	userGroup.Use(h.RequireJobOwner())
+	RegisterConversionRoutes(userGroup, h)
+	admin := api.Group("/admin")
+	admin.Use(middleware.RequireAdminToken(cfg.AdminAPIToken))
+	RegisterAdminRoutes(admin, NewAdminHandler(cfg, jm))
+
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	cases := []struct {
+		name, path, token string
+		want              int
+	}{
+		{"owner", "/api/job/" + job.ID + "/log", userToken("user-1"), http.StatusOK},
+		{"other user", "/api/job/" + job.ID + "/log", userToken("user-2"), http.StatusNotFound},
+		{"admin token on the user route", "/api/job/" + job.ID + "/log", "admin-secret", http.StatusUnauthorized},
+		{"admin token on the admin route", "/api/admin/jobs/" + job.ID + "/log", "admin-secret", http.StatusOK},
+		{"user token on the admin route", "/api/admin/jobs/" + job.ID + "/log", userToken("user-1"), http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if got := get(tc.path, tc.token); got != tc.want {
+			t.Errorf("%s: %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/storage"
+)
+
+// DownloadOriginal sends back the file the job was created from, under the
+// name it was uploaded with. Originals stay available until
+// UPLOAD_RETENTION_SECONDS expires them, unless KEEP_ORIGINALS=false.
+func (h *ConversionHandler) DownloadOriginal(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	fileName := job.OriginalFile.Name
+	if fileName == "" {
+		fileName = "original"
+	}
+	originalPath, ok := h.originalPath(job)
+	if !ok {
+		if job.Status == models.StatusCompleted && h.redirectToStoredOriginal(c, job, fileName) {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Original file is no longer available"})
+		return
+	}
+	contentType := job.OriginalFile.Type
+	if contentType == "" {
+		contentType = outputContentType(fileName)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Content-Type", contentType)
+	h.sendLocalFile(c, originalPath)
+}
+
+// originalPath finds the job's original upload in UPLOAD_DIR. Every intake
+// path stores it as "original_<name>"; multi-file tools store several, the
+// first of which is the one reported as the job's original.
+func (h *ConversionHandler) originalPath(job *models.ConversionJob) (string, bool) {
+	originals, _ := filepath.Glob(filepath.Join(h.cfg.UploadDir, job.ID, "original_*"))
+	if len(originals) == 0 {
+		return "", false
+	}
+	sort.Strings(originals)
+	return originals[0], true
+}
+
+// storedOriginalPath is where /api/upload put the original before it was
+// moved to remote storage; its base name is the storage key's.
+func (h *ConversionHandler) storedOriginalPath(job *models.ConversionJob) string {
+	return filepath.Join(h.cfg.UploadDir, job.ID, "original_"+safeFilename(job.OriginalFile.Name))
+}
+
+// redirectToStoredOriginal answers with a presigned URL for an original
+// that has moved to remote storage.
+func (h *ConversionHandler) redirectToStoredOriginal(c *gin.Context, job *models.ConversionJob, fileName string) bool {
+	if h.storage == nil || !h.storage.Remote() || !h.cfg.KeepOriginals {
+		return false
+	}
+	key := storage.Key(storage.AreaUploads, job.ID, h.storedOriginalPath(job))
+	url, _, err := h.storage.PresignGet(c.Request.Context(), key, fileName)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoPresign) {
+			log.Printf("storage: failed to presign original of job %s: %v", job.ID, err)
+		}
+		return false
+	}
+	c.Redirect(http.StatusFound, url)
+	return true
+}
+
+// discardOriginal deletes the job's original upload once the job is done
+// when KEEP_ORIGINALS=false.
+func (h *ConversionHandler) discardOriginal(job *models.ConversionJob, inputPath string) {
+	if h.cfg.KeepOriginals {
+		return
+	}
+	if err := os.Remove(inputPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("failed to delete original of job %s: %v", job.ID, err)
+	}
+}
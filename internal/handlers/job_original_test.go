@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestOriginalPathAndDiscard(t *testing.T) {
+	uploadDir := t.TempDir()
+	job := &models.ConversionJob{ID: "job-1", OriginalFile: models.OriginalFileInfo{Name: "clip.mov"}}
+	h := &ConversionHandler{cfg: &config.Config{UploadDir: uploadDir, KeepOriginals: true}}
+	if _, ok := h.originalPath(job); ok {
+		t.Fatal("found an original before one was uploaded")
+	}
+
+	dir := filepath.Join(uploadDir, job.ID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"original_b.mov", "original_a.mov", "frames.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, ok := h.originalPath(job)
+	if want := filepath.Join(dir, "original_a.mov"); !ok || got != want {
+		t.Fatalf("originalPath = %q, %v; want %q", got, ok, want)
+	}
+
+	h.discardOriginal(job, got)
+	if _, err := os.Stat(got); err != nil {
+		t.Fatal("original deleted although KeepOriginals is set")
+	}
+	h.cfg.KeepOriginals = false
+	h.discardOriginal(job, got)
+	if _, err := os.Stat(got); !os.IsNotExist(err) {
+		t.Fatal("original kept although KeepOriginals is off")
+	}
+}
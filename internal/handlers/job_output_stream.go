@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+const (
+	// outputStreamPollInterval is how often the tail loop checks the encoder's
+	// output file for new bytes.
+	outputStreamPollInterval = 500 * time.Millisecond
+	// outputStreamStartTimeout bounds how long we wait for the encoder to
+	// create the output file before giving up.
+	outputStreamStartTimeout = 2 * time.Minute
+)
+
+// StreamJobOutput tails a job's output file while the encoder is still
+// writing it, as a chunked HTTP response, so a pipeline consumer can start
+// reading a long render before the job completes. The stream ends when the
+// job completes and every byte has been sent; the final status is reported
+// in the X-Job-Status trailer ("completed" or "failed") because the headers
+// are long gone by then.
+//
+// Only append-only outputs can be tailed safely: MP4/MOV must be requested
+// with containerFlags=fragmented, since the faststart pass rewrites the whole
+// file once encoding finishes.
+func (h *ConversionHandler) StreamJobOutput(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.Status == models.StatusFailed || job.Status == models.StatusInfected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job failed"})
+		return
+	}
+	if ok, reason := streamableOutput(job); !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+
+	outputPath := h.outputPath(job, filepath.Join(h.cfg.OutputDir, job.ID))
+	ctx := c.Request.Context()
+	file, err := waitForOutputFile(ctx.Done(), outputPath, func() bool {
+		j, err := h.jobManager.GetJob(jobID)
+		return err != nil || j.Status == models.StatusFailed || j.Status == models.StatusInfected
+	})
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("Trailer", "X-Job-Status")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(outputStreamPollInterval)
+	defer ticker.Stop()
+	for {
+		// Snapshot the status BEFORE draining: if the job was already
+		// completed, the drain below is guaranteed to reach the true end.
+		j, err := h.jobManager.GetJob(jobID)
+		status := models.StatusFailed
+		if err == nil {
+			status = j.Status
+		}
+		if _, err := io.Copy(c.Writer, file); err != nil {
+			return
+		}
+		c.Writer.Flush()
+		if status.Finished() {
+			c.Writer.Header().Set("X-Job-Status", string(status))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// streamableOutput reports whether a job's output is written append-only and
+// can therefore be tailed while the encoder runs.
+func streamableOutput(job *models.ConversionJob) (bool, string) {
+	if services.DeliveryEncryptionSuffix(job.Options) != "" {
+		return false, "encrypted deliveries are only available once the job completes"
+	}
+	format, _ := job.Options["format"].(string)
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch models.GetFileType(job.OriginalFile.Type) {
+	case models.FileTypeVideo:
+		switch format {
+		case "mp4", "mov", "":
+			if flags, _ := job.Options["containerFlags"].(string); flags == "fragmented" {
+				return true, ""
+			}
+			return false, "MP4/MOV output can only be streamed when requested with containerFlags=fragmented"
+		case "webm", "mkv", "flv", "avi":
+			return true, ""
+		}
+		return false, "this output format cannot be streamed while encoding"
+	case models.FileTypeAudio:
+		switch format {
+		case "mp3", "aac", "ogg", "opus", "flac", "wav", "ac3":
+			return true, ""
+		}
+		return false, "this output format cannot be streamed while encoding"
+	}
+	return false, "only audio and video conversions can be streamed while encoding"
+}
+
+// waitForOutputFile polls until the encoder has created path, the request is
+// cancelled, the job has failed, or outputStreamStartTimeout elapses.
+func waitForOutputFile(done <-chan struct{}, path string, failed func() bool) (*os.File, error) {
+	deadline := time.Now().Add(outputStreamStartTimeout)
+	for {
+		if f, err := os.Open(path); err == nil {
+			return f, nil
+		}
+		if failed() {
+			return nil, errors.New("job failed before producing output")
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for the encoder to start writing output")
+		}
+		select {
+		case <-done:
+			return nil, errors.New("request cancelled")
+		case <-time.After(outputStreamPollInterval):
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestStreamableOutput(t *testing.T) {
+	cases := []struct {
+		mime    string
+		options map[string]interface{}
+		want    bool
+	}{
+		{"video/mp4", map[string]interface{}{"format": "mp4"}, false},
+		{"video/mp4", map[string]interface{}{"format": "mp4", "containerFlags": "fragmented"}, true},
+		{"video/quicktime", map[string]interface{}{"format": "webm"}, true},
+		{"video/mp4", map[string]interface{}{"format": "gif"}, false},
+		{"audio/mpeg", map[string]interface{}{"format": "flac"}, true},
+		{"image/png", map[string]interface{}{"format": "jpg"}, false},
+	}
+	for _, tc := range cases {
+		job := &models.ConversionJob{OriginalFile: models.OriginalFileInfo{Type: tc.mime}, Options: tc.options}
+		if got, reason := streamableOutput(job); got != tc.want {
+			t.Errorf("streamableOutput(%s, %v) = %v (%s), want %v", tc.mime, tc.options, got, reason, tc.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+)
+
+// RequireJobOwner guards every route with a :jobId parameter. A job created
+// by an authenticated user (see middleware.BearerUser) is only visible to
+// that user; anyone else gets the same 404 as for an unknown job, so job
+// IDs can't be probed. Jobs without an owner stay reachable by anyone who
+// has the ID, as before.
+func (h *ConversionHandler) RequireJobOwner() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := strings.TrimSpace(c.Param("jobId"))
+		if jobID == "" {
+			c.Next()
+			return
+		}
+		job, err := h.jobManager.GetJob(jobID)
+		if err != nil || job.Owner == "" || job.Owner == middleware.User(c) {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	}
+}
+
+// ListJobs returns the caller's jobs, newest first. Only authenticated users
+// own jobs, so anonymous callers get a 401.
+func (h *ConversionHandler) ListJobs(c *gin.Context) {
+	owner := middleware.User(c)
+	if owner == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": h.jobManager.JobsOwnedBy(owner)})
+}
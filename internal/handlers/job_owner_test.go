@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestRequireJobOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm}
+	owned := jm.CreateOwnedJob("user-1", models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	anonymous := jm.CreateJob(models.OriginalFileInfo{Name: "b.mp4"}, nil)
+
+	serve := func(user, path string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if user != "" {
+				c.Set(middleware.UserContextKey, user)
+			}
+		})
+		r.Use(h.RequireJobOwner())
+		r.GET("/api/jobs", h.ListJobs)
+		r.GET("/api/job/:jobId", func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	cases := []struct {
+		user, jobID string
+		want        int
+	}{
+		{"user-1", owned.ID, http.StatusOK},
+		{"user-2", owned.ID, http.StatusNotFound},
+		{"", owned.ID, http.StatusNotFound},
+		{"", anonymous.ID, http.StatusOK},
+		{"user-2", anonymous.ID, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := serve(tc.user, "/api/job/"+tc.jobID).Code; got != tc.want {
+			t.Errorf("user %q, job %s: %d, want %d", tc.user, tc.jobID, got, tc.want)
+		}
+	}
+
+	if got := serve("", "/api/jobs").Code; got != http.StatusUnauthorized {
+		t.Fatalf("anonymous listing: %d, want 401", got)
+	}
+	w := serve("user-1", "/api/jobs")
+	var body struct {
+		Jobs []models.ConversionJob `json:"jobs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Jobs) != 1 || body.Jobs[0].ID != owned.ID {
+		t.Fatalf("listing = %s (%v)", w.Body.String(), err)
+	}
+}
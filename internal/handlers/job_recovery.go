@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// interruptedError is the error recorded on jobs a restart cut short.
+const interruptedError = "Interrupted by a server restart"
+
+// RecoverInterruptedJobs handles the jobs the previous process left pending
+// or processing (see JobManager.UsePersistentStore). Conversions are rerun
+// from their original upload after their partial output is removed. Jobs
+// that can't be rerun are failed with interruptedError instead of being left
+// processing forever; the cleanup worker expires their files as usual.
+func (h *ConversionHandler) RecoverInterruptedJobs(jobs []*models.ConversionJob) {
+	for _, job := range jobs {
+		outputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+		if len(job.Attempts) > 0 {
+			h.removePartialOutputs(job, outputDir)
+		}
+		inputPath, reason := h.rerunInput(job)
+		if reason == "" && atrest.IsSealed(inputPath) {
+			if err := h.unsealOriginal(inputPath); err != nil {
+				jobLogger(job).Error("failed to decrypt original for recovery", "error", err)
+				reason = "Failed to decrypt original file"
+			}
+		}
+		if reason == "" && os.MkdirAll(outputDir, 0755) != nil {
+			reason = "Failed to create output directory"
+		}
+		if reason != "" {
+			jobLogger(job).Warn("failing interrupted job", "reason", reason)
+			_ = h.jobManager.UpdateJobError(job.ID, interruptedError)
+			continue
+		}
+		if err := h.jobManager.RequeueJob(job.ID, interruptedError); err != nil {
+			continue
+		}
+		jobLogger(job).Info("rerunning interrupted job")
+		h.jobManager.Dispatch(func() { h.runConversion(job, inputPath, outputDir, nil, models.AttemptRecovery) })
+	}
+}
+
+// removePartialOutputs deletes what an interrupted conversion was writing:
+// the output itself and unfinished ".part" files such as a preview proxy.
+func (h *ConversionHandler) removePartialOutputs(job *models.ConversionJob, outputDir string) {
+	partial, _ := filepath.Glob(filepath.Join(outputDir, "*.part"))
+	for _, path := range append(partial, h.outputPath(job, outputDir)) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			jobLogger(job).Warn("failed to remove partial output", "file", filepath.Base(path), "error", err)
+		}
+	}
+}
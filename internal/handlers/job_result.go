@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// resultProbeTimeout bounds the output probe; it's a metadata read, so a
+// slow one means something is wrong with the file rather than it being big.
+const resultProbeTimeout = time.Minute
+
+// recordResultMetadata probes a job's finished output (size, duration,
+// dimensions, codecs, bitrate) and attaches it to the job as
+// result.metadata. It runs before the job is marked completed, so the
+// completed snapshot clients receive already carries it. A failed probe is
+// logged and leaves the job without metadata.
+func (h *ConversionHandler) recordResultMetadata(job *models.ConversionJob, outputPath string) {
+	if h.inspector == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resultProbeTimeout)
+	defer cancel()
+	metadata, err := services.ProbeResultMetadata(ctx, h.inspector, outputPath)
+	if err != nil {
+		log.Printf("result probe failed for job %s: %v", job.ID, err)
+		return
+	}
+	_ = h.jobManager.SetOutputMetadata(job.ID, metadata)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// withRetries runs one step of a job, running it again after a transient
+// failure up to JOB_RETRY_MAX times with exponential backoff. Each run is
+// recorded as an attempt on the job.
+func (h *ConversionHandler) withRetries(job *models.ConversionJob, outputPath string, run func() error) error {
+	err := run()
+	for retry := 1; err != nil && retry <= h.cfg.JobRetryMax && services.IsTransientError(err); retry++ {
+		delay := services.RetryBackoff(h.cfg.JobRetryBackoff, h.cfg.JobRetryMaxBackoff, retry)
+		jobLogger(job).Warn("transient failure, retrying", "delay", delay, "error", err)
+		_ = os.Remove(outputPath)
+		_ = h.jobManager.DeferAttempt(job.ID, err.Error(), time.Now().Add(delay))
+		time.Sleep(delay)
+		_ = h.jobManager.StartAttempt(job.ID, models.AttemptAutomatic)
+		err = run()
+	}
+	return err
+}
+
+// RetryJob runs a failed job again from its original upload, with the
+// options it was created with.
+func (h *ConversionHandler) RetryJob(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.Status != models.StatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed jobs can be retried"})
+		return
+	}
+	inputPath, reason := h.rerunInput(job)
+	if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+	if err := h.jobManager.RetryJob(job.ID); err != nil {
+		if errors.Is(err, services.ErrJobNotFailed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only failed jobs can be retried"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	// The original was sealed when the failed run finished; the new run
+	// seals it again.
+	if atrest.IsSealed(inputPath) {
+		if err := h.unsealOriginal(inputPath); err != nil {
+			logger.WithRequest(c).Error("failed to decrypt original for retry", "jobId", job.ID, "error", err)
+			_ = h.jobManager.UpdateJobError(job.ID, "Failed to decrypt original file")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decrypt original file"})
+			return
+		}
+	}
+	outputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "Failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare output"})
+		return
+	}
+	h.jobManager.Dispatch(func() { h.runConversion(job, inputPath, outputDir, nil, models.AttemptManual) })
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+// rerunInput is the original upload job can be converted again from, or
+// the reason it can't be.
+func (h *ConversionHandler) rerunInput(job *models.ConversionJob) (string, string) {
+	// Attempts are recorded by runConversion. A job without any failed
+	// before its conversion started (e.g. a URL import that never
+	// downloaded) or isn't a conversion, so there is nothing to rerun.
+	if len(job.Attempts) == 0 {
+		return "", "This job can't be retried"
+	}
+	if _, ok := job.Options["deliveryEncryption"]; ok {
+		return "", "Jobs with deliveryEncryption can't be retried because the key isn't kept"
+	}
+	inputPath, ok := h.originalPath(job)
+	if !ok {
+		return "", "Original file is no longer available"
+	}
+	return inputPath, ""
+}
+
+func (h *ConversionHandler) unsealOriginal(path string) error {
+	if h.atRest == nil {
+		return errors.New("no storage encryption key is configured")
+	}
+	tmp := path + ".retry"
+	if err := h.atRest.DecryptFile(path, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
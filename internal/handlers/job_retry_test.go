@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestWithRetries(t *testing.T) {
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm, cfg: &config.Config{JobRetryMax: 2, JobRetryBackoff: time.Millisecond, JobRetryMaxBackoff: time.Millisecond}}
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+	_ = jm.StartAttempt(job.ID, models.AttemptInitial)
+
+	runs := 0
+	err := h.withRetries(job, "", func() error {
+		runs++
+		if runs == 1 {
+			return fmt.Errorf("failed to write output: %w", &os.PathError{Op: "write", Path: "out.mp4", Err: syscall.ENOSPC})
+		}
+		return nil
+	})
+	if err != nil || runs != 2 {
+		t.Fatalf("err = %v after %d runs", err, runs)
+	}
+	got, _ := jm.GetJob(job.ID)
+	if len(got.Attempts) != 2 || got.Attempts[0].RetryAt == nil || got.Attempts[1].Trigger != models.AttemptAutomatic {
+		t.Fatalf("attempts = %+v", got.Attempts)
+	}
+
+	runs = 0
+	err = h.withRetries(job, "", func() error {
+		runs++
+		return errors.New("invalid conversion options: unsupported codec")
+	})
+	if err == nil || runs != 1 {
+		t.Fatalf("non-transient error ran %d times", runs)
+	}
+
+	runs = 0
+	_ = h.withRetries(job, "", func() error {
+		runs++
+		return errors.New("ffmpeg failed: No space left on device")
+	})
+	if runs != 3 {
+		t.Fatalf("transient error ran %d times, want 1 + JobRetryMax", runs)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/storage"
+)
+
+// SetStorage replaces the default local job storage, e.g. with an S3
+// bucket. A nil backend keeps the local one.
+func (h *ConversionHandler) SetStorage(b storage.Backend) {
+	if b != nil {
+		h.storage = b
+	}
+}
+
+// persistJobFiles moves a completed job's output and original upload to a
+// remote storage backend, points the job's result URL at a presigned
+// download and deletes the local copies. Failed jobs stay local for the
+// cleanup worker, and so does anything whose upload fails: the job is still
+// served from disk.
+func (h *ConversionHandler) persistJobFiles(job *models.ConversionJob, inputPath, outputDir string) {
+	if h.storage == nil || !h.storage.Remote() {
+		return
+	}
+	current, err := h.jobManager.GetJob(job.ID)
+	if err != nil || current.Status != models.StatusCompleted {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	outputPath := h.outputPath(job, outputDir)
+	outputKey := storage.Key(storage.AreaOutputs, job.ID, outputPath)
+	if err := h.storage.Save(ctx, outputKey, outputPath, mime.TypeByExtension(filepath.Ext(outputPath))); err != nil {
+		log.Printf("storage: failed to store output of job %s: %v", job.ID, err)
+		return
+	}
+	fileName := h.getOutputFilename(job)
+	url, expiresAt, err := h.storage.PresignGet(ctx, outputKey, fileName)
+	if err != nil {
+		log.Printf("storage: failed to presign output of job %s: %v", job.ID, err)
+		return
+	}
+	_ = h.jobManager.SetResultMetadata(job.ID, outputKey, fileName, expiresAt)
+	_ = h.jobManager.UpdateJobResult(job.ID, url)
+	_ = os.Remove(outputPath)
+
+	if _, err := os.Stat(inputPath); err != nil {
+		return // discarded (KEEP_ORIGINALS=false)
+	}
+	inputKey := storage.Key(storage.AreaUploads, job.ID, inputPath)
+	if err := h.storage.Save(ctx, inputKey, inputPath, current.OriginalFile.Type); err != nil {
+		log.Printf("storage: failed to store original of job %s: %v", job.ID, err)
+		return
+	}
+	_ = os.Remove(inputPath)
+}
+
+// redirectToStoredOutput answers a download of an output that has moved to
+// remote storage with a fresh presigned URL; the one on the job may have
+// expired. The URL downloads the file as attachmentName, or serves it
+// inline when that is empty. It reports false when the output isn't stored
+// remotely.
+func (h *ConversionHandler) redirectToStoredOutput(c *gin.Context, job *models.ConversionJob, outputPath, attachmentName string) bool {
+	key := storage.Key(storage.AreaOutputs, job.ID, outputPath)
+	if h.storage == nil || !h.storage.Remote() || job.ResultS3Key != key {
+		return false
+	}
+	url, _, err := h.storage.PresignGet(c.Request.Context(), key, attachmentName)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoPresign) {
+			log.Printf("storage: failed to presign download of job %s: %v", job.ID, err)
+		}
+		return false
+	}
+	c.Redirect(http.StatusFound, url)
+	return true
+}
+
+// fetchStoredJobFile copies a job file that has moved to remote storage
+// into workDir, returning the local path.
+func (h *ConversionHandler) fetchStoredJobFile(ctx context.Context, area, jobID, localPath, workDir, name string) (string, error) {
+	if h.storage == nil || !h.storage.Remote() {
+		return "", os.ErrNotExist
+	}
+	dst := filepath.Join(workDir, name+filepath.Ext(localPath))
+	if err := h.storage.Fetch(ctx, storage.Key(area, jobID, localPath), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// AnalyzeLoudness measures integrated loudness, loudness range, true peak
+// and per-second momentary loudness of an uploaded audio or video file, and
+// checks them against the loudnessNormalize presets. Like /api/waveform it
+// answers synchronously and keeps nothing.
+func (h *ConversionHandler) AnalyzeLoudness(c *gin.Context) {
+	file, fileHeader, err := h.multipartFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("loudness_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+	if err := h.saveUploadedFile(file, tempPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+		return
+	}
+	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	if !h.inspector.HasAudioStream(ctx, tempPath) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file has no audio stream"})
+		return
+	}
+	analysis, err := services.AnalyzeLoudness(ctx, tempPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to analyze loudness: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
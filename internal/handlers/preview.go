@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/atrest"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// previewProxyName is the low-bitrate copy of a video output, kept next to
+// it in the job's output directory so cleanup removes both.
+const previewProxyName = "preview_proxy.mp4"
+
+// PreviewFile serves a finished output inline so the UI can show it before
+// the user downloads it. With ?proxy=true, video outputs are served as a
+// 480p low-bitrate MP4 instead, built on first request and then reused.
+func (h *ConversionHandler) PreviewFile(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	job, err := h.jobManager.GetJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.Status != models.StatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job not completed"})
+		return
+	}
+
+	outputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	outputPath := h.outputPath(job, outputDir)
+	if _, err := os.Stat(outputPath); err != nil {
+		if h.redirectToStoredOutput(c, job, outputPath, "") {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Converted file not found"})
+		return
+	}
+	fileName := h.getOutputFilename(job)
+	contentType := outputContentType(fileName)
+
+	if wantProxy(c.Query("proxy")) && strings.HasPrefix(contentType, "video/") && !atrest.IsSealed(outputPath) {
+		proxyPath, err := h.previewProxy(c.Request.Context(), outputPath, filepath.Join(outputDir, previewProxyName))
+		if err != nil {
+			log.Printf("preview: job %s: %v", job.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build preview"})
+			return
+		}
+		outputPath = proxyPath
+		fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "_preview.mp4"
+		contentType = "video/mp4"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", fileName))
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	h.sendOutputFile(c, outputPath)
+}
+
+func wantProxy(raw string) bool {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// previewProxy returns the proxy at proxyPath, building it from outputPath
+// if needed. Builds are serialized: concurrent requests for the same video
+// wait for the first one instead of all running ffmpeg.
+func (h *ConversionHandler) previewProxy(ctx context.Context, outputPath, proxyPath string) (string, error) {
+	h.previewMu.Lock()
+	defer h.previewMu.Unlock()
+	if _, err := os.Stat(proxyPath); err == nil {
+		return proxyPath, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.CommandTimeout)
+	defer cancel()
+	if err := services.BuildPreviewProxy(ctx, outputPath, proxyPath); err != nil {
+		return "", err
+	}
+	return proxyPath, nil
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// QRCodeGenerate renders a QR code from the JSON body and returns the PNG or
+// SVG directly. Nothing is kept after the response.
+func (h *ConversionHandler) QRCodeGenerate(c *gin.Context) {
+	var req services.QRCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := services.ValidateQRCodeRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	outputPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("qr_%d.%s", time.Now().UnixNano(), req.Format))
+	defer func() { _ = os.Remove(outputPath) }()
+	if err := services.GenerateQRCode(ctx, &req, outputPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate QR code: %v", err)})
+		return
+	}
+	contentType := "image/png"
+	if req.Format == "svg" {
+		contentType = "image/svg+xml"
+	}
+	c.Header("Content-Type", contentType)
+	c.File(outputPath)
+}
+
+// QRCodeDecode reads every QR code and barcode in an uploaded image.
+func (h *ConversionHandler) QRCodeDecode(c *gin.Context) {
+	file, fileHeader, err := h.multipartFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("qr_decode_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+	if err := h.saveUploadedFile(file, tempPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+		return
+	}
+	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	if fileType, _ := h.inspector.DetectFile(ctx, tempPath, fileHeader.GetHeader("Content-Type")); fileType != models.FileTypeImage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is not an image"})
+		return
+	}
+	result, err := services.DecodeBarcodes(ctx, tempPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to decode barcodes: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
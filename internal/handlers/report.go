@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// writeConversionReport builds and stores the per-job report next to the
+// converted file. A report failure is logged but never fails the job: the
+// media itself is still good.
+func (h *ConversionHandler) writeConversionReport(job *models.ConversionJob, inputPath, outputPath, outputDir, format string, commands []services.RecordedCommand) {
+	// Re-read the job so results recorded during conversion (quality
+	// metrics) are included.
+	if current, err := h.jobManager.GetJob(job.ID); err == nil {
+		job = current
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	defer cancel()
+	report := services.BuildConversionReport(ctx, h.inspector, services.ConversionReportInput{
+		Job:        job,
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Commands:   commands,
+		Redact:     h.reportRedactions(job, outputDir),
+	})
+	if err := services.WriteConversionReport(outputDir, report, format); err != nil {
+		log.Printf("failed to write conversion report for job %s: %v", job.ID, err)
+	}
+}
+
+// reportRedactions maps the job's server directories to the placeholders
+// reports and diagnostic logs show instead.
+func (h *ConversionHandler) reportRedactions(job *models.ConversionJob, outputDir string) map[string]string {
+	return map[string]string{
+		filepath.Join(h.cfg.UploadDir, job.ID): "<upload-dir>",
+		outputDir:                              "<output-dir>",
+		h.cfg.TempDir:                          "<temp-dir>",
+	}
+}
+
+// GetConversionReport serves the report for a job uploaded with "report" set.
+// ?format=html returns the HTML rendering when one was requested; JSON is the
+// default.
+func (h *ConversionHandler) GetConversionReport(c *gin.Context) {
+	jobID := strings.TrimSpace(c.Param("jobId"))
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job ID is required"})
+		return
+	}
+	if _, err := h.jobManager.GetJob(jobID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	name := services.ConversionReportJSONFile
+	if strings.EqualFold(strings.TrimSpace(c.Query("format")), "html") {
+		name = services.ConversionReportHTMLFile
+	}
+	reportPath := filepath.Join(h.cfg.OutputDir, jobID, name)
+	if _, err := os.Stat(reportPath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversion report not available"})
+		return
+	}
+	h.sendLocalFile(c, reportPath)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// cachedJob looks for an earlier job that converted the same file with the
+// same options, when RESULT_CACHE_ENABLED is set. It returns that job if its
+// result is still available (or on its way), and otherwise the cache key to
+// record on the new job. Only jobs of the same owner are reused.
+// Delivery-encrypted jobs are never cached: their output is only readable
+// with the client's key.
+func (h *ConversionHandler) cachedJob(owner, inputPath string, options map[string]interface{}, delivery *services.DeliveryEncryption) (*models.ConversionJob, string) {
+	if !h.cfg.ResultCacheEnabled || delivery != nil {
+		return nil, ""
+	}
+	sum, err := hashFile(inputPath)
+	if err != nil {
+		log.Printf("result cache: failed to hash %s: %v", filepath.Base(inputPath), err)
+		return nil, ""
+	}
+	key, err := services.ResultCacheKey(sum, options)
+	if err != nil {
+		return nil, ""
+	}
+	job, ok := h.jobManager.FindByCacheKey(key, owner)
+	if !ok {
+		return nil, key
+	}
+	if !h.resultAvailable(job) {
+		_ = h.jobManager.SetCacheKey(job.ID, "")
+		return nil, key
+	}
+	log.Printf("result cache: reusing job %s", job.ID)
+	return job, ""
+}
+
+// resultAvailable reports whether the job's output can still be downloaded,
+// or will be once it finishes.
+func (h *ConversionHandler) resultAvailable(job *models.ConversionJob) bool {
+	switch job.Status {
+	case models.StatusPending, models.StatusProcessing:
+		return true
+	case models.StatusCompleted:
+	default:
+		return false
+	}
+	if job.ResultS3Key != "" {
+		// The cleanup worker doesn't touch remote storage; remote outputs
+		// expire after OUTPUT_RETENTION_SECONDS like local ones.
+		return h.storage != nil && h.storage.Remote() && job.CompletedAt != nil &&
+			time.Since(*job.CompletedAt) < h.cfg.OutputRetention
+	}
+	_, err := os.Stat(h.outputPath(job, filepath.Join(h.cfg.OutputDir, job.ID)))
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// AnalyzeSilence reports the silent intervals of an uploaded audio or video
+// file without cutting anything, so they can be reviewed before removal.
+// Optional form fields thresholdDb and minDuration tune the detection.
+func (h *ConversionHandler) AnalyzeSilence(c *gin.Context) {
+	file, fileHeader, err := h.multipartFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	thresholdDB, minDuration := services.DefaultSilenceThresholdDB, services.DefaultSilenceMinDuration
+	for _, f := range []struct {
+		field    string
+		dst      *float64
+		min, max float64
+	}{
+		{"thresholdDb", &thresholdDB, -90, 0},
+		{"minDuration", &minDuration, 0.05, 60},
+	} {
+		raw := strings.TrimSpace(c.Request.FormValue(f.field))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < f.min || v > f.max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be between %g and %g", f.field, f.min, f.max)})
+			return
+		}
+		*f.dst = v
+	}
+
+	release, ok := h.acquireProbeSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("silence_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+	if err := h.saveUploadedFile(file, tempPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+		return
+	}
+	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	if !h.inspector.HasAudioStream(ctx, tempPath) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file has no audio stream"})
+		return
+	}
+	analysis, err := services.AnalyzeSilence(ctx, tempPath, thresholdDB, minDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to analyze silence: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
@@ -41,6 +41,8 @@ type StudioHandler struct {
 	export        *services.StudioExportService
 	ai            *services.AIService
 	transcription *services.TranscriptionService
+	// virusScanner, when set, checks each asset before it is ingested.
+	virusScanner *services.ClamdScanner
 	// peaksMu dedupes on-demand waveform backfill per asset id so concurrent
 	// /peaks requests don't decode the same source twice.
 	peaksMu sync.Map
@@ -102,6 +104,11 @@ func RegisterStudioRoutes(r gin.IRouter, h *StudioHandler) {
 	studio.POST("/projects/:id/captions/generate", h.GenerateCaptions)
 }
 
+// SetVirusScanner turns on virus scanning of uploaded assets.
+func (h *StudioHandler) SetVirusScanner(s *services.ClamdScanner) {
+	h.virusScanner = s
+}
+
 func (h *StudioHandler) dbReady(c *gin.Context) bool {
 	if h.repo == nil || !h.repo.Enabled() {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Content Studio requires a database, which is currently unavailable"})
@@ -229,6 +236,10 @@ func (h *StudioHandler) PresignAsset(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if err := checkUploadName(h.cfg, req.FileName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	fileName := safeFilename(req.FileName)
 	ext := sanitizeExtension(filepath.Ext(fileName))
 	if ext == "" || !isSupportedStudioExtension(ext) {
@@ -353,16 +364,21 @@ func (h *StudioHandler) CompleteAsset(c *gin.Context) {
 			HasAudio:         false,
 			ProbeJSON:        map[string]any{},
 		}
+		job, status, err := createClientJob(c, h.jobManager, h.cfg,
+			models.OriginalFileInfo{Name: fileName, Size: objectSize, Type: "application/octet-stream"},
+			map[string]interface{}{"mode": "studio_lut"},
+		)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 		created, err := h.repo.CreateAsset(ctx, asset)
 		if err != nil {
 			log.Printf("studio: create LUT asset failed: %v", err)
+			h.jobManager.UpdateJobError(job.ID, "Failed to record asset")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record asset"})
 			return
 		}
-		job := h.jobManager.CreateJob(
-			models.OriginalFileInfo{Name: fileName, Size: objectSize, Type: "application/octet-stream"},
-			map[string]interface{}{"mode": "studio_lut"},
-		)
 		_ = h.jobManager.UpdateJobResult(job.ID, "/api/studio/assets/"+created.ID+"/file")
 		_ = h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted)
 		c.JSON(http.StatusOK, models.StudioAssetCompleteResponse{Asset: created, JobID: job.ID})
@@ -373,7 +389,11 @@ func (h *StudioHandler) CompleteAsset(c *gin.Context) {
 	// job can read it. (Cleanup worker reaps it later; export re-downloads from
 	// S3, so the local copy is disposable.)
 	originalFile := models.OriginalFileInfo{Name: fileName, Size: objectSize, Type: contentType}
-	job := h.jobManager.CreateJob(originalFile, map[string]interface{}{"mode": "studio_ingest"})
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, map[string]interface{}{"mode": "studio_ingest"})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "studio_ingest")
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
@@ -449,17 +469,21 @@ func (h *StudioHandler) CompleteAsset(c *gin.Context) {
 		return
 	}
 
-	go h.runIngestJob(job.ID, created, key, kind, uploadPath, probe.DurationSeconds)
+	h.jobManager.Dispatch(func() { h.runIngestJob(job, created, key, kind, uploadPath, probe.DurationSeconds) })
 
 	c.JSON(http.StatusOK, models.StudioAssetCompleteResponse{Asset: created, JobID: job.ID})
 }
 
-func (h *StudioHandler) runIngestJob(jobID string, asset *models.StudioAsset, originalKey string, kind models.StudioMediaKind, inputPath string, totalSeconds float64) {
+func (h *StudioHandler) runIngestJob(job *models.ConversionJob, asset *models.StudioAsset, originalKey string, kind models.StudioMediaKind, inputPath string, totalSeconds float64) {
+	jobID := job.ID
+	if !scanJobInputs(h.virusScanner, h.jobManager, job, inputPath) {
+		return
+	}
 	if err := h.jobManager.UpdateJobStatus(jobID, models.StatusProcessing); err != nil {
 		log.Printf("studio ingest: failed to mark job %s processing: %v", jobID, err)
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 
 	res, err := h.ingest.Generate(ctx, jobID, originalKey, asset.ID, kind, asset.HasAudio, inputPath, totalSeconds)
@@ -537,29 +561,34 @@ func (h *StudioHandler) DeriveAsset(c *gin.Context) {
 		HasAudio:         true,
 		ProbeJSON:        map[string]any{},
 	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg,
+		models.OriginalFileInfo{Name: asset.OriginalFileName, Size: 0, Type: "audio/wav"},
+		map[string]interface{}{"mode": "studio_derive"},
+	)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	created, err := h.repo.CreateAsset(ctx, asset)
 	if err != nil {
 		log.Printf("studio derive: create asset failed: %v", err)
+		h.jobManager.UpdateJobError(job.ID, "Failed to record asset")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record asset"})
 		return
 	}
-
-	job := h.jobManager.CreateJob(
-		models.OriginalFileInfo{Name: asset.OriginalFileName, Size: 0, Type: "audio/wav"},
-		map[string]interface{}{"mode": "studio_derive"},
-	)
 	_ = h.jobManager.SetMode(job.ID, "studio_derive")
 
-	go h.runDeriveJob(job.ID, created, src.S3KeyOriginal, derivedKey, op)
+	h.jobManager.Dispatch(func() { h.runDeriveJob(job, created, src.S3KeyOriginal, derivedKey, op) })
 	c.JSON(http.StatusOK, models.StudioAssetCompleteResponse{Asset: created, JobID: job.ID})
 }
 
-func (h *StudioHandler) runDeriveJob(jobID string, asset *models.StudioAsset, srcKey, derivedKey, op string) {
+func (h *StudioHandler) runDeriveJob(job *models.ConversionJob, asset *models.StudioAsset, srcKey, derivedKey, op string) {
+	jobID := job.ID
 	if err := h.jobManager.UpdateJobStatus(jobID, models.StatusProcessing); err != nil {
 		log.Printf("studio derive: failed to mark job %s processing: %v", jobID, err)
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 
 	workDir := filepath.Join(h.cfg.UploadDir, "studio_derive_"+jobID)
@@ -858,10 +887,14 @@ func (h *StudioHandler) ExportProject(c *gin.Context) {
 	quality := normalizeExportQuality(req.Preset)
 	baseName := safeFilename(firstNonEmpty(req.FileName, project.Name, "export")) + ".mp4"
 
-	job := h.jobManager.CreateJob(
+	job, status, err := createClientJob(c, h.jobManager, h.cfg,
 		models.OriginalFileInfo{Name: baseName, Size: 0, Type: "video/mp4"},
 		map[string]interface{}{"mode": "studio_export", "format": "mp4"},
 	)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
 	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
 		h.jobManager.UpdateJobError(job.ID, "Failed to create output directory")
@@ -872,17 +905,18 @@ func (h *StudioHandler) ExportProject(c *gin.Context) {
 	// default video job, so /api/download/:jobId serves it unchanged.
 	outputPath := filepath.Join(jobOutputDir, "converted.mp4")
 
-	go h.runExportJob(job.ID, refs, exportCfg, project.Width, project.Height, project.FPS, duration, quality, outputPath)
+	h.jobManager.Dispatch(func() { h.runExportJob(job, refs, exportCfg, project.Width, project.Height, project.FPS, duration, quality, outputPath) })
 
 	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
 }
 
-func (h *StudioHandler) runExportJob(jobID string, refs []studioClipRef, exportCfg studioExportConfig, width, height int, fps, duration float64, quality, outputPath string) {
+func (h *StudioHandler) runExportJob(job *models.ConversionJob, refs []studioClipRef, exportCfg studioExportConfig, width, height int, fps, duration float64, quality, outputPath string) {
+	jobID := job.ID
 	if err := h.jobManager.UpdateJobStatus(jobID, models.StatusProcessing); err != nil {
 		log.Printf("studio export: failed to mark job %s processing: %v", jobID, err)
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 
 	// Download each distinct source once; clips that share an asset reuse the
@@ -1048,12 +1082,16 @@ func (h *StudioHandler) GenerateCaptions(c *gin.Context) {
 		return
 	}
 
-	job := h.jobManager.CreateJob(
+	job, status, err := createClientJob(c, h.jobManager, h.cfg,
 		models.OriginalFileInfo{Name: "captions.vtt", Size: 0, Type: "audio/wav"},
 		map[string]interface{}{"mode": "studio_captions"},
 	)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "studio_captions")
-	go h.runCaptionsJob(job, projectID, refs, duration, strings.TrimSpace(req.Language))
+	h.jobManager.Dispatch(func() { h.runCaptionsJob(job, projectID, refs, duration, strings.TrimSpace(req.Language)) })
 	c.JSON(http.StatusOK, gin.H{"jobId": job.ID})
 }
 
@@ -1063,7 +1101,7 @@ func (h *StudioHandler) runCaptionsJob(job *models.ConversionJob, projectID stri
 		log.Printf("studio captions: failed to mark job %s processing: %v", jobID, err)
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 
 	workDir := filepath.Join(h.cfg.UploadDir, "studio_captions_"+jobID)
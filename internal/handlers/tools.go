@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,6 +16,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
 )
@@ -25,6 +29,16 @@ func RegisterToolRoutes(r gin.IRouter, h *ConversionHandler) {
 	tools := r.Group("/tools")
 	tools.POST("/caption-translator", h.CaptionTranslatorUpload)
 	tools.POST("/stitch-audio-to-video", h.StitchAudioToVideoUpload)
+	tools.POST("/image-sequence-to-video", h.ImageSequenceToVideoUpload)
+	tools.POST("/live-record", h.LiveRecordStart)
+	tools.POST("/url-import", h.MediaURLImportStart)
+	tools.POST("/webpage-screenshot", h.WebpageScreenshotStart)
+	tools.POST("/document-thumbnail", h.DocumentThumbnailUpload)
+	tools.POST("/batch-images", h.BatchImagesUpload)
+	tools.POST("/video-grid", h.VideoGridUpload)
+	tools.POST("/audio-join", h.AudioJoinUpload)
+	tools.POST("/qr-code", h.QRCodeGenerate)
+	tools.POST("/qr-decode", h.QRCodeDecode)
 }
 
 // ----------------------------------------------------------------------- //
@@ -49,6 +63,10 @@ func (h *ConversionHandler) CaptionTranslatorUpload(c *gin.Context) {
 		return
 	}
 	defer file.Close()
+	if err := checkUploadName(h.cfg, fileHeader.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	cleanName := safeFilename(fileHeader.Filename)
 	inputFormat := strings.ToLower(strings.TrimSpace(c.Request.FormValue("inputFormat")))
@@ -98,7 +116,12 @@ func (h *ConversionHandler) CaptionTranslatorUpload(c *gin.Context) {
 		"sourceLanguage": sourceLanguage,
 		"targetLanguage": targetLanguage,
 	}
-	job := h.jobManager.CreateJob(originalFile, jobOptions)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
 	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
@@ -122,21 +145,21 @@ func (h *ConversionHandler) CaptionTranslatorUpload(c *gin.Context) {
 	}
 
 	outputPath := h.outputPath(job, jobOutputDir)
-	go h.runCaptionTranslator(job, uploadPath, outputPath, prepareReq)
+	h.startToolJob(job, func() { h.runCaptionTranslator(job, uploadPath, outputPath, prepareReq) })
 
 	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
 }
 
 func (h *ConversionHandler) runCaptionTranslator(job *models.ConversionJob, inputPath, outputPath string, req services.PrepareCaptionJobRequest) {
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
-		log.Printf("caption translator: failed to mark job %s processing: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job processing", "tool", "caption-translator", "error", err)
 		return
 	}
 	if h.captionTranslator == nil {
 		_ = h.jobManager.UpdateJobError(job.ID, "caption translator service is not available")
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 	input := services.CaptionTranslatorInput{
 		JobID:          job.ID,
@@ -148,17 +171,18 @@ func (h *ConversionHandler) runCaptionTranslator(job *models.ConversionJob, inpu
 		TargetLanguage: req.TargetLanguage,
 	}
 	if err := h.captionTranslator.Translate(ctx, input); err != nil {
-		log.Printf("caption translator: job %s failed: %v", job.ID, err)
+		jobLogger(job).Warn("tool job failed", "tool", "caption-translator", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
 		return
 	}
+	h.recordResultMetadata(job, outputPath)
 	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
-		log.Printf("caption translator: failed to update job %s result: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job result", "tool", "caption-translator", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
 		return
 	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
-		log.Printf("caption translator: failed to mark job %s completed: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job completed", "tool", "caption-translator", "error", err)
 	}
 }
 
@@ -175,9 +199,12 @@ const MaxStitchAudioTracks = 3
 // to MaxStitchAudioTracks audio files. The audio files are submitted as
 // fields named "audio_0", "audio_1", "audio_2" (the count is read from the
 // "trackCount" field). Per-track volume + offset come in as parallel form
-// fields. We validate aggressively here because any FFmpeg argument that
-// comes from the client gets sanity-checked before being passed to the
-// command line.
+// fields, and "originalVolume" sets the level of the video's own audio in
+// mix mode. duck_N=true marks a music bed that ducks under the speech
+// (duckAmountDb / duckAttackMs / duckReleaseMs tune it). mode=mute strips
+// the audio and takes no tracks. We validate aggressively here because any
+// FFmpeg argument that comes from the client gets sanity-checked before
+// being passed to the command line.
 func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
 	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
@@ -191,19 +218,57 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 		return
 	}
 	defer videoFile.Close()
+	if err := checkUploadName(h.cfg, videoHeader.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	mode := strings.ToLower(strings.TrimSpace(c.Request.FormValue("mode")))
 	if mode == "" {
 		mode = "mix"
 	}
-	if mode != "mix" && mode != "replace" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'mix' or 'replace'"})
+	if mode != "mix" && mode != "replace" && mode != "mute" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'mix', 'replace' or 'mute'"})
 		return
 	}
 	trimToVideo := strings.EqualFold(strings.TrimSpace(c.Request.FormValue("trimToVideoDuration")), "true")
+	originalVolume := 1.0
+	if raw := strings.TrimSpace(c.Request.FormValue("originalVolume")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 4 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "originalVolume must be between 0 and 4"})
+			return
+		}
+		originalVolume = v
+	}
+	// Ducking settings; only used when some track sets duck_N=true.
+	ducking := &services.StudioDucking{AmountDb: 9, AttackMs: 20, ReleaseMs: 300}
+	for _, f := range []struct {
+		field    string
+		dst      *float64
+		min, max float64
+	}{
+		{"duckAmountDb", &ducking.AmountDb, 0, 24},
+		{"duckAttackMs", &ducking.AttackMs, 1, 2000},
+		{"duckReleaseMs", &ducking.ReleaseMs, 10, 5000},
+	} {
+		raw := strings.TrimSpace(c.Request.FormValue(f.field))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < f.min || v > f.max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be between %g and %g", f.field, f.min, f.max)})
+			return
+		}
+		*f.dst = v
+	}
 
 	trackCount, _ := strconv.Atoi(strings.TrimSpace(c.Request.FormValue("trackCount")))
-	if trackCount < 1 {
+	if mode == "mute" {
+		// Muting takes no tracks; ignore any that were sent.
+		trackCount = 0
+	} else if trackCount < 1 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one audio track is required"})
 		return
 	}
@@ -228,6 +293,7 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 		volume   float64
 		delaySec float64
 		loop     bool
+		duck     bool
 	}
 	stagedTracks := make([]stagedTrack, 0, trackCount)
 	for i := 0; i < trackCount; i++ {
@@ -270,7 +336,17 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("offset_%d must be between 0 and 3600 seconds", i)})
 			return
 		}
+		if err := checkUploadName(h.cfg, audioHeader.Filename); err != nil {
+			audioFile.Close()
+			_ = os.Remove(incomingVideoPath)
+			for _, st := range stagedTracks {
+				_ = os.Remove(st.path)
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("audio_%d: %v", i, err)})
+			return
+		}
 		loop := strings.EqualFold(strings.TrimSpace(c.Request.FormValue(fmt.Sprintf("loop_%d", i))), "true")
+		duck := strings.EqualFold(strings.TrimSpace(c.Request.FormValue(fmt.Sprintf("duck_%d", i))), "true")
 		cleanAudioName := safeFilename(audioHeader.Filename)
 		audioPath := filepath.Join(h.cfg.UploadDir, fmt.Sprintf("stitch_audio_%d_%d_%s", time.Now().UnixNano(), i, cleanAudioName))
 		if err := h.saveUploadedFile(audioFile, audioPath); err != nil {
@@ -283,7 +359,7 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 			return
 		}
 		audioFile.Close()
-		stagedTracks = append(stagedTracks, stagedTrack{path: audioPath, volume: volume, delaySec: delay, loop: loop})
+		stagedTracks = append(stagedTracks, stagedTrack{path: audioPath, volume: volume, delaySec: delay, loop: loop, duck: duck})
 	}
 
 	// Promote the video to its job dir.
@@ -299,7 +375,34 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 		"trimToVideoDuration": trimToVideo,
 		"trackCount":         trackCount,
 	}
-	job := h.jobManager.CreateJob(originalFile, jobOptions)
+	if mode == "mix" {
+		jobOptions["originalVolume"] = originalVolume
+	}
+	duckedTracks := 0
+	for _, st := range stagedTracks {
+		if st.duck {
+			duckedTracks++
+		}
+	}
+	if duckedTracks == 0 {
+		ducking = nil
+	} else {
+		jobOptions["ducking"] = map[string]interface{}{
+			"tracks":    duckedTracks,
+			"amountDb":  ducking.AmountDb,
+			"attackMs":  ducking.AttackMs,
+			"releaseMs": ducking.ReleaseMs,
+		}
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		_ = os.Remove(incomingVideoPath)
+		for _, st := range stagedTracks {
+			_ = os.Remove(st.path)
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
 	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
 	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
@@ -339,41 +442,1117 @@ func (h *ConversionHandler) StitchAudioToVideoUpload(c *gin.Context) {
 			Volume:   st.volume,
 			DelaySec: st.delaySec,
 			Loop:     st.loop,
+			Duck:     st.duck,
 		})
 	}
 
 	outputPath := h.outputPath(job, jobOutputDir)
-	go h.runStitchAudioToVideo(job, finalVideoPath, outputPath, services.StitchAudioRequest{
+	stitchReq := services.StitchAudioRequest{
 		Mode:                mode,
 		TrimToVideoDuration: trimToVideo,
+		OriginalVolume:      originalVolume,
+		Ducking:             ducking,
 		Tracks:              finalTracks,
-	})
+	}
+	h.startToolJob(job, func() { h.runStitchAudioToVideo(job, finalVideoPath, outputPath, stitchReq) })
 
 	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
 }
 
 func (h *ConversionHandler) runStitchAudioToVideo(job *models.ConversionJob, videoPath, outputPath string, req services.StitchAudioRequest) {
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
-		log.Printf("stitch-audio: failed to mark job %s processing: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job processing", "tool", "stitch-audio", "error", err)
 		return
 	}
 	if h.stitchAudioTool == nil {
 		_ = h.jobManager.UpdateJobError(job.ID, "stitch tool service is not available")
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CommandTimeout)
+	ctx, cancel := jobCommandContext(h.cfg, job)
 	defer cancel()
 	if err := h.stitchAudioTool.Stitch(ctx, job, videoPath, outputPath, req); err != nil {
-		log.Printf("stitch-audio: job %s failed: %v", job.ID, err)
+		jobLogger(job).Warn("tool job failed", "tool", "stitch-audio", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "stitch-audio", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "stitch-audio", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// IMAGE SEQUENCE TO VIDEO
+// ----------------------------------------------------------------------- //
+
+// ImageSequenceToVideoUpload accepts a multipart POST with either repeated
+// "images" file fields (rendered in the order submitted) or a single
+// "archive" ZIP (rendered in archive name order), an optional "audio" file
+// for background music, and the fps / transition / transitionDuration /
+// width / height / background form fields. The result is an MP4 served via
+// /api/download/:jobId.
+func (h *ConversionHandler) ImageSequenceToVideoUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form (request may be too large)"})
+		return
+	}
+	form := c.Request.MultipartForm
+	imageHeaders := form.File["images"]
+	archiveHeaders := form.File["archive"]
+	if len(imageHeaders) == 0 && len(archiveHeaders) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provide images or a zip archive of images"})
+		return
+	}
+	if len(imageHeaders) > 0 && len(archiveHeaders) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provide either images or an archive, not both"})
+		return
+	}
+	if err := checkUploadNames(h.cfg, append(imageHeaders, archiveHeaders...)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(archiveHeaders) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only one archive is supported"})
+		return
+	}
+	if len(imageHeaders) > services.ImageSequenceMaxImages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d images are supported", services.ImageSequenceMaxImages)})
+		return
+	}
+	for _, fh := range imageHeaders {
+		if !services.IsImageSequenceFile(fh.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported image type: %s", safeFilename(fh.Filename))})
+			return
+		}
+	}
+
+	opts := services.ImageSequenceOptions{
+		Transition: c.Request.FormValue("transition"),
+		Background: strings.TrimSpace(c.Request.FormValue("background")),
+	}
+	if raw := strings.TrimSpace(c.Request.FormValue("fps")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fps must be a number"})
+			return
+		}
+		opts.FPS = v
+	}
+	if raw := strings.TrimSpace(c.Request.FormValue("transitionDuration")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transitionDuration must be a number"})
+			return
+		}
+		opts.TransitionDuration = v
+	}
+	opts.Width, _ = strconv.Atoi(strings.TrimSpace(c.Request.FormValue("width")))
+	opts.Height, _ = strconv.Atoi(strings.TrimSpace(c.Request.FormValue("height")))
+
+	// Validate everything we can before staging files. The image count for
+	// an archive isn't known until it's unpacked; the service re-validates.
+	countHint := len(imageHeaders)
+	if countHint == 0 {
+		countHint = 1
+	}
+	probe := services.ImageSequenceRequest{Images: make([]string, countHint), Options: opts}
+	if err := services.ValidateImageSequenceRequest(&probe); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts = probe.Options
+
+	firstName := ""
+	if len(archiveHeaders) > 0 {
+		firstName = safeFilename(archiveHeaders[0].Filename)
+	} else {
+		firstName = safeFilename(imageHeaders[0].Filename)
+	}
+	var totalSize int64
+	for _, fh := range append(append([]*multipart.FileHeader{}, imageHeaders...), archiveHeaders...) {
+		totalSize += fh.Size
+	}
+	originalFile := models.OriginalFileInfo{
+		Name: firstName,
+		Size: totalSize,
+		Type: "image/sequence",
+	}
+	jobOptions := map[string]interface{}{
+		"mode":       "image_sequence_to_video",
+		"format":     "mp4",
+		"fps":        opts.FPS,
+		"transition": opts.Transition,
+		"width":      opts.Width,
+		"height":     opts.Height,
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+
+	imagesDir := filepath.Join(jobUploadDir, "images")
+	var imagePaths []string
+	if len(archiveHeaders) > 0 {
+		archivePath := filepath.Join(jobUploadDir, "original_"+firstName)
+		if err := saveMultipartFile(archiveHeaders[0], archivePath, h.saveUploadedFile); err != nil {
+			h.jobManager.UpdateJobError(job.ID, "failed to save archive")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save archive"})
+			return
+		}
+		paths, err := services.ExtractImageSequenceArchive(archivePath, imagesDir, h.cfg.MaxFileSize)
+		if err != nil {
+			h.jobManager.UpdateJobError(job.ID, err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		imagePaths = paths
+	} else {
+		for i, fh := range imageHeaders {
+			dest := filepath.Join(imagesDir, fmt.Sprintf("%05d_%s", i, safeFilename(fh.Filename)))
+			if err := saveMultipartFile(fh, dest, h.saveUploadedFile); err != nil {
+				h.jobManager.UpdateJobError(job.ID, "failed to save image")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+				return
+			}
+			imagePaths = append(imagePaths, dest)
+		}
+	}
+
+	req := services.ImageSequenceRequest{Images: imagePaths, Options: opts}
+	if err := services.ValidateImageSequenceRequest(&req); err != nil {
+		h.jobManager.UpdateJobError(job.ID, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if audioHeaders := form.File["audio"]; len(audioHeaders) > 0 {
+		audioPath := filepath.Join(jobUploadDir, "audio_"+safeFilename(audioHeaders[0].Filename))
+		if err := saveMultipartFile(audioHeaders[0], audioPath, h.saveUploadedFile); err != nil {
+			h.jobManager.UpdateJobError(job.ID, "failed to save audio")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save audio"})
+			return
+		}
+		req.AudioPath = audioPath
+	}
+
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runImageSequenceToVideo(job, outputPath, req) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+// saveMultipartFile opens one part of an already-parsed multipart form and
+// writes it to dest via the supplied saver.
+func saveMultipartFile(fh *multipart.FileHeader, dest string, save func(io.Reader, string) error) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return save(src, dest)
+}
+
+func (h *ConversionHandler) runImageSequenceToVideo(job *models.ConversionJob, outputPath string, req services.ImageSequenceRequest) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "image-sequence", "error", err)
+		return
+	}
+	if h.imageSequenceTool == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "image sequence service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	if err := h.imageSequenceTool.Render(ctx, job, outputPath, req); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "image-sequence", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "image-sequence", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "image-sequence", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// LIVE RECORD
+// ----------------------------------------------------------------------- //
+
+type liveRecordRequest struct {
+	URL             string                 `json:"url"`
+	DurationSeconds int                    `json:"durationSeconds"`
+	Options         map[string]interface{} `json:"options"`
+}
+
+// LiveRecordStart accepts a JSON body naming a live source (rtmp://, srt://
+// or an https:// HLS playlist), a capture duration, and the usual video
+// conversion options. The job records that window of the stream and then
+// runs the captured file through the standard conversion pipeline, so
+// polling and download work exactly like an uploaded file.
+func (h *ConversionHandler) LiveRecordStart(c *gin.Context) {
+	if !h.cfg.LiveRecordEnabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "live recording is not enabled on this server"})
+		return
+	}
+	var req liveRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.DurationSeconds < 1 || req.DurationSeconds > h.cfg.LiveRecordMaxSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("durationSeconds must be between 1 and %d", h.cfg.LiveRecordMaxSeconds)})
+		return
+	}
+	source, err := services.ValidateLiveSourceURL(c.Request.Context(), req.URL, h.cfg.LiveRecordAllowPrivateHosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	options := req.Options
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	if format, _ := options["format"].(string); strings.TrimSpace(format) == "" {
+		options["format"] = "mp4"
+	}
+	if err := h.converter.ValidateVideoOptions(options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	delivery, err := services.ParseDeliveryEncryption(options)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if delivery != nil {
+		options["deliveryEncryption"] = delivery.Redacted()
+	}
+	// The redacted source (scheme://host) is all we keep on the job: RTMP
+	// URLs routinely embed the stream key.
+	options["liveSource"] = services.RedactLiveSourceURL(source)
+	options["liveDurationSeconds"] = req.DurationSeconds
+
+	originalFile := models.OriginalFileInfo{
+		Name: "live_" + safeFilename(source.Hostname()) + ".mkv",
+		Type: "video/x-matroska",
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare recording"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+	capturePath := filepath.Join(jobUploadDir, "original_"+originalFile.Name)
+	h.startToolJob(job, func() { h.runLiveRecord(job, source, req.DurationSeconds, capturePath, jobOutputDir, delivery) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runLiveRecord(job *models.ConversionJob, source *url.URL, durationSec int, capturePath, outputDir string, delivery *services.DeliveryEncryption) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "live-record", "error", err)
+		return
+	}
+	if h.liveRecord == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "live record service is not available")
+		return
+	}
+	ctx, cancel := services.JobContext(h.cfg, job)
+	defer cancel()
+	if err := h.liveRecord.Record(ctx, job, source, durationSec, capturePath); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "live-record", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.processConversion(job, capturePath, outputDir, delivery)
+}
+
+// ----------------------------------------------------------------------- //
+// PLATFORM URL IMPORT
+// ----------------------------------------------------------------------- //
+
+type mediaURLImportRequest struct {
+	URL     string                 `json:"url"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// MediaURLImportStart accepts a JSON body with a YouTube, Vimeo or SoundCloud
+// link and the usual conversion options. The job downloads the media with
+// yt-dlp — only the audio when the output format is an audio one — and then
+// converts it exactly like an uploaded file.
+func (h *ConversionHandler) MediaURLImportStart(c *gin.Context) {
+	if !h.cfg.MediaURLImportEnabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL import is not enabled on this server"})
+		return
+	}
+	var req mediaURLImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	source, err := services.ValidateMediaURL(c.Request.Context(), req.URL, h.cfg.MediaURLImportHosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	raw, _ := json.Marshal(req.Options)
+	options, delivery, err := parseUploadOptions(string(raw))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if format, _ := options["format"].(string); strings.TrimSpace(format) == "" {
+		options["format"] = "mp4"
+	}
+	format, _ := options["format"].(string)
+	audioOnly := services.IsAudioOutputFormat(format)
+	fileType := models.FileTypeVideo
+	if audioOnly {
+		fileType = models.FileTypeAudio
+	}
+	if t := middleware.Tenant(c); t != nil {
+		if err := t.Policy.Apply(fileType, options); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	probe := &models.ConversionJob{Options: options}
+	standard := !isTranscribeMode(probe) && specializedMode(probe) == ""
+	if delivery != nil && !standard {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deliveryEncryption is only supported for standard conversions"})
+		return
+	}
+	if standard && !audioOnly {
+		if err := h.converter.ValidateVideoOptions(options); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	options["mediaSource"] = source.String()
+
+	// The name and type are placeholders until the download tells us what
+	// the platform served.
+	originalFile := models.OriginalFileInfo{Name: safeFilename(source.Hostname()), Type: "video/x-matroska"}
+	if audioOnly {
+		originalFile.Type = "audio/ogg"
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare download"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+	h.startToolJob(job, func() { h.runMediaURLImport(job, source, fileType, jobUploadDir, jobOutputDir, delivery) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runMediaURLImport(job *models.ConversionJob, source *url.URL, fileType models.FileType, uploadDir, outputDir string, delivery *services.DeliveryEncryption) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "url-import", "error", err)
+		return
+	}
+	if h.mediaURL == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "URL import service is not available")
+		return
+	}
+	downloadCtx, cancelDownload := services.JobContext(h.cfg, job)
+	defer cancelDownload()
+	downloaded, err := h.mediaURL.Download(downloadCtx, job, source, fileType == models.FileTypeAudio, uploadDir)
+	if err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "url-import", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	detected, mimeType := h.inspector.DetectFile(ctx, downloaded, "")
+	cancel()
+	if detected != fileType {
+		_ = os.Remove(downloaded)
+		msg := "linked media has no video stream; choose an audio output format"
+		if fileType == models.FileTypeAudio {
+			msg = "linked media is not audio"
+		}
+		_ = h.jobManager.UpdateJobError(job.ID, msg)
+		return
+	}
+	name := filepath.Base(downloaded)
+	inputPath := filepath.Join(uploadDir, "original_"+name)
+	if err := os.Rename(downloaded, inputPath); err != nil {
+		_ = os.Remove(downloaded)
+		_ = h.jobManager.UpdateJobError(job.ID, "Failed to finalize downloaded file")
+		return
+	}
+	var size int64
+	if info, err := os.Stat(inputPath); err == nil {
+		size = info.Size()
+	}
+	_ = h.jobManager.SetOriginalFile(job.ID, models.OriginalFileInfo{Name: name, Size: size, Type: mimeType})
+	h.processConversion(job, inputPath, outputDir, delivery)
+}
+
+// ----------------------------------------------------------------------- //
+// WEBPAGE SCREENSHOT
+// ----------------------------------------------------------------------- //
+
+type webpageScreenshotRequest struct {
+	URL string `json:"url"`
+	services.WebpageScreenshotOptions
+}
+
+// WebpageScreenshotStart renders a URL to PNG/WebP/PDF in a headless browser.
+// The job then behaves like any other: poll /api/job/:id and fetch the
+// result from /api/download/:id.
+func (h *ConversionHandler) WebpageScreenshotStart(c *gin.Context) {
+	if !h.cfg.ScreenshotEnabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webpage screenshots are not enabled on this server"})
+		return
+	}
+	var req webpageScreenshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	opts := req.WebpageScreenshotOptions
+	target, err := services.ValidateWebpageScreenshotRequest(c.Request.Context(), req.URL, &opts, h.cfg.ScreenshotAllowPrivateHosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	originalFile := models.OriginalFileInfo{
+		Name: safeFilename(target.Hostname()) + ".html",
+		Type: "text/html",
+	}
+	jobOptions := map[string]interface{}{
+		"mode":    "webpage_screenshot",
+		"format":  opts.Format,
+		"url":     target.String(),
+		"width":   opts.Width,
+		"height":  opts.Height,
+		"delayMs": *opts.DelayMs,
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runWebpageScreenshot(job, target, opts, outputPath) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runWebpageScreenshot(job *models.ConversionJob, target *url.URL, opts services.WebpageScreenshotOptions, outputPath string) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "webpage-screenshot", "error", err)
+		return
+	}
+	if h.webpageScreenshot == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "webpage screenshot service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	if err := h.webpageScreenshot.Capture(ctx, job, target, opts, outputPath); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "webpage-screenshot", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "webpage-screenshot", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "webpage-screenshot", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// DOCUMENT THUMBNAIL
+// ----------------------------------------------------------------------- //
+
+// DocumentThumbnailUpload accepts one document ("file": a PDF, or an office
+// file when LibreOffice support is enabled) plus optional "size" (long edge,
+// px) and "format" (png|jpg) fields, and renders a first-page thumbnail.
+func (h *ConversionHandler) DocumentThumbnailUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form (file may be too large)"})
+		return
+	}
+	file, fileHeader, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no document provided"})
+		return
+	}
+	defer file.Close()
+	if err := checkUploadName(h.cfg, fileHeader.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cleanName := safeFilename(fileHeader.Filename)
+	opts := services.DocumentThumbnailOptions{Format: c.Request.FormValue("format")}
+	if raw := strings.TrimSpace(c.Request.FormValue("size")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be an integer"})
+			return
+		}
+		opts.Size = v
+	}
+	if err := services.ValidateDocumentThumbnailRequest(cleanName, &opts, h.cfg.DocumentThumbnailOfficeEnabled); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	originalFile := models.OriginalFileInfo{
+		Name: cleanName,
+		Size: fileHeader.Size,
+		Type: fileHeader.Header.Get("Content-Type"),
+	}
+	jobOptions := map[string]interface{}{
+		"mode":   "document_thumbnail",
+		"format": opts.Format,
+		"size":   opts.Size,
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+	uploadPath := filepath.Join(jobUploadDir, "original_"+cleanName)
+	if err := h.saveUploadedFile(file, uploadPath); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to save document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save document"})
+		return
+	}
+
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runDocumentThumbnail(job, uploadPath, outputPath, opts) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runDocumentThumbnail(job *models.ConversionJob, inputPath, outputPath string, opts services.DocumentThumbnailOptions) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "document-thumbnail", "error", err)
+		return
+	}
+	if h.documentThumbnail == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "document thumbnail service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	if err := h.documentThumbnail.Render(ctx, job, inputPath, outputPath, opts); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "document-thumbnail", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "document-thumbnail", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "document-thumbnail", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// BATCH IMAGES
+// ----------------------------------------------------------------------- //
+
+// BatchImagesUpload converts many images in one job. Multipart fields:
+//
+//   - files:     the images (repeat the field)
+//   - options:   JSON image options shared by every file (same shape as
+//     /api/upload's options)
+//   - overrides: optional JSON object keyed by filename or zero-based upload
+//     index, each value a partial options object merged over the base
+//
+// The result is a ZIP of the converted images plus summary.json with the
+// per-file status, effective options and warnings.
+func (h *ConversionHandler) BatchImagesUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form (request may be too large)"})
+		return
+	}
+	headers := c.Request.MultipartForm.File["files"]
+	if len(headers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no images provided"})
+		return
+	}
+	if len(headers) > services.BatchImageMaxFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d images are supported per batch", services.BatchImageMaxFiles)})
+		return
+	}
+	if err := checkUploadNames(h.cfg, headers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	base, err := parseOptions(c.Request.FormValue("options"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	overrides := map[string]map[string]interface{}{}
+	if raw := strings.TrimSpace(c.Request.FormValue("overrides")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid overrides format (expected an object of option objects)"})
+			return
+		}
+	}
+
+	// Reject bad option combinations before anything is staged; the service
+	// re-validates per file.
+	names := make([]string, len(headers))
+	var totalSize int64
+	for i, fh := range headers {
+		names[i] = safeFilename(fh.Filename)
+		totalSize += fh.Size
+	}
+	perFile, _ := services.ResolveBatchImageOverrides(names, overrides)
+	for i := range headers {
+		if err := h.converter.ValidateImageOptions(services.MergeBatchImageOptions(base, perFile[i])); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %v", names[i], err)})
+			return
+		}
+	}
+
+	originalFile := models.OriginalFileInfo{
+		Name: names[0],
+		Size: totalSize,
+		Type: "image/batch",
+	}
+	jobOptions := map[string]interface{}{
+		"mode":      "batch_images",
+		"format":    "zip",
+		"fileCount": len(headers),
+		"base":      base,
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+	inputs := make([]services.BatchImageInput, 0, len(headers))
+	for i, fh := range headers {
+		dest := filepath.Join(jobUploadDir, fmt.Sprintf("%05d_%s", i, names[i]))
+		if err := saveMultipartFile(fh, dest, h.saveUploadedFile); err != nil {
+			h.jobManager.UpdateJobError(job.ID, "failed to save image")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+			return
+		}
+		fileType, mimeType := h.inspector.DetectFile(ctx, dest, fh.Header.Get("Content-Type"))
+		if fileType != models.FileTypeImage {
+			h.jobManager.UpdateJobError(job.ID, "batch contains a non-image file")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not an image", names[i])})
+			return
+		}
+		inputs = append(inputs, services.BatchImageInput{Name: names[i], Path: dest, Type: mimeType})
+	}
+
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runBatchImages(job, inputs, base, overrides, outputPath) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runBatchImages(job *models.ConversionJob, inputs []services.BatchImageInput, base map[string]interface{}, overrides map[string]map[string]interface{}, outputPath string) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "batch-images", "error", err)
+		return
+	}
+	if h.batchImages == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "batch image service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	summary, err := h.batchImages.Run(ctx, job, inputs, base, overrides, outputPath)
+	if err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "batch-images", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	jobLogger(job).Info("batch finished", "tool", "batch-images", "succeeded", summary.Succeeded, "failed", summary.Failed)
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "batch-images", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "batch-images", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// VIDEO GRID
+// ----------------------------------------------------------------------- //
+
+// VideoGridUpload composes 2–4 videos into one side-by-side, stacked or 2x2
+// grid video. Multipart fields:
+//
+//   - videos:     the inputs, in cell order (repeat the field)
+//   - layout:     hstack (default) | vstack | grid
+//   - cellWidth:  per-cell width in pixels (default 960)
+//   - cellHeight: per-cell height in pixels (default 540)
+//   - audio:      mix (default) | first | none
+//   - duration:   shortest (default) | longest
+//   - quality:    low | medium (default) | high
+//
+// The result is a single MP4.
+func (h *ConversionHandler) VideoGridUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form (request may be too large)"})
+		return
+	}
+	headers := c.Request.MultipartForm.File["videos"]
+	if len(headers) < services.VideoGridMinInputs || len(headers) > services.VideoGridMaxInputs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("provide between %d and %d videos", services.VideoGridMinInputs, services.VideoGridMaxInputs)})
+		return
+	}
+	if err := checkUploadNames(h.cfg, headers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts := services.VideoGridOptions{
+		Layout:   c.Request.FormValue("layout"),
+		Audio:    c.Request.FormValue("audio"),
+		Duration: c.Request.FormValue("duration"),
+		Quality:  strings.ToLower(strings.TrimSpace(c.Request.FormValue("quality"))),
+	}
+	for field, dst := range map[string]*int{"cellWidth": &opts.CellWidth, "cellHeight": &opts.CellHeight} {
+		raw := strings.TrimSpace(c.Request.FormValue(field))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a positive integer", field)})
+			return
+		}
+		*dst = v
+	}
+	if err := services.ValidateVideoGridOptions(&opts, len(headers)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	names := make([]string, len(headers))
+	var totalSize int64
+	for i, fh := range headers {
+		names[i] = safeFilename(fh.Filename)
+		totalSize += fh.Size
+	}
+	originalFile := models.OriginalFileInfo{
+		Name: names[0],
+		Size: totalSize,
+		Type: "video/grid",
+	}
+	jobOptions := map[string]interface{}{
+		"mode":       "video_grid",
+		"format":     "mp4",
+		"layout":     opts.Layout,
+		"cellWidth":  opts.CellWidth,
+		"cellHeight": opts.CellHeight,
+		"audio":      opts.Audio,
+		"duration":   opts.Duration,
+		"quality":    opts.Quality,
+		"inputCount": len(headers),
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+	inputs := make([]string, 0, len(headers))
+	for i, fh := range headers {
+		dest := filepath.Join(jobUploadDir, fmt.Sprintf("%02d_%s", i, names[i]))
+		if err := saveMultipartFile(fh, dest, h.saveUploadedFile); err != nil {
+			h.jobManager.UpdateJobError(job.ID, "failed to save video")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save video"})
+			return
+		}
+		if fileType, _ := h.inspector.DetectFile(ctx, dest, fh.Header.Get("Content-Type")); fileType != models.FileTypeVideo {
+			h.jobManager.UpdateJobError(job.ID, "grid input is not a video")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not a video", names[i])})
+			return
+		}
+		inputs = append(inputs, dest)
+	}
+
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runVideoGrid(job, inputs, opts, outputPath) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runVideoGrid(job *models.ConversionJob, inputs []string, opts services.VideoGridOptions, outputPath string) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "video-grid", "error", err)
+		return
+	}
+	if h.videoGrid == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "video grid service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	if err := h.videoGrid.Compose(ctx, job, inputs, opts, outputPath); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "video-grid", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
+		return
+	}
+	h.recordResultMetadata(job, outputPath)
+	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
+		jobLogger(job).Error("failed to update job result", "tool", "video-grid", "error", err)
+		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
+		return
+	}
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
+		jobLogger(job).Error("failed to mark job completed", "tool", "video-grid", "error", err)
+	}
+}
+
+// ----------------------------------------------------------------------- //
+// AUDIO JOIN
+// ----------------------------------------------------------------------- //
+
+// AudioJoinUpload joins 2–20 audio files end to end, optionally crossfading
+// each pair. Multipart fields:
+//
+//   - audio:     the segments, in playback order (repeat the field)
+//   - crossfade: seconds of overlap between segments, 0–10 (default 0)
+//   - curve:     tri (default) | qsin | hsin | esin | log | exp
+//   - format:    mp3 (default) | wav | aac | ogg | flac | opus
+//   - bitrate:   kbps for lossy formats, 32–512 (default 192)
+func (h *ConversionHandler) AudioJoinUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxFileSize)
+	if err := c.Request.ParseMultipartForm(h.cfg.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse form (request may be too large)"})
+		return
+	}
+	headers := c.Request.MultipartForm.File["audio"]
+	if len(headers) < services.AudioJoinMinInputs || len(headers) > services.AudioJoinMaxInputs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("provide between %d and %d audio files", services.AudioJoinMinInputs, services.AudioJoinMaxInputs)})
+		return
+	}
+	if err := checkUploadNames(h.cfg, headers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts := services.AudioJoinOptions{
+		Curve:  c.Request.FormValue("curve"),
+		Format: c.Request.FormValue("format"),
+	}
+	if raw := strings.TrimSpace(c.Request.FormValue("crossfade")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "crossfade must be a number of seconds"})
+			return
+		}
+		opts.Crossfade = v
+	}
+	if raw := strings.TrimSpace(c.Request.FormValue("bitrate")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bitrate must be an integer"})
+			return
+		}
+		opts.Bitrate = v
+	}
+	if err := services.ValidateAudioJoinOptions(&opts, len(headers)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	names := make([]string, len(headers))
+	var totalSize int64
+	for i, fh := range headers {
+		names[i] = safeFilename(fh.Filename)
+		totalSize += fh.Size
+	}
+	originalFile := models.OriginalFileInfo{
+		Name: names[0],
+		Size: totalSize,
+		Type: "audio/join",
+	}
+	jobOptions := map[string]interface{}{
+		"mode":       "audio_join",
+		"format":     opts.Format,
+		"crossfade":  opts.Crossfade,
+		"curve":      opts.Curve,
+		"bitrate":    opts.Bitrate,
+		"inputCount": len(headers),
+	}
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, jobOptions)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
+	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
+	if err := os.MkdirAll(jobUploadDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create upload directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+	if err := os.MkdirAll(jobOutputDir, 0o755); err != nil {
+		h.jobManager.UpdateJobError(job.ID, "failed to create output directory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare output"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+	inputs := make([]string, 0, len(headers))
+	for i, fh := range headers {
+		dest := filepath.Join(jobUploadDir, fmt.Sprintf("%02d_%s", i, names[i]))
+		if err := saveMultipartFile(fh, dest, h.saveUploadedFile); err != nil {
+			h.jobManager.UpdateJobError(job.ID, "failed to save audio")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save audio"})
+			return
+		}
+		if fileType, _ := h.inspector.DetectFile(ctx, dest, fh.Header.Get("Content-Type")); fileType != models.FileTypeAudio && fileType != models.FileTypeVideo {
+			h.jobManager.UpdateJobError(job.ID, "join input is not audio")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not an audio file", names[i])})
+			return
+		}
+		inputs = append(inputs, dest)
+	}
+
+	outputPath := h.outputPath(job, jobOutputDir)
+	h.startToolJob(job, func() { h.runAudioJoin(job, inputs, opts, outputPath) })
+
+	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+}
+
+func (h *ConversionHandler) runAudioJoin(job *models.ConversionJob, inputs []string, opts services.AudioJoinOptions, outputPath string) {
+	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusProcessing); err != nil {
+		jobLogger(job).Error("failed to mark job processing", "tool", "audio-join", "error", err)
+		return
+	}
+	if h.audioJoin == nil {
+		_ = h.jobManager.UpdateJobError(job.ID, "audio join service is not available")
+		return
+	}
+	ctx, cancel := jobCommandContext(h.cfg, job)
+	defer cancel()
+	if err := h.audioJoin.Join(ctx, job, inputs, opts, outputPath); err != nil {
+		jobLogger(job).Warn("tool job failed", "tool", "audio-join", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, err.Error())
 		return
 	}
+	h.recordResultMetadata(job, outputPath)
 	if err := h.jobManager.UpdateJobResult(job.ID, "/api/download/"+job.ID); err != nil {
-		log.Printf("stitch-audio: failed to update job %s result: %v", job.ID, err)
+		jobLogger(job).Error("failed to update job result", "tool", "audio-join", "error", err)
 		_ = h.jobManager.UpdateJobError(job.ID, "failed to update job result")
 		return
 	}
 	if err := h.jobManager.UpdateJobStatus(job.ID, models.StatusCompleted); err != nil {
-		log.Printf("stitch-audio: failed to mark job %s completed: %v", job.ID, err)
+		jobLogger(job).Error("failed to mark job completed", "tool", "audio-join", "error", err)
 	}
 }
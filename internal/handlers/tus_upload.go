@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// tus 1.0.0 resumable uploads at /api/tus. A finished upload goes through the
+// same detection, policy and conversion path as /api/upload; the upload's
+// metadata carries what the multipart form would have (filename, filetype
+// and the options JSON). The job ID comes back on the final PATCH, and on
+// any later HEAD, in X-MM-Job-ID.
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	tusJobHeader  = "X-MM-Job-ID"
+)
+
+// TusOptions advertises the server's tus capabilities.
+func (h *ConversionHandler) TusOptions(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxFileSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate starts an upload. The options metadata is validated now so a
+// client doesn't send a whole file only to have it rejected.
+func (h *ConversionHandler) TusCreate(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length must be a positive integer"})
+		return
+	}
+	if length > h.cfg.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds maximum upload size"})
+		return
+	}
+	metadata, err := services.ParseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkUploadName(h.cfg, metadata["filename"]); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, _, err := parseUploadOptions(metadata["options"]); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := h.tusUploads.Create(length, metadata)
+	if err != nil {
+		log.Printf("failed to create tus upload: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// TusHead reports how much of an upload the server has.
+func (h *ConversionHandler) TusHead(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	upload, ok := h.tusUploads.Get(c.Param("uploadId"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	if upload.JobID != "" {
+		c.Header(tusJobHeader, upload.JobID)
+	}
+	c.Status(http.StatusOK)
+}
+
+// TusPatch appends the request body at Upload-Offset. The PATCH that
+// delivers the last byte starts the conversion.
+func (h *ConversionHandler) TusPatch(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	id := c.Param("uploadId")
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be a non-negative integer"})
+		return
+	}
+	current, ok := h.tusUploads.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrTusUploadNotFound.Error()})
+		return
+	}
+	if c.Request.ContentLength > current.Length-offset {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrTusUploadTooLarge.Error()})
+		return
+	}
+
+	upload, err := h.tusUploads.Append(id, offset, c.Request.Body)
+	if upload != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	}
+	switch {
+	case errors.Is(err, services.ErrTusUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrTusOffsetMismatch), errors.Is(err, services.ErrTusUploadBusy), errors.Is(err, services.ErrTusUploadComplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrTusUploadTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		// The client went away mid-body; what arrived is kept for HEAD to
+		// report.
+		log.Printf("tus upload %s interrupted at offset %d: %v", id, upload.Offset, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload interrupted"})
+		return
+	}
+	if !upload.Complete() {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	jobID, status, err := h.finishTusUpload(c, upload)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header(tusJobHeader, jobID)
+	c.Status(http.StatusNoContent)
+}
+
+// TusDelete abandons an upload (the termination extension).
+func (h *ConversionHandler) TusDelete(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	switch err := h.tusUploads.Delete(c.Param("uploadId")); {
+	case errors.Is(err, services.ErrTusUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTusUploadBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// finishTusUpload moves a complete upload out of the tus store and starts
+// its conversion job.
+func (h *ConversionHandler) finishTusUpload(c *gin.Context, upload *services.TusUpload) (string, int, error) {
+	options, delivery, err := parseUploadOptions(upload.Metadata["options"])
+	if err != nil {
+		_ = h.tusUploads.Delete(upload.ID)
+		return "", http.StatusBadRequest, err
+	}
+	fileName := upload.Metadata["filename"]
+	incomingPath := filepath.Join(h.cfg.UploadDir, fmt.Sprintf("incoming_%d_%s", time.Now().UnixNano(), safeFilename(fileName)))
+	if err := h.tusUploads.Take(upload.ID, incomingPath); err != nil {
+		log.Printf("failed to take tus upload %s: %v", upload.ID, err)
+		return "", http.StatusInternalServerError, fmt.Errorf("Failed to finalize upload")
+	}
+	job, status, err := h.startUploadJob(c, incomingPath, fileName, upload.Length, upload.Metadata["filetype"], options, delivery)
+	if err != nil {
+		_ = h.tusUploads.Delete(upload.ID)
+		return "", status, err
+	}
+	h.tusUploads.SetJob(upload.ID, job.ID)
+	return job.ID, http.StatusOK, nil
+}
+
+// tusResumable sets Tus-Resumable on the response and rejects requests for
+// a protocol version the server doesn't speak.
+func tusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if v := c.GetHeader("Tus-Resumable"); v != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": fmt.Sprintf("unsupported Tus-Resumable %q", v)})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+// maxSafeFilenameBytes keeps "original_<name>" and friends well under the
+// 255-byte component limit of common filesystems.
+const maxSafeFilenameBytes = 200
+
+var errUploadNameTraversal = errors.New("file name must not contain path segments")
+
+// errUploadNameDash refuses names that a converter could read as an option.
+var errUploadNameDash = errors.New(`file name must not start with "-"`)
+
+// checkUploadName applies the upload filename policy to a client-supplied
+// name before anything is written: path traversal, a leading "-", then the
+// configured extension allow/deny lists. Every extension in the name is checked against
+// the deny list so "report.php.pdf" is refused along with "report.php".
+func checkUploadName(cfg *config.Config, raw string) error {
+	if strings.ContainsRune(raw, 0) {
+		return errors.New("invalid file name")
+	}
+	segments := strings.Split(strings.ReplaceAll(raw, "\\", "/"), "/")
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == ".." {
+			return errUploadNameTraversal
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(segments[len(segments)-1]), "-") {
+		return errUploadNameDash
+	}
+	if cfg == nil {
+		return nil
+	}
+	parts := strings.Split(strings.ToLower(safeFilename(raw)), ".")
+	exts := parts[1:]
+	if len(cfg.UploadAllowedExtensions) > 0 {
+		final := ""
+		if len(exts) > 0 {
+			final = exts[len(exts)-1]
+		}
+		if !containsString(cfg.UploadAllowedExtensions, final) {
+			if final == "" {
+				return errors.New("file name must have an extension")
+			}
+			return fmt.Errorf("file extension .%s is not allowed", final)
+		}
+	}
+	for _, ext := range exts {
+		if containsString(cfg.UploadDeniedExtensions, strings.TrimSpace(ext)) {
+			return fmt.Errorf("file extension .%s is not allowed", ext)
+		}
+	}
+	return nil
+}
+
+// checkUploadNames is checkUploadName for every file of a multi-file form
+// field; the error names the offending file.
+func checkUploadNames(cfg *config.Config, headers []*multipart.FileHeader) error {
+	for _, fh := range headers {
+		if err := checkUploadName(cfg, fh.Filename); err != nil {
+			return fmt.Errorf("%s: %w", safeFilename(fh.Filename), err)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// safeFilename reduces a client-supplied name to one path component that is
+// safe to embed in a path. Directories are dropped whichever separator the
+// client used, anything but letters, digits, space and ._- becomes "_",
+// leading dots go (no hidden files, no "..") as do leading dashes (so the
+// name can't be taken for a command-line option), and overlong names are
+// cut with the extension kept.
+func safeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_', r == ' ':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name = strings.TrimRight(strings.TrimLeft(b.String(), ". -"), ". ")
+	if len(name) > maxSafeFilenameBytes {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		stem := name[:maxSafeFilenameBytes-len(ext)]
+		for !utf8.ValidString(stem) {
+			stem = stem[:len(stem)-1]
+		}
+		name = stem + ext
+	}
+	if name == "" {
+		return "upload"
+	}
+	return name
+}
+
+// pathWithin reports whether p is inside one of roots.
+func pathWithin(p string, roots ...string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(rootAbs, abs)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func TestSafeFilename(t *testing.T) {
+	cases := map[string]string{
+		"clip.mp4":                        "clip.mp4",
+		"../../etc/passwd":                "passwd",
+		`C:\Users\me\holiday.mov`:         "holiday.mov",
+		"..":                              "upload",
+		".htaccess":                       "htaccess",
+		"a;b$(rm -rf).png":                "a_b__rm -rf_.png",
+		"film\x00.mp4":                    "film_.mp4",
+		"photo. ":                         "photo",
+		"-rf.mp4":                         "rf.mp4",
+		"--output=x.png":                  "output_x.png",
+		". -.-x.jpg":                      "x.jpg",
+		"résumé vidéo.mp4":                "résumé vidéo.mp4",
+		"":                                "upload",
+		strings.Repeat("a", 300) + ".mp4": strings.Repeat("a", maxSafeFilenameBytes-4) + ".mp4",
+	}
+	for in, want := range cases {
+		if got := safeFilename(in); got != want {
+			t.Errorf("safeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckUploadName(t *testing.T) {
+	cfg := &config.Config{UploadDeniedExtensions: []string{"exe", "php"}}
+	for _, name := range []string{"clip.mp4", "my.holiday.clip.mov", "noext"} {
+		if err := checkUploadName(cfg, name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{"setup.EXE", "shell.php.jpg", "../secret.mp4", `..\..\x.png`, "a\x00.mp4", "-rf.mp4", " --help.png", "dir/-x.mov"} {
+		if err := checkUploadName(cfg, name); err == nil {
+			t.Errorf("%q: expected rejection", name)
+		}
+	}
+
+	cfg.UploadAllowedExtensions = []string{"mp4", "png"}
+	if err := checkUploadName(cfg, "clip.mp4"); err != nil {
+		t.Errorf("allowed extension rejected: %v", err)
+	}
+	for _, name := range []string{"clip.mov", "noext"} {
+		if err := checkUploadName(cfg, name); err == nil {
+			t.Errorf("%q: expected rejection by the allow list", name)
+		}
+	}
+}
+
+func TestSaveUploadedFileStaysInUploadDirs(t *testing.T) {
+	root := t.TempDir()
+	h := &ConversionHandler{cfg: &config.Config{UploadDir: filepath.Join(root, "uploads"), TempDir: filepath.Join(root, "temp")}}
+
+	dst := filepath.Join(h.cfg.UploadDir, "job", "original_clip.mp4")
+	if err := h.saveUploadedFile(bytes.NewReader([]byte("data")), dst); err != nil {
+		t.Fatalf("save inside upload dir: %v", err)
+	}
+	if err := h.saveUploadedFile(bytes.NewReader([]byte("again")), dst); err == nil {
+		t.Error("overwriting an existing upload should fail")
+	}
+	outside := filepath.Join(root, "elsewhere.mp4")
+	if err := h.saveUploadedFile(bytes.NewReader([]byte("data")), outside); err == nil {
+		t.Error("saving outside the upload dirs should fail")
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("file written outside the upload dirs: %v", err)
+	}
+}
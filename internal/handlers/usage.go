@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// checkQuota refuses a job with an input of size bytes when it would take
+// the caller's tenant past its monthly quota, with Retry-After pointing at
+// the start of next month. Callers without an API key or quota pass.
+func checkQuota(c *gin.Context, jm *services.JobManager, size int64) error {
+	t := middleware.Tenant(c)
+	if t == nil || t.Policy.Quota == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	usage := jm.Usage().Get(t.Name, services.UsageMonth(now))
+	if err := t.Policy.Quota.Check(usage, size); err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(nextUsageMonth(now).Sub(now).Seconds())+1))
+		return err
+	}
+	return nil
+}
+
+// GetUsage reports the caller's tenant usage for a month ("month=2006-01",
+// default the current one) with its quota. It needs an API key.
+func (h *ConversionHandler) GetUsage(c *gin.Context) {
+	t := middleware.Tenant(c)
+	if t == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "An API key is required"})
+		return
+	}
+	now := time.Now().UTC()
+	month := strings.TrimSpace(c.Query("month"))
+	if month == "" {
+		month = services.UsageMonth(now)
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must look like 2006-01"})
+		return
+	}
+	resp := gin.H{
+		"tenant": t.Name,
+		"month":  month,
+		"usage":  h.jobManager.Usage().Get(t.Name, month),
+	}
+	if t.Policy.Quota != nil {
+		resp["quota"] = t.Policy.Quota
+	}
+	if month == services.UsageMonth(now) {
+		resp["resetsAt"] = nextUsageMonth(now)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// nextUsageMonth returns the start of the month after t's (UTC).
+func nextUsageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/tenant"
+)
+
+func TestToolsJobsCountAgainstQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg, err := tenant.Parse([]byte(`{"tenants":[{"name":"acme","keys":["acme-test-key-0001"],"policy":{"quota":{"jobsPerMonth":1}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, _ := services.NewUsageTracker("")
+	jm := services.NewJobManager()
+	jm.TrackUsage(usage)
+	cfg := &config.Config{}
+	r := gin.New()
+	r.Use(middleware.APIKey(reg))
+	r.POST("/api/tools/batch-images", func(c *gin.Context) {
+		originalFile := models.OriginalFileInfo{Name: "a.png", Size: 512, Type: "image/batch"}
+		job, status, err := createClientJob(c, jm, cfg, originalFile, map[string]interface{}{"mode": "batch_images"})
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
+	})
+	create := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tools/batch-images", nil)
+		req.Header.Set("X-API-Key", "acme-test-key-0001")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := create(); w.Code != http.StatusOK {
+		t.Fatalf("first tools job = %d %s", w.Code, w.Body)
+	}
+	if got := usage.Get("acme", services.UsageMonth(time.Now())); got.Jobs != 1 || got.Bytes != 512 {
+		t.Fatalf("usage after a tools job = %+v", got)
+	}
+	w := create()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("tools job past the quota = %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
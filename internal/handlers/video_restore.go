@@ -152,12 +152,16 @@ func (h *VideoRestoreHandler) StartVideoRestore(c *gin.Context) {
 		"includeFrames":    req.IncludeFrames,
 	}
 	originalFile := models.OriginalFileInfo{Name: fileName, Size: objectSize, Type: contentType}
-	job := h.jobManager.CreateJob(originalFile, options)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "restore")
 	stages := h.restore.BuildStages(selected)
 	_ = h.jobManager.ReplaceStages(job.ID, stages, "queued")
 
-	go h.restore.Process(context.Background(), services.RestoreRequest{
+	restoreReq := services.RestoreRequest{
 		JobID:            job.ID,
 		S3Key:            key,
 		FileName:         fileName,
@@ -169,7 +173,8 @@ func (h *VideoRestoreHandler) StartVideoRestore(c *gin.Context) {
 		IncludeFrames:    req.IncludeFrames,
 		SessionID:        sessionID,
 		RequestID:        c.Writer.Header().Get("X-MM-Request-ID"),
-	})
+	}
+	h.jobManager.Dispatch(func() { h.restore.Process(context.Background(), restoreReq) })
 
 	c.JSON(http.StatusAccepted, models.RestoreStartResponse{JobID: job.ID})
 }
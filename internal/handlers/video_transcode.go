@@ -64,6 +64,10 @@ func (h *ConversionHandler) ProbeVideoForTranscode(c *gin.Context) {
 		return
 	}
 	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
 
 	report, err := services.ProbeVideoReport(ctx, tempPath)
 	if err != nil {
@@ -188,7 +192,11 @@ func (h *ConversionHandler) StartVideoTranscode(c *gin.Context) {
 	}
 
 	originalFile := models.OriginalFileInfo{Name: fileName, Size: size, Type: contentType}
-	job := h.jobManager.CreateJob(originalFile, options)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 	_ = h.jobManager.SetMode(job.ID, "transcode")
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
@@ -211,7 +219,7 @@ func (h *ConversionHandler) StartVideoTranscode(c *gin.Context) {
 		return
 	}
 
-	probe, probeErr := services.ProbeVideoReport(context.Background(), uploadPath)
+	probe, probeErr := services.ProbeVideoReport(ctx, uploadPath)
 	if probeErr != nil {
 		_ = h.jobManager.UpdateJobError(job.ID, "Failed to probe video: "+probeErr.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to probe uploaded video"})
@@ -257,7 +265,14 @@ func (h *ConversionHandler) StartVideoTranscode(c *gin.Context) {
 		Probe:               probe,
 		ResultBucket:        h.cfg.S3Bucket,
 	}
-	go h.transcode.Process(context.Background(), pipelineReq)
+	h.jobManager.Dispatch(func() {
+		if !h.scanInput(job, uploadPath) {
+			return
+		}
+		ctx, cancel := services.JobContext(h.cfg, job)
+		defer cancel()
+		h.transcode.Process(ctx, pipelineReq)
+	})
 
 	c.JSON(http.StatusOK, models.TranscodeStartResponse{
 		JobID:         job.ID,
@@ -17,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/middleware"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
 	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
 )
@@ -33,6 +34,10 @@ func (h *ConversionHandler) PresignVideoUpload(c *gin.Context) {
 		return
 	}
 
+	if err := checkUploadName(h.cfg, req.FileName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	fileName := safeFilename(req.FileName)
 	ext := sanitizeExtension(filepath.Ext(fileName))
 	if ext == "" || !isSupportedVideoExtension(ext) {
@@ -149,8 +154,50 @@ func (h *ConversionHandler) CompleteVideoUpload(c *gin.Context) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
+	delivery, err := services.ParseDeliveryEncryption(options)
+	if err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if delivery != nil {
+		options["deliveryEncryption"] = delivery.Redacted()
+	}
+	if t := middleware.Tenant(c); t != nil {
+		if err := t.Policy.Apply(fileType, options); err != nil {
+			_ = os.Remove(incomingPath)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := h.checkFormatPolicy(mimeType, fileName, options); err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(rejectionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	metadata, probeErr := h.inspector.ProbeFile(ctx, incomingPath, fileType)
+	if err := h.checkInputResolution(metadata); err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(rejectionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	owner := middleware.User(c)
+	cached, cacheKey := h.cachedJob(owner, incomingPath, options, delivery)
+	if cached != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(http.StatusOK, models.UploadResponse{JobID: cached.ID})
+		return
+	}
 	originalFile := models.OriginalFileInfo{Name: fileName, Size: objectSize, Type: mimeType}
-	job := h.jobManager.CreateJob(originalFile, options)
+	job, status, err := createClientJob(c, h.jobManager, h.cfg, originalFile, options)
+	if err != nil {
+		_ = os.Remove(incomingPath)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if cacheKey != "" {
+		_ = h.jobManager.SetCacheKey(job.ID, cacheKey)
+	}
 
 	jobUploadDir := filepath.Join(h.cfg.UploadDir, job.ID)
 	jobOutputDir := filepath.Join(h.cfg.OutputDir, job.ID)
@@ -175,7 +222,6 @@ func (h *ConversionHandler) CompleteVideoUpload(c *gin.Context) {
 		return
 	}
 
-	metadata, probeErr := h.inspector.ProbeFile(ctx, uploadPath, fileType)
 	if probeErr != nil {
 		log.Printf("metadata probe failed for S3 video job %s: %v", job.ID, probeErr)
 	}
@@ -186,10 +232,10 @@ func (h *ConversionHandler) CompleteVideoUpload(c *gin.Context) {
 		log.Printf("failed to write metadata for S3 video job %s: %v", job.ID, err)
 	}
 
-	if !isTranscribeMode(job) && specializedMode(job) == "" {
+	if !isTranscribeMode(job) && specializedMode(job) == "" && h.virusScanner == nil {
 		h.analysisJobs.Enqueue(services.AnalysisJob{JobID: job.ID, InputPath: uploadPath, OutputDir: jobOutputDir, FileType: fileType, MimeType: mimeType})
 	}
-	go h.processConversion(job, uploadPath, jobOutputDir)
+	h.jobManager.Dispatch(func() { h.processConversion(job, uploadPath, jobOutputDir, delivery) })
 
 	c.JSON(http.StatusOK, models.UploadResponse{JobID: job.ID})
 }
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// scanFailedError is recorded on jobs whose input clamd couldn't check.
+const scanFailedError = "The uploaded file could not be scanned for viruses"
+
+// SetVirusScanner turns on virus scanning of conversion inputs, tool
+// uploads and the files the analysis routes read. A nil scanner
+// (CLAMD_ADDRESS unset) processes files unscanned.
+func (h *ConversionHandler) SetVirusScanner(s *services.ClamdScanner) {
+	h.virusScanner = s
+}
+
+// virusScanError is why an uploaded file may not be processed: clamd found
+// threat in it, or couldn't check it when threat is "".
+type virusScanError struct {
+	threat string
+}
+
+func (e *virusScanError) Error() string {
+	if e.threat == "" {
+		return scanFailedError
+	}
+	return fmt.Sprintf("The uploaded file is infected (%s)", e.threat)
+}
+
+// status is the HTTP status for a request whose upload failed its scan.
+func (e *virusScanError) status() int {
+	if e.threat == "" {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// record ends job over e: infected, or failed so it can be retried.
+func (e *virusScanError) record(jm *services.JobManager, job *models.ConversionJob) {
+	if e.threat != "" {
+		_ = jm.MarkInfected(job.ID, e.threat)
+		return
+	}
+	_ = jm.UpdateJobError(job.ID, scanFailedError)
+}
+
+// scanFile checks path with scanner, deleting it when it is infected. A
+// scan that can't complete fails closed rather than letting an unchecked
+// file through.
+func scanFile(ctx context.Context, log *slog.Logger, scanner *services.ClamdScanner, path string) *virusScanError {
+	if scanner == nil {
+		return nil
+	}
+	threat, err := scanner.Scan(ctx, path)
+	if err != nil {
+		log.Error("virus scan failed", "error", err)
+		return &virusScanError{}
+	}
+	if threat == "" {
+		return nil
+	}
+	log.Warn("upload is infected; deleting it", "threat", threat)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Error("failed to delete infected upload", "error", err)
+	}
+	return &virusScanError{threat: threat}
+}
+
+// scanUpload checks a file a request uploaded before anything reads it, for
+// the routes that answer from the file or probe it before their job starts.
+func scanUpload(c *gin.Context, scanner *services.ClamdScanner, path string) *virusScanError {
+	return scanFile(c.Request.Context(), logger.WithRequest(c), scanner, path)
+}
+
+// scanJobInputs checks a job's input files before it processes them and
+// reports whether it may go ahead. An infected input is deleted and the job
+// is marked infected; a scan that can't complete fails the job, which can
+// be retried.
+func scanJobInputs(scanner *services.ClamdScanner, jm *services.JobManager, job *models.ConversionJob, paths ...string) bool {
+	for _, path := range paths {
+		if err := scanFile(context.Background(), jobLogger(job), scanner, path); err != nil {
+			err.record(jm, job)
+			return false
+		}
+	}
+	return true
+}
+
+// scanInput checks a job's input before it is converted and reports whether
+// the conversion may go ahead.
+func (h *ConversionHandler) scanInput(job *models.ConversionJob, inputPath string) bool {
+	return scanJobInputs(h.virusScanner, h.jobManager, job, inputPath)
+}
+
+// scanJobUploads is scanInput for every file in the job's upload
+// directory, where the tools keep their inputs.
+func (h *ConversionHandler) scanJobUploads(job *models.ConversionJob) bool {
+	if h.virusScanner == nil {
+		return true
+	}
+	var paths []string
+	_ = filepath.WalkDir(filepath.Join(h.cfg.UploadDir, job.ID), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return scanJobInputs(h.virusScanner, h.jobManager, job, paths...)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+func TestScanInputFailsClosed(t *testing.T) {
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm}
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+	input := filepath.Join(t.TempDir(), "original_clip.mp4")
+	_ = os.WriteFile(input, []byte("data"), 0o644)
+
+	if !h.scanInput(job, input) {
+		t.Fatal("without a scanner the conversion must go ahead")
+	}
+
+	h.SetVirusScanner(services.NewClamdScanner(filepath.Join(t.TempDir(), "missing.sock"), time.Second))
+	if h.scanInput(job, input) {
+		t.Fatal("an unreachable clamd must stop the conversion")
+	}
+	got, _ := jm.GetJob(job.ID)
+	if got.Status != models.StatusFailed || got.Error != scanFailedError {
+		t.Fatalf("job = %s %q", got.Status, got.Error)
+	}
+}
+
+// fakeClamd serves one scan on a unix socket, answering that the stream
+// is infected.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Read the whole stream before answering, as clamd does.
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil || (n >= 4 && string(buf[n-4:n]) == "\x00\x00\x00\x00") {
+				break
+			}
+		}
+		_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+	}()
+	return sock
+}
+
+func TestScanInputMarksInfected(t *testing.T) {
+	jm := services.NewJobManager()
+	h := &ConversionHandler{jobManager: jm}
+	h.SetVirusScanner(services.NewClamdScanner(fakeClamd(t), 5*time.Second))
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "eicar.com"}, nil)
+	input := filepath.Join(t.TempDir(), "original_eicar.com")
+	_ = os.WriteFile(input, []byte("X5O!P%@AP"), 0o644)
+
+	if h.scanInput(job, input) {
+		t.Fatal("an infected upload must not be converted")
+	}
+	got, _ := jm.GetJob(job.ID)
+	if got.Status != models.StatusInfected || got.Threat != "Eicar-Test-Signature" || !got.Status.Finished() {
+		t.Fatalf("job = %s %q", got.Status, got.Threat)
+	}
+	if _, err := os.Stat(input); !os.IsNotExist(err) {
+		t.Fatalf("infected upload was kept: %v", err)
+	}
+}
+
+func TestToolJobUploadsAreScanned(t *testing.T) {
+	jm := services.NewJobManager()
+	dir := t.TempDir()
+	h := &ConversionHandler{jobManager: jm, cfg: &config.Config{UploadDir: dir, OutputDir: dir}}
+	h.SetVirusScanner(services.NewClamdScanner(fakeClamd(t), 5*time.Second))
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "grid"}, nil)
+	input := filepath.Join(dir, job.ID, "00_eicar.mp4")
+	_ = os.MkdirAll(filepath.Dir(input), 0o755)
+	_ = os.WriteFile(input, []byte("X5O!P%@AP"), 0o644)
+
+	ran := make(chan struct{}, 1)
+	h.startToolJob(job, func() { ran <- struct{}{} })
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := jm.GetJob(job.ID)
+		if got.Status == models.StatusInfected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %s, want infected", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-ran:
+		t.Fatal("the tool ran on an infected upload")
+	default:
+	}
+	if _, err := os.Stat(input); !os.IsNotExist(err) {
+		t.Fatalf("infected upload was kept: %v", err)
+	}
+}
+
+func TestScanUploadRejectsInfected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "waveform_eicar.wav")
+	_ = os.WriteFile(path, []byte("X5O!P%@AP"), 0o644)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/waveform", nil)
+
+	if err := scanUpload(c, nil, path); err != nil {
+		t.Fatalf("without a scanner: %v", err)
+	}
+	err := scanUpload(c, services.NewClamdScanner(fakeClamd(t), 5*time.Second), path)
+	if err == nil || err.status() != http.StatusUnprocessableEntity || err.threat != "Eicar-Test-Signature" {
+		t.Fatalf("err = %+v", err)
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Fatalf("infected upload was kept: %v", statErr)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/services"
+)
+
+// Waveform renders the waveform of an uploaded audio or video file
+// synchronously: a PNG (format=png) or downsampled min/max peaks as JSON
+// (format=json, the default) for trim UIs. Nothing is kept after the
+// response, so there is no job to poll.
+func (h *ConversionHandler) Waveform(c *gin.Context) {
+	file, fileHeader, err := h.multipartFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	req := services.WaveformRequest{Format: c.Request.FormValue("format")}
+	for _, field := range []struct {
+		name string
+		dst  *int
+	}{
+		{"points", &req.Points},
+		{"width", &req.Image.Width},
+		{"height", &req.Image.Height},
+	} {
+		raw := strings.TrimSpace(c.Request.FormValue(field.name))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be an integer", field.name)})
+			return
+		}
+		*field.dst = v
+	}
+	req.Image.ColorPrimary = strings.TrimSpace(c.Request.FormValue("colorPrimary"))
+	req.Image.ColorSecondary = strings.TrimSpace(c.Request.FormValue("colorSecondary"))
+	req.Image.SplitChannels = c.Request.FormValue("splitChannels") == "true"
+	req.Image.Scale = c.Request.FormValue("scale")
+	req.Image.Draw = c.Request.FormValue("draw")
+	if err := services.ValidateWaveformRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tempPath := filepath.Join(h.cfg.TempDir, fmt.Sprintf("waveform_%d_%s", time.Now().UnixNano(), safeFilename(fileHeader.Filename)))
+	if err := h.saveUploadedFile(file, tempPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save temporary file"})
+		return
+	}
+	defer func() { _ = os.Remove(tempPath) }()
+	if scanErr := scanUpload(c, h.virusScanner, tempPath); scanErr != nil {
+		c.JSON(scanErr.status(), gin.H{"error": scanErr.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.CommandTimeout)
+	defer cancel()
+
+	if !h.inspector.HasAudioStream(ctx, tempPath) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file has no audio stream"})
+		return
+	}
+
+	if req.Format == "png" {
+		pngPath := tempPath + ".png"
+		defer func() { _ = os.Remove(pngPath) }()
+		if err := services.RenderWaveformPNG(ctx, tempPath, pngPath, &req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to render waveform: %v", err)})
+			return
+		}
+		c.Header("Content-Type", "image/png")
+		c.File(pngPath)
+		return
+	}
+
+	peaks, err := services.GenerateWaveformPeaks(ctx, tempPath, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to compute waveform: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, peaks)
+}
@@ -0,0 +1,242 @@
+// Package i18n translates the human-readable error and warning strings the
+// API returns (request validation, converter validation, FFmpeg error
+// diagnosis) into the language the client asks for via Accept-Language.
+//
+// Messages are still produced in English throughout the code base; the
+// catalog maps English templates to translations and Localize rewrites a
+// finished message at the edge. Anything not in the catalog is returned
+// unchanged, so an untranslated message degrades to English rather than
+// disappearing.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the language messages are authored in.
+const Default = "en"
+
+// GinKey is the gin context key the negotiated language is stored under.
+const GinKey = "mm.lang"
+
+// Supported lists the languages with a catalog, Default first.
+var Supported = []string{Default, "es", "fr", "de", "pt"}
+
+// entry is one English template and its translations. Templates use %s for
+// every placeholder regardless of the original verb; translations may
+// reorder them with %[n]s.
+type entry struct {
+	en, es, fr, de, pt string
+}
+
+func (e entry) in(lang string) string {
+	switch lang {
+	case "es":
+		return e.es
+	case "fr":
+		return e.fr
+	case "de":
+		return e.de
+	case "pt":
+		return e.pt
+	}
+	return ""
+}
+
+// catalog is matched in order, so specific templates must precede the
+// generic "%s must be ..." ones that would also match them.
+var catalog = []entry{
+	// Request handling.
+	{"Job ID is required", "Se requiere el ID del trabajo", "L'identifiant de la tâche est requis", "Auftrags-ID ist erforderlich", "O ID do trabalho é obrigatório"},
+	{"Job not found", "Trabajo no encontrado", "Tâche introuvable", "Auftrag nicht gefunden", "Trabalho não encontrado"},
+	{"Job not completed", "El trabajo no ha terminado", "La tâche n'est pas terminée", "Auftrag ist noch nicht abgeschlossen", "O trabalho não foi concluído"},
+	{"Converted file not found", "No se encontró el archivo convertido", "Fichier converti introuvable", "Konvertierte Datei nicht gefunden", "Arquivo convertido não encontrado"},
+	{"Diagnostic log not available", "El registro de diagnóstico no está disponible", "Journal de diagnostic indisponible", "Diagnoseprotokoll nicht verfügbar", "Registro de diagnóstico não disponível"},
+	{"Unsupported file type", "Tipo de archivo no compatible", "Type de fichier non pris en charge", "Nicht unterstützter Dateityp", "Tipo de arquivo não suportado"},
+	{"File content (%s) does not match its declared type (%s)", "El contenido del archivo (%s) no coincide con su tipo declarado (%s)", "Le contenu du fichier (%s) ne correspond pas à son type déclaré (%s)", "Der Dateiinhalt (%s) passt nicht zum angegebenen Typ (%s)", "O conteúdo do arquivo (%s) não corresponde ao tipo declarado (%s)"},
+	{"%s files are not accepted by this server.", "Este servidor no acepta archivos %s.", "Ce serveur n'accepte pas les fichiers %s.", "%s-Dateien werden von diesem Server nicht angenommen.", "Este servidor não aceita arquivos %s."},
+	{"Converting to %s is not allowed on this server.", "No se permite convertir a %s en este servidor.", "La conversion en %s n'est pas autorisée sur ce serveur.", "Die Konvertierung nach %s ist auf diesem Server nicht erlaubt.", "A conversão para %s não é permitida neste servidor."},
+	{"Requested size %s exceeds the limit of %s.", "El tamaño solicitado %s supera el límite de %s.", "La taille demandée %s dépasse la limite de %s.", "Die angeforderte Größe %s überschreitet das Limit von %s.", "O tamanho solicitado %s excede o limite de %s."},
+	{"Resolution %s exceeds the limit of %s.", "La resolución %s supera el límite de %s.", "La résolution %s dépasse la limite de %s.", "Die Auflösung %s überschreitet das Limit von %s.", "A resolução %s excede o limite de %s."},
+	{"The uploaded file is infected (%s)", "El archivo subido está infectado (%s)", "Le fichier envoyé est infecté (%s)", "Die hochgeladene Datei ist infiziert (%s)", "O arquivo enviado está infectado (%s)"},
+	{"The uploaded file could not be scanned for viruses", "No se pudo analizar el archivo subido en busca de virus", "Le fichier envoyé n'a pas pu être analysé contre les virus", "Die hochgeladene Datei konnte nicht auf Viren geprüft werden", "Não foi possível verificar o arquivo enviado contra vírus"},
+	{"Invalid request body", "Cuerpo de la solicitud no válido", "Corps de requête invalide", "Ungültiger Anfragetext", "Corpo da requisição inválido"},
+	{"failed to parse form (request may be too large)", "no se pudo leer el formulario (la solicitud puede ser demasiado grande)", "impossible de lire le formulaire (la requête est peut-être trop volumineuse)", "Formular konnte nicht gelesen werden (die Anfrage ist möglicherweise zu groß)", "não foi possível ler o formulário (a requisição pode ser grande demais)"},
+	{"failed to parse form (file may be too large)", "no se pudo leer el formulario (el archivo puede ser demasiado grande)", "impossible de lire le formulaire (le fichier est peut-être trop volumineux)", "Formular konnte nicht gelesen werden (die Datei ist möglicherweise zu groß)", "não foi possível ler o formulário (o arquivo pode ser grande demais)"},
+	{"Video exceeds maximum upload size", "El video supera el tamaño máximo de subida", "La vidéo dépasse la taille maximale autorisée", "Das Video überschreitet die maximale Upload-Größe", "O vídeo excede o tamanho máximo de envio"},
+	{"Failed to save file", "No se pudo guardar el archivo", "Impossible d'enregistrer le fichier", "Datei konnte nicht gespeichert werden", "Não foi possível salvar o arquivo"},
+	{"Failed to save temporary file", "No se pudo guardar el archivo temporal", "Impossible d'enregistrer le fichier temporaire", "Temporäre Datei konnte nicht gespeichert werden", "Não foi possível salvar o arquivo temporário"},
+	{"Failed to prepare upload", "No se pudo preparar la subida", "Impossible de préparer le téléversement", "Upload konnte nicht vorbereitet werden", "Não foi possível preparar o envio"},
+	{"Failed to prepare output", "No se pudo preparar la salida", "Impossible de préparer la sortie", "Ausgabe konnte nicht vorbereitet werden", "Não foi possível preparar a saída"},
+	{"Failed to finalize upload", "No se pudo completar la subida", "Impossible de finaliser le téléversement", "Upload konnte nicht abgeschlossen werden", "Não foi possível concluir o envio"},
+	{"Failed to create upload directory", "No se pudo crear el directorio de subida", "Impossible de créer le répertoire de téléversement", "Upload-Verzeichnis konnte nicht erstellt werden", "Não foi possível criar o diretório de envio"},
+	{"Failed to create output directory", "No se pudo crear el directorio de salida", "Impossible de créer le répertoire de sortie", "Ausgabeverzeichnis konnte nicht erstellt werden", "Não foi possível criar o diretório de saída"},
+	{"Failed to finalize uploaded file", "No se pudo completar el archivo subido", "Impossible de finaliser le fichier téléversé", "Hochgeladene Datei konnte nicht abgeschlossen werden", "Não foi possível concluir o arquivo enviado"},
+	{"Failed to encrypt output for delivery", "No se pudo cifrar el resultado para su entrega", "Impossible de chiffrer le résultat pour la livraison", "Ergebnis konnte für die Auslieferung nicht verschlüsselt werden", "Não foi possível criptografar o resultado para entrega"},
+	{"Transcription service is not available", "El servicio de transcripción no está disponible", "Le service de transcription n'est pas disponible", "Der Transkriptionsdienst ist nicht verfügbar", "O serviço de transcrição não está disponível"},
+	{"Transcription only supports video or audio files", "La transcripción solo admite archivos de video o audio", "La transcription ne prend en charge que les fichiers vidéo ou audio", "Die Transkription unterstützt nur Video- oder Audiodateien", "A transcrição só suporta arquivos de vídeo ou áudio"},
+	{"invalid file name", "nombre de archivo no válido", "nom de fichier invalide", "ungültiger Dateiname", "nome de arquivo inválido"},
+	{"file name must not contain path segments", "el nombre del archivo no debe contener segmentos de ruta", "le nom de fichier ne doit pas contenir de segments de chemin", "der Dateiname darf keine Pfadsegmente enthalten", "o nome do arquivo não deve conter segmentos de caminho"},
+	{`file name must not start with "-"`, `el nombre del archivo no debe empezar por "-"`, `le nom de fichier ne doit pas commencer par "-"`, `der Dateiname darf nicht mit "-" beginnen`, `o nome do arquivo não deve começar com "-"`},
+	{"file name must have an extension", "el nombre del archivo debe tener una extensión", "le nom de fichier doit avoir une extension", "der Dateiname muss eine Endung haben", "o nome do arquivo deve ter uma extensão"},
+	{"file extension .%s is not allowed", "la extensión de archivo .%s no está permitida", "l'extension de fichier .%s n'est pas autorisée", "die Dateiendung .%s ist nicht erlaubt", "a extensão de arquivo .%s não é permitida"},
+
+	// Wrappers the converter puts in front of a validation error; the
+	// remainder of the message is localized separately.
+	{"input validation failed", "la validación de la entrada falló", "échec de la validation de l'entrée", "Eingabeprüfung fehlgeschlagen", "falha na validação da entrada"},
+	{"invalid conversion options", "opciones de conversión no válidas", "options de conversion invalides", "ungültige Konvertierungsoptionen", "opções de conversão inválidas"},
+	{"invalid image options", "opciones de imagen no válidas", "options d'image invalides", "ungültige Bildoptionen", "opções de imagem inválidas"},
+	{"invalid video options", "opciones de video no válidas", "options vidéo invalides", "ungültige Videooptionen", "opções de vídeo inválidas"},
+	{"invalid audio options", "opciones de audio no válidas", "options audio invalides", "ungültige Audiooptionen", "opções de áudio inválidas"},
+
+	// Converter validation.
+	{"unsupported file type: %s", "tipo de archivo no compatible: %s", "type de fichier non pris en charge : %s", "nicht unterstützter Dateityp: %s", "tipo de arquivo não suportado: %s"},
+	{"unsupported format: %s", "formato no compatible: %s", "format non pris en charge : %s", "nicht unterstütztes Format: %s", "formato não suportado: %s"},
+	{"input file does not exist: %s", "el archivo de entrada no existe: %s", "le fichier d'entrée n'existe pas : %s", "Eingabedatei existiert nicht: %s", "o arquivo de entrada não existe: %s"},
+	{"input file is empty: %s", "el archivo de entrada está vacío: %s", "le fichier d'entrée est vide : %s", "Eingabedatei ist leer: %s", "o arquivo de entrada está vazio: %s"},
+	{"trim end time (%s) must be greater than start time (%s)", "el tiempo final del recorte (%s) debe ser mayor que el inicial (%s)", "la fin du découpage (%s) doit être postérieure au début (%s)", "das Schnittende (%s) muss nach dem Schnittanfang (%s) liegen", "o tempo final do corte (%s) deve ser maior que o inicial (%s)"},
+	{"trim duration must be at least 0.1 seconds, got %s", "el recorte debe durar al menos 0.1 segundos; se recibió %s", "le découpage doit durer au moins 0,1 seconde, reçu %s", "der Schnitt muss mindestens 0,1 Sekunden lang sein, erhalten: %s", "o corte deve durar pelo menos 0,1 segundo; recebido %s"},
+	{"%s too large (max %s), got %s", "%s demasiado grande (máx. %s); se recibió %s", "%s trop grand (max. %s), reçu %s", "%s zu groß (max. %s), erhalten: %s", "%s grande demais (máx. %s); recebido %s"},
+	{"%s must be between %s and %s, got %s", "%s debe estar entre %s y %s; se recibió %s", "%s doit être compris entre %s et %s, reçu %s", "%s muss zwischen %s und %s liegen, erhalten: %s", "%s deve estar entre %s e %s; recebido %s"},
+	{"%s must be non-negative, got %s", "%s no debe ser negativo; se recibió %s", "%s ne doit pas être négatif, reçu %s", "%s darf nicht negativ sein, erhalten: %s", "%s não deve ser negativo; recebido %s"},
+	{"%s must be positive, got %s", "%s debe ser positivo; se recibió %s", "%s doit être positif, reçu %s", "%s muss positiv sein, erhalten: %s", "%s deve ser positivo; recebido %s"},
+
+	// FFmpeg error diagnosis.
+	{"file not found error: input file '%s' may have been moved or deleted during processing", "archivo no encontrado: el archivo de entrada '%s' pudo haberse movido o eliminado durante el procesamiento", "fichier introuvable : le fichier d'entrée '%s' a peut-être été déplacé ou supprimé pendant le traitement", "Datei nicht gefunden: die Eingabedatei '%s' wurde während der Verarbeitung möglicherweise verschoben oder gelöscht", "arquivo não encontrado: o arquivo de entrada '%s' pode ter sido movido ou excluído durante o processamento"},
+	{"permission error: insufficient permissions to read '%s' or write to '%s'", "error de permisos: permisos insuficientes para leer '%s' o escribir en '%s'", "erreur de permission : droits insuffisants pour lire '%s' ou écrire dans '%s'", "Berechtigungsfehler: unzureichende Rechte, um '%s' zu lesen oder nach '%s' zu schreiben", "erro de permissão: permissões insuficientes para ler '%s' ou gravar em '%s'"},
+	{"corrupted file: the input file '%s' appears to be corrupted or in an unsupported format", "archivo dañado: el archivo de entrada '%s' parece estar dañado o en un formato no compatible", "fichier corrompu : le fichier d'entrée '%s' semble corrompu ou dans un format non pris en charge", "beschädigte Datei: die Eingabedatei '%s' scheint beschädigt zu sein oder hat ein nicht unterstütztes Format", "arquivo corrompido: o arquivo de entrada '%s' parece estar corrompido ou em um formato não suportado"},
+	{"codec not supported: FFmpeg cannot decode the input file format. Please try a different input file", "códec no compatible: FFmpeg no puede decodificar el formato del archivo de entrada. Pruebe con otro archivo", "codec non pris en charge : FFmpeg ne peut pas décoder le format du fichier d'entrée. Essayez un autre fichier", "Codec nicht unterstützt: FFmpeg kann das Format der Eingabedatei nicht dekodieren. Bitte eine andere Datei versuchen", "codec não suportado: o FFmpeg não consegue decodificar o formato do arquivo de entrada. Tente outro arquivo"},
+	{"output format not supported: FFmpeg cannot encode to the requested output format", "formato de salida no compatible: FFmpeg no puede codificar al formato solicitado", "format de sortie non pris en charge : FFmpeg ne peut pas encoder dans le format demandé", "Ausgabeformat nicht unterstützt: FFmpeg kann nicht in das gewünschte Format kodieren", "formato de saída não suportado: o FFmpeg não consegue codificar no formato solicitado"},
+	{"FFmpeg general error (exit code 1): %s. This usually indicates invalid parameters or unsupported codec. Command: ffmpeg %s", "error general de FFmpeg (código de salida 1): %s. Suele indicar parámetros no válidos o un códec no compatible. Comando: ffmpeg %s", "erreur générale FFmpeg (code de sortie 1) : %s. Cela indique généralement des paramètres invalides ou un codec non pris en charge. Commande : ffmpeg %s", "allgemeiner FFmpeg-Fehler (Exit-Code 1): %s. Das deutet meist auf ungültige Parameter oder einen nicht unterstützten Codec hin. Befehl: ffmpeg %s", "erro geral do FFmpeg (código de saída 1): %s. Geralmente indica parâmetros inválidos ou codec não suportado. Comando: ffmpeg %s"},
+	{"FFmpeg parameter/data error (exit code 8): %s. This usually indicates invalid filter parameters, corrupted input data, or incompatible format conversion. Command: ffmpeg %s", "error de parámetros/datos de FFmpeg (código de salida 8): %s. Suele indicar parámetros de filtro no válidos, datos de entrada dañados o una conversión de formato incompatible. Comando: ffmpeg %s", "erreur de paramètres/données FFmpeg (code de sortie 8) : %s. Cela indique généralement des paramètres de filtre invalides, des données d'entrée corrompues ou une conversion de format incompatible. Commande : ffmpeg %s", "FFmpeg-Parameter-/Datenfehler (Exit-Code 8): %s. Das deutet meist auf ungültige Filterparameter, beschädigte Eingabedaten oder eine inkompatible Formatkonvertierung hin. Befehl: ffmpeg %s", "erro de parâmetros/dados do FFmpeg (código de saída 8): %s. Geralmente indica parâmetros de filtro inválidos, dados de entrada corrompidos ou conversão de formato incompatível. Comando: ffmpeg %s"},
+	{"FFmpeg parameter/data error (exit code 8): %s. Possible issues: %s. Command: ffmpeg %s", "error de parámetros/datos de FFmpeg (código de salida 8): %s. Posibles causas: %s. Comando: ffmpeg %s", "erreur de paramètres/données FFmpeg (code de sortie 8) : %s. Causes possibles : %s. Commande : ffmpeg %s", "FFmpeg-Parameter-/Datenfehler (Exit-Code 8): %s. Mögliche Ursachen: %s. Befehl: ffmpeg %s", "erro de parâmetros/dados do FFmpeg (código de saída 8): %s. Possíveis causas: %s. Comando: ffmpeg %s"},
+	{"FFmpeg conversion failed (exit code %s): %s. Command: ffmpeg %s", "la conversión con FFmpeg falló (código de salida %s): %s. Comando: ffmpeg %s", "échec de la conversion FFmpeg (code de sortie %s) : %s. Commande : ffmpeg %s", "FFmpeg-Konvertierung fehlgeschlagen (Exit-Code %s): %s. Befehl: ffmpeg %s", "a conversão com FFmpeg falhou (código de saída %s): %s. Comando: ffmpeg %s"},
+}
+
+var (
+	compileOnce sync.Once
+	patterns    []*regexp.Regexp
+)
+
+// compile turns each English template into an anchored, case-insensitive
+// pattern with one capture per placeholder. Handlers are inconsistent about
+// capitalising the first word ("Failed to ..." vs "failed to ..."), hence
+// the (?i).
+func compile() {
+	patterns = make([]*regexp.Regexp, len(catalog))
+	for i, e := range catalog {
+		parts := strings.Split(e.en, "%s")
+		for j := range parts {
+			parts[j] = regexp.QuoteMeta(parts[j])
+		}
+		patterns[i] = regexp.MustCompile(`(?is)^` + strings.Join(parts, "(.+?)") + `$`)
+	}
+}
+
+// Localize returns msg in lang, or msg unchanged when lang is the default,
+// unsupported, or the message has no catalog entry. A message that only
+// matches after its "prefix: " wrappers are split off is translated piece
+// by piece, so "invalid video options: brightness must be ..." comes out
+// fully translated.
+func Localize(lang, msg string) string {
+	if lang == "" || lang == Default || msg == "" {
+		return msg
+	}
+	head, tail, found := strings.Cut(msg, ": ")
+	// Known wrappers are split off first; otherwise the generic "%s must
+	// be ..." templates would swallow them into their first placeholder.
+	if found && isWrapper(head) {
+		out, _ := lookup(lang, head)
+		return out + ": " + Localize(lang, tail)
+	}
+	if out, ok := lookup(lang, msg); ok {
+		return out
+	}
+	if !found {
+		return msg
+	}
+	if out, ok := lookup(lang, head); ok {
+		head = out
+	}
+	return head + ": " + Localize(lang, tail)
+}
+
+// isWrapper reports whether s is a placeholder-free catalog entry, i.e. a
+// fixed phrase that may prefix another message.
+func isWrapper(s string) bool {
+	for _, e := range catalog {
+		if !strings.Contains(e.en, "%s") && strings.EqualFold(e.en, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookup(lang, msg string) (string, bool) {
+	compileOnce.Do(compile)
+	for i, re := range patterns {
+		m := re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		tmpl := catalog[i].in(lang)
+		if tmpl == "" {
+			return "", false
+		}
+		args := make([]interface{}, len(m)-1)
+		for j, s := range m[1:] {
+			args[j] = s
+		}
+		return fmt.Sprintf(tmpl, args...), true
+	}
+	return "", false
+}
+
+// Negotiate picks the best supported language for an Accept-Language
+// header, honouring q-values and falling back from a regional tag
+// ("pt-BR") to its base language. It returns Default when nothing matches.
+func Negotiate(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		candidates = append(candidates, candidate{lang: base, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if c.lang == "*" {
+			return Default
+		}
+		for _, s := range Supported {
+			if c.lang == s {
+				return s
+			}
+		}
+	}
+	return Default
+}
@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                           "en",
+		"es":                         "es",
+		"pt-BR,pt;q=0.9,en;q=0.8":    "pt",
+		"ja,fr;q=0.5":                "fr",
+		"en;q=0.2,de-CH;q=0.9":       "de",
+		"fr;q=0,es;q=0.1":            "es",
+		"ja, zh-CN":                  "en",
+		"*":                          "en",
+		"DE-de ; q=1.0, en ; q=0.5 ": "de",
+	}
+	for header, want := range cases {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	cases := []struct {
+		lang, msg, want string
+	}{
+		{"en", "Job not found", "Job not found"},
+		{"es", "Job not found", "Trabajo no encontrado"},
+		{"de", "failed to prepare upload", "Upload konnte nicht vorbereitet werden"},
+		{"fr", "unsupported format: xyz", "format non pris en charge : xyz"},
+		{"es", "invalid video options: brightness must be between -100 and 100, got 150",
+			"opciones de video no válidas: brightness debe estar entre -100 y 100; se recibió 150"},
+		{"pt", "input validation failed: input file is empty: /tmp/a.mp4",
+			"falha na validação da entrada: o arquivo de entrada está vazio: /tmp/a.mp4"},
+		{"es", "clip.exe: file extension .exe is not allowed", "clip.exe: la extensión de archivo .exe no está permitida"},
+		{"fr", "something nobody translated", "something nobody translated"},
+		{"xx", "Job not found", "Job not found"},
+	}
+	for _, tc := range cases {
+		if got := Localize(tc.lang, tc.msg); got != tc.want {
+			t.Errorf("Localize(%q, %q) = %q, want %q", tc.lang, tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestLocalizeKeepsFFmpegDetail(t *testing.T) {
+	msg := "FFmpeg conversion failed (exit code 187): Invalid data found: when processing input. Command: ffmpeg -i in.mp4 out.webm"
+	got := Localize("de", msg)
+	if !strings.HasPrefix(got, "FFmpeg-Konvertierung fehlgeschlagen (Exit-Code 187)") ||
+		!strings.Contains(got, "Invalid data found: when processing input") ||
+		!strings.HasSuffix(got, "Befehl: ffmpeg -i in.mp4 out.webm") {
+		t.Fatalf("unexpected translation: %q", got)
+	}
+}
+
+func TestCatalogComplete(t *testing.T) {
+	for _, e := range catalog {
+		n := strings.Count(e.en, "%s")
+		for _, lang := range Supported[1:] {
+			tr := e.in(lang)
+			if tr == "" {
+				t.Errorf("%q has no %s translation", e.en, lang)
+				continue
+			}
+			if got := strings.Count(tr, "%s"); got != n {
+				t.Errorf("%q (%s) has %d placeholders, want %d", e.en, lang, got, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package limits
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Buckets is an in-process token bucket per client. Unlike the Redis
+// windows above it needs no backing store, so per-client limits hold on a
+// single node even when RATE_LIMIT_ENABLED is off or Redis is down.
+type Buckets struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket remembers the rate and burst it was last taken with, so the sweep
+// judges each bucket by its own client's limits.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  int
+}
+
+// bucketSweepInterval is how often buckets that have refilled completely
+// are dropped, so one-off clients don't accumulate.
+const bucketSweepInterval = time.Minute
+
+// NewBuckets returns an empty set of buckets.
+func NewBuckets() *Buckets {
+	return &Buckets{buckets: map[string]*bucket{}}
+}
+
+// Take spends one token from key's bucket, which holds burst tokens and
+// refills at rate per second. Remaining is what is left after this request,
+// RetryAfterSeconds is how long until the next token when the request is
+// refused, and ResetSeconds is how long until the bucket is full again.
+func (b *Buckets) Take(key string, rate float64, burst int, now time.Time) Decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.lastSweep) >= bucketSweepInterval {
+		b.sweep(now)
+	}
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: float64(burst), last: now}
+		b.buckets[key] = bk
+	}
+	if elapsed := now.Sub(bk.last).Seconds(); elapsed > 0 {
+		bk.tokens = math.Min(float64(burst), bk.tokens+elapsed*rate)
+	}
+	bk.last, bk.rate, bk.burst = now, rate, burst
+	d := Decision{LimitCount: burst}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfterSeconds = int(math.Ceil((1 - bk.tokens) / rate))
+	}
+	d.Remaining = int(bk.tokens)
+	d.ResetSeconds = int(math.Ceil((float64(burst) - bk.tokens) / rate))
+	return d
+}
+
+// sweep drops the buckets that would be full by now; a fresh bucket behaves
+// the same.
+func (b *Buckets) sweep(now time.Time) {
+	b.lastSweep = now
+	for key, bk := range b.buckets {
+		if bk.tokens+now.Sub(bk.last).Seconds()*bk.rate >= float64(bk.burst) {
+			delete(b.buckets, key)
+		}
+	}
+}
+
+// Len reports how many buckets are held.
+func (b *Buckets) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buckets)
+}
@@ -0,0 +1,53 @@
+package limits
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsTake(t *testing.T) {
+	b := NewBuckets()
+	now := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 3; i++ {
+		if d := b.Take("ip:1.2.3.4", 1, 3, now); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("take %d = %+v", i, d)
+		}
+	}
+	d := b.Take("ip:1.2.3.4", 1, 3, now)
+	if d.Allowed || d.RetryAfterSeconds != 1 || d.ResetSeconds != 3 {
+		t.Fatalf("empty bucket = %+v", d)
+	}
+	if d := b.Take("ip:5.6.7.8", 1, 3, now); !d.Allowed {
+		t.Fatal("buckets must be per key")
+	}
+	// Half a second refills half a token: still refused.
+	if d := b.Take("ip:1.2.3.4", 1, 3, now.Add(500*time.Millisecond)); d.Allowed {
+		t.Fatalf("partial refill = %+v", d)
+	}
+	if d := b.Take("ip:1.2.3.4", 1, 3, now.Add(1500*time.Millisecond)); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("after refill = %+v", d)
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	b := NewBuckets()
+	now := time.Unix(1_700_000_000, 0)
+	b.Take("ip:1.2.3.4", 10, 10, now)
+	b.Take("ip:5.6.7.8", 10, 10, now)
+	b.Take("ip:9.9.9.9", 10, 10, now.Add(2*time.Minute))
+	if n := b.Len(); n != 1 {
+		t.Fatalf("Len after sweep = %d, want 1", n)
+	}
+}
+
+func TestBucketsSweepKeepsSlowTenantBuckets(t *testing.T) {
+	b := NewBuckets()
+	now := time.Unix(1_700_000_000, 0)
+	// A tenant refilling one token every ~17 minutes is far from full two
+	// minutes later, whatever rate the request that triggers the sweep has.
+	b.Take("key:acme", 0.001, 100, now)
+	b.Take("ip:9.9.9.9", 10, 10, now.Add(2*time.Minute))
+	if n := b.Len(); n != 2 {
+		t.Fatalf("Len after sweep = %d, want the tenant's bucket kept", n)
+	}
+}
@@ -7,6 +7,9 @@
 //  2. Fixed-window counters per (session|ip, route, hour) for the
 //     session/IP-per-hour upload/transcode/analysis limits.
 //
+// Buckets adds an in-process token bucket per client (API key or IP) that
+// works without Redis.
+//
 // Keys never contain the raw IP — we hash IPs first so a Redis dump doesn't
 // leak end-user addresses. Raw IPs are still recorded in Postgres
 // `mm_rate_limit_events.ip` for blocked requests (subject to standard audit
@@ -51,8 +54,11 @@ type Decision struct {
 	LimitCount        int
 	Remaining         int
 	RetryAfterSeconds int
-	Scope             string
-	KeyHash           string
+	// ResetSeconds is how long until the limit is back at LimitCount. Only
+	// the in-process token buckets set it.
+	ResetSeconds int
+	Scope        string
+	KeyHash      string
 }
 
 // New constructs a Limiter. When rdb is nil all decisions Allow=true.
@@ -206,6 +206,28 @@ func ContextFromGin(c *gin.Context) context.Context {
 	return ctx
 }
 
+// RequestID returns the request ID RequestContext assigned to c, or "".
+func RequestID(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if v, ok := c.Get(GinKey); ok {
+		if f, ok := v.(*Fields); ok && f != nil {
+			return f.RequestID
+		}
+	}
+	return ""
+}
+
+// WithRequestID returns a child context carrying the request ID, for work
+// that outlives the request it came from.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, CtxRequestID, requestID)
+}
+
 // WithJob returns a child context carrying the job ID.
 func WithJob(ctx context.Context, jobID string) context.Context {
 	if jobID == "" {
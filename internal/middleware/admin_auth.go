@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operator auth for /api/admin/*. These endpoints (self-test, stats) are for
+// whoever runs the deployment, not end users, so a single shared bearer
+// token from ADMIN_API_TOKEN is enough. With no token configured the group
+// answers 404 — the admin surface simply doesn't exist rather than being
+// open.
+
+// RequireAdminToken gates a route group behind "Authorization: Bearer
+// <token>". The comparison is constant-time so the token can't be probed
+// byte by byte.
+func RequireAdminToken(token string) gin.HandlerFunc {
+	expected := []byte(strings.TrimSpace(token))
+	return func(c *gin.Context) {
+		if len(expected) == 0 {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		presented := bearerToken(c)
+		if presented == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or
+// "".
+func bearerToken(c *gin.Context) string {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/tenant"
+)
+
+func TestAPIKeyRestrictedWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg, err := tenant.Parse([]byte(`{"tenants":[{"name":"acme","keys":["acme-test-key-0001"],"policy":{"allowedFormats":["mp4"]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(APIKey(reg))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST("/api/tus", ok)
+	r.HEAD("/api/tus/:uploadId", ok)
+	r.PATCH("/api/tus/:uploadId", ok)
+	r.POST("/api/tools/audio-join", ok)
+	r.POST("/api/tools/url-import", ok)
+	r.POST("/api/video-upload/complete", ok)
+	r.DELETE("/api/job/:jobId", ok)
+	r.DELETE("/api/job/:jobId/files", ok)
+	r.POST("/api/job/:jobId/retry", ok)
+	do := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", "acme-test-key-0001")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, step := range []struct{ method, path string }{
+		{http.MethodPost, "/api/tus"},
+		{http.MethodHead, "/api/tus/abc123"},
+		{http.MethodPatch, "/api/tus/abc123"},
+		{http.MethodPost, "/api/tools/url-import"},
+		{http.MethodPost, "/api/video-upload/complete"},
+		{http.MethodDelete, "/api/job/job-1"},
+		{http.MethodDelete, "/api/job/job-1/files"},
+		{http.MethodPost, "/api/job/job-1/retry"},
+	} {
+		if code := do(step.method, step.path); code != http.StatusNoContent {
+			t.Errorf("%s %s = %d, want it let through", step.method, step.path, code)
+		}
+	}
+	if code := do(http.MethodPost, "/api/tools/audio-join"); code != http.StatusForbidden {
+		t.Errorf("tools endpoint = %d, want 403 for a restricted key", code)
+	}
+}
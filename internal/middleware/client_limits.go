@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/geo"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/limits"
+)
+
+// ClientKey identifies the caller for per-client limits: the tenant of its
+// API key, so every key of a tenant shares one budget, or else its IP. It
+// is empty when neither is known. Must run after APIKey.
+func ClientKey(c *gin.Context) string {
+	if t := Tenant(c); t != nil {
+		return "key:" + t.Name
+	}
+	if ip := geo.ExtractIP(c); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// ClientRateLimit spends a token from the caller's bucket on every request,
+// refilled at rps per second up to burst; a tenant policy can set its own
+// rate and burst. The bucket state goes out as X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (seconds until full), and an
+// empty bucket answers 429 with Retry-After. rps <= 0 without a tenant
+// override lets the request through untouched. Must run after APIKey.
+func ClientRateLimit(buckets *limits.Buckets, rps float64, burst int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rate, size := rps, burst
+		if t := Tenant(c); t != nil {
+			if t.Policy.RequestsPerSecond > 0 {
+				rate = t.Policy.RequestsPerSecond
+			}
+			if t.Policy.Burst > 0 {
+				size = t.Policy.Burst
+			}
+		}
+		key := ClientKey(c)
+		if rate <= 0 || size <= 0 || key == "" {
+			c.Next()
+			return
+		}
+		d := buckets.Take(key, rate, size, time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(d.LimitCount))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(d.ResetSeconds))
+		if !d.Allowed {
+			retryAfter := max(1, d.RetryAfterSeconds)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":             "Too many requests. Please slow down and try again shortly.",
+				"retryAfterSeconds": retryAfter,
+				"scope":             "client",
+			})
+			return
+		}
+		c.Next()
+	}
+}
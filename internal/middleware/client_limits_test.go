@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/limits"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/tenant"
+)
+
+func TestClientRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg, err := tenant.Parse([]byte(`{"tenants":[{"name":"acme","keys":["acme-test-key-0001"],"policy":{"requestsPerSecond":1,"burst":3}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(APIKey(reg), ClientRateLimit(limits.NewBuckets(), 0.5, 1))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("203.0.113.7", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("first anonymous request: %d %v", w.Code, w.Header())
+	}
+	w := get("203.0.113.7", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("second anonymous request: %d Retry-After=%q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// The tenant's own burst applies, shared across its IPs.
+	for i, ip := range []string{"203.0.113.7", "198.51.100.1", "198.51.100.2"} {
+		if w := get(ip, "acme-test-key-0001"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "3" {
+			t.Fatalf("tenant request %d: %d %v", i, w.Code, w.Header())
+		}
+	}
+	if w := get("198.51.100.3", "acme-test-key-0001"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("tenant over burst: %d", w.Code)
+	}
+}
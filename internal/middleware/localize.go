@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/i18n"
+)
+
+// Localize negotiates the response language from Accept-Language, stores it
+// under i18n.GinKey for handlers that localize 200 payloads (job status and
+// events), and rewrites the "error"/"warning"/"warnings" fields of JSON
+// error responses. English requests pass straight through.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(i18n.GinKey, lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Writer.Header().Set("Content-Language", lang)
+		if lang == i18n.Default {
+			c.Next()
+			return
+		}
+		w := &localizingWriter{ResponseWriter: c.Writer, lang: lang}
+		c.Writer = w
+		c.Next()
+		w.flush()
+	}
+}
+
+// localizingWriter buffers JSON bodies of >= 400 responses so their
+// messages can be translated before they go out. Everything else (downloads,
+// SSE streams, successful JSON) is written through untouched.
+type localizingWriter struct {
+	gin.ResponseWriter
+	lang      string
+	buf       bytes.Buffer
+	buffering bool
+}
+
+func (w *localizingWriter) capture() bool {
+	if w.buffering {
+		return true
+	}
+	if w.ResponseWriter.Status() < 400 || w.ResponseWriter.Written() {
+		return false
+	}
+	if !strings.HasPrefix(w.ResponseWriter.Header().Get("Content-Type"), "application/json") {
+		return false
+	}
+	w.buffering = true
+	return true
+}
+
+func (w *localizingWriter) Write(b []byte) (int, error) {
+	if w.capture() {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *localizingWriter) WriteString(s string) (int, error) {
+	if w.capture() {
+		return w.buf.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *localizingWriter) flush() {
+	if !w.buffering {
+		return
+	}
+	body := w.buf.Bytes()
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil && localizePayload(w.lang, payload) {
+		if out, err := json.Marshal(payload); err == nil {
+			body = out
+		}
+	}
+	w.Header().Del("Content-Length")
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// localizePayload translates the message fields in place and reports
+// whether anything changed.
+func localizePayload(lang string, payload map[string]interface{}) bool {
+	changed := false
+	for _, key := range []string{"error", "warning", "message"} {
+		if s, ok := payload[key].(string); ok {
+			if t := i18n.Localize(lang, s); t != s {
+				payload[key] = t
+				changed = true
+			}
+		}
+	}
+	if list, ok := payload["warnings"].([]interface{}); ok {
+		for i, v := range list {
+			if s, ok := v.(string); ok {
+				if t := i18n.Localize(lang, s); t != s {
+					list[i] = t
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// Language returns the language Localize negotiated for c.
+func Language(c *gin.Context) string {
+	if lang := c.GetString(i18n.GinKey); lang != "" {
+		return lang
+	}
+	return i18n.Default
+}
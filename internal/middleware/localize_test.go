@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLocalizeRewritesErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Localize())
+	r.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found", "jobId": "abc"})
+	})
+	r.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"error": "Job not found"})
+	})
+
+	cases := []struct {
+		path, lang, want string
+	}{
+		{"/missing", "es-ES,es;q=0.9", "Trabajo no encontrado"},
+		{"/missing", "", "Job not found"},
+		{"/ok", "es", "Job not found"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.lang != "" {
+			req.Header.Set("Accept-Language", tc.lang)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s (%q): invalid JSON %q: %v", tc.path, tc.lang, rec.Body.String(), err)
+		}
+		if body["error"] != tc.want {
+			t.Errorf("%s (%q): error = %v, want %q", tc.path, tc.lang, body["error"], tc.want)
+		}
+		if tc.path == "/missing" && body["jobId"] != "abc" {
+			t.Errorf("%s (%q): other fields were dropped: %v", tc.path, tc.lang, body)
+		}
+	}
+}
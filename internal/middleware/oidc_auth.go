@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OIDC bearer tokens for multi-user deployments. When OIDC_ISSUER is set,
+// the conversion, tool, studio and document-scan routes accept
+// "Authorization: Bearer <JWT>" signed by a key from the issuer's JWKS, as
+// an alternative to X-API-Key. The token's subject claim becomes the owner
+// of the jobs the request creates. Requests without a bearer token stay
+// anonymous unless OIDC_REQUIRED is on. The admin, restore and /api/dr
+// groups keep their own bearer schemes and never see this middleware.
+
+// UserContextKey is the gin context key BearerUser stores the verified
+// subject under; read it with User.
+const UserContextKey = "mm.user"
+
+// oidcSigningMethods are the algorithms a token may be signed with. HMAC is
+// left out: a JWKS only publishes public keys.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCVerifier checks JWTs from one issuer.
+type OIDCVerifier struct {
+	issuer       string
+	audience     string
+	subjectClaim string
+	keys         jwt.Keyfunc
+}
+
+// NewOIDCVerifier fetches the issuer's signing keys and keeps them
+// refreshed in the background. jwksURL defaults to the jwks_uri of the
+// issuer's /.well-known/openid-configuration. An empty audience skips the
+// aud check; subjectClaim defaults to "sub".
+func NewOIDCVerifier(ctx context.Context, issuer, jwksURL, audience, subjectClaim string, logger *slog.Logger) (*OIDCVerifier, error) {
+	issuer = strings.TrimRight(strings.TrimSpace(issuer), "/")
+	if issuer == "" {
+		return nil, errors.New("no issuer configured")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if jwksURL == "" {
+		discovered, err := discoverJWKSURL(ctx, issuer)
+		if err != nil {
+			return nil, err
+		}
+		jwksURL = discovered
+	}
+	jwks, err := keyfunc.Get(jwksURL, keyfunc.Options{
+		Ctx:               ctx,
+		RefreshInterval:   time.Hour,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			logger.Warn("oidc jwks refresh failed", "url", jwksURL, "error", err.Error())
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS from %s: %w", jwksURL, err)
+	}
+	return newOIDCVerifier(issuer, audience, subjectClaim, jwks.Keyfunc), nil
+}
+
+func newOIDCVerifier(issuer, audience, subjectClaim string, keys jwt.Keyfunc) *OIDCVerifier {
+	if strings.TrimSpace(subjectClaim) == "" {
+		subjectClaim = "sub"
+	}
+	return &OIDCVerifier{
+		issuer:       strings.TrimRight(strings.TrimSpace(issuer), "/"),
+		audience:     strings.TrimSpace(audience),
+		subjectClaim: strings.TrimSpace(subjectClaim),
+		keys:         keys,
+	}
+}
+
+// discoverJWKSURL reads jwks_uri from the issuer's discovery document.
+func discoverJWKSURL(ctx context.Context, issuer string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC discovery document returned %s", resp.Status)
+	}
+	var doc struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("OIDC discovery document has no jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
+
+// Subject verifies the token's signature, expiry, issuer and audience and
+// returns its subject claim.
+func (v *OIDCVerifier) Subject(raw string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, v.keys, jwt.WithValidMethods(oidcSigningMethods)); err != nil {
+		return "", err
+	}
+	// Parsing only checks exp when the token has one; a token without it
+	// would never expire.
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return "", errors.New("token has no expiry")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != v.issuer {
+		return "", errors.New("token issuer mismatch")
+	}
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return "", errors.New("token audience mismatch")
+	}
+	subject, _ := claims[v.subjectClaim].(string)
+	if strings.TrimSpace(subject) == "" {
+		return "", fmt.Errorf("token has no %q claim", v.subjectClaim)
+	}
+	return subject, nil
+}
+
+// BearerUser authenticates "Authorization: Bearer <JWT>" with verifier and
+// records the subject for User. enabled=false passes everything through.
+// enabled=true with a nil verifier (init failed) answers bearer requests
+// with 503 rather than treating them as anonymous. With required set, a
+// request needs either a valid token or an X-API-Key tenant.
+func BearerUser(enabled bool, verifier *OIDCVerifier, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+		header := strings.TrimSpace(c.GetHeader("Authorization"))
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		token = strings.TrimSpace(token)
+		if !ok || token == "" {
+			if required && Tenant(c) == nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+				return
+			}
+			c.Next()
+			return
+		}
+		if verifier == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is unavailable"})
+			return
+		}
+		subject, err := verifier.Subject(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired credentials"})
+			return
+		}
+		c.Set(UserContextKey, subject)
+		c.Next()
+	}
+}
+
+// User returns the subject BearerUser verified, or "" for requests without
+// a bearer token.
+func User(c *gin.Context) string {
+	return c.GetString(UserContextKey)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -152,6 +153,37 @@ func AccessLog(store *telemetry.Store, enricher *geo.Enricher) gin.HandlerFunc {
 	}
 }
 
+// RequestLog writes one structured log line per request, in place of gin's
+// default logger. It logs the route pattern rather than the path and never
+// the headers or query string, which can carry credentials. Health checks
+// log at debug level and server errors at error level.
+func RequestLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case route == "/healthz" || route == "/api/health":
+			level = slog.LevelDebug
+		}
+		// The route comes from the Fields RequestContext attached.
+		attrs := []any{
+			"method", c.Request.Method,
+			"status", status,
+			"durationMs", time.Since(start).Milliseconds(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+		logger.WithRequest(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
 // SetTool annotates the current request with a tool/stage label so the
 // access log writes them out.
 func SetTool(c *gin.Context, tool, stage string) {
@@ -12,8 +12,16 @@ const (
 	StatusProcessing JobStatus = "processing"
 	StatusCompleted  JobStatus = "completed"
 	StatusFailed     JobStatus = "failed"
+	// StatusInfected marks a job whose upload the virus scanner flagged. It
+	// never ran and can't be retried.
+	StatusInfected JobStatus = "infected"
 )
 
+// Finished reports whether a job in status s is done for good.
+func (s JobStatus) Finished() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusInfected
+}
+
 type FileType string
 
 const (
@@ -34,6 +42,8 @@ type ConversionJob struct {
 	Options         map[string]interface{} `json:"options"`
 	CreatedAt       time.Time              `json:"createdAt"`
 	CompletedAt     *time.Time             `json:"completedAt,omitempty"`
+	// StartedAt is when the job last began processing.
+	StartedAt       *time.Time             `json:"startedAt,omitempty"`
 	Mode            string                 `json:"mode,omitempty"`
 	CurrentStage    string                 `json:"currentStage,omitempty"`
 	Stages          []TranscodeJobStage    `json:"stages,omitempty"`
@@ -41,9 +51,75 @@ type ConversionJob struct {
 	ResultFileName  string                 `json:"resultFileName,omitempty"`
 	// ResultSizeBytes is the size of the packaged result artifact (e.g. the
 	// restoration tarball). Optional — only pipelines that know the size set it.
-	ResultSizeBytes int64               `json:"resultSizeBytes,omitempty"`
-	ExpiresAt       *time.Time          `json:"expiresAt,omitempty"`
-	TranscodeReport *VideoProbeResponse `json:"transcodeReport,omitempty"`
+	ResultSizeBytes int64                 `json:"resultSizeBytes,omitempty"`
+	ExpiresAt       *time.Time            `json:"expiresAt,omitempty"`
+	TranscodeReport *VideoProbeResponse   `json:"transcodeReport,omitempty"`
+	QualityMetrics  *QualityMetricsResult `json:"qualityMetrics,omitempty"`
+	// GIFOptimization is set by the image "optimize" operation.
+	GIFOptimization *GIFOptimizationResult `json:"gifOptimization,omitempty"`
+	// Result describes the output file once the job has completed.
+	Result *JobResult `json:"result,omitempty"`
+	// Attempts records each run of the job, oldest first.
+	Attempts []JobAttempt `json:"attempts,omitempty"`
+	// CacheKey identifies the input and options when result caching is on,
+	// so identical uploads can reuse this job.
+	CacheKey string `json:"cacheKey,omitempty"`
+	// Owner is the OIDC subject of the user who created the job, empty for
+	// anonymous and API-key requests.
+	Owner string `json:"owner,omitempty"`
+	// Client is the API key tenant or IP the job counts against for
+	// MAX_CONCURRENT_JOBS_PER_CLIENT. It isn't served; the job store keeps
+	// it alongside the job.
+	Client string `json:"-"`
+	// RequestID is the X-MM-Request-ID of the request that created the job,
+	// so the job's processing logs can be tied back to it. It isn't served;
+	// the job store keeps it alongside the job.
+	RequestID string `json:"-"`
+	// Threat is the signature the virus scanner found in the upload of an
+	// infected job.
+	Threat string `json:"threat,omitempty"`
+}
+
+// Job attempt triggers.
+const (
+	AttemptInitial   = "initial"
+	AttemptAutomatic = "automatic"
+	AttemptManual    = "manual"
+	AttemptRecovery  = "recovery"
+)
+
+// JobAttempt is one run of a job. An attempt that failed with a transient
+// error and is about to be retried carries the time of the retry.
+type JobAttempt struct {
+	Number     int        `json:"number"`
+	Trigger    string     `json:"trigger"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	RetryAt    *time.Time `json:"retryAt,omitempty"`
+}
+
+// JobResult describes a completed job's output.
+type JobResult struct {
+	Metadata *ResultMetadata `json:"metadata,omitempty"`
+}
+
+// ResultMetadata is the probed output file, so clients don't have to
+// inspect the download themselves. Zero values mean the property is unknown
+// or doesn't apply (no duration for a still image).
+type ResultMetadata struct {
+	MimeType        string  `json:"mimeType,omitempty"`
+	Format          string  `json:"format,omitempty"`
+	SizeBytes       int64   `json:"sizeBytes"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	BitrateBps      int64   `json:"bitrateBps,omitempty"`
+	VideoCodec      string  `json:"videoCodec,omitempty"`
+	FrameRate       float64 `json:"frameRate,omitempty"`
+	AudioCodec      string  `json:"audioCodec,omitempty"`
+	Channels        int     `json:"channels,omitempty"`
+	SampleRate      int     `json:"sampleRate,omitempty"`
 }
 
 type OriginalFileInfo struct {
@@ -81,6 +157,11 @@ type VisualEffects struct {
 	UnsharpMask  *UnsharpMask `json:"unsharpMask,omitempty"`
 	Artistic     *string      `json:"artistic,omitempty"`
 	Noise        *NoiseEffect `json:"noise,omitempty"`
+	// Deblock smooths the 8x8 block edges of over-compressed sources and
+	// Deband dithers away stair-stepped gradients. Each takes a strength of
+	// "light", "medium" or "strong" ("none" or empty disables it).
+	Deblock *string `json:"deblock,omitempty"`
+	Deband  *string `json:"deband,omitempty"`
 }
 
 type MotionBlur struct {
@@ -105,6 +186,11 @@ type Transform struct {
 	FlipVertical   *bool     `json:"flipVertical,omitempty"`
 	Crop           *CropArea `json:"crop,omitempty"`
 	Padding        *Padding  `json:"padding,omitempty"`
+	// AspectRatio letterboxes/pillarboxes the frame out to a target ratio
+	// ("16:9", "9:16", "1:1", "4:5", "4:3", "21:9") without cropping.
+	// AspectColor is the #RRGGBB fill (default black).
+	AspectRatio string `json:"aspectRatio,omitempty"`
+	AspectColor string `json:"aspectColor,omitempty"`
 }
 
 type Padding struct {
@@ -143,6 +229,11 @@ type AdvancedProcessing struct {
 	Deinterlace *bool       `json:"deinterlace,omitempty"`
 	HDR         *HDRConfig  `json:"hdr,omitempty"`
 	ColorSpace  *ColorSpace `json:"colorSpace,omitempty"`
+	// Detelecine reverses 3:2 pulldown, turning telecined 29.97i (NTSC DVD
+	// film content) back into progressive 23.976p: "fieldmatch" (field
+	// matching + decimate, best for clean sources) or "pullup" (tolerates
+	// broken cadence from edits). Empty leaves the frames alone.
+	Detelecine string `json:"detelecine,omitempty"`
 }
 
 type HDRConfig struct {
@@ -166,10 +257,16 @@ type BasicProcessing struct {
 	Stereo       *StereoProcessing `json:"stereo,omitempty"`
 }
 
+// DynamicRange is a downward compressor. Threshold is in dBFS; Attack and
+// Release are in milliseconds and MakeupGain in dB (defaults 20 ms, 250 ms
+// and 0 dB).
 type DynamicRange struct {
-	Enabled   bool    `json:"enabled"`
-	Ratio     float64 `json:"ratio"`
-	Threshold float64 `json:"threshold"`
+	Enabled    bool     `json:"enabled"`
+	Ratio      float64  `json:"ratio"`
+	Threshold  float64  `json:"threshold"`
+	Attack     *float64 `json:"attack,omitempty"`
+	Release    *float64 `json:"release,omitempty"`
+	MakeupGain *float64 `json:"makeupGain,omitempty"`
 }
 
 type Equalizer struct {
@@ -297,10 +394,10 @@ type Spectral struct {
 
 // Image conversion options
 type ImageConversionOptions struct {
-	// Output container. Raster formats (jpg/png/webp/gif/avif) go through the
+	// Output container. Raster formats (jpg/png/webp/gif/avif/jxl) go through the
 	// ImageMagick pipeline; "pdf" routes to the document pathway; "svg" routes
 	// to potrace vectorization; "ico" routes to multi-size ICO generation.
-	Format         string               `json:"format" binding:"required,oneof=jpg png webp gif avif pdf svg ico"`
+	Format         string               `json:"format" binding:"required,oneof=jpg png webp gif avif jxl pdf svg ico"`
 	Width          *int                 `json:"width,omitempty"`
 	Height         *int                 `json:"height,omitempty"`
 	Quality        int                  `json:"quality" binding:"min=1,max=100"`
@@ -318,6 +415,130 @@ type ImageConversionOptions struct {
 	Vectorize *VectorizeOptions `json:"vectorize,omitempty"`
 	// ICO controls the multi-size .ico generation flow (Format=="ico").
 	ICO *ICOOptions `json:"ico,omitempty"`
+	// QualityTarget replaces the fixed Quality with a search for the
+	// smallest webp/avif/jpg encode within a perceptual distance.
+	QualityTarget *ImageQualityTarget `json:"qualityTarget,omitempty"`
+	// MaxWidth / MaxHeight shrink the result to fit, keeping the aspect
+	// ratio; smaller images are left alone. API-key policies set them.
+	MaxWidth  *int `json:"maxWidth,omitempty"`
+	MaxHeight *int `json:"maxHeight,omitempty"`
+	// ImageIndex picks the image of a multi-image HEIC/HEIF file (bursts,
+	// collections). 0, the default, is the first — normally the primary.
+	ImageIndex int `json:"imageIndex,omitempty"`
+	// JXL tunes JPEG XL output (Format=="jxl").
+	JXL *JXLOptions `json:"jxl,omitempty"`
+	// AutoOrient rotates the pixels upright from the EXIF orientation before
+	// anything else (default true). false keeps the stored pixel layout, and
+	// the orientation tag travels with the metadata instead.
+	AutoOrient *bool `json:"autoOrient,omitempty"`
+	// ColorProfile handles the embedded ICC profile: "preserve" (default)
+	// keeps it, "strip" removes it, and "srgb" converts the pixels to sRGB
+	// so CMYK and wide-gamut files display correctly on the web.
+	ColorProfile string `json:"colorProfile,omitempty"`
+	// Sizes renders one output per entry, each fit within a size x size box
+	// (longest edge, never enlarged), and packages them as a .zip.
+	Sizes []int `json:"sizes,omitempty"`
+	// Optimize shrinks GIF output with gifsicle after the ImageMagick chain.
+	Optimize *GIFOptimizeOptions `json:"optimize,omitempty"`
+	// Upscale enlarges the image 2x or 4x, with Real-ESRGAN when installed
+	// and a Lanczos resize otherwise.
+	Upscale int `json:"upscale,omitempty"`
+	// SmartCrop cuts to an aspect ratio and picks the window automatically.
+	// Mutually exclusive with Crop.
+	SmartCrop *SmartCropOptions `json:"smartCrop,omitempty"`
+	// Fit decides how Width and Height apply together: "fill" (default,
+	// exact size), "inside", "outside", "cover" or "contain". Background
+	// pads contain ("#rrggbb" or "transparent"), and WithoutEnlargement
+	// never scales a smaller image up.
+	Fit                string `json:"fit,omitempty"`
+	Background         string `json:"background,omitempty"`
+	WithoutEnlargement bool   `json:"withoutEnlargement,omitempty"`
+	// Padding adds a Background-coloured margin, BorderWidth/BorderColor a
+	// solid frame outside it, and CornerRadius rounds the result with
+	// transparent corners (Background-filled for JPEG). Sizes are pixels.
+	Padding      int    `json:"padding,omitempty"`
+	BorderWidth  int    `json:"borderWidth,omitempty"`
+	BorderColor  string `json:"borderColor,omitempty"`
+	CornerRadius int    `json:"cornerRadius,omitempty"`
+	// Filters applies several filters in order, after Filter, each at its
+	// own strength.
+	Filters []ImageFilterStep `json:"filters,omitempty"`
+	// TintStrength (0–100, default 30) and TintMode ("tint" default,
+	// "colorize", "multiply", "overlay") control how Tint is applied.
+	TintStrength *int   `json:"tintStrength,omitempty"`
+	TintMode     string `json:"tintMode,omitempty"`
+	// Rotation turns the image clockwise by any angle in degrees; corners
+	// opened up by non-right angles are filled with Background. The flips
+	// mirror it. Same fields as the video Transform.
+	Rotation       *float64 `json:"rotation,omitempty"`
+	FlipHorizontal *bool    `json:"flipHorizontal,omitempty"`
+	FlipVertical   *bool    `json:"flipVertical,omitempty"`
+	// Progressive writes a progressive JPEG or an Adam7-interlaced PNG, which
+	// browsers can show coarse-to-fine while downloading.
+	// ChromaSubsampling picks JPEG chroma sampling: "4:4:4", "4:2:2" or
+	// "4:2:0"; empty leaves ImageMagick's quality-based default.
+	Progressive       bool   `json:"progressive,omitempty"`
+	ChromaSubsampling string `json:"chromaSubsampling,omitempty"`
+	// BlurFaces detects faces and obscures them before any other step.
+	// FaceBlurMode is "blur" (default), "pixelate" or "blackbox".
+	BlurFaces    bool   `json:"blurFaces,omitempty"`
+	FaceBlurMode string `json:"faceBlurMode,omitempty"`
+}
+
+// ImageFilterStep is one entry of a filters chain. Name takes the same
+// values as Filter plus "vignette"; Strength runs 0–100 and defaults to 50,
+// which matches the single-filter look.
+type ImageFilterStep struct {
+	Name     string   `json:"name"`
+	Strength *float64 `json:"strength,omitempty"`
+}
+
+// SmartCropOptions selects the smart crop window. Mode is "center",
+// "gravity" (place by Gravity), "entropy" (most detail by histogram),
+// "attention" (strongest edges) or "face" (keep detected faces in frame,
+// attention when none are found). Empty Mode places by Gravity, centred
+// when that is empty too.
+type SmartCropOptions struct {
+	AspectRatio string `json:"aspectRatio"`
+	Mode        string `json:"mode,omitempty"`
+	Gravity     string `json:"gravity,omitempty"`
+}
+
+// GIFOptimizeOptions tunes the GIF "optimize" operation. Every field is
+// optional; frame differencing always runs.
+type GIFOptimizeOptions struct {
+	Colors *int     `json:"colors,omitempty"` // palette size, 2-256. Default keeps the palette.
+	Lossy  *int     `json:"lossy,omitempty"`  // gifsicle --lossy strength, 1-200. Default off.
+	FPS    *float64 `json:"fps,omitempty"`    // drop frames down to this rate, 1-50. Default keeps every frame.
+	Level  *int     `json:"level,omitempty"`  // gifsicle --optimize level 1-3. Default 3.
+}
+
+// GIFOptimizationResult reports what the GIF optimize operation saved.
+type GIFOptimizationResult struct {
+	OriginalBytes  int64   `json:"originalBytes"`
+	OptimizedBytes int64   `json:"optimizedBytes"`
+	SavedPercent   float64 `json:"savedPercent"`
+	OriginalFrames int     `json:"originalFrames"`
+	Frames         int     `json:"frames"`
+}
+
+// JXLOptions controls the libjxl encoder. Quality still comes from the
+// top-level Quality field unless Lossless is set.
+type JXLOptions struct {
+	// Effort trades encode time for size, 1 (fastest) to 9 (smallest);
+	// default 7.
+	Effort int `json:"effort,omitempty"`
+	// Lossless stores the pixels exactly, ignoring Quality.
+	Lossless bool `json:"lossless,omitempty"`
+}
+
+// ImageQualityTarget asks for the lowest encoder quality whose output stays
+// within MaxDistance of the processed image under Metric.
+type ImageQualityTarget struct {
+	Metric      string  `json:"metric,omitempty"`      // dssim (default) or butteraugli
+	MaxDistance float64 `json:"maxDistance,omitempty"` // default 0.0015 (dssim) / 1.0 (butteraugli)
+	MinQuality  int     `json:"minQuality,omitempty"`  // search floor, default 30
+	MaxQuality  int     `json:"maxQuality,omitempty"`  // search ceiling, default 95
 }
 
 // VectorizeOptions tunes the potrace-based raster -> SVG conversion. Threshold
@@ -516,6 +737,114 @@ type VideoConversionOptions struct {
 	Preset string `json:"preset,omitempty"`
 	// StripAudio drops the audio track entirely for a smaller file.
 	StripAudio bool `json:"stripAudio,omitempty"`
+	// AudioTracks keeps only these audio streams of a multi-track source, by
+	// zero-based audio index as listed in the /api/details audioTracks
+	// field, in output order. Empty keeps FFmpeg's default single track.
+	AudioTracks []int `json:"audioTracks,omitempty"`
+	// ContainerFlags selects the MP4/MOV muxer layout: "faststart" (default —
+	// moov atom up front so browsers can progressive-play) or "fragmented"
+	// (fMP4 for MSE/low-latency streaming). Ignored for other containers.
+	ContainerFlags string `json:"containerFlags,omitempty"`
+	// KeyframeIntervalSeconds forces a keyframe every N seconds, independent
+	// of the source frame rate. Streaming ingest (Twitch, YouTube Live, HLS
+	// packagers) usually demands 2. Optional.
+	KeyframeIntervalSeconds *float64 `json:"keyframeIntervalSeconds,omitempty"`
+	// BFrames caps consecutive B-frames (0 disables them). H.264/H.265 only.
+	BFrames *int `json:"bFrames,omitempty"`
+	// SceneCut toggles keyframes on scene changes. Set false together with
+	// KeyframeIntervalSeconds for a strictly fixed GOP. nil = encoder default.
+	SceneCut *bool `json:"sceneCut,omitempty"`
+	// LoudnessNormalize runs two-pass EBU R128 loudness normalization on the
+	// audio track. Ignored when StripAudio is set or the input has no audio.
+	LoudnessNormalize *LoudnessNormalizeOptions `json:"loudnessNormalize,omitempty"`
+	// QualityMetrics scores the finished encode against the source (VMAF,
+	// PSNR, SSIM) and attaches the numbers to the job. Adds a full decode of
+	// both files, so it is opt-in.
+	QualityMetrics *QualityMetricsOptions `json:"qualityMetrics,omitempty"`
+	// Timecode burns a running SMPTE timecode into the picture for review
+	// and dailies copies. Not available for GIF output.
+	Timecode *TimecodeBurnInOptions `json:"timecode,omitempty"`
+	// Chunked splits a long source at keyframes, encodes the pieces in
+	// parallel and joins them, trading a little bitrate for wall-clock time
+	// on multi-core hosts. Not combinable with trim, reverse or timecode.
+	Chunked *ChunkedEncodingOptions `json:"chunked,omitempty"`
+	// TextOverlay draws text over every frame (drawtext), e.g. a watermark.
+	// Same fields as the image overlay; gravity defaults to South.
+	TextOverlay *ImageTextOverlay `json:"textOverlay,omitempty"`
+	// MaxWidth / MaxHeight shrink the output to fit after all geometry
+	// changes, keeping the aspect ratio; smaller videos are left alone.
+	MaxWidth  *int `json:"maxWidth,omitempty"`
+	MaxHeight *int `json:"maxHeight,omitempty"`
+	// StripMetadata drops container metadata and chapters from the output.
+	StripMetadata bool `json:"stripMetadata,omitempty"`
+	// TrimSegments keeps only these ranges, in order, and joins them — e.g.
+	// to cut ads or dead air out of the middle. Replaces Trim.
+	TrimSegments []TrimRange `json:"trimSegments,omitempty"`
+	// Upscale enlarges the picture 2x or 4x with the Lanczos scaler.
+	Upscale int `json:"upscale,omitempty"`
+	// BlurFaces obscures the faces in every frame; FaceBlurMode as for
+	// images. Audio is kept; subtitle and data streams are dropped.
+	BlurFaces    bool   `json:"blurFaces,omitempty"`
+	FaceBlurMode string `json:"faceBlurMode,omitempty"`
+}
+
+// ChunkedEncodingOptions tunes chunk-parallel encoding. Sources shorter than
+// two chunks are encoded in one pass as usual.
+type ChunkedEncodingOptions struct {
+	Enabled bool `json:"enabled"`
+	// ChunkSeconds is the target chunk length (10–600, default 60). Chunks
+	// end on the first keyframe after it, so sparse-GOP sources get longer
+	// chunks.
+	ChunkSeconds float64 `json:"chunkSeconds,omitempty"`
+	// Workers caps concurrent chunk encodes; 0 or anything above
+	// CHUNK_ENCODE_WORKERS uses that server limit.
+	Workers int `json:"workers,omitempty"`
+}
+
+// TimecodeBurnInOptions configures the burned-in timecode.
+type TimecodeBurnInOptions struct {
+	Enabled bool `json:"enabled"`
+	// Start is the timecode of the first output frame, HH:MM:SS:FF. Use ';'
+	// before the frames (HH:MM:SS;FF) for drop-frame at 29.97/59.94.
+	// Default 00:00:00:00.
+	Start string `json:"start,omitempty"`
+	// FrameRate is the timecode base: 23.976, 24, 25, 29.97, 30, 48, 50,
+	// 59.94 or 60. Empty uses the output frame rate.
+	FrameRate string `json:"frameRate,omitempty"`
+	// Position: top-left, top-center, top-right, bottom-left,
+	// bottom-center (default) or bottom-right.
+	Position string `json:"position,omitempty"`
+	// Font is one of the server's bundled fonts; default DejaVu-Sans-Mono.
+	Font string `json:"font,omitempty"`
+	// FontSize in pixels; 0 scales with the frame height.
+	FontSize int `json:"fontSize,omitempty"`
+	// FontColor is #RRGGBB; default white on a translucent black box.
+	FontColor string `json:"fontColor,omitempty"`
+}
+
+// QualityMetricsOptions selects which full-reference metrics to compute.
+// Empty Metrics means all of vmaf, psnr and ssim.
+type QualityMetricsOptions struct {
+	Enabled bool     `json:"enabled"`
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+// QualityMetricsResult holds the pooled (mean) scores for a finished encode.
+// A metric is nil when it was not requested or could not be computed; the
+// reason lands in Skipped.
+type QualityMetricsResult struct {
+	VMAF    *float64          `json:"vmaf,omitempty"`
+	PSNR    *float64          `json:"psnr,omitempty"` // dB, average over Y/U/V
+	SSIM    *float64          `json:"ssim,omitempty"` // 0..1, "All" channel
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	// Image quality-target search results: the distance of the delivered
+	// encode, the encoder quality that produced it, and whether it is
+	// within the requested distance.
+	DSSIM          *float64 `json:"dssim,omitempty"`
+	Butteraugli    *float64 `json:"butteraugli,omitempty"`
+	EncoderQuality *int     `json:"encoderQuality,omitempty"`
+	TargetMet      *bool    `json:"targetMet,omitempty"`
 }
 
 // AIVideoOptions selects a Phase 1 AI video operation. Only one operation runs
@@ -564,6 +893,47 @@ type AudioConversionOptions struct {
 	Restoration      *Restoration      `json:"restoration,omitempty"`
 	Advanced         *AdvancedAudio    `json:"advanced,omitempty"`
 	AI               *AIAudioOptions   `json:"ai,omitempty"`
+	// LoudnessNormalize runs two-pass EBU R128 loudness normalization as the
+	// last step of the filter chain.
+	LoudnessNormalize *LoudnessNormalizeOptions `json:"loudnessNormalize,omitempty"`
+	// StripMetadata drops tags (ID3, Vorbis comments) and chapters.
+	StripMetadata bool `json:"stripMetadata,omitempty"`
+	// Tags are written to the output after any stripping, so combining the
+	// two replaces the source tags instead of adding to them.
+	Tags *AudioTags `json:"tags,omitempty"`
+	// SampleFormat is the WAV/FLAC bit depth: s16 (the default), s24, or
+	// f32 (WAV only).
+	SampleFormat string `json:"sampleFormat,omitempty" binding:"omitempty,oneof=s16 s24 f32"`
+	// Resampler "soxr" converts the sample rate with libsoxr instead of
+	// FFmpeg's built-in swresample; ResamplerPrecision is its precision in
+	// bits (15–33, default 28).
+	Resampler          string `json:"resampler,omitempty" binding:"omitempty,oneof=swr soxr"`
+	ResamplerPrecision int    `json:"resamplerPrecision,omitempty"`
+	// TrimSegments keeps only these ranges, in order, and joins them. Replaces
+	// Trim.
+	TrimSegments []TrimRange `json:"trimSegments,omitempty"`
+}
+
+// AudioTags are the common ID3 / Vorbis comment / RIFF INFO fields. Empty
+// fields are left as they are in the source.
+type AudioTags struct {
+	Title   string `json:"title,omitempty"`
+	Artist  string `json:"artist,omitempty"`
+	Album   string `json:"album,omitempty"`
+	Year    string `json:"year,omitempty"`
+	Genre   string `json:"genre,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// LoudnessNormalizeOptions targets a deliverable loudness spec. Preset picks
+// the defaults (podcast -16 LUFS, broadcast -23 LUFS / EBU R128, streaming
+// -14 LUFS); the explicit fields override individual values.
+type LoudnessNormalizeOptions struct {
+	Enabled        bool     `json:"enabled"`
+	Preset         string   `json:"preset,omitempty"`
+	IntegratedLUFS *float64 `json:"integratedLufs,omitempty"`
+	TruePeak       *float64 `json:"truePeak,omitempty"`
+	LRA            *float64 `json:"lra,omitempty"`
 }
 
 // AIAudioOptions selects a Phase 1 AI audio operation. Only one operation runs
@@ -613,6 +983,52 @@ type StructuredImageMetadata struct {
 	AdvancedDeviceMetadata map[string]map[string]interface{} `json:"advancedDeviceMetadata,omitempty"`
 }
 
+// ImageIdentification is the typed summary of ImageMagick's JSON description
+// of an image. Width/Height are the stored pixels; DisplayWidth/DisplayHeight
+// are after EXIF auto-orientation. Histogram is only present for images with
+// few enough colours for ImageMagick to list them.
+type ImageIdentification struct {
+	Format            string                            `json:"format"`
+	MimeType          string                            `json:"mimeType,omitempty"`
+	Width             int                               `json:"width"`
+	Height            int                               `json:"height"`
+	DisplayWidth      int                               `json:"displayWidth"`
+	DisplayHeight     int                               `json:"displayHeight"`
+	Orientation       string                            `json:"orientation,omitempty"`
+	Frames            int                               `json:"frames"`
+	Colorspace        string                            `json:"colorspace,omitempty"`
+	Type              string                            `json:"type,omitempty"`
+	Depth             int                               `json:"depth,omitempty"`
+	HasAlpha          bool                              `json:"hasAlpha"`
+	Compression       string                            `json:"compression,omitempty"`
+	Quality           int                               `json:"quality,omitempty"`
+	Interlace         string                            `json:"interlace,omitempty"`
+	ResolutionX       float64                           `json:"resolutionX,omitempty"`
+	ResolutionY       float64                           `json:"resolutionY,omitempty"`
+	ResolutionUnits   string                            `json:"resolutionUnits,omitempty"`
+	Exif              map[string]string                 `json:"exif,omitempty"`
+	Properties        map[string]string                 `json:"properties,omitempty"`
+	Profiles          map[string]int                    `json:"profiles,omitempty"`
+	ChannelStatistics map[string]ImageChannelStatistics `json:"channelStatistics,omitempty"`
+	Histogram         []ImageHistogramEntry             `json:"histogram,omitempty"`
+}
+
+// ImageChannelStatistics are one channel's pixel statistics, in the
+// channel's own range (0–255 for 8-bit images).
+type ImageChannelStatistics struct {
+	Min               float64 `json:"min"`
+	Max               float64 `json:"max"`
+	Mean              float64 `json:"mean"`
+	StandardDeviation float64 `json:"standardDeviation"`
+	Entropy           float64 `json:"entropy,omitempty"`
+}
+
+// ImageHistogramEntry is one colour and how many pixels have it.
+type ImageHistogramEntry struct {
+	Color string `json:"color"`
+	Count int64  `json:"count"`
+}
+
 // Progress update
 type ProgressUpdate struct {
 	JobID    string `json:"jobId"`
@@ -627,8 +1043,27 @@ type FileIdentificationResponse struct {
 	MimeType      string                   `json:"mimeType"`
 	Details       map[string]interface{}   `json:"details"`
 	ImageMetadata *StructuredImageMetadata `json:"imageMetadata,omitempty"`
-	Tool          string                   `json:"tool"`      // Which tool was used for identification
-	RawOutput     string                   `json:"rawOutput"` // Raw command output for debugging
+	AudioTracks   []AudioTrack             `json:"audioTracks,omitempty"` // Video/audio only
+	AudioTags     *AudioTags               `json:"audioTags,omitempty"`   // Video/audio only, when tagged
+	Tool          string                   `json:"tool"`                  // Which tool was used for identification
+	RawOutput     string                   `json:"rawOutput"`             // Raw command output for debugging
+	// Identify is the typed description of an image.
+	Identify *ImageIdentification `json:"identify,omitempty"`
+}
+
+// AudioTrack describes one audio stream of an identified file.
+type AudioTrack struct {
+	// Index is the zero-based position among the file's audio streams, the
+	// value to put in the audioTracks video conversion option.
+	Index         int    `json:"index"`
+	StreamIndex   int    `json:"streamIndex"`
+	Codec         string `json:"codec,omitempty"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channelLayout,omitempty"`
+	SampleRate    int    `json:"sampleRate,omitempty"`
+	Language      string `json:"language,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Default       bool   `json:"default,omitempty"`
 }
 
 // Helper function to determine file type from MIME type
@@ -0,0 +1,12 @@
+package models
+
+// Usage is what an API key tenant used in one calendar month.
+type Usage struct {
+	// Jobs counts the jobs started, retries excluded.
+	Jobs int `json:"jobs"`
+	// Bytes adds up the size of those jobs' inputs.
+	Bytes int64 `json:"bytes"`
+	// EncodeSeconds is the processing time of every attempt that finished
+	// in the month.
+	EncodeSeconds float64 `json:"encodeSeconds"`
+}
@@ -122,7 +122,7 @@ func (a *AIService) runAI(ctx context.Context, label string, env []string, name
 			return fmt.Errorf("%s: %s not found: %v", label, name, err)
 		}
 	}
-	cmd := exec.CommandContext(ctx, name, args...)
+	cmd := limitedCommand(ctx, name, args...)
 	if len(env) > 0 {
 		cmd.Env = append(os.Environ(), env...)
 	}
@@ -131,7 +131,7 @@ func (a *AIService) runAI(ctx context.Context, label string, env []string, name
 	cmd.Stderr = &combined
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() != nil {
-			return fmt.Errorf("%s timed out: %w", label, ctx.Err())
+			return fmt.Errorf("%s timed out: %w", label, context.Cause(ctx))
 		}
 		return fmt.Errorf("%s failed: %v\n%s", label, err, commandTail(combined.String(), 4000))
 	}
@@ -389,6 +389,33 @@ func (a *AIService) FacePrivacy(ctx context.Context, jobID, inputPath, outputPat
 	return nil
 }
 
+// BlurVideoFaces runs the runtime face privacy script in --video mode: every
+// frame of inputPath is scanned and its faces redacted, and the result is
+// written to outputPath (Matroska, near-lossless H.264 plus the source audio).
+func (a *AIService) BlurVideoFaces(ctx context.Context, jobID, inputPath, outputPath, mode string) error {
+	if mode == "" {
+		mode = "blur"
+	}
+	if !validFaceModes[mode] {
+		return fmt.Errorf("unsupported face mode: %s", mode)
+	}
+	a.sendProgress(jobID, 10)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+	if err := a.runAI(ctx, "face_privacy video", a.cudaEnv(), a.cfg.VisionPython,
+		a.cfg.FacePrivacyScript,
+		"--input", inputPath,
+		"--output", outputPath,
+		"--mode", mode,
+		"--video",
+	); err != nil {
+		return err
+	}
+	a.sendProgress(jobID, 40)
+	return nil
+}
+
 // DetectFaces runs the runtime script in --detect-only mode. The script
 // (configured via AI_FACE_PRIVACY_SCRIPT, defaults to
 // /opt/media-manipulator-ai/scripts/face_privacy.py) writes a JSON document
@@ -639,14 +666,14 @@ func (a *AIService) RemoveObject(ctx context.Context, jobID, inputPath, outputPa
 // pipeline already depends on ImageMagick and it covers WebP/HEIC/TIFF without
 // pulling in extra Go image codecs.
 func imageDimensions(ctx context.Context, inputPath string) (int, int, error) {
-	cmd := exec.CommandContext(ctx, "identify", "-format", "%w %h", inputPath+"[0]")
+	cmd := limitedCommand(ctx, "identify", "-format", "%w %h", inputPath+"[0]")
 	var out bytes.Buffer
 	var errBuf bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &errBuf
 	if err := cmd.Run(); err != nil {
 		if magick, mErr := exec.LookPath("magick"); mErr == nil {
-			cmd = exec.CommandContext(ctx, magick, "identify", "-format", "%w %h", inputPath+"[0]")
+			cmd = limitedCommand(ctx, magick, "identify", "-format", "%w %h", inputPath+"[0]")
 			out.Reset()
 			errBuf.Reset()
 			cmd.Stdout = &out
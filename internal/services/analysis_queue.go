@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
@@ -25,6 +26,8 @@ type AnalysisQueue struct {
 	cfg       *config.Config
 	inspector *MediaInspector
 	jobs      chan AnalysisJob
+	// busy counts the workers running a job.
+	busy atomic.Int32
 
 	// telemetry is set via SetTelemetry at startup. When nil, persistence
 	// hooks become no-ops so unit tests don't need a DB.
@@ -84,14 +87,29 @@ func (q *AnalysisQueue) Enqueue(job AnalysisJob) {
 
 func (q *AnalysisQueue) worker() {
 	for job := range q.jobs {
+		q.busy.Add(1)
 		ctx, cancel := context.WithTimeout(context.Background(), q.cfg.CommandTimeout)
 		if err := q.run(ctx, job); err != nil {
 			log.Printf("analysis job %s failed: %v", job.JobID, err)
 		}
 		cancel()
+		q.busy.Add(-1)
 	}
 }
 
+// WorkerPool is how busy a fixed pool of workers is.
+type WorkerPool struct {
+	Workers int `json:"workers"`
+	Busy    int `json:"busy"`
+	Queued  int `json:"queued"`
+}
+
+// Utilization reports the ANALYSIS_WORKERS pool. Jobs waiting for room in
+// a full buffer aren't counted as queued.
+func (q *AnalysisQueue) Utilization() WorkerPool {
+	return WorkerPool{Workers: max(0, q.cfg.AnalysisWorkers), Busy: int(q.busy.Load()), Queued: len(q.jobs)}
+}
+
 func (q *AnalysisQueue) run(ctx context.Context, job AnalysisJob) error {
 	started := time.Now().UTC()
 	result := analysisResult{JobID: job.JobID, FileType: job.FileType, Mode: job.Mode, Model: envOrDefault("OLLAMA_VLM_MODEL", defaultVLMModel), StartedAt: started, AudioDescription: job.AudioDescription}
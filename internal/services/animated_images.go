@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// Animated GIF/WebP input. GIF frames are often stored as partial rectangles
+// over the previous frame, so cropping or filtering them as-is mangles every
+// frame after the first. The chain coalesces them into full frames first,
+// drops the per-frame page offsets left behind by geometry operations, and
+// re-optimizes the GIF on the way out. Frame delays and the loop count
+// survive coalescing untouched.
+
+// isAnimatedImage reports whether path is a GIF or WebP with more than one
+// frame.
+func isAnimatedImage(ctx context.Context, path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif", ".webp":
+	default:
+		return false
+	}
+	n, err := countImages(ctx, path)
+	return err == nil && n > 1
+}
+
+// animatedOutputFormat reports whether format keeps every frame.
+func animatedOutputFormat(format string) bool {
+	return format == "gif" || format == "webp"
+}
+
+// animatedImageArgs rewrites a BuildImageCommand argument list for an
+// animated input. Animated outputs get -coalesce after the input, +repage
+// after each geometry change and, for GIF, -layers Optimize before the
+// output. Still outputs read only the first frame instead of writing one
+// numbered file per frame.
+func animatedImageArgs(args []string, format string) []string {
+	if len(args) < 2 {
+		return args
+	}
+	if !animatedOutputFormat(format) {
+		return append([]string{args[0] + "[0]"}, args[1:]...)
+	}
+	out := []string{args[0], "-coalesce"}
+	body := args[1 : len(args)-1]
+	for i := 0; i < len(body); i++ {
+		out = append(out, body[i])
+		switch body[i] {
+		case "-crop", "-rotate", "-distort":
+			// Options with operands: copy them before repaging.
+			n := 1
+			if body[i] == "-distort" {
+				n = 2
+			}
+			for ; n > 0 && i+1 < len(body); n-- {
+				i++
+				out = append(out, body[i])
+			}
+			out = append(out, "+repage")
+		}
+	}
+	if format == "gif" {
+		out = append(out, "-layers", "Optimize")
+	}
+	return append(out, args[len(args)-1])
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestAnimatedImageArgs(t *testing.T) {
+	width := 320
+	options := &models.ImageConversionOptions{
+		Format: "gif",
+		Width:  &width,
+		Crop:   &models.CropArea{X: 10, Y: 20, Width: 200, Height: 100},
+		Filter: "barrel-distortion",
+	}
+	got := animatedImageArgs(BuildImageCommand("in.gif", "out.gif", options), "gif")
+	want := []string{
+		"in.gif", "-coalesce", "-auto-orient",
+		"-crop", "200x100+10+20", "+repage",
+		"-resize", "320x",
+		"-distort", "Barrel", "0.1 0.0 0.0 1.0", "+repage",
+		"-layers", "Optimize", "out.gif",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("gif args =\n%q\nwant\n%q", got, want)
+	}
+
+	options.Format = "webp"
+	options.Quality = 80
+	got = animatedImageArgs(BuildImageCommand("in.gif", "out.webp", options), "webp")
+	if got[len(got)-2] == "Optimize" || got[1] != "-coalesce" {
+		t.Fatalf("webp args = %q, want coalesced frames without GIF layer optimization", got)
+	}
+
+	options.Format = "png"
+	got = animatedImageArgs(BuildImageCommand("in.gif", "out.png", options), "png")
+	if got[0] != "in.gif[0]" || got[1] == "-coalesce" {
+		t.Fatalf("png args = %q, want only the first frame", got)
+	}
+}
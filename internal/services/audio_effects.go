@@ -0,0 +1,283 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Parametric audio effects whose parameters map straight onto FFmpeg filter
+// options, as opposed to the fixed presets inlined in BuildAudioFilters.
+
+const (
+	eqMaxBands     = 16
+	eqMinFrequency = 20.0
+	eqMaxFrequency = 20000.0
+	eqMaxGain      = 24.0
+	eqDefaultQ     = 1.0
+
+	compressorDefaultAttack  = 20.0
+	compressorDefaultRelease = 250.0
+)
+
+func validateEqualizer(eq *models.Equalizer) error {
+	if len(eq.Bands) > eqMaxBands {
+		return fmt.Errorf("at most %d EQ bands are supported, got %d", eqMaxBands, len(eq.Bands))
+	}
+	for i, b := range eq.Bands {
+		if b.Frequency < eqMinFrequency || b.Frequency > eqMaxFrequency {
+			return fmt.Errorf("EQ band %d frequency must be between %g and %g Hz, got %g", i+1, eqMinFrequency, eqMaxFrequency, b.Frequency)
+		}
+		if b.Gain < -eqMaxGain || b.Gain > eqMaxGain {
+			return fmt.Errorf("EQ band %d gain must be between %g and %g dB, got %g", i+1, -eqMaxGain, eqMaxGain, b.Gain)
+		}
+		if b.Q != 0 && (b.Q < 0.1 || b.Q > 30) {
+			return fmt.Errorf("EQ band %d Q must be between 0.1 and 30, got %g", i+1, b.Q)
+		}
+	}
+	for name, f := range map[string]*float64{"low-pass": eq.LowPass, "high-pass": eq.HighPass} {
+		if f != nil && (*f < eqMinFrequency || *f > eqMaxFrequency) {
+			return fmt.Errorf("EQ %s cutoff must be between %g and %g Hz, got %g", name, eqMinFrequency, eqMaxFrequency, *f)
+		}
+	}
+	if eq.LowPass != nil && eq.HighPass != nil && *eq.HighPass >= *eq.LowPass {
+		return fmt.Errorf("EQ high-pass cutoff (%g Hz) must be below the low-pass cutoff (%g Hz)", *eq.HighPass, *eq.LowPass)
+	}
+	return nil
+}
+
+// equalizerFilters returns the high-pass, low-pass and peaking band filters
+// for eq. A band's Q defaults to 1 (about 1.4 octaves); 0 dB bands are
+// skipped.
+func equalizerFilters(eq *models.Equalizer) []string {
+	var filters []string
+	if eq.HighPass != nil {
+		filters = append(filters, fmt.Sprintf("highpass=f=%g", *eq.HighPass))
+	}
+	if eq.LowPass != nil {
+		filters = append(filters, fmt.Sprintf("lowpass=f=%g", *eq.LowPass))
+	}
+	for _, b := range eq.Bands {
+		if b.Gain == 0 {
+			continue
+		}
+		q := b.Q
+		if q == 0 {
+			q = eqDefaultQ
+		}
+		filters = append(filters, fmt.Sprintf("equalizer=f=%g:width_type=q:width=%g:g=%g", b.Frequency, q, b.Gain))
+	}
+	return filters
+}
+
+func validateDynamicRange(dr *models.DynamicRange) error {
+	if dr.Ratio < 1 || dr.Ratio > 20 {
+		return fmt.Errorf("compressor ratio must be between 1 and 20, got %g", dr.Ratio)
+	}
+	if dr.Threshold < -60 || dr.Threshold > 0 {
+		return fmt.Errorf("compressor threshold must be between -60 and 0 dB, got %g", dr.Threshold)
+	}
+	if dr.Attack != nil && (*dr.Attack < 0.01 || *dr.Attack > 2000) {
+		return fmt.Errorf("compressor attack must be between 0.01 and 2000 ms, got %g", *dr.Attack)
+	}
+	if dr.Release != nil && (*dr.Release < 0.01 || *dr.Release > 9000) {
+		return fmt.Errorf("compressor release must be between 0.01 and 9000 ms, got %g", *dr.Release)
+	}
+	// acompressor caps makeup at 64x linear, about 36 dB.
+	if dr.MakeupGain != nil && (*dr.MakeupGain < 0 || *dr.MakeupGain > 36) {
+		return fmt.Errorf("compressor makeup gain must be between 0 and 36 dB, got %g", *dr.MakeupGain)
+	}
+	return nil
+}
+
+// compressorFilter maps DynamicRange onto acompressor. FFmpeg parses the dB
+// suffix on threshold and makeup, which are otherwise linear amplitudes.
+func compressorFilter(dr *models.DynamicRange) string {
+	attack, release, makeup := compressorDefaultAttack, compressorDefaultRelease, 0.0
+	if dr.Attack != nil {
+		attack = *dr.Attack
+	}
+	if dr.Release != nil {
+		release = *dr.Release
+	}
+	if dr.MakeupGain != nil {
+		makeup = *dr.MakeupGain
+	}
+	return fmt.Sprintf("acompressor=threshold=%gdB:ratio=%g:attack=%g:release=%g:makeup=%gdB",
+		dr.Threshold, dr.Ratio, attack, release, makeup)
+}
+
+// modulationFilter returns the filter for a modulation effect ("" for
+// none). Validation goes through the same switch, so a type without an
+// implementation is rejected instead of silently dropped.
+func modulationFilter(m *models.Modulation) (string, error) {
+	if m.Type != "none" && (m.Depth < 0 || m.Depth > 100) {
+		return "", fmt.Errorf("modulation depth must be between 0 and 100, got %g", m.Depth)
+	}
+	rate, depth := m.Rate, m.Depth/100.0
+	switch m.Type {
+	case "none":
+		return "", nil
+	case "chorus":
+		return "chorus=0.7:0.9:55:0.4:0.25:2:t", nil
+	case "flanger":
+		return "flanger", nil
+	case "phaser":
+		// aphaser sweeps at 0.1–2 Hz; depth sets how strongly the delayed
+		// signal feeds back, which is what makes the notches audible.
+		if rate < 0.1 || rate > 2 {
+			return "", fmt.Errorf("phaser rate must be between 0.1 and 2 Hz, got %g", rate)
+		}
+		return fmt.Sprintf("aphaser=delay=3:decay=%.2f:speed=%.2f:type=t", math.Min(depth, 0.99), rate), nil
+	case "tremolo", "vibrato":
+		if rate < 0.1 || rate > 20 {
+			return "", fmt.Errorf("%s rate must be between 0.1 and 20 Hz, got %g", m.Type, rate)
+		}
+		return fmt.Sprintf("%s=f=%.2f:d=%.2f", m.Type, rate, depth), nil
+	}
+	return "", fmt.Errorf("unsupported modulation type: %s", m.Type)
+}
+
+// reverbSpace is the echo pattern of one reverb type at medium size: tap
+// delays in milliseconds and their relative levels.
+type reverbSpace struct {
+	delays []float64
+	decays []float64
+}
+
+var reverbSpaces = map[string]reverbSpace{
+	"room":   {delays: []float64{23, 41, 60}, decays: []float64{0.5, 0.4, 0.3}},
+	"hall":   {delays: []float64{40, 60, 87, 120}, decays: []float64{0.5, 0.45, 0.35, 0.3}},
+	"plate":  {delays: []float64{15, 28, 40, 53}, decays: []float64{0.45, 0.35, 0.3, 0.25}},
+	"spring": {delays: []float64{100, 200}, decays: []float64{0.5, 0.3}},
+}
+
+const (
+	reverbDefaultSize    = 50.0
+	reverbDefaultDamping = 50.0
+	reverbDefaultWet     = 40.0
+	reverbDefaultDry     = 80.0
+)
+
+// reverbFilter maps the reverb controls onto a multi-tap aecho ("" for
+// none):
+//
+//   - roomSize (0–100) stretches the tap delays from half to double the
+//     type's pattern
+//   - damping (0–100) makes each later tap fall off faster
+//   - wetLevel / dryLevel (0–100) set the echo and direct signal levels
+//
+// With wet and dry both unset, size 50, damping 50, wet 40 and dry 80 are
+// used.
+//
+// The output gain keeps the sum of the taps from clipping.
+func reverbFilter(r *models.Reverb) (string, error) {
+	if r.Type == "none" {
+		return "", nil
+	}
+	space, ok := reverbSpaces[r.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported reverb type: %s", r.Type)
+	}
+	for name, v := range map[string]float64{"room size": r.RoomSize, "damping": r.Damping, "wet level": r.WetLevel, "dry level": r.DryLevel} {
+		if v < 0 || v > 100 {
+			return "", fmt.Errorf("reverb %s must be between 0 and 100, got %.2f", name, v)
+		}
+	}
+	size, damping, wet, dry := r.RoomSize, r.Damping, r.WetLevel, r.DryLevel
+	if wet == 0 && dry == 0 {
+		// A request that only picks the type gets a medium space.
+		size, damping, wet, dry = reverbDefaultSize, reverbDefaultDamping, reverbDefaultWet, reverbDefaultDry
+	}
+	scale := 0.5 + 1.5*size/100
+	falloff := 1 - 0.7*damping/100
+
+	delays := make([]string, len(space.delays))
+	decays := make([]string, len(space.decays))
+	inGain := dry / 100
+	total := inGain
+	for i := range space.delays {
+		decay := space.decays[i] * wet / 100 * math.Pow(falloff, float64(i))
+		delays[i] = fmt.Sprintf("%.0f", space.delays[i]*scale)
+		decays[i] = fmt.Sprintf("%.3f", decay)
+		total += decay
+	}
+	outGain := 1.0
+	if total > 0.9 {
+		outGain = 0.9 / total
+	}
+	return fmt.Sprintf("aecho=%.2f:%.2f:%s:%s", inGain, outGain, strings.Join(delays, "|"), strings.Join(decays, "|")), nil
+}
+
+const (
+	spatialMaxCoordinate = 10.0
+	// spatialMaxITD is the largest interaural time difference in ms, for a
+	// source directly to one side of an average head.
+	spatialMaxITD = 0.66
+)
+
+// spatialPositionFilters places the source at p for the 3d spatial mode.
+// Coordinates are metres from the listener: x to the right, y up, z in
+// front. The input is folded to mono and then:
+//
+//   - panned with constant power by the source's left/right angle
+//   - delayed in the far ear by up to 0.66 ms (interaural time difference)
+//   - low-passed as it moves behind the head, down to 6 kHz directly behind
+//   - brightened above and dulled below by up to 4 dB of treble
+//   - attenuated by inverse distance beyond 1 m
+//
+// A nil position is straight ahead at 1 m.
+func spatialPositionFilters(p *models.Position) ([]string, error) {
+	x, y, z := 0.0, 0.0, 1.0
+	if p != nil {
+		x, y, z = p.X, p.Y, p.Z
+	}
+	for name, v := range map[string]float64{"x": x, "y": y, "z": z} {
+		if v < -spatialMaxCoordinate || v > spatialMaxCoordinate {
+			return nil, fmt.Errorf("spatial position %s must be between %g and %g, got %g", name, -spatialMaxCoordinate, spatialMaxCoordinate, v)
+		}
+	}
+	dist := math.Sqrt(x*x + y*y + z*z)
+	if dist == 0 {
+		// At the listener's head: centred, unfiltered.
+		z, dist = 1, 1
+	}
+	lateral, elevation, rear := x/dist, y/dist, math.Max(-z/dist, 0)
+
+	angle := (lateral + 1) * math.Pi / 4
+	filters := []string{
+		"aformat=channel_layouts=mono",
+		fmt.Sprintf("pan=stereo|FL=%.3f*c0|FR=%.3f*c0", math.Cos(angle), math.Sin(angle)),
+	}
+	if itd := math.Abs(lateral) * spatialMaxITD; itd >= 0.01 {
+		if lateral > 0 {
+			filters = append(filters, fmt.Sprintf("adelay=%.2f|0", itd))
+		} else {
+			filters = append(filters, fmt.Sprintf("adelay=0|%.2f", itd))
+		}
+	}
+	if rear >= 0.01 {
+		filters = append(filters, fmt.Sprintf("lowpass=f=%.0f", 20000-rear*14000))
+	}
+	if gain := 4 * elevation; math.Abs(gain) >= 0.1 {
+		filters = append(filters, fmt.Sprintf("treble=g=%.1f:f=8000", gain))
+	}
+	if dist > 1 {
+		filters = append(filters, fmt.Sprintf("volume=%.3f", 1/dist))
+	}
+	return filters, nil
+}
+
+// pitchShiftFilter shifts pitch by whole semitones with librubberband,
+// which keeps the duration unchanged. With PreserveFormants the spectral
+// envelope stays put, so voices keep their character instead of sounding
+// chipmunk-like or muffled.
+func pitchShiftFilter(ps *models.PitchShift) string {
+	filter := fmt.Sprintf("rubberband=pitch=%.6f:pitchq=quality", math.Pow(2, float64(ps.Semitones)/12))
+	if ps.PreserveFormants {
+		filter += ":formant=preserved"
+	}
+	return filter
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestEqualizerFilters(t *testing.T) {
+	eq := &models.Equalizer{
+		Enabled:  true,
+		HighPass: floatPtr(80),
+		LowPass:  floatPtr(12000),
+		Bands: []models.EQBand{
+			{Frequency: 250, Gain: -3, Q: 2},
+			{Frequency: 1000, Gain: 0, Q: 1},
+			{Frequency: 3500, Gain: 4.5},
+		},
+	}
+	if err := validateEqualizer(eq); err != nil {
+		t.Fatalf("valid EQ rejected: %v", err)
+	}
+	want := []string{
+		"highpass=f=80",
+		"lowpass=f=12000",
+		"equalizer=f=250:width_type=q:width=2:g=-3",
+		"equalizer=f=3500:width_type=q:width=1:g=4.5",
+	}
+	if got := equalizerFilters(eq); !reflect.DeepEqual(got, want) {
+		t.Fatalf("filters = %q, want %q", got, want)
+	}
+}
+
+func TestValidateEqualizer(t *testing.T) {
+	for name, eq := range map[string]*models.Equalizer{
+		"subsonic band":  {Bands: []models.EQBand{{Frequency: 5, Gain: 3}}},
+		"huge gain":      {Bands: []models.EQBand{{Frequency: 100, Gain: 40}}},
+		"narrow Q":       {Bands: []models.EQBand{{Frequency: 100, Gain: 3, Q: 50}}},
+		"crossed cuts":   {HighPass: floatPtr(5000), LowPass: floatPtr(1000)},
+		"ultrasonic cut": {LowPass: floatPtr(30000)},
+		"too many bands": {Bands: make([]models.EQBand, eqMaxBands+1)},
+	} {
+		if err := validateEqualizer(eq); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCompressorFilter(t *testing.T) {
+	dr := &models.DynamicRange{Enabled: true, Ratio: 4, Threshold: -18}
+	if err := validateDynamicRange(dr); err != nil {
+		t.Fatalf("valid compressor rejected: %v", err)
+	}
+	if got, want := compressorFilter(dr), "acompressor=threshold=-18dB:ratio=4:attack=20:release=250:makeup=0dB"; got != want {
+		t.Fatalf("defaults: got %q, want %q", got, want)
+	}
+	dr.Attack, dr.Release, dr.MakeupGain = floatPtr(5), floatPtr(100), floatPtr(6)
+	if got, want := compressorFilter(dr), "acompressor=threshold=-18dB:ratio=4:attack=5:release=100:makeup=6dB"; got != want {
+		t.Fatalf("custom: got %q, want %q", got, want)
+	}
+	for name, bad := range map[string]models.DynamicRange{
+		"ratio below 1":  {Ratio: 0.5, Threshold: -18},
+		"positive level": {Ratio: 4, Threshold: 3},
+		"slow attack":    {Ratio: 4, Threshold: -18, Attack: floatPtr(5000)},
+		"huge makeup":    {Ratio: 4, Threshold: -18, MakeupGain: floatPtr(48)},
+	} {
+		if err := validateDynamicRange(&bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestModulationFilter(t *testing.T) {
+	for _, tc := range []struct {
+		mod  models.Modulation
+		want string
+	}{
+		{models.Modulation{Type: "none"}, ""},
+		{models.Modulation{Type: "phaser", Rate: 0.5, Depth: 60}, "aphaser=delay=3:decay=0.60:speed=0.50:type=t"},
+		{models.Modulation{Type: "phaser", Rate: 2, Depth: 100}, "aphaser=delay=3:decay=0.99:speed=2.00:type=t"},
+		{models.Modulation{Type: "tremolo", Rate: 5, Depth: 50}, "tremolo=f=5.00:d=0.50"},
+	} {
+		got, err := modulationFilter(&tc.mod)
+		if err != nil || got != tc.want {
+			t.Errorf("%+v: got %q, %v; want %q", tc.mod, got, err, tc.want)
+		}
+	}
+	for name, bad := range map[string]models.Modulation{
+		"unknown type":  {Type: "wah", Rate: 1, Depth: 50},
+		"fast phaser":   {Type: "phaser", Rate: 5, Depth: 50},
+		"still vibrato": {Type: "vibrato", Rate: 0, Depth: 50},
+		"deep chorus":   {Type: "chorus", Depth: 150},
+	} {
+		if _, err := modulationFilter(&bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReverbFilter(t *testing.T) {
+	got, err := reverbFilter(&models.Reverb{Type: "room", RoomSize: 50, Damping: 50, WetLevel: 50, DryLevel: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "aecho=1.00:0.62:29|51|75:0.250|0.130|0.063"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// The controls have to change the sound.
+	base := models.Reverb{Type: "hall", RoomSize: 50, Damping: 50, WetLevel: 50, DryLevel: 80}
+	baseFilter, _ := reverbFilter(&base)
+	for name, change := range map[string]func(*models.Reverb){
+		"room size": func(r *models.Reverb) { r.RoomSize = 90 },
+		"damping":   func(r *models.Reverb) { r.Damping = 10 },
+		"wet level": func(r *models.Reverb) { r.WetLevel = 20 },
+		"dry level": func(r *models.Reverb) { r.DryLevel = 40 },
+	} {
+		r := base
+		change(&r)
+		if f, _ := reverbFilter(&r); f == baseFilter {
+			t.Errorf("%s had no effect: %s", name, f)
+		}
+	}
+
+	if f, _ := reverbFilter(&models.Reverb{Type: "none"}); f != "" {
+		t.Errorf("none = %q", f)
+	}
+	for name, bad := range map[string]models.Reverb{
+		"unknown type": {Type: "cathedral"},
+		"wet over 100": {Type: "room", WetLevel: 120},
+		"negative dry": {Type: "room", DryLevel: -1},
+	} {
+		if _, err := reverbFilter(&bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSpatialPositionFilters(t *testing.T) {
+	// Behind and to the right, 2 m away.
+	got, err := spatialPositionFilters(&models.Position{X: 1.2, Z: -1.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"aformat=channel_layouts=mono",
+		"pan=stereo|FL=0.309*c0|FR=0.951*c0",
+		"adelay=0.40|0",
+		"lowpass=f=8800",
+		"volume=0.500",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("filters = %q, want %q", got, want)
+	}
+
+	// Straight ahead at 1 m is a centred pan with nothing else.
+	ahead, _ := spatialPositionFilters(nil)
+	if want := []string{"aformat=channel_layouts=mono", "pan=stereo|FL=0.707*c0|FR=0.707*c0"}; !reflect.DeepEqual(ahead, want) {
+		t.Fatalf("default position = %q", ahead)
+	}
+	left, _ := spatialPositionFilters(&models.Position{X: -1})
+	if left[1] != "pan=stereo|FL=1.000*c0|FR=0.000*c0" || left[2] != "adelay=0|0.66" {
+		t.Fatalf("hard left = %q", left)
+	}
+	above, _ := spatialPositionFilters(&models.Position{Y: 1})
+	if above[len(above)-1] != "treble=g=4.0:f=8000" {
+		t.Fatalf("overhead = %q", above)
+	}
+
+	if _, err := spatialPositionFilters(&models.Position{Z: 25}); err == nil {
+		t.Fatal("expected out-of-range coordinate to be rejected")
+	}
+}
+
+func TestPitchShiftFilter(t *testing.T) {
+	if got, want := pitchShiftFilter(&models.PitchShift{Enabled: true, Semitones: 12}), "rubberband=pitch=2.000000:pitchq=quality"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got, want := pitchShiftFilter(&models.PitchShift{Enabled: true, Semitones: -3, PreserveFormants: true}),
+		"rubberband=pitch=0.840896:pitchq=quality:formant=preserved"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
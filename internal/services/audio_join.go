@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// AudioJoinService stitches audio files end to end — podcast segments,
+// intros and outros — optionally overlapping each pair with a crossfade.
+// Inputs are conformed to one sample rate and channel layout first so the
+// concat and acrossfade filters accept any mix of sources.
+type AudioJoinService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewAudioJoinService(cfg *config.Config, jm *JobManager) *AudioJoinService {
+	return &AudioJoinService{cfg: cfg, jobManager: jm}
+}
+
+const (
+	AudioJoinMinInputs = 2
+	AudioJoinMaxInputs = 20
+
+	audioJoinMaxCrossfade = 10.0
+	audioJoinSampleRate   = 48000
+)
+
+// audioJoinCurves are the acrossfade curve names offered to clients.
+var audioJoinCurves = map[string]bool{"tri": true, "qsin": true, "hsin": true, "esin": true, "log": true, "exp": true}
+
+// AudioJoinOptions are the validated join parameters.
+type AudioJoinOptions struct {
+	// Crossfade overlaps consecutive segments by this many seconds (0, the
+	// default, butts them together).
+	Crossfade float64 `json:"crossfade"`
+	// Curve is the acrossfade fade shape applied to both sides (tri default).
+	Curve string `json:"curve"`
+	// Format is the output format: mp3 (default), wav, aac, ogg, flac, opus.
+	Format string `json:"format"`
+	// Bitrate in kbps for lossy formats (default 192).
+	Bitrate int `json:"bitrate"`
+}
+
+func (o *AudioJoinOptions) applyDefaults(inputs int) error {
+	if inputs < AudioJoinMinInputs || inputs > AudioJoinMaxInputs {
+		return fmt.Errorf("provide between %d and %d audio files", AudioJoinMinInputs, AudioJoinMaxInputs)
+	}
+	if o.Crossfade < 0 || o.Crossfade > audioJoinMaxCrossfade {
+		return fmt.Errorf("crossfade must be between 0 and %g seconds", audioJoinMaxCrossfade)
+	}
+	o.Curve = strings.ToLower(strings.TrimSpace(o.Curve))
+	if o.Curve == "" {
+		o.Curve = "tri"
+	}
+	if !audioJoinCurves[o.Curve] {
+		return fmt.Errorf("unsupported crossfade curve: %s (expected tri|qsin|hsin|esin|log|exp)", o.Curve)
+	}
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	switch o.Format {
+	case "":
+		o.Format = "mp3"
+	case "mp3", "wav", "aac", "ogg", "flac", "opus":
+	default:
+		return fmt.Errorf("unsupported output format: %s", o.Format)
+	}
+	if o.Bitrate == 0 {
+		o.Bitrate = 192
+	}
+	if o.Bitrate < 32 || o.Bitrate > 512 {
+		return errors.New("bitrate must be between 32 and 512 kbps")
+	}
+	return nil
+}
+
+// ValidateAudioJoinOptions normalizes opts for the given input count.
+func ValidateAudioJoinOptions(opts *AudioJoinOptions, inputs int) error {
+	return opts.applyDefaults(inputs)
+}
+
+// checkAudioJoinDurations rejects segments too short for the crossfade: the
+// first and last lose one fade's worth of audio, the ones in between two.
+func checkAudioJoinDurations(durations []float64, crossfade float64) error {
+	if crossfade == 0 {
+		return nil
+	}
+	for i, d := range durations {
+		need := crossfade
+		if i > 0 && i < len(durations)-1 {
+			need = 2 * crossfade
+		}
+		if d <= need {
+			return fmt.Errorf("audio %d is %.2fs long, too short for a %gs crossfade", i+1, d, crossfade)
+		}
+	}
+	return nil
+}
+
+// buildAudioJoinFilter builds the -filter_complex graph ending in [aout].
+func buildAudioJoinFilter(opts AudioJoinOptions, inputs int) string {
+	parts := make([]string, 0, inputs+1)
+	for i := 0; i < inputs; i++ {
+		parts = append(parts, fmt.Sprintf("[%d:a]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=stereo[a%d]", i, audioJoinSampleRate, i))
+	}
+	if opts.Crossfade == 0 {
+		var ins strings.Builder
+		for i := 0; i < inputs; i++ {
+			fmt.Fprintf(&ins, "[a%d]", i)
+		}
+		parts = append(parts, fmt.Sprintf("%sconcat=n=%d:v=0:a=1[aout]", ins.String(), inputs))
+		return strings.Join(parts, ";")
+	}
+	// acrossfade takes two inputs, so the segments are folded in one at a time.
+	prev := "[a0]"
+	for i := 1; i < inputs; i++ {
+		out := fmt.Sprintf("[x%d]", i)
+		if i == inputs-1 {
+			out = "[aout]"
+		}
+		parts = append(parts, fmt.Sprintf("%s[a%d]acrossfade=d=%.3f:c1=%s:c2=%s%s", prev, i, opts.Crossfade, opts.Curve, opts.Curve, out))
+		prev = out
+	}
+	return strings.Join(parts, ";")
+}
+
+// buildAudioJoinArgs assembles the full ffmpeg command.
+func buildAudioJoinArgs(inputs []string, opts AudioJoinOptions, outputPath string) []string {
+	args := []string{"-hide_banner", "-y"}
+	for _, in := range inputs {
+		args = append(args, "-i", in)
+	}
+	args = append(args, "-filter_complex", buildAudioJoinFilter(opts, len(inputs)), "-map", "[aout]")
+	args = append(args, audioCodecArgs(opts.Format)...)
+	if opts.Format != "wav" && opts.Format != "flac" {
+		args = append(args, "-b:a", fmt.Sprintf("%dk", opts.Bitrate))
+	}
+	return append(args, outputPath)
+}
+
+// Join renders the joined audio to outputPath.
+func (s *AudioJoinService) Join(ctx context.Context, job *models.ConversionJob, inputs []string, opts AudioJoinOptions, outputPath string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+	if err := opts.applyDefaults(len(inputs)); err != nil {
+		return err
+	}
+	durations := make([]float64, len(inputs))
+	for i, in := range inputs {
+		if !ffprobeHasStream(ctx, in, "a") {
+			return fmt.Errorf("input %d has no audio stream", i+1)
+		}
+		d, err := probeMediaDurationSeconds(ctx, in)
+		if err != nil {
+			return fmt.Errorf("could not read the duration of audio %d: %w", i+1, err)
+		}
+		durations[i] = d
+	}
+	if err := checkAudioJoinDurations(durations, opts.Crossfade); err != nil {
+		return err
+	}
+	s.progress(job.ID, 10)
+	_, stderr, err := runCommand(ctx, "ffmpeg", buildAudioJoinArgs(inputs, opts, outputPath)...)
+	if err != nil {
+		return fmt.Errorf("audio join failed: %w (%s)", err, commandTail(stderr, 1000))
+	}
+	if err := requireNonEmpty(outputPath); err != nil {
+		return fmt.Errorf("audio join produced no output: %w", err)
+	}
+	s.progress(job.ID, 95)
+	return nil
+}
+
+func (s *AudioJoinService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
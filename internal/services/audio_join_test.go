@@ -0,0 +1,71 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateAudioJoinOptions(t *testing.T) {
+	opts := AudioJoinOptions{}
+	if err := ValidateAudioJoinOptions(&opts, 2); err != nil {
+		t.Fatalf("defaults rejected: %v", err)
+	}
+	if opts.Curve != "tri" || opts.Format != "mp3" || opts.Bitrate != 192 {
+		t.Fatalf("defaults not applied: %+v", opts)
+	}
+	for name, tc := range map[string]struct {
+		opts   AudioJoinOptions
+		inputs int
+	}{
+		"one input":      {AudioJoinOptions{}, 1},
+		"too many":       {AudioJoinOptions{}, 21},
+		"long crossfade": {AudioJoinOptions{Crossfade: 12}, 2},
+		"bad curve":      {AudioJoinOptions{Curve: "square"}, 2},
+		"bad format":     {AudioJoinOptions{Format: "dts"}, 2},
+		"bad bitrate":    {AudioJoinOptions{Bitrate: 8}, 2},
+	} {
+		o := tc.opts
+		if err := ValidateAudioJoinOptions(&o, tc.inputs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBuildAudioJoinArgs(t *testing.T) {
+	opts := AudioJoinOptions{Crossfade: 1.5, Curve: "qsin", Format: "flac"}
+	if err := opts.applyDefaults(3); err != nil {
+		t.Fatal(err)
+	}
+	got := buildAudioJoinArgs([]string{"intro.wav", "talk.mp3", "outro.ogg"}, opts, "out.flac")
+	want := []string{"-hide_banner", "-y", "-i", "intro.wav", "-i", "talk.mp3", "-i", "outro.ogg",
+		"-filter_complex",
+		"[0:a]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=stereo[a0];" +
+			"[1:a]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=stereo[a1];" +
+			"[2:a]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=stereo[a2];" +
+			"[a0][a1]acrossfade=d=1.500:c1=qsin:c2=qsin[x1];" +
+			"[x1][a2]acrossfade=d=1.500:c1=qsin:c2=qsin[aout]",
+		"-map", "[aout]", "-c:a", "flac", "out.flac"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args:\n%q\nwant\n%q", got, want)
+	}
+
+	opts = AudioJoinOptions{}
+	_ = opts.applyDefaults(2)
+	if graph := buildAudioJoinFilter(opts, 2); !strings.HasSuffix(graph, ";[a0][a1]concat=n=2:v=0:a=1[aout]") {
+		t.Fatalf("plain join should concat: %s", graph)
+	}
+}
+
+func TestCheckAudioJoinDurations(t *testing.T) {
+	if err := checkAudioJoinDurations([]float64{3, 5, 3}, 2); err != nil {
+		t.Fatalf("valid durations rejected: %v", err)
+	}
+	// The middle segment loses a fade at both ends.
+	if err := checkAudioJoinDurations([]float64{3, 3.5, 3}, 2); err == nil {
+		t.Fatal("expected the middle segment to be too short")
+	}
+	if err := checkAudioJoinDurations([]float64{0.2, 0.2}, 0); err != nil {
+		t.Fatalf("no crossfade should accept any length: %v", err)
+	}
+}
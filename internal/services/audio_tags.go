@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Audio tags map onto FFmpeg's generic metadata keys, which each muxer
+// translates to its own scheme: ID3v2 frames for MP3 (TIT2, TPE1, …),
+// Vorbis comments for OGG/Opus/FLAC, iTunes atoms for ALAC and RIFF INFO
+// chunks for WAV.
+
+const maxAudioTagLength = 1024
+
+var audioTagYear = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// audioTagKeys pairs each field with its FFmpeg metadata key.
+func audioTagKeys(tags *models.AudioTags) []struct{ key, value string } {
+	return []struct{ key, value string }{
+		{"title", tags.Title},
+		{"artist", tags.Artist},
+		{"album", tags.Album},
+		{"date", tags.Year},
+		{"genre", tags.Genre},
+		{"comment", tags.Comment},
+	}
+}
+
+func validateAudioTags(tags *models.AudioTags, format string) error {
+	if tags == nil {
+		return nil
+	}
+	switch format {
+	case "ac3", "dts":
+		// Raw elementary streams have nowhere to put tags.
+		return fmt.Errorf("tags are not supported for %s output", format)
+	}
+	for _, t := range audioTagKeys(tags) {
+		if len(t.value) > maxAudioTagLength {
+			return fmt.Errorf("tag %s must be at most %d bytes", t.key, maxAudioTagLength)
+		}
+		if !utf8.ValidString(t.value) || strings.ContainsFunc(t.value, func(r rune) bool { return r < 0x20 && r != '\n' && r != '\t' }) {
+			return fmt.Errorf("tag %s contains invalid characters", t.key)
+		}
+	}
+	if tags.Year != "" && !audioTagYear.MatchString(tags.Year) {
+		return fmt.Errorf("tag year must be YYYY or YYYY-MM-DD, got %q", tags.Year)
+	}
+	return nil
+}
+
+// audioTagArgs returns the -metadata flags for the non-empty tags. MP3 gets
+// ID3v2.3, which more players read than FFmpeg's default v2.4, and raw AAC
+// (ADTS) only carries tags in an ID3v2 header when asked to.
+func audioTagArgs(tags *models.AudioTags, format string) []string {
+	if tags == nil {
+		return nil
+	}
+	var args []string
+	for _, t := range audioTagKeys(tags) {
+		if v := strings.TrimSpace(t.value); v != "" {
+			args = append(args, "-metadata", t.key+"="+v)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	switch format {
+	case "mp3":
+		args = append(args, "-id3v2_version", "3")
+	case "aac":
+		args = append(args, "-write_id3v2", "1")
+	}
+	return args
+}
+
+// audioTagsFromProbe reads the tags out of ffprobe -show_format -show_streams
+// JSON. Container tags win; Ogg and Opus keep their comments on the audio
+// stream instead, so those fill the gaps. Keys are matched
+// case-insensitively (FLAC writes TITLE, MP3 title). Returns nil when the
+// file has none of the fields.
+func audioTagsFromProbe(details map[string]any) *models.AudioTags {
+	sources := []map[string]any{}
+	if format, ok := details["format"].(map[string]any); ok {
+		if tags, ok := format["tags"].(map[string]any); ok {
+			sources = append(sources, tags)
+		}
+	}
+	streams, _ := details["streams"].([]any)
+	for _, raw := range streams {
+		stream, _ := raw.(map[string]any)
+		if stream["codec_type"] != "audio" {
+			continue
+		}
+		if tags, ok := stream["tags"].(map[string]any); ok {
+			sources = append(sources, tags)
+		}
+		break
+	}
+
+	lookup := func(keys ...string) string {
+		for _, src := range sources {
+			for _, want := range keys {
+				for k, v := range src {
+					if s, ok := v.(string); ok && strings.EqualFold(k, want) && strings.TrimSpace(s) != "" {
+						return strings.TrimSpace(s)
+					}
+				}
+			}
+		}
+		return ""
+	}
+	tags := &models.AudioTags{
+		Title:   lookup("title"),
+		Artist:  lookup("artist"),
+		Album:   lookup("album"),
+		Year:    lookup("date", "year"),
+		Genre:   lookup("genre"),
+		Comment: lookup("comment", "description"),
+	}
+	if *tags == (models.AudioTags{}) {
+		return nil
+	}
+	return tags
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestAudioTagsFromProbe(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw  string
+		want *models.AudioTags
+	}{
+		"mp3 container tags": {
+			raw:  `{"format":{"tags":{"title":"Song","artist":"Band","album":"LP","date":"1999","genre":"Rock","comment":"remaster","encoder":"Lavf"}}}`,
+			want: &models.AudioTags{Title: "Song", Artist: "Band", Album: "LP", Year: "1999", Genre: "Rock", Comment: "remaster"},
+		},
+		"ogg stream comments": {
+			raw:  `{"format":{"tags":{"ALBUM":"Container LP"}},"streams":[{"codec_type":"audio","tags":{"TITLE":"Song","ALBUM":"Stream LP","DATE":"2020-05-01"}}]}`,
+			want: &models.AudioTags{Title: "Song", Album: "Container LP", Year: "2020-05-01"},
+		},
+		"untagged": {
+			raw: `{"format":{"tags":{"encoder":"Lavf"}},"streams":[{"codec_type":"audio"}]}`,
+		},
+	} {
+		var details map[string]any
+		if err := json.Unmarshal([]byte(tc.raw), &details); err != nil {
+			t.Fatal(err)
+		}
+		if got := audioTagsFromProbe(details); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, tc.want)
+		}
+	}
+}
+
+func TestAudioTagArgs(t *testing.T) {
+	tags := &models.AudioTags{Title: "Song", Artist: " ", Year: "1999"}
+	want := []string{"-metadata", "title=Song", "-metadata", "date=1999", "-id3v2_version", "3"}
+	if got := audioTagArgs(tags, "mp3"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("mp3 args = %q, want %q", got, want)
+	}
+	if got := audioTagArgs(tags, "aac"); valueAfter(got, "-write_id3v2") != "1" {
+		t.Fatalf("aac args = %q", got)
+	}
+	if audioTagArgs(&models.AudioTags{}, "flac") != nil {
+		t.Fatal("empty tags should add no flags")
+	}
+}
+
+func TestValidateAudioTags(t *testing.T) {
+	if err := validateAudioTags(&models.AudioTags{Title: "Song", Year: "1999-12"}, "flac"); err != nil {
+		t.Fatalf("valid tags rejected: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		tags   models.AudioTags
+		format string
+	}{
+		"raw stream": {models.AudioTags{Title: "Song"}, "ac3"},
+		"bad year":   {models.AudioTags{Year: "99"}, "mp3"},
+		"control":    {models.AudioTags{Comment: "a\x00b"}, "mp3"},
+	} {
+		if err := validateAudioTags(&tc.tags, tc.format); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Multi-track sources (MKV/MOV with several languages, commentary, a 5.1
+// and a stereo mix) otherwise come out with whichever single audio stream
+// FFmpeg's default selection picks. AudioTracks on the video options maps
+// an explicit set instead, addressed by zero-based audio index — the
+// "index" of each entry in the audioTracks list /api/details returns.
+
+const maxAudioTracks = 16
+
+// audioTracksFromProbe lists the audio streams in ffprobe -show_streams JSON.
+func audioTracksFromProbe(details map[string]any) []models.AudioTrack {
+	streams, _ := details["streams"].([]any)
+	var tracks []models.AudioTrack
+	for _, raw := range streams {
+		stream, _ := raw.(map[string]any)
+		if stream == nil || stream["codec_type"] != "audio" {
+			continue
+		}
+		str := func(m map[string]any, key string) string { v, _ := m[key].(string); return v }
+		num := func(v any) int {
+			switch n := v.(type) {
+			case float64:
+				return int(n)
+			case string:
+				i, _ := strconv.Atoi(n)
+				return i
+			}
+			return 0
+		}
+		tags, _ := stream["tags"].(map[string]any)
+		disposition, _ := stream["disposition"].(map[string]any)
+		tracks = append(tracks, models.AudioTrack{
+			Index:         len(tracks),
+			StreamIndex:   num(stream["index"]),
+			Codec:         str(stream, "codec_name"),
+			Channels:      num(stream["channels"]),
+			ChannelLayout: str(stream, "channel_layout"),
+			SampleRate:    num(stream["sample_rate"]),
+			Language:      str(tags, "language"),
+			Title:         str(tags, "title"),
+			Default:       num(disposition["default"]) == 1,
+		})
+	}
+	return tracks
+}
+
+func validateAudioTracks(tracks []int, stripAudio bool) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	if stripAudio {
+		return fmt.Errorf("audioTracks cannot be combined with stripAudio")
+	}
+	if len(tracks) > maxAudioTracks {
+		return fmt.Errorf("at most %d audio tracks can be selected, got %d", maxAudioTracks, len(tracks))
+	}
+	seen := make(map[int]bool, len(tracks))
+	for _, t := range tracks {
+		if t < 0 {
+			return fmt.Errorf("audio track index must be non-negative, got %d", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("audio track %d is selected more than once", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// countAudioStreams returns how many audio streams path has.
+func countAudioStreams(ctx context.Context, path string) (int, error) {
+	stdout, stderr, err := runCommand(ctx, "ffprobe", "-v", "error", "-select_streams", "a", "-show_entries", "stream=index", "-of", "csv=p=0", path)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe audio streams: %w (%s)", err, commandTail(stderr, 300))
+	}
+	return len(strings.Fields(stdout)), nil
+}
+
+// checkAudioTracksExist fails with a readable message when a selected track
+// is past the end of the input's audio streams, rather than letting FFmpeg
+// report "Stream map '0:a:3' matches no streams".
+func checkAudioTracksExist(ctx context.Context, path string, tracks []int) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	n, err := countAudioStreams(ctx, path)
+	if err != nil {
+		return err
+	}
+	for _, t := range tracks {
+		if t >= n {
+			return fmt.Errorf("audio track %d does not exist (input has %d audio tracks)", t, n)
+		}
+	}
+	return nil
+}
+
+// audioTrackMapArgs maps the first video stream plus the selected audio
+// tracks in the order given. Dispositions are copied from the input by
+// default, so they are reset to make the first selected track the default.
+func audioTrackMapArgs(tracks []int) []string {
+	if len(tracks) == 0 {
+		return nil
+	}
+	args := []string{"-map", "0:v:0"}
+	for _, t := range tracks {
+		args = append(args, "-map", fmt.Sprintf("0:a:%d", t))
+	}
+	args = append(args, "-disposition:a:0", "default")
+	for i := 1; i < len(tracks); i++ {
+		args = append(args, fmt.Sprintf("-disposition:a:%d", i), "0")
+	}
+	return args
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestAudioTracksFromProbe(t *testing.T) {
+	raw := `{"streams":[
+		{"index":0,"codec_type":"video","codec_name":"h264"},
+		{"index":1,"codec_type":"audio","codec_name":"ac3","channels":6,"channel_layout":"5.1(side)","sample_rate":"48000","tags":{"language":"eng","title":"Surround"},"disposition":{"default":1}},
+		{"index":2,"codec_type":"subtitle","codec_name":"subrip"},
+		{"index":3,"codec_type":"audio","codec_name":"aac","channels":2,"sample_rate":"44100","tags":{"language":"spa"},"disposition":{"default":0}}
+	]}`
+	var details map[string]any
+	if err := json.Unmarshal([]byte(raw), &details); err != nil {
+		t.Fatal(err)
+	}
+	got := audioTracksFromProbe(details)
+	want := []models.AudioTrack{
+		{Index: 0, StreamIndex: 1, Codec: "ac3", Channels: 6, ChannelLayout: "5.1(side)", SampleRate: 48000, Language: "eng", Title: "Surround", Default: true},
+		{Index: 1, StreamIndex: 3, Codec: "aac", Channels: 2, SampleRate: 44100, Language: "spa"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audioTracksFromProbe = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateAudioTracks(t *testing.T) {
+	if err := validateAudioTracks(nil, true); err != nil {
+		t.Fatalf("empty selection should pass: %v", err)
+	}
+	if err := validateAudioTracks([]int{1, 0}, false); err != nil {
+		t.Fatalf("valid selection rejected: %v", err)
+	}
+	for _, tc := range []struct {
+		tracks []int
+		strip  bool
+	}{
+		{[]int{0}, true},
+		{[]int{-1}, false},
+		{[]int{1, 1}, false},
+		{make([]int, maxAudioTracks+1), false},
+	} {
+		if err := validateAudioTracks(tc.tracks, tc.strip); err == nil {
+			t.Errorf("validateAudioTracks(%v, %v) should fail", tc.tracks, tc.strip)
+		}
+	}
+}
+
+func TestAudioTrackMapArgs(t *testing.T) {
+	got := audioTrackMapArgs([]int{2, 0})
+	want := []string{"-map", "0:v:0", "-map", "0:a:2", "-map", "0:a:0", "-disposition:a:0", "default", "-disposition:a:1", "0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audioTrackMapArgs = %v, want %v", got, want)
+	}
+	if audioTrackMapArgs(nil) != nil {
+		t.Fatal("no selection should add no -map flags")
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// BatchImageService converts a set of uploaded images with one shared base
+// options object plus optional per-file overrides (a different crop for one
+// image, a different format for another), and packages every output together
+// with a summary.json into a single ZIP. Each file goes through the regular
+// Converter image path, so batch output matches single-file output exactly.
+// A failed file does not fail the batch; it's reported in the summary.
+type BatchImageService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+	converter  *Converter
+}
+
+func NewBatchImageService(cfg *config.Config, jm *JobManager, converter *Converter) *BatchImageService {
+	return &BatchImageService{cfg: cfg, jobManager: jm, converter: converter}
+}
+
+// BatchImageMaxFiles bounds one batch.
+const BatchImageMaxFiles = 100
+
+// BatchImageInput is one staged upload.
+type BatchImageInput struct {
+	Name string // client filename, sanitized
+	Path string
+	Type string // MIME type
+}
+
+// BatchImageFileResult is one row of the summary report.
+type BatchImageFileResult struct {
+	Index    int                    `json:"index"`
+	Name     string                 `json:"name"`
+	Output   string                 `json:"output,omitempty"`
+	Status   string                 `json:"status"` // ok | failed
+	Error    string                 `json:"error,omitempty"`
+	Warnings []string               `json:"warnings,omitempty"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+// BatchImageSummary is written to summary.json at the root of the ZIP.
+type BatchImageSummary struct {
+	Total     int                    `json:"total"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Warnings  []string               `json:"warnings,omitempty"`
+	Files     []BatchImageFileResult `json:"files"`
+}
+
+// MergeBatchImageOptions overlays override onto base. Nested objects (crop,
+// textOverlay, metadata, ...) merge key by key; an explicit null in the
+// override removes the key. Neither input is modified.
+func MergeBatchImageOptions(base, override map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range override {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		if ov, ok := v.(map[string]interface{}); ok {
+			if bv, ok := out[k].(map[string]interface{}); ok {
+				out[k] = MergeBatchImageOptions(bv, ov)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// ResolveBatchImageOverrides maps override keys onto files. A key is either
+// the file's name or its zero-based upload index ("0", "1", ...); when both
+// match the same file the index wins. Keys that match nothing come back as
+// warnings rather than errors so a stale client doesn't fail the batch.
+func ResolveBatchImageOverrides(names []string, overrides map[string]map[string]interface{}) ([]map[string]interface{}, []string) {
+	perFile := make([]map[string]interface{}, len(names))
+	byName := make(map[string][]int, len(names))
+	for i, n := range names {
+		byName[n] = append(byName[n], i)
+	}
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var warnings []string
+	// Names first, then indexes, so an index override lands on top.
+	for _, k := range keys {
+		idxs, ok := byName[k]
+		if !ok {
+			continue
+		}
+		if len(idxs) > 1 {
+			warnings = append(warnings, fmt.Sprintf("override %q matches %d files with the same name; applied to all", k, len(idxs)))
+		}
+		for _, i := range idxs {
+			perFile[i] = overrides[k]
+		}
+	}
+	for _, k := range keys {
+		if _, ok := byName[k]; ok {
+			continue
+		}
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(names) {
+			warnings = append(warnings, fmt.Sprintf("override %q does not match any uploaded file", k))
+			continue
+		}
+		if perFile[i] != nil {
+			perFile[i] = MergeBatchImageOptions(perFile[i], overrides[k])
+		} else {
+			perFile[i] = overrides[k]
+		}
+	}
+	return perFile, warnings
+}
+
+// batchImageOutputExtension mirrors the single-file handler's extension rule
+// for image jobs.
+func batchImageOutputExtension(options map[string]interface{}) string {
+	if format, _ := options["format"].(string); strings.TrimSpace(format) != "" {
+		return "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(format)), ".")
+	}
+	return ".jpg"
+}
+
+// uniqueBatchName returns name, or name_2, name_3 ... if already taken.
+func uniqueBatchName(used map[string]bool, stem, ext string) string {
+	name := stem + ext
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s_%d%s", stem, n, ext)
+	}
+	used[name] = true
+	return name
+}
+
+// Run converts every input and writes the ZIP to outputPath.
+func (s *BatchImageService) Run(ctx context.Context, job *models.ConversionJob, inputs []BatchImageInput, base map[string]interface{}, overrides map[string]map[string]interface{}, outputPath string) (*BatchImageSummary, error) {
+	if s.converter == nil {
+		return nil, errors.New("converter is not available")
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("no images to process")
+	}
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "batch-")
+	if err != nil {
+		return nil, fmt.Errorf("create batch workdir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	names := make([]string, len(inputs))
+	for i, in := range inputs {
+		names[i] = in.Name
+	}
+	perFile, warnings := ResolveBatchImageOverrides(names, overrides)
+	summary := &BatchImageSummary{Total: len(inputs), Warnings: warnings}
+	used := map[string]bool{"summary.json": true}
+	var produced []string
+
+	for i, in := range inputs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts := MergeBatchImageOptions(base, perFile[i])
+		res := BatchImageFileResult{Index: i, Name: in.Name, Options: opts}
+		if perFile[i] != nil {
+			if f, ok := perFile[i]["format"].(string); ok && f != "" {
+				if bf, _ := base["format"].(string); bf != "" && !strings.EqualFold(bf, f) {
+					res.Warnings = append(res.Warnings, fmt.Sprintf("format overridden: %s -> %s", bf, f))
+				}
+			}
+		}
+
+		stem := strings.TrimSuffix(in.Name, filepath.Ext(in.Name))
+		if stem == "" {
+			stem = fmt.Sprintf("image_%d", i+1)
+		}
+		outName := uniqueBatchName(used, stem, batchImageOutputExtension(opts))
+		if outName != stem+batchImageOutputExtension(opts) {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("renamed to %s to avoid a name collision", outName))
+		}
+		outPath := filepath.Join(workDir, outName)
+
+		err := s.convertOne(job.ID, i, in, opts, outPath)
+		if err != nil {
+			res.Status = "failed"
+			res.Error = err.Error()
+			summary.Failed++
+		} else {
+			res.Status = "ok"
+			res.Output = outName
+			summary.Succeeded++
+			produced = append(produced, outPath)
+		}
+		summary.Files = append(summary.Files, res)
+		s.progress(job.ID, 5+90*(i+1)/len(inputs))
+	}
+
+	summaryPath := filepath.Join(workDir, "summary.json")
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode batch summary: %w", err)
+	}
+	if err := os.WriteFile(summaryPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("write batch summary: %w", err)
+	}
+	if summary.Succeeded == 0 {
+		return summary, fmt.Errorf("all %d images failed; first error: %s", summary.Total, summary.Files[0].Error)
+	}
+	if err := zipFiles(outputPath, append([]string{summaryPath}, produced...)); err != nil {
+		return nil, fmt.Errorf("package batch output: %w", err)
+	}
+	return summary, nil
+}
+
+// convertOne runs a single image through the Converter under a throwaway job
+// record. The child ID is never registered with the JobManager, so the
+// converter's own progress updates for it are dropped and the batch job's
+// progress stays monotonic.
+func (s *BatchImageService) convertOne(parentID string, index int, in BatchImageInput, opts map[string]interface{}, outPath string) error {
+	if ai, ok := opts["ai"].(map[string]interface{}); ok {
+		if enabled, _ := ai["enabled"].(bool); enabled {
+			return errors.New("AI operations are not supported in batch jobs")
+		}
+	}
+	if err := s.converter.ValidateImageOptions(opts); err != nil {
+		return err
+	}
+	child := &models.ConversionJob{
+		ID:           fmt.Sprintf("%s-batch-%d", parentID, index),
+		Status:       models.StatusProcessing,
+		OriginalFile: models.OriginalFileInfo{Name: in.Name, Type: in.Type},
+		Options:      opts,
+	}
+	return s.converter.ConvertFile(child, in.Path, outPath)
+}
+
+func (s *BatchImageService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeBatchImageOptions(t *testing.T) {
+	base := map[string]interface{}{
+		"format":  "webp",
+		"quality": float64(80),
+		"crop":    map[string]interface{}{"x": float64(0), "y": float64(0), "width": float64(100), "height": float64(100)},
+		"tint":    "#ff0000",
+	}
+	override := map[string]interface{}{
+		"crop": map[string]interface{}{"x": float64(20)},
+		"tint": nil,
+	}
+	got := MergeBatchImageOptions(base, override)
+	want := map[string]interface{}{
+		"format":  "webp",
+		"quality": float64(80),
+		"crop":    map[string]interface{}{"x": float64(20), "y": float64(0), "width": float64(100), "height": float64(100)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merge =\n%v\nwant\n%v", got, want)
+	}
+	if base["tint"] != "#ff0000" || base["crop"].(map[string]interface{})["x"] != float64(0) {
+		t.Fatal("base options were mutated")
+	}
+}
+
+func TestResolveBatchImageOverrides(t *testing.T) {
+	names := []string{"a.jpg", "b.jpg", "a.jpg"}
+	overrides := map[string]map[string]interface{}{
+		"a.jpg":   {"quality": float64(50)},
+		"2":       {"format": "png"},
+		"missing": {"quality": float64(10)},
+		"7":       {"quality": float64(10)},
+		"b.jpg":   {"format": "avif"},
+	}
+	perFile, warnings := ResolveBatchImageOverrides(names, overrides)
+	if perFile[0]["quality"] != float64(50) || perFile[0]["format"] != nil {
+		t.Errorf("file 0 = %v", perFile[0])
+	}
+	if perFile[1]["format"] != "avif" {
+		t.Errorf("file 1 = %v", perFile[1])
+	}
+	// Index override layers on top of the name override.
+	if perFile[2]["quality"] != float64(50) || perFile[2]["format"] != "png" {
+		t.Errorf("file 2 = %v", perFile[2])
+	}
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{`"missing"`, `"7"`, "same name"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("warnings missing %s: %v", want, warnings)
+		}
+	}
+}
+
+func TestUniqueBatchName(t *testing.T) {
+	used := map[string]bool{"summary.json": true}
+	if got := uniqueBatchName(used, "photo", ".png"); got != "photo.png" {
+		t.Fatalf("first = %s", got)
+	}
+	if got := uniqueBatchName(used, "photo", ".png"); got != "photo_2.png" {
+		t.Fatalf("second = %s", got)
+	}
+	if got := uniqueBatchName(used, "summary", ".json"); got != "summary_2.json" {
+		t.Fatalf("summary collision = %s", got)
+	}
+}
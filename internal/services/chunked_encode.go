@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Chunk-parallel encoding: the video stream is stream-copied into chunks at
+// keyframes (the segment muxer only cuts on keyframes, so no frame is
+// re-encoded twice or dropped), the chunks are encoded concurrently with the
+// job's filter chain and codec settings, and the encoded chunks are joined
+// with the concat demuxer. Audio never goes through the chunks: the final
+// mux encodes it in one pass from the original input, so loudness
+// normalization and tempo changes see the whole track.
+//
+// Each chunk starts its encoder from a fresh rate-control state, which costs
+// a little bitrate at every boundary — the trade for wall-clock time.
+
+const (
+	defaultChunkSeconds = 60
+	minChunkSeconds     = 10
+	maxChunkSeconds     = 600
+	maxChunkWorkers     = 64
+)
+
+// validateChunkedEncoding rejects options that can't be split: filters that
+// need the whole timeline (reverse, a running timecode), trims (stream-copy
+// cuts snap to keyframes) and the GIF pipeline.
+func validateChunkedEncoding(options *models.VideoConversionOptions) error {
+	ch := options.Chunked
+	if ch == nil || !ch.Enabled {
+		return nil
+	}
+	if ch.ChunkSeconds != 0 && (math.IsNaN(ch.ChunkSeconds) || ch.ChunkSeconds < minChunkSeconds || ch.ChunkSeconds > maxChunkSeconds) {
+		return fmt.Errorf("chunked.chunkSeconds must be between %d and %d, got %v", minChunkSeconds, maxChunkSeconds, ch.ChunkSeconds)
+	}
+	if ch.Workers < 0 || ch.Workers > maxChunkWorkers {
+		return fmt.Errorf("chunked.workers must be between 0 and %d, got %d", maxChunkWorkers, ch.Workers)
+	}
+	switch {
+	case strings.EqualFold(options.Format, "gif"):
+		return errors.New("chunked encoding is not available for GIF output")
+	case options.Trim != nil || len(options.TrimSegments) > 0:
+		return errors.New("chunked encoding cannot be combined with trim")
+	case options.Temporal != nil && options.Temporal.Reverse != nil && *options.Temporal.Reverse:
+		return errors.New("chunked encoding cannot be combined with reverse")
+	case options.Timecode != nil && options.Timecode.Enabled:
+		return errors.New("chunked encoding cannot be combined with a timecode burn-in")
+	}
+	return nil
+}
+
+// chunkWorkerCount resolves the requested concurrency against the server
+// limit and the number of chunks.
+func chunkWorkerCount(requested, limit, chunks int) int {
+	if limit < 1 {
+		limit = 1
+	}
+	workers := limit
+	if requested > 0 && requested < limit {
+		workers = requested
+	}
+	return max(1, min(workers, chunks))
+}
+
+// splitChunkCodecArgs divides buildVideoCodecArgs output into the flags each
+// chunk encode needs (video codec and rate control) and the flags of the
+// final mux (audio codec, container flags). The codec tag is needed in both:
+// stream copy doesn't carry it into the MP4/MOV muxer.
+func splitChunkCodecArgs(codecArgs []string) (videoArgs, muxArgs []string) {
+	for i := 0; i < len(codecArgs); i++ {
+		flag := codecArgs[i]
+		switch flag {
+		case "-an":
+			muxArgs = append(muxArgs, flag)
+		case "-c:a", "-b:a", "-movflags":
+			if i+1 < len(codecArgs) {
+				muxArgs = append(muxArgs, flag, codecArgs[i+1])
+				i++
+			}
+		case "-tag:v":
+			if i+1 < len(codecArgs) {
+				videoArgs = append(videoArgs, flag, codecArgs[i+1])
+				muxArgs = append(muxArgs, flag, codecArgs[i+1])
+				i++
+			}
+		default:
+			videoArgs = append(videoArgs, flag)
+		}
+	}
+	return videoArgs, muxArgs
+}
+
+// chunkSplitArgs stream-copies the first video stream into src0000.mkv,
+// src0001.mkv, … in workDir.
+func chunkSplitArgs(inputPath, workDir string, chunkSeconds float64) []string {
+	return []string{
+		"-hide_banner", "-i", inputPath,
+		"-map", "0:v:0", "-c", "copy", "-an", "-sn", "-dn",
+		"-f", "segment", "-segment_time", strconv.FormatFloat(chunkSeconds, 'f', -1, 64),
+		"-reset_timestamps", "1",
+		"-y", filepath.Join(workDir, "src%04d.mkv"),
+	}
+}
+
+// chunkEncodeArgs encodes one chunk video-only with the job's filter chain.
+func chunkEncodeArgs(sourcePath, encodedPath string, videoFilters, videoArgs []string, threads int) []string {
+	args := []string{"-hide_banner", "-i", sourcePath, "-map", "0:v:0"}
+	if len(videoFilters) > 0 {
+		args = append(args, "-vf", strings.Join(videoFilters, ","))
+	}
+	args = append(args, videoArgs...)
+	return append(args, "-threads", strconv.Itoa(threads), "-an", "-y", encodedPath)
+}
+
+// chunkMuxArgs joins the encoded chunks listed in listPath and encodes the
+// audio from the original input alongside them. The original is input 0 so
+// audioTrackMapArgs' stream specifiers apply unchanged.
+func chunkMuxArgs(inputPath, listPath, outputPath string, options *models.VideoConversionOptions, audioFilters, muxArgs []string) []string {
+	args := []string{"-hide_banner", "-i", inputPath, "-f", "concat", "-safe", "0", "-i", listPath, "-map", "1:v:0"}
+	if !options.StripAudio {
+		if len(options.AudioTracks) > 0 {
+			// Drop the leading "-map 0:v:0"; the video comes from the chunks.
+			args = append(args, audioTrackMapArgs(options.AudioTracks)[2:]...)
+		} else {
+			args = append(args, "-map", "0:a:0?")
+		}
+		if len(audioFilters) > 0 {
+			args = append(args, "-af", strings.Join(audioFilters, ","))
+		}
+	}
+	args = append(args, "-c:v", "copy")
+	args = append(args, muxArgs...)
+	if options.StripMetadata {
+		args = append(args, "-map_metadata", "-1", "-map_chapters", "-1")
+	}
+	return append(args, "-y", outputPath)
+}
+
+// encodeVideoChunked runs the chunked pipeline. It returns false without
+// writing the output when the source is too short to be worth splitting
+// (or splits into a single chunk), so the caller encodes it in one pass.
+func (c *Converter) encodeVideoChunked(jobID, inputPath, outputPath string, options *models.VideoConversionOptions, videoFilters, audioFilters []string, settings videoEncodeSettings) (bool, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return false, fmt.Errorf("ffmpeg is required for video and audio processing but was not found on PATH — install FFmpeg (apt install ffmpeg / brew install ffmpeg) or see https://ffmpeg.org/download.html")
+	}
+	chunkSeconds := options.Chunked.ChunkSeconds
+	if chunkSeconds == 0 {
+		chunkSeconds = defaultChunkSeconds
+	}
+
+	ctx, cancel := c.commandContext(jobID)
+	defer cancel()
+
+	duration, err := probeMediaDurationSeconds(ctx, inputPath)
+	if err != nil || duration < 2*chunkSeconds {
+		c.jobLogger(jobID).Debug("chunked encoding skipped: too short", "durationSeconds", duration, "chunkSeconds", chunkSeconds)
+		return false, nil
+	}
+
+	workDir, err := os.MkdirTemp(c.cfg.TempDir, "chunked-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create chunk directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	run := func(ctx context.Context, args []string) error {
+		done := c.recordCommand(jobID, "ffmpeg", args)
+		_, stderr, err := runCommand(ctx, "ffmpeg", args...)
+		done(stderr, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("FFmpeg timed out: %w", context.Cause(ctx))
+			}
+			return fmt.Errorf("%v. FFmpeg stderr: %s", err, commandTail(stderr, 4000))
+		}
+		return nil
+	}
+
+	if err := run(ctx, chunkSplitArgs(inputPath, workDir, chunkSeconds)); err != nil {
+		return false, fmt.Errorf("failed to split video into chunks: %w", err)
+	}
+	sources, _ := filepath.Glob(filepath.Join(workDir, "src*.mkv"))
+	sort.Strings(sources)
+	if len(sources) < 2 {
+		c.jobLogger(jobID).Debug("chunked encoding skipped: too few keyframes")
+		return false, nil
+	}
+	c.reportProgress(jobID, 65)
+
+	videoArgs, muxArgs := splitChunkCodecArgs(buildVideoCodecArgs(settings))
+	workers := chunkWorkerCount(options.Chunked.Workers, c.cfg.ChunkEncodeWorkers, len(sources))
+	threads := max(1, runtime.NumCPU()/workers)
+	c.jobLogger(jobID).Debug("chunked encoding", "chunks", len(sources), "workers", workers, "threads", threads)
+
+	encoded := make([]string, len(sources))
+	for i := range sources {
+		encoded[i] = filepath.Join(workDir, fmt.Sprintf("enc%04d.mkv", i))
+	}
+
+	encodeCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		done     int
+	)
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				err := run(encodeCtx, chunkEncodeArgs(sources[i], encoded[i], videoFilters, videoArgs, threads))
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to encode chunk %d: %w", i, err)
+					stop()
+				}
+				done++
+				progress := 65 + 25*done/len(sources)
+				mu.Unlock()
+				if err == nil {
+					c.reportProgress(jobID, progress)
+				}
+			}
+		}()
+	}
+	for i := range sources {
+		if encodeCtx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if firstErr != nil {
+		return false, firstErr
+	}
+
+	var list strings.Builder
+	for _, path := range encoded {
+		fmt.Fprintf(&list, "file '%s'\n", filepath.Base(path))
+	}
+	listPath := filepath.Join(workDir, "chunks.txt")
+	if err := os.WriteFile(listPath, []byte(list.String()), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write chunk list: %v", err)
+	}
+	if err := run(ctx, chunkMuxArgs(inputPath, listPath, outputPath, options, audioFilters, muxArgs)); err != nil {
+		return false, fmt.Errorf("failed to join encoded chunks: %w", err)
+	}
+	c.reportProgress(jobID, 100)
+	return true, nil
+}
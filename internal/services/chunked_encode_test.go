@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateChunkedEncoding(t *testing.T) {
+	reverse := true
+	ok := &models.VideoConversionOptions{Format: "mp4", Chunked: &models.ChunkedEncodingOptions{Enabled: true, ChunkSeconds: 30, Workers: 8}}
+	if err := validateChunkedEncoding(ok); err != nil {
+		t.Fatalf("valid options rejected: %v", err)
+	}
+	disabled := &models.VideoConversionOptions{Format: "gif", Chunked: &models.ChunkedEncodingOptions{ChunkSeconds: 1}}
+	if err := validateChunkedEncoding(disabled); err != nil {
+		t.Fatalf("disabled chunking should not be validated: %v", err)
+	}
+	for name, opts := range map[string]models.VideoConversionOptions{
+		"short chunks": {Format: "mp4", Chunked: &models.ChunkedEncodingOptions{Enabled: true, ChunkSeconds: 5}},
+		"workers":      {Format: "mp4", Chunked: &models.ChunkedEncodingOptions{Enabled: true, Workers: -1}},
+		"gif":          {Format: "gif", Chunked: &models.ChunkedEncodingOptions{Enabled: true}},
+		"trim":         {Format: "mp4", Trim: &models.TrimRange{EndTime: 10}, Chunked: &models.ChunkedEncodingOptions{Enabled: true}},
+		"reverse":      {Format: "mp4", Temporal: &models.TemporalEffects{Reverse: &reverse}, Chunked: &models.ChunkedEncodingOptions{Enabled: true}},
+		"timecode":     {Format: "mp4", Timecode: &models.TimecodeBurnInOptions{Enabled: true}, Chunked: &models.ChunkedEncodingOptions{Enabled: true}},
+	} {
+		opts := opts
+		if err := validateChunkedEncoding(&opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestChunkWorkerCount(t *testing.T) {
+	for _, tc := range []struct{ requested, limit, chunks, want int }{
+		{0, 4, 10, 4},
+		{2, 4, 10, 2},
+		{16, 4, 10, 4},
+		{0, 8, 3, 3},
+		{0, 0, 3, 1},
+	} {
+		if got := chunkWorkerCount(tc.requested, tc.limit, tc.chunks); got != tc.want {
+			t.Errorf("chunkWorkerCount(%d, %d, %d) = %d, want %d", tc.requested, tc.limit, tc.chunks, got, tc.want)
+		}
+	}
+}
+
+func TestSplitChunkCodecArgs(t *testing.T) {
+	codec := buildVideoCodecArgs(videoEncodeSettings{Format: "mp4", Quality: "medium", Codec: "h265"})
+	videoArgs, muxArgs := splitChunkCodecArgs(codec)
+	wantMux := []string{"-tag:v", "hvc1", "-movflags", "+faststart", "-c:a", "aac"}
+	if !reflect.DeepEqual(muxArgs, wantMux) {
+		t.Fatalf("mux args = %v, want %v", muxArgs, wantMux)
+	}
+	if valueAfter(videoArgs, "-c:v") != "libx265" || countFlag(videoArgs, "-c:a") != 0 || countFlag(videoArgs, "-movflags") != 0 {
+		t.Fatalf("unexpected chunk video args: %v", videoArgs)
+	}
+}
+
+func TestChunkMuxArgs(t *testing.T) {
+	opts := &models.VideoConversionOptions{Format: "mkv", AudioTracks: []int{1}}
+	args := chunkMuxArgs("in.mkv", "chunks.txt", "out.mkv", opts, []string{"atempo=1.50"}, []string{"-c:a", "aac"})
+	joined := strings.Join(args, " ")
+	for _, want := range []string{
+		"-i in.mkv -f concat -safe 0 -i chunks.txt -map 1:v:0 -map 0:a:1",
+		"-af atempo=1.50 -c:v copy -c:a aac -y out.mkv",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("mux args %q missing %q", joined, want)
+		}
+	}
+	if strings.Contains(joined, "0:v:0") {
+		t.Errorf("video must come from the chunks: %q", joined)
+	}
+
+	opts = &models.VideoConversionOptions{Format: "mp4", StripAudio: true}
+	joined = strings.Join(chunkMuxArgs("in.mp4", "chunks.txt", "out.mp4", opts, nil, []string{"-an"}), " ")
+	if strings.Contains(joined, "0:a") || !strings.Contains(joined, "-c:v copy -an") {
+		t.Errorf("unexpected strip-audio mux args: %q", joined)
+	}
+}
@@ -0,0 +1,961 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Command builders. Each one turns validated options into the exact argument
+// list a conversion runs, without running or probing anything, so a new
+// option can be covered by a table-driven test of its arguments (see
+// commands_test.go and testdata/golden). The convert* methods probe what the
+// builders cannot know and pass it in through CommandEnv.
+
+// CommandEnv is what a conversion learns at run time before it builds its
+// command.
+type CommandEnv struct {
+	// WebMVP9 reports whether this FFmpeg build has VP9 and Opus for WebM.
+	WebMVP9 bool
+	// TimecodeRate is the frame rate a timecode burn-in counts at.
+	TimecodeRate string
+	// Loudnorm is the second-pass loudnorm filter from the measurement
+	// pass, or empty when loudness normalization is off.
+	Loudnorm string
+}
+
+// trimArgs returns the output-side seek and duration for a trim range.
+func trimArgs(trim *models.TrimRange) []string {
+	if trim == nil {
+		return nil
+	}
+	return []string{
+		"-ss", fmt.Sprintf("%.2f", trim.StartTime),
+		"-t", fmt.Sprintf("%.2f", trim.EndTime-trim.StartTime),
+	}
+}
+
+const maxTrimSegments = 50
+
+// validateTrimSegments requires ascending, non-overlapping ranges of at
+// least 0.1 seconds each, and no single trim range alongside them.
+func validateTrimSegments(segments []models.TrimRange, trim *models.TrimRange) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	if trim != nil {
+		return errors.New("use either trim or trimSegments, not both")
+	}
+	if len(segments) > maxTrimSegments {
+		return fmt.Errorf("at most %d trim segments are supported, got %d", maxTrimSegments, len(segments))
+	}
+	prevEnd := 0.0
+	for i, s := range segments {
+		if s.StartTime < prevEnd {
+			return fmt.Errorf("trim segment %d starts at %.2f, before the previous segment ends (%.2f); list segments in order without overlaps", i+1, s.StartTime, prevEnd)
+		}
+		if s.EndTime-s.StartTime < 0.1 {
+			return fmt.Errorf("trim segment %d must be at least 0.1 seconds long, got %.2f", i+1, s.EndTime-s.StartTime)
+		}
+		prevEnd = s.EndTime
+	}
+	return nil
+}
+
+// trimSegmentsExpr is true for timestamps inside any of the segments.
+func trimSegmentsExpr(segments []models.TrimRange) string {
+	terms := make([]string, len(segments))
+	for i, s := range segments {
+		terms[i] = fmt.Sprintf("between(t,%.3f,%.3f)", s.StartTime, s.EndTime)
+	}
+	return strings.Join(terms, "+")
+}
+
+// trimSegmentsVideoFilter drops the frames outside the segments and
+// renumbers the rest so the kept pieces play back to back.
+func trimSegmentsVideoFilter(segments []models.TrimRange) string {
+	if len(segments) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("select='%s',setpts=N/FRAME_RATE/TB", trimSegmentsExpr(segments))
+}
+
+// trimSegmentsAudioFilter is the audio counterpart of
+// trimSegmentsVideoFilter.
+func trimSegmentsAudioFilter(segments []models.TrimRange) string {
+	if len(segments) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("aselect='%s',asetpts=N/SR/TB", trimSegmentsExpr(segments))
+}
+
+// BuildImageCommand returns the ImageMagick convert arguments that render
+// inputPath to outputPath.
+func BuildImageCommand(inputPath, outputPath string, options *models.ImageConversionOptions) []string {
+	// -auto-orient is intentionally first so EXIF-oriented JPEGs are normalized
+	// before format conversion/crop/resize. Without this, PNG/WebP outputs can
+	// appear rotated 90 degrees because those formats do not preserve the same
+	// display-orientation hint browsers use for the original JPEG.
+	args := []string{inputPath}
+	if options.AutoOrient == nil || *options.AutoOrient {
+		args = append(args, "-auto-orient")
+	}
+	// Colour management comes next so every later step works in the output
+	// colour space.
+	args = append(args, imageColorProfileArgs(options)...)
+
+	// Apply cropping first if specified
+	if options.Crop != nil {
+		cropArg := fmt.Sprintf("%dx%d+%d+%d",
+			options.Crop.Width, options.Crop.Height, options.Crop.X, options.Crop.Y)
+		args = append(args, "-crop", cropArg)
+	}
+	if options.Upscale > 1 {
+		args = append(args, lanczosUpscaleArgs(options.Upscale)...)
+	}
+
+	// Resize if specified (after cropping)
+	if options.Width != nil || options.Height != nil {
+		args = append(args, imageResizeArgs(options)...)
+	}
+	if bound := imageMaxSizeArg(options.MaxWidth, options.MaxHeight); bound != "" {
+		args = append(args, "-resize", bound)
+	}
+	args = append(args, imageRotationArgs(options)...)
+
+	// Apply filters: the legacy single filter, then the chain in order.
+	if options.Filter != "" && options.Filter != "none" {
+		args = append(args, imageFilterArgs(options.Filter, nil)...)
+	}
+	for _, f := range options.Filters {
+		args = append(args, imageFilterArgs(f.Name, f.Strength)...)
+	}
+
+	// Set quality for lossy and web output formats. ImageMagick ignores quality
+	// where it is not applicable, but this keeps WebP quality controllable too.
+	// A quality target picks the number itself after rendering.
+	qualityTarget, _ := resolveQualityTarget(options.QualityTarget, options.Format)
+	if qualityTarget == nil && (options.Format == "jpg" || options.Format == "jpeg" || options.Format == "webp") {
+		args = append(args, "-quality", strconv.Itoa(options.Quality))
+	}
+	if qualityTarget == nil {
+		args = append(args, imageEncodingArgs(options)...)
+	}
+	if options.Format == "jxl" {
+		args = append(args, jxlArgs(options)...)
+	}
+
+	// Apply tint if specified
+	if options.Tint != nil && *options.Tint != "" && *options.Tint != "#000000" {
+		args = append(args, imageTintArgs(*options.Tint, options.TintStrength, options.TintMode)...)
+	}
+
+	if options.TextOverlay != nil && strings.TrimSpace(options.TextOverlay.Text) != "" {
+		args = append(args, imageTextOverlayArgs(options.TextOverlay)...)
+	}
+	args = append(args, imageFrameArgs(options)...)
+
+	return append(args, outputPath)
+}
+
+// BuildAudioFilters returns the audio filter chain for options, ahead of
+// loudness normalization.
+func BuildAudioFilters(options *models.AudioConversionOptions) []string {
+	var audioFilters []string
+
+	// Cutting comes first so every later stage only sees the kept audio.
+	if f := trimSegmentsAudioFilter(options.TrimSegments); f != "" {
+		audioFilters = append(audioFilters, f)
+	}
+
+	// Basic volume adjustment (from the main volume option)
+	if options.Volume != 1.0 {
+		volumeFilter := fmt.Sprintf("volume=%.2f", options.Volume)
+		audioFilters = append(audioFilters, volumeFilter)
+	}
+
+	// Apply basic processing effects
+	if options.BasicProcessing != nil {
+		bp := options.BasicProcessing
+
+		// Normalize audio
+		if bp.Normalize != nil && *bp.Normalize {
+			audioFilters = append(audioFilters, "loudnorm")
+		}
+
+		// Amplify (additional volume adjustment in dB)
+		if bp.Amplify != nil && *bp.Amplify != 0 {
+			// Convert dB to linear gain
+			gain := fmt.Sprintf("%.2f", *bp.Amplify)
+			amplifyFilter := fmt.Sprintf("volume=%sdB", gain)
+			audioFilters = append(audioFilters, amplifyFilter)
+		}
+
+		// Fade in
+		if bp.FadeIn != nil && *bp.FadeIn > 0 {
+			fadeInFilter := fmt.Sprintf("afade=t=in:d=%.2f", *bp.FadeIn)
+			audioFilters = append(audioFilters, fadeInFilter)
+		}
+
+		// Fade out
+		if bp.FadeOut != nil && *bp.FadeOut > 0 {
+			fadeOutFilter := fmt.Sprintf("afade=t=out:d=%.2f", *bp.FadeOut)
+			audioFilters = append(audioFilters, fadeOutFilter)
+		}
+
+		// EQ presets
+		if bp.Equalizer != nil && bp.Equalizer.Enabled && bp.Equalizer.Preset != "none" {
+			var eqFilter string
+			switch bp.Equalizer.Preset {
+			case "bass-boost":
+				eqFilter = "equalizer=f=80:width_type=o:width=2:g=6"
+			case "treble-boost":
+				eqFilter = "equalizer=f=10000:width_type=o:width=2:g=6"
+			case "vocal":
+				eqFilter = "equalizer=f=1000:width_type=o:width=2:g=3,equalizer=f=3000:width_type=o:width=2:g=3"
+			case "classical":
+				eqFilter = "equalizer=f=315:width_type=o:width=2:g=2,equalizer=f=1000:width_type=o:width=2:g=-2,equalizer=f=8000:width_type=o:width=2:g=4"
+			case "rock":
+				eqFilter = "equalizer=f=80:width_type=o:width=2:g=4,equalizer=f=250:width_type=o:width=2:g=-2,equalizer=f=1000:width_type=o:width=2:g=2,equalizer=f=4000:width_type=o:width=2:g=4"
+			case "jazz":
+				eqFilter = "equalizer=f=125:width_type=o:width=2:g=3,equalizer=f=500:width_type=o:width=2:g=-2,equalizer=f=2000:width_type=o:width=2:g=2,equalizer=f=8000:width_type=o:width=2:g=3"
+			}
+			if eqFilter != "" {
+				audioFilters = append(audioFilters, eqFilter)
+			}
+		}
+		// Custom bands and cut filters shape the result after any preset.
+		if bp.Equalizer != nil && bp.Equalizer.Enabled {
+			audioFilters = append(audioFilters, equalizerFilters(bp.Equalizer)...)
+		}
+
+		// Compression follows EQ so boosted bands are kept in check.
+		if bp.DynamicRange != nil && bp.DynamicRange.Enabled {
+			audioFilters = append(audioFilters, compressorFilter(bp.DynamicRange))
+		}
+
+		// Stereo processing
+		if bp.Stereo != nil {
+			// Pan adjustment
+			if bp.Stereo.Pan != nil && *bp.Stereo.Pan != 0 {
+				// Convert -100 to 100 range to -1 to 1
+				panValue := float64(*bp.Stereo.Pan) / 100.0
+				panFilter := fmt.Sprintf("pan=stereo|c0=%.2f*c0+%.2f*c1|c1=%.2f*c0+%.2f*c1",
+					1.0-panValue, panValue, panValue, 1.0-panValue)
+				audioFilters = append(audioFilters, panFilter)
+			}
+
+			// Stereo width adjustment
+			if bp.Stereo.Width != nil && *bp.Stereo.Width != 100 {
+				widthValue := float64(*bp.Stereo.Width) / 100.0
+				widthFilter := fmt.Sprintf("extrastereo=m=%.2f", widthValue)
+				audioFilters = append(audioFilters, widthFilter)
+			}
+
+			// Mono conversion
+			if bp.Stereo.MonoConversion != nil && *bp.Stereo.MonoConversion {
+				audioFilters = append(audioFilters, "pan=mono|c0=0.5*c0+0.5*c1")
+			}
+
+			// Channel swap
+			if bp.Stereo.ChannelSwap != nil && *bp.Stereo.ChannelSwap {
+				audioFilters = append(audioFilters, "pan=stereo|c0=c1|c1=c0")
+			}
+		}
+	}
+
+	// Apply time-based effects
+	if options.TimeBasedEffects != nil {
+		tbe := options.TimeBasedEffects
+
+		// Reverb
+		if tbe.Reverb != nil && tbe.Reverb.Enabled {
+			if reverbFilter, _ := reverbFilter(tbe.Reverb); reverbFilter != "" {
+				audioFilters = append(audioFilters, reverbFilter)
+			}
+		}
+
+		// Delay/Echo
+		if tbe.Delay != nil && tbe.Delay.Enabled && tbe.Delay.Type != "none" {
+			feedback := tbe.Delay.Feedback / 100.0
+
+			var delayFilter string
+			switch tbe.Delay.Type {
+			case "echo":
+				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f", feedback, tbe.Delay.Time, feedback)
+			case "multi-tap":
+				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f,aecho=0.6:%.2f:%.0f:%.2f",
+					feedback, tbe.Delay.Time, feedback*0.8, feedback*0.8, tbe.Delay.Time*1.5, feedback*0.6)
+			case "ping-pong":
+				// Simplified ping-pong delay
+				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f", feedback, tbe.Delay.Time, feedback)
+			}
+			if delayFilter != "" {
+				audioFilters = append(audioFilters, delayFilter)
+			}
+		}
+
+		// Modulation effects
+		if tbe.Modulation != nil && tbe.Modulation.Enabled {
+			if modFilter, _ := modulationFilter(tbe.Modulation); modFilter != "" {
+				audioFilters = append(audioFilters, modFilter)
+			}
+		}
+	}
+
+	// Apply restoration effects
+	if options.Restoration != nil {
+		rest := options.Restoration
+
+		// Noise reduction
+		if rest.NoiseReduction != nil && rest.NoiseReduction.Enabled {
+			switch rest.NoiseReduction.Type {
+			case "spectral":
+				// Use afftdn filter for spectral noise reduction
+				noiseFilter := fmt.Sprintf("afftdn=nr=%.2f:nf=%.2f", rest.NoiseReduction.Strength/10.0, rest.NoiseReduction.Strength/20.0)
+				audioFilters = append(audioFilters, noiseFilter)
+			case "adaptive":
+				// Use anlmdn filter for adaptive noise reduction
+				noiseFilter := fmt.Sprintf("anlmdn=s=%.2f", rest.NoiseReduction.Strength/10.0)
+				audioFilters = append(audioFilters, noiseFilter)
+			case "gate":
+				// Use gate filter for noise gating
+				threshold := -40.0 + (rest.NoiseReduction.Strength * 0.4) // -40dB to 0dB
+				gateFilter := fmt.Sprintf("agate=threshold=%.1fdB:ratio=10", threshold)
+				audioFilters = append(audioFilters, gateFilter)
+			}
+		}
+
+		// De-hum filter
+		if rest.DeHum != nil && rest.DeHum.Enabled {
+			var humFreq string
+			switch rest.DeHum.Frequency {
+			case "50hz":
+				humFreq = "50"
+			case "60hz":
+				humFreq = "60"
+			case "auto":
+				humFreq = "60" // Default to 60Hz for auto
+			}
+			if humFreq != "" {
+				// Use notch filter to remove hum
+				dehumFilter := fmt.Sprintf("highpass=f=%s,lowpass=f=%s", humFreq, humFreq)
+				// Better approach: use equalizer to notch out hum frequency
+				dehumFilter = fmt.Sprintf("equalizer=f=%s:width_type=q:width=0.5:g=-40", humFreq)
+				audioFilters = append(audioFilters, dehumFilter)
+			}
+		}
+
+		// De-clip restoration
+		if rest.Declip != nil && rest.Declip.Enabled {
+			// Use adeclip filter for clipping restoration
+			declipFilter := fmt.Sprintf("adeclip=threshold=%.2f", rest.Declip.Threshold/100.0)
+			audioFilters = append(audioFilters, declipFilter)
+		}
+
+		// Silence detection and removal
+		if rest.SilenceDetection != nil && rest.SilenceDetection.Enabled {
+			threshold := -50.0 + (rest.SilenceDetection.Threshold * 0.5) // -50dB to 0dB
+			duration := rest.SilenceDetection.MinDuration
+			silenceFilter := fmt.Sprintf("silenceremove=start_periods=1:start_threshold=%.1fdB:start_duration=%.2f",
+				threshold, duration)
+			audioFilters = append(audioFilters, silenceFilter)
+		}
+	}
+
+	// Apply advanced audio processing
+	if options.Advanced != nil {
+		adv := options.Advanced
+
+		// Pitch shifting
+		if adv.PitchShift != nil && adv.PitchShift.Enabled && adv.PitchShift.Semitones != 0 {
+			audioFilters = append(audioFilters, pitchShiftFilter(adv.PitchShift))
+		}
+
+		// Time stretching (without pitch change)
+		if adv.TimeStretch != nil && adv.TimeStretch.Enabled {
+			factor := adv.TimeStretch.Factor
+			algorithm := adv.TimeStretch.Algorithm
+
+			switch algorithm {
+			case "pitch":
+				// Use rubberband for high-quality time stretching
+				timeStretchFilter := fmt.Sprintf("rubberband=tempo=%.2f", factor)
+				audioFilters = append(audioFilters, timeStretchFilter)
+			case "time":
+				// Use atempo for simple time stretching
+				if factor > 2.0 {
+					// Chain multiple atempo filters for extreme stretching
+					for f := factor; f > 1.0; f /= 2.0 {
+						if f >= 2.0 {
+							audioFilters = append(audioFilters, "atempo=2.0")
+						} else {
+							audioFilters = append(audioFilters, fmt.Sprintf("atempo=%.2f", f))
+						}
+					}
+				} else {
+					audioFilters = append(audioFilters, fmt.Sprintf("atempo=%.2f", factor))
+				}
+			case "formant":
+				// Formant-preserving stretch using asetrate + aresample combination
+				stretchFilter := fmt.Sprintf("asetrate=48000/%.2f,aresample=48000", factor)
+				audioFilters = append(audioFilters, stretchFilter)
+			}
+		}
+
+		// Spatial audio processing
+		if adv.SpatialAudio != nil && adv.SpatialAudio.Enabled && adv.SpatialAudio.Type != "none" {
+			switch adv.SpatialAudio.Type {
+			case "binaural":
+				// Simple binaural processing using crossfeed
+				binauralFilter := "crossfeed=strength=0.8:range=0.5"
+				audioFilters = append(audioFilters, binauralFilter)
+			case "surround":
+				// Upmix stereo to surround using surround filter
+				surroundFilter := "surround"
+				audioFilters = append(audioFilters, surroundFilter)
+			case "3d":
+				spatialFilters, _ := spatialPositionFilters(adv.SpatialAudio.Position)
+				audioFilters = append(audioFilters, spatialFilters...)
+			}
+		}
+	}
+
+	// Speed adjustment (atempo for audio speed without pitch change)
+	if options.Speed != 1.0 {
+		// FFmpeg atempo filter has limitations, so handle extreme speeds
+		speed := options.Speed
+		for speed > 2.0 {
+			audioFilters = append(audioFilters, "atempo=2.0")
+			speed /= 2.0
+		}
+		for speed < 0.5 {
+			audioFilters = append(audioFilters, "atempo=0.5")
+			speed *= 2.0
+		}
+		if speed != 1.0 {
+			tempoFilter := fmt.Sprintf("atempo=%.2f", speed)
+			audioFilters = append(audioFilters, tempoFilter)
+		}
+	}
+	return audioFilters
+}
+
+// BuildAudioCommand returns the FFmpeg arguments for an audio conversion.
+func BuildAudioCommand(inputPath, outputPath string, options *models.AudioConversionOptions, env CommandEnv) []string {
+	args := append([]string{"-i", inputPath}, trimArgs(options.Trim)...)
+
+	audioFilters := BuildAudioFilters(options)
+	// Two-pass EBU R128 loudness normalization is always the final stage: the
+	// analysis pass measured the output of every filter above it.
+	if env.Loudnorm != "" {
+		audioFilters = append(audioFilters, env.Loudnorm)
+	}
+	// The explicit resample comes last so it performs the only rate change
+	// (loudnorm, for one, works at 192 kHz).
+	if f := resamplerFilter(options); f != "" {
+		audioFilters = append(audioFilters, f)
+	}
+	if len(audioFilters) > 0 {
+		args = append(args, "-af", strings.Join(audioFilters, ","))
+	}
+
+	args = append(args, losslessCodecArgs(options.Format, options.SampleFormat)...)
+
+	// Sample rate
+	args = append(args, "-ar", options.SampleRate)
+
+	// Channels
+	switch options.Channels {
+	case "mono":
+		args = append(args, "-ac", "1")
+	case "stereo":
+		args = append(args, "-ac", "2")
+	case "5.1":
+		args = append(args, "-ac", "6")
+	case "7.1":
+		args = append(args, "-ac", "8")
+	}
+
+	// Bitrate (skip for lossless formats)
+	if options.Format != "wav" && options.Format != "flac" && options.Format != "alac" {
+		args = append(args, "-b:a", options.Bitrate+"k")
+	}
+	if options.StripMetadata {
+		args = append(args, "-map_metadata", "-1", "-map_chapters", "-1")
+	}
+	args = append(args, audioTagArgs(options.Tags, options.Format)...)
+
+	return append(args, "-y", outputPath)
+}
+
+// audioCodecArgs selects the encoder for an audio output format.
+func audioCodecArgs(format string) []string {
+	codec, ok := map[string]string{
+		"mp3":  "libmp3lame",
+		"wav":  "pcm_s16le",
+		"aac":  "aac",
+		"ogg":  "libvorbis",
+		"flac": "flac",
+		"opus": "libopus",
+		"ac3":  "ac3",
+		"alac": "alac",
+		"dts":  "dca",
+	}[format]
+	if !ok {
+		return nil
+	}
+	switch format {
+	case "alac":
+		// ALAC lives in an MP4 (.m4a) container; ipod is the iTunes-compatible
+		// flavour of the MP4 muxer.
+		return []string{"-c:a", codec, "-f", "ipod"}
+	case "dts":
+		// FFmpeg's DTS Coherent Acoustics encoder is still marked experimental.
+		return []string{"-c:a", codec, "-strict", "-2"}
+	}
+	return []string{"-c:a", codec}
+}
+
+// validateDTSOutput checks the limits of the dca encoder: 44.1 or 48 kHz and
+// at most 5.1 channels.
+func validateDTSOutput(options *models.AudioConversionOptions) error {
+	if options.Format != "dts" {
+		return nil
+	}
+	if options.SampleRate != "44100" && options.SampleRate != "48000" {
+		return fmt.Errorf("dts output supports 44100 or 48000 Hz, got %s", options.SampleRate)
+	}
+	if options.Channels == "7.1" {
+		return errors.New("dts output supports at most 5.1 channels")
+	}
+	return nil
+}
+
+// wavSampleCodecs are the PCM encoders for each WAV sample format.
+var wavSampleCodecs = map[string]string{"s16": "pcm_s16le", "s24": "pcm_s24le", "f32": "pcm_f32le"}
+
+func validateSampleFormat(sampleFormat, format string) error {
+	if sampleFormat == "" {
+		return nil
+	}
+	if _, ok := wavSampleCodecs[sampleFormat]; !ok {
+		return fmt.Errorf("unsupported sample format: %s (expected s16|s24|f32)", sampleFormat)
+	}
+	switch {
+	case format != "wav" && format != "flac":
+		return fmt.Errorf("sample format applies to wav and flac output, not %s", format)
+	case format == "flac" && sampleFormat == "f32":
+		return errors.New("flac stores integer samples only; use s16 or s24")
+	}
+	return nil
+}
+
+// losslessCodecArgs is audioCodecArgs with the WAV/FLAC bit depth applied.
+// FFmpeg's FLAC encoder takes 24-bit audio as s32 samples marked with 24
+// significant bits.
+func losslessCodecArgs(format, sampleFormat string) []string {
+	switch {
+	case sampleFormat == "":
+	case format == "wav":
+		return []string{"-c:a", wavSampleCodecs[sampleFormat]}
+	case format == "flac" && sampleFormat == "s16":
+		return append(audioCodecArgs(format), "-sample_fmt", "s16")
+	case format == "flac" && sampleFormat == "s24":
+		return append(audioCodecArgs(format), "-sample_fmt", "s32", "-bits_per_raw_sample", "24")
+	}
+	return audioCodecArgs(format)
+}
+
+const soxrDefaultPrecision = 28
+
+func validateResampler(options *models.AudioConversionOptions) error {
+	switch options.Resampler {
+	case "", "swr", "soxr":
+	default:
+		return fmt.Errorf("unsupported resampler: %s (expected swr|soxr)", options.Resampler)
+	}
+	if p := options.ResamplerPrecision; p != 0 {
+		if options.Resampler != "soxr" {
+			return errors.New("resampler precision requires resampler soxr")
+		}
+		if p < 15 || p > 33 {
+			return fmt.Errorf("resampler precision must be between 15 and 33 bits, got %d", p)
+		}
+	}
+	return nil
+}
+
+// resamplerFilter converts to the output sample rate with libsoxr when it
+// was asked for; otherwise FFmpeg's default swresample conversion applies.
+func resamplerFilter(options *models.AudioConversionOptions) string {
+	if options.Resampler != "soxr" {
+		return ""
+	}
+	precision := options.ResamplerPrecision
+	if precision == 0 {
+		precision = soxrDefaultPrecision
+	}
+	return fmt.Sprintf("aresample=%s:resampler=soxr:precision=%d", options.SampleRate, precision)
+}
+
+const jxlDefaultEffort = 7
+
+// jxlArgs sets the libjxl quality and effort. ImageMagick encodes quality 100
+// as mathematically lossless.
+func jxlArgs(options *models.ImageConversionOptions) []string {
+	quality, effort := options.Quality, jxlDefaultEffort
+	if jxl := options.JXL; jxl != nil {
+		if jxl.Lossless {
+			quality = 100
+		}
+		if jxl.Effort != 0 {
+			effort = jxl.Effort
+		}
+	}
+	return []string{"-quality", strconv.Itoa(quality), "-define", fmt.Sprintf("jxl:effort=%d", effort)}
+}
+
+// BuildVideoFilters returns the video filter chain for options. timecodeRate
+// is only read when a timecode burn-in is enabled.
+func BuildVideoFilters(options *models.VideoConversionOptions, timecodeRate string) ([]string, error) {
+	var videoFilters []string
+
+	// Inverse telecine needs the untouched fields, so it runs before anything
+	// else; deblocking works on the source's block grid, so it precedes
+	// scaling.
+	if ivtc := detelecineFilter(options.Advanced); ivtc != "" {
+		videoFilters = append(videoFilters, ivtc)
+	}
+	if f := trimSegmentsVideoFilter(options.TrimSegments); f != "" {
+		videoFilters = append(videoFilters, f)
+	}
+	if options.VisualEffects != nil {
+		if f := cleanupFilter(deblockLevels, options.VisualEffects.Deblock); f != "" {
+			videoFilters = append(videoFilters, f)
+		}
+	}
+
+	// Scale/resize filter (should come first in filter chain)
+	if options.Width != nil || options.Height != nil {
+		var scaleFilter string
+		if options.Width != nil && options.Height != nil {
+			if options.PreserveAspectRatio {
+				scaleFilter = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", *options.Width, *options.Height)
+			} else {
+				scaleFilter = fmt.Sprintf("scale=%d:%d", *options.Width, *options.Height)
+			}
+		} else if options.Width != nil {
+			scaleFilter = fmt.Sprintf("scale=%d:-1", *options.Width)
+		} else {
+			scaleFilter = fmt.Sprintf("scale=-1:%d", *options.Height)
+		}
+		videoFilters = append(videoFilters, scaleFilter)
+	}
+
+	// Apply visual effects
+	if options.VisualEffects != nil {
+		ve := options.VisualEffects
+
+		// Color correction filters
+		var colorFilters []string
+
+		if ve.Brightness != nil && *ve.Brightness != 0 {
+			// FFmpeg eq filter brightness: -1.0 to 1.0 (we receive -100 to 100)
+			brightness := float64(*ve.Brightness) / 100.0
+			colorFilters = append(colorFilters, fmt.Sprintf("brightness=%.2f", brightness))
+		}
+
+		if ve.Contrast != nil && *ve.Contrast != 0 {
+			// FFmpeg eq filter contrast: 0.0 to 2.0 (we receive -100 to 100)
+			contrast := 1.0 + (float64(*ve.Contrast) / 100.0)
+			if contrast < 0.0 {
+				contrast = 0.0
+			}
+			colorFilters = append(colorFilters, fmt.Sprintf("contrast=%.2f", contrast))
+		}
+
+		if ve.Saturation != nil && *ve.Saturation != 0 {
+			// FFmpeg eq filter saturation: 0.0 to 3.0 (we receive -100 to 100)
+			saturation := 1.0 + (float64(*ve.Saturation) / 100.0)
+			if saturation < 0.0 {
+				saturation = 0.0
+			}
+			colorFilters = append(colorFilters, fmt.Sprintf("saturation=%.2f", saturation))
+		}
+
+		if ve.Gamma != nil && *ve.Gamma != 1.0 {
+			colorFilters = append(colorFilters, fmt.Sprintf("gamma=%.2f", *ve.Gamma))
+		}
+
+		if ve.Hue != nil && *ve.Hue != 0 {
+			// FFmpeg hue filter expects degrees
+			colorFilters = append(colorFilters, fmt.Sprintf("h=%d", *ve.Hue))
+		}
+
+		// Advanced color adjustments
+		if ve.Exposure != nil && *ve.Exposure != 0 {
+			// Exposure adjustment using curves filter
+			exposure := 1.0 + (float64(*ve.Exposure) / 100.0)
+			if exposure < 0.1 {
+				exposure = 0.1
+			}
+			colorFilters = append(colorFilters, fmt.Sprintf("exposure=%.2f", exposure))
+		}
+
+		if ve.Shadows != nil && *ve.Shadows != 0 {
+			// Shadow lift using eq filter
+			shadowLift := float64(*ve.Shadows) / 100.0
+			colorFilters = append(colorFilters, fmt.Sprintf("gamma_b=%.2f", 1.0-shadowLift*0.3))
+		}
+
+		if ve.Highlights != nil && *ve.Highlights != 0 {
+			// Highlight recovery using eq filter
+			highlightRecovery := float64(*ve.Highlights) / 100.0
+			colorFilters = append(colorFilters, fmt.Sprintf("gamma_r=%.2f", 1.0+highlightRecovery*0.3))
+		}
+
+		// Combine color filters into eq filter if any exist
+		if len(colorFilters) > 0 {
+			eqFilter := "eq=" + strings.Join(colorFilters, ":")
+			videoFilters = append(videoFilters, eqFilter)
+		}
+
+		// Gaussian blur
+		if ve.GaussianBlur != nil && *ve.GaussianBlur > 0 {
+			blurFilter := fmt.Sprintf("gblur=sigma=%d", *ve.GaussianBlur)
+			videoFilters = append(videoFilters, blurFilter)
+		}
+
+		// Motion blur
+		if ve.MotionBlur != nil && ve.MotionBlur.Distance > 0 {
+			// Use minterpolate filter for motion blur effect
+			motionBlurFilter := fmt.Sprintf("minterpolate=fps=25:mc_mode=aobmc:me_mode=bidir:vsbmc=1")
+			videoFilters = append(videoFilters, motionBlurFilter)
+		}
+
+		// Unsharp mask (sharpening)
+		if ve.UnsharpMask != nil && ve.UnsharpMask.Amount > 0 {
+			amount := ve.UnsharpMask.Amount / 100.0
+			radius := int(ve.UnsharpMask.Radius)
+
+			unsharpFilter := fmt.Sprintf("unsharp=luma_msize_x=%d:luma_msize_y=%d:luma_amount=%.2f",
+				radius*2+1, radius*2+1, amount)
+			videoFilters = append(videoFilters, unsharpFilter)
+		}
+
+		// Noise effects
+		if ve.Noise != nil && ve.Noise.Amount > 0 && ve.Noise.Type != "none" {
+			switch ve.Noise.Type {
+			case "film-grain":
+				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=t", int(ve.Noise.Amount))
+				videoFilters = append(videoFilters, noiseFilter)
+			case "digital":
+				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=u", int(ve.Noise.Amount))
+				videoFilters = append(videoFilters, noiseFilter)
+			case "vintage":
+				// Combine noise with slight desaturation for vintage look
+				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=t", int(ve.Noise.Amount)/2)
+				videoFilters = append(videoFilters, noiseFilter)
+			}
+		}
+
+		// Artistic effects
+		if ve.Artistic != nil && *ve.Artistic != "none" {
+			switch *ve.Artistic {
+			case "oil-painting":
+				// Use convolution matrix to create oil painting effect
+				oilFilter := "convolution=0 0 0 0:0 1 0 0:0 0 0 0:0 0 0 0:1:1:1:1:0:128"
+				videoFilters = append(videoFilters, oilFilter)
+			case "watercolor":
+				// Combine blur with edge detection for watercolor effect
+				watercolorFilter := "gblur=sigma=2,edgedetect=low=0.1:high=0.4"
+				videoFilters = append(videoFilters, watercolorFilter)
+			case "sketch":
+				// Enhanced edge detection for sketch effect
+				sketchFilter := "edgedetect=low=0.05:high=0.2,negate"
+				videoFilters = append(videoFilters, sketchFilter)
+			case "emboss":
+				embossFilter := "convolution=0 -1 0:-1 5 -1:0 -1 0:0:1:1:0:128:1:0"
+				videoFilters = append(videoFilters, embossFilter)
+			case "edge-detection":
+				edgeFilter := "edgedetect=low=0.1:high=0.3"
+				videoFilters = append(videoFilters, edgeFilter)
+			case "posterize":
+				// Reduce color depth for posterize effect
+				posterizeFilter := "palettegen=stats_mode=single:max_colors=16,paletteuse=dither=none"
+				videoFilters = append(videoFilters, posterizeFilter)
+			}
+		}
+
+		if f := cleanupFilter(debandLevels, ve.Deband); f != "" {
+			videoFilters = append(videoFilters, f)
+		}
+	}
+
+	// Apply transform effects
+	if options.Transform != nil {
+		t := options.Transform
+
+		// Rotation. For the cardinal 90/180/270 angles we use `transpose`
+		// (which correctly swaps width/height for 90/270) instead of `rotate`,
+		// which keeps the original canvas and leaves black corners. Arbitrary
+		// angles still fall back to `rotate`.
+		if t.Rotation != nil && *t.Rotation != 0 {
+			norm := math.Mod(*t.Rotation, 360)
+			if norm < 0 {
+				norm += 360
+			}
+			switch norm {
+			case 90:
+				videoFilters = append(videoFilters, "transpose=1") // 90° clockwise
+			case 180:
+				videoFilters = append(videoFilters, "transpose=1", "transpose=1")
+			case 270:
+				videoFilters = append(videoFilters, "transpose=2") // 90° counter-clockwise
+			default:
+				radians := (norm * 3.14159) / 180
+				videoFilters = append(videoFilters, fmt.Sprintf("rotate=%.4f", radians))
+			}
+		}
+
+		// Flips
+		if t.FlipHorizontal != nil && *t.FlipHorizontal {
+			videoFilters = append(videoFilters, "hflip")
+		}
+
+		if t.FlipVertical != nil && *t.FlipVertical {
+			videoFilters = append(videoFilters, "vflip")
+		}
+
+		// Crop (if not already handled by trimming)
+		if t.Crop != nil {
+			cropFilter := fmt.Sprintf("crop=%d:%d:%d:%d", t.Crop.Width, t.Crop.Height, t.Crop.X, t.Crop.Y)
+			videoFilters = append(videoFilters, cropFilter)
+		}
+
+		// Fixed padding, then pad-to-aspect, both after crop so they frame
+		// the final picture.
+		if t.Padding != nil {
+			padFilter := videoPaddingFilter(t.Padding)
+			videoFilters = append(videoFilters, padFilter)
+		}
+		if t.AspectRatio != "" {
+			aspectFilter, err := videoAspectPadFilter(t.AspectRatio, t.AspectColor)
+			if err != nil {
+				return nil, err
+			}
+			videoFilters = append(videoFilters, aspectFilter)
+		}
+	}
+	if options.Upscale > 1 {
+		videoFilters = append(videoFilters, lanczosUpscaleFilter(options.Upscale))
+	}
+	if f := videoMaxSizeFilter(options.MaxWidth, options.MaxHeight); f != "" {
+		videoFilters = append(videoFilters, f)
+	}
+
+	// Apply temporal effects
+	if options.Temporal != nil {
+		te := options.Temporal
+
+		// Reverse video
+		if te.Reverse != nil && *te.Reverse {
+			videoFilters = append(videoFilters, "reverse")
+		}
+
+		// Frame rate conversion
+		if te.FrameRate != nil && te.FrameRate.Target != nil {
+			fpsFilter := fmt.Sprintf("fps=%d", *te.FrameRate.Target)
+			videoFilters = append(videoFilters, fpsFilter)
+		}
+
+		// Video stabilization
+		if te.Stabilization != nil && te.Stabilization.Enabled {
+			stabFilter := fmt.Sprintf("deshake=x=%d:y=%d", te.Stabilization.Shakiness, te.Stabilization.Accuracy)
+			videoFilters = append(videoFilters, stabFilter)
+		}
+	}
+
+	// Speed adjustment (use setpts for video speed)
+	if options.Speed != 1.0 {
+		speedFilter := fmt.Sprintf("setpts=%.2f*PTS", 1.0/options.Speed)
+		videoFilters = append(videoFilters, speedFilter)
+	}
+
+	if o := options.TextOverlay; o != nil && strings.TrimSpace(o.Text) != "" {
+		videoFilters = append(videoFilters, videoTextOverlayFilter(o))
+	}
+
+	// Timecode burn-in goes last so it numbers the frames actually delivered.
+	if tc := options.Timecode; tc != nil && tc.Enabled {
+		videoFilters = append(videoFilters, timecodeBurnInFilter(tc, timecodeRate))
+	}
+	return videoFilters, nil
+}
+
+// videoAudioFilters keeps the audio in step with a speed change; loudness
+// normalization is appended after it.
+func videoAudioFilters(options *models.VideoConversionOptions) []string {
+	var filters []string
+	if f := trimSegmentsAudioFilter(options.TrimSegments); f != "" {
+		filters = append(filters, f)
+	}
+	if options.Speed != 1.0 {
+		filters = append(filters, fmt.Sprintf("atempo=%.2f", options.Speed))
+	}
+	return filters
+}
+
+// videoEncodeSettingsFor collects the codec, rate control and container
+// options buildVideoCodecArgs turns into flags.
+func videoEncodeSettingsFor(options *models.VideoConversionOptions, webmVP9 bool) videoEncodeSettings {
+	return videoEncodeSettings{
+		Format:       options.Format,
+		Quality:      options.Quality,
+		Codec:        options.VideoCodec,
+		CRF:          options.CRF,
+		VideoBitrate: options.VideoBitrateKbps,
+		AudioBitrate: options.AudioBitrateKbps,
+		Preset:       options.Preset,
+		StripAudio:   options.StripAudio,
+		WebMVP9:      webmVP9,
+		MovFlags:     options.ContainerFlags,
+
+		KeyframeInterval: options.KeyframeIntervalSeconds,
+		BFrames:          options.BFrames,
+		SceneCut:         options.SceneCut,
+	}
+}
+
+// BuildVideoCommand returns the single-pass FFmpeg arguments for a video
+// conversion.
+func BuildVideoCommand(inputPath, outputPath string, options *models.VideoConversionOptions, env CommandEnv) ([]string, error) {
+	videoFilters, err := BuildVideoFilters(options, env.TimecodeRate)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"-i", inputPath}, trimArgs(options.Trim)...)
+	if len(options.AudioTracks) > 0 {
+		args = append(args, audioTrackMapArgs(options.AudioTracks)...)
+	}
+	if len(videoFilters) > 0 {
+		args = append(args, "-vf", strings.Join(videoFilters, ","))
+	}
+	audioFilters := videoAudioFilters(options)
+	if env.Loudnorm != "" {
+		audioFilters = append(audioFilters, env.Loudnorm)
+	}
+	if len(audioFilters) > 0 {
+		args = append(args, "-af", strings.Join(audioFilters, ","))
+	}
+	if options.StripMetadata {
+		args = append(args, "-map_metadata", "-1", "-map_chapters", "-1")
+	}
+	args = append(args, buildVideoCodecArgs(videoEncodeSettingsFor(options, env.WebMVP9))...)
+	return append(args, "-y", outputPath), nil
+}
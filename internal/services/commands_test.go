@@ -0,0 +1,329 @@
+package services
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// The builder tests compare each command against testdata/golden/<name>.golden,
+// one argument per line. After an intended change, regenerate them with
+//
+//	go test ./internal/services -run Command -update
+//
+// and review the golden diff like any other code change.
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden files")
+
+func checkGolden(t *testing.T, name string, args []string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	got := strings.Join(args, "\n") + "\n"
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: %v (run with -update to create it)", name, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s: command changed\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// decodeOptions fills options from JSON the way a job's options are decoded.
+func decodeOptions(t *testing.T, raw string, options any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(raw), options); err != nil {
+		t.Fatalf("options %s: %v", raw, err)
+	}
+}
+
+func TestBuildAudioCommand(t *testing.T) {
+	const base = `"bitrate":"192","sampleRate":"44100","channels":"stereo","speed":1,"volume":1`
+	for _, tc := range []struct {
+		name    string
+		options string
+		env     CommandEnv
+	}{
+		{name: "audio_mp3_defaults", options: `{"format":"mp3",` + base + `}`},
+		{name: "audio_flac_lossless", options: `{"format":"flac","bitrate":"192","sampleRate":"96000","channels":"mono","speed":1,"volume":1}`},
+		{name: "audio_wav_float", options: `{"format":"wav",` + base + `,"sampleFormat":"f32"}`},
+		{name: "audio_flac_24bit", options: `{"format":"flac","bitrate":"192","sampleRate":"96000","channels":"stereo","speed":1,"volume":1,"sampleFormat":"s24"}`},
+		{name: "audio_soxr_resample", options: `{"format":"flac","bitrate":"192","sampleRate":"44100","channels":"stereo","speed":1,"volume":1,
+			"resampler":"soxr","resamplerPrecision":33,"loudnessNormalize":{"preset":"streaming"}}`,
+			env: CommandEnv{Loudnorm: "loudnorm=I=-14:TP=-1:LRA=11:measured_I=-18.5:measured_TP=-2.0:measured_LRA=7.0:measured_thresh=-29.0:offset=0.1:linear=true"}},
+		{name: "audio_alac", options: `{"format":"alac","bitrate":"192","sampleRate":"48000","channels":"stereo","speed":1,"volume":1}`},
+		{name: "audio_dts_surround", options: `{"format":"dts","bitrate":"1024","sampleRate":"48000","channels":"5.1","speed":1,"volume":1}`},
+		{name: "audio_trim_segments", options: `{"format":"mp3",` + base + `,"trimSegments":[{"startTime":2,"endTime":10},{"startTime":12,"endTime":20},{"startTime":45,"endTime":60}],
+			"basicProcessing":{"fadeIn":1}}`},
+		{name: "audio_trim_volume", options: `{"format":"ogg",` + base + `,"volume":1.5,"trim":{"startTime":1.5,"endTime":4}}`},
+		{name: "audio_basic_processing", options: `{"format":"mp3",` + base + `,"basicProcessing":{"normalize":true,"amplify":3,"fadeIn":1,"fadeOut":2,
+			"equalizer":{"enabled":true,"preset":"rock"},"stereo":{"pan":-50,"width":150}}}`},
+		{name: "audio_parametric_eq", options: `{"format":"flac",` + base + `,"basicProcessing":{"equalizer":{"enabled":true,"preset":"vocal",
+			"highPass":80,"lowPass":15000,"bands":[{"frequency":200,"gain":-4,"q":1.5},{"frequency":5000,"gain":3}]}}}`},
+		{name: "audio_compressor", options: `{"format":"mp3",` + base + `,"basicProcessing":{"amplify":2,
+			"dynamicRange":{"enabled":true,"ratio":3,"threshold":-24,"attack":10,"makeupGain":4},"stereo":{"width":120}}}`},
+		{name: "audio_time_effects", options: `{"format":"wav",` + base + `,"timeBasedEffects":{"reverb":{"enabled":true,"type":"hall"},
+			"delay":{"enabled":true,"type":"multi-tap","time":250,"feedback":40},"modulation":{"enabled":true,"type":"tremolo","rate":5,"depth":50}}}`},
+		{name: "audio_reverb_params", options: `{"format":"flac",` + base + `,"timeBasedEffects":{"reverb":{"enabled":true,"type":"plate",
+			"roomSize":80,"damping":30,"wetLevel":60,"dryLevel":70}}}`},
+		{name: "audio_phaser", options: `{"format":"ogg",` + base + `,"timeBasedEffects":{"modulation":{"enabled":true,"type":"phaser","rate":0.8,"depth":70}}}`},
+		{name: "audio_spatial_3d", options: `{"format":"wav",` + base + `,"advanced":{"spatialAudio":{"enabled":true,"type":"3d",
+			"position":{"x":-2,"y":0.5,"z":-3}}}}`},
+		{name: "audio_restoration", options: `{"format":"aac",` + base + `,"restoration":{"noiseReduction":{"enabled":true,"type":"gate","strength":50},
+			"deHum":{"enabled":true,"frequency":"50hz"},"declip":{"enabled":true,"threshold":20}}}`},
+		{name: "audio_speed_pitch", options: `{"format":"opus",` + base + `,"speed":3,"advanced":{"pitchShift":{"enabled":true,"semitones":-3,"preserveFormants":true},
+			"timeStretch":{"enabled":true,"factor":1.25,"algorithm":"time"}}}`},
+		{name: "audio_loudness_tags", options: `{"format":"mp3",` + base + `,"stripMetadata":true,"loudnessNormalize":{"preset":"podcast"},
+			"tags":{"title":"Episode 1","artist":"Host"}}`,
+			env: CommandEnv{Loudnorm: "loudnorm=I=-16:TP=-1.5:LRA=11:measured_I=-20.1:measured_TP=-3.2:measured_LRA=6.0:measured_thresh=-30.4:offset=0.2:linear=true"}},
+	} {
+		var options models.AudioConversionOptions
+		decodeOptions(t, tc.options, &options)
+		checkGolden(t, tc.name, BuildAudioCommand("in.wav", "out."+options.Format, &options, tc.env))
+	}
+}
+
+func TestValidateResampler(t *testing.T) {
+	for name, o := range map[string]models.AudioConversionOptions{
+		"unknown resampler":  {Resampler: "speex"},
+		"precision with swr": {Resampler: "swr", ResamplerPrecision: 20},
+		"precision too high": {Resampler: "soxr", ResamplerPrecision: 40},
+	} {
+		if err := validateResampler(&o); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if f := resamplerFilter(&models.AudioConversionOptions{SampleRate: "48000", Resampler: "soxr"}); f != "aresample=48000:resampler=soxr:precision=28" {
+		t.Fatalf("default soxr filter = %q", f)
+	}
+}
+
+func TestValidateDTSOutput(t *testing.T) {
+	if err := validateDTSOutput(&models.AudioConversionOptions{Format: "dts", SampleRate: "48000", Channels: "5.1"}); err != nil {
+		t.Fatalf("valid dts rejected: %v", err)
+	}
+	for name, o := range map[string]models.AudioConversionOptions{
+		"96 kHz": {Format: "dts", SampleRate: "96000", Channels: "stereo"},
+		"7.1":    {Format: "dts", SampleRate: "48000", Channels: "7.1"},
+	} {
+		if err := validateDTSOutput(&o); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestValidateTrimSegments(t *testing.T) {
+	ok := []models.TrimRange{{StartTime: 0, EndTime: 10}, {StartTime: 10, EndTime: 12.5}, {StartTime: 40, EndTime: 41}}
+	if err := validateTrimSegments(ok, nil); err != nil {
+		t.Fatalf("valid segments rejected: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		segments []models.TrimRange
+		trim     *models.TrimRange
+	}{
+		"with trim":    {ok, &models.TrimRange{StartTime: 1, EndTime: 2}},
+		"overlapping":  {[]models.TrimRange{{StartTime: 0, EndTime: 10}, {StartTime: 9, EndTime: 12}}, nil},
+		"out of order": {[]models.TrimRange{{StartTime: 20, EndTime: 30}, {StartTime: 0, EndTime: 10}}, nil},
+		"too short":    {[]models.TrimRange{{StartTime: 5, EndTime: 5.05}}, nil},
+		"reversed":     {[]models.TrimRange{{StartTime: 5, EndTime: 2}}, nil},
+	} {
+		if err := validateTrimSegments(tc.segments, tc.trim); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestValidateSampleFormat(t *testing.T) {
+	for _, ok := range [][2]string{{"", "mp3"}, {"s24", "wav"}, {"f32", "wav"}, {"s24", "flac"}} {
+		if err := validateSampleFormat(ok[0], ok[1]); err != nil {
+			t.Errorf("%s/%s rejected: %v", ok[0], ok[1], err)
+		}
+	}
+	for _, bad := range [][2]string{{"s24", "mp3"}, {"f32", "flac"}, {"u8", "wav"}} {
+		if err := validateSampleFormat(bad[0], bad[1]); err == nil {
+			t.Errorf("%s/%s: expected an error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestBuildVideoCommand(t *testing.T) {
+	const base = `"speed":1,"quality":"medium"`
+	for _, tc := range []struct {
+		name    string
+		options string
+		env     CommandEnv
+	}{
+		{name: "video_mp4_defaults", options: `{"format":"mp4",` + base + `}`},
+		{name: "video_webm_vp9", options: `{"format":"webm",` + base + `,"quality":"high"}`, env: CommandEnv{WebMVP9: true}},
+		{name: "video_webm_vp8_fallback", options: `{"format":"webm",` + base + `}`},
+		{name: "video_upscale_lanczos", options: `{"format":"mp4",` + base + `,"upscale":2,"maxWidth":3840}`},
+		{name: "video_scale_trim_speed", options: `{"format":"mp4","speed":2,"quality":"low","width":1280,"height":720,"preserveAspectRatio":true,
+			"trim":{"startTime":5,"endTime":15}}`},
+		{name: "video_effects_transform", options: `{"format":"mov",` + base + `,"visualEffects":{"brightness":10,"contrast":-20,"gaussianBlur":2},
+			"transform":{"rotation":90,"flipHorizontal":true,"crop":{"x":0,"y":0,"width":640,"height":360}},
+			"temporal":{"reverse":true,"frameRate":{"target":24}}}`},
+		{name: "video_compression_overrides", options: `{"format":"mp4",` + base + `,"videoCodec":"h265","crf":28,"preset":"slow",
+			"audioBitrateKbps":96,"containerFlags":"fragmented","keyframeIntervalSeconds":2,"stripMetadata":true}`},
+		{name: "video_tracks_loudness_timecode", options: `{"format":"mkv",` + base + `,"audioTracks":[1,0],
+			"loudnessNormalize":{"preset":"broadcast"},"timecode":{"enabled":true}}`,
+			env: CommandEnv{TimecodeRate: "25", Loudnorm: "loudnorm=I=-23:TP=-1:LRA=7:measured_I=-18.0:measured_TP=-0.5:measured_LRA=5.0:measured_thresh=-28.0:offset=0.0:linear=true"}},
+		{name: "video_trim_segments", options: `{"format":"mp4","speed":1.5,"quality":"medium","width":1280,
+			"trimSegments":[{"startTime":0,"endTime":30},{"startTime":95.5,"endTime":180}]}`},
+		{name: "video_strip_audio", options: `{"format":"mp4",` + base + `,"stripAudio":true}`},
+		{name: "video_text_overlay_literal", options: `{"format":"mp4",` + base + `,
+			"textOverlay":{"text":"it's: 100%,[out];drawtext=textfile=/etc/passwd","gravity":"North"}}`},
+	} {
+		var options models.VideoConversionOptions
+		decodeOptions(t, tc.options, &options)
+		args, err := BuildVideoCommand("in.mp4", "out."+options.Format, &options, tc.env)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		checkGolden(t, tc.name, args)
+	}
+}
+
+func TestBuildImageCommand(t *testing.T) {
+	t.Setenv("SRGB_ICC_PROFILE", "/etc/icc/sRGB.icc")
+	for _, tc := range []struct {
+		name    string
+		options string
+	}{
+		{name: "image_png_defaults", options: `{"format":"png","quality":90}`},
+		{name: "image_jpg_crop_resize", options: `{"format":"jpg","quality":80,"width":800,"crop":{"x":10,"y":20,"width":1000,"height":600}}`},
+		{name: "image_jxl_effort", options: `{"format":"jxl","quality":85,"width":2048,"jxl":{"effort":9}}`},
+		{name: "image_jxl_lossless", options: `{"format":"jxl","quality":60,"jxl":{"lossless":true}}`},
+		{name: "image_upscale_lanczos", options: `{"format":"png","quality":90,"upscale":4,"crop":{"x":10,"y":10,"width":100,"height":80}}`},
+		{name: "image_fit_cover", options: `{"format":"jpg","quality":85,"width":400,"height":400,"fit":"cover"}`},
+		{name: "image_fit_contain", options: `{"format":"jpg","quality":85,"width":400,"height":300,"fit":"contain","background":"#202020","withoutEnlargement":true}`},
+		{name: "image_fit_contain_transparent", options: `{"format":"png","quality":90,"width":400,"height":300,"fit":"contain"}`},
+		{name: "image_avatar_frame", options: `{"format":"png","quality":90,"width":256,"height":256,"fit":"cover","padding":8,"borderWidth":4,"borderColor":"#ffffff","cornerRadius":64}`},
+		{name: "image_card_rounded_jpeg", options: `{"format":"jpg","quality":85,"cornerRadius":24,"background":"#f0f0f0"}`},
+		{name: "image_filter_chain", options: `{"format":"jpg","quality":85,"filters":[{"name":"grayscale"},{"name":"sharpen","strength":75},{"name":"vignette","strength":30}]}`},
+		{name: "image_tint_colorize", options: `{"format":"png","quality":90,"tint":"#3366ff","tintStrength":60,"tintMode":"colorize"}`},
+		{name: "image_tint_multiply", options: `{"format":"jpg","quality":85,"tint":"#ffcc00","tintStrength":45,"tintMode":"multiply"}`},
+		{name: "image_rotate_angle_flip", options: `{"format":"png","quality":90,"rotation":12.5,"flipHorizontal":true}`},
+		{name: "image_rotate_right_angle_jpeg", options: `{"format":"jpg","quality":85,"rotation":-90,"flipVertical":true}`},
+		{name: "image_progressive_jpeg_444", options: `{"format":"jpg","quality":92,"progressive":true,"chromaSubsampling":"4:4:4"}`},
+		{name: "image_interlaced_png", options: `{"format":"png","quality":90,"progressive":true}`},
+		{name: "image_keep_orientation", options: `{"format":"png","quality":90,"autoOrient":false}`},
+		{name: "image_color_profile_srgb", options: `{"format":"jpg","quality":85,"colorProfile":"srgb","width":1200}`},
+		{name: "image_color_profile_strip", options: `{"format":"webp","quality":80,"colorProfile":"strip"}`},
+		{name: "image_webp_filter_tint", options: `{"format":"webp","quality":75,"filter":"sepia","tint":"#ff8800","maxWidth":1024}`},
+		{name: "image_text_overlay_literal", options: `{"format":"png","quality":90,"textOverlay":{"text":"@/etc/passwd %[filename] -fx"}}`},
+	} {
+		var options models.ImageConversionOptions
+		decodeOptions(t, tc.options, &options)
+		checkGolden(t, tc.name, BuildImageCommand("in.png", "out."+options.Format, &options))
+	}
+}
+
+// TestImageMetadataCommands covers the exiftool runs of each metadata mode.
+// Each run is written as its action, with "(best effort)" when its failure
+// is only logged, followed by its arguments.
+func TestImageMetadataCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options string
+	}{
+		{name: "image_metadata_default", options: `{"format":"jpg"}`},
+		{name: "image_metadata_preserve", options: `{"format":"jpg","metadataMode":"preserve"}`},
+		{name: "image_metadata_preserve_strip_icc", options: `{"format":"webp","metadataMode":"preserve","colorProfile":"strip"}`},
+		{name: "image_metadata_strip_gps", options: `{"format":"jpg","metadataMode":"stripGps"}`},
+		{name: "image_metadata_strip", options: `{"format":"jpg","metadataMode":"strip"}`},
+		{name: "image_metadata_remove_metadata", options: `{"format":"jpg","metadataMode":"preserve","removeMetadata":true}`},
+	} {
+		var options models.ImageConversionOptions
+		decodeOptions(t, tc.options, &options)
+		steps, err := imageMetadataSteps("in.jpg", "out."+options.Format, &options)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var lines []string
+		for _, step := range steps {
+			header := "# " + step.action
+			if step.bestEffort {
+				header += " (best effort)"
+			}
+			lines = append(lines, header)
+			lines = append(lines, step.args...)
+		}
+		checkGolden(t, tc.name, lines)
+	}
+	var options models.ImageConversionOptions
+	decodeOptions(t, `{"metadataMode":"scrub"}`, &options)
+	if _, err := imageMetadataSteps("in.jpg", "out.jpg", &options); err == nil {
+		t.Error("unknown metadata mode accepted")
+	}
+}
+
+// TestValidateRejectsArgumentInjection checks that option strings which end
+// up as converter arguments are refused rather than passed through.
+func TestValidateRejectsArgumentInjection(t *testing.T) {
+	c := &Converter{}
+	for name, raw := range map[string]map[string]interface{}{
+		"tint":          {"format": "png", "quality": 90, "tint": "red -fx exit"},
+		"tint name":     {"format": "png", "quality": 90, "tint": "red"},
+		"overlay color": {"format": "png", "quality": 90, "textOverlay": map[string]interface{}{"text": "x", "color": "#fff -draw"}},
+		"overlay font":  {"format": "png", "quality": 90, "textOverlay": map[string]interface{}{"text": "x", "font": "/etc/passwd"}},
+		"background":    {"format": "png", "quality": 90, "width": 10, "height": 10, "fit": "contain", "background": "@bg.txt"},
+	} {
+		if err := c.ValidateImageOptions(raw); err == nil {
+			t.Errorf("%s: expected %v to be rejected", name, raw)
+		}
+	}
+}
+
+// TestBuiltCommandsRun feeds the sample media through a few built commands,
+// catching argument lists FFmpeg itself rejects.
+func TestBuiltCommandsRun(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	var audio models.AudioConversionOptions
+	decodeOptions(t, `{"format":"mp3","bitrate":"128","sampleRate":"44100","channels":"stereo","speed":1.5,"volume":0.8,
+		"basicProcessing":{"fadeIn":0.2,"equalizer":{"enabled":true,"preset":"vocal"}}}`, &audio)
+	out := filepath.Join(t.TempDir(), "out.mp3")
+	runBuilt(t, BuildAudioCommand(sampleWAV(t, 1), out, &audio, CommandEnv{}))
+
+	var video models.VideoConversionOptions
+	decodeOptions(t, `{"format":"mp4","speed":1,"quality":"low","width":32,"transform":{"flipVertical":true}}`, &video)
+	args, err := BuildVideoCommand(sampleVideo(t), filepath.Join(t.TempDir(), "out.mp4"), &video, CommandEnv{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runBuilt(t, args)
+}
+
+func TestBuiltImageCommandRuns(t *testing.T) {
+	if _, err := exec.LookPath("convert"); err != nil {
+		t.Skip("ImageMagick not installed")
+	}
+	var options models.ImageConversionOptions
+	decodeOptions(t, `{"format":"jpg","quality":70,"width":16,"filter":"grayscale"}`, &options)
+	args := BuildImageCommand(samplePNG(t, 32, 24), filepath.Join(t.TempDir(), "out.jpg"), &options)
+	if out, err := exec.Command("convert", args...).CombinedOutput(); err != nil {
+		t.Fatalf("convert %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func runBuilt(t *testing.T, args []string) {
+	t.Helper()
+	cmd := exec.Command("ffmpeg", append([]string{"-hide_banner", "-loglevel", "error"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// sniffedFileTypes is the allowlist of content types, as sniffed from a
+// file's leading bytes, that uploads are accepted as. Anything else is
+// unsupported whatever its name or Content-Type claims.
+//
+// Each type carries the format name the input format policy
+// (ALLOWED_INPUT_FORMATS, DENIED_INPUT_FORMATS) matches against.
+var sniffedFileTypes = map[string]sniffedType{
+	"image/jpeg":                {models.FileTypeImage, "jpg"},
+	"image/png":                 {models.FileTypeImage, "png"},
+	"image/vnd.mozilla.apng":    {models.FileTypeImage, "apng"},
+	"image/gif":                 {models.FileTypeImage, "gif"},
+	"image/webp":                {models.FileTypeImage, "webp"},
+	"image/bmp":                 {models.FileTypeImage, "bmp"},
+	"image/tiff":                {models.FileTypeImage, "tiff"},
+	"image/heic":                {models.FileTypeImage, "heic"},
+	"image/heic-sequence":       {models.FileTypeImage, "heic"},
+	"image/heif":                {models.FileTypeImage, "heif"},
+	"image/heif-sequence":       {models.FileTypeImage, "heif"},
+	"image/avif":                {models.FileTypeImage, "avif"},
+	"image/jxl":                 {models.FileTypeImage, "jxl"},
+	"image/jp2":                 {models.FileTypeImage, "jp2"},
+	"image/svg+xml":             {models.FileTypeImage, "svg"},
+	"image/x-icon":              {models.FileTypeImage, "ico"},
+	"image/vnd.adobe.photoshop": {models.FileTypeImage, "psd"},
+
+	"video/mp4":        {models.FileTypeVideo, "mp4"},
+	"video/x-m4v":      {models.FileTypeVideo, "m4v"},
+	"video/quicktime":  {models.FileTypeVideo, "mov"},
+	"video/webm":       {models.FileTypeVideo, "webm"},
+	"video/x-matroska": {models.FileTypeVideo, "mkv"},
+	"video/x-msvideo":  {models.FileTypeVideo, "avi"},
+	"video/x-flv":      {models.FileTypeVideo, "flv"},
+	"video/x-ms-asf":   {models.FileTypeVideo, "wmv"},
+	"video/mpeg":       {models.FileTypeVideo, "mpeg"},
+	"video/3gpp":       {models.FileTypeVideo, "3gp"},
+	"video/3gpp2":      {models.FileTypeVideo, "3g2"},
+	"video/ogg":        {models.FileTypeVideo, "ogv"},
+
+	"audio/mpeg":      {models.FileTypeAudio, "mp3"},
+	"audio/flac":      {models.FileTypeAudio, "flac"},
+	"audio/wav":       {models.FileTypeAudio, "wav"},
+	"audio/aiff":      {models.FileTypeAudio, "aiff"},
+	"audio/aac":       {models.FileTypeAudio, "aac"},
+	"audio/mp4":       {models.FileTypeAudio, "m4a"},
+	"audio/x-m4a":     {models.FileTypeAudio, "m4a"},
+	"audio/ogg":       {models.FileTypeAudio, "ogg"},
+	"application/ogg": {models.FileTypeAudio, "ogg"},
+	"audio/amr":       {models.FileTypeAudio, "amr"},
+	"audio/ape":       {models.FileTypeAudio, "ape"},
+
+	"application/pdf": {models.FileTypeDocument, "pdf"},
+}
+
+type sniffedType struct {
+	fileType models.FileType
+	format   string
+}
+
+// unsniffableExtensions are formats without reliable magic bytes (raw AC-3
+// and DTS streams). They are accepted by extension, but only when the
+// content sniffs as nothing more specific than binary data.
+var unsniffableExtensions = map[string]models.FileType{
+	"ac3": models.FileTypeAudio,
+	"dts": models.FileTypeAudio,
+}
+
+// classifySniffed returns the file type of content sniffed as mimeType,
+// falling back to name's extension for the formats that can't be sniffed.
+// It is FileTypeUnknown for content outside the allowlist.
+func classifySniffed(mimeType, name string) models.FileType {
+	base := mediaType(mimeType)
+	if sniffed, ok := sniffedFileTypes[base]; ok {
+		return sniffed.fileType
+	}
+	if base == "application/octet-stream" {
+		if fileType, ok := unsniffableExtensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))]; ok {
+			return fileType
+		}
+	}
+	return models.FileTypeUnknown
+}
+
+// InputFormat names the format of content sniffed as mimeType, such as
+// "mp4", "flv" or "jpg"; "" outside the allowlist. name supplies the
+// extension of formats that can't be sniffed.
+func InputFormat(mimeType, name string) string {
+	base := mediaType(mimeType)
+	if sniffed, ok := sniffedFileTypes[base]; ok {
+		return sniffed.format
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if _, ok := unsniffableExtensions[ext]; ok && base == "application/octet-stream" {
+		return ext
+	}
+	return ""
+}
+
+// CheckDeclaredType rejects an upload whose Content-Type or file extension
+// names a different kind of file than its content, such as a PDF renamed to
+// .jpg or a video sent as image/png. Audio and video count as the same
+// kind, since containers such as WebM and MP4 carry either. Generic or
+// missing declarations are not checked.
+func CheckDeclaredType(fileType models.FileType, declaredMime, name string) error {
+	for _, declared := range []models.FileType{models.GetFileType(mediaType(declaredMime)), detectTypeByExtension(name)} {
+		if declared != models.FileTypeUnknown && !sameMediaKind(declared, fileType) {
+			return fmt.Errorf("File content (%s) does not match its declared type (%s)", fileType, declared)
+		}
+	}
+	return nil
+}
+
+func sameMediaKind(a, b models.FileType) bool {
+	av := func(t models.FileType) bool { return t == models.FileTypeAudio || t == models.FileTypeVideo }
+	return a == b || (av(a) && av(b))
+}
+
+// mediaType lowercases a MIME type and drops its parameters.
+func mediaType(mimeType string) string {
+	if parsed, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
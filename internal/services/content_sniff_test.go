@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestDetectFileSniffsContent(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+	cases := []struct {
+		name     string
+		data     []byte
+		declared string
+		want     models.FileType
+	}{
+		{name: "photo.png", data: png, want: models.FileTypeImage},
+		{name: "renamed.mp4", data: png, declared: "video/mp4", want: models.FileTypeImage},
+		{name: "script.jpg", data: []byte("#!/bin/sh\nrm -rf /\n"), declared: "image/jpeg", want: models.FileTypeUnknown},
+		{name: "blob.mp4", data: []byte{0x01, 0x02, 0x03}, declared: "video/mp4", want: models.FileTypeUnknown},
+		{name: "track.ac3", data: []byte{0x0b, 0x77, 0x00, 0x01}, want: models.FileTypeAudio},
+		{name: "x", data: []byte("a"), want: models.FileTypeUnknown},
+	}
+	inspector := NewMediaInspector(0)
+	for _, tc := range cases {
+		path := filepath.Join(dir, tc.name)
+		_ = os.WriteFile(path, tc.data, 0o644)
+		if got, mime := inspector.DetectFile(t.Context(), path, tc.declared); got != tc.want {
+			t.Errorf("DetectFile(%s) = %s (%s), want %s", tc.name, got, mime, tc.want)
+		}
+		if got, _ := inspector.DetectBytes(tc.data, tc.name, tc.declared); got != tc.want {
+			t.Errorf("DetectBytes(%s) = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckDeclaredType(t *testing.T) {
+	cases := []struct {
+		fileType       models.FileType
+		declared, name string
+		wantErr        bool
+	}{
+		{fileType: models.FileTypeImage, declared: "image/png", name: "a.png"},
+		{fileType: models.FileTypeImage, declared: "application/octet-stream", name: "upload"},
+		{fileType: models.FileTypeVideo, declared: "audio/webm", name: "voice.webm"},
+		{fileType: models.FileTypeAudio, declared: "video/mp4; codecs=avc1", name: "clip.mp4"},
+		{fileType: models.FileTypeVideo, declared: "image/png", name: "clip.mp4", wantErr: true},
+		{fileType: models.FileTypeDocument, declared: "", name: "invoice.jpg", wantErr: true},
+		{fileType: models.FileTypeImage, declared: "IMAGE/JPEG", name: "report.pdf", wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := CheckDeclaredType(tc.fileType, tc.declared, tc.name); (err != nil) != tc.wantErr {
+			t.Errorf("%s as %q %s: err = %v, wantErr %v", tc.fileType, tc.declared, tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestGetFileTypeShortInput(t *testing.T) {
+	for in, want := range map[string]models.FileType{"": models.FileTypeUnknown, "i": models.FileTypeUnknown, "image": models.FileTypeUnknown, "video/": models.FileTypeVideo} {
+		if got := models.GetFileType(in); got != want {
+			t.Errorf("GetFileType(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
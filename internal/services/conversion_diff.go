@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// A conversion diff is the end-user "receipt" of a job: both files are
+// probed on demand and reduced to the handful of properties people care
+// about, then compared. Unlike the opt-in conversion report it needs nothing
+// recorded at conversion time, so it works for any finished job whose
+// original is still on disk.
+
+// MediaSummary is the comparable subset of a probe. Zero values mean the
+// property is unknown or doesn't apply (no duration for a still image).
+type MediaSummary struct {
+	MimeType        string  `json:"mimeType,omitempty"`
+	Format          string  `json:"format,omitempty"`
+	SizeBytes       int64   `json:"sizeBytes"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	BitrateBps      int64   `json:"bitrateBps,omitempty"`
+	VideoCodec      string  `json:"videoCodec,omitempty"`
+	FrameRate       float64 `json:"frameRate,omitempty"`
+	AudioCodec      string  `json:"audioCodec,omitempty"`
+	AudioTracks     int     `json:"audioTracks,omitempty"`
+	Channels        int     `json:"channels,omitempty"`
+	SampleRate      int     `json:"sampleRate,omitempty"`
+	MetadataTags    int     `json:"metadataTags"`
+	HasGPS          bool    `json:"hasGps,omitempty"`
+}
+
+// DiffChange is one property that differs between input and output.
+type DiffChange struct {
+	Field   string `json:"field"`
+	Before  any    `json:"before"`
+	After   any    `json:"after"`
+	Summary string `json:"summary"`
+}
+
+// ConversionDiff is the GET /api/job/:jobId/diff response.
+type ConversionDiff struct {
+	JobID            string       `json:"jobId"`
+	Input            MediaSummary `json:"input"`
+	Output           MediaSummary `json:"output"`
+	SizeDeltaBytes   int64        `json:"sizeDeltaBytes"`
+	SizeDeltaPercent float64      `json:"sizeDeltaPercent"`
+	Changes          []DiffChange `json:"changes"`
+	// Summary is Changes as plain sentences, in the same order.
+	Summary []string `json:"summary"`
+}
+
+// ignoredContainerTags are written by the muxer itself, so they show up on
+// every output and would make a metadata strip look incomplete.
+var ignoredContainerTags = map[string]bool{
+	"encoder": true, "major_brand": true, "minor_version": true, "compatible_brands": true,
+}
+
+// BuildConversionDiff probes inputPath (a file of the job's original type)
+// and outputPath and compares them.
+func BuildConversionDiff(ctx context.Context, inspector *MediaInspector, job *models.ConversionJob, inputPath, outputPath string) (*ConversionDiff, error) {
+	in, err := summarizeFile(ctx, inspector, inputPath, models.GetFileType(job.OriginalFile.Type))
+	if err != nil {
+		return nil, fmt.Errorf("probe input: %w", err)
+	}
+	out, err := summarizeFile(ctx, inspector, outputPath, models.FileTypeUnknown)
+	if err != nil {
+		return nil, fmt.Errorf("probe output: %w", err)
+	}
+	diff := DiffMediaSummaries(in, out)
+	diff.JobID = job.ID
+	return diff, nil
+}
+
+func summarizeFile(ctx context.Context, inspector *MediaInspector, path string, fileType models.FileType) (MediaSummary, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return MediaSummary{}, err
+	}
+	if fileType == models.FileTypeUnknown {
+		fileType, _ = inspector.DetectFile(ctx, path, "")
+	}
+	probe, err := inspector.ProbeFile(ctx, path, fileType)
+	if probe == nil {
+		return MediaSummary{}, err
+	}
+	// A failed probe still carries the MIME type; the size alone is worth
+	// reporting.
+	summary := SummarizeProbe(probe)
+	summary.SizeBytes = stat.Size()
+	return summary, nil
+}
+
+// ProbeResultMetadata probes a finished job's output for the job record.
+func ProbeResultMetadata(ctx context.Context, inspector *MediaInspector, path string) (*models.ResultMetadata, error) {
+	s, err := summarizeFile(ctx, inspector, path, models.FileTypeUnknown)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResultMetadata{
+		MimeType:        s.MimeType,
+		Format:          s.Format,
+		SizeBytes:       s.SizeBytes,
+		Width:           s.Width,
+		Height:          s.Height,
+		DurationSeconds: s.DurationSeconds,
+		BitrateBps:      s.BitrateBps,
+		VideoCodec:      s.VideoCodec,
+		FrameRate:       s.FrameRate,
+		AudioCodec:      s.AudioCodec,
+		Channels:        s.Channels,
+		SampleRate:      s.SampleRate,
+	}, nil
+}
+
+// SummarizeProbe reduces an inspector probe to a MediaSummary.
+func SummarizeProbe(probe *MediaMetadata) MediaSummary {
+	s := MediaSummary{MimeType: probe.MimeType}
+	switch probe.FileType {
+	case models.FileTypeVideo, models.FileTypeAudio:
+		summarizeFFprobe(&s, probe.Details)
+	case models.FileTypeImage:
+		summarizeIdentify(&s, probe)
+	}
+	return s
+}
+
+func summarizeFFprobe(s *MediaSummary, details map[string]any) {
+	format, _ := details["format"].(map[string]any)
+	s.Format, _ = format["format_name"].(string)
+	s.DurationSeconds = floatFromAny(format["duration"])
+	s.BitrateBps = int64(floatFromAny(format["bit_rate"]))
+	if tags, ok := format["tags"].(map[string]any); ok {
+		for k := range tags {
+			if !ignoredContainerTags[strings.ToLower(k)] {
+				s.MetadataTags++
+			}
+			if strings.EqualFold(k, "location") || strings.EqualFold(k, "com.apple.quicktime.location.ISO6709") {
+				s.HasGPS = true
+			}
+		}
+	}
+	streams, _ := details["streams"].([]any)
+	for _, raw := range streams {
+		stream, _ := raw.(map[string]any)
+		switch stream["codec_type"] {
+		case "video":
+			if s.VideoCodec != "" || isAttachedPicture(stream) {
+				continue
+			}
+			s.VideoCodec, _ = stream["codec_name"].(string)
+			s.Width = int(floatFromAny(stream["width"]))
+			s.Height = int(floatFromAny(stream["height"]))
+			rate, _ := stream["avg_frame_rate"].(string)
+			s.FrameRate = parseProbeRate(rate)
+		case "audio":
+			s.AudioTracks++
+			if s.AudioTracks > 1 {
+				continue
+			}
+			s.AudioCodec, _ = stream["codec_name"].(string)
+			s.Channels = int(floatFromAny(stream["channels"]))
+			s.SampleRate = int(floatFromAny(stream["sample_rate"]))
+		}
+	}
+}
+
+func isAttachedPicture(stream map[string]any) bool {
+	disposition, _ := stream["disposition"].(map[string]any)
+	return floatFromAny(disposition["attached_pic"]) == 1
+}
+
+// parseProbeRate reads ffprobe's "30000/1001" frame rates. "0/0" (unknown)
+// yields 0.
+func parseProbeRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		return floatFromAny(rate)
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return math.Round(n/d*1000) / 1000
+}
+
+var identifyGeometry = regexp.MustCompile(`^(\d+)x(\d+)`)
+
+func summarizeIdentify(s *MediaSummary, probe *MediaMetadata) {
+	if id := probe.Identify; id != nil {
+		s.Format, s.Width, s.Height = id.Format, id.Width, id.Height
+		summarizeImageTags(s, probe)
+		return
+	}
+	// Metadata written before images were described with json: keeps
+	// identify -verbose's flat keys.
+	container := probe.Details
+	if probe.ImageMetadata != nil && probe.ImageMetadata.Container != nil {
+		container = probe.ImageMetadata.Container
+	}
+	if format, ok := container["Format"].(string); ok {
+		// "JPEG (Joint Photographic Experts Group JFIF format)"
+		s.Format, _, _ = strings.Cut(format, " ")
+	}
+	if geometry, ok := container["Geometry"].(string); ok {
+		if m := identifyGeometry.FindStringSubmatch(geometry); m != nil {
+			s.Width, _ = strconv.Atoi(m[1])
+			s.Height, _ = strconv.Atoi(m[2])
+		}
+	}
+	summarizeImageTags(s, probe)
+}
+
+func summarizeImageTags(s *MediaSummary, probe *MediaMetadata) {
+	if meta := probe.ImageMetadata; meta != nil {
+		s.MetadataTags = len(meta.ExifTiff) + len(meta.GPSLocation)
+		for _, group := range meta.AdvancedDeviceMetadata {
+			s.MetadataTags += len(group)
+		}
+		s.HasGPS = len(meta.GPSLocation) > 0
+	}
+}
+
+// DiffMediaSummaries compares two summaries. Properties unknown on either
+// side are skipped rather than reported as added or removed, except audio,
+// whose disappearance is itself a change worth telling the user about.
+func DiffMediaSummaries(in, out MediaSummary) *ConversionDiff {
+	d := &ConversionDiff{Input: in, Output: out, Changes: []DiffChange{}, Summary: []string{}}
+	add := func(field string, before, after any, summary string) {
+		d.Changes = append(d.Changes, DiffChange{Field: field, Before: before, After: after, Summary: summary})
+		d.Summary = append(d.Summary, summary)
+	}
+
+	if in.Format != "" && out.Format != "" && !strings.EqualFold(in.Format, out.Format) {
+		add("format", in.Format, out.Format, fmt.Sprintf("Format %s → %s", in.Format, out.Format))
+	}
+	if in.Width > 0 && out.Width > 0 && (in.Width != out.Width || in.Height != out.Height) {
+		add("resolution", fmt.Sprintf("%dx%d", in.Width, in.Height), fmt.Sprintf("%dx%d", out.Width, out.Height),
+			fmt.Sprintf("Resolution %dx%d → %dx%d", in.Width, in.Height, out.Width, out.Height))
+	}
+	if in.VideoCodec != "" && out.VideoCodec != "" && in.VideoCodec != out.VideoCodec {
+		add("videoCodec", in.VideoCodec, out.VideoCodec, fmt.Sprintf("Video codec %s → %s", in.VideoCodec, out.VideoCodec))
+	}
+	if in.FrameRate > 0 && out.FrameRate > 0 && math.Abs(in.FrameRate-out.FrameRate) >= 0.01 {
+		add("frameRate", in.FrameRate, out.FrameRate, fmt.Sprintf("Frame rate %s → %s fps", formatRate(in.FrameRate), formatRate(out.FrameRate)))
+	}
+	if in.DurationSeconds > 0 && out.DurationSeconds > 0 && math.Abs(in.DurationSeconds-out.DurationSeconds) >= 0.1 {
+		add("duration", in.DurationSeconds, out.DurationSeconds,
+			fmt.Sprintf("Duration %s → %s", formatDurationSeconds(in.DurationSeconds), formatDurationSeconds(out.DurationSeconds)))
+	}
+	if in.BitrateBps > 0 && out.BitrateBps > 0 && in.BitrateBps != out.BitrateBps {
+		add("bitrate", in.BitrateBps, out.BitrateBps, fmt.Sprintf("Bitrate %s → %s", formatBitrate(in.BitrateBps), formatBitrate(out.BitrateBps)))
+	}
+	switch {
+	case in.AudioCodec != "" && out.AudioCodec == "" && out.VideoCodec != "":
+		add("audio", in.AudioCodec, nil, "Audio removed")
+	case in.AudioCodec != "" && out.AudioCodec != "" && in.AudioCodec != out.AudioCodec:
+		add("audioCodec", in.AudioCodec, out.AudioCodec, fmt.Sprintf("Audio codec %s → %s", in.AudioCodec, out.AudioCodec))
+	}
+	if in.AudioTracks > 1 && out.AudioTracks > 0 && in.AudioTracks != out.AudioTracks {
+		add("audioTracks", in.AudioTracks, out.AudioTracks, fmt.Sprintf("Audio tracks %d → %d", in.AudioTracks, out.AudioTracks))
+	}
+	if in.Channels > 0 && out.Channels > 0 && in.Channels != out.Channels {
+		add("channels", in.Channels, out.Channels, fmt.Sprintf("Channels %s → %s", channelName(in.Channels), channelName(out.Channels)))
+	}
+	if in.SampleRate > 0 && out.SampleRate > 0 && in.SampleRate != out.SampleRate {
+		add("sampleRate", in.SampleRate, out.SampleRate, fmt.Sprintf("Sample rate %d Hz → %d Hz", in.SampleRate, out.SampleRate))
+	}
+	switch {
+	case in.MetadataTags > 0 && out.MetadataTags == 0:
+		add("metadata", in.MetadataTags, 0, fmt.Sprintf("Metadata stripped (%d tags removed)", in.MetadataTags))
+	case out.MetadataTags < in.MetadataTags:
+		add("metadata", in.MetadataTags, out.MetadataTags, fmt.Sprintf("%d of %d metadata tags removed", in.MetadataTags-out.MetadataTags, in.MetadataTags))
+	}
+	if in.HasGPS && !out.HasGPS {
+		add("gps", true, false, "GPS location removed")
+	}
+
+	d.SizeDeltaBytes = out.SizeBytes - in.SizeBytes
+	if in.SizeBytes > 0 {
+		d.SizeDeltaPercent = math.Round(float64(d.SizeDeltaBytes)/float64(in.SizeBytes)*1000) / 10
+	}
+	add("size", in.SizeBytes, out.SizeBytes, fmt.Sprintf("Size %s → %s (%+.1f%%)", formatByteSize(in.SizeBytes), formatByteSize(out.SizeBytes), d.SizeDeltaPercent))
+	return d
+}
+
+func formatRate(fps float64) string {
+	return strconv.FormatFloat(fps, 'f', -1, 64)
+}
+
+func formatDurationSeconds(s float64) string {
+	if s < 60 {
+		return fmt.Sprintf("%.1fs", s)
+	}
+	total := int(math.Round(s))
+	if total < 3600 {
+		return fmt.Sprintf("%d:%02d", total/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+func formatBitrate(bps int64) string {
+	if bps >= 1_000_000 {
+		return fmt.Sprintf("%.1f Mbps", float64(bps)/1e6)
+	}
+	return fmt.Sprintf("%d kbps", int(math.Round(float64(bps)/1e3)))
+}
+
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func channelName(n int) string {
+	switch n {
+	case 1:
+		return "mono"
+	case 2:
+		return "stereo"
+	case 6:
+		return "5.1"
+	case 8:
+		return "7.1"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func ffprobeDetails(t *testing.T, doc string) map[string]any {
+	t.Helper()
+	var details map[string]any
+	if err := json.Unmarshal([]byte(doc), &details); err != nil {
+		t.Fatal(err)
+	}
+	return details
+}
+
+func TestSummarizeProbeVideo(t *testing.T) {
+	got := SummarizeProbe(&MediaMetadata{FileType: models.FileTypeVideo, Details: ffprobeDetails(t, `{
+		"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "125.4", "bit_rate": "8000000",
+			"tags": {"major_brand": "isom", "encoder": "Lavf60", "title": "Holiday", "location": "+48.85+002.29/"}},
+		"streams": [
+			{"codec_type": "video", "codec_name": "h264", "width": 3840, "height": 2160, "avg_frame_rate": "30000/1001"},
+			{"codec_type": "audio", "codec_name": "aac", "channels": 6, "sample_rate": "48000"},
+			{"codec_type": "audio", "codec_name": "ac3", "channels": 2, "sample_rate": "48000"},
+			{"codec_type": "video", "codec_name": "mjpeg", "width": 300, "height": 300, "disposition": {"attached_pic": 1}}
+		]}`)})
+	want := MediaSummary{
+		Format: "mov,mp4,m4a,3gp,3g2,mj2", Width: 3840, Height: 2160, DurationSeconds: 125.4, BitrateBps: 8000000,
+		VideoCodec: "h264", FrameRate: 29.97, AudioCodec: "aac", AudioTracks: 2, Channels: 6, SampleRate: 48000,
+		MetadataTags: 2, HasGPS: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("summary = %+v\nwant      %+v", got, want)
+	}
+}
+
+func TestSummarizeProbeImage(t *testing.T) {
+	got := SummarizeProbe(&MediaMetadata{FileType: models.FileTypeImage, ImageMetadata: &models.StructuredImageMetadata{
+		Container:   map[string]any{"Format": "JPEG (Joint Photographic Experts Group JFIF format)", "Geometry": "4032x3024+0+0"},
+		ExifTiff:    map[string]any{"Make": "Apple", "Model": "iPhone"},
+		GPSLocation: map[string]any{"GPSLatitude": "48.85"},
+	}})
+	if got.Format != "JPEG" || got.Width != 4032 || got.Height != 3024 || got.MetadataTags != 3 || !got.HasGPS {
+		t.Fatalf("summary = %+v", got)
+	}
+}
+
+func TestSummarizeProbeImageIdentify(t *testing.T) {
+	got := SummarizeProbe(&MediaMetadata{FileType: models.FileTypeImage,
+		Identify:      &models.ImageIdentification{Format: "PNG", Width: 800, Height: 600},
+		ImageMetadata: &models.StructuredImageMetadata{ExifTiff: map[string]any{"Make": "Apple"}},
+	})
+	if got.Format != "PNG" || got.Width != 800 || got.Height != 600 || got.MetadataTags != 1 {
+		t.Fatalf("summary = %+v", got)
+	}
+}
+
+func TestDiffMediaSummaries(t *testing.T) {
+	in := MediaSummary{Format: "mov,mp4,m4a,3gp,3g2,mj2", SizeBytes: 100 << 20, Width: 3840, Height: 2160, DurationSeconds: 125.4,
+		BitrateBps: 8000000, VideoCodec: "h264", AudioCodec: "aac", Channels: 6, SampleRate: 48000, MetadataTags: 2, HasGPS: true}
+	out := MediaSummary{Format: "matroska,webm", SizeBytes: 25 << 20, Width: 1920, Height: 1080, DurationSeconds: 125.42,
+		BitrateBps: 2100000, VideoCodec: "vp9", AudioCodec: "opus", Channels: 2, SampleRate: 48000}
+
+	d := DiffMediaSummaries(in, out)
+	want := []string{
+		"Format mov,mp4,m4a,3gp,3g2,mj2 → matroska,webm",
+		"Resolution 3840x2160 → 1920x1080",
+		"Video codec h264 → vp9",
+		"Bitrate 8.0 Mbps → 2.1 Mbps",
+		"Audio codec aac → opus",
+		"Channels 5.1 → stereo",
+		"Metadata stripped (2 tags removed)",
+		"GPS location removed",
+		"Size 100.0 MiB → 25.0 MiB (-75.0%)",
+	}
+	if !reflect.DeepEqual(d.Summary, want) {
+		t.Fatalf("summary:\n%q\nwant\n%q", d.Summary, want)
+	}
+	if d.SizeDeltaBytes != -75<<20 || len(d.Changes) != len(want) {
+		t.Fatalf("delta = %d, changes = %d", d.SizeDeltaBytes, len(d.Changes))
+	}
+
+	silent := out
+	silent.AudioCodec, silent.Channels, silent.SampleRate = "", 0, 0
+	if got := DiffMediaSummaries(out, silent).Summary; got[0] != "Audio removed" {
+		t.Fatalf("audio removal not reported: %q", got)
+	}
+}
+
+func TestProbeResultMetadataReportsSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ProbeResultMetadata(context.Background(), NewMediaInspector(time.Minute), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SizeBytes != 5 || !strings.HasPrefix(got.MimeType, "text/plain") {
+		t.Fatalf("metadata = %+v", got)
+	}
+	if _, err := ProbeResultMetadata(context.Background(), NewMediaInspector(time.Minute), path+".missing"); err == nil {
+		t.Fatal("probing a missing output succeeded")
+	}
+}
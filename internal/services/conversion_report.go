@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Conversion reports are an opt-in per-job artifact ("report": "json" or
+// "html" in the upload options) for clients that archive conversions and need
+// to show exactly what was done to a file: the input and output probes, the
+// options as submitted, every command line the converter ran, the filter
+// graphs pulled out of those commands, and any quality metrics.
+
+const (
+	ConversionReportJSONFile = "report.json"
+	ConversionReportHTMLFile = "report.html"
+)
+
+// ConversionReportFormat reads the "report" job option. It returns "" when no
+// report was requested, "json", or "html" (which also writes the JSON).
+func ConversionReportFormat(options map[string]interface{}) (string, error) {
+	switch v := options["report"].(type) {
+	case nil:
+		return "", nil
+	case bool:
+		if v {
+			return "json", nil
+		}
+		return "", nil
+	case string:
+		switch f := strings.ToLower(strings.TrimSpace(v)); f {
+		case "", "none":
+			return "", nil
+		case "json", "html":
+			return f, nil
+		default:
+			return "", fmt.Errorf("unsupported report format: %s (expected json|html)", v)
+		}
+	default:
+		return "", fmt.Errorf("report must be \"json\", \"html\" or a boolean")
+	}
+}
+
+// ConversionReport is the document written to report.json.
+type ConversionReport struct {
+	JobID          string                       `json:"jobId"`
+	GeneratedAt    time.Time                    `json:"generatedAt"`
+	CreatedAt      time.Time                    `json:"createdAt"`
+	Input          ConversionReportFile         `json:"input"`
+	Output         ConversionReportFile         `json:"output"`
+	Options        map[string]interface{}       `json:"options"`
+	Filters        []ConversionReportFilter     `json:"filters"`
+	Commands       []string                     `json:"commands"`
+	QualityMetrics *models.QualityMetricsResult `json:"qualityMetrics,omitempty"`
+}
+
+// ConversionReportFile describes one side of the conversion.
+type ConversionReportFile struct {
+	Name   string         `json:"name"`
+	Type   string         `json:"type,omitempty"`
+	Size   int64          `json:"size"`
+	SHA256 string         `json:"sha256,omitempty"`
+	Probe  *MediaMetadata `json:"probe,omitempty"`
+}
+
+// ConversionReportFilter is one filter graph or operator chain, tied to the
+// command (by index into Commands) it came from.
+type ConversionReportFilter struct {
+	Command int    `json:"command"`
+	Flag    string `json:"flag"`
+	Value   string `json:"value"`
+}
+
+// RecordedCommand is one external command the converter ran for a job.
+// Stderr and Err are set once the command has finished; Stderr is kept to
+// its last commandLogStderrBytes.
+type RecordedCommand struct {
+	Name     string
+	Args     []string
+	Started  time.Time
+	Duration time.Duration
+	Stderr   string
+	Err      string
+}
+
+// commandLogStderrBytes bounds the stderr kept per recorded command.
+const commandLogStderrBytes = 8000
+
+// commandLog collects the commands of conversions between BeginCommandLog
+// and TakeCommandLog, for conversion reports and the diagnostic log. Jobs
+// that never called BeginCommandLog are not recorded.
+type commandLog struct {
+	mu   sync.Mutex
+	jobs map[string][]RecordedCommand
+}
+
+// BeginCommandLog starts recording the commands run for jobID.
+func (c *Converter) BeginCommandLog(jobID string) {
+	c.commands.mu.Lock()
+	defer c.commands.mu.Unlock()
+	if c.commands.jobs == nil {
+		c.commands.jobs = map[string][]RecordedCommand{}
+	}
+	c.commands.jobs[jobID] = []RecordedCommand{}
+}
+
+// TakeCommandLog stops recording for jobID and returns what was recorded.
+func (c *Converter) TakeCommandLog(jobID string) []RecordedCommand {
+	c.commands.mu.Lock()
+	defer c.commands.mu.Unlock()
+	cmds := c.commands.jobs[jobID]
+	delete(c.commands.jobs, jobID)
+	return cmds
+}
+
+// recordCommand records a command about to run for jobID. The returned
+// func records how it ended.
+func (c *Converter) recordCommand(jobID, name string, args []string) func(stderr string, err error) {
+	c.commands.mu.Lock()
+	defer c.commands.mu.Unlock()
+	cmds, ok := c.commands.jobs[jobID]
+	if !ok {
+		return func(string, error) {}
+	}
+	started := time.Now()
+	index := len(cmds)
+	c.commands.jobs[jobID] = append(cmds, RecordedCommand{Name: name, Args: append([]string(nil), args...), Started: started})
+	return func(stderr string, err error) {
+		c.commands.mu.Lock()
+		defer c.commands.mu.Unlock()
+		cmds := c.commands.jobs[jobID]
+		if index >= len(cmds) || !cmds[index].Started.Equal(started) {
+			return // the log was taken or restarted meanwhile
+		}
+		cmds[index].Duration = time.Since(started)
+		cmds[index].Stderr = commandTail(stderr, commandLogStderrBytes)
+		if err != nil {
+			cmds[index].Err = err.Error()
+		}
+	}
+}
+
+// ffmpegFilterFlags are the ffmpeg options whose value is a filter graph.
+var ffmpegFilterFlags = map[string]bool{
+	"-vf": true, "-af": true, "-filter:v": true, "-filter:a": true,
+	"-filter_complex": true, "-lavfi": true,
+}
+
+// reportFilters pulls the filter graphs out of ffmpeg commands and the
+// operator chain out of ImageMagick commands.
+func reportFilters(cmds []RecordedCommand, redact func(string) string) []ConversionReportFilter {
+	filters := []ConversionReportFilter{}
+	for i, cmd := range cmds {
+		switch filepath.Base(cmd.Name) {
+		case "ffmpeg":
+			for j := 0; j+1 < len(cmd.Args); j++ {
+				if ffmpegFilterFlags[cmd.Args[j]] {
+					filters = append(filters, ConversionReportFilter{Command: i, Flag: cmd.Args[j], Value: redact(cmd.Args[j+1])})
+					j++
+				}
+			}
+		case "convert", "magick":
+			args := cmd.Args
+			if len(args) > 0 && args[0] == "convert" {
+				args = args[1:]
+			}
+			// The last argument is the output path.
+			if len(args) > 1 {
+				filters = append(filters, ConversionReportFilter{Command: i, Flag: "operators", Value: redact(strings.Join(args[:len(args)-1], " "))})
+			}
+		}
+	}
+	return filters
+}
+
+// formatCommandLine renders a command the way it would be typed in a shell,
+// quoting arguments that need it.
+func formatCommandLine(cmd RecordedCommand) string {
+	parts := make([]string, 0, len(cmd.Args)+1)
+	parts = append(parts, cmd.Name)
+	for _, a := range cmd.Args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`;&|<>()[]*?!#") {
+			a = strconv.Quote(a)
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// reportRedactor replaces server paths with stable placeholders so a report
+// can be handed to a client without disclosing the storage layout. Longer
+// paths are replaced first so the input/output names win over their
+// directories.
+func reportRedactor(replacements map[string]string) func(string) string {
+	keys := make([]string, 0, len(replacements))
+	for k := range replacements {
+		if strings.TrimSpace(k) != "" && k != "." {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, replacements[k])
+	}
+	r := strings.NewReplacer(pairs...)
+	return r.Replace
+}
+
+// ConversionReportInput gathers what BuildConversionReport needs.
+type ConversionReportInput struct {
+	Job        *models.ConversionJob
+	InputPath  string
+	OutputPath string
+	Commands   []RecordedCommand
+	// Redact maps server paths (upload/output/temp directories) to the
+	// placeholder that replaces them in command lines.
+	Redact map[string]string
+}
+
+// BuildConversionReport probes both files and assembles the report.
+func BuildConversionReport(ctx context.Context, inspector *MediaInspector, in ConversionReportInput) *ConversionReport {
+	replacements := map[string]string{in.InputPath: "<input>", in.OutputPath: "<output>"}
+	for k, v := range in.Redact {
+		replacements[k] = v
+	}
+	redact := reportRedactor(replacements)
+
+	report := &ConversionReport{
+		JobID:          in.Job.ID,
+		GeneratedAt:    time.Now().UTC(),
+		CreatedAt:      in.Job.CreatedAt,
+		Options:        in.Job.Options,
+		Filters:        reportFilters(in.Commands, redact),
+		Commands:       make([]string, 0, len(in.Commands)),
+		QualityMetrics: in.Job.QualityMetrics,
+	}
+	for _, cmd := range in.Commands {
+		report.Commands = append(report.Commands, redact(formatCommandLine(cmd)))
+	}
+	report.Input = reportFile(ctx, inspector, in.InputPath, in.Job.OriginalFile.Name, models.GetFileType(in.Job.OriginalFile.Type))
+	report.Input.Type = in.Job.OriginalFile.Type
+	report.Output = reportFile(ctx, inspector, in.OutputPath, filepath.Base(in.OutputPath), models.FileTypeUnknown)
+	return report
+}
+
+func reportFile(ctx context.Context, inspector *MediaInspector, path, name string, fileType models.FileType) ConversionReportFile {
+	f := ConversionReportFile{Name: name}
+	if sum, size, err := hashAndSize(path); err == nil {
+		f.SHA256, f.Size = sum, size
+	}
+	if inspector == nil {
+		return f
+	}
+	if fileType == models.FileTypeUnknown {
+		fileType, f.Type = inspector.DetectFile(ctx, path, "")
+	}
+	probe, _ := inspector.ProbeFile(ctx, path, fileType)
+	if probe != nil {
+		// The raw tool output duplicates Details and can run to megabytes.
+		probe.Raw = ""
+		if f.Type == "" {
+			f.Type = probe.MimeType
+		}
+		// Drop the server-side mtime; the report carries its own timestamp.
+		delete(probe.Details, "modification_time")
+		f.Probe = probe
+	}
+	return f
+}
+
+// WriteConversionReport writes report.json into dir, plus report.html when
+// format is "html".
+func WriteConversionReport(dir string, report *ConversionReport, format string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ConversionReportJSONFile), data, 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	if format != "html" {
+		return nil
+	}
+	page, err := renderConversionReportHTML(report)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ConversionReportHTMLFile), page, 0o644); err != nil {
+		return fmt.Errorf("write html report: %w", err)
+	}
+	return nil
+}
+
+var conversionReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"json": func(v interface{}) string {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Conversion report {{.JobID}}</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:2rem;color:#222}
+h1{font-size:1.4rem}h2{font-size:1.1rem;margin-top:2rem;border-bottom:1px solid #ddd}
+table{border-collapse:collapse}td,th{padding:.25rem .75rem;text-align:left;vertical-align:top;border-bottom:1px solid #eee}
+pre{background:#f6f8fa;padding:.75rem;overflow-x:auto;white-space:pre-wrap;word-break:break-all}
+</style>
+</head>
+<body>
+<h1>Conversion report</h1>
+<table>
+<tr><th>Job</th><td>{{.JobID}}</td></tr>
+<tr><th>Created</th><td>{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+<tr><th>Generated</th><td>{{.GeneratedAt.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+</table>
+<h2>Files</h2>
+<table>
+<tr><th></th><th>Name</th><th>Type</th><th>Size</th><th>SHA-256</th></tr>
+<tr><th>Input</th><td>{{.Input.Name}}</td><td>{{.Input.Type}}</td><td>{{.Input.Size}}</td><td><code>{{.Input.SHA256}}</code></td></tr>
+<tr><th>Output</th><td>{{.Output.Name}}</td><td>{{.Output.Type}}</td><td>{{.Output.Size}}</td><td><code>{{.Output.SHA256}}</code></td></tr>
+</table>
+<h2>Options</h2>
+<pre>{{json .Options}}</pre>
+<h2>Filters</h2>
+{{if .Filters}}<table>
+<tr><th>Command</th><th>Flag</th><th>Value</th></tr>
+{{range .Filters}}<tr><td>{{.Command}}</td><td><code>{{.Flag}}</code></td><td><code>{{.Value}}</code></td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+<h2>Commands</h2>
+{{range $i, $c := .Commands}}<p>#{{$i}}</p><pre>{{$c}}</pre>
+{{else}}<p>None recorded.</p>{{end}}
+{{with .QualityMetrics}}<h2>Quality metrics</h2>
+<pre>{{json .}}</pre>{{end}}
+<h2>Input probe</h2>
+<pre>{{json .Input.Probe}}</pre>
+<h2>Output probe</h2>
+<pre>{{json .Output.Probe}}</pre>
+</body>
+</html>
+`))
+
+func renderConversionReportHTML(report *ConversionReport) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := conversionReportTemplate.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("render html report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
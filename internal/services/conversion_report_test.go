@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestConversionReportFormat(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want string
+		err  bool
+	}{
+		{nil, "", false},
+		{false, "", false},
+		{true, "json", false},
+		{"JSON", "json", false},
+		{"html", "html", false},
+		{"none", "", false},
+		{"pdf", "", true},
+		{float64(1), "", true},
+	} {
+		opts := map[string]interface{}{}
+		if tc.in != nil {
+			opts["report"] = tc.in
+		}
+		got, err := ConversionReportFormat(opts)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("report=%v: got %q, %v", tc.in, got, err)
+		}
+	}
+}
+
+func TestConverterCommandLog(t *testing.T) {
+	c := &Converter{}
+	c.recordCommand("job", "ffmpeg", []string{"-i", "x"})
+	c.BeginCommandLog("job")
+	args := []string{"-i", "in.mp4", "out.mp4"}
+	c.recordCommand("job", "ffmpeg", args)
+	c.recordCommand("other", "ffmpeg", args)
+	args[1] = "mutated"
+	got := c.TakeCommandLog("job")
+	if len(got) != 1 || got[0].Args[1] != "in.mp4" {
+		t.Fatalf("unexpected log: %+v", got)
+	}
+	if again := c.TakeCommandLog("job"); len(again) != 0 {
+		t.Fatalf("log not cleared: %+v", again)
+	}
+}
+
+func TestCommandLogRecordsOutcome(t *testing.T) {
+	c := &Converter{}
+	c.BeginCommandLog("job")
+	done := c.recordCommand("job", "ffmpeg", []string{"-i", "in.mp4"})
+	done(strings.Repeat("x", commandLogStderrBytes)+"Invalid data found", errors.New("exit status 1"))
+	got := c.TakeCommandLog("job")
+	if len(got) != 1 || got[0].Err != "exit status 1" || got[0].Started.IsZero() {
+		t.Fatalf("unexpected log: %+v", got)
+	}
+	if len(got[0].Stderr) != commandLogStderrBytes || !strings.HasSuffix(got[0].Stderr, "Invalid data found") {
+		t.Errorf("stderr should keep its tail, got %d bytes", len(got[0].Stderr))
+	}
+	// A command finishing after its log was taken records nothing.
+	c.BeginCommandLog("job")
+	late := c.recordCommand("job", "ffmpeg", nil)
+	c.TakeCommandLog("job")
+	late("", errors.New("killed"))
+}
+
+func TestBuildDiagnosticLog(t *testing.T) {
+	job := &models.ConversionJob{ID: "j1"}
+	log := BuildDiagnosticLog(DiagnosticLogInput{
+		Job:        job,
+		InputPath:  "/srv/uploads/j1/original_a.mp4",
+		OutputPath: "/srv/outputs/j1/converted.webm",
+		Attempts: []RecordedAttempt{
+			{Number: 1, Commands: []RecordedCommand{{Name: "ffmpeg", Args: []string{"-i", "/srv/uploads/j1/original_a.mp4", "/srv/outputs/j1/converted.webm"},
+				Duration: 1500 * time.Millisecond, Err: "exit status 1", Stderr: "/srv/uploads/j1/original_a.mp4: Invalid data found"}}},
+			{Number: 2},
+		},
+		Err:    errors.New("exit status 1. FFmpeg stderr: /srv/uploads/j1/original_a.mp4: Invalid data found"),
+		Redact: map[string]string{"/srv/uploads/j1": "<upload-dir>"},
+	})
+	if log.Status != models.StatusFailed || len(log.Attempts) != 2 || log.Attempts[1].Number != 2 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	cmd := log.Attempts[0].Commands[0]
+	if cmd.Command != "ffmpeg -i <input> <output>" || cmd.Stderr != "<input>: Invalid data found" || cmd.DurationMs != 1500 {
+		t.Errorf("command = %+v", cmd)
+	}
+	if strings.Contains(log.Error, "/srv/") {
+		t.Errorf("error not redacted: %s", log.Error)
+	}
+
+	dir := t.TempDir()
+	if err := WriteDiagnosticLog(dir, log); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DiagnosticLogFile)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReportFiltersAndRedaction(t *testing.T) {
+	cmds := []RecordedCommand{
+		{Name: "ffmpeg", Args: []string{"-i", "/srv/uploads/j1/original_a b.mp4", "-vf", "scale=1280:-2,fps=30", "-af", "loudnorm", "/srv/outputs/j1/converted.mp4"}},
+		{Name: "magick", Args: []string{"convert", "/srv/uploads/j1/in.png", "-resize", "50%", "-strip", "/srv/outputs/j1/converted.png"}},
+		{Name: "gifsicle", Args: []string{"--colors", "256"}},
+	}
+	redact := reportRedactor(map[string]string{
+		"/srv/uploads/j1/original_a b.mp4": "<input>",
+		"/srv/uploads/j1":                  "<upload-dir>",
+		"/srv/outputs/j1":                  "<output-dir>",
+	})
+	filters := reportFilters(cmds, redact)
+	if len(filters) != 3 {
+		t.Fatalf("filters = %+v", filters)
+	}
+	if filters[0].Flag != "-vf" || filters[0].Value != "scale=1280:-2,fps=30" || filters[1].Flag != "-af" {
+		t.Errorf("ffmpeg filters = %+v", filters[:2])
+	}
+	if filters[2].Command != 1 || filters[2].Value != "<upload-dir>/in.png -resize 50% -strip" {
+		t.Errorf("imagemagick operators = %+v", filters[2])
+	}
+
+	line := redact(formatCommandLine(cmds[0]))
+	if strings.Contains(line, "/srv/") {
+		t.Errorf("server path leaked: %s", line)
+	}
+	if !strings.Contains(line, `"<input>"`) || !strings.Contains(line, "<output-dir>/converted.mp4") {
+		t.Errorf("unexpected command line: %s", line)
+	}
+}
+
+func TestWriteConversionReport(t *testing.T) {
+	dir := t.TempDir()
+	report := &ConversionReport{
+		JobID:       "j1",
+		GeneratedAt: time.Now(),
+		Options:     map[string]interface{}{"format": "mp4"},
+		Commands:    []string{`ffmpeg -i <input> -vf "drawtext=text='<b>'" <output>`},
+		QualityMetrics: &models.QualityMetricsResult{
+			Skipped: map[string]string{"vmaf": "ffmpeg was built without libvmaf"},
+		},
+	}
+	if err := WriteConversionReport(dir, report, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ConversionReportHTMLFile)); err == nil {
+		t.Fatal("html written for a json report")
+	}
+	if err := WriteConversionReport(dir, report, "html"); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, ConversionReportHTMLFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(page), "<b>") || !strings.Contains(string(page), "libvmaf") {
+		t.Fatalf("html not escaped or missing metrics:\n%s", page)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ConversionReportJSONFile))
+	if err != nil || !strings.Contains(string(data), `"jobId": "j1"`) {
+		t.Fatalf("json report: %v\n%s", err, data)
+	}
+}
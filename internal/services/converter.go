@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"os"
 	"os/exec"
@@ -28,6 +29,9 @@ type Converter struct {
 	cfg                *config.Config
 	ai                 *AIService
 	faceDetectionStore *FaceDetectionStore
+	commands           commandLog
+	stages             stageTracker
+	jobContexts        jobContexts
 }
 
 func NewConverter(cfg *config.Config) *Converter {
@@ -65,9 +69,13 @@ func (c *Converter) ConvertFile(job *models.ConversionJob, inputPath, outputPath
 	case models.FileTypeImage:
 		return c.convertImage(job, inputPath, outputPath)
 	case models.FileTypeVideo:
-		return c.convertVideo(job, inputPath, outputPath)
+		err := c.convertVideo(job, inputPath, outputPath)
+		c.endStages(job.ID, err)
+		return err
 	case models.FileTypeAudio:
-		return c.convertAudio(job, inputPath, outputPath)
+		err := c.convertAudio(job, inputPath, outputPath)
+		c.endStages(job.ID, err)
+		return err
 	case models.FileTypeDocument:
 		return c.convertPDFToImages(job, inputPath, outputPath)
 	default:
@@ -106,8 +114,8 @@ func (c *Converter) validateInputFile(inputPath string) error {
 }
 
 func (c *Converter) convertImage(job *models.ConversionJob, inputPath, outputPath string) error {
-	fmt.Printf("[DEBUG] Starting ImageMagick-based image conversion for job %s\n", job.ID)
-	fmt.Printf("[DEBUG] Input: %s, Output: %s\n", inputPath, outputPath)
+	jobLog := c.jobLogger(job.ID)
+	jobLog.Debug("image conversion started", "input", inputPath, "output", outputPath)
 
 	// Parse options
 	optionsBytes, _ := json.Marshal(job.Options)
@@ -121,7 +129,24 @@ func (c *Converter) convertImage(job *models.ConversionJob, inputPath, outputPat
 		return fmt.Errorf("invalid conversion options: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Parsed options: %+v\n", options)
+	// HEIC/HEIF is decoded up front so every pathway below sees a plain PNG;
+	// metadata is still copied from the original.
+	metadataSource := inputPath
+	if isHEIFInput(inputPath) {
+		ctx, cancel := c.commandContext(job.ID)
+		decoded, err := decodeHEIF(ctx, inputPath, options.ImageIndex, c.cfg.TempDir)
+		cancel()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(decoded)
+		inputPath = decoded
+	} else if options.ImageIndex != 0 {
+		return fmt.Errorf("invalid conversion options: imageIndex only applies to HEIC/HEIF input")
+	}
+	if strings.EqualFold(filepath.Ext(inputPath), ".jxl") && !imageMagickCanRead("JXL") {
+		return fmt.Errorf("JPEG XL input needs ImageMagick built with libjxl, which this server lacks")
+	}
 
 	// Image -> PDF takes a dedicated, deterministic pure-Go path (no
 	// ImageMagick PDF coder, no Ghostscript) so it works regardless of the
@@ -152,14 +177,13 @@ func (c *Converter) convertImage(job *models.ConversionJob, inputPath, outputPat
 	// the normal ImageMagick pipeline. AI ops are mutually exclusive with the
 	// conventional convert chain at execution time.
 	if c.ai != nil && options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
-		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+		ctx, cancel := c.commandContext(job.ID)
 		defer cancel()
 		return c.runImageAI(ctx, job, &options, inputPath, outputPath)
 	}
 
 	// Update progress
 	if c.jobManager != nil {
-		fmt.Printf("[DEBUG] Sending progress update: 10%%\n")
 		c.jobManager.SendProgressUpdate(job.ID, 10)
 	}
 
@@ -168,129 +192,135 @@ func (c *Converter) convertImage(job *models.ConversionJob, inputPath, outputPat
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
-	fmt.Printf("[DEBUG] Output directory created: %s\n", outputDir)
-
-	// Build ImageMagick convert command.
-	// -auto-orient is intentionally first so EXIF-oriented JPEGs are normalized
-	// before format conversion/crop/resize. Without this, PNG/WebP outputs can
-	// appear rotated 90 degrees because those formats do not preserve the same
-	// display-orientation hint browsers use for the original JPEG.
-	args := []string{inputPath, "-auto-orient"}
-
-	// Apply cropping first if specified
-	if options.Crop != nil {
-		cropArg := fmt.Sprintf("%dx%d+%d+%d",
-			options.Crop.Width, options.Crop.Height, options.Crop.X, options.Crop.Y)
-		args = append(args, "-crop", cropArg)
-		fmt.Printf("[DEBUG] Added crop: %s\n", cropArg)
-	}
-
-	// Resize if specified (after cropping)
-	if options.Width != nil || options.Height != nil {
-		var resizeArg string
-		if options.Width != nil && options.Height != nil {
-			resizeArg = fmt.Sprintf("%dx%d!", *options.Width, *options.Height)
-		} else if options.Width != nil {
-			resizeArg = fmt.Sprintf("%dx", *options.Width)
-		} else {
-			resizeArg = fmt.Sprintf("x%d", *options.Height)
-		}
-		args = append(args, "-resize", resizeArg)
-		fmt.Printf("[DEBUG] Added resize: %s\n", resizeArg)
-	}
 
 	// Update progress
 	if c.jobManager != nil {
-		fmt.Printf("[DEBUG] Sending progress update: 30%%\n")
 		c.jobManager.SendProgressUpdate(job.ID, 30)
 	}
 
-	// Apply filters
-	if options.Filter != "" && options.Filter != "none" {
-		fmt.Printf("[DEBUG] Applying filter: %s\n", options.Filter)
-		switch options.Filter {
-		case "grayscale":
-			args = append(args, "-colorspace", "Gray")
-		case "sepia":
-			args = append(args, "-sepia-tone", "80%")
-		case "blur":
-			args = append(args, "-blur", "0x8")
-		case "sharpen":
-			args = append(args, "-sharpen", "0x1")
-		case "swirl":
-			args = append(args, "-swirl", "90")
-		case "barrel-distortion":
-			args = append(args, "-distort", "Barrel", "0.1 0.0 0.0 1.0")
-		case "oil-painting":
-			args = append(args, "-paint", "4")
-		case "vintage":
-			args = append(args, "-modulate", "120,50,100", "-colorize", "10,5,15")
-		case "emboss":
-			args = append(args, "-emboss", "2")
-		case "charcoal":
-			args = append(args, "-charcoal", "2")
-		case "sketch":
-			args = append(args, "-sketch", "0x20+120")
-		case "rotate-45º":
-			args = append(args, "-rotate", "45")
-		case "rotate-90º":
-			args = append(args, "-rotate", "90")
-		case "rotate-180º":
-			args = append(args, "-rotate", "180")
-		case "rotate-270º":
-			args = append(args, "-rotate", "270")
-		}
-	} else {
-		fmt.Printf("[DEBUG] No filter applied (filter value: '%s')\n", options.Filter)
+	if options.Format == "jxl" && !imageMagickCanWrite("JXL") {
+		return fmt.Errorf("JPEG XL output needs ImageMagick built with libjxl, which this server lacks")
 	}
 
-	// Update progress
-	if c.jobManager != nil {
-		fmt.Printf("[DEBUG] Sending progress update: 60%%\n")
-		c.jobManager.SendProgressUpdate(job.ID, 60)
+	probeCtx, cancelProbe := c.commandContext(job.ID)
+	animated := isAnimatedImage(probeCtx, inputPath)
+	cancelProbe()
+	// The corner mask and blended tints are built from a single clone of the
+	// picture.
+	if animated && animatedOutputFormat(options.Format) {
+		if options.CornerRadius > 0 {
+			return fmt.Errorf("invalid conversion options: cornerRadius does not support animated output")
+		}
+		if options.TintMode == "multiply" || options.TintMode == "overlay" {
+			return fmt.Errorf("invalid conversion options: tintMode %s does not support animated output", options.TintMode)
+		}
+		if options.BlurFaces {
+			return fmt.Errorf("invalid conversion options: blurFaces does not support animated output")
+		}
 	}
-
-	// Set quality for lossy and web output formats. ImageMagick ignores quality
-	// where it is not applicable, but this keeps WebP quality controllable too.
-	if options.Format == "jpg" || options.Format == "jpeg" || options.Format == "webp" {
-		args = append(args, "-quality", strconv.Itoa(options.Quality))
+	if options.SmartCrop != nil {
+		ctx, cancel := c.commandContext(job.ID)
+		err := c.resolveSmartCrop(ctx, inputPath, &options)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("smart crop failed: %v", err)
+		}
 	}
-
-	// Apply tint if specified
-	if options.Tint != nil && *options.Tint != "" && *options.Tint != "#000000" {
-		args = append(args, "-fill", *options.Tint, "-tint", "30")
-		fmt.Printf("[DEBUG] Added tint: %s\n", *options.Tint)
+	if options.BlurFaces {
+		if !c.faceDetectionAvailable() {
+			return fmt.Errorf("face blurring is not available on this server")
+		}
+		workDir, err := os.MkdirTemp(c.cfg.TempDir, "face-blur-*")
+		if err != nil {
+			return fmt.Errorf("failed to create face blur workspace: %v", err)
+		}
+		defer os.RemoveAll(workDir)
+		ctx, cancel := c.commandContext(job.ID)
+		blurred, err := c.blurImageFaces(ctx, job.ID, inputPath, workDir, &options)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("face blur failed: %v", err)
+		}
+		inputPath = blurred
+	}
+	// Real-ESRGAN works on single frames; animations take the Lanczos path.
+	if options.Upscale > 1 && !animated && c.realESRGANAvailable() {
+		workDir, err := os.MkdirTemp(c.cfg.TempDir, "upscale-*")
+		if err != nil {
+			return fmt.Errorf("failed to create upscale workspace: %v", err)
+		}
+		defer os.RemoveAll(workDir)
+		ctx, cancel := c.commandContext(job.ID)
+		upscaled, err := c.upscaleImageAI(ctx, job.ID, inputPath, workDir, &options)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("upscale failed: %v", err)
+		}
+		inputPath = upscaled
+	}
+	if len(options.Sizes) > 0 {
+		return c.convertImageSizes(job, &options, inputPath, metadataSource, outputPath, animated)
 	}
 
-	if options.TextOverlay != nil && strings.TrimSpace(options.TextOverlay.Text) != "" {
-		args = append(args, imageTextOverlayArgs(options.TextOverlay)...)
-		fmt.Printf("[DEBUG] Added text overlay\n")
+	qualityTarget, _ := resolveQualityTarget(options.QualityTarget, options.Format)
+	if qualityTarget != nil && animated && animatedOutputFormat(options.Format) {
+		return fmt.Errorf("invalid conversion options: qualityTarget does not support animated output")
 	}
 
-	// Set output file
-	args = append(args, outputPath)
+	// With a quality target the chain renders a lossless reference that the
+	// search then encodes from.
+	renderPath := outputPath
+	if qualityTarget != nil {
+		workDir, err := os.MkdirTemp(c.cfg.TempDir, "quality-target-*")
+		if err != nil {
+			return fmt.Errorf("failed to create quality target workspace: %v", err)
+		}
+		defer os.RemoveAll(workDir)
+		renderPath = filepath.Join(workDir, "reference.png")
+	}
+	args := BuildImageCommand(inputPath, renderPath, &options)
+	if animated {
+		args = animatedImageArgs(args, options.Format)
+	}
 
 	// Update progress
 	if c.jobManager != nil {
-		fmt.Printf("[DEBUG] Sending progress update: 80%%\n")
 		c.jobManager.SendProgressUpdate(job.ID, 80)
 	}
-	fmt.Printf("[DEBUG] ImageMagick command: convert %s\n", strings.Join(args, " "))
+	jobLog.Debug("imagemagick command", "args", args)
 
 	// Run ImageMagick convert command
 	if err := c.runImageMagickWithProgress(job.ID, "convert", args...); err != nil {
-		fmt.Printf("[DEBUG] ImageMagick error: %v\n", err)
+		jobLog.Debug("imagemagick failed", "error", err)
 		return fmt.Errorf("ImageMagick conversion failed: %v", err)
 	}
-	if err := applyImageMetadataOptions(inputPath, outputPath, &options); err != nil {
+	if options.Optimize != nil {
+		ctx, cancel := c.commandContext(job.ID)
+		err := c.optimizeGIF(ctx, job.ID, metadataSource, outputPath, options.Optimize)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("gif optimization failed: %v", err)
+		}
+	}
+	if qualityTarget != nil {
+		ctx, cancel := c.commandContext(job.ID)
+		result, err := c.encodeToQualityTarget(ctx, job.ID, renderPath, outputPath, qualityTarget, imageEncodingArgs(&options))
+		cancel()
+		if err != nil {
+			return fmt.Errorf("quality target search failed: %v", err)
+		}
+		if c.jobManager != nil {
+			_ = c.jobManager.SetQualityMetrics(job.ID, result)
+		}
+	}
+	if err := applyImageMetadataOptions(metadataSource, outputPath, &options); err != nil {
 		return fmt.Errorf("image metadata update failed: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Image conversion completed successfully\n")
+	jobLog.Debug("image conversion completed")
 
 	// Update progress to 100% after successful completion
 	if c.jobManager != nil {
-		fmt.Printf("[DEBUG] Sending final progress update: 100%%\n")
 		c.jobManager.SendProgressUpdate(job.ID, 100)
 	}
 
@@ -312,27 +342,46 @@ func (c *Converter) validateImageOptions(options *models.ImageConversionOptions)
 		return fmt.Errorf("height too large (max 10000), got %d", *options.Height)
 	}
 
+	if options.ImageIndex < 0 {
+		return fmt.Errorf("imageIndex must not be negative, got %d", options.ImageIndex)
+	}
+
 	// Validate quality
 	if options.Quality < 1 || options.Quality > 100 {
 		return fmt.Errorf("quality must be between 1 and 100, got %d", options.Quality)
 	}
 
 	// Validate format
-	validFormats := map[string]bool{"jpg": true, "jpeg": true, "png": true, "webp": true, "gif": true, "avif": true, "pdf": true, "svg": true, "ico": true}
+	validFormats := map[string]bool{"jpg": true, "jpeg": true, "png": true, "webp": true, "gif": true, "avif": true, "jxl": true, "pdf": true, "svg": true, "ico": true}
 	if !validFormats[options.Format] {
 		return fmt.Errorf("unsupported format: %s", options.Format)
 	}
+	if jxl := options.JXL; jxl != nil && (jxl.Effort < 0 || jxl.Effort > 9) {
+		return fmt.Errorf("jxl effort must be between 1 and 9, got %d", jxl.Effort)
+	}
+	if _, err := resolveQualityTarget(options.QualityTarget, options.Format); err != nil {
+		return err
+	}
 
 	// Validate filter - Updated to include all implemented filters
-	validFilters := map[string]bool{
-		"none": true, "grayscale": true, "sepia": true, "blur": true, "sharpen": true,
-		"swirl": true, "barrel-distortion": true, "oil-painting": true, "vintage": true,
-		"emboss": true, "charcoal": true, "sketch": true, "rotate-45º": true,
-		"rotate-90º": true, "rotate-180º": true, "rotate-270º": true,
-	}
-	if options.Filter != "" && !validFilters[options.Filter] {
+	if options.Filter != "" && !validImageFilters[options.Filter] {
 		return fmt.Errorf("unsupported filter: %s", options.Filter)
 	}
+	if err := validateImageFilters(options.Filters); err != nil {
+		return err
+	}
+	if err := validateImageTint(options); err != nil {
+		return err
+	}
+	if err := validateImageEncoding(options); err != nil {
+		return err
+	}
+	if err := validateImageFaceBlur(options); err != nil {
+		return err
+	}
+	if err := validateImageColorProfile(options); err != nil {
+		return err
+	}
 
 	// Validate crop area if specified
 	if options.Crop != nil {
@@ -355,6 +404,27 @@ func (c *Converter) validateImageOptions(options *models.ImageConversionOptions)
 			return fmt.Errorf("crop height too large (max 10000), got %d", options.Crop.Height)
 		}
 	}
+	if err := validateMaxSize(options.MaxWidth, options.MaxHeight); err != nil {
+		return err
+	}
+	if err := validateImageSizes(options); err != nil {
+		return err
+	}
+	if err := validateGIFOptimize(options); err != nil {
+		return err
+	}
+	if err := validateImageUpscale(options); err != nil {
+		return err
+	}
+	if err := validateSmartCrop(options); err != nil {
+		return err
+	}
+	if err := validateImageFit(options); err != nil {
+		return err
+	}
+	if err := validateImageFrame(options); err != nil {
+		return err
+	}
 	if options.TextOverlay != nil {
 		if err := validateImageTextOverlay(options.TextOverlay); err != nil {
 			return err
@@ -363,7 +433,7 @@ func (c *Converter) validateImageOptions(options *models.ImageConversionOptions)
 
 	metadataMode := strings.TrimSpace(options.MetadataMode)
 	if metadataMode != "" {
-		validMetadataModes := map[string]bool{"keep": true, "strip": true, "custom": true}
+		validMetadataModes := map[string]bool{"keep": true, "preserve": true, "strip": true, "stripGps": true, "custom": true}
 		if !validMetadataModes[metadataMode] {
 			return fmt.Errorf("unsupported metadata mode: %s", metadataMode)
 		}
@@ -515,45 +585,83 @@ func imageMetadataArgs(options *models.ImageConversionOptions) []string {
 }
 
 func applyImageMetadataOptions(inputPath, outputPath string, options *models.ImageConversionOptions) error {
+	steps, err := imageMetadataSteps(inputPath, outputPath, options)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		_, stderr, err := runCommand(context.Background(), "exiftool", step.args...)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s with exiftool: %s", step.action, strings.TrimSpace(stderr))
+		if step.bestEffort {
+			slog.Debug("metadata preservation skipped", "error", err)
+			continue
+		}
+		return err
+	}
+	return nil
+}
+
+// imageMetadataStep is one exiftool run of the metadata handling. A
+// bestEffort step only logs when it fails.
+type imageMetadataStep struct {
+	action     string
+	args       []string
+	bestEffort bool
+}
+
+// imageMetadataSteps returns the exiftool runs for the image's metadata mode,
+// in order.
+func imageMetadataSteps(inputPath, outputPath string, options *models.ImageConversionOptions) ([]imageMetadataStep, error) {
 	mode := strings.TrimSpace(options.MetadataMode)
 	if options.RemoveMetadata {
 		mode = "strip"
 	}
+	// The copy is best effort: ImageMagick usually carries the tags over on
+	// its own.
+	copyStep := imageMetadataStep{action: "copy metadata", args: copyImageMetadataArgs(inputPath, outputPath, keepsSourceICCProfile(options)), bestEffort: true}
+	stripStep := imageMetadataStep{action: "strip metadata", args: []string{"-overwrite_original", "-all=", outputPath}}
 	switch mode {
-	case "", "keep":
-		if err := copyImageMetadata(inputPath, outputPath); err != nil {
-			fmt.Printf("[DEBUG] Metadata preservation skipped: %v\n", err)
-		}
-		return nil
+	case "", "keep", "preserve":
+		return []imageMetadataStep{copyStep}, nil
+	case "stripGps":
+		// Everything but the location is kept. ImageMagick may already have
+		// carried the EXIF block over, so the GPS removal must succeed even
+		// when the copy doesn't.
+		return []imageMetadataStep{copyStep, {action: "strip GPS metadata", args: stripImageGPSArgs(outputPath)}}, nil
 	case "strip":
-		return stripImageMetadata(outputPath)
+		return []imageMetadataStep{stripStep}, nil
 	case "custom":
-		if err := stripImageMetadata(outputPath); err != nil {
-			return err
+		steps := []imageMetadataStep{stripStep}
+		if args := customImageMetadataArgs(outputPath, options); args != nil {
+			steps = append(steps, imageMetadataStep{action: "write metadata", args: args})
 		}
-		return writeCustomImageMetadata(outputPath, options)
+		return steps, nil
 	default:
-		return fmt.Errorf("unsupported metadata mode: %s", mode)
+		return nil, fmt.Errorf("unsupported metadata mode: %s", mode)
 	}
 }
 
-func copyImageMetadata(inputPath, outputPath string) error {
-	_, stderr, err := runCommand(context.Background(), "exiftool", "-overwrite_original", "-TagsFromFile", inputPath, "-all:all", "-unsafe", outputPath)
-	if err != nil {
-		return fmt.Errorf("copy metadata with exiftool: %s", strings.TrimSpace(stderr))
+// copyImageMetadataArgs copies the source tags onto the output. -unsafe
+// brings the ICC profile along, so withICC false excludes it.
+func copyImageMetadataArgs(inputPath, outputPath string, withICC bool) []string {
+	args := []string{"-overwrite_original", "-TagsFromFile", inputPath, "-all:all", "-unsafe"}
+	if !withICC {
+		args = append(args, "--ICC_Profile:all")
 	}
-	return nil
+	return append(args, outputPath)
 }
 
-func stripImageMetadata(outputPath string) error {
-	_, stderr, err := runCommand(context.Background(), "exiftool", "-overwrite_original", "-all=", outputPath)
-	if err != nil {
-		return fmt.Errorf("strip metadata with exiftool: %s", strings.TrimSpace(stderr))
-	}
-	return nil
+// stripImageGPSArgs deletes the EXIF GPS block and the XMP location tags.
+func stripImageGPSArgs(outputPath string) []string {
+	return []string{"-overwrite_original", "-gps:all=", "-xmp:geotag=", outputPath}
 }
 
-func writeCustomImageMetadata(outputPath string, options *models.ImageConversionOptions) error {
+// customImageMetadataArgs writes the tags of the "custom" mode, or returns
+// nil when there are none.
+func customImageMetadataArgs(outputPath string, options *models.ImageConversionOptions) []string {
 	args := []string{"-overwrite_original"}
 	if options.GPSOptions != nil {
 		args = append(args, gpsMetadataArgs(options.GPSOptions)...)
@@ -569,12 +677,7 @@ func writeCustomImageMetadata(outputPath string, options *models.ImageConversion
 	if len(args) == 1 {
 		return nil
 	}
-	args = append(args, outputPath)
-	_, stderr, err := runCommand(context.Background(), "exiftool", args...)
-	if err != nil {
-		return fmt.Errorf("write metadata with exiftool: %s", strings.TrimSpace(stderr))
-	}
-	return nil
+	return append(args, outputPath)
 }
 
 func metadataFieldArgs(metadata *models.ImageMetadataFields) []string {
@@ -716,15 +819,47 @@ func isSafeImageColor(value string) bool {
 	return matched
 }
 
+// sanitizeAnnotationText makes overlay text literal for -annotate: a
+// leading "@" would read the text from a file, and percent escapes such as
+// %[filename] would expand to image properties.
 func sanitizeAnnotationText(value string) string {
 	value = strings.TrimSpace(value)
 	value = strings.ReplaceAll(value, "\x00", "")
+	value = strings.ReplaceAll(value, "%", "%%")
 	if strings.HasPrefix(value, "@") {
 		value = `\` + value
 	}
 	return value
 }
 
+// ValidateImageOptions is the image counterpart of ValidateVideoOptions.
+func (c *Converter) ValidateImageOptions(raw map[string]interface{}) error {
+	optionsBytes, _ := json.Marshal(raw)
+	var options models.ImageConversionOptions
+	if err := json.Unmarshal(optionsBytes, &options); err != nil {
+		return fmt.Errorf("invalid image options: %v", err)
+	}
+	if err := c.validateImageOptions(&options); err != nil {
+		return fmt.Errorf("invalid conversion options: %v", err)
+	}
+	return nil
+}
+
+// ValidateVideoOptions runs the same parse + validation convertVideo does,
+// for callers that must reject bad options before any media exists (e.g. a
+// live capture that would otherwise record for minutes first).
+func (c *Converter) ValidateVideoOptions(raw map[string]interface{}) error {
+	optionsBytes, _ := json.Marshal(raw)
+	var options models.VideoConversionOptions
+	if err := json.Unmarshal(optionsBytes, &options); err != nil {
+		return fmt.Errorf("invalid video options: %v", err)
+	}
+	if err := c.validateVideoOptions(&options); err != nil {
+		return fmt.Errorf("invalid conversion options: %v", err)
+	}
+	return nil
+}
+
 func (c *Converter) convertVideo(job *models.ConversionJob, inputPath, outputPath string) error {
 	// Parse options
 	optionsBytes, _ := json.Marshal(job.Options)
@@ -738,345 +873,126 @@ func (c *Converter) convertVideo(job *models.ConversionJob, inputPath, outputPat
 		return fmt.Errorf("invalid conversion options: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Starting video conversion for job %s\n", job.ID)
-	fmt.Printf("[DEBUG] Input: %s, Output: %s\n", inputPath, outputPath)
-	fmt.Printf("[DEBUG] Parsed options: %+v\n", options)
+	jobLog := c.jobLogger(job.ID)
+	jobLog.Debug("video conversion started", "input", inputPath, "output", outputPath)
 
 	// AI video operations (e.g. AI frame interpolation) own the entire output
 	// pipeline. The helper script handles frame extract → RIFE → encode, so we
 	// skip both the GIF branch and the normal ffmpeg filter chain.
 	if c.ai != nil && options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
-		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+		ctx, cancel := c.commandContext(job.ID)
 		defer cancel()
 		return c.runVideoAI(ctx, job, &options, inputPath, outputPath)
 	}
 
+	c.beginStages(job.ID, videoStagePlan(&options))
+
+	if options.BlurFaces {
+		c.startStage(job.ID, "face_blur")
+		if !c.faceDetectionAvailable() {
+			return fmt.Errorf("face blurring is not available on this server")
+		}
+		workDir, err := os.MkdirTemp(c.cfg.TempDir, "face-blur-*")
+		if err != nil {
+			return fmt.Errorf("failed to create face blur workspace: %v", err)
+		}
+		defer os.RemoveAll(workDir)
+		ctx, cancel := c.commandContext(job.ID)
+		blurred, err := c.blurVideoFaces(ctx, job.ID, inputPath, workDir, options.FaceBlurMode)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("face blur failed: %v", err)
+		}
+		inputPath = blurred
+	}
+
 	// Animated GIF is a two-stage pipeline (ffmpeg + gifsicle) that does not
 	// share the standard video codec/filter chain, so it gets its own handler.
 	if strings.EqualFold(options.Format, "gif") {
 		return c.convertVideoToGIF(job, &options, inputPath, outputPath)
 	}
 
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 10)
+	c.reportProgress(job.ID, 10)
+
+	// Everything after "-i input" that selects what is heard: the loudness
+	// analysis pass replays it so it measures the same span and track.
+	inputArgs := trimArgs(options.Trim)
+	if len(options.AudioTracks) > 0 {
+		probeCtx, probeCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := checkAudioTracksExist(probeCtx, inputPath, options.AudioTracks)
+		probeCancel()
+		if err != nil {
+			return err
+		}
+		// Measure the track that becomes the output's default.
+		inputArgs = append(inputArgs, "-map", fmt.Sprintf("0:a:%d", options.AudioTracks[0]))
 	}
 
-	// Build ffmpeg command
-	args := []string{"-i", inputPath}
+	c.reportProgress(job.ID, 20)
 
-	// Add trimming if specified (must come after input)
-	if options.Trim != nil {
-		// Seek to start time
-		args = append(args, "-ss", fmt.Sprintf("%.2f", options.Trim.StartTime))
-		// Set duration (end time - start time)
-		duration := options.Trim.EndTime - options.Trim.StartTime
-		args = append(args, "-t", fmt.Sprintf("%.2f", duration))
-		fmt.Printf("[DEBUG] Added trimming: start=%.2f, duration=%.2f\n", options.Trim.StartTime, duration)
+	// The command itself comes from BuildVideoCommand; what it cannot know
+	// without probing is collected here first.
+	var env CommandEnv
+	if tc := options.Timecode; tc != nil && tc.Enabled {
+		env.TimecodeRate = resolveTimecodeRate(tc, &options, inputPath)
 	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 20)
+	// WebM falls back from VP9+Opus to VP8+Vorbis when this FFmpeg build
+	// lacks them.
+	if options.Format == "webm" {
+		env.WebMVP9 = ffmpegSupportsWebMVP9()
 	}
-
-	// Build video filter chain
-	var videoFilters []string
-
-	// Scale/resize filter (should come first in filter chain)
-	if options.Width != nil || options.Height != nil {
-		var scaleFilter string
-		if options.Width != nil && options.Height != nil {
-			if options.PreserveAspectRatio {
-				scaleFilter = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", *options.Width, *options.Height)
-			} else {
-				scaleFilter = fmt.Sprintf("scale=%d:%d", *options.Width, *options.Height)
-			}
-		} else if options.Width != nil {
-			scaleFilter = fmt.Sprintf("scale=%d:-1", *options.Width)
-		} else {
-			scaleFilter = fmt.Sprintf("scale=-1:%d", *options.Height)
-		}
-		videoFilters = append(videoFilters, scaleFilter)
-		fmt.Printf("[DEBUG] Added scale filter: %s\n", scaleFilter)
+	videoFilters, err := BuildVideoFilters(&options, env.TimecodeRate)
+	if err != nil {
+		return err
 	}
-
-	// Apply visual effects
-	if options.VisualEffects != nil {
-		ve := options.VisualEffects
-
-		// Color correction filters
-		var colorFilters []string
-
-		if ve.Brightness != nil && *ve.Brightness != 0 {
-			// FFmpeg eq filter brightness: -1.0 to 1.0 (we receive -100 to 100)
-			brightness := float64(*ve.Brightness) / 100.0
-			colorFilters = append(colorFilters, fmt.Sprintf("brightness=%.2f", brightness))
-		}
-
-		if ve.Contrast != nil && *ve.Contrast != 0 {
-			// FFmpeg eq filter contrast: 0.0 to 2.0 (we receive -100 to 100)
-			contrast := 1.0 + (float64(*ve.Contrast) / 100.0)
-			if contrast < 0.0 {
-				contrast = 0.0
+	jobLog.Debug("video filter chain", "filters", strings.Join(videoFilters, ","))
+
+	c.reportProgress(job.ID, 60)
+
+	// Two-pass loudness normalization runs last so it sees the final tempo.
+	audioFilters := videoAudioFilters(&options)
+	if target, ok, _ := resolveLoudnessTarget(options.LoudnessNormalize); ok && !options.StripAudio {
+		probeCtx, probeCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		hasAudio := ffprobeHasStream(probeCtx, inputPath, "a")
+		probeCancel()
+		if hasAudio {
+			c.startStage(job.ID, "loudness_analysis")
+			loudnorm, err := c.loudnessNormalizeFilter(job.ID, inputPath, inputArgs, audioFilters, target)
+			if err != nil {
+				return err
 			}
-			colorFilters = append(colorFilters, fmt.Sprintf("contrast=%.2f", contrast))
-		}
-
-		if ve.Saturation != nil && *ve.Saturation != 0 {
-			// FFmpeg eq filter saturation: 0.0 to 3.0 (we receive -100 to 100)
-			saturation := 1.0 + (float64(*ve.Saturation) / 100.0)
-			if saturation < 0.0 {
-				saturation = 0.0
-			}
-			colorFilters = append(colorFilters, fmt.Sprintf("saturation=%.2f", saturation))
-		}
-
-		if ve.Gamma != nil && *ve.Gamma != 1.0 {
-			colorFilters = append(colorFilters, fmt.Sprintf("gamma=%.2f", *ve.Gamma))
-		}
-
-		if ve.Hue != nil && *ve.Hue != 0 {
-			// FFmpeg hue filter expects degrees
-			colorFilters = append(colorFilters, fmt.Sprintf("h=%d", *ve.Hue))
-		}
-
-		// Advanced color adjustments
-		if ve.Exposure != nil && *ve.Exposure != 0 {
-			// Exposure adjustment using curves filter
-			exposure := 1.0 + (float64(*ve.Exposure) / 100.0)
-			if exposure < 0.1 {
-				exposure = 0.1
-			}
-			colorFilters = append(colorFilters, fmt.Sprintf("exposure=%.2f", exposure))
-		}
-
-		if ve.Shadows != nil && *ve.Shadows != 0 {
-			// Shadow lift using eq filter
-			shadowLift := float64(*ve.Shadows) / 100.0
-			colorFilters = append(colorFilters, fmt.Sprintf("gamma_b=%.2f", 1.0-shadowLift*0.3))
-		}
-
-		if ve.Highlights != nil && *ve.Highlights != 0 {
-			// Highlight recovery using eq filter
-			highlightRecovery := float64(*ve.Highlights) / 100.0
-			colorFilters = append(colorFilters, fmt.Sprintf("gamma_r=%.2f", 1.0+highlightRecovery*0.3))
-		}
-
-		// Combine color filters into eq filter if any exist
-		if len(colorFilters) > 0 {
-			eqFilter := "eq=" + strings.Join(colorFilters, ":")
-			videoFilters = append(videoFilters, eqFilter)
-			fmt.Printf("[DEBUG] Added color correction filter: %s\n", eqFilter)
-		}
-
-		// Gaussian blur
-		if ve.GaussianBlur != nil && *ve.GaussianBlur > 0 {
-			blurFilter := fmt.Sprintf("gblur=sigma=%d", *ve.GaussianBlur)
-			videoFilters = append(videoFilters, blurFilter)
-			fmt.Printf("[DEBUG] Added blur filter: %s\n", blurFilter)
-		}
-
-		// Motion blur
-		if ve.MotionBlur != nil && ve.MotionBlur.Distance > 0 {
-			// Use minterpolate filter for motion blur effect
-			motionBlurFilter := fmt.Sprintf("minterpolate=fps=25:mc_mode=aobmc:me_mode=bidir:vsbmc=1")
-			videoFilters = append(videoFilters, motionBlurFilter)
-			fmt.Printf("[DEBUG] Added motion blur filter: %s\n", motionBlurFilter)
-		}
-
-		// Unsharp mask (sharpening)
-		if ve.UnsharpMask != nil && ve.UnsharpMask.Amount > 0 {
-			amount := ve.UnsharpMask.Amount / 100.0
-			radius := int(ve.UnsharpMask.Radius)
-
-			unsharpFilter := fmt.Sprintf("unsharp=luma_msize_x=%d:luma_msize_y=%d:luma_amount=%.2f",
-				radius*2+1, radius*2+1, amount)
-			videoFilters = append(videoFilters, unsharpFilter)
-			fmt.Printf("[DEBUG] Added unsharp mask filter: %s\n", unsharpFilter)
-		}
-
-		// Noise effects
-		if ve.Noise != nil && ve.Noise.Amount > 0 && ve.Noise.Type != "none" {
-			switch ve.Noise.Type {
-			case "film-grain":
-				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=t", int(ve.Noise.Amount))
-				videoFilters = append(videoFilters, noiseFilter)
-			case "digital":
-				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=u", int(ve.Noise.Amount))
-				videoFilters = append(videoFilters, noiseFilter)
-			case "vintage":
-				// Combine noise with slight desaturation for vintage look
-				noiseFilter := fmt.Sprintf("noise=alls=%d:allf=t", int(ve.Noise.Amount)/2)
-				videoFilters = append(videoFilters, noiseFilter)
-			}
-			fmt.Printf("[DEBUG] Added noise filter for type: %s\n", ve.Noise.Type)
-		}
-
-		// Artistic effects
-		if ve.Artistic != nil && *ve.Artistic != "none" {
-			switch *ve.Artistic {
-			case "oil-painting":
-				// Use convolution matrix to create oil painting effect
-				oilFilter := "convolution=0 0 0 0:0 1 0 0:0 0 0 0:0 0 0 0:1:1:1:1:0:128"
-				videoFilters = append(videoFilters, oilFilter)
-			case "watercolor":
-				// Combine blur with edge detection for watercolor effect
-				watercolorFilter := "gblur=sigma=2,edgedetect=low=0.1:high=0.4"
-				videoFilters = append(videoFilters, watercolorFilter)
-			case "sketch":
-				// Enhanced edge detection for sketch effect
-				sketchFilter := "edgedetect=low=0.05:high=0.2,negate"
-				videoFilters = append(videoFilters, sketchFilter)
-			case "emboss":
-				embossFilter := "convolution=0 -1 0:-1 5 -1:0 -1 0:0:1:1:0:128:1:0"
-				videoFilters = append(videoFilters, embossFilter)
-			case "edge-detection":
-				edgeFilter := "edgedetect=low=0.1:high=0.3"
-				videoFilters = append(videoFilters, edgeFilter)
-			case "posterize":
-				// Reduce color depth for posterize effect
-				posterizeFilter := "palettegen=stats_mode=single:max_colors=16,paletteuse=dither=none"
-				videoFilters = append(videoFilters, posterizeFilter)
-			}
-			fmt.Printf("[DEBUG] Added artistic filter: %s\n", *ve.Artistic)
+			env.Loudnorm = loudnorm
+			audioFilters = append(audioFilters, loudnorm)
 		}
 	}
 
-	// Apply transform effects
-	if options.Transform != nil {
-		t := options.Transform
-
-		// Rotation. For the cardinal 90/180/270 angles we use `transpose`
-		// (which correctly swaps width/height for 90/270) instead of `rotate`,
-		// which keeps the original canvas and leaves black corners. Arbitrary
-		// angles still fall back to `rotate`.
-		if t.Rotation != nil && *t.Rotation != 0 {
-			norm := math.Mod(*t.Rotation, 360)
-			if norm < 0 {
-				norm += 360
-			}
-			switch norm {
-			case 90:
-				videoFilters = append(videoFilters, "transpose=1") // 90° clockwise
-			case 180:
-				videoFilters = append(videoFilters, "transpose=1", "transpose=1")
-			case 270:
-				videoFilters = append(videoFilters, "transpose=2") // 90° counter-clockwise
-			default:
-				radians := (norm * 3.14159) / 180
-				videoFilters = append(videoFilters, fmt.Sprintf("rotate=%.4f", radians))
-			}
-			fmt.Printf("[DEBUG] Added rotation filter for %.2f°\n", norm)
-		}
-
-		// Flips
-		if t.FlipHorizontal != nil && *t.FlipHorizontal {
-			videoFilters = append(videoFilters, "hflip")
-			fmt.Printf("[DEBUG] Added horizontal flip\n")
-		}
-
-		if t.FlipVertical != nil && *t.FlipVertical {
-			videoFilters = append(videoFilters, "vflip")
-			fmt.Printf("[DEBUG] Added vertical flip\n")
-		}
-
-		// Crop (if not already handled by trimming)
-		if t.Crop != nil {
-			cropFilter := fmt.Sprintf("crop=%d:%d:%d:%d", t.Crop.Width, t.Crop.Height, t.Crop.X, t.Crop.Y)
-			videoFilters = append(videoFilters, cropFilter)
-			fmt.Printf("[DEBUG] Added crop filter: %s\n", cropFilter)
+	// Chunked mode reuses the filter chains and codec settings built above
+	// and falls through to the single-pass encode for short sources.
+	c.startStage(job.ID, "encode")
+	encoded := false
+	if options.Chunked != nil && options.Chunked.Enabled {
+		encoded, err = c.encodeVideoChunked(job.ID, inputPath, outputPath, &options, videoFilters, audioFilters, videoEncodeSettingsFor(&options, env.WebMVP9))
+		if err != nil {
+			return err
 		}
 	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 40)
-	}
-
-	// Apply temporal effects
-	if options.Temporal != nil {
-		te := options.Temporal
-
-		// Reverse video
-		if te.Reverse != nil && *te.Reverse {
-			videoFilters = append(videoFilters, "reverse")
-			fmt.Printf("[DEBUG] Added reverse filter\n")
+	if !encoded {
+		args, err := BuildVideoCommand(inputPath, outputPath, &options, env)
+		if err != nil {
+			return err
 		}
 
-		// Frame rate conversion
-		if te.FrameRate != nil && te.FrameRate.Target != nil {
-			fpsFilter := fmt.Sprintf("fps=%d", *te.FrameRate.Target)
-			videoFilters = append(videoFilters, fpsFilter)
-			fmt.Printf("[DEBUG] Added fps filter: %s\n", fpsFilter)
-		}
+		jobLog.Debug("ffmpeg command", "args", args)
 
-		// Video stabilization
-		if te.Stabilization != nil && te.Stabilization.Enabled {
-			stabFilter := fmt.Sprintf("deshake=x=%d:y=%d", te.Stabilization.Shakiness, te.Stabilization.Accuracy)
-			videoFilters = append(videoFilters, stabFilter)
-			fmt.Printf("[DEBUG] Added stabilization filter: %s\n", stabFilter)
+		if err := c.runFFmpegWithProgress(job.ID, "ffmpeg", args...); err != nil {
+			return err
 		}
 	}
-
-	// Speed adjustment (use setpts for video speed)
-	if options.Speed != 1.0 {
-		speedFilter := fmt.Sprintf("setpts=%.2f*PTS", 1.0/options.Speed)
-		videoFilters = append(videoFilters, speedFilter)
-		fmt.Printf("[DEBUG] Added speed filter: %s\n", speedFilter)
+	if metrics, ok, _ := resolveQualityMetrics(options.QualityMetrics); ok && c.jobManager != nil {
+		c.startStage(job.ID, "quality_metrics")
+		_ = c.jobManager.SetQualityMetrics(job.ID, c.computeQualityMetrics(job.ID, inputPath, outputPath, &options, metrics))
 	}
-
-	// Apply video filters if any exist
-	if len(videoFilters) > 0 {
-		filterChain := strings.Join(videoFilters, ",")
-		args = append(args, "-vf", filterChain)
-		fmt.Printf("[DEBUG] Complete video filter chain: %s\n", filterChain)
-	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 60)
-	}
-
-	// Audio processing for speed changes
-	if options.Speed != 1.0 {
-		// Adjust audio tempo to match video speed
-		audioFilter := fmt.Sprintf("atempo=%.2f", options.Speed)
-		args = append(args, "-af", audioFilter)
-		fmt.Printf("[DEBUG] Added audio tempo filter: %s\n", audioFilter)
-	}
-
-	// Output container + codec selection.
-	//
-	// Centralized in buildVideoCodecArgs so each format sets its video codec,
-	// audio codec, and any container-specific muxer flags exactly once. The old
-	// code appended a default "-c:v libx264 -c:a aac" and then appended a second
-	// "-c:v"/"-c:a" for special formats, leaving FFmpeg to silently honor the
-	// last flag — correct in practice but confusing and easy to break. WebM
-	// falls back from VP9+Opus to VP8+Vorbis when this FFmpeg build lacks them.
-	// Optional compression overrides (codec, CRF, bitrate, preset, strip-audio)
-	// from the video-compressor / compress-mp4 pages are threaded through here.
-	webmVP9 := false
-	if options.Format == "webm" {
-		webmVP9 = ffmpegSupportsWebMVP9()
-	}
-	args = append(args, buildVideoCodecArgs(videoEncodeSettings{
-		Format:       options.Format,
-		Quality:      options.Quality,
-		Codec:        options.VideoCodec,
-		CRF:          options.CRF,
-		VideoBitrate: options.VideoBitrateKbps,
-		AudioBitrate: options.AudioBitrateKbps,
-		Preset:       options.Preset,
-		StripAudio:   options.StripAudio,
-		WebMVP9:      webmVP9,
-	})...)
-
-	args = append(args, "-y", outputPath)
-
-	fmt.Printf("[DEBUG] Complete FFmpeg command: ffmpeg %s\n", strings.Join(args, " "))
-
-	return c.runFFmpegWithProgress(job.ID, "ffmpeg", args...)
+	return nil
 }
 
 // videoCRF maps the quality preset to an x264 / VPx CRF value (lower = higher
@@ -1119,6 +1035,11 @@ type videoEncodeSettings struct {
 	Preset       string
 	StripAudio   bool
 	WebMVP9      bool
+	MovFlags     string // "", faststart, fragmented — MP4/MOV only
+
+	KeyframeInterval *float64 // seconds between forced keyframes
+	BFrames          *int     // H.26x only
+	SceneCut         *bool    // nil = encoder default
 }
 
 // videoOutputCodecArgs is the legacy quality-only entry point (kept so existing
@@ -1131,7 +1052,8 @@ func videoOutputCodecArgs(format, quality string, webmVP9 bool) []string {
 // video format. It is the single source of truth for codec selection so the
 // FFmpeg command never carries duplicate or conflicting -c:v / -c:a flags.
 //
-//   - MP4 / MOV  -> H.264 + AAC, yuv420p, +faststart (universal playback)
+//   - MP4 / MOV  -> H.264 + AAC, yuv420p, +faststart (universal playback) or
+//     fragmented MP4 when MovFlags asks for it
 //   - WebM       -> VP9 + Opus, or VP8 + Vorbis when WebMVP9 is false
 //   - MKV / FLV  -> H.264 + AAC (flexible / Flash-9 containers)
 //   - AVI        -> H.264 + MP3 (AVI predates AAC; MP3 stays broadly playable)
@@ -1139,8 +1061,9 @@ func videoOutputCodecArgs(format, quality string, webmVP9 bool) []string {
 //   - ProRes     -> prores_ks profile 2 + PCM (editing intermediate)
 //   - DNxHD      -> DNxHR HQ + PCM (resolution-independent editing intermediate)
 //
-// Codec/CRF/bitrate/preset/strip-audio overrides apply to the H.26x and WebM
-// paths; the intra/editing codecs (prores/dnxhd/wmv) ignore them. gif is
+// Codec/CRF/bitrate/preset/strip-audio and GOP (keyframe interval, B-frames,
+// scene cut) overrides apply to the H.26x and WebM paths; the intra/editing
+// codecs (prores/dnxhd/wmv) ignore them. gif is
 // handled by convertVideoToGIF and never reaches this function.
 func buildVideoCodecArgs(s videoEncodeSettings) []string {
 	crf := videoCRF(s.Quality)
@@ -1166,6 +1089,9 @@ func buildVideoCodecArgs(s videoEncodeSettings) []string {
 		}
 		return nil
 	}
+	gopArgs := func(vcodec string) []string {
+		return videoGOPArgs(vcodec, s.KeyframeInterval, s.BFrames, s.SceneCut)
+	}
 
 	switch s.Format {
 	case "webm":
@@ -1173,6 +1099,7 @@ func buildVideoCodecArgs(s videoEncodeSettings) []string {
 		// the FFmpeg build lacks VP9/Opus and the caller did not force a codec.
 		if !s.WebMVP9 && s.Codec == "" {
 			args := []string{"-c:v", "libvpx", "-crf", crf, "-b:v", "1M"}
+			args = append(args, gopArgs("libvpx")...)
 			return append(args, audioArgs("libvorbis")...)
 		}
 		vcodec := "libvpx-vp9"
@@ -1186,6 +1113,7 @@ func buildVideoCodecArgs(s videoEncodeSettings) []string {
 			args = []string{"-c:v", vcodec, "-b:v", "0", "-crf", crf}
 		}
 		args = append(args, bitrateArgs()...)
+		args = append(args, gopArgs(vcodec)...)
 		return append(args, audioArgs("libopus")...)
 	case "prores":
 		return []string{"-c:v", "prores_ks", "-profile:v", "2", "-pix_fmt", "yuv422p10le", "-c:a", "pcm_s16le"}
@@ -1199,6 +1127,7 @@ func buildVideoCodecArgs(s videoEncodeSettings) []string {
 			args = append(args, "-preset", s.Preset)
 		}
 		args = append(args, bitrateArgs()...)
+		args = append(args, gopArgs("libx264")...)
 		// AVI predates AAC; MP3 keeps the container broadly playable.
 		return append(args, audioArgs("libmp3lame")...)
 	default:
@@ -1225,13 +1154,97 @@ func buildVideoCodecArgs(s videoEncodeSettings) []string {
 			args = append(args, "-preset", s.Preset)
 		}
 		args = append(args, bitrateArgs()...)
+		args = append(args, gopArgs(vcodec)...)
 		if s.Format == "mp4" || s.Format == "mov" {
-			args = append(args, "-movflags", "+faststart")
+			args = append(args, "-movflags", mp4MovFlags(s.MovFlags))
 		}
 		return append(args, audioArgs("aac")...)
 	}
 }
 
+// videoGOPArgs builds the keyframe/B-frame flags for one encoder.
+//
+// The keyframe interval uses -force_key_frames with a time expression rather
+// than -g, because -g counts frames and we don't know the source frame rate
+// when building the command. Disabling scene cut is encoder specific; libvpx
+// doesn't insert scene-change keyframes, so there is nothing to turn off.
+func videoGOPArgs(vcodec string, keyframeInterval *float64, bFrames *int, sceneCut *bool) []string {
+	var args []string
+	if keyframeInterval != nil {
+		interval := strconv.FormatFloat(*keyframeInterval, 'f', -1, 64)
+		args = append(args, "-force_key_frames", "expr:gte(t,n_forced*"+interval+")")
+	}
+	if bFrames != nil && (vcodec == "libx264" || vcodec == "libx265") {
+		args = append(args, "-bf", strconv.Itoa(*bFrames))
+	}
+	if sceneCut != nil && !*sceneCut {
+		switch vcodec {
+		case "libx264":
+			args = append(args, "-sc_threshold", "0")
+		case "libx265":
+			args = append(args, "-x265-params", "scenecut=0")
+		case "libsvtav1":
+			args = append(args, "-svtav1-params", "scd=0")
+		}
+	}
+	return args
+}
+
+// videoAspectRatios lists the supported pad-to-aspect targets as W:H pairs.
+var videoAspectRatios = map[string][2]int{
+	"16:9": {16, 9},
+	"9:16": {9, 16},
+	"1:1":  {1, 1},
+	"4:5":  {4, 5},
+	"4:3":  {4, 3},
+	"21:9": {21, 9},
+}
+
+// videoPaddingFilter adds fixed borders. The output size is rounded up to
+// even dimensions so yuv420p encoders accept odd padding values.
+func videoPaddingFilter(p *models.Padding) string {
+	color := "black"
+	if p.Color != "" {
+		color = hexToFFmpegColor(p.Color)
+	}
+	return fmt.Sprintf("pad=ceil((iw+%d)/2)*2:ceil((ih+%d)/2)*2:%d:%d:color=%s",
+		p.Left+p.Right, p.Top+p.Bottom, p.Left, p.Top, color)
+}
+
+// videoAspectPadFilter grows the canvas along one axis until it matches the
+// target ratio and centers the picture — letterbox for wide targets,
+// pillarbox for tall ones. Commas inside the expressions are escaped so the
+// filter can sit in a comma-joined -vf chain.
+func videoAspectPadFilter(ratio, color string) (string, error) {
+	wh, ok := videoAspectRatios[strings.TrimSpace(ratio)]
+	if !ok {
+		return "", fmt.Errorf("unsupported aspect ratio: %s (expected 16:9, 9:16, 1:1, 4:5, 4:3 or 21:9)", ratio)
+	}
+	fill := "black"
+	if color != "" {
+		if !hexColorRegexp.MatchString(color) {
+			return "", fmt.Errorf("invalid aspect color: %s (expected #RRGGBB)", color)
+		}
+		fill = hexToFFmpegColor(color)
+	}
+	w, h := wh[0], wh[1]
+	return fmt.Sprintf(`pad=w=ceil(max(iw\,ih*%d/%d)/2)*2:h=ceil(max(ih\,iw*%d/%d)/2)*2:x=(ow-iw)/2:y=(oh-ih)/2:color=%s`,
+		w, h, h, w, fill), nil
+}
+
+// mp4MovFlags maps the ContainerFlags option onto an FFmpeg -movflags value.
+// faststart relocates the moov atom to the head of the file (a second pass
+// over the output) so browsers can start playback before the download
+// finishes. Fragmented MP4 writes a moof/mdat pair per keyframe instead, which
+// MSE players and chunked delivery need; it never has a trailing moov, so
+// faststart would be meaningless there.
+func mp4MovFlags(mode string) string {
+	if mode == "fragmented" {
+		return "+frag_keyframe+empty_moov+default_base_moof"
+	}
+	return "+faststart"
+}
+
 var (
 	webmVP9Once   sync.Once
 	webmVP9Cached bool
@@ -1303,6 +1316,43 @@ func (c *Converter) validateVideoOptions(options *models.VideoConversionOptions)
 	if options.AudioBitrateKbps != nil && (*options.AudioBitrateKbps < 8 || *options.AudioBitrateKbps > 1024) {
 		return fmt.Errorf("audio bitrate must be between 8 and 1024 kbps, got %d", *options.AudioBitrateKbps)
 	}
+	switch options.ContainerFlags {
+	case "", "faststart", "fragmented":
+	default:
+		return fmt.Errorf("unsupported container flags: %s (expected faststart|fragmented)", options.ContainerFlags)
+	}
+	if _, _, err := resolveLoudnessTarget(options.LoudnessNormalize); err != nil {
+		return err
+	}
+	if _, _, err := resolveQualityMetrics(options.QualityMetrics); err != nil {
+		return err
+	}
+	if k := options.KeyframeIntervalSeconds; k != nil && (math.IsNaN(*k) || *k < 0.1 || *k > 20) {
+		return fmt.Errorf("keyframe interval must be between 0.1 and 20 seconds, got %v", *k)
+	}
+	if options.BFrames != nil && (*options.BFrames < 0 || *options.BFrames > 16) {
+		return fmt.Errorf("b-frames must be between 0 and 16, got %d", *options.BFrames)
+	}
+	if err := validateTimecodeBurnIn(options.Timecode, options.Format); err != nil {
+		return err
+	}
+	if err := validateAudioTracks(options.AudioTracks, options.StripAudio); err != nil {
+		return err
+	}
+	if err := validateChunkedEncoding(options); err != nil {
+		return err
+	}
+	if err := validateMaxSize(options.MaxWidth, options.MaxHeight); err != nil {
+		return err
+	}
+	if options.TextOverlay != nil {
+		if err := validateImageTextOverlay(options.TextOverlay); err != nil {
+			return err
+		}
+	}
+	if err := validateDetelecine(options.Advanced, options.Format); err != nil {
+		return err
+	}
 	if options.Preset != "" {
 		validPresets := map[string]bool{
 			"ultrafast": true, "superfast": true, "veryfast": true, "faster": true,
@@ -1328,6 +1378,24 @@ func (c *Converter) validateVideoOptions(options *models.VideoConversionOptions)
 			return fmt.Errorf("trim duration must be at least 0.1 seconds, got %.2f", options.Trim.EndTime-options.Trim.StartTime)
 		}
 	}
+	if err := validateTrimSegments(options.TrimSegments, options.Trim); err != nil {
+		return err
+	}
+	if err := validateUpscale(options.Upscale); err != nil {
+		return err
+	}
+	if options.Upscale != 0 && (options.Width != nil || options.Height != nil) {
+		return fmt.Errorf("upscale cannot be combined with width/height")
+	}
+	if options.Upscale != 0 && options.Format == "gif" {
+		return fmt.Errorf("upscale does not apply to gif output")
+	}
+	if err := validateFaceBlur(options.BlurFaces, options.FaceBlurMode); err != nil {
+		return err
+	}
+	if options.BlurFaces && options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
+		return fmt.Errorf("blurFaces cannot be combined with AI video operations")
+	}
 
 	// Validate visual effects if specified
 	if options.VisualEffects != nil {
@@ -1359,6 +1427,12 @@ func (c *Converter) validateVideoOptions(options *models.VideoConversionOptions)
 				return fmt.Errorf("unsupported artistic effect: %s", *ve.Artistic)
 			}
 		}
+		if err := validateCleanupLevel("deblock", ve.Deblock); err != nil {
+			return err
+		}
+		if err := validateCleanupLevel("deband", ve.Deband); err != nil {
+			return err
+		}
 	}
 
 	// Validate transform if specified
@@ -1375,6 +1449,22 @@ func (c *Converter) validateVideoOptions(options *models.VideoConversionOptions)
 				return fmt.Errorf("crop dimensions must be positive")
 			}
 		}
+		if p := t.Padding; p != nil {
+			if p.Top < 0 || p.Bottom < 0 || p.Left < 0 || p.Right < 0 {
+				return fmt.Errorf("padding must be non-negative")
+			}
+			if p.Top+p.Bottom > 4096 || p.Left+p.Right > 4096 {
+				return fmt.Errorf("padding must not exceed 4096 pixels per axis")
+			}
+			if p.Color != "" && !hexColorRegexp.MatchString(p.Color) {
+				return fmt.Errorf("invalid padding color: %s (expected #RRGGBB)", p.Color)
+			}
+		}
+		if t.AspectRatio != "" {
+			if _, err := videoAspectPadFilter(t.AspectRatio, t.AspectColor); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Validate temporal effects if specified
@@ -1487,9 +1577,8 @@ func (c *Converter) convertVideoToGIF(job *models.ConversionJob, options *models
 	rawGIFPath := filepath.Join(outputDir, fmt.Sprintf("raw_%d.gif", time.Now().UnixNano()))
 	defer func() { _ = os.Remove(rawGIFPath) }()
 
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 5)
-	}
+	c.startStage(job.ID, "render")
+	c.reportProgress(job.ID, 5)
 
 	ffArgs := []string{"-y", "-i", inputPath}
 	if options.Trim != nil {
@@ -1500,15 +1589,23 @@ func (c *Converter) convertVideoToGIF(job *models.ConversionJob, options *models
 	// codecs prefer even dimensions); pix_fmt rgb8 + low fps keep the file
 	// small before gifsicle quantizes the palette.
 	vf := fmt.Sprintf("scale=%d:-4", gifWidth)
+	if f := trimSegmentsVideoFilter(options.TrimSegments); f != "" {
+		vf = f + "," + vf
+	}
+	if f := videoMaxSizeFilter(options.MaxWidth, options.MaxHeight); f != "" {
+		vf += "," + f
+	}
+	if o := options.TextOverlay; o != nil && strings.TrimSpace(o.Text) != "" {
+		vf += "," + videoTextOverlayFilter(o)
+	}
 	ffArgs = append(ffArgs, "-vf", vf, "-pix_fmt", "rgb8", "-r", strconv.Itoa(gifFPS), "-f", "gif", rawGIFPath)
-	fmt.Printf("[DEBUG] GIF stage 1 (ffmpeg): ffmpeg %s\n", strings.Join(ffArgs, " "))
+	c.jobLogger(job.ID).Debug("gif palette command", "args", ffArgs)
 	if err := c.runFFmpegWithProgress(job.ID, "ffmpeg", ffArgs...); err != nil {
 		return fmt.Errorf("ffmpeg gif stage failed: %v", err)
 	}
 
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 75)
-	}
+	c.startStage(job.ID, "optimize")
+	c.reportProgress(job.ID, 75)
 
 	gifsicleArgs := []string{
 		fmt.Sprintf("--optimize=%d", gifOptimize),
@@ -1518,17 +1615,17 @@ func (c *Converter) convertVideoToGIF(job *models.ConversionJob, options *models
 		rawGIFPath,
 		"-o", outputPath,
 	}
-	fmt.Printf("[DEBUG] GIF stage 2 (gifsicle): gifsicle %s\n", strings.Join(gifsicleArgs, " "))
-	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Hour)
+	c.jobLogger(job.ID).Debug("gifsicle command", "args", gifsicleArgs)
+	ctx, cancel := c.commandContext(job.ID)
 	defer cancel()
+	done := c.recordCommand(job.ID, "gifsicle", gifsicleArgs)
 	_, stderr, err := runCommand(ctx, "gifsicle", gifsicleArgs...)
+	done(stderr, err)
 	if err != nil {
 		return fmt.Errorf("gifsicle failed: %v: %s", err, strings.TrimSpace(stderr))
 	}
 
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 100)
-	}
+	c.reportProgress(job.ID, 100)
 	return nil
 }
 
@@ -1545,421 +1642,61 @@ func (c *Converter) convertAudio(job *models.ConversionJob, inputPath, outputPat
 		return fmt.Errorf("invalid conversion options: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Starting audio conversion for job %s\n", job.ID)
-	fmt.Printf("[DEBUG] Input: %s, Output: %s\n", inputPath, outputPath)
-	fmt.Printf("[DEBUG] Parsed options: %+v\n", options)
+	jobLog := c.jobLogger(job.ID)
+	jobLog.Debug("audio conversion started", "input", inputPath, "output", outputPath)
 
 	// If an AI audio operation is selected, route to the AI service and skip
 	// the normal FFmpeg pipeline.
 	if c.ai != nil && options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
-		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+		ctx, cancel := c.commandContext(job.ID)
 		defer cancel()
 		return c.runAudioAI(ctx, job, &options, inputPath, outputPath)
 	}
 
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 10)
-	}
-
-	// Build ffmpeg command
-	args := []string{"-i", inputPath}
-
-	// Add trimming if specified (must come after input)
-	if options.Trim != nil {
-		// Seek to start time
-		args = append(args, "-ss", fmt.Sprintf("%.2f", options.Trim.StartTime))
-		// Set duration (end time - start time)
-		duration := options.Trim.EndTime - options.Trim.StartTime
-		args = append(args, "-t", fmt.Sprintf("%.2f", duration))
-		fmt.Printf("[DEBUG] Added trimming: start=%.2f, duration=%.2f\n", options.Trim.StartTime, duration)
-	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 20)
-	}
-
-	// Build audio filter chain
-	var audioFilters []string
+	c.beginStages(job.ID, audioStagePlan(&options))
+	c.reportProgress(job.ID, 10)
+	c.reportProgress(job.ID, 20)
 
-	// Basic volume adjustment (from the main volume option)
-	if options.Volume != 1.0 {
-		volumeFilter := fmt.Sprintf("volume=%.2f", options.Volume)
-		audioFilters = append(audioFilters, volumeFilter)
-		fmt.Printf("[DEBUG] Added volume filter: %s\n", volumeFilter)
-	}
-
-	// Apply basic processing effects
-	if options.BasicProcessing != nil {
-		bp := options.BasicProcessing
-
-		// Normalize audio
-		if bp.Normalize != nil && *bp.Normalize {
-			audioFilters = append(audioFilters, "loudnorm")
-			fmt.Printf("[DEBUG] Added normalize filter\n")
-		}
-
-		// Amplify (additional volume adjustment in dB)
-		if bp.Amplify != nil && *bp.Amplify != 0 {
-			// Convert dB to linear gain
-			gain := fmt.Sprintf("%.2f", *bp.Amplify)
-			amplifyFilter := fmt.Sprintf("volume=%sdB", gain)
-			audioFilters = append(audioFilters, amplifyFilter)
-			fmt.Printf("[DEBUG] Added amplify filter: %s\n", amplifyFilter)
-		}
-
-		// Fade in
-		if bp.FadeIn != nil && *bp.FadeIn > 0 {
-			fadeInFilter := fmt.Sprintf("afade=t=in:d=%.2f", *bp.FadeIn)
-			audioFilters = append(audioFilters, fadeInFilter)
-			fmt.Printf("[DEBUG] Added fade in filter: %s\n", fadeInFilter)
-		}
-
-		// Fade out
-		if bp.FadeOut != nil && *bp.FadeOut > 0 {
-			fadeOutFilter := fmt.Sprintf("afade=t=out:d=%.2f", *bp.FadeOut)
-			audioFilters = append(audioFilters, fadeOutFilter)
-			fmt.Printf("[DEBUG] Added fade out filter: %s\n", fadeOutFilter)
-		}
-
-		// EQ presets
-		if bp.Equalizer != nil && bp.Equalizer.Enabled && bp.Equalizer.Preset != "none" {
-			var eqFilter string
-			switch bp.Equalizer.Preset {
-			case "bass-boost":
-				eqFilter = "equalizer=f=80:width_type=o:width=2:g=6"
-			case "treble-boost":
-				eqFilter = "equalizer=f=10000:width_type=o:width=2:g=6"
-			case "vocal":
-				eqFilter = "equalizer=f=1000:width_type=o:width=2:g=3,equalizer=f=3000:width_type=o:width=2:g=3"
-			case "classical":
-				eqFilter = "equalizer=f=315:width_type=o:width=2:g=2,equalizer=f=1000:width_type=o:width=2:g=-2,equalizer=f=8000:width_type=o:width=2:g=4"
-			case "rock":
-				eqFilter = "equalizer=f=80:width_type=o:width=2:g=4,equalizer=f=250:width_type=o:width=2:g=-2,equalizer=f=1000:width_type=o:width=2:g=2,equalizer=f=4000:width_type=o:width=2:g=4"
-			case "jazz":
-				eqFilter = "equalizer=f=125:width_type=o:width=2:g=3,equalizer=f=500:width_type=o:width=2:g=-2,equalizer=f=2000:width_type=o:width=2:g=2,equalizer=f=8000:width_type=o:width=2:g=3"
-			}
-			if eqFilter != "" {
-				audioFilters = append(audioFilters, eqFilter)
-				fmt.Printf("[DEBUG] Added EQ preset filter: %s\n", eqFilter)
-			}
-		}
-
-		// Stereo processing
-		if bp.Stereo != nil {
-			// Pan adjustment
-			if bp.Stereo.Pan != nil && *bp.Stereo.Pan != 0 {
-				// Convert -100 to 100 range to -1 to 1
-				panValue := float64(*bp.Stereo.Pan) / 100.0
-				panFilter := fmt.Sprintf("pan=stereo|c0=%.2f*c0+%.2f*c1|c1=%.2f*c0+%.2f*c1",
-					1.0-panValue, panValue, panValue, 1.0-panValue)
-				audioFilters = append(audioFilters, panFilter)
-				fmt.Printf("[DEBUG] Added pan filter: %s\n", panFilter)
-			}
-
-			// Stereo width adjustment
-			if bp.Stereo.Width != nil && *bp.Stereo.Width != 100 {
-				widthValue := float64(*bp.Stereo.Width) / 100.0
-				widthFilter := fmt.Sprintf("extrastereo=m=%.2f", widthValue)
-				audioFilters = append(audioFilters, widthFilter)
-				fmt.Printf("[DEBUG] Added stereo width filter: %s\n", widthFilter)
-			}
-
-			// Mono conversion
-			if bp.Stereo.MonoConversion != nil && *bp.Stereo.MonoConversion {
-				audioFilters = append(audioFilters, "pan=mono|c0=0.5*c0+0.5*c1")
-				fmt.Printf("[DEBUG] Added mono conversion filter\n")
-			}
-
-			// Channel swap
-			if bp.Stereo.ChannelSwap != nil && *bp.Stereo.ChannelSwap {
-				audioFilters = append(audioFilters, "pan=stereo|c0=c1|c1=c0")
-				fmt.Printf("[DEBUG] Added channel swap filter\n")
-			}
-		}
-	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 40)
-	}
-
-	// Apply time-based effects
-	if options.TimeBasedEffects != nil {
-		tbe := options.TimeBasedEffects
-
-		// Reverb
-		if tbe.Reverb != nil && tbe.Reverb.Enabled && tbe.Reverb.Type != "none" {
-			var reverbFilter string
-			switch tbe.Reverb.Type {
-			case "room":
-				reverbFilter = fmt.Sprintf("aecho=0.8:0.88:60:0.4")
-			case "hall":
-				reverbFilter = fmt.Sprintf("aecho=0.8:0.88:60:0.4,aecho=0.8:0.88:40:0.3")
-			case "plate":
-				reverbFilter = fmt.Sprintf("aecho=0.8:0.7:40:0.25")
-			case "spring":
-				reverbFilter = fmt.Sprintf("aecho=0.6:0.6:100:0.5")
-			}
-			if reverbFilter != "" {
-				audioFilters = append(audioFilters, reverbFilter)
-				fmt.Printf("[DEBUG] Added reverb filter: %s\n", reverbFilter)
-			}
-		}
-
-		// Delay/Echo
-		if tbe.Delay != nil && tbe.Delay.Enabled && tbe.Delay.Type != "none" {
-			feedback := tbe.Delay.Feedback / 100.0
-
-			var delayFilter string
-			switch tbe.Delay.Type {
-			case "echo":
-				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f", feedback, tbe.Delay.Time, feedback)
-			case "multi-tap":
-				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f,aecho=0.6:%.2f:%.0f:%.2f",
-					feedback, tbe.Delay.Time, feedback*0.8, feedback*0.8, tbe.Delay.Time*1.5, feedback*0.6)
-			case "ping-pong":
-				// Simplified ping-pong delay
-				delayFilter = fmt.Sprintf("aecho=0.8:%.2f:%.0f:%.2f", feedback, tbe.Delay.Time, feedback)
-			}
-			if delayFilter != "" {
-				audioFilters = append(audioFilters, delayFilter)
-				fmt.Printf("[DEBUG] Added delay filter: %s\n", delayFilter)
-			}
-		}
-
-		// Modulation effects (basic implementations)
-		if tbe.Modulation != nil && tbe.Modulation.Enabled && tbe.Modulation.Type != "none" {
-			var modFilter string
-			rate := tbe.Modulation.Rate
-			depth := tbe.Modulation.Depth / 100.0
-
-			switch tbe.Modulation.Type {
-			case "chorus":
-				modFilter = fmt.Sprintf("chorus=0.7:0.9:55:0.4:0.25:2:t")
-			case "flanger":
-				modFilter = fmt.Sprintf("flanger")
-			case "tremolo":
-				modFilter = fmt.Sprintf("tremolo=f=%.2f:d=%.2f", rate, depth)
-			case "vibrato":
-				modFilter = fmt.Sprintf("vibrato=f=%.2f:d=%.2f", rate, depth)
-			}
-			if modFilter != "" {
-				audioFilters = append(audioFilters, modFilter)
-				fmt.Printf("[DEBUG] Added modulation filter: %s\n", modFilter)
-			}
-		}
-	}
-
-	// Update progress
-	if c.jobManager != nil {
-		c.jobManager.SendProgressUpdate(job.ID, 60)
-	}
-
-	// Apply restoration effects
-	if options.Restoration != nil {
-		rest := options.Restoration
-
-		// Noise reduction
-		if rest.NoiseReduction != nil && rest.NoiseReduction.Enabled {
-			switch rest.NoiseReduction.Type {
-			case "spectral":
-				// Use afftdn filter for spectral noise reduction
-				noiseFilter := fmt.Sprintf("afftdn=nr=%.2f:nf=%.2f", rest.NoiseReduction.Strength/10.0, rest.NoiseReduction.Strength/20.0)
-				audioFilters = append(audioFilters, noiseFilter)
-				fmt.Printf("[DEBUG] Added spectral noise reduction: %s\n", noiseFilter)
-			case "adaptive":
-				// Use anlmdn filter for adaptive noise reduction
-				noiseFilter := fmt.Sprintf("anlmdn=s=%.2f", rest.NoiseReduction.Strength/10.0)
-				audioFilters = append(audioFilters, noiseFilter)
-				fmt.Printf("[DEBUG] Added adaptive noise reduction: %s\n", noiseFilter)
-			case "gate":
-				// Use gate filter for noise gating
-				threshold := -40.0 + (rest.NoiseReduction.Strength * 0.4) // -40dB to 0dB
-				gateFilter := fmt.Sprintf("agate=threshold=%.1fdB:ratio=10", threshold)
-				audioFilters = append(audioFilters, gateFilter)
-				fmt.Printf("[DEBUG] Added noise gate: %s\n", gateFilter)
-			}
-		}
-
-		// De-hum filter
-		if rest.DeHum != nil && rest.DeHum.Enabled {
-			var humFreq string
-			switch rest.DeHum.Frequency {
-			case "50hz":
-				humFreq = "50"
-			case "60hz":
-				humFreq = "60"
-			case "auto":
-				humFreq = "60" // Default to 60Hz for auto
-			}
-			if humFreq != "" {
-				// Use notch filter to remove hum
-				dehumFilter := fmt.Sprintf("highpass=f=%s,lowpass=f=%s", humFreq, humFreq)
-				// Better approach: use equalizer to notch out hum frequency
-				dehumFilter = fmt.Sprintf("equalizer=f=%s:width_type=q:width=0.5:g=-40", humFreq)
-				audioFilters = append(audioFilters, dehumFilter)
-				fmt.Printf("[DEBUG] Added de-hum filter: %s\n", dehumFilter)
-			}
-		}
-
-		// De-clip restoration
-		if rest.Declip != nil && rest.Declip.Enabled {
-			// Use adeclip filter for clipping restoration
-			declipFilter := fmt.Sprintf("adeclip=threshold=%.2f", rest.Declip.Threshold/100.0)
-			audioFilters = append(audioFilters, declipFilter)
-			fmt.Printf("[DEBUG] Added declip filter: %s\n", declipFilter)
-		}
-
-		// Silence detection and removal
-		if rest.SilenceDetection != nil && rest.SilenceDetection.Enabled {
-			threshold := -50.0 + (rest.SilenceDetection.Threshold * 0.5) // -50dB to 0dB
-			duration := rest.SilenceDetection.MinDuration
-			silenceFilter := fmt.Sprintf("silenceremove=start_periods=1:start_threshold=%.1fdB:start_duration=%.2f",
-				threshold, duration)
-			audioFilters = append(audioFilters, silenceFilter)
-			fmt.Printf("[DEBUG] Added silence removal: %s\n", silenceFilter)
+	// Two-pass EBU R128 loudness normalization is always the final stage: the
+	// analysis pass measures the output of every filter BuildAudioFilters
+	// returns, over the same trimmed span.
+	var env CommandEnv
+	if target, ok, _ := resolveLoudnessTarget(options.LoudnessNormalize); ok {
+		c.startStage(job.ID, "loudness_analysis")
+		loudnorm, err := c.loudnessNormalizeFilter(job.ID, inputPath, trimArgs(options.Trim), BuildAudioFilters(&options), target)
+		if err != nil {
+			return err
 		}
+		env.Loudnorm = loudnorm
+		jobLog.Debug("two-pass loudness normalization", "filter", loudnorm)
 	}
 
-	// Apply advanced audio processing
-	if options.Advanced != nil {
-		adv := options.Advanced
+	c.startStage(job.ID, "encode")
+	c.reportProgress(job.ID, 60)
 
-		// Pitch shifting
-		if adv.PitchShift != nil && adv.PitchShift.Enabled {
-			// Convert semitones to pitch ratio (2^(semitones/12))
-			pitchRatio := math.Pow(2.0, float64(adv.PitchShift.Semitones)/12.0)
-			pitchFilter := fmt.Sprintf("asetrate=48000*%.4f,aresample=48000", pitchRatio)
-			audioFilters = append(audioFilters, pitchFilter)
-			fmt.Printf("[DEBUG] Added pitch shift: %d semitones (ratio: %.4f)\n", adv.PitchShift.Semitones, pitchRatio)
-		}
+	args := BuildAudioCommand(inputPath, outputPath, &options, env)
+	jobLog.Debug("ffmpeg command", "args", args)
 
-		// Time stretching (without pitch change)
-		if adv.TimeStretch != nil && adv.TimeStretch.Enabled {
-			factor := adv.TimeStretch.Factor
-			algorithm := adv.TimeStretch.Algorithm
-
-			switch algorithm {
-			case "pitch":
-				// Use rubberband for high-quality time stretching
-				timeStretchFilter := fmt.Sprintf("rubberband=tempo=%.2f", factor)
-				audioFilters = append(audioFilters, timeStretchFilter)
-			case "time":
-				// Use atempo for simple time stretching
-				if factor > 2.0 {
-					// Chain multiple atempo filters for extreme stretching
-					for f := factor; f > 1.0; f /= 2.0 {
-						if f >= 2.0 {
-							audioFilters = append(audioFilters, "atempo=2.0")
-						} else {
-							audioFilters = append(audioFilters, fmt.Sprintf("atempo=%.2f", f))
-						}
-					}
-				} else {
-					audioFilters = append(audioFilters, fmt.Sprintf("atempo=%.2f", factor))
-				}
-			case "formant":
-				// Formant-preserving stretch using asetrate + aresample combination
-				stretchFilter := fmt.Sprintf("asetrate=48000/%.2f,aresample=48000", factor)
-				audioFilters = append(audioFilters, stretchFilter)
-			}
-			fmt.Printf("[DEBUG] Added time stretch: factor %.2f using %s algorithm\n", factor, algorithm)
-		}
-
-		// Spatial audio processing
-		if adv.SpatialAudio != nil && adv.SpatialAudio.Enabled && adv.SpatialAudio.Type != "none" {
-			switch adv.SpatialAudio.Type {
-			case "binaural":
-				// Simple binaural processing using crossfeed
-				binauralFilter := "crossfeed=strength=0.8:range=0.5"
-				audioFilters = append(audioFilters, binauralFilter)
-			case "surround":
-				// Upmix stereo to surround using surround filter
-				surroundFilter := "surround"
-				audioFilters = append(audioFilters, surroundFilter)
-			case "3d":
-				// 3D audio processing using sofalizer (if available)
-				spatialFilter := "sofalizer=sofa=/usr/share/sofa/default.sofa"
-				audioFilters = append(audioFilters, spatialFilter)
-			}
-			fmt.Printf("[DEBUG] Added spatial audio: %s\n", adv.SpatialAudio.Type)
-		}
-	}
+	return c.runFFmpegWithProgress(job.ID, "ffmpeg", args...)
+}
 
-	// Speed adjustment (atempo for audio speed without pitch change)
-	if options.Speed != 1.0 {
-		// FFmpeg atempo filter has limitations, so handle extreme speeds
-		speed := options.Speed
-		for speed > 2.0 {
-			audioFilters = append(audioFilters, "atempo=2.0")
-			speed /= 2.0
-		}
-		for speed < 0.5 {
-			audioFilters = append(audioFilters, "atempo=0.5")
-			speed *= 2.0
-		}
-		if speed != 1.0 {
-			tempoFilter := fmt.Sprintf("atempo=%.2f", speed)
-			audioFilters = append(audioFilters, tempoFilter)
-		}
-		fmt.Printf("[DEBUG] Added speed adjustment filters for %.2fx speed\n", options.Speed)
+func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions) error {
+	if _, _, err := resolveLoudnessTarget(options.LoudnessNormalize); err != nil {
+		return err
 	}
-
-	// Apply audio filters if any exist
-	if len(audioFilters) > 0 {
-		filterChain := strings.Join(audioFilters, ",")
-		args = append(args, "-af", filterChain)
-		fmt.Printf("[DEBUG] Complete audio filter chain: %s\n", filterChain)
+	if err := validateAudioTags(options.Tags, options.Format); err != nil {
+		return err
 	}
-
-	// Audio codec based on format
-	switch options.Format {
-	case "mp3":
-		args = append(args, "-c:a", "libmp3lame")
-	case "wav":
-		args = append(args, "-c:a", "pcm_s16le")
-	case "aac":
-		args = append(args, "-c:a", "aac")
-	case "ogg":
-		args = append(args, "-c:a", "libvorbis")
-	case "flac":
-		args = append(args, "-c:a", "flac")
-	case "opus":
-		args = append(args, "-c:a", "libopus")
-	case "ac3":
-		args = append(args, "-c:a", "ac3")
+	if err := validateSampleFormat(options.SampleFormat, options.Format); err != nil {
+		return err
 	}
-
-	// Sample rate
-	args = append(args, "-ar", options.SampleRate)
-
-	// Channels
-	switch options.Channels {
-	case "mono":
-		args = append(args, "-ac", "1")
-	case "stereo":
-		args = append(args, "-ac", "2")
-	case "5.1":
-		args = append(args, "-ac", "6")
-	case "7.1":
-		args = append(args, "-ac", "8")
+	if err := validateResampler(options); err != nil {
+		return err
 	}
-
-	// Bitrate (skip for lossless formats)
-	if options.Format != "wav" && options.Format != "flac" && options.Format != "alac" {
-		args = append(args, "-b:a", options.Bitrate+"k")
+	if err := validateDTSOutput(options); err != nil {
+		return err
 	}
 
-	args = append(args, "-y", outputPath)
-
-	fmt.Printf("[DEBUG] Complete FFmpeg command: ffmpeg %s\n", strings.Join(args, " "))
-
-	return c.runFFmpegWithProgress(job.ID, "ffmpeg", args...)
-}
-
-func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions) error {
 	// Validate speed
 	if options.Speed < 0.25 || options.Speed > 4.0 {
 		return fmt.Errorf("speed must be between 0.25 and 4.0, got %.2f", options.Speed)
@@ -2009,6 +1746,9 @@ func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions)
 			return fmt.Errorf("trim duration must be at least 0.1 seconds, got %.2f", options.Trim.EndTime-options.Trim.StartTime)
 		}
 	}
+	if err := validateTrimSegments(options.TrimSegments, options.Trim); err != nil {
+		return err
+	}
 
 	// Validate basic processing if specified
 	if options.BasicProcessing != nil {
@@ -2024,12 +1764,20 @@ func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions)
 		}
 		if bp.Equalizer != nil && bp.Equalizer.Enabled {
 			validEQPresets := map[string]bool{
-				"none": true, "bass-boost": true, "treble-boost": true, "vocal": true,
+				"": true, "none": true, "bass-boost": true, "treble-boost": true, "vocal": true,
 				"classical": true, "rock": true, "jazz": true,
 			}
 			if !validEQPresets[bp.Equalizer.Preset] {
 				return fmt.Errorf("unsupported EQ preset: %s", bp.Equalizer.Preset)
 			}
+			if err := validateEqualizer(bp.Equalizer); err != nil {
+				return err
+			}
+		}
+		if bp.DynamicRange != nil && bp.DynamicRange.Enabled {
+			if err := validateDynamicRange(bp.DynamicRange); err != nil {
+				return err
+			}
 		}
 		if bp.Stereo != nil {
 			if bp.Stereo.Pan != nil && (*bp.Stereo.Pan < -100 || *bp.Stereo.Pan > 100) {
@@ -2045,12 +1793,8 @@ func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions)
 	if options.TimeBasedEffects != nil {
 		tbe := options.TimeBasedEffects
 		if tbe.Reverb != nil && tbe.Reverb.Enabled {
-			validReverbTypes := map[string]bool{"none": true, "room": true, "hall": true, "plate": true, "spring": true}
-			if !validReverbTypes[tbe.Reverb.Type] {
-				return fmt.Errorf("unsupported reverb type: %s", tbe.Reverb.Type)
-			}
-			if tbe.Reverb.RoomSize < 0 || tbe.Reverb.RoomSize > 100 {
-				return fmt.Errorf("reverb room size must be between 0 and 100, got %.2f", tbe.Reverb.RoomSize)
+			if _, err := reverbFilter(tbe.Reverb); err != nil {
+				return err
 			}
 		}
 		if tbe.Delay != nil && tbe.Delay.Enabled {
@@ -2066,9 +1810,8 @@ func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions)
 			}
 		}
 		if tbe.Modulation != nil && tbe.Modulation.Enabled {
-			validModTypes := map[string]bool{"none": true, "chorus": true, "flanger": true, "phaser": true, "tremolo": true, "vibrato": true}
-			if !validModTypes[tbe.Modulation.Type] {
-				return fmt.Errorf("unsupported modulation type: %s", tbe.Modulation.Type)
+			if _, err := modulationFilter(tbe.Modulation); err != nil {
+				return err
 			}
 		}
 	}
@@ -2112,6 +1855,11 @@ func (c *Converter) validateAudioOptions(options *models.AudioConversionOptions)
 			if !validSpatialTypes[adv.SpatialAudio.Type] {
 				return fmt.Errorf("unsupported spatial audio type: %s", adv.SpatialAudio.Type)
 			}
+			if adv.SpatialAudio.Type == "3d" {
+				if _, err := spatialPositionFilters(adv.SpatialAudio.Position); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	if err := validateAIAudioOptions(options.AI); err != nil {
@@ -2215,14 +1963,15 @@ func (c *Converter) diagnoseExitCode8Error(errorMsg, inputPath, outputPath strin
 // Helper functions
 
 func (c *Converter) runFFmpegWithProgress(jobID string, name string, args ...string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Hour)
+	ctx, cancel := c.commandContext(jobID)
 	defer cancel()
 
 	if _, err := exec.LookPath(name); err != nil {
 		return fmt.Errorf("%s is required for video and audio processing but was not found on PATH — install FFmpeg (apt install ffmpeg / brew install ffmpeg) or see https://ffmpeg.org/download.html", name)
 	}
 
-	cmd := exec.CommandContext(ctx, name, args...)
+	done := c.recordCommand(jobID, name, args)
+	cmd := limitedCommand(ctx, name, args...)
 
 	// Create pipes for both stdout and stderr to capture all output
 	stderr, err := cmd.StderrPipe()
@@ -2234,6 +1983,7 @@ func (c *Converter) runFFmpegWithProgress(jobID string, name string, args ...str
 	var stderrBuf bytes.Buffer
 
 	if err := cmd.Start(); err != nil {
+		done("", err)
 		return fmt.Errorf("failed to start FFmpeg: %v", err)
 	}
 
@@ -2272,16 +2022,16 @@ func (c *Converter) runFFmpegWithProgress(jobID string, name string, args ...str
 				progress = 100
 			}
 
-			if c.jobManager != nil {
-				c.jobManager.SendProgressUpdate(jobID, progress)
-			}
+			c.reportProgress(jobID, progress)
 		}
 	}
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	done(stderrBuf.String(), err)
+	if err != nil {
 		if ctx.Err() != nil {
-			return fmt.Errorf("FFmpeg timed out: %w", ctx.Err())
+			return fmt.Errorf("FFmpeg timed out: %w", context.Cause(ctx))
 		}
 		// Include stderr output in error for better debugging
 		stderrOutput := commandTail(stderrBuf.String(), 8000)
@@ -2295,11 +2045,12 @@ func (c *Converter) runFFmpegWithProgress(jobID string, name string, args ...str
 }
 
 func (c *Converter) runImageMagickWithProgress(jobID string, name string, args ...string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Hour)
+	ctx, cancel := c.commandContext(jobID)
 	defer cancel()
 
 	commandName, commandArgs := resolveImageMagickConvertCommand(name, args)
-	cmd := exec.CommandContext(ctx, commandName, commandArgs...)
+	done := c.recordCommand(jobID, commandName, commandArgs)
+	cmd := limitedCommand(ctx, commandName, commandArgs...)
 
 	// Create pipes for stderr to capture any error output
 	stderr, err := cmd.StderrPipe()
@@ -2311,6 +2062,7 @@ func (c *Converter) runImageMagickWithProgress(jobID string, name string, args .
 	var stderrBuf bytes.Buffer
 
 	if err := cmd.Start(); err != nil {
+		done("", err)
 		return fmt.Errorf("failed to start ImageMagick (%s): %v", commandName, err)
 	}
 
@@ -2322,9 +2074,11 @@ func (c *Converter) runImageMagickWithProgress(jobID string, name string, args .
 	}
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	done(stderrBuf.String(), err)
+	if err != nil {
 		if ctx.Err() != nil {
-			return fmt.Errorf("ImageMagick timed out: %w", ctx.Err())
+			return fmt.Errorf("ImageMagick timed out: %w", context.Cause(ctx))
 		}
 		// Include stderr output in error for better debugging
 		stderrOutput := commandTail(stderrBuf.String(), 8000)
@@ -2367,7 +2121,7 @@ func (c *Converter) runImageAI(ctx context.Context, job *models.ConversionJob, o
 		return fmt.Errorf("AI service not available")
 	}
 	op := strings.ToLower(strings.TrimSpace(options.AI.Operation))
-	fmt.Printf("[DEBUG] Routing image job %s to AI op %s\n", job.ID, op)
+	c.jobLogger(job.ID).Debug("routing to AI", "op", op)
 	switch op {
 	case AIImageOpFacePrivacy:
 		selectionPath, cleanup, err := c.prepareFaceSelection(options.AI, inputPath)
@@ -2400,7 +2154,7 @@ func (c *Converter) runAudioAI(ctx context.Context, job *models.ConversionJob, o
 		return fmt.Errorf("AI service not available")
 	}
 	op := strings.ToLower(strings.TrimSpace(options.AI.Operation))
-	fmt.Printf("[DEBUG] Routing audio job %s to AI op %s\n", job.ID, op)
+	c.jobLogger(job.ID).Debug("routing to AI", "op", op)
 	switch op {
 	case AIAudioOpCleanVoice:
 		return c.ai.CleanVoice(ctx, job.ID, inputPath, outputPath, true)
@@ -2603,7 +2357,7 @@ func (c *Converter) runVideoAI(ctx context.Context, job *models.ConversionJob, o
 		return fmt.Errorf("AI service not available")
 	}
 	op := strings.ToLower(strings.TrimSpace(options.AI.Operation))
-	fmt.Printf("[DEBUG] Routing video job %s to AI op %s\n", job.ID, op)
+	c.jobLogger(job.ID).Debug("routing to AI", "op", op)
 	switch op {
 	case AIVideoOpFrameInterpolation:
 		fi := models.AIFrameInterpolationOptions{}
@@ -2664,7 +2418,7 @@ func validateAIVideoOptions(options *models.VideoConversionOptions) error {
 		if options.Temporal != nil && options.Temporal.FrameRate != nil && options.Temporal.FrameRate.Target != nil {
 			return fmt.Errorf("AI frame interpolation owns the output FPS — remove the temporal frame rate override")
 		}
-		if options.Trim != nil {
+		if options.Trim != nil || len(options.TrimSegments) > 0 {
 			return fmt.Errorf("AI frame interpolation does not support trim in v1 — trim the video first, then run interpolation")
 		}
 		return nil
@@ -3,6 +3,8 @@ package services
 import (
 	"strings"
 	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
 )
 
 // countFlag returns how many times an exact flag token appears in args.
@@ -212,3 +214,128 @@ func TestBuildVideoCodecArgs_NoDuplicateFlagsWithOverrides(t *testing.T) {
 		t.Errorf("preset = %q, want slow", valueAfter(args, "-preset"))
 	}
 }
+
+func TestBuildVideoCodecArgs_FragmentedMP4(t *testing.T) {
+	args := buildVideoCodecArgs(videoEncodeSettings{Format: "mp4", Quality: "medium", MovFlags: "fragmented"})
+	if got := valueAfter(args, "-movflags"); got != "+frag_keyframe+empty_moov+default_base_moof" {
+		t.Errorf("fragmented movflags = %q", got)
+	}
+	if countFlag(args, "-movflags") != 1 {
+		t.Errorf("expected exactly one -movflags, got %v", args)
+	}
+	// Non-MP4 containers never carry movflags, whatever was requested.
+	mkv := buildVideoCodecArgs(videoEncodeSettings{Format: "mkv", Quality: "medium", MovFlags: "fragmented"})
+	if countFlag(mkv, "-movflags") != 0 {
+		t.Errorf("mkv should not carry -movflags, got %v", mkv)
+	}
+}
+
+func TestBuildVideoCodecArgs_GOPControls(t *testing.T) {
+	interval := 2.0
+	off := false
+	args := buildVideoCodecArgs(videoEncodeSettings{
+		Format: "mp4", Quality: "medium",
+		KeyframeInterval: &interval, BFrames: intPtr(0), SceneCut: &off,
+	})
+	if got := valueAfter(args, "-force_key_frames"); got != "expr:gte(t,n_forced*2)" {
+		t.Errorf("force_key_frames = %q", got)
+	}
+	if got := valueAfter(args, "-bf"); got != "0" {
+		t.Errorf("-bf = %q", got)
+	}
+	if got := valueAfter(args, "-sc_threshold"); got != "0" {
+		t.Errorf("-sc_threshold = %q", got)
+	}
+
+	hevc := buildVideoCodecArgs(videoEncodeSettings{Format: "mkv", Codec: "h265", SceneCut: &off})
+	if got := valueAfter(hevc, "-x265-params"); got != "scenecut=0" {
+		t.Errorf("x265 scenecut = %q", got)
+	}
+
+	// VP9 has no B-frames flag and no scene-cut switch; only the keyframe
+	// interval carries over.
+	vp9 := buildVideoCodecArgs(videoEncodeSettings{Format: "webm", WebMVP9: true, KeyframeInterval: &interval, BFrames: intPtr(3), SceneCut: &off})
+	if countFlag(vp9, "-bf") != 0 || countFlag(vp9, "-sc_threshold") != 0 || countFlag(vp9, "-force_key_frames") != 1 {
+		t.Errorf("unexpected vp9 GOP args: %v", vp9)
+	}
+
+	// Intra-only editing codecs ignore GOP settings entirely.
+	prores := buildVideoCodecArgs(videoEncodeSettings{Format: "prores", KeyframeInterval: &interval})
+	if countFlag(prores, "-force_key_frames") != 0 {
+		t.Errorf("prores should ignore keyframe interval: %v", prores)
+	}
+}
+
+func TestVideoAspectPadFilter(t *testing.T) {
+	f, err := videoAspectPadFilter("9:16", "#112233")
+	if err != nil {
+		t.Fatalf("videoAspectPadFilter: %v", err)
+	}
+	want := `pad=w=ceil(max(iw\,ih*9/16)/2)*2:h=ceil(max(ih\,iw*16/9)/2)*2:x=(ow-iw)/2:y=(oh-ih)/2:color=0x112233`
+	if f != want {
+		t.Errorf("filter =\n%s\nwant\n%s", f, want)
+	}
+	if _, err := videoAspectPadFilter("2:1", ""); err == nil {
+		t.Error("expected rejection of unsupported ratio")
+	}
+	if _, err := videoAspectPadFilter("1:1", "red"); err == nil {
+		t.Error("expected rejection of non-hex color")
+	}
+}
+
+func TestVideoPaddingFilter(t *testing.T) {
+	f := videoPaddingFilter(&models.Padding{Top: 10, Bottom: 11, Left: 4, Right: 6})
+	want := "pad=ceil((iw+10)/2)*2:ceil((ih+21)/2)*2:4:10:color=black"
+	if f != want {
+		t.Errorf("filter = %q, want %q", f, want)
+	}
+}
+
+func TestCleanupFilters(t *testing.T) {
+	strong, none, upper := "strong", "none", "Light"
+	if got := cleanupFilter(deblockLevels, &strong); !strings.HasPrefix(got, "deblock=filter=strong") {
+		t.Errorf("strong deblock = %q", got)
+	}
+	if got := cleanupFilter(debandLevels, &upper); !strings.HasPrefix(got, "deband=") {
+		t.Errorf("level names should be case-insensitive, got %q", got)
+	}
+	if got := cleanupFilter(debandLevels, &none); got != "" {
+		t.Errorf("none should disable the filter, got %q", got)
+	}
+	if got := cleanupFilter(debandLevels, nil); got != "" {
+		t.Errorf("nil should disable the filter, got %q", got)
+	}
+
+	c := &Converter{}
+	bad := "extreme"
+	err := c.validateVideoOptions(&models.VideoConversionOptions{Format: "mp4", Quality: "medium", Speed: 1, VisualEffects: &models.VisualEffects{Deband: &bad}})
+	if err == nil || !strings.Contains(err.Error(), "deband") {
+		t.Errorf("expected a deband strength error, got %v", err)
+	}
+}
+
+func TestDetelecineFilter(t *testing.T) {
+	if got := detelecineFilter(&models.AdvancedProcessing{Detelecine: "fieldmatch"}); !strings.HasSuffix(got, ",decimate") || !strings.HasPrefix(got, "fieldmatch") {
+		t.Errorf("fieldmatch chain = %q", got)
+	}
+	if got := detelecineFilter(&models.AdvancedProcessing{Detelecine: "pullup"}); got != "pullup,fps=24000/1001" {
+		t.Errorf("pullup chain = %q", got)
+	}
+	if got := detelecineFilter(nil); got != "" {
+		t.Errorf("nil advanced options should add nothing, got %q", got)
+	}
+
+	yes := true
+	bad := []*models.AdvancedProcessing{
+		{Detelecine: "ivtc"},
+		{Detelecine: "pullup", Deinterlace: &yes},
+	}
+	for _, adv := range bad {
+		if err := validateDetelecine(adv, "mp4"); err == nil {
+			t.Errorf("%+v: expected an error", adv)
+		}
+	}
+	if err := validateDetelecine(&models.AdvancedProcessing{Detelecine: "fieldmatch"}, "gif"); err == nil {
+		t.Error("detelecine should be rejected for GIF output")
+	}
+}
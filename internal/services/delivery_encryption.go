@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// End-to-end encrypted delivery: the client supplies an age recipient, an
+// OpenPGP public key or a passphrase with the upload, and the converted file
+// is encrypted with it before the job completes. Only the ciphertext is kept,
+// so the download link can be passed around without exposing the media.
+
+const (
+	DeliveryEncryptionAge        = "age"
+	DeliveryEncryptionGPG        = "gpg"
+	DeliveryEncryptionPassphrase = "passphrase"
+
+	deliveryEncryptionOption   = "deliveryEncryption"
+	deliveryMaxPublicKeyBytes  = 64 * 1024
+	deliveryMinPassphraseChars = 12
+)
+
+var ageRecipientPattern = regexp.MustCompile(`^age1[02-9ac-hj-np-z]{58}$`)
+
+// DeliveryEncryption is the client's choice of key for an encrypted
+// delivery. It carries secrets, so it never goes into job options.
+type DeliveryEncryption struct {
+	// Method: age (Recipient), gpg (PublicKey) or passphrase (Passphrase,
+	// encrypted with gpg --symmetric / AES-256).
+	Method string `json:"method"`
+	// Recipient is an age public key (age1...) or an SSH ed25519/RSA key.
+	Recipient string `json:"recipient,omitempty"`
+	// PublicKey is an ASCII-armored OpenPGP public key.
+	PublicKey  string `json:"publicKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// ParseDeliveryEncryption reads and validates options["deliveryEncryption"].
+// It returns nil when the option is absent.
+func ParseDeliveryEncryption(options map[string]interface{}) (*DeliveryEncryption, error) {
+	raw, ok := options[deliveryEncryptionOption].(map[string]interface{})
+	if !ok {
+		if options[deliveryEncryptionOption] != nil {
+			return nil, errors.New("deliveryEncryption must be an object")
+		}
+		return nil, nil
+	}
+	str := func(key string) string { v, _ := raw[key].(string); return strings.TrimSpace(v) }
+	d := &DeliveryEncryption{
+		Method:    strings.ToLower(str("method")),
+		Recipient: str("recipient"),
+		PublicKey: str("publicKey"),
+	}
+	// Passphrases are taken verbatim; surrounding spaces may be intended.
+	d.Passphrase, _ = raw["passphrase"].(string)
+	switch d.Method {
+	case DeliveryEncryptionAge:
+		if !ageRecipientPattern.MatchString(d.Recipient) &&
+			!strings.HasPrefix(d.Recipient, "ssh-ed25519 ") && !strings.HasPrefix(d.Recipient, "ssh-rsa ") {
+			return nil, errors.New("deliveryEncryption.recipient must be an age (age1...) or SSH ed25519/RSA public key")
+		}
+	case DeliveryEncryptionGPG:
+		if len(d.PublicKey) > deliveryMaxPublicKeyBytes || !strings.Contains(d.PublicKey, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+			return nil, errors.New("deliveryEncryption.publicKey must be an ASCII-armored OpenPGP public key")
+		}
+	case DeliveryEncryptionPassphrase:
+		if len([]rune(d.Passphrase)) < deliveryMinPassphraseChars || len(d.Passphrase) > 1024 || strings.ContainsAny(d.Passphrase, "\r\n") {
+			return nil, fmt.Errorf("deliveryEncryption.passphrase must be %d-1024 characters on one line", deliveryMinPassphraseChars)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported deliveryEncryption.method: %q (expected age|gpg|passphrase)", d.Method)
+	}
+	return d, nil
+}
+
+// Redacted is the form kept in job options: just the method, which is all
+// the download path needs to name the file.
+func (d *DeliveryEncryption) Redacted() map[string]interface{} {
+	return map[string]interface{}{"method": d.Method}
+}
+
+// Suffix is the extension appended to the delivered file.
+func (d *DeliveryEncryption) Suffix() string {
+	if d.Method == DeliveryEncryptionAge {
+		return ".age"
+	}
+	return ".gpg"
+}
+
+// DeliveryEncryptionSuffix returns the encrypted-file extension recorded in
+// job options, or "" for a plaintext delivery.
+func DeliveryEncryptionSuffix(options map[string]interface{}) string {
+	raw, _ := options[deliveryEncryptionOption].(map[string]interface{})
+	method, _ := raw["method"].(string)
+	switch method {
+	case DeliveryEncryptionAge, DeliveryEncryptionGPG, DeliveryEncryptionPassphrase:
+		return (&DeliveryEncryption{Method: method}).Suffix()
+	}
+	return ""
+}
+
+// EncryptForDelivery encrypts src into dst with the client's key.
+func EncryptForDelivery(ctx context.Context, d *DeliveryEncryption, src, dst string) error {
+	if d.Method == DeliveryEncryptionAge {
+		if _, err := exec.LookPath("age"); err != nil {
+			return errors.New("age not found in PATH")
+		}
+		if _, stderr, err := runCommand(ctx, "age", "--encrypt", "--recipient", d.Recipient, "--output", dst, src); err != nil {
+			return fmt.Errorf("age encryption failed: %w (%s)", err, commandTail(stderr, 500))
+		}
+		return requireNonEmpty(dst)
+	}
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return errors.New("gpg not found in PATH")
+	}
+	// A throwaway keyring per job: nothing the client sends is imported into
+	// a shared one, and trust decisions can't leak between jobs.
+	home, err := os.MkdirTemp("", "delivery-gpg-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+	args := []string{"--batch", "--no-tty", "--yes", "--homedir", home, "--output", dst}
+	cmd := exec.CommandContext(ctx, "gpg")
+	if d.Method == DeliveryEncryptionGPG {
+		keyFile := filepath.Join(home, "recipient.asc")
+		if err := os.WriteFile(keyFile, []byte(d.PublicKey), 0o600); err != nil {
+			return err
+		}
+		args = append(args, "--trust-model", "always", "--recipient-file", keyFile, "--encrypt", src)
+	} else {
+		// The passphrase goes over stdin so it never shows up in ps output
+		// or the command audit log.
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0",
+			"--symmetric", "--cipher-algo", "AES256", src)
+		cmd.Stdin = strings.NewReader(d.Passphrase + "\n")
+	}
+	cmd.Args = append(cmd.Args, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("gpg encryption failed: %w (%s)", err, commandTail(stderr.String(), 500))
+	}
+	return requireNonEmpty(dst)
+}
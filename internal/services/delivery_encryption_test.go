@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDeliveryEncryption(t *testing.T) {
+	if d, err := ParseDeliveryEncryption(map[string]interface{}{"format": "mp4"}); d != nil || err != nil {
+		t.Fatalf("absent option: got %v, %v", d, err)
+	}
+	valid := []map[string]interface{}{
+		{"method": "age", "recipient": "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+		{"method": "age", "recipient": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample user@host"},
+		{"method": "gpg", "publicKey": "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQINBF...\n-----END PGP PUBLIC KEY BLOCK-----"},
+		{"method": "Passphrase", "passphrase": "correct horse battery"},
+	}
+	for _, raw := range valid {
+		if _, err := ParseDeliveryEncryption(map[string]interface{}{"deliveryEncryption": raw}); err != nil {
+			t.Errorf("%v: unexpected error %v", raw, err)
+		}
+	}
+	invalid := []interface{}{
+		"age",
+		map[string]interface{}{"method": "zip"},
+		map[string]interface{}{"method": "age", "recipient": "age1short"},
+		map[string]interface{}{"method": "gpg", "publicKey": "not a key"},
+		map[string]interface{}{"method": "passphrase", "passphrase": "short"},
+		map[string]interface{}{"method": "passphrase", "passphrase": "two lines\nof passphrase"},
+	}
+	for _, raw := range invalid {
+		if _, err := ParseDeliveryEncryption(map[string]interface{}{"deliveryEncryption": raw}); err == nil {
+			t.Errorf("%v: expected an error", raw)
+		}
+	}
+}
+
+func TestDeliveryEncryptionRedactedAndSuffix(t *testing.T) {
+	d, err := ParseDeliveryEncryption(map[string]interface{}{"deliveryEncryption": map[string]interface{}{
+		"method": "passphrase", "passphrase": "correct horse battery",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]interface{}{"deliveryEncryption": d.Redacted()}
+	if strings.Contains(strings.ToLower(strings.Join(keys(d.Redacted()), ",")), "passphrase") {
+		t.Errorf("redacted options leak the passphrase: %v", d.Redacted())
+	}
+	if got := DeliveryEncryptionSuffix(options); got != ".gpg" {
+		t.Errorf("suffix = %q, want .gpg", got)
+	}
+	if got := DeliveryEncryptionSuffix(map[string]interface{}{"deliveryEncryption": map[string]interface{}{"method": "age"}}); got != ".age" {
+		t.Errorf("age suffix = %q", got)
+	}
+	if got := DeliveryEncryptionSuffix(map[string]interface{}{}); got != "" {
+		t.Errorf("plain delivery suffix = %q", got)
+	}
+}
+
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestEncryptForDeliveryPassphraseRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "converted.mp4")
+	if err := os.WriteFile(src, []byte("media bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := &DeliveryEncryption{Method: DeliveryEncryptionPassphrase, Passphrase: "correct horse battery"}
+	dst := src + d.Suffix()
+	if err := EncryptForDelivery(context.Background(), d, src, dst); err != nil {
+		t.Fatalf("EncryptForDelivery: %v", err)
+	}
+	home := t.TempDir()
+	cmd := exec.Command("gpg", "--batch", "--homedir", home, "--pinentry-mode", "loopback",
+		"--passphrase-fd", "0", "--decrypt", dst)
+	cmd.Stdin = strings.NewReader(d.Passphrase + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("gpg --decrypt: %v", err)
+	}
+	if string(out) != "media bytes" {
+		t.Errorf("decrypted = %q", out)
+	}
+}
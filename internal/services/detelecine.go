@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// detelecineRate is the film rate inverse telecine restores from 29.97i.
+const detelecineRate = "24000/1001"
+
+// detelecineFilter returns the inverse-telecine chain, "" when not requested.
+// It has to see the decoded fields untouched, so it leads the filter chain.
+//   - fieldmatch rebuilds progressive frames from matching fields, yadif
+//     cleans up the odd frame left combed (deint=interlaced only touches
+//     frames fieldmatch flagged), and decimate drops the duplicate in every
+//     five.
+//   - pullup does its own matching and dropping but leaves a variable frame
+//     rate, so fps pins the output back to 23.976.
+func detelecineFilter(adv *models.AdvancedProcessing) string {
+	if adv == nil {
+		return ""
+	}
+	switch strings.ToLower(strings.TrimSpace(adv.Detelecine)) {
+	case "fieldmatch":
+		return "fieldmatch=order=auto:combmatch=full,yadif=deint=interlaced,decimate"
+	case "pullup":
+		return "pullup,fps=" + detelecineRate
+	}
+	return ""
+}
+
+func validateDetelecine(adv *models.AdvancedProcessing, format string) error {
+	if adv == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(adv.Detelecine)) {
+	case "":
+		return nil
+	case "fieldmatch", "pullup":
+	default:
+		return fmt.Errorf("unsupported detelecine method: %s (expected fieldmatch|pullup)", adv.Detelecine)
+	}
+	if strings.EqualFold(format, "gif") {
+		return fmt.Errorf("detelecine is not supported for GIF output")
+	}
+	if adv.Deinterlace != nil && *adv.Deinterlace {
+		return fmt.Errorf("detelecine and deinterlace are mutually exclusive; telecined film needs detelecine")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// The diagnostic log is written for every standard conversion, successful
+// or not, so a user reporting a bad result can attach the exact commands
+// the converter ran and what they printed. Server paths are replaced the
+// same way as in conversion reports.
+
+const DiagnosticLogFile = "diagnostics.json"
+
+// DiagnosticLog is the document written to diagnostics.json.
+type DiagnosticLog struct {
+	JobID       string              `json:"jobId"`
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Status      models.JobStatus    `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	Attempts    []DiagnosticAttempt `json:"attempts"`
+}
+
+// DiagnosticAttempt is one attempt at the job, numbered as in its Attempts.
+type DiagnosticAttempt struct {
+	Number   int                 `json:"number"`
+	Commands []DiagnosticCommand `json:"commands"`
+}
+
+// DiagnosticCommand is one command line with how it ended.
+type DiagnosticCommand struct {
+	Command    string    `json:"command"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	Stderr     string    `json:"stderr,omitempty"`
+}
+
+// RecordedAttempt is the commands of one attempt, numbered as in the job's
+// Attempts.
+type RecordedAttempt struct {
+	Number   int
+	Commands []RecordedCommand
+}
+
+// DiagnosticLogInput gathers what BuildDiagnosticLog needs.
+type DiagnosticLogInput struct {
+	Job        *models.ConversionJob
+	InputPath  string
+	OutputPath string
+	// Attempts are the attempts of this run, oldest first.
+	Attempts []RecordedAttempt
+	// Err is how the conversion ended.
+	Err error
+	// Redact is as for ConversionReportInput.
+	Redact map[string]string
+}
+
+// BuildDiagnosticLog assembles the diagnostic log of a finished conversion.
+func BuildDiagnosticLog(in DiagnosticLogInput) *DiagnosticLog {
+	replacements := map[string]string{in.InputPath: "<input>", in.OutputPath: "<output>"}
+	for k, v := range in.Redact {
+		replacements[k] = v
+	}
+	redact := reportRedactor(replacements)
+
+	log := &DiagnosticLog{
+		JobID:       in.Job.ID,
+		GeneratedAt: time.Now().UTC(),
+		Status:      models.StatusCompleted,
+		Attempts:    make([]DiagnosticAttempt, 0, len(in.Attempts)),
+	}
+	if in.Err != nil {
+		log.Status = models.StatusFailed
+		log.Error = redact(in.Err.Error())
+	}
+	for _, recorded := range in.Attempts {
+		attempt := DiagnosticAttempt{Number: recorded.Number, Commands: make([]DiagnosticCommand, 0, len(recorded.Commands))}
+		for _, cmd := range recorded.Commands {
+			attempt.Commands = append(attempt.Commands, DiagnosticCommand{
+				Command:    redact(formatCommandLine(cmd)),
+				StartedAt:  cmd.Started.UTC(),
+				DurationMs: cmd.Duration.Milliseconds(),
+				Error:      redact(cmd.Err),
+				Stderr:     redact(cmd.Stderr),
+			})
+		}
+		log.Attempts = append(log.Attempts, attempt)
+	}
+	return log
+}
+
+// WriteDiagnosticLog writes diagnostics.json into dir.
+func WriteDiagnosticLog(dir string, log *DiagnosticLog) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode diagnostic log: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DiagnosticLogFile), data, 0o644); err != nil {
+		return fmt.Errorf("write diagnostic log: %w", err)
+	}
+	return nil
+}
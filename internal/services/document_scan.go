@@ -48,6 +48,7 @@ type DocumentScanService struct {
 func NewDocumentScanService(cfg *config.Config, jm *JobManager, gpuMgr *gpu.Manager, store *telemetry.Store, runner *cmdaudit.Runner) *DocumentScanService {
 	if runner == nil {
 		runner = cmdaudit.NewRunner(nil, nil)
+		runner.Command = limitedCommand
 	}
 	n := cfg.DocumentScanMaxConcurrentJobs
 	if n <= 0 {
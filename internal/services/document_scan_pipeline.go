@@ -74,7 +74,7 @@ var documentScanNotes = []string{
 }
 
 // Process runs one document-scan job end to end. Launched as a goroutine with
-// context.Background(); the whole job runs under cfg.CommandTimeout, with a
+// the job's JobContext; the whole job runs under cfg.CommandTimeout, with a
 // tighter DocumentScanModelTimeout per wrapper stage.
 func (s *DocumentScanService) Process(ctx context.Context, req DocumentScanRequest) {
 	ctx, cancel := context.WithTimeout(ctx, s.cfg.CommandTimeout)
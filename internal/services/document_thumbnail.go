@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// DocumentThumbnailService renders a first-page preview image for a document,
+// the shape a file-manager preview pipeline wants. PDFs go straight to
+// poppler's pdftoppm; office formats (docx, pptx, xlsx, odt, ...) are first
+// converted to PDF by LibreOffice in headless mode, which is opt-in because
+// it is a heavy dependency and a large attack surface.
+type DocumentThumbnailService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewDocumentThumbnailService(cfg *config.Config, jm *JobManager) *DocumentThumbnailService {
+	return &DocumentThumbnailService{cfg: cfg, jobManager: jm}
+}
+
+const (
+	documentThumbnailDefaultSize = 256
+	documentThumbnailMinSize     = 32
+	documentThumbnailMaxSize     = 2048
+)
+
+// documentOfficeExtensions are the formats routed through LibreOffice.
+var documentOfficeExtensions = map[string]bool{
+	".doc": true, ".docx": true, ".odt": true, ".rtf": true,
+	".ppt": true, ".pptx": true, ".odp": true,
+	".xls": true, ".xlsx": true, ".ods": true,
+}
+
+// DocumentThumbnailOptions controls the rendered preview.
+type DocumentThumbnailOptions struct {
+	// Size is the long edge of the thumbnail in pixels. Default 256.
+	Size int `json:"size"`
+	// Format: png (default) or jpg.
+	Format string `json:"format"`
+}
+
+func (o *DocumentThumbnailOptions) applyDefaults() error {
+	if o.Size == 0 {
+		o.Size = documentThumbnailDefaultSize
+	}
+	if o.Size < documentThumbnailMinSize || o.Size > documentThumbnailMaxSize {
+		return fmt.Errorf("size must be between %d and %d pixels", documentThumbnailMinSize, documentThumbnailMaxSize)
+	}
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	switch o.Format {
+	case "":
+		o.Format = "png"
+	case "jpeg":
+		o.Format = "jpg"
+	case "png", "jpg":
+	default:
+		return fmt.Errorf("unsupported thumbnail format: %s (expected png|jpg)", o.Format)
+	}
+	return nil
+}
+
+// ValidateDocumentThumbnailRequest checks the upload's type against what this
+// deployment can render and normalizes opts in place.
+func ValidateDocumentThumbnailRequest(filename string, opts *DocumentThumbnailOptions, officeEnabled bool) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".pdf":
+	case documentOfficeExtensions[ext]:
+		if !officeEnabled {
+			return fmt.Errorf("%s thumbnails are not enabled on this server (PDF only)", ext)
+		}
+	default:
+		return fmt.Errorf("unsupported document type: %q", ext)
+	}
+	return opts.applyDefaults()
+}
+
+// buildDocumentThumbnailArgs renders page 1 only, scaled so its long edge is
+// Size pixels, to <outputPrefix>.<png|jpg>.
+func buildDocumentThumbnailArgs(pdfPath, outputPrefix string, opts DocumentThumbnailOptions) []string {
+	args := []string{"-f", "1", "-l", "1", "-singlefile", "-scale-to", strconv.Itoa(opts.Size)}
+	if opts.Format == "jpg" {
+		args = append(args, "-jpeg", "-jpegopt", "quality=85")
+	} else {
+		args = append(args, "-png")
+	}
+	return append(args, pdfPath, outputPrefix)
+}
+
+// buildOfficeToPDFArgs converts one office file to PDF in outDir. Each run
+// gets its own LibreOffice profile: concurrent soffice processes sharing the
+// default profile block on its lock file.
+func buildOfficeToPDFArgs(inputPath, outDir, profileDir string) []string {
+	return []string{
+		"-env:UserInstallation=file://" + filepath.ToSlash(profileDir),
+		"--headless", "--norestore", "--nolockcheck",
+		"--convert-to", "pdf",
+		"--outdir", outDir,
+		inputPath,
+	}
+}
+
+// Render writes the first-page thumbnail of inputPath to outputPath.
+func (s *DocumentThumbnailService) Render(ctx context.Context, job *models.ConversionJob, inputPath, outputPath string, opts DocumentThumbnailOptions) error {
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return errors.New("document thumbnails are unavailable: pdftoppm (poppler-utils) is not installed")
+	}
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "doc-thumb-")
+	if err != nil {
+		return fmt.Errorf("create thumbnail workdir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	s.progress(job.ID, 10)
+
+	pdfPath := inputPath
+	if ext := strings.ToLower(filepath.Ext(inputPath)); documentOfficeExtensions[ext] {
+		if !s.cfg.DocumentThumbnailOfficeEnabled {
+			return fmt.Errorf("%s thumbnails are not enabled on this server", ext)
+		}
+		soffice := s.cfg.LibreOfficePath
+		if _, err := exec.LookPath(soffice); err != nil {
+			return fmt.Errorf("office thumbnails are unavailable: %s (LibreOffice) is not installed", soffice)
+		}
+		args := buildOfficeToPDFArgs(inputPath, workDir, filepath.Join(workDir, "lo-profile"))
+		if _, stderr, err := runCommand(ctx, soffice, args...); err != nil {
+			return fmt.Errorf("office to PDF conversion failed: %w (%s)", err, commandTail(stderr, 800))
+		}
+		pdfPath = filepath.Join(workDir, strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))+".pdf")
+		if err := requireNonEmpty(pdfPath); err != nil {
+			return fmt.Errorf("office to PDF conversion produced no PDF: %w", err)
+		}
+		s.progress(job.ID, 60)
+	}
+
+	prefix := filepath.Join(workDir, "thumb")
+	if _, stderr, err := runCommand(ctx, "pdftoppm", buildDocumentThumbnailArgs(pdfPath, prefix, opts)...); err != nil {
+		return fmt.Errorf("pdftoppm failed: %w (%s)", err, tail(stderr, 1500))
+	}
+	rendered := prefix + "." + opts.Format
+	if err := requireNonEmpty(rendered); err != nil {
+		return fmt.Errorf("no thumbnail was rendered: %w", err)
+	}
+	if err := os.Rename(rendered, outputPath); err != nil {
+		return fmt.Errorf("finalize thumbnail: %w", err)
+	}
+	s.progress(job.ID, 95)
+	return nil
+}
+
+func (s *DocumentThumbnailService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDocumentThumbnailRequest(t *testing.T) {
+	opts := DocumentThumbnailOptions{}
+	if err := ValidateDocumentThumbnailRequest("report.PDF", &opts, false); err != nil {
+		t.Fatalf("pdf rejected: %v", err)
+	}
+	if opts.Size != 256 || opts.Format != "png" {
+		t.Fatalf("defaults not applied: %+v", opts)
+	}
+
+	opts = DocumentThumbnailOptions{Format: "jpeg"}
+	if err := ValidateDocumentThumbnailRequest("deck.pptx", &opts, false); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatalf("office file accepted without LibreOffice support: %v", err)
+	}
+	if err := ValidateDocumentThumbnailRequest("deck.pptx", &opts, true); err != nil {
+		t.Fatalf("office file rejected with support enabled: %v", err)
+	}
+	if opts.Format != "jpg" {
+		t.Fatalf("jpeg not normalized: %q", opts.Format)
+	}
+
+	for name, o := range map[string]DocumentThumbnailOptions{
+		"photo.png": {},
+		"a.pdf":     {Size: 4096},
+		"b.pdf":     {Format: "gif"},
+	} {
+		o := o
+		if err := ValidateDocumentThumbnailRequest(name, &o, true); err == nil {
+			t.Errorf("%s %+v: expected error", name, o)
+		}
+	}
+}
+
+func TestBuildDocumentThumbnailArgs(t *testing.T) {
+	args := buildDocumentThumbnailArgs("/in.pdf", "/work/thumb", DocumentThumbnailOptions{Size: 512, Format: "jpg"})
+	if valueAfter(args, "-f") != "1" || valueAfter(args, "-l") != "1" || countFlag(args, "-singlefile") != 1 {
+		t.Fatalf("expected first page only: %v", args)
+	}
+	if valueAfter(args, "-scale-to") != "512" || countFlag(args, "-jpeg") != 1 {
+		t.Fatalf("unexpected size/format args: %v", args)
+	}
+	if args[len(args)-2] != "/in.pdf" || args[len(args)-1] != "/work/thumb" {
+		t.Fatalf("input/prefix must be last: %v", args)
+	}
+
+	lo := buildOfficeToPDFArgs("/in/deck.pptx", "/work", "/work/lo-profile")
+	if lo[0] != "-env:UserInstallation=file:///work/lo-profile" || valueAfter(lo, "--convert-to") != "pdf" || valueAfter(lo, "--outdir") != "/work" {
+		t.Fatalf("unexpected soffice args: %v", lo)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// blurFaces obscures every detected face before the rest of the pipeline
+// runs, using the face_privacy script's YuNet detector. Images go through
+// one detect-and-redact pass over a PNG rendering. Video is re-encoded frame
+// by frame into a near-lossless intermediate that the normal encode then
+// starts from. The face_privacy AI op does the same for images but replaces
+// the pipeline and lets the user pick faces; blurFaces is for publishing
+// flows that want every face gone.
+
+// validateFaceBlur checks the redaction mode.
+func validateFaceBlur(enabled bool, mode string) error {
+	if mode != "" && !validFaceModes[mode] {
+		return fmt.Errorf("unsupported faceBlurMode: %s (expected blur, pixelate or blackbox)", mode)
+	}
+	if mode != "" && !enabled {
+		return fmt.Errorf("faceBlurMode needs blurFaces")
+	}
+	return nil
+}
+
+// validateImageFaceBlur checks blurFaces against the other image options.
+func validateImageFaceBlur(options *models.ImageConversionOptions) error {
+	if err := validateFaceBlur(options.BlurFaces, options.FaceBlurMode); err != nil {
+		return err
+	}
+	if !options.BlurFaces {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(options.Format)) {
+	case "pdf", "svg", "ico":
+		return fmt.Errorf("blurFaces does not apply to %s output", options.Format)
+	}
+	if options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
+		return fmt.Errorf("blurFaces cannot be combined with AI image operations")
+	}
+	return nil
+}
+
+// faceDetectionAvailable reports whether the face_privacy script is
+// installed.
+func (c *Converter) faceDetectionAvailable() bool {
+	return c.ai != nil && statOK(c.cfg.FacePrivacyScript)
+}
+
+// blurImageFaces renders inputPath the way the pipeline will see it and
+// redacts the faces in it, returning the PNG to convert from. OpenCV reads
+// three channels, so transparency is flattened onto white first.
+func (c *Converter) blurImageFaces(ctx context.Context, jobID, inputPath, workDir string, options *models.ImageConversionOptions) (string, error) {
+	source := filepath.Join(workDir, "faces_source.png")
+	args := []string{inputPath + "[0]"}
+	if options.AutoOrient == nil || *options.AutoOrient {
+		args = append(args, "-auto-orient")
+	}
+	name, args := resolveImageMagickConvertCommand("convert", append(args, "-background", "white", "-alpha", "remove", "PNG:"+source))
+	if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+		return "", fmt.Errorf("face blur source render failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	blurred := filepath.Join(workDir, "faces_blurred.png")
+	if err := c.ai.FacePrivacy(ctx, jobID, source, blurred, options.FaceBlurMode, ""); err != nil {
+		return "", err
+	}
+	return blurred, nil
+}
+
+// blurVideoFaces redacts the faces in every frame of inputPath and returns
+// the intermediate to encode from. Audio is copied; other streams are
+// dropped.
+func (c *Converter) blurVideoFaces(ctx context.Context, jobID, inputPath, workDir, mode string) (string, error) {
+	blurred := filepath.Join(workDir, "faces_blurred.mkv")
+	if err := c.ai.BlurVideoFaces(ctx, jobID, inputPath, blurred, mode); err != nil {
+		return "", err
+	}
+	return blurred, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageFaceBlur(t *testing.T) {
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr bool
+	}{
+		{name: "off", options: models.ImageConversionOptions{Format: "jpg"}},
+		{name: "default mode", options: models.ImageConversionOptions{Format: "jpg", BlurFaces: true}},
+		{name: "pixelate", options: models.ImageConversionOptions{Format: "png", BlurFaces: true, FaceBlurMode: "pixelate"}},
+		{name: "unknown mode", options: models.ImageConversionOptions{Format: "png", BlurFaces: true, FaceBlurMode: "emoji"}, wantErr: true},
+		{name: "mode without blurFaces", options: models.ImageConversionOptions{Format: "png", FaceBlurMode: "blur"}, wantErr: true},
+		{name: "svg output", options: models.ImageConversionOptions{Format: "svg", BlurFaces: true}, wantErr: true},
+		{name: "with AI op", options: models.ImageConversionOptions{Format: "png", BlurFaces: true, AI: &models.AIImageOptions{Enabled: true, Operation: "face_privacy"}}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateImageFaceBlur(&tc.options); (err != nil) != tc.wantErr {
+				t.Fatalf("validateImageFaceBlur error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
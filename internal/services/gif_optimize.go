@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// validateGIFOptimize checks the GIF "optimize" operation options.
+func validateGIFOptimize(options *models.ImageConversionOptions) error {
+	o := options.Optimize
+	if o == nil {
+		return nil
+	}
+	if options.Format != "gif" {
+		return fmt.Errorf("optimize only applies to gif output, got %s", options.Format)
+	}
+	if len(options.Sizes) > 0 {
+		return fmt.Errorf("optimize cannot be combined with sizes")
+	}
+	if o.Colors != nil && (*o.Colors < 2 || *o.Colors > 256) {
+		return fmt.Errorf("optimize colors must be between 2 and 256, got %d", *o.Colors)
+	}
+	if o.Lossy != nil && (*o.Lossy < 1 || *o.Lossy > 200) {
+		return fmt.Errorf("optimize lossy must be between 1 and 200, got %d", *o.Lossy)
+	}
+	if o.FPS != nil && (*o.FPS < 1 || *o.FPS > 50) {
+		return fmt.Errorf("optimize fps must be between 1 and 50, got %g", *o.FPS)
+	}
+	if o.Level != nil && (*o.Level < 1 || *o.Level > 3) {
+		return fmt.Errorf("optimize level must be between 1 and 3, got %d", *o.Level)
+	}
+	return nil
+}
+
+// gifFrameDelay is the delay browsers actually show for a GIF frame, in
+// centiseconds: 0 and 1 are played as 10.
+func gifFrameDelay(delay int) int {
+	if delay < 2 {
+		return 10
+	}
+	return delay
+}
+
+// decimateGIFFrames picks the frames to keep so playback runs at no more
+// than fps. A kept frame absorbs the delays of the frames dropped after it,
+// so the animation keeps its running time. Delays are in centiseconds.
+func decimateGIFFrames(delays []int, fps float64) (keep, keptDelays []int) {
+	if fps <= 0 || len(delays) == 0 {
+		keep = make([]int, len(delays))
+		for i := range delays {
+			keep[i] = i
+		}
+		return keep, append([]int(nil), delays...)
+	}
+	interval := 100 / fps
+	start, next := 0, 0.0
+	for i, d := range delays {
+		if float64(start) >= next-1e-9 {
+			keep = append(keep, i)
+			keptDelays = append(keptDelays, 0)
+			next = (math.Floor(float64(start)/interval+1e-9) + 1) * interval
+		}
+		keptDelays[len(keptDelays)-1] += gifFrameDelay(d)
+		start += gifFrameDelay(d)
+	}
+	return keep, keptDelays
+}
+
+// gifsicleOptimizeArgs builds the gifsicle command. With frame dropping the
+// input is listed once per kept frame, each with its new delay, and
+// --unoptimize makes every selected frame complete on its own.
+func gifsicleOptimizeArgs(inputPath, outputPath string, o *models.GIFOptimizeOptions, keep, delays []int) []string {
+	level := 3
+	if o.Level != nil {
+		level = *o.Level
+	}
+	args := []string{"--no-warnings", fmt.Sprintf("--optimize=%d", level)}
+	if o.Colors != nil {
+		args = append(args, "--colors", strconv.Itoa(*o.Colors))
+	}
+	if o.Lossy != nil {
+		args = append(args, fmt.Sprintf("--lossy=%d", *o.Lossy))
+	}
+	if keep == nil {
+		return append(args, inputPath, "-o", outputPath)
+	}
+	args = append(args, "--unoptimize")
+	for i, frame := range keep {
+		args = append(args, fmt.Sprintf("--delay=%d", delays[i]), inputPath, fmt.Sprintf("#%d", frame))
+	}
+	return append(args, "-o", outputPath)
+}
+
+// gifFrameDelays reads every frame's delay (centiseconds) from path.
+func gifFrameDelays(ctx context.Context, path string) ([]int, error) {
+	name, args := resolveIdentifyFormatCommand("%T\n", path)
+	stdout, stderr, err := runCommand(ctx, name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("identify failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	var delays []int
+	for _, line := range strings.Fields(stdout) {
+		d, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse frame delay %q", line)
+		}
+		delays = append(delays, d)
+	}
+	return delays, nil
+}
+
+// optimizeGIF re-encodes the GIF at outputPath in place with gifsicle and
+// records the size of originalPath against the result.
+func (c *Converter) optimizeGIF(ctx context.Context, jobID, originalPath, outputPath string, o *models.GIFOptimizeOptions) error {
+	if _, err := exec.LookPath("gifsicle"); err != nil {
+		return fmt.Errorf("gifsicle is required for GIF optimization but was not found on PATH (install with: brew install gifsicle / apt install gifsicle)")
+	}
+	delays, err := gifFrameDelays(ctx, outputPath)
+	if err != nil {
+		return err
+	}
+	var keep, keptDelays []int
+	frames := len(delays)
+	if o.FPS != nil {
+		keep, keptDelays = decimateGIFFrames(delays, *o.FPS)
+		frames = len(keep)
+	}
+
+	rendered := outputPath + ".render.gif"
+	if err := os.Rename(outputPath, rendered); err != nil {
+		return err
+	}
+	defer os.Remove(rendered)
+	args := gifsicleOptimizeArgs(rendered, outputPath, o, keep, keptDelays)
+	done := c.recordCommand(jobID, "gifsicle", args)
+	_, stderr, err := runCommand(ctx, "gifsicle", args...)
+	done(stderr, err)
+	if err != nil {
+		return fmt.Errorf("gifsicle failed: %v: %s", err, strings.TrimSpace(stderr))
+	}
+
+	before, err := os.Stat(originalPath)
+	if err != nil {
+		return err
+	}
+	after, err := os.Stat(outputPath)
+	if err != nil {
+		return err
+	}
+	result := &models.GIFOptimizationResult{
+		OriginalBytes:  before.Size(),
+		OptimizedBytes: after.Size(),
+		OriginalFrames: len(delays),
+		Frames:         frames,
+	}
+	if before.Size() > 0 {
+		result.SavedPercent = math.Round(1000*(1-float64(after.Size())/float64(before.Size()))) / 10
+	}
+	if c.jobManager != nil {
+		_ = c.jobManager.SetGIFOptimization(jobID, result)
+	}
+	return nil
+}
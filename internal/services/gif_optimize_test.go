@@ -0,0 +1,58 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestDecimateGIFFrames(t *testing.T) {
+	// 12 frames at 4cs (25 fps) down to 10 fps keeps every 2-3 frames and
+	// the same 48cs running time.
+	delays := []int{4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}
+	keep, kept := decimateGIFFrames(delays, 10)
+	if want := []int{0, 3, 5, 8, 10}; !reflect.DeepEqual(keep, want) {
+		t.Fatalf("keep = %v, want %v", keep, want)
+	}
+	total := 0
+	for _, d := range kept {
+		total += d
+	}
+	if total != 48 {
+		t.Fatalf("kept delays %v sum to %d, want 48", kept, total)
+	}
+
+	// Slow frames are never dropped, and 0cs delays count as 10cs.
+	keep, kept = decimateGIFFrames([]int{50, 0, 50}, 10)
+	if !reflect.DeepEqual(keep, []int{0, 1, 2}) || !reflect.DeepEqual(kept, []int{50, 10, 50}) {
+		t.Fatalf("keep = %v, delays = %v", keep, kept)
+	}
+}
+
+func TestGIFsicleOptimizeArgs(t *testing.T) {
+	colors, lossy := 64, 80
+	o := &models.GIFOptimizeOptions{Colors: &colors, Lossy: &lossy}
+	got := strings.Join(gifsicleOptimizeArgs("in.gif", "out.gif", o, nil, nil), " ")
+	if want := "--no-warnings --optimize=3 --colors 64 --lossy=80 in.gif -o out.gif"; got != want {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+	got = strings.Join(gifsicleOptimizeArgs("in.gif", "out.gif", &models.GIFOptimizeOptions{}, []int{0, 2}, []int{8, 12}), " ")
+	if want := "--no-warnings --optimize=3 --unoptimize --delay=8 in.gif #0 --delay=12 in.gif #2 -o out.gif"; got != want {
+		t.Fatalf("decimated args = %q, want %q", got, want)
+	}
+}
+
+func TestValidateGIFOptimize(t *testing.T) {
+	level := 4
+	if err := validateGIFOptimize(&models.ImageConversionOptions{Format: "png", Optimize: &models.GIFOptimizeOptions{}}); err == nil {
+		t.Fatal("expected non-gif output to be rejected")
+	}
+	if err := validateGIFOptimize(&models.ImageConversionOptions{Format: "gif", Optimize: &models.GIFOptimizeOptions{Level: &level}}); err == nil {
+		t.Fatal("expected level 4 to be rejected")
+	}
+	if err := validateGIFOptimize(&models.ImageConversionOptions{Format: "gif", Optimize: &models.GIFOptimizeOptions{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
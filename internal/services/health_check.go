@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+// HealthChecker runs the deep health check behind /api/health?deep=true:
+// the media tools can be run, and the work directories are writable with
+// disk to spare. A result is reused for healthCheckTTL, so polling the
+// endpoint doesn't start a burst of processes per request.
+type HealthChecker struct {
+	cfg *config.Config
+
+	mu      sync.Mutex
+	last    *HealthReport
+	checked time.Time
+}
+
+// HealthReport is the outcome of a deep health check. Status is "healthy"
+// when every check passed and "degraded" otherwise.
+type HealthReport struct {
+	Status      string       `json:"status"`
+	CheckedAt   time.Time    `json:"checkedAt"`
+	Tools       []ToolHealth `json:"tools"`
+	Directories []DirHealth  `json:"directories"`
+}
+
+// ToolHealth is whether one external tool runs, and its version.
+type ToolHealth struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DirHealth is whether one work directory is writable and has free space.
+// The endpoint is public, so neither carries server paths.
+type DirHealth struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Writable   bool   `json:"writable"`
+	FreeBytes  uint64 `json:"freeBytes"`
+	TotalBytes uint64 `json:"totalBytes"`
+	Error      string `json:"error,omitempty"`
+}
+
+const (
+	healthCheckTTL       = 10 * time.Second
+	healthToolTimeout    = 5 * time.Second
+	healthStatusOK       = "healthy"
+	healthStatusDegraded = "degraded"
+)
+
+// Healthy reports whether every check passed.
+func (r *HealthReport) Healthy() bool {
+	return r.Status == healthStatusOK
+}
+
+func NewHealthChecker(cfg *config.Config) *HealthChecker {
+	return &HealthChecker{cfg: cfg}
+}
+
+// Check returns the deep health report, running the checks unless a recent
+// result can be reused.
+func (h *HealthChecker) Check(ctx context.Context) *HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.last != nil && time.Since(h.checked) < healthCheckTTL {
+		return h.last
+	}
+	// The result is shared, so a caller going away mustn't fail it.
+	ctx = context.WithoutCancel(ctx)
+	report := &HealthReport{Status: healthStatusOK, CheckedAt: time.Now().UTC()}
+	magick := "convert"
+	if _, err := exec.LookPath("magick"); err == nil {
+		magick = "magick"
+	}
+	for _, tool := range []string{"ffmpeg", "ffprobe", magick} {
+		t := checkTool(ctx, tool)
+		if !t.OK {
+			report.Status = healthStatusDegraded
+		}
+		report.Tools = append(report.Tools, t)
+	}
+	for _, dir := range []struct{ name, path string }{
+		{"uploads", h.cfg.UploadDir},
+		{"outputs", h.cfg.OutputDir},
+		{"temp", h.cfg.TempDir},
+	} {
+		d := checkDir(dir.name, dir.path, h.cfg.HealthMinFreeDiskBytes)
+		if !d.OK {
+			report.Status = healthStatusDegraded
+		}
+		report.Directories = append(report.Directories, d)
+	}
+	h.last, h.checked = report, time.Now()
+	return report
+}
+
+// checkTool runs "name -version" and keeps the version it prints.
+func checkTool(ctx context.Context, name string) ToolHealth {
+	t := ToolHealth{Name: name}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		t.Error = "not found on PATH"
+		return t
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthToolTimeout)
+	defer cancel()
+	stdout, stderr, err := runCommand(ctx, path, "-version")
+	if err != nil {
+		t.Error = fmt.Sprintf("%v: %s", err, commandTail(stderr, 300))
+		return t
+	}
+	t.OK, t.Version = true, parseToolVersion(stdout)
+	return t
+}
+
+// parseToolVersion picks the version out of the first line of -version
+// output: "ffmpeg version 6.1.1-3ubuntu5 Copyright ..." or "Version:
+// ImageMagick 7.1.1-21 Q16-HDRI ...". An unrecognised line is returned
+// whole.
+func parseToolVersion(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if (f == "version" || f == "ImageMagick") && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// checkDir creates and removes a file in dir and reads its filesystem's
+// free space. Less than minFree bytes free is a failure.
+func checkDir(name, dir string, minFree int64) DirHealth {
+	d := DirHealth{Name: name}
+	f, err := os.CreateTemp(dir, ".healthcheck-*")
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		d.Error = fmt.Sprintf("not writable: %v", err)
+		return d
+	}
+	f.Close()
+	os.Remove(f.Name())
+	d.Writable = true
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		d.Error = fmt.Sprintf("statfs: %v", err)
+		return d
+	}
+	d.FreeBytes = st.Bavail * uint64(st.Bsize)
+	d.TotalBytes = st.Blocks * uint64(st.Bsize)
+	if minFree > 0 && d.FreeBytes < uint64(minFree) {
+		d.Error = fmt.Sprintf("only %d bytes free (HEALTH_MIN_FREE_DISK_BYTES is %d)", d.FreeBytes, minFree)
+		return d
+	}
+	d.OK = true
+	return d
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func TestParseToolVersion(t *testing.T) {
+	for _, tc := range []struct{ out, want string }{
+		{"ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc", "6.1.1-3ubuntu5"},
+		{"ffprobe version n7.0 Copyright (c) 2007-2024", "n7.0"},
+		{"Version: ImageMagick 7.1.1-21 Q16-HDRI x86_64 21639 https://imagemagick.org\nCopyright: ...", "7.1.1-21"},
+		{"something else\n", "something else"},
+	} {
+		if got := parseToolVersion(tc.out); got != tc.want {
+			t.Errorf("parseToolVersion(%q) = %q, want %q", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	if d := checkDir("uploads", dir, 1); !d.OK || !d.Writable || d.FreeBytes == 0 || d.TotalBytes < d.FreeBytes {
+		t.Errorf("writable dir = %+v", d)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("probe file left behind: %v", entries)
+	}
+	if d := checkDir("uploads", dir, 1<<62); d.OK || !strings.Contains(d.Error, "HEALTH_MIN_FREE_DISK_BYTES") {
+		t.Errorf("low disk = %+v", d)
+	}
+	missing := filepath.Join(dir, "missing")
+	if d := checkDir("temp", missing, 0); d.OK || d.Writable || strings.Contains(d.Error, missing) {
+		t.Errorf("missing dir = %+v", d)
+	}
+}
+
+func TestHealthCheckerDegraded(t *testing.T) {
+	t.Setenv("PATH", "")
+	dir := t.TempDir()
+	h := NewHealthChecker(&config.Config{UploadDir: dir, OutputDir: dir, TempDir: dir})
+	report := h.Check(context.Background())
+	if report.Healthy() || len(report.Tools) != 3 || report.Tools[0].OK || len(report.Directories) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	for _, d := range report.Directories {
+		if !d.OK {
+			t.Errorf("directory %s: %s", d.Name, d.Error)
+		}
+	}
+	if again := h.Check(context.Background()); again != report {
+		t.Error("a recent report should be reused")
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HEIC/HEIF input (iPhone photos). ImageMagick decodes it through its libheif
+// delegate; everything else in the image pipeline works on a PNG decoded from
+// the selected image, so crop/resize/ICO/PDF/AI paths need no HEIF support of
+// their own.
+
+// heifMajorBrands are the ftyp major brands of HEIF still images and image
+// sequences. mif1/msf1 are generic and shared with AVIF, so they only count
+// when no AVIF brand is listed.
+var heifMajorBrands = map[string]string{
+	"heic": "image/heic", "heix": "image/heic", "heim": "image/heic", "heis": "image/heic",
+	"hevc": "image/heic-sequence", "hevx": "image/heic-sequence",
+	"mif1": "image/heif", "msf1": "image/heif-sequence",
+}
+
+// sniffHEIF returns the HEIF MIME type of data, or "" when it isn't one.
+func sniffHEIF(data []byte) string {
+	if len(data) < 12 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return ""
+	}
+	mime, ok := heifMajorBrands[string(data[8:12])]
+	if !ok {
+		return ""
+	}
+	boxSize := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if boxSize > len(data) {
+		boxSize = len(data)
+	}
+	// Compatible brands follow the major brand and minor version.
+	for i := 16; i+4 <= boxSize; i += 4 {
+		if brand := string(data[i : i+4]); brand == "avif" || brand == "avis" {
+			return ""
+		}
+	}
+	return mime
+}
+
+// isHEIFInput reports whether path is a HEIC/HEIF file, by extension first
+// and then by its ftyp box.
+func isHEIFInput(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".heic", ".heif", ".hif":
+		return true
+	}
+	return sniffHEIF(readFilePrefix(path, 64)) != ""
+}
+
+// countImages returns how many images ImageMagick reads from path.
+func countImages(ctx context.Context, path string) (int, error) {
+	name, args := "identify", []string{"-format", "%n\n", path}
+	if _, err := exec.LookPath("magick"); err == nil {
+		name, args = "magick", append([]string{"identify"}, args...)
+	}
+	stdout, stderr, err := runCommand(ctx, name, args...)
+	if err != nil {
+		return 0, fmt.Errorf("identify failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(stdout), "\n")
+	return strconv.Atoi(strings.TrimSpace(first))
+}
+
+// heifDecodeArgs selects image index of a HEIF file and writes it as a
+// lossless PNG. libheif has already applied the container's rotation and
+// mirroring, so the orientation tag is reset to keep -auto-orient later in the
+// chain from turning the picture a second time.
+func heifDecodeArgs(inputPath string, index int, outputPath string) []string {
+	return []string{fmt.Sprintf("%s[%d]", inputPath, index), "-orient", "TopLeft", "PNG:" + outputPath}
+}
+
+// decodeHEIF renders image index (0 is the first, normally the primary image)
+// of a HEIC/HEIF file into a PNG under dir. Multi-image files — bursts, image
+// collections — report their count when the index is out of range.
+func decodeHEIF(ctx context.Context, inputPath string, index int, dir string) (string, error) {
+	if !imageMagickCanRead("HEIC") {
+		return "", fmt.Errorf("HEIC/HEIF input needs ImageMagick built with libheif, which this server lacks")
+	}
+	if index > 0 {
+		n, err := countImages(ctx, inputPath)
+		if err != nil {
+			return "", err
+		}
+		if index >= n {
+			return "", fmt.Errorf("imageIndex %d is out of range: the file has %d image(s)", index, n)
+		}
+	}
+	outputPath := filepath.Join(dir, fmt.Sprintf("heif_%d.png", time.Now().UnixNano()))
+	name, args := resolveImageMagickConvertCommand("convert", heifDecodeArgs(inputPath, index, outputPath))
+	if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("HEIF decode failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	return outputPath, nil
+}
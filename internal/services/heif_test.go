@@ -0,0 +1,45 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ftyp builds an ISO BMFF ftyp box with the given major and compatible brands.
+func ftyp(major string, compatible ...string) []byte {
+	size := 16 + 4*len(compatible)
+	box := []byte{0, 0, 0, byte(size), 'f', 't', 'y', 'p'}
+	box = append(box, major...)
+	box = append(box, 0, 0, 0, 0)
+	for _, b := range compatible {
+		box = append(box, b...)
+	}
+	return box
+}
+
+func TestSniffHEIF(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"iphone photo":     {ftyp("heic", "mif1", "heic"), "image/heic"},
+		"generic heif":     {ftyp("mif1", "heic"), "image/heif"},
+		"burst sequence":   {ftyp("hevc", "msf1"), "image/heic-sequence"},
+		"avif is not heif": {ftyp("mif1", "avif", "miaf"), ""},
+		"mp4 video":        {ftyp("isom", "iso2", "mp41"), ""},
+		"too short":        {[]byte("ftyp"), ""},
+		"not an ftyp box":  {[]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), ""},
+	} {
+		if got := sniffHEIF(tc.data); got != tc.want {
+			t.Errorf("%s: sniffHEIF = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestHEIFDecodeArgs(t *testing.T) {
+	got := heifDecodeArgs("/tmp/IMG_0001.HEIC", 2, "/tmp/out.png")
+	want := []string{"/tmp/IMG_0001.HEIC[2]", "-orient", "TopLeft", "PNG:/tmp/out.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// srgbProfileCandidates are where distributions install an sRGB ICC profile
+// (colord, icc-profiles-free, Ghostscript). SRGB_ICC_PROFILE overrides them.
+var srgbProfileCandidates = []string{
+	"/usr/share/color/icc/sRGB.icc",
+	"/usr/share/color/icc/colord/sRGB.icc",
+	"/usr/share/color/icc/sRGB.icm",
+	"/usr/share/color/icc/ghostscript/srgb.icc",
+}
+
+// validateImageColorProfile checks colorProfile: "preserve" (default),
+// "strip" or "srgb".
+func validateImageColorProfile(options *models.ImageConversionOptions) error {
+	switch options.ColorProfile {
+	case "", "preserve", "strip", "srgb":
+		return nil
+	}
+	return fmt.Errorf("unsupported colorProfile: %s (expected preserve, strip or srgb)", options.ColorProfile)
+}
+
+// imageColorProfileArgs returns the ImageMagick steps for colorProfile.
+// "srgb" converts the pixels from the embedded profile (CMYK, Adobe RGB,
+// Display P3…) into sRGB and embeds sRGB in its place; without an sRGB
+// profile on disk it falls back to a plain colorspace conversion, which is
+// close for CMYK but can't honour the source profile. "strip" drops the
+// profile and leaves the pixel values alone.
+func imageColorProfileArgs(options *models.ImageConversionOptions) []string {
+	switch options.ColorProfile {
+	case "strip":
+		return []string{"+profile", "icc"}
+	case "srgb":
+		if profile := srgbProfilePath(); profile != "" {
+			return []string{"-intent", "Perceptual", "-profile", profile}
+		}
+		return []string{"-colorspace", "sRGB", "+profile", "icc"}
+	}
+	return nil
+}
+
+// srgbProfilePath returns SRGB_ICC_PROFILE, or the first sRGB profile found
+// in the usual system locations, or "" when there is none.
+func srgbProfilePath() string {
+	if path := strings.TrimSpace(os.Getenv("SRGB_ICC_PROFILE")); path != "" {
+		return path
+	}
+	for _, path := range srgbProfileCandidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// keepsSourceICCProfile reports whether the metadata copy may carry the
+// source ICC profile back onto the output. After "strip" or "srgb" it would
+// undo the work or mislabel the converted pixels.
+func keepsSourceICCProfile(options *models.ImageConversionOptions) bool {
+	return options.ColorProfile != "strip" && options.ColorProfile != "srgb"
+}
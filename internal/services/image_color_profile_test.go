@@ -0,0 +1,25 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestImageColorProfileArgs(t *testing.T) {
+	if err := validateImageColorProfile(&models.ImageConversionOptions{ColorProfile: "cmyk"}); err == nil {
+		t.Fatal("cmyk must be rejected")
+	}
+	srgb := &models.ImageConversionOptions{ColorProfile: "srgb"}
+	t.Setenv("SRGB_ICC_PROFILE", "/profiles/sRGB.icc")
+	if got, want := imageColorProfileArgs(srgb), []string{"-intent", "Perceptual", "-profile", "/profiles/sRGB.icc"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("srgb args = %v, want %v", got, want)
+	}
+	if keepsSourceICCProfile(srgb) {
+		t.Fatal("the metadata copy must not restore the source profile after srgb")
+	}
+	if args := imageColorProfileArgs(&models.ImageConversionOptions{ColorProfile: "preserve"}); args != nil {
+		t.Fatalf("preserve args = %v", args)
+	}
+}
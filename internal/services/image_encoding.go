@@ -0,0 +1,46 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// jpegSamplingFactors maps chroma subsampling names to libjpeg sampling
+// factors. 4:2:0 is what ImageMagick picks on its own below quality 90.
+var jpegSamplingFactors = map[string]string{"4:4:4": "1x1", "4:2:2": "2x1", "4:2:0": "2x2"}
+
+// validateImageEncoding checks progressive and chromaSubsampling against the
+// output format.
+func validateImageEncoding(options *models.ImageConversionOptions) error {
+	isJPEG := options.Format == "jpg" || options.Format == "jpeg"
+	if options.Progressive && !isJPEG && options.Format != "png" {
+		return fmt.Errorf("progressive applies to jpg and png output, not %s", options.Format)
+	}
+	if options.ChromaSubsampling != "" {
+		if !isJPEG {
+			return fmt.Errorf("chromaSubsampling applies to jpg output, not %s", options.Format)
+		}
+		if jpegSamplingFactors[options.ChromaSubsampling] == "" {
+			return fmt.Errorf("unsupported chromaSubsampling: %s (expected 4:4:4, 4:2:2 or 4:2:0)", options.ChromaSubsampling)
+		}
+	}
+	return nil
+}
+
+// imageEncodingArgs returns the encoder settings for progressive JPEG,
+// Adam7-interlaced PNG and JPEG chroma subsampling.
+func imageEncodingArgs(options *models.ImageConversionOptions) []string {
+	var args []string
+	if options.Progressive {
+		if options.Format == "png" {
+			args = append(args, "-interlace", "PNG")
+		} else {
+			args = append(args, "-interlace", "Plane")
+		}
+	}
+	if factor := jpegSamplingFactors[options.ChromaSubsampling]; factor != "" {
+		args = append(args, "-sampling-factor", factor)
+	}
+	return args
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageEncoding(t *testing.T) {
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr bool
+	}{
+		{name: "progressive jpeg", options: models.ImageConversionOptions{Format: "jpg", Progressive: true, ChromaSubsampling: "4:2:2"}},
+		{name: "interlaced png", options: models.ImageConversionOptions{Format: "png", Progressive: true}},
+		{name: "progressive webp", options: models.ImageConversionOptions{Format: "webp", Progressive: true}, wantErr: true},
+		{name: "subsampling png", options: models.ImageConversionOptions{Format: "png", ChromaSubsampling: "4:4:4"}, wantErr: true},
+		{name: "unknown subsampling", options: models.ImageConversionOptions{Format: "jpeg", ChromaSubsampling: "4:1:1"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateImageEncoding(&tc.options); (err != nil) != tc.wantErr {
+				t.Fatalf("validateImageEncoding error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// maxImageFilterSteps caps the length of a filters chain.
+const maxImageFilterSteps = 10
+
+// defaultFilterStrength is the strength a filter runs at when none is given;
+// at 50 every filter produces its historical fixed setting.
+const defaultFilterStrength = 50.0
+
+// validImageFilters lists every filter name. Rotations and grayscale take
+// no strength.
+var validImageFilters = map[string]bool{
+	"none": true, "grayscale": true, "sepia": true, "blur": true, "sharpen": true,
+	"swirl": true, "barrel-distortion": true, "oil-painting": true, "vintage": true,
+	"emboss": true, "charcoal": true, "sketch": true, "vignette": true, "rotate-45º": true,
+	"rotate-90º": true, "rotate-180º": true, "rotate-270º": true,
+}
+
+// validateImageFilters checks the filters chain.
+func validateImageFilters(filters []models.ImageFilterStep) error {
+	if len(filters) > maxImageFilterSteps {
+		return fmt.Errorf("at most %d filters are allowed, got %d", maxImageFilterSteps, len(filters))
+	}
+	for i, f := range filters {
+		if !validImageFilters[f.Name] {
+			return fmt.Errorf("filters[%d]: unsupported filter: %s", i, f.Name)
+		}
+		if f.Strength != nil && (*f.Strength < 0 || *f.Strength > 100) {
+			return fmt.Errorf("filters[%d]: strength must be between 0 and 100, got %g", i, *f.Strength)
+		}
+	}
+	return nil
+}
+
+// filterNum formats a filter parameter without trailing zeros.
+func filterNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// imageFilterArgs returns the ImageMagick operators for one filter at
+// strength 0–100 (nil means defaultFilterStrength).
+func imageFilterArgs(name string, strength *float64) []string {
+	s := defaultFilterStrength
+	if strength != nil {
+		s = *strength
+	}
+	switch name {
+	case "grayscale":
+		return []string{"-colorspace", "Gray"}
+	case "sepia":
+		return []string{"-sepia-tone", filterNum(math.Min(s*1.6, 100)) + "%"}
+	case "blur":
+		return []string{"-blur", "0x" + filterNum(s/6.25)}
+	case "sharpen":
+		return []string{"-sharpen", "0x" + filterNum(s/50)}
+	case "swirl":
+		return []string{"-swirl", filterNum(s * 1.8)}
+	case "barrel-distortion":
+		return []string{"-distort", "Barrel", filterNum(s/500) + " 0.0 0.0 1.0"}
+	case "oil-painting":
+		return []string{"-paint", filterNum(s / 12.5)}
+	case "vintage":
+		return []string{"-modulate", fmt.Sprintf("%s,%s,100", filterNum(100+s*0.4), filterNum(100-s)), "-colorize", fmt.Sprintf("%s,%s,%s", filterNum(s/5), filterNum(s/10), filterNum(s*0.3))}
+	case "emboss":
+		return []string{"-emboss", filterNum(s / 25)}
+	case "charcoal":
+		return []string{"-charcoal", filterNum(s / 25)}
+	case "sketch":
+		return []string{"-sketch", fmt.Sprintf("0x%s+120", filterNum(s/2.5))}
+	case "vignette":
+		return []string{"-background", "black", "-vignette", "0x" + filterNum(s*0.4)}
+	case "rotate-45º":
+		return []string{"-rotate", "45"}
+	case "rotate-90º":
+		return []string{"-rotate", "90"}
+	case "rotate-180º":
+		return []string{"-rotate", "180"}
+	case "rotate-270º":
+		return []string{"-rotate", "270"}
+	}
+	return nil
+}
+
+// defaultTintStrength is the historical fixed -tint amount.
+const defaultTintStrength = 30
+
+var imageTintModes = map[string]bool{"tint": true, "colorize": true, "multiply": true, "overlay": true}
+
+// validateImageTint checks the tint colour, strength and mode. The colour
+// goes to -fill as is, so only hex forms are accepted.
+func validateImageTint(options *models.ImageConversionOptions) error {
+	if c := options.Tint; c != nil && *c != "" && !isSafeImageColor(*c) {
+		return fmt.Errorf("invalid tint color")
+	}
+	if s := options.TintStrength; s != nil && (*s < 0 || *s > 100) {
+		return fmt.Errorf("tintStrength must be between 0 and 100, got %d", *s)
+	}
+	if options.TintMode != "" && !imageTintModes[options.TintMode] {
+		return fmt.Errorf("unsupported tintMode: %s", options.TintMode)
+	}
+	return nil
+}
+
+// imageTintArgs applies color at strength 0–100. "tint" shifts the
+// mid-tones and leaves black and white alone, "colorize" washes the whole
+// picture, and "multiply"/"overlay" blend a solid layer of the colour with
+// that compose mode, mixed in at strength percent.
+func imageTintArgs(color string, strength *int, mode string) []string {
+	s := defaultTintStrength
+	if strength != nil {
+		s = *strength
+	}
+	switch mode {
+	case "colorize":
+		return []string{"-fill", color, "-colorize", fmt.Sprintf("%d%%", s)}
+	case "multiply", "overlay":
+		compose := "Multiply"
+		if mode == "overlay" {
+			compose = "Overlay"
+		}
+		return []string{
+			"(", "+clone", "(", "+clone", "-fill", color, "-colorize", "100", ")",
+			"-compose", compose, "-composite", ")",
+			"-compose", "Blend", "-define", fmt.Sprintf("compose:args=%d", s), "-composite", "-compose", "Over",
+		}
+	}
+	return []string{"-fill", color, "-tint", strconv.Itoa(s)}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestImageFilterArgsDefaultStrength(t *testing.T) {
+	// At the default strength every filter keeps the values the single
+	// Filter option has always used.
+	cases := map[string][]string{
+		"sepia":             {"-sepia-tone", "80%"},
+		"blur":              {"-blur", "0x8"},
+		"sharpen":           {"-sharpen", "0x1"},
+		"swirl":             {"-swirl", "90"},
+		"barrel-distortion": {"-distort", "Barrel", "0.1 0.0 0.0 1.0"},
+		"oil-painting":      {"-paint", "4"},
+		"vintage":           {"-modulate", "120,50,100", "-colorize", "10,5,15"},
+		"emboss":            {"-emboss", "2"},
+		"charcoal":          {"-charcoal", "2"},
+		"sketch":            {"-sketch", "0x20+120"},
+	}
+	for name, want := range cases {
+		if got := imageFilterArgs(name, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("imageFilterArgs(%q) = %q, want %q", name, got, want)
+		}
+	}
+	strong := 100.0
+	if got := imageFilterArgs("blur", &strong); !reflect.DeepEqual(got, []string{"-blur", "0x16"}) {
+		t.Errorf("blur at 100 = %q", got)
+	}
+}
+
+func TestValidateImageFilters(t *testing.T) {
+	over := 120.0
+	if err := validateImageFilters([]models.ImageFilterStep{{Name: "grayscale"}, {Name: "vignette"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateImageFilters([]models.ImageFilterStep{{Name: "posterize"}}); err == nil {
+		t.Fatal("expected an unknown filter to fail")
+	}
+	if err := validateImageFilters([]models.ImageFilterStep{{Name: "blur", Strength: &over}}); err == nil {
+		t.Fatal("expected strength 120 to fail")
+	}
+}
+
+func TestValidateImageTint(t *testing.T) {
+	color, strength := "#ff8800", 150
+	if err := validateImageTint(&models.ImageConversionOptions{Tint: &color, TintMode: "overlay"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateImageTint(&models.ImageConversionOptions{Tint: &color, TintStrength: &strength}); err == nil {
+		t.Fatal("expected strength 150 to fail")
+	}
+	if err := validateImageTint(&models.ImageConversionOptions{Tint: &color, TintMode: "screen"}); err == nil {
+		t.Fatal("expected an unknown mode to fail")
+	}
+	for _, bad := range []string{"red", "#ff8800 -fx 0", "rgb(1,2,3)"} {
+		if err := validateImageTint(&models.ImageConversionOptions{Tint: &bad}); err == nil {
+			t.Errorf("expected tint %q to fail", bad)
+		}
+	}
+}
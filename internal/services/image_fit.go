@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Fit modes follow sharp/imgproxy semantics for a width x height box:
+//
+//	fill    — stretch to exactly the box, ignoring the aspect ratio (default)
+//	inside  — as large as possible while fitting inside the box
+//	outside — as small as possible while covering the box
+//	cover   — cover the box, then crop the overflow around the centre
+//	contain — fit inside the box, then pad it out with Background
+//
+// A single dimension always scales proportionally.
+var imageFitModes = map[string]bool{"fill": true, "inside": true, "outside": true, "cover": true, "contain": true}
+
+// validateImageFit checks fit, background and withoutEnlargement.
+func validateImageFit(options *models.ImageConversionOptions) error {
+	if options.Fit != "" && !imageFitModes[options.Fit] {
+		return fmt.Errorf("unsupported fit: %s", options.Fit)
+	}
+	if options.Fit != "" && options.Fit != "fill" && (options.Width == nil || options.Height == nil) {
+		return fmt.Errorf("fit %s needs both width and height", options.Fit)
+	}
+	if options.Background != "" && options.Background != "transparent" && !isSafeImageColor(options.Background) {
+		return fmt.Errorf("invalid background color")
+	}
+	return nil
+}
+
+// imageFitBackground is the padding colour for fit=contain: the requested
+// colour, else transparent, or white for formats without alpha.
+func imageFitBackground(options *models.ImageConversionOptions) string {
+	switch {
+	case options.Background == "transparent":
+		return "none"
+	case options.Background != "":
+		return options.Background
+	case options.Format == "jpg" || options.Format == "jpeg":
+		return "white"
+	}
+	return "none"
+}
+
+// imageResizeArgs returns the ImageMagick resize for Width/Height and Fit.
+func imageResizeArgs(options *models.ImageConversionOptions) []string {
+	noEnlarge := ""
+	if options.WithoutEnlargement {
+		noEnlarge = ">"
+	}
+	if options.Width == nil || options.Height == nil {
+		if options.Width != nil {
+			return []string{"-resize", fmt.Sprintf("%dx%s", *options.Width, noEnlarge)}
+		}
+		return []string{"-resize", fmt.Sprintf("x%d%s", *options.Height, noEnlarge)}
+	}
+	box := fmt.Sprintf("%dx%d", *options.Width, *options.Height)
+	switch options.Fit {
+	case "inside":
+		return []string{"-resize", box + noEnlarge}
+	case "outside":
+		return []string{"-resize", box + "^" + noEnlarge}
+	case "cover":
+		return []string{"-resize", box + "^" + noEnlarge, "-gravity", "center", "-extent", box, "+gravity", "+repage"}
+	case "contain":
+		return []string{"-resize", box + noEnlarge, "-background", imageFitBackground(options), "-gravity", "center", "-extent", box, "+gravity", "+repage"}
+	}
+	return []string{"-resize", box + "!" + noEnlarge}
+}
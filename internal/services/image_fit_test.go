@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageFit(t *testing.T) {
+	w, h := 400, 300
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr bool
+	}{
+		{name: "default", options: models.ImageConversionOptions{Width: &w, Height: &h}},
+		{name: "cover", options: models.ImageConversionOptions{Width: &w, Height: &h, Fit: "cover"}},
+		{name: "contain transparent", options: models.ImageConversionOptions{Width: &w, Height: &h, Fit: "contain", Background: "transparent"}},
+		{name: "unknown fit", options: models.ImageConversionOptions{Width: &w, Height: &h, Fit: "stretch"}, wantErr: true},
+		{name: "cover needs both sides", options: models.ImageConversionOptions{Width: &w, Fit: "cover"}, wantErr: true},
+		{name: "bad background", options: models.ImageConversionOptions{Width: &w, Height: &h, Fit: "contain", Background: "red;rm"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateImageFit(&tc.options); (err != nil) != tc.wantErr {
+				t.Fatalf("validateImageFit error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageResizeArgsWithoutEnlargement(t *testing.T) {
+	w := 640
+	got := imageResizeArgs(&models.ImageConversionOptions{Width: &w, WithoutEnlargement: true})
+	if len(got) != 2 || got[1] != "640x>" {
+		t.Fatalf("imageResizeArgs = %q, want [-resize 640x>]", got)
+	}
+}
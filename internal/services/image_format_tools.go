@@ -1,7 +1,6 @@
 package services
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -147,7 +146,7 @@ func (c *Converter) convertImageToSVG(job *models.ConversionJob, options *models
 	}
 
 	// Step 2: potrace -> SVG.
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+	ctx, cancel := c.commandContext(job.ID)
 	defer cancel()
 	potraceArgs := []string{"-s", "-t", strconv.Itoa(turd), "-o", outputPath, pbm}
 	if _, stderr, err := runCommand(ctx, "potrace", potraceArgs...); err != nil {
@@ -175,7 +174,7 @@ func (c *Converter) convertSVGToRaster(job *models.ConversionJob, options *model
 		c.jobManager.SendProgressUpdate(job.ID, 25)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+	ctx, cancel := c.commandContext(job.ID)
 	defer cancel()
 
 	if _, err := exec.LookPath("rsvg-convert"); err == nil {
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// imageRotationArgs rotates by any angle and then flips. Right angles need
+// no fill; other angles open up corners painted with the fit background.
+func imageRotationArgs(options *models.ImageConversionOptions) []string {
+	var args []string
+	if r := options.Rotation; r != nil && math.Mod(*r, 360) != 0 {
+		if math.Mod(*r, 90) != 0 {
+			args = append(args, "-background", imageFitBackground(options))
+		}
+		args = append(args, "-rotate", strconv.FormatFloat(*r, 'f', -1, 64), "+repage")
+	}
+	if options.FlipHorizontal != nil && *options.FlipHorizontal {
+		args = append(args, "-flop")
+	}
+	if options.FlipVertical != nil && *options.FlipVertical {
+		args = append(args, "-flip")
+	}
+	return args
+}
+
+// validateImageFrame checks padding, border, corner radius and rotation.
+func validateImageFrame(options *models.ImageConversionOptions) error {
+	if options.Padding < 0 || options.Padding > 1000 {
+		return fmt.Errorf("padding must be between 0 and 1000, got %d", options.Padding)
+	}
+	if options.BorderWidth < 0 || options.BorderWidth > 500 {
+		return fmt.Errorf("borderWidth must be between 0 and 500, got %d", options.BorderWidth)
+	}
+	if options.BorderColor != "" && !isSafeImageColor(options.BorderColor) {
+		return fmt.Errorf("invalid border color")
+	}
+	if options.CornerRadius < 0 || options.CornerRadius > 5000 {
+		return fmt.Errorf("cornerRadius must be between 0 and 5000, got %d", options.CornerRadius)
+	}
+	if r := options.Rotation; r != nil && (*r < -360 || *r > 360) {
+		return fmt.Errorf("rotation must be between -360 and 360, got %.2f", *r)
+	}
+	return nil
+}
+
+// imageFrameArgs pads, borders and rounds the finished picture, in that
+// order, so the border sits outside the padding and the corners cut through
+// both. Formats without alpha get the cut corners filled with the
+// background colour instead of black.
+func imageFrameArgs(options *models.ImageConversionOptions) []string {
+	var args []string
+	if options.Padding > 0 {
+		args = append(args, "-alpha", "set", "-bordercolor", imageFitBackground(options), "-border", fmt.Sprint(options.Padding))
+	}
+	if options.BorderWidth > 0 {
+		color := options.BorderColor
+		if color == "" {
+			color = "#000000"
+		}
+		args = append(args, "-bordercolor", color, "-border", fmt.Sprint(options.BorderWidth))
+	}
+	if r := options.CornerRadius; r > 0 {
+		// A corner mask drawn once and mirrored to all four corners, so it
+		// works without knowing the image size.
+		corner := fmt.Sprintf("fill black polygon 0,0 0,%d %d,0 fill white circle %d,%d %d,0", r, r, r, r, r)
+		args = append(args,
+			"(", "+clone", "-alpha", "extract", "-draw", corner,
+			"(", "+clone", "-flip", ")", "-compose", "Multiply", "-composite",
+			"(", "+clone", "-flop", ")", "-compose", "Multiply", "-composite", ")",
+			"-alpha", "off", "-compose", "CopyOpacity", "-composite", "-compose", "Over")
+		if options.Format == "jpg" || options.Format == "jpeg" {
+			args = append(args, "-background", imageFitBackground(options), "-flatten")
+		}
+	}
+	return args
+}
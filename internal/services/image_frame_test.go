@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageFrame(t *testing.T) {
+	rotation, overRotation := 33.3, 400.0
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr bool
+	}{
+		{name: "none", options: models.ImageConversionOptions{}},
+		{name: "avatar", options: models.ImageConversionOptions{Padding: 8, BorderWidth: 4, BorderColor: "#fff", CornerRadius: 128}},
+		{name: "negative padding", options: models.ImageConversionOptions{Padding: -1}, wantErr: true},
+		{name: "bad border color", options: models.ImageConversionOptions{BorderWidth: 2, BorderColor: "white"}, wantErr: true},
+		{name: "huge radius", options: models.ImageConversionOptions{CornerRadius: 10000}, wantErr: true},
+		{name: "rotation", options: models.ImageConversionOptions{Rotation: &rotation}},
+		{name: "rotation out of range", options: models.ImageConversionOptions{Rotation: &overRotation}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateImageFrame(&tc.options); (err != nil) != tc.wantErr {
+				t.Fatalf("validateImageFrame error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
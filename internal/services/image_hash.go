@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Perceptual hashes for duplicate detection. Both are 64-bit fingerprints
+// of a small grayscale rendering, so re-encodes, resizes and light edits of
+// the same picture land a few bits apart while different pictures differ in
+// about half of them:
+//
+//	pHash — the signs of the lowest 8x8 DCT frequencies of a 32x32 rendering
+//	        against their median; robust to scaling, compression and gamma.
+//	dHash — whether each pixel of a 9x8 rendering is brighter than its right
+//	        neighbour; cheap and good at near-identical copies.
+
+// DefaultDuplicateThreshold is the largest pHash distance, in bits, at which
+// two images are reported as duplicates.
+const DefaultDuplicateThreshold = 10
+
+// PerceptualHash is a 64-bit image hash, written as 16 hex digits.
+type PerceptualHash uint64
+
+func (h PerceptualHash) String() string { return fmt.Sprintf("%016x", uint64(h)) }
+
+func (h PerceptualHash) MarshalText() ([]byte, error) { return []byte(h.String()), nil }
+
+func (h *PerceptualHash) UnmarshalText(text []byte) error {
+	v, err := strconv.ParseUint(strings.TrimSpace(string(text)), 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid perceptual hash %q", text)
+	}
+	*h = PerceptualHash(v)
+	return nil
+}
+
+// Distance is the number of differing bits.
+func (h PerceptualHash) Distance(other PerceptualHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// ImageHashes is the response of POST /api/analyze/image-hash.
+type ImageHashes struct {
+	PHash PerceptualHash `json:"phash"`
+	DHash PerceptualHash `json:"dhash"`
+}
+
+// ImageSimilarity scores two images. Similarity is 1 - pHashDistance/64.
+type ImageSimilarity struct {
+	A             ImageHashes `json:"a"`
+	B             ImageHashes `json:"b"`
+	PHashDistance int         `json:"phashDistance"`
+	DHashDistance int         `json:"dhashDistance"`
+	Similarity    float64     `json:"similarity"`
+	Threshold     int         `json:"threshold"`
+	Duplicate     bool        `json:"duplicate"`
+}
+
+// ComputeImageHashes hashes the first frame of an image as displayed:
+// auto-oriented, with transparency flattened onto white.
+func ComputeImageHashes(ctx context.Context, path string) (*ImageHashes, error) {
+	large, err := hashRendering(ctx, path, 32, 32)
+	if err != nil {
+		return nil, err
+	}
+	small, err := hashRendering(ctx, path, 9, 8)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageHashes{PHash: perceptualHash(large), DHash: differenceHash(small)}, nil
+}
+
+// CompareImageHashes scores a against b; pHash distances up to threshold
+// count as duplicates.
+func CompareImageHashes(a, b ImageHashes, threshold int) *ImageSimilarity {
+	s := &ImageSimilarity{
+		A:             a,
+		B:             b,
+		PHashDistance: a.PHash.Distance(b.PHash),
+		DHashDistance: a.DHash.Distance(b.DHash),
+		Threshold:     threshold,
+	}
+	s.Similarity = math.Round((1-float64(s.PHashDistance)/64)*1000) / 1000
+	s.Duplicate = s.PHashDistance <= threshold
+	return s
+}
+
+// hashRendering returns w*h 8-bit luminance values, row by row.
+func hashRendering(ctx context.Context, path string, w, h int) ([]byte, error) {
+	name, args := resolveImageMagickConvertCommand("convert", []string{
+		path + "[0]", "-auto-orient", "-background", "white", "-alpha", "remove",
+		"-colorspace", "Gray", "-resize", fmt.Sprintf("%dx%d!", w, h), "-depth", "8", "gray:-",
+	})
+	stdout, stderr, err := runCommand(ctx, name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("image hash render failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	if len(stdout) != w*h {
+		return nil, fmt.Errorf("image hash render returned %d bytes, want %d", len(stdout), w*h)
+	}
+	return []byte(stdout), nil
+}
+
+// perceptualHash hashes a 32x32 rendering.
+func perceptualHash(px []byte) PerceptualHash {
+	const n, low = 32, 8
+	var cos [low][n]float64
+	for u := 0; u < low; u++ {
+		for x := 0; x < n; x++ {
+			cos[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * n))
+		}
+	}
+	// Only the low frequencies are needed: transform the rows, then the
+	// columns of the first eight coefficients.
+	var rows [n][low]float64
+	for y := 0; y < n; y++ {
+		for u := 0; u < low; u++ {
+			for x := 0; x < n; x++ {
+				rows[y][u] += float64(px[y*n+x]) * cos[u][x]
+			}
+		}
+	}
+	coeffs := make([]float64, 0, low*low)
+	for v := 0; v < low; v++ {
+		for u := 0; u < low; u++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				sum += rows[y][u] * cos[v][y]
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+	sorted := append([]float64(nil), coeffs...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	var hash PerceptualHash
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << (63 - i)
+		}
+	}
+	return hash
+}
+
+// differenceHash hashes a 9x8 rendering.
+func differenceHash(px []byte) PerceptualHash {
+	var hash PerceptualHash
+	i := 0
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if px[y*9+x] < px[y*9+x+1] {
+				hash |= 1 << (63 - i)
+			}
+			i++
+		}
+	}
+	return hash
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// hashTestImage renders a w x h pattern: a diagonal gradient with a bright
+// block whose corner is at (bx, by).
+func hashTestImage(w, h, bx, by int, offset int) []byte {
+	px := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := (x+y)*200/(w+h) + offset
+			if x >= bx && y >= by && x < bx+w/3 && y < by+h/3 {
+				v = 230 + offset/4
+			}
+			px[y*w+x] = byte(min(max(v, 0), 255))
+		}
+	}
+	return px
+}
+
+func TestPerceptualHashIgnoresBrightness(t *testing.T) {
+	a := perceptualHash(hashTestImage(32, 32, 4, 4, 0))
+	b := perceptualHash(hashTestImage(32, 32, 4, 4, 20))
+	if d := a.Distance(b); d > 4 {
+		t.Fatalf("brightened copy is %d bits away", d)
+	}
+	other := perceptualHash(hashTestImage(32, 32, 20, 20, 0))
+	if d := a.Distance(other); d < 10 {
+		t.Fatalf("different picture is only %d bits away", d)
+	}
+}
+
+func TestDifferenceHash(t *testing.T) {
+	rising := make([]byte, 72)
+	for i := range rising {
+		rising[i] = byte(i % 9 * 10)
+	}
+	if h := differenceHash(rising); h != PerceptualHash(^uint64(0)) {
+		t.Fatalf("rising rows = %s", h)
+	}
+	flat := make([]byte, 72)
+	if h := differenceHash(flat); h != 0 {
+		t.Fatalf("flat = %s", h)
+	}
+}
+
+func TestCompareImageHashes(t *testing.T) {
+	a := ImageHashes{PHash: 0xff00ff00ff00ff00, DHash: 0x0f}
+	b := ImageHashes{PHash: 0xff00ff00ff00ff0f, DHash: 0xff}
+	s := CompareImageHashes(a, b, DefaultDuplicateThreshold)
+	if s.PHashDistance != 4 || s.DHashDistance != 4 || s.Similarity != 0.938 || !s.Duplicate {
+		t.Fatalf("similarity = %+v", s)
+	}
+	if CompareImageHashes(a, ImageHashes{PHash: 0x00ff00ff00ff00ff}, DefaultDuplicateThreshold).Duplicate {
+		t.Fatal("inverted hash reported as duplicate")
+	}
+}
+
+func TestPerceptualHashJSON(t *testing.T) {
+	body, err := json.Marshal(ImageHashes{PHash: 0xc3, DHash: 0xffffffffffffffff})
+	if err != nil || string(body) != `{"phash":"00000000000000c3","dhash":"ffffffffffffffff"}` {
+		t.Fatalf("json = %s, %v", body, err)
+	}
+	var back ImageHashes
+	if err := json.Unmarshal(body, &back); err != nil || back.PHash != 0xc3 {
+		t.Fatalf("round trip = %+v, %v", back, err)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Images are described with ImageMagick's json: coder rather than
+// identify -verbose, whose indented text flattens badly into key/value pairs.
+// ImageMagick 7 prints a JSON array of {"version", "image"} records, one per
+// frame; ImageMagick 6 prints one {"image": …} object per frame back to back.
+
+// imageMagickJSONCommand describes path (or "-" for stdin) as JSON.
+func imageMagickJSONCommand(path string) (string, []string) {
+	return resolveImageMagickConvertCommand("convert", []string{path, "json:"})
+}
+
+// parseImageMagickJSON returns the "image" object of every frame.
+func parseImageMagickJSON(raw string) ([]map[string]any, error) {
+	var records []map[string]any
+	trimmed := strings.TrimSpace(raw)
+	if !strings.HasPrefix(trimmed, "[") {
+		// Some ImageMagick 6 builds separate the frames with commas.
+		if json.Unmarshal([]byte("["+trimmed+"]"), &records) != nil {
+			records = nil
+			dec := json.NewDecoder(strings.NewReader(trimmed))
+			for {
+				var record map[string]any
+				if err := dec.Decode(&record); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					return nil, fmt.Errorf("parse imagemagick json: %w", err)
+				}
+				records = append(records, record)
+			}
+		}
+	} else if err := json.Unmarshal([]byte(trimmed), &records); err != nil {
+		return nil, fmt.Errorf("parse imagemagick json: %w", err)
+	}
+	frames := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		if image, ok := record["image"].(map[string]any); ok {
+			frames = append(frames, image)
+		} else if record != nil {
+			frames = append(frames, record)
+		}
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("parse imagemagick json: no images")
+	}
+	return frames, nil
+}
+
+// identificationFromJSON summarizes the first frame and counts the rest.
+func identificationFromJSON(frames []map[string]any) *models.ImageIdentification {
+	image := frames[0]
+	id := &models.ImageIdentification{
+		Format:      jsonString(image["format"]),
+		MimeType:    jsonString(image["mimeType"]),
+		Orientation: jsonString(image["orientation"]),
+		Frames:      len(frames),
+		Colorspace:  jsonString(image["colorspace"]),
+		Type:        jsonString(image["type"]),
+		Depth:       int(jsonNumber(image["depth"])),
+		Compression: jsonString(image["compression"]),
+		Quality:     int(jsonNumber(image["quality"])),
+		Interlace:   jsonString(image["interlace"]),
+	}
+	if geometry, ok := image["geometry"].(map[string]any); ok {
+		id.Width, id.Height = int(jsonNumber(geometry["width"])), int(jsonNumber(geometry["height"]))
+	}
+	id.DisplayWidth, id.DisplayHeight = orientedSize(id.Width, id.Height, id.Orientation)
+	if resolution, ok := image["resolution"].(map[string]any); ok {
+		id.ResolutionX, id.ResolutionY = jsonNumber(resolution["x"]), jsonNumber(resolution["y"])
+		id.ResolutionUnits = jsonString(image["units"])
+	}
+
+	if properties, ok := image["properties"].(map[string]any); ok {
+		id.Exif, id.Properties = map[string]string{}, map[string]string{}
+		for key, value := range properties {
+			if tag, ok := strings.CutPrefix(key, "exif:"); ok {
+				id.Exif[tag] = jsonString(value)
+			} else {
+				id.Properties[key] = jsonString(value)
+			}
+		}
+	}
+	if profiles, ok := image["profiles"].(map[string]any); ok {
+		id.Profiles = map[string]int{}
+		for name, profile := range profiles {
+			p, _ := profile.(map[string]any)
+			id.Profiles[name] = int(jsonNumber(p["length"]))
+		}
+	}
+	if channels, ok := image["channelStatistics"].(map[string]any); ok {
+		id.ChannelStatistics = map[string]models.ImageChannelStatistics{}
+		for name, channel := range channels {
+			stats, ok := channel.(map[string]any)
+			if !ok {
+				continue
+			}
+			id.ChannelStatistics[strings.ToLower(name)] = models.ImageChannelStatistics{
+				Min:               jsonNumber(stats["min"]),
+				Max:               jsonNumber(stats["max"]),
+				Mean:              jsonNumber(stats["mean"]),
+				StandardDeviation: jsonNumber(stats["standardDeviation"]),
+				Entropy:           jsonNumber(stats["entropy"]),
+			}
+		}
+	}
+	_, alphaChannel := id.ChannelStatistics["alpha"]
+	id.HasAlpha = alphaChannel || strings.Contains(id.Type, "Alpha") || strings.Contains(id.Type, "Matte")
+	id.Histogram = imageHistogram(image["histogram"])
+	return id
+}
+
+// imageHistogram reads the histogram ImageMagick includes for images with few
+// colours: an array of {"count", "color"|"hex"} entries in ImageMagick 7, a
+// colour → count object in ImageMagick 6. Entries are sorted most common
+// first.
+func imageHistogram(raw any) []models.ImageHistogramEntry {
+	var entries []models.ImageHistogramEntry
+	switch h := raw.(type) {
+	case []any:
+		for _, item := range h {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			color := jsonString(entry["color"])
+			if color == "" {
+				color = jsonString(entry["hex"])
+			}
+			entries = append(entries, models.ImageHistogramEntry{Color: color, Count: int64(jsonNumber(entry["count"]))})
+		}
+	case map[string]any:
+		for color, count := range h {
+			entries = append(entries, models.ImageHistogramEntry{Color: color, Count: int64(jsonNumber(count))})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Color < entries[j].Color
+	})
+	return entries
+}
+
+// orientedSize is the size a w x h image displays at once auto-oriented.
+// EXIF orientations 5–8 turn the picture a quarter, so a portrait phone photo
+// stored as 4032x3024 displays at 3024x4032.
+func orientedSize(w, h int, orientation string) (int, int) {
+	switch orientation {
+	case "LeftTop", "RightTop", "RightBottom", "LeftBottom":
+		return h, w
+	}
+	return w, h
+}
+
+// jsonNumber reads a number ImageMagick may print either bare or quoted.
+func jsonNumber(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f
+	}
+	return 0
+}
+
+func jsonString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+const im7PhotoJSON = `[{
+  "version": "1.0",
+  "image": {
+    "name": "-",
+    "format": "JPEG",
+    "mimeType": "image/jpeg",
+    "geometry": {"width": 4032, "height": 3024, "x": 0, "y": 0},
+    "resolution": {"x": 72, "y": 72},
+    "units": "PixelsPerInch",
+    "type": "TrueColor",
+    "colorspace": "sRGB",
+    "depth": 8,
+    "channelStatistics": {
+      "red": {"min": 0, "max": 255, "mean": 121.5, "standardDeviation": 60.2, "entropy": 0.93},
+      "green": {"min": 3, "max": 250, "mean": 110, "standardDeviation": 55.1, "entropy": 0.91}
+    },
+    "interlace": "None",
+    "compression": "JPEG",
+    "quality": 92,
+    "orientation": "RightTop",
+    "properties": {
+      "date:create": "2026-01-01T00:00:00+00:00",
+      "exif:Make": "Apple",
+      "exif:Model": "iPhone 15"
+    },
+    "profiles": {"exif": {"length": 2940}, "icc": {"length": 548}}
+  }
+}]`
+
+const im6AnimationJSON = `{
+  "image": {
+    "format": "GIF",
+    "mimeType": "image/gif",
+    "geometry": {"width": "64", "height": "32", "x": "0", "y": "0"},
+    "type": "PaletteAlpha",
+    "colorspace": "sRGB",
+    "depth": "8",
+    "histogram": {"srgba(0,0,0,0)": "100", "srgba(255,0,0,1)": "1948"}
+  }
+}
+{
+  "image": {
+    "format": "GIF",
+    "geometry": {"width": "64", "height": "32", "x": "0", "y": "0"}
+  }
+}`
+
+func TestIdentificationFromIM7JSON(t *testing.T) {
+	frames, err := parseImageMagickJSON(im7PhotoJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := identificationFromJSON(frames)
+	if id.Format != "JPEG" || id.MimeType != "image/jpeg" || id.Frames != 1 || id.Quality != 92 || id.Depth != 8 {
+		t.Fatalf("identification = %+v", id)
+	}
+	if id.Width != 4032 || id.Height != 3024 || id.DisplayWidth != 3024 || id.DisplayHeight != 4032 {
+		t.Fatalf("dimensions = %dx%d display %dx%d", id.Width, id.Height, id.DisplayWidth, id.DisplayHeight)
+	}
+	if id.Exif["Make"] != "Apple" || id.Exif["Model"] != "iPhone 15" || len(id.Exif) != 2 || id.Properties["date:create"] == "" {
+		t.Fatalf("exif = %v, properties = %v", id.Exif, id.Properties)
+	}
+	if id.Profiles["icc"] != 548 || id.ResolutionX != 72 || id.ResolutionUnits != "PixelsPerInch" || id.HasAlpha {
+		t.Fatalf("identification = %+v", id)
+	}
+	if red := id.ChannelStatistics["red"]; red.Max != 255 || red.Mean != 121.5 || red.StandardDeviation != 60.2 {
+		t.Fatalf("red statistics = %+v", red)
+	}
+}
+
+func TestIdentificationFromIM6JSON(t *testing.T) {
+	frames, err := parseImageMagickJSON(im6AnimationJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := identificationFromJSON(frames)
+	if id.Frames != 2 || id.Width != 64 || id.Height != 32 || id.DisplayWidth != 64 || id.Depth != 8 || !id.HasAlpha {
+		t.Fatalf("identification = %+v", id)
+	}
+	want := []models.ImageHistogramEntry{{Color: "srgba(255,0,0,1)", Count: 1948}, {Color: "srgba(0,0,0,0)", Count: 100}}
+	if len(id.Histogram) != 2 || id.Histogram[0] != want[0] || id.Histogram[1] != want[1] {
+		t.Fatalf("histogram = %v", id.Histogram)
+	}
+}
+
+func TestParseImageMagickJSONCommaSeparatedFrames(t *testing.T) {
+	frames, err := parseImageMagickJSON(`{"image": {"format": "GIF"}},` + "\n" + `{"image": {"format": "GIF"}}`)
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("frames = %v, %v", frames, err)
+	}
+	if _, err := parseImageMagickJSON("identify: no decode delegate"); err == nil {
+		t.Fatal("expected an error for non-JSON output")
+	}
+}
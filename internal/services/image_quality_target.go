@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Visual quality targeting: rather than encoding at a fixed quality number,
+// binary-search the encoder quality for the lowest setting whose output stays
+// within a perceptual distance of the processed image. Lower quality means a
+// smaller file, so the first passing setting is the smallest file at that
+// perceived quality.
+//
+// Distances are measured against a lossless PNG render of the fully
+// processed image (crop, resize, filters, overlay), so the search only
+// measures encoder loss.
+
+const (
+	QualityTargetDSSIM       = "dssim"
+	QualityTargetButteraugli = "butteraugli"
+
+	defaultDSSIMTarget       = 0.0015
+	defaultButteraugliTarget = 1.0
+	defaultTargetMinQuality  = 30
+	defaultTargetMaxQuality  = 95
+)
+
+var (
+	firstFloatPattern       = regexp.MustCompile(`[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+	normalizedMetricPattern = regexp.MustCompile(`\(([^)]+)\)`)
+)
+
+func supportsQualityTarget(format string) bool {
+	switch strings.ToLower(format) {
+	case "webp", "avif", "jpg", "jpeg":
+		return true
+	}
+	return false
+}
+
+// resolveQualityTarget fills in defaults and validates the option.
+func resolveQualityTarget(t *models.ImageQualityTarget, format string) (*models.ImageQualityTarget, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if !supportsQualityTarget(format) {
+		return nil, fmt.Errorf("qualityTarget is only supported for webp, avif and jpg output, got %s", format)
+	}
+	r := *t
+	r.Metric = strings.ToLower(strings.TrimSpace(r.Metric))
+	switch r.Metric {
+	case "", QualityTargetDSSIM:
+		r.Metric = QualityTargetDSSIM
+		if r.MaxDistance == 0 {
+			r.MaxDistance = defaultDSSIMTarget
+		}
+		if r.MaxDistance < 0 || r.MaxDistance >= 1 {
+			return nil, fmt.Errorf("qualityTarget.maxDistance for dssim must be between 0 and 1, got %v", r.MaxDistance)
+		}
+	case QualityTargetButteraugli:
+		if r.MaxDistance == 0 {
+			r.MaxDistance = defaultButteraugliTarget
+		}
+		if r.MaxDistance < 0 || r.MaxDistance > 20 {
+			return nil, fmt.Errorf("qualityTarget.maxDistance for butteraugli must be between 0 and 20, got %v", r.MaxDistance)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported qualityTarget.metric: %s (expected dssim|butteraugli)", r.Metric)
+	}
+	if math.IsNaN(r.MaxDistance) {
+		return nil, errors.New("qualityTarget.maxDistance must be a number")
+	}
+	if r.MinQuality == 0 {
+		r.MinQuality = defaultTargetMinQuality
+	}
+	if r.MaxQuality == 0 {
+		r.MaxQuality = defaultTargetMaxQuality
+	}
+	if r.MinQuality < 1 || r.MaxQuality > 100 || r.MinQuality > r.MaxQuality {
+		return nil, fmt.Errorf("qualityTarget quality range must satisfy 1 <= minQuality <= maxQuality <= 100, got %d-%d", r.MinQuality, r.MaxQuality)
+	}
+	return &r, nil
+}
+
+// encodeToQualityTarget encodes referencePath into outputPath at the lowest
+// quality in the target's range that meets its distance. If even the top of
+// the range misses, that encode is kept and TargetMet is false. encodeArgs
+// are the encoder settings every candidate is written with.
+func (c *Converter) encodeToQualityTarget(ctx context.Context, jobID, referencePath, outputPath string, t *models.ImageQualityTarget, encodeArgs []string) (*models.QualityMetricsResult, error) {
+	measure, err := qualityTargetMeasurer(t.Metric)
+	if err != nil {
+		return nil, err
+	}
+	workDir := filepath.Dir(referencePath)
+	ext := filepath.Ext(outputPath)
+
+	encode := func(quality int) (string, float64, error) {
+		candidate := filepath.Join(workDir, fmt.Sprintf("q%03d%s", quality, ext))
+		args := append([]string{referencePath, "-quality", strconv.Itoa(quality)}, encodeArgs...)
+		if err := c.runImageMagickWithProgress(jobID, "convert", append(args, candidate)...); err != nil {
+			return "", 0, err
+		}
+		distance, err := measure(ctx, referencePath, candidate)
+		if err != nil {
+			return "", 0, err
+		}
+		c.jobLogger(jobID).Debug("quality target step", "quality", quality, "metric", t.Metric, "distance", distance)
+		return candidate, distance, nil
+	}
+
+	lo, hi := t.MinQuality, t.MaxQuality
+	best, bestPath, bestDistance := -1, "", 0.0
+	steps := 0
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		candidate, distance, err := encode(mid)
+		if err != nil {
+			return nil, err
+		}
+		if distance <= t.MaxDistance {
+			best, bestPath, bestDistance = mid, candidate, distance
+			hi = mid - 1
+		} else {
+			lo = mid + 1
+		}
+		steps++
+		if c.jobManager != nil {
+			c.jobManager.SendProgressUpdate(jobID, min(95, 80+steps*2))
+		}
+	}
+
+	met := best >= 0
+	if !met {
+		best = t.MaxQuality
+		bestPath, bestDistance, err = encode(best)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := moveFile(bestPath, outputPath); err != nil {
+		return nil, err
+	}
+
+	result := &models.QualityMetricsResult{EncoderQuality: &best, TargetMet: &met}
+	if t.Metric == QualityTargetButteraugli {
+		result.Butteraugli = &bestDistance
+	} else {
+		result.DSSIM = &bestDistance
+	}
+	return result, nil
+}
+
+type distanceFunc func(ctx context.Context, referencePath, candidatePath string) (float64, error)
+
+// qualityTargetMeasurer picks the tool for a metric. DSSIM prefers the
+// standalone dssim tool and falls back to ImageMagick's DSSIM metric (a
+// different scale: (1-SSIM)/2); butteraugli needs libjxl's butteraugli_main
+// or the original butteraugli binary.
+func qualityTargetMeasurer(metric string) (distanceFunc, error) {
+	if metric == QualityTargetButteraugli {
+		for _, tool := range []string{"butteraugli_main", "butteraugli"} {
+			if _, err := exec.LookPath(tool); err == nil {
+				return pngToolDistance(tool), nil
+			}
+		}
+		return nil, errors.New("butteraugli quality targeting requires butteraugli_main (libjxl) or butteraugli on PATH")
+	}
+	if _, err := exec.LookPath("dssim"); err == nil {
+		return pngToolDistance("dssim"), nil
+	}
+	return imageMagickDSSIM, nil
+}
+
+// pngToolDistance runs a `tool reference.png candidate.png` comparer. These
+// tools only read PNG, so the candidate is decoded first.
+func pngToolDistance(tool string) distanceFunc {
+	return func(ctx context.Context, referencePath, candidatePath string) (float64, error) {
+		decoded := strings.TrimSuffix(candidatePath, filepath.Ext(candidatePath)) + ".decoded.png"
+		defer os.Remove(decoded)
+		name, args := resolveImageMagickConvertCommand("convert", []string{candidatePath, decoded})
+		if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+			return 0, fmt.Errorf("decode candidate: %w (%s)", err, commandTail(stderr, 300))
+		}
+		stdout, stderr, err := runCommand(ctx, tool, referencePath, decoded)
+		if err != nil {
+			return 0, fmt.Errorf("%s failed: %w (%s)", tool, err, commandTail(stderr, 300))
+		}
+		return parseFirstFloat(stdout)
+	}
+}
+
+// imageMagickDSSIM uses `compare -metric DSSIM`, which prints the distance
+// on stderr and exits 1 whenever the images differ at all. Newer releases
+// print "absolute (normalized)"; the normalized value is the one used.
+func imageMagickDSSIM(ctx context.Context, referencePath, candidatePath string) (float64, error) {
+	name, args := "compare", []string{"-metric", "DSSIM", referencePath, candidatePath, "null:"}
+	if _, err := exec.LookPath("magick"); err == nil {
+		name, args = "magick", append([]string{"compare"}, args...)
+	}
+	_, stderr, err := runCommand(ctx, name, args...)
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return 0, fmt.Errorf("compare failed: %w (%s)", err, commandTail(stderr, 300))
+	}
+	if m := normalizedMetricPattern.FindStringSubmatch(stderr); m != nil {
+		return parseFirstFloat(m[1])
+	}
+	return parseFirstFloat(stderr)
+}
+
+func parseFirstFloat(s string) (float64, error) {
+	match := firstFloatPattern.FindString(s)
+	if match == "" {
+		return 0, fmt.Errorf("no distance in output %q", commandTail(s, 200))
+	}
+	return strconv.ParseFloat(match, 64)
+}
+
+// moveFile renames src to dst, copying when they are on different
+// filesystems (the search runs in TEMP_DIR).
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestResolveQualityTarget(t *testing.T) {
+	got, err := resolveQualityTarget(&models.ImageQualityTarget{}, "webp")
+	if err != nil {
+		t.Fatalf("defaults rejected: %v", err)
+	}
+	if got.Metric != QualityTargetDSSIM || got.MaxDistance != defaultDSSIMTarget ||
+		got.MinQuality != defaultTargetMinQuality || got.MaxQuality != defaultTargetMaxQuality {
+		t.Fatalf("unexpected defaults: %+v", got)
+	}
+
+	got, err = resolveQualityTarget(&models.ImageQualityTarget{Metric: "Butteraugli", MinQuality: 50}, "avif")
+	if err != nil {
+		t.Fatalf("butteraugli rejected: %v", err)
+	}
+	if got.Metric != QualityTargetButteraugli || got.MaxDistance != defaultButteraugliTarget || got.MinQuality != 50 {
+		t.Fatalf("unexpected butteraugli target: %+v", got)
+	}
+
+	if got, err := resolveQualityTarget(nil, "png"); got != nil || err != nil {
+		t.Fatalf("nil target should resolve to nil, got %+v, %v", got, err)
+	}
+
+	for name, tc := range map[string]struct {
+		target models.ImageQualityTarget
+		format string
+	}{
+		"png output":       {models.ImageQualityTarget{}, "png"},
+		"unknown metric":   {models.ImageQualityTarget{Metric: "psnr"}, "webp"},
+		"dssim too large":  {models.ImageQualityTarget{MaxDistance: 1.5}, "webp"},
+		"negative":         {models.ImageQualityTarget{Metric: "butteraugli", MaxDistance: -1}, "jpg"},
+		"inverted range":   {models.ImageQualityTarget{MinQuality: 80, MaxQuality: 60}, "webp"},
+		"quality over 100": {models.ImageQualityTarget{MaxQuality: 101}, "avif"},
+	} {
+		target := tc.target
+		if _, err := resolveQualityTarget(&target, tc.format); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseFirstFloat(t *testing.T) {
+	cases := map[string]float64{
+		"0.00123\tq050.webp\n":            0.00123,
+		"1.2345678\n3-norm: 0.512\n":      1.2345678,
+		"4.2e-05 /tmp/q030.decoded.png\n": 4.2e-05,
+	}
+	for in, want := range cases {
+		got, err := parseFirstFloat(in)
+		if err != nil || got != want {
+			t.Errorf("parseFirstFloat(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseFirstFloat("compare: unable to open image"); err == nil {
+		t.Error("expected an error for output without a number")
+	}
+}
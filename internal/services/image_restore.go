@@ -40,6 +40,7 @@ type ImageRestoreService struct {
 func NewImageRestoreService(cfg *config.Config, jm *JobManager, gpuMgr *gpu.Manager, store *telemetry.Store, runner *cmdaudit.Runner) *ImageRestoreService {
 	if runner == nil {
 		runner = cmdaudit.NewRunner(nil, nil)
+		runner.Command = limitedCommand
 	}
 	n := cfg.ImageRestoreMaxConcurrentJobs
 	if n <= 0 {
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// ImageSequenceToVideoService renders an ordered set of still images into a
+// single H.264 MP4. Two shapes of output fall out of the same options:
+//
+//   - timelapse: a high fps (e.g. 24) and no transition — every image is one
+//     frame, fed through FFmpeg's concat demuxer
+//   - slideshow: a low fps (e.g. 0.25 = four seconds per image), optionally
+//     with a crossfade between slides built from looped image inputs + xfade
+//
+// An optional background audio track is muxed on top and trimmed to the
+// video length.
+type ImageSequenceToVideoService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewImageSequenceToVideoService(cfg *config.Config, jm *JobManager) *ImageSequenceToVideoService {
+	return &ImageSequenceToVideoService{cfg: cfg, jobManager: jm}
+}
+
+const (
+	// ImageSequenceMaxImages bounds the number of frames/slides in one job.
+	ImageSequenceMaxImages = 500
+	// imageSequenceMaxFadeImages bounds the crossfade path, which opens one
+	// FFmpeg input per image and keeps every decoder alive for the graph.
+	imageSequenceMaxFadeImages = 60
+
+	imageSequenceMinFPS        = 0.05
+	imageSequenceMaxFPS        = 60.0
+	imageSequenceOutputFPS     = 30
+	imageSequenceDefaultWidth  = 1920
+	imageSequenceDefaultHeight = 1080
+	imageSequenceMaxDimension  = 4096
+)
+
+// imageSequenceExtensions lists the still formats we accept, both as direct
+// uploads and as members of a ZIP archive.
+var imageSequenceExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".bmp": true, ".tif": true, ".tiff": true,
+}
+
+// IsImageSequenceFile reports whether name has one of the accepted still
+// image extensions.
+func IsImageSequenceFile(name string) bool {
+	return imageSequenceExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// ImageSequenceOptions captures the validated render parameters.
+type ImageSequenceOptions struct {
+	// FPS is images per second. 24 gives a timelapse; 0.25 holds each slide
+	// for four seconds. Default 1.
+	FPS float64 `json:"fps"`
+	// Transition: none (default) or fade (crossfade between slides).
+	Transition string `json:"transition"`
+	// TransitionDuration in seconds; must be shorter than one slide.
+	TransitionDuration float64 `json:"transitionDuration"`
+	// Width / Height of the output frame. Images are letterboxed to fit.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Background is the pad color as #RRGGBB. Default black.
+	Background string `json:"background"`
+}
+
+func (o *ImageSequenceOptions) applyDefaults() error {
+	if o.FPS == 0 {
+		o.FPS = 1
+	}
+	if math.IsNaN(o.FPS) || math.IsInf(o.FPS, 0) || o.FPS < imageSequenceMinFPS || o.FPS > imageSequenceMaxFPS {
+		return fmt.Errorf("invalid fps: %v (allowed %v-%v)", o.FPS, imageSequenceMinFPS, imageSequenceMaxFPS)
+	}
+	o.Transition = strings.ToLower(strings.TrimSpace(o.Transition))
+	if o.Transition == "" {
+		o.Transition = "none"
+	}
+	switch o.Transition {
+	case "none":
+		o.TransitionDuration = 0
+	case "fade":
+		if o.TransitionDuration == 0 {
+			o.TransitionDuration = 0.5
+		}
+		slide := 1 / o.FPS
+		if math.IsNaN(o.TransitionDuration) || o.TransitionDuration < 0.1 || o.TransitionDuration >= slide {
+			return fmt.Errorf("invalid transitionDuration: %v (must be at least 0.1s and shorter than one slide, %.2fs)", o.TransitionDuration, slide)
+		}
+	default:
+		return fmt.Errorf("invalid transition: %q (expected none|fade)", o.Transition)
+	}
+	if o.Width == 0 {
+		o.Width = imageSequenceDefaultWidth
+	}
+	if o.Height == 0 {
+		o.Height = imageSequenceDefaultHeight
+	}
+	if o.Width < 16 || o.Width > imageSequenceMaxDimension || o.Height < 16 || o.Height > imageSequenceMaxDimension {
+		return fmt.Errorf("invalid dimensions %dx%d (allowed 16-%d)", o.Width, o.Height, imageSequenceMaxDimension)
+	}
+	// libx264 + yuv420p needs even dimensions.
+	o.Width -= o.Width % 2
+	o.Height -= o.Height % 2
+	if o.Background == "" {
+		o.Background = "#000000"
+	}
+	if !hexColorRegexp.MatchString(o.Background) {
+		return fmt.Errorf("invalid background color: %q (expected #RRGGBB)", o.Background)
+	}
+	return nil
+}
+
+// ImageSequenceRequest is the ready-to-run job: the ordered, already-staged
+// image paths plus an optional background audio path.
+type ImageSequenceRequest struct {
+	Images    []string
+	AudioPath string
+	Options   ImageSequenceOptions
+}
+
+// ValidateImageSequenceRequest checks the request shape before a job is
+// created, so clients get a synchronous 400 instead of a failed job.
+func ValidateImageSequenceRequest(req *ImageSequenceRequest) error {
+	if len(req.Images) == 0 {
+		return errors.New("at least one image is required")
+	}
+	if len(req.Images) > ImageSequenceMaxImages {
+		return fmt.Errorf("too many images: %d (max %d)", len(req.Images), ImageSequenceMaxImages)
+	}
+	if err := req.Options.applyDefaults(); err != nil {
+		return err
+	}
+	if req.Options.Transition == "fade" && len(req.Images) > imageSequenceMaxFadeImages {
+		return fmt.Errorf("fade transitions support at most %d images (got %d) — use transition=none for longer sequences", imageSequenceMaxFadeImages, len(req.Images))
+	}
+	return nil
+}
+
+// Render builds the video. Per-image durations are derived from FPS, and the
+// frame is normalized (scale-to-fit + pad + square pixels) so mixed-size
+// sources render cleanly.
+func (s *ImageSequenceToVideoService) Render(ctx context.Context, job *models.ConversionJob, outputPath string, req ImageSequenceRequest) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+	if err := ValidateImageSequenceRequest(&req); err != nil {
+		return err
+	}
+	s.progress(job.ID, 10)
+
+	var args []string
+	if req.Options.Transition == "fade" && len(req.Images) > 1 {
+		args = buildImageSequenceFadeArgs(req, outputPath)
+	} else {
+		listPath := filepath.Join(filepath.Dir(outputPath), "sequence.txt")
+		if err := os.WriteFile(listPath, []byte(buildImageSequenceConcatList(req.Images, 1/req.Options.FPS)), 0o644); err != nil {
+			return fmt.Errorf("write concat list: %w", err)
+		}
+		defer os.Remove(listPath)
+		args = buildImageSequenceConcatArgs(req, listPath, outputPath)
+	}
+	s.progress(job.ID, 25)
+
+	if _, stderr, err := runCommand(ctx, "ffmpeg", args...); err != nil {
+		return fmt.Errorf("ffmpeg image sequence render failed: %w (%s)", err, tail(stderr, 1500))
+	}
+	s.progress(job.ID, 100)
+	return nil
+}
+
+// imageSequenceFrameFilter scales each image to fit inside WxH, pads the
+// remainder with the background color and pins the pixel format.
+func imageSequenceFrameFilter(o ImageSequenceOptions) string {
+	return fmt.Sprintf(
+		"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=%s,setsar=1,fps=%d,format=yuv420p",
+		o.Width, o.Height, o.Width, o.Height, hexToFFmpegColor(o.Background), imageSequenceOutputFPS,
+	)
+}
+
+// buildImageSequenceConcatList renders a concat-demuxer script. The final
+// entry is repeated because the demuxer ignores the last duration directive.
+func buildImageSequenceConcatList(images []string, perImage float64) string {
+	var sb strings.Builder
+	sb.WriteString("ffconcat version 1.0\n")
+	dur := strconv.FormatFloat(perImage, 'f', 4, 64)
+	for _, img := range images {
+		fmt.Fprintf(&sb, "file %s\nduration %s\n", concatQuote(img), dur)
+	}
+	fmt.Fprintf(&sb, "file %s\n", concatQuote(images[len(images)-1]))
+	return sb.String()
+}
+
+// concatQuote escapes a path for the concat demuxer's single-quoted syntax.
+func concatQuote(p string) string {
+	return "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
+
+func buildImageSequenceConcatArgs(req ImageSequenceRequest, listPath, outputPath string) []string {
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", listPath}
+	if req.AudioPath != "" {
+		args = append(args, "-i", req.AudioPath)
+	}
+	args = append(args, "-vf", imageSequenceFrameFilter(req.Options), "-map", "0:v:0")
+	return appendImageSequenceOutputArgs(args, req, outputPath)
+}
+
+// buildImageSequenceFadeArgs loops each image for one slide duration and
+// chains xfade between consecutive slides. Each xfade shortens the running
+// total by the transition length, so the k-th offset is k*(slide-fade).
+func buildImageSequenceFadeArgs(req ImageSequenceRequest, outputPath string) []string {
+	o := req.Options
+	slide := 1 / o.FPS
+	slideArg := strconv.FormatFloat(slide, 'f', 4, 64)
+	args := []string{"-y"}
+	for _, img := range req.Images {
+		args = append(args, "-loop", "1", "-t", slideArg, "-i", img)
+	}
+	if req.AudioPath != "" {
+		args = append(args, "-i", req.AudioPath)
+	}
+
+	frame := imageSequenceFrameFilter(o)
+	parts := make([]string, 0, 2*len(req.Images))
+	for i := range req.Images {
+		parts = append(parts, fmt.Sprintf("[%d:v]%s[s%d]", i, frame, i))
+	}
+	prev := "s0"
+	for k := 1; k < len(req.Images); k++ {
+		out := fmt.Sprintf("x%d", k)
+		if k == len(req.Images)-1 {
+			out = "vout"
+		}
+		offset := float64(k) * (slide - o.TransitionDuration)
+		parts = append(parts, fmt.Sprintf("[%s][s%d]xfade=transition=fade:duration=%s:offset=%s[%s]",
+			prev, k, strconv.FormatFloat(o.TransitionDuration, 'f', 3, 64), strconv.FormatFloat(offset, 'f', 4, 64), out))
+		prev = out
+	}
+	args = append(args, "-filter_complex", strings.Join(parts, ";"), "-map", "[vout]")
+	return appendImageSequenceOutputArgs(args, req, outputPath)
+}
+
+// appendImageSequenceOutputArgs adds the optional audio mapping and the
+// shared H.264/AAC encode settings. The audio input is always the last one.
+func appendImageSequenceOutputArgs(args []string, req ImageSequenceRequest, outputPath string) []string {
+	if req.AudioPath != "" {
+		audioIdx := 1
+		if req.Options.Transition == "fade" && len(req.Images) > 1 {
+			audioIdx = len(req.Images)
+		}
+		args = append(args, "-map", fmt.Sprintf("%d:a:0", audioIdx), "-c:a", "aac", "-b:a", "192k", "-shortest")
+	}
+	args = append(args,
+		"-c:v", "libx264",
+		"-preset", "medium",
+		"-crf", "20",
+		"-pix_fmt", "yuv420p",
+		"-r", strconv.Itoa(imageSequenceOutputFPS),
+		"-movflags", "+faststart",
+		outputPath,
+	)
+	return args
+}
+
+// ExtractImageSequenceArchive unpacks the image members of a ZIP into destDir
+// and returns their paths sorted by archive name. Directory structure is
+// flattened (guarding against zip-slip) and non-image members are skipped.
+// maxBytes caps the total uncompressed size to defend against zip bombs.
+func ExtractImageSequenceArchive(zipPath, destDir string, maxBytes int64) ([]string, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+	defer zr.Close()
+
+	type member struct {
+		name string
+		file *zip.File
+	}
+	members := make([]member, 0, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		base := filepath.Base(f.Name)
+		// Skip macOS resource forks and dotfiles that ride along in archives.
+		if strings.HasPrefix(base, ".") || strings.Contains(f.Name, "__MACOSX") || !IsImageSequenceFile(base) {
+			continue
+		}
+		members = append(members, member{name: f.Name, file: f})
+	}
+	if len(members) == 0 {
+		return nil, errors.New("zip contains no supported images (jpg, png, webp, bmp, tiff)")
+	}
+	if len(members) > ImageSequenceMaxImages {
+		return nil, fmt.Errorf("zip contains too many images: %d (max %d)", len(members), ImageSequenceMaxImages)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, err
+	}
+	var total int64
+	paths := make([]string, 0, len(members))
+	for i, m := range members {
+		// Prefix with the sorted index so identically-named files from
+		// different folders can't collide once flattened.
+		dest := filepath.Join(destDir, fmt.Sprintf("%05d_%s", i, filepath.Base(m.name)))
+		n, err := extractZipMember(m.file, dest, maxBytes-total)
+		if err != nil {
+			return nil, err
+		}
+		total += n
+		paths = append(paths, dest)
+	}
+	return paths, nil
+}
+
+func extractZipMember(f *zip.File, dest string, remaining int64) (int64, error) {
+	if remaining <= 0 {
+		return 0, errors.New("zip contents exceed the maximum upload size")
+	}
+	src, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("read %s from zip: %w", f.Name, err)
+	}
+	defer src.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, io.LimitReader(src, remaining+1))
+	if err != nil {
+		return n, fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	if n > remaining {
+		return n, errors.New("zip contents exceed the maximum upload size")
+	}
+	return n, nil
+}
+
+func (s *ImageSequenceToVideoService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageSequenceOptions_Defaults(t *testing.T) {
+	o := ImageSequenceOptions{}
+	if err := o.applyDefaults(); err != nil {
+		t.Fatalf("applyDefaults: %v", err)
+	}
+	if o.FPS != 1 || o.Transition != "none" {
+		t.Fatalf("defaults wrong: fps=%v transition=%q", o.FPS, o.Transition)
+	}
+	if o.Width != imageSequenceDefaultWidth || o.Height != imageSequenceDefaultHeight {
+		t.Fatalf("default dims = %dx%d", o.Width, o.Height)
+	}
+}
+
+func TestImageSequenceOptions_FadeMustFitInSlide(t *testing.T) {
+	o := ImageSequenceOptions{FPS: 1, Transition: "fade", TransitionDuration: 1}
+	if err := o.applyDefaults(); err == nil {
+		t.Fatalf("expected rejection of a fade as long as the slide")
+	}
+	o = ImageSequenceOptions{FPS: 0.5, Transition: "fade", TransitionDuration: 1}
+	if err := o.applyDefaults(); err != nil {
+		t.Fatalf("valid fade rejected: %v", err)
+	}
+}
+
+func TestImageSequenceOptions_RejectsBadInput(t *testing.T) {
+	cases := []ImageSequenceOptions{
+		{FPS: 120},
+		{Transition: "wipe"},
+		{Width: 10000},
+		{Background: "red"},
+	}
+	for _, o := range cases {
+		if err := o.applyDefaults(); err == nil {
+			t.Fatalf("expected rejection of %+v", o)
+		}
+	}
+}
+
+func TestBuildImageSequenceConcatList_RepeatsLastFile(t *testing.T) {
+	list := buildImageSequenceConcatList([]string{"/a/1.jpg", "/a/it's.png"}, 0.5)
+	if strings.Count(list, "duration 0.5000") != 2 {
+		t.Fatalf("expected one duration per image:\n%s", list)
+	}
+	if !strings.HasSuffix(list, "file '/a/it'\\''s.png'\n") {
+		t.Fatalf("last file not repeated / quoted:\n%s", list)
+	}
+}
+
+func TestBuildImageSequenceFadeArgs_Offsets(t *testing.T) {
+	req := ImageSequenceRequest{
+		Images:    []string{"a.jpg", "b.jpg", "c.jpg"},
+		AudioPath: "music.mp3",
+		Options:   ImageSequenceOptions{FPS: 0.5, Transition: "fade", TransitionDuration: 0.5},
+	}
+	if err := req.Options.applyDefaults(); err != nil {
+		t.Fatalf("applyDefaults: %v", err)
+	}
+	args := buildImageSequenceFadeArgs(req, "out.mp4")
+	graph := valueAfter(args, "-filter_complex")
+	if !strings.Contains(graph, "offset=1.5000[x1]") || !strings.Contains(graph, "offset=3.0000[vout]") {
+		t.Fatalf("unexpected xfade offsets: %s", graph)
+	}
+	if got := countFlag(args, "-loop"); got != 3 {
+		t.Fatalf("expected 3 looped inputs, got %d", got)
+	}
+	if !strings.Contains(strings.Join(args, " "), "-map 3:a:0") {
+		t.Fatalf("audio should map from the input after the images: %v", args)
+	}
+}
+
+func TestExtractImageSequenceArchive_FiltersAndSorts(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "in.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"b.png", "notes.txt", "../evil/a.jpg", "__MACOSX/._a.jpg"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte("data"))
+	}
+	zw.Close()
+	f.Close()
+
+	out := filepath.Join(dir, "out")
+	paths, err := ExtractImageSequenceArchive(zipPath, out, 1<<20)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 images, got %v", paths)
+	}
+	for _, p := range paths {
+		if filepath.Dir(p) != out {
+			t.Fatalf("member escaped destination: %s", p)
+		}
+	}
+	if !strings.HasSuffix(paths[0], "_a.jpg") || !strings.HasSuffix(paths[1], "_b.png") {
+		t.Fatalf("unexpected order: %v", paths)
+	}
+	if _, err := ExtractImageSequenceArchive(zipPath, filepath.Join(dir, "small"), 4); err == nil {
+		t.Fatalf("expected size cap to trip")
+	}
+}
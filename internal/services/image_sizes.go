@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// maxImageSizes caps how many sizes one image job may request.
+const maxImageSizes = 10
+
+// validateImageSizes checks the multi-size (thumbnail set) request. Each
+// size is the longest edge in pixels; the sizes replace maxWidth/maxHeight
+// and only apply to the raster ImageMagick chain.
+func validateImageSizes(options *models.ImageConversionOptions) error {
+	if len(options.Sizes) == 0 {
+		return nil
+	}
+	if len(options.Sizes) > maxImageSizes {
+		return fmt.Errorf("at most %d sizes are allowed, got %d", maxImageSizes, len(options.Sizes))
+	}
+	seen := make(map[int]bool, len(options.Sizes))
+	for _, size := range options.Sizes {
+		if size < 16 || size > 10000 {
+			return fmt.Errorf("sizes must be between 16 and 10000, got %d", size)
+		}
+		if seen[size] {
+			return fmt.Errorf("size %d is listed more than once", size)
+		}
+		seen[size] = true
+	}
+	switch strings.ToLower(strings.TrimSpace(options.Format)) {
+	case "pdf", "svg", "ico":
+		return fmt.Errorf("sizes do not apply to %s output", options.Format)
+	}
+	if options.MaxWidth != nil || options.MaxHeight != nil {
+		return fmt.Errorf("sizes cannot be combined with maxWidth/maxHeight")
+	}
+	if options.QualityTarget != nil {
+		return fmt.Errorf("sizes cannot be combined with qualityTarget")
+	}
+	if options.AI != nil && options.AI.Enabled && isActiveAIOp(options.AI.Operation) {
+		return fmt.Errorf("sizes cannot be combined with AI image operations")
+	}
+	return nil
+}
+
+// imageSizeVariant returns a copy of options that bounds the output to a
+// size x size box.
+func imageSizeVariant(options *models.ImageConversionOptions, size int) models.ImageConversionOptions {
+	variant := *options
+	variant.Sizes = nil
+	variant.MaxWidth, variant.MaxHeight = &size, &size
+	return variant
+}
+
+// imageSizeFileName names one entry of the size archive, e.g. "256px.webp".
+func imageSizeFileName(size int, format string) string {
+	ext := strings.ToLower(strings.TrimSpace(format))
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	return fmt.Sprintf("%dpx.%s", size, ext)
+}
+
+// convertImageSizes renders every requested size from the same input and
+// writes them as a .zip at outputPath.
+func (c *Converter) convertImageSizes(job *models.ConversionJob, options *models.ImageConversionOptions, inputPath, metadataSource, outputPath string, animated bool) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	workDir, err := os.MkdirTemp(filepath.Dir(outputPath), "sizes-")
+	if err != nil {
+		return fmt.Errorf("failed to create sizes workspace: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	produced := make([]string, 0, len(options.Sizes))
+	for i, size := range options.Sizes {
+		variant := imageSizeVariant(options, size)
+		path := filepath.Join(workDir, imageSizeFileName(size, options.Format))
+		args := BuildImageCommand(inputPath, path, &variant)
+		if animated {
+			args = animatedImageArgs(args, options.Format)
+		}
+		if err := c.runImageMagickWithProgress(job.ID, "convert", args...); err != nil {
+			return fmt.Errorf("ImageMagick conversion failed for size %d: %v", size, err)
+		}
+		if err := applyImageMetadataOptions(metadataSource, path, &variant); err != nil {
+			return fmt.Errorf("image metadata update failed: %v", err)
+		}
+		produced = append(produced, path)
+		if c.jobManager != nil {
+			c.jobManager.SendProgressUpdate(job.ID, 30+60*(i+1)/len(options.Sizes))
+		}
+	}
+	if err := zipFiles(outputPath, produced); err != nil {
+		return fmt.Errorf("package sizes: %v", err)
+	}
+	if c.jobManager != nil {
+		c.jobManager.SendProgressUpdate(job.ID, 100)
+	}
+	return nil
+}
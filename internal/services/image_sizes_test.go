@@ -0,0 +1,54 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageSizes(t *testing.T) {
+	maxWidth := 800
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr string
+	}{
+		{name: "none", options: models.ImageConversionOptions{Format: "png"}},
+		{name: "thumbnail set", options: models.ImageConversionOptions{Format: "webp", Sizes: []int{64, 256, 1024}}},
+		{name: "too small", options: models.ImageConversionOptions{Format: "png", Sizes: []int{8}}, wantErr: "between 16 and 10000"},
+		{name: "duplicate", options: models.ImageConversionOptions{Format: "png", Sizes: []int{64, 64}}, wantErr: "more than once"},
+		{name: "too many", options: models.ImageConversionOptions{Format: "png", Sizes: []int{16, 32, 48, 64, 96, 128, 192, 256, 512, 1024, 2048}}, wantErr: "at most 10"},
+		{name: "pdf output", options: models.ImageConversionOptions{Format: "pdf", Sizes: []int{64}}, wantErr: "pdf output"},
+		{name: "with max size", options: models.ImageConversionOptions{Format: "png", Sizes: []int{64}, MaxWidth: &maxWidth}, wantErr: "maxWidth"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateImageSizes(&tc.options)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want it to mention %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageSizeVariant(t *testing.T) {
+	options := &models.ImageConversionOptions{Format: "jpeg", Quality: 85, Sizes: []int{64, 256}}
+	variant := imageSizeVariant(options, 256)
+	args := strings.Join(BuildImageCommand("in.jpg", "out.jpg", &variant), " ")
+	if !strings.Contains(args, "-resize 256x256>") {
+		t.Fatalf("args = %q, want a 256x256 bound", args)
+	}
+	if len(options.Sizes) != 2 || options.MaxWidth != nil {
+		t.Fatalf("imageSizeVariant modified the original options: %+v", options)
+	}
+	if got := imageSizeFileName(256, "jpeg"); got != "256px.jpg" {
+		t.Fatalf("imageSizeFileName = %q, want 256px.jpg", got)
+	}
+}
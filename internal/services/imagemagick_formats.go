@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	imageMagickFormatsOnce   sync.Once
+	imageMagickFormatsCached map[string]string
+)
+
+// imageMagickFormatModes maps each format ImageMagick lists under
+// `-list format` to its mode ("rw+", "r--", …). Formats that depend on an
+// optional delegate (HEIC via libheif, JXL via libjxl) are only listed when
+// it was compiled in. Probed once.
+func imageMagickFormatModes() map[string]string {
+	imageMagickFormatsOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		name, args := resolveImageMagickConvertCommand("convert", []string{"-list", "format"})
+		stdout, _, err := runCommand(ctx, name, args...)
+		if err != nil {
+			imageMagickFormatsCached = map[string]string{}
+			return
+		}
+		imageMagickFormatsCached = parseImageMagickFormats(stdout)
+	})
+	return imageMagickFormatsCached
+}
+
+// parseImageMagickFormats reads lines such as
+//
+//	HEIC* HEIC      rw+   High Efficiency Image Format (1.17.6)
+func parseImageMagickFormats(list string) map[string]string {
+	modes := map[string]string{}
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields[2]) != 3 || !strings.ContainsAny(fields[2][:1], "r-") {
+			continue
+		}
+		modes[strings.TrimSuffix(fields[0], "*")] = fields[2]
+	}
+	return modes
+}
+
+// imageMagickCanRead and imageMagickCanWrite report delegate support for an
+// ImageMagick format name such as "HEIC" or "JXL".
+func imageMagickCanRead(format string) bool {
+	return strings.HasPrefix(imageMagickFormatModes()[format], "r")
+}
+
+func imageMagickCanWrite(format string) bool {
+	mode := imageMagickFormatModes()[format]
+	return len(mode) == 3 && mode[1] == 'w'
+}
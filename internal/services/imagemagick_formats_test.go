@@ -0,0 +1,23 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImageMagickFormats(t *testing.T) {
+	list := `   Format  Module    Mode  Description
+-------------------------------------------------------------------------------
+      3FR  DNG       r--   Hasselblad CFV/H3D39II Raw Format (0.21.2-Release)
+     HEIC* HEIC      rw+   High Efficiency Image Format (1.17.6)
+      JXL  JXL       rw-   JPEG XL (ISO/IEC 18181) (libjxl 0.10.2)
+      PNG* PNG       rw-   Portable Network Graphics (libpng 1.6.43)
+
+* native blob support
+r read support
+`
+	want := map[string]string{"3FR": "r--", "HEIC": "rw+", "JXL": "rw-", "PNG": "rw-"}
+	if got := parseImageMagickFormats(list); !reflect.DeepEqual(got, want) {
+		t.Fatalf("formats = %v, want %v", got, want)
+	}
+}
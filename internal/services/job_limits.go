@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/logger"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// ErrJobTimeout is the cause of a conversion's commands being stopped once
+// the job has run for JOB_TIMEOUT_SECONDS.
+var ErrJobTimeout = errors.New("job exceeded its maximum processing time (JOB_TIMEOUT_SECONDS)")
+
+// jobContexts holds the context of every conversion between BeginJob and
+// the func it returns. Commands started for the job derive from it, so they
+// share its deadline and process limits.
+type jobContexts struct {
+	mu   sync.Mutex
+	jobs map[string]context.Context
+}
+
+// processLimits are applied to each command a conversion runs.
+type processLimits struct {
+	nice        int
+	memoryBytes int64
+	cpuSeconds  int
+	sandbox     sandbox
+}
+
+type processLimitsKey struct{}
+
+// defaultLimits apply to the commands whose context carries no job's
+// limits; see UseProcessLimits.
+var defaultLimits atomic.Pointer[processLimits]
+
+// UseProcessLimits runs every command the services start under cfg's
+// SANDBOX and per-job process limits, not only a conversion's (see
+// BeginJob): tool, studio, transcode and document-scan jobs, transcription
+// and the probes and analyses of uploads parse untrusted input too. main
+// calls it once at startup.
+func UseProcessLimits(cfg *config.Config) {
+	limits := newProcessLimits(cfg)
+	defaultLimits.Store(&limits)
+}
+
+func newProcessLimits(cfg *config.Config) processLimits {
+	return processLimits{
+		nice:        cfg.JobNice,
+		memoryBytes: cfg.JobMemoryLimitBytes,
+		cpuSeconds:  cfg.JobCPULimitSeconds,
+		sandbox:     newSandbox(cfg),
+	}
+}
+
+// JobContext is the context for the processing of a job other than a
+// conversion: its commands run under the per-job process limits and are
+// killed with ErrJobTimeout once cfg's JobTimeout has passed, and its logs
+// carry the job and request IDs. Start it when the job leaves the queue.
+func JobContext(cfg *config.Config, job *models.ConversionJob) (context.Context, context.CancelFunc) {
+	ctx := logger.WithRequestID(logger.WithJob(context.Background(), job.ID), job.RequestID)
+	ctx = context.WithValue(ctx, processLimitsKey{}, newProcessLimits(cfg))
+	if cfg.JobTimeout > 0 {
+		return context.WithTimeoutCause(ctx, cfg.JobTimeout, ErrJobTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+type networkKey struct{}
+
+// withNetwork lets the commands of ctx reach the network from inside the
+// sandbox, for those that fetch their input: live recordings, URL imports
+// and page screenshots.
+func withNetwork(ctx context.Context) context.Context {
+	return context.WithValue(ctx, networkKey{}, true)
+}
+
+var prlimitMissing sync.Once
+
+// BeginJob starts the clock on a conversion. Until the returned func is
+// called, the job's commands run under JobTimeout and the per-job process
+// limits, and its logs carry the job and request IDs.
+func (c *Converter) BeginJob(job *models.ConversionJob) func() {
+	jobID := job.ID
+	ctx := logger.WithRequestID(logger.WithJob(context.Background(), jobID), job.RequestID)
+	cancel := context.CancelFunc(func() {})
+	if c.cfg != nil {
+		ctx, cancel = JobContext(c.cfg, job)
+	}
+	c.jobContexts.mu.Lock()
+	if c.jobContexts.jobs == nil {
+		c.jobContexts.jobs = map[string]context.Context{}
+	}
+	c.jobContexts.jobs[jobID] = ctx
+	c.jobContexts.mu.Unlock()
+	return func() {
+		cancel()
+		c.jobContexts.mu.Lock()
+		delete(c.jobContexts.jobs, jobID)
+		c.jobContexts.mu.Unlock()
+	}
+}
+
+// RunningJobs counts the conversions between BeginJob and its end.
+func (c *Converter) RunningJobs() int {
+	c.jobContexts.mu.Lock()
+	defer c.jobContexts.mu.Unlock()
+	return len(c.jobContexts.jobs)
+}
+
+// jobLogger is the logger for jobID's processing. While the job runs it
+// carries the request ID of the upload as well.
+func (c *Converter) jobLogger(jobID string) *slog.Logger {
+	c.jobContexts.mu.Lock()
+	ctx, ok := c.jobContexts.jobs[jobID]
+	c.jobContexts.mu.Unlock()
+	if !ok {
+		ctx = logger.WithJob(context.Background(), jobID)
+	}
+	return logger.FromContext(ctx)
+}
+
+// commandContext is the context for one command of jobID: the job's
+// context, when it has begun, bounded by CommandTimeout.
+func (c *Converter) commandContext(jobID string) (context.Context, context.CancelFunc) {
+	c.jobContexts.mu.Lock()
+	parent, ok := c.jobContexts.jobs[jobID]
+	c.jobContexts.mu.Unlock()
+	if !ok {
+		parent = context.Background()
+	}
+	timeout := 6 * time.Hour
+	if c.cfg != nil && c.cfg.CommandTimeout > 0 {
+		timeout = c.cfg.CommandTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// limitedCommand is exec.CommandContext, plus the process limits of the job
+// ctx belongs to, or the defaults set by UseProcessLimits. Limited commands
+// run in their own process group, which is killed as a whole when ctx ends
+// so helpers they started don't outlive them.
+func limitedCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	limits, ok := ctx.Value(processLimitsKey{}).(processLimits)
+	if !ok {
+		defaults := defaultLimits.Load()
+		if defaults == nil {
+			return exec.CommandContext(ctx, name, args...)
+		}
+		limits = *defaults
+	}
+	if ctx.Value(networkKey{}) != nil {
+		limits.sandbox.network = true
+	}
+	name, args = limits.wrap(name, args)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: limits.sandbox.credential()}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd
+}
+
+// LimitedCommand is limitedCommand for the commands run on the services'
+// behalf elsewhere, such as by the restoration pipelines' cmdaudit.Runner.
+func LimitedCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return limitedCommand(ctx, name, args...)
+}
+
+// wrap prefixes the command with prlimit and nice as the limits require,
+// all inside the sandbox when there is one. Without prlimit on PATH the
+// memory and CPU limits are skipped, with a warning logged once.
+func (l processLimits) wrap(name string, args []string) (string, []string) {
+	name, args = l.limit(name, args)
+	return l.sandbox.wrap(name, args)
+}
+
+func (l processLimits) limit(name string, args []string) (string, []string) {
+	var prefix []string
+	if l.memoryBytes > 0 || l.cpuSeconds > 0 {
+		if path, err := exec.LookPath("prlimit"); err == nil {
+			prefix = append(prefix, path)
+			if l.memoryBytes > 0 {
+				prefix = append(prefix, "--as="+strconv.FormatInt(l.memoryBytes, 10))
+			}
+			if l.cpuSeconds > 0 {
+				prefix = append(prefix, "--cpu="+strconv.Itoa(l.cpuSeconds))
+			}
+			prefix = append(prefix, "--")
+		} else {
+			prlimitMissing.Do(func() {
+				slog.Warn("prlimit (util-linux) is not on PATH; JOB_MEMORY_LIMIT_BYTES and JOB_CPU_LIMIT_SECONDS are not applied")
+			})
+		}
+	}
+	if l.nice > 0 {
+		prefix = append(prefix, "nice", "-n", strconv.Itoa(l.nice))
+	}
+	if len(prefix) == 0 {
+		return name, args
+	}
+	wrapped := append(prefix[1:], name)
+	return prefix[0], append(wrapped, args...)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestProcessLimitsWrap(t *testing.T) {
+	args := []string{"-i", "in.mp4", "out.webm"}
+	if name, got := (processLimits{}).wrap("ffmpeg", args); name != "ffmpeg" || !reflect.DeepEqual(got, args) {
+		t.Fatalf("no limits: got %s %v", name, got)
+	}
+	name, got := processLimits{nice: 10}.wrap("ffmpeg", args)
+	want := []string{"-n", "10", "ffmpeg", "-i", "in.mp4", "out.webm"}
+	if name != "nice" || !reflect.DeepEqual(got, want) {
+		t.Fatalf("nice: got %s %v", name, got)
+	}
+	prlimit, err := exec.LookPath("prlimit")
+	if err != nil {
+		t.Skip("prlimit not installed")
+	}
+	name, got = processLimits{nice: 5, memoryBytes: 1 << 30, cpuSeconds: 60}.wrap("ffmpeg", args)
+	want = []string{"--as=1073741824", "--cpu=60", "--", "nice", "-n", "5", "ffmpeg", "-i", "in.mp4", "out.webm"}
+	if name != prlimit || !reflect.DeepEqual(got, want) {
+		t.Fatalf("prlimit: got %s %v", name, got)
+	}
+}
+
+func TestBeginJobTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not installed")
+	}
+	c := &Converter{cfg: &config.Config{CommandTimeout: time.Minute, JobTimeout: 100 * time.Millisecond}}
+	end := c.BeginJob(&models.ConversionJob{ID: "job-1"})
+	ctx, cancel := c.commandContext("job-1")
+	defer cancel()
+	start := time.Now()
+	_, _, err := runCommand(ctx, "sleep", "10")
+	if !errors.Is(err, ErrJobTimeout) {
+		t.Fatalf("err = %v, want ErrJobTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("command ran for %s after the job deadline", elapsed)
+	}
+	end()
+
+	// Once the job has ended its commands only get CommandTimeout.
+	ctx, cancel = c.commandContext("job-1")
+	defer cancel()
+	if _, _, err := runCommand(ctx, "sleep", "0"); err != nil {
+		t.Fatalf("command after end: %v", err)
+	}
+}
+
+func TestJobContextTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not installed")
+	}
+	ctx, cancel := JobContext(&config.Config{JobTimeout: 100 * time.Millisecond, JobNice: 5}, &models.ConversionJob{ID: "job-1"})
+	defer cancel()
+	if limits, ok := ctx.Value(processLimitsKey{}).(processLimits); !ok || limits.nice != 5 {
+		t.Fatalf("limits = %+v", limits)
+	}
+	start := time.Now()
+	if _, _, err := runCommand(ctx, "sleep", "10"); !errors.Is(err, ErrJobTimeout) {
+		t.Fatalf("err = %v, want ErrJobTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("command ran for %s after the job deadline", elapsed)
+	}
+}
+
+func TestDefaultLimitsSandboxToolCommands(t *testing.T) {
+	bin, dir := t.TempDir(), t.TempDir()
+	log := filepath.Join(dir, "bwrap.log")
+	// The fake bwrap records its arguments and plays ffprobe and ffmpeg:
+	// every stream is found and the output, the last argument, is written.
+	scripts := map[string]string{
+		"bwrap":  "#!/bin/sh\necho \"$*\" >> " + log + "\ncase \"$*\" in *\"-- ffprobe\"*) echo 0 ;; *) for a; do out=$a; done; echo mp4 > \"$out\" ;; esac\n",
+		"ffmpeg": "#!/bin/sh\nexit 1\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cfg := &config.Config{Sandbox: "bwrap", TempDir: dir}
+	UseProcessLimits(cfg)
+	t.Cleanup(func() { defaultLimits.Store(nil) })
+
+	// A tool job's commands carry no job limits in their context.
+	inputs := []string{filepath.Join(dir, "a.mp4"), filepath.Join(dir, "b.mp4")}
+	err := NewVideoGridService(cfg, nil).Compose(context.Background(), &models.ConversionJob{ID: "job-1"},
+		inputs, VideoGridOptions{}, filepath.Join(dir, "grid.mp4"))
+	if err != nil {
+		t.Fatalf("Compose: %v", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--unshare-all", "-- ffprobe", "-- ffmpeg"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("sandboxed commands %q lack %q", data, want)
+		}
+	}
+	if strings.Contains(string(data), "--share-net") {
+		t.Errorf("tool commands got the network: %q", data)
+	}
+}
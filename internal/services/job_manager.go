@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -19,6 +21,13 @@ type JobManager struct {
 	// A separate mutex avoids reentrancy with the main jobs lock.
 	subMu       sync.Mutex
 	subscribers map[string][]chan *models.ConversionJob
+
+	// store, when set by UsePersistentStore, records jobs on disk.
+	store *jobStore
+	// usage, when set by TrackUsage, accounts jobs to their API key tenant.
+	usage *UsageTracker
+	// queue holds new conversions while an operator has paused them.
+	queue queueGate
 }
 
 func NewJobManager() *JobManager {
@@ -31,6 +40,61 @@ func NewJobManager() *JobManager {
 	return jm
 }
 
+// UsePersistentStore loads the jobs recorded in dir and records every job
+// there from now on. Call it once at startup, before jobs are created. It
+// returns the loaded jobs that were still pending or processing: their work
+// died with the previous process and the caller has to rerun or fail them.
+func (jm *JobManager) UsePersistentStore(dir string, maxAge time.Duration) ([]*models.ConversionJob, error) {
+	store := &jobStore{dir: dir}
+	jobs, err := store.load(maxAge)
+	if err != nil {
+		return nil, err
+	}
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.store = store
+	var interrupted []*models.ConversionJob
+	for _, job := range jobs {
+		jm.jobs[job.ID] = job
+		if job.Status == models.StatusPending || job.Status == models.StatusProcessing {
+			interrupted = append(interrupted, job)
+		}
+	}
+	return interrupted, nil
+}
+
+// TrackUsage accounts every job with a client (see SetClient) in u. Call it
+// once at startup.
+func (jm *JobManager) TrackUsage(u *UsageTracker) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.usage = u
+}
+
+// Usage returns the tracker set by TrackUsage, or nil.
+func (jm *JobManager) Usage() *UsageTracker {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.usage
+}
+
+func (jm *JobManager) saveLocked(job *models.ConversionJob) {
+	if jm.store != nil {
+		jm.store.save(job)
+	}
+}
+
+// FlushStore writes the job records still queued for the persistent
+// store. main calls it on shutdown so the latest states survive.
+func (jm *JobManager) FlushStore() {
+	jm.mu.RLock()
+	store := jm.store
+	jm.mu.RUnlock()
+	if store != nil {
+		store.flush()
+	}
+}
+
 // Subscribe returns a channel that receives a fresh snapshot of the job after
 // every state change. The channel is buffered so slow consumers don't block
 // the pipeline; bursts beyond the buffer are dropped (the consumer can always
@@ -44,20 +108,26 @@ func (jm *JobManager) Subscribe(jobID string) chan *models.ConversionJob {
 	return ch
 }
 
-// Unsubscribe removes the subscriber channel and closes it.
+// Unsubscribe removes the subscriber channel and closes it. A channel that
+// DeleteJob already closed is left alone.
 func (jm *JobManager) Unsubscribe(jobID string, ch chan *models.ConversionJob) {
 	jm.subMu.Lock()
 	defer jm.subMu.Unlock()
 	subs := jm.subscribers[jobID]
+	found := false
 	for i, existing := range subs {
 		if existing == ch {
 			jm.subscribers[jobID] = append(subs[:i], subs[i+1:]...)
+			found = true
 			break
 		}
 	}
 	if len(jm.subscribers[jobID]) == 0 {
 		delete(jm.subscribers, jobID)
 	}
+	if !found {
+		return
+	}
 	// Drain any pending messages so a Close() doesn't block on a full channel.
 	select {
 	case <-ch:
@@ -101,6 +171,12 @@ func (jm *JobManager) notifySubscribers(jobID string) {
 }
 
 func (jm *JobManager) CreateJob(originalFile models.OriginalFileInfo, options map[string]interface{}) *models.ConversionJob {
+	return jm.CreateOwnedJob("", originalFile, options)
+}
+
+// CreateOwnedJob is CreateJob for a job that belongs to owner (see
+// ConversionJob.Owner).
+func (jm *JobManager) CreateOwnedJob(owner string, originalFile models.OriginalFileInfo, options map[string]interface{}) *models.ConversionJob {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 	if options == nil {
@@ -113,8 +189,10 @@ func (jm *JobManager) CreateJob(originalFile models.OriginalFileInfo, options ma
 		OriginalFile: originalFile,
 		Options:      options,
 		CreatedAt:    time.Now().UTC(),
+		Owner:        owner,
 	}
 	jm.jobs[job.ID] = job
+	jm.saveLocked(job)
 	return job
 }
 
@@ -135,14 +213,19 @@ func (jm *JobManager) UpdateJobStatus(jobID string, status models.JobStatus) err
 		jm.mu.Unlock()
 		return fmt.Errorf("job not found")
 	}
+	now := time.Now().UTC()
+	if status == models.StatusProcessing && job.Status != models.StatusProcessing {
+		job.StartedAt = &now
+	}
 	job.Status = status
-	if status == models.StatusCompleted || status == models.StatusFailed {
-		now := time.Now().UTC()
+	if status.Finished() {
+		jm.finishRun(job, now, job.Error)
 		job.CompletedAt = &now
 		if status == models.StatusCompleted {
 			job.Progress = 100
 		}
 	}
+	jm.saveLocked(job)
 	jm.mu.Unlock()
 	jm.notifySubscribers(jobID)
 	return nil
@@ -155,7 +238,7 @@ func (jm *JobManager) UpdateJobProgress(jobID string, progress int) error {
 		jm.mu.Unlock()
 		return fmt.Errorf("job not found")
 	}
-	if job.Status == models.StatusCompleted || job.Status == models.StatusFailed {
+	if job.Status.Finished() {
 		jm.mu.Unlock()
 		return nil
 	}
@@ -179,6 +262,23 @@ func (jm *JobManager) UpdateJobResult(jobID string, resultURL string) error {
 		return fmt.Errorf("job not found")
 	}
 	job.ResultURL = resultURL
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// SetOriginalFile replaces the job's source file details, for jobs whose
+// input is only known once it has been fetched.
+func (jm *JobManager) SetOriginalFile(jobID string, file models.OriginalFileInfo) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.OriginalFile = file
+	jm.saveLocked(job)
 	jm.mu.Unlock()
 	jm.notifySubscribers(jobID)
 	return nil
@@ -230,6 +330,36 @@ func (jm *JobManager) SetTranscodeReport(jobID string, report *models.VideoProbe
 	return nil
 }
 
+// SetQualityMetrics attaches the post-encode VMAF/PSNR/SSIM scores.
+func (jm *JobManager) SetQualityMetrics(jobID string, metrics *models.QualityMetricsResult) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.QualityMetrics = metrics
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// SetGIFOptimization attaches the before/after sizes of a GIF optimize run.
+func (jm *JobManager) SetGIFOptimization(jobID string, result *models.GIFOptimizationResult) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.GIFOptimization = result
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
 // SetResultMetadata records the S3 key + filename + expiry for a transcode
 // result. The download URL itself goes through UpdateJobResult.
 func (jm *JobManager) SetResultMetadata(jobID, s3Key, fileName string, expiresAt time.Time) error {
@@ -245,6 +375,22 @@ func (jm *JobManager) SetResultMetadata(jobID, s3Key, fileName string, expiresAt
 		exp := expiresAt
 		job.ExpiresAt = &exp
 	}
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// SetOutputMetadata attaches the probed output file to the job.
+func (jm *JobManager) SetOutputMetadata(jobID string, metadata *models.ResultMetadata) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.Result = &models.JobResult{Metadata: metadata}
+	jm.saveLocked(job)
 	jm.mu.Unlock()
 	jm.notifySubscribers(jobID)
 	return nil
@@ -274,12 +420,235 @@ func (jm *JobManager) UpdateJobError(jobID string, errorMsg string) error {
 	job.Error = errorMsg
 	job.Status = models.StatusFailed
 	now := time.Now().UTC()
+	jm.finishRun(job, now, errorMsg)
+	job.CompletedAt = &now
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// MarkInfected ends a job whose upload contains threat, the signature the
+// virus scanner matched.
+func (jm *JobManager) MarkInfected(jobID, threat string) error {
+	jm.mu.Lock()
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.Status = models.StatusInfected
+	job.Threat = threat
+	job.Error = fmt.Sprintf("The uploaded file is infected (%s)", threat)
+	now := time.Now().UTC()
 	job.CompletedAt = &now
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// StartAttempt records the start of a run of the job. trigger is one of the
+// models.Attempt* constants.
+func (jm *JobManager) StartAttempt(jobID, trigger string) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.Attempts = append(job.Attempts, models.JobAttempt{
+		Number:    len(job.Attempts) + 1,
+		Trigger:   trigger,
+		StartedAt: time.Now().UTC(),
+	})
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// DeferAttempt ends the current attempt with errorMsg while the job stays
+// processing, to be retried at retryAt.
+func (jm *JobManager) DeferAttempt(jobID, errorMsg string, retryAt time.Time) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	if jm.finishAttempt(job, time.Now().UTC(), errorMsg) {
+		retryAt = retryAt.UTC()
+		job.Attempts[len(job.Attempts)-1].RetryAt = &retryAt
+	}
+	job.Progress = 0
+	jm.saveLocked(job)
 	jm.mu.Unlock()
 	jm.notifySubscribers(jobID)
 	return nil
 }
 
+// SetCacheKey records the result cache key of the job; an empty key takes
+// it out of the cache.
+func (jm *JobManager) SetCacheKey(jobID, key string) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	job.CacheKey = key
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	return nil
+}
+
+// SetClient records the client a job counts against (see
+// ConversionJob.Client) and accounts the job and its input size to it.
+func (jm *JobManager) SetClient(jobID, client string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		return fmt.Errorf("job not found")
+	}
+	job.Client = client
+	jm.usage.AddJob(client, job.OriginalFile.Size, time.Now())
+	jm.saveLocked(job)
+	return nil
+}
+
+// SetRequestID records the request that created the job.
+func (jm *JobManager) SetRequestID(jobID, requestID string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		return fmt.Errorf("job not found")
+	}
+	job.RequestID = requestID
+	jm.saveLocked(job)
+	return nil
+}
+
+// FindByCacheKey returns owner's newest job with key that hasn't failed.
+// Jobs are never shared between owners.
+func (jm *JobManager) FindByCacheKey(key, owner string) (*models.ConversionJob, bool) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	var found *models.ConversionJob
+	for _, job := range jm.jobs {
+		if job.CacheKey != key || job.Owner != owner || job.Status == models.StatusFailed || job.Status == models.StatusInfected {
+			continue
+		}
+		if found == nil || job.CreatedAt.After(found.CreatedAt) {
+			found = job
+		}
+	}
+	return found, found != nil
+}
+
+// JobsOwnedBy returns copies of owner's jobs, newest first.
+func (jm *JobManager) JobsOwnedBy(owner string) []models.ConversionJob {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	jobs := []models.ConversionJob{}
+	for _, job := range jm.jobs {
+		if job.Owner == owner {
+			jobs = append(jobs, *job)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b models.ConversionJob) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return jobs
+}
+
+// ErrJobNotFailed is returned by RetryJob for a job that hasn't failed.
+var ErrJobNotFailed = errors.New("only failed jobs can be retried")
+
+// RetryJob puts a failed job back to pending, clearing what the failed run
+// left behind. Its attempt history is kept.
+func (jm *JobManager) RetryJob(jobID string) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	if job.Status != models.StatusFailed {
+		jm.mu.Unlock()
+		return ErrJobNotFailed
+	}
+	resetForRun(job)
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// RequeueJob puts a job that was interrupted mid-run back to pending,
+// closing its open attempt with reason.
+func (jm *JobManager) RequeueJob(jobID, reason string) error {
+	jm.mu.Lock()
+	job, ok := jm.jobs[jobID]
+	if !ok {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	jm.finishRun(job, time.Now().UTC(), reason)
+	resetForRun(job)
+	jm.saveLocked(job)
+	jm.mu.Unlock()
+	jm.notifySubscribers(jobID)
+	return nil
+}
+
+// resetForRun clears what a previous run left on the job. Callers hold
+// jm.mu.
+func resetForRun(job *models.ConversionJob) {
+	job.Status = models.StatusPending
+	job.Progress = 0
+	job.Error = ""
+	job.ResultURL = ""
+	job.CompletedAt = nil
+	job.StartedAt = nil
+	job.CurrentStage = ""
+	job.Stages = nil
+	job.QualityMetrics = nil
+	job.Result = nil
+}
+
+// finishRun ends the job's run at at. Jobs that record attempts account
+// their encode time per attempt; the rest, such as tool jobs, account the
+// time since they began processing, once. Callers hold jm.mu and call it
+// before setting CompletedAt.
+func (jm *JobManager) finishRun(job *models.ConversionJob, at time.Time, errorMsg string) {
+	if len(job.Attempts) > 0 {
+		jm.finishAttempt(job, at, errorMsg)
+		return
+	}
+	if job.StartedAt != nil && job.CompletedAt == nil {
+		jm.usage.AddEncodeTime(job.Client, at.Sub(*job.StartedAt), at)
+	}
+}
+
+// finishAttempt closes the job's open attempt, if any, and accounts its
+// duration as encode time. Callers hold jm.mu.
+func (jm *JobManager) finishAttempt(job *models.ConversionJob, at time.Time, errorMsg string) bool {
+	if len(job.Attempts) == 0 {
+		return false
+	}
+	if job.Attempts[len(job.Attempts)-1].FinishedAt != nil {
+		return false
+	}
+	// Snapshots already sent to subscribers share the old backing array.
+	job.Attempts = slices.Clone(job.Attempts)
+	last := &job.Attempts[len(job.Attempts)-1]
+	last.FinishedAt = &at
+	last.Error = errorMsg
+	jm.usage.AddEncodeTime(job.Client, at.Sub(last.StartedAt), at)
+	return true
+}
+
 func (jm *JobManager) SendProgressUpdate(jobID string, progress int) {
 	select {
 	case jm.progressCh <- models.ProgressUpdate{JobID: jobID, Progress: progress}:
@@ -300,7 +669,7 @@ func (jm *JobManager) ActiveJobIDs() map[string]struct{} {
 	defer jm.mu.RUnlock()
 	out := make(map[string]struct{}, len(jm.jobs))
 	for id, job := range jm.jobs {
-		if job.Status != models.StatusCompleted && job.Status != models.StatusFailed {
+		if !job.Status.Finished() {
 			out[id] = struct{}{}
 		}
 	}
@@ -313,20 +682,69 @@ func (jm *JobManager) ActiveCount() int {
 	defer jm.mu.RUnlock()
 	n := 0
 	for _, job := range jm.jobs {
-		if job.Status != models.StatusCompleted && job.Status != models.StatusFailed {
+		if !job.Status.Finished() {
+			n++
+		}
+	}
+	return n
+}
+
+// ActiveCountFor is ActiveCount restricted to client's jobs.
+func (jm *JobManager) ActiveCountFor(client string) int {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	n := 0
+	for _, job := range jm.jobs {
+		if job.Client == client && !job.Status.Finished() {
 			n++
 		}
 	}
 	return n
 }
 
-func (jm *JobManager) CleanupOldJobs(maxAge time.Duration) {
+// DeleteJob forgets a job. Its subscribers are closed so open event streams
+// end instead of waiting for updates that will never come.
+func (jm *JobManager) DeleteJob(jobID string) error {
+	jm.mu.Lock()
+	if _, exists := jm.jobs[jobID]; !exists {
+		jm.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	delete(jm.jobs, jobID)
+	store := jm.store
+	jm.mu.Unlock()
+	if store != nil {
+		store.remove(jobID)
+	}
+
+	jm.subMu.Lock()
+	subs := jm.subscribers[jobID]
+	delete(jm.subscribers, jobID)
+	jm.subMu.Unlock()
+	for _, ch := range subs {
+		close(ch)
+	}
+	return nil
+}
+
+// CleanupOldJobs forgets jobs that finished more than maxAge ago and
+// returns how many it removed. The cleanup worker calls it every sweep.
+func (jm *JobManager) CleanupOldJobs(maxAge time.Duration) int {
 	jm.mu.Lock()
-	defer jm.mu.Unlock()
 	cutoff := time.Now().UTC().Add(-maxAge)
+	var removed []string
 	for jobID, job := range jm.jobs {
 		if job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
 			delete(jm.jobs, jobID)
+			removed = append(removed, jobID)
+		}
+	}
+	store := jm.store
+	jm.mu.Unlock()
+	if store != nil {
+		for _, jobID := range removed {
+			store.remove(jobID)
 		}
 	}
+	return len(removed)
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestDeleteJobClosesSubscribers(t *testing.T) {
+	jm := NewJobManager()
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+	ch := jm.Subscribe(job.ID)
+
+	if err := jm.DeleteJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jm.GetJob(job.ID); err == nil {
+		t.Fatal("job still present after DeleteJob")
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("subscriber channel left open")
+	}
+	// The event stream's deferred Unsubscribe must not close it again.
+	jm.Unsubscribe(job.ID, ch)
+
+	if err := jm.DeleteJob(job.ID); err == nil {
+		t.Fatal("deleting a missing job succeeded")
+	}
+}
+
+func TestRetryJobKeepsAttempts(t *testing.T) {
+	jm := NewJobManager()
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+	if err := jm.RetryJob(job.ID); !errors.Is(err, ErrJobNotFailed) {
+		t.Fatalf("retrying a pending job: %v", err)
+	}
+
+	_ = jm.StartAttempt(job.ID, models.AttemptInitial)
+	_ = jm.UpdateJobError(job.ID, "ffmpeg failed")
+	if err := jm.RetryJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := jm.GetJob(job.ID)
+	if got.Status != models.StatusPending || got.Error != "" || got.CompletedAt != nil {
+		t.Fatalf("job not reset: %+v", got)
+	}
+	if len(got.Attempts) != 1 || got.Attempts[0].Error != "ffmpeg failed" || got.Attempts[0].FinishedAt == nil {
+		t.Fatalf("attempts = %+v", got.Attempts)
+	}
+
+	_ = jm.StartAttempt(job.ID, models.AttemptManual)
+	_ = jm.UpdateJobStatus(job.ID, models.StatusCompleted)
+	got, _ = jm.GetJob(job.ID)
+	if last := got.Attempts[1]; last.Number != 2 || last.FinishedAt == nil || last.Error != "" {
+		t.Fatalf("second attempt = %+v", last)
+	}
+}
+
+func TestUsePersistentStore(t *testing.T) {
+	dir := t.TempDir()
+	jm := NewJobManager()
+	if _, err := jm.UsePersistentStore(dir, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	done := jm.CreateJob(models.OriginalFileInfo{Name: "done.mp4"}, map[string]interface{}{"format": "webm"})
+	_ = jm.UpdateJobResult(done.ID, "/api/download/"+done.ID)
+	_ = jm.UpdateJobStatus(done.ID, models.StatusCompleted)
+	running := jm.CreateJob(models.OriginalFileInfo{Name: "running.mp4"}, nil)
+	_ = jm.UpdateJobStatus(running.ID, models.StatusProcessing)
+	_ = jm.StartAttempt(running.ID, models.AttemptInitial)
+	deleted := jm.CreateJob(models.OriginalFileInfo{Name: "deleted.mp4"}, nil)
+	_ = jm.DeleteJob(deleted.ID)
+
+	// A finished job past maxAge is dropped on load.
+	old := jm.CreateJob(models.OriginalFileInfo{Name: "old.mp4"}, nil)
+	_ = jm.UpdateJobStatus(old.ID, models.StatusFailed)
+	old.CompletedAt = ptrTime(time.Now().Add(-2 * time.Hour))
+	jm.saveLocked(old)
+	// Set after creation, these must be saved too.
+	_ = jm.SetRequestID(running.ID, "req-1")
+	_ = jm.SetClient(running.ID, "tenant-a")
+	_ = jm.SetQualityMetrics(done.ID, &models.QualityMetricsResult{})
+	jm.FlushStore()
+
+	restarted := NewJobManager()
+	interrupted, err := restarted.UsePersistentStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Records are written in the background; finish before dir is removed.
+	t.Cleanup(restarted.FlushStore)
+	if len(interrupted) != 1 || interrupted[0].ID != running.ID || interrupted[0].RequestID != "req-1" || interrupted[0].Client != "tenant-a" {
+		t.Fatalf("interrupted = %+v", interrupted)
+	}
+	got, err := restarted.GetJob(done.ID)
+	if err != nil || got.Status != models.StatusCompleted || got.ResultURL != "/api/download/"+done.ID || got.Options["format"] != "webm" || got.QualityMetrics == nil {
+		t.Fatalf("completed job = %+v, %v", got, err)
+	}
+	for _, id := range []string{deleted.ID, old.ID} {
+		if _, err := restarted.GetJob(id); err == nil {
+			t.Errorf("job %s restored", id)
+		}
+	}
+
+	if err := restarted.RequeueJob(running.ID, "interrupted"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = restarted.GetJob(running.ID)
+	if got.Status != models.StatusPending || got.Attempts[0].Error != "interrupted" || got.Attempts[0].FinishedAt == nil {
+		t.Fatalf("requeued job = %+v", got)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
+
+func TestFindByCacheKey(t *testing.T) {
+	jm := NewJobManager()
+	failed := jm.CreateJob(models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	_ = jm.SetCacheKey(failed.ID, "k")
+	_ = jm.UpdateJobError(failed.ID, "ffmpeg failed")
+	if _, ok := jm.FindByCacheKey("k", ""); ok {
+		t.Fatal("failed job returned from the cache")
+	}
+
+	done := jm.CreateJob(models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	_ = jm.SetCacheKey(done.ID, "k")
+	_ = jm.UpdateJobStatus(done.ID, models.StatusCompleted)
+	if got, ok := jm.FindByCacheKey("k", ""); !ok || got.ID != done.ID {
+		t.Fatalf("FindByCacheKey = %v, %v", got, ok)
+	}
+	if _, ok := jm.FindByCacheKey("k", "user-1"); ok {
+		t.Fatal("anonymous job returned to another owner")
+	}
+	_ = jm.SetCacheKey(done.ID, "")
+	if _, ok := jm.FindByCacheKey("k", ""); ok {
+		t.Fatal("job still cached after its key was cleared")
+	}
+}
+
+func TestJobsOwnedBy(t *testing.T) {
+	jm := NewJobManager()
+	older := jm.CreateOwnedJob("user-1", models.OriginalFileInfo{Name: "a.mp4"}, nil)
+	jm.CreateOwnedJob("user-2", models.OriginalFileInfo{Name: "b.mp4"}, nil)
+	jm.CreateJob(models.OriginalFileInfo{Name: "c.mp4"}, nil)
+	newer := jm.CreateOwnedJob("user-1", models.OriginalFileInfo{Name: "d.mp4"}, nil)
+	newer.CreatedAt = older.CreatedAt.Add(time.Second)
+
+	jobs := jm.JobsOwnedBy("user-1")
+	if len(jobs) != 2 || jobs[0].ID != newer.ID || jobs[1].ID != older.ID {
+		t.Fatalf("JobsOwnedBy = %+v", jobs)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// queueGate holds jobs in pending while an operator has paused the
+// queue. Jobs already running are not interrupted.
+type queueGate struct {
+	mu       sync.Mutex
+	pausedAt *time.Time
+	// resumed is closed when the queue is resumed.
+	resumed chan struct{}
+}
+
+// QueueState is whether new conversions are being held.
+type QueueState struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"pausedAt,omitempty"`
+}
+
+// PauseQueue holds conversions that haven't started yet until ResumeQueue.
+// Pausing a paused queue changes nothing. The pause isn't persisted, so a
+// restart resumes the queue.
+func (jm *JobManager) PauseQueue(now time.Time) QueueState {
+	jm.queue.mu.Lock()
+	defer jm.queue.mu.Unlock()
+	if jm.queue.pausedAt == nil {
+		at := now.UTC()
+		jm.queue.pausedAt = &at
+		jm.queue.resumed = make(chan struct{})
+	}
+	return QueueState{Paused: true, PausedAt: jm.queue.pausedAt}
+}
+
+// ResumeQueue releases the conversions held by PauseQueue.
+func (jm *JobManager) ResumeQueue() QueueState {
+	jm.queue.mu.Lock()
+	defer jm.queue.mu.Unlock()
+	if jm.queue.pausedAt != nil {
+		close(jm.queue.resumed)
+		jm.queue.pausedAt = nil
+	}
+	return QueueState{}
+}
+
+// QueueState reports whether the queue is paused.
+func (jm *JobManager) QueueState() QueueState {
+	jm.queue.mu.Lock()
+	defer jm.queue.mu.Unlock()
+	return QueueState{Paused: jm.queue.pausedAt != nil, PausedAt: jm.queue.pausedAt}
+}
+
+// Dispatch runs a job in the background once the queue isn't paused. Every
+// handler starts its jobs through it, so a pause holds conversions, tools,
+// studio, transcode, restore and document scan jobs alike.
+func (jm *JobManager) Dispatch(run func()) {
+	go func() {
+		jm.WaitForQueue()
+		run()
+	}()
+}
+
+// WaitForQueue blocks while the queue is paused.
+func (jm *JobManager) WaitForQueue() {
+	jm.queue.mu.Lock()
+	paused, resumed := jm.queue.pausedAt != nil, jm.queue.resumed
+	jm.queue.mu.Unlock()
+	if paused {
+		<-resumed
+	}
+}
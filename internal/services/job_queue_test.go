@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueuePause(t *testing.T) {
+	jm := NewJobManager()
+	jm.WaitForQueue() // not paused: returns at once
+
+	state := jm.PauseQueue(time.Now())
+	if !state.Paused || state.PausedAt == nil || jm.PauseQueue(time.Now()).PausedAt != state.PausedAt {
+		t.Fatalf("pause = %+v", state)
+	}
+	released := make(chan struct{})
+	go func() {
+		jm.WaitForQueue()
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("WaitForQueue returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if jm.ResumeQueue().Paused || jm.QueueState().Paused {
+		t.Fatal("queue still paused after resume")
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("resume did not release the waiting conversion")
+	}
+	jm.ResumeQueue() // resuming twice is harmless
+}
+
+func TestDispatchWaitsForQueue(t *testing.T) {
+	jm := NewJobManager()
+	jm.PauseQueue(time.Now())
+	ran := make(chan struct{})
+	jm.Dispatch(func() { close(ran) })
+	select {
+	case <-ran:
+		t.Fatal("Dispatch ran the job while the queue was paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	jm.ResumeQueue()
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("resume did not start the dispatched job")
+	}
+}
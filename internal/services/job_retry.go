@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// transientErrnos are failures of the machine rather than of the job: a
+// retry may succeed once space, descriptors or memory free up.
+var transientErrnos = []syscall.Errno{
+	syscall.ENOSPC,
+	syscall.EIO,
+	syscall.EAGAIN,
+	syscall.EMFILE,
+	syscall.ENFILE,
+	syscall.ENOMEM,
+	syscall.EBUSY,
+}
+
+// IsTransientError reports whether a failed conversion is worth retrying.
+// Most converter errors are formatted with %v, and FFmpeg reports its own
+// I/O errors on stderr, so the errno messages are matched in the text too.
+// Timeouts are not transient: the retry would run just as long.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) || strings.Contains(msg, strings.ToLower(errno.Error())) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryBackoff is the wait before retry number attempt (1-based): base,
+// doubling with each retry, capped at limit.
+func RetryBackoff(base, limit time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
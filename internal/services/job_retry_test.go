@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransientError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&os.PathError{Op: "write", Path: "out.mp4", Err: syscall.ENOSPC}, true},
+		{fmt.Errorf("failed to create output directory: %v", &os.PathError{Op: "mkdir", Path: "x", Err: syscall.EIO}), true},
+		{errors.New("FFmpeg failed: exit status 1\nav_interleaved_write_frame(): No space left on device"), true},
+		{errors.New("invalid video options: bad width"), false},
+		{fmt.Errorf("FFmpeg timed out: %w", context.DeadlineExceeded), false},
+	} {
+		if got := IsTransientError(tc.err); got != tc.want {
+			t.Errorf("IsTransientError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	base, limit := 5*time.Second, 30*time.Second
+	for attempt, want := range map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 3: 20 * time.Second, 4: 30 * time.Second, 10: 30 * time.Second} {
+		if got := RetryBackoff(base, limit, attempt); got != want {
+			t.Errorf("RetryBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
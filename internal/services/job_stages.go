@@ -0,0 +1,197 @@
+package services
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Multi-step conversions (face blur, a loudness analysis pass, the encode and
+// quality scoring; or a GIF's render and optimize steps) publish a stage
+// timeline on the job, the same shape the transcode and restore pipelines
+// use. Each stage carries its own progress and the job's progress is their
+// weighted sum, so it only moves forward instead of restarting with every
+// ffmpeg run.
+
+// stagePlan is one step of a conversion. weight is its rough share of the
+// job's run time.
+type stagePlan struct {
+	key    string
+	label  string
+	weight int
+}
+
+type jobStages struct {
+	stages  []models.TranscodeJobStage
+	weights []int
+	current int
+}
+
+// stageTracker holds the timelines of jobs that called beginStages. Jobs
+// with a single step are not tracked and report plain progress.
+type stageTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*jobStages
+}
+
+// videoStagePlan lists the steps convertVideo will run for options.
+func videoStagePlan(options *models.VideoConversionOptions) []stagePlan {
+	var plan []stagePlan
+	if options.BlurFaces {
+		plan = append(plan, stagePlan{"face_blur", "Blurring faces", 35})
+	}
+	if strings.EqualFold(options.Format, "gif") {
+		return append(plan,
+			stagePlan{"render", "Rendering GIF", 70},
+			stagePlan{"optimize", "Optimizing GIF", 30})
+	}
+	if _, ok, _ := resolveLoudnessTarget(options.LoudnessNormalize); ok && !options.StripAudio {
+		plan = append(plan, stagePlan{"loudness_analysis", "Measuring loudness", 10})
+	}
+	plan = append(plan, stagePlan{"encode", "Encoding", 50})
+	if _, ok, _ := resolveQualityMetrics(options.QualityMetrics); ok {
+		plan = append(plan, stagePlan{"quality_metrics", "Scoring quality", 20})
+	}
+	return plan
+}
+
+// audioStagePlan lists the steps convertAudio will run for options.
+func audioStagePlan(options *models.AudioConversionOptions) []stagePlan {
+	if _, ok, _ := resolveLoudnessTarget(options.LoudnessNormalize); !ok {
+		return nil
+	}
+	return []stagePlan{
+		{"loudness_analysis", "Measuring loudness", 25},
+		{"encode", "Encoding", 75},
+	}
+}
+
+// beginStages publishes plan as jobID's stage timeline, all pending.
+func (c *Converter) beginStages(jobID string, plan []stagePlan) {
+	if c.jobManager == nil || len(plan) < 2 {
+		return
+	}
+	s := &jobStages{current: -1}
+	for _, p := range plan {
+		s.stages = append(s.stages, models.TranscodeJobStage{Key: p.key, Label: p.label, Status: models.StageStatusPending})
+		s.weights = append(s.weights, p.weight)
+	}
+	c.stages.mu.Lock()
+	defer c.stages.mu.Unlock()
+	if c.stages.jobs == nil {
+		c.stages.jobs = map[string]*jobStages{}
+	}
+	c.stages.jobs[jobID] = s
+	c.publishStagesLocked(jobID, s)
+}
+
+// startStage completes the running stage and starts key.
+func (c *Converter) startStage(jobID, key string) {
+	c.stages.mu.Lock()
+	defer c.stages.mu.Unlock()
+	s, ok := c.stages.jobs[jobID]
+	if !ok {
+		return
+	}
+	next := -1
+	for i := range s.stages {
+		if s.stages[i].Key == key {
+			next = i
+		}
+	}
+	if next < 0 {
+		return
+	}
+	if s.current >= 0 && s.stages[s.current].Status == models.StageStatusProcessing {
+		s.stages[s.current].Status = models.StageStatusCompleted
+		s.stages[s.current].Progress = 100
+	}
+	s.current = next
+	s.stages[next].Status = models.StageStatusProcessing
+	c.publishStagesLocked(jobID, s)
+}
+
+// reportProgress records percent for the running stage of a tracked job,
+// or as the job's progress otherwise. Progress between stages is dropped.
+func (c *Converter) reportProgress(jobID string, percent int) {
+	c.stages.mu.Lock()
+	s, ok := c.stages.jobs[jobID]
+	if !ok {
+		c.stages.mu.Unlock()
+		if c.jobManager != nil {
+			c.jobManager.SendProgressUpdate(jobID, percent)
+		}
+		return
+	}
+	defer c.stages.mu.Unlock()
+	if s.current < 0 {
+		return
+	}
+	stage := &s.stages[s.current]
+	percent = min(percent, 100)
+	if stage.Status != models.StageStatusProcessing || percent <= stage.Progress {
+		return
+	}
+	stage.Progress = percent
+	c.publishStagesLocked(jobID, s)
+}
+
+// endStages stops tracking jobID. On success the running stage completes
+// and stages that never started (e.g. loudness analysis of a silent video)
+// are marked skipped; on failure the running stage is marked failed.
+func (c *Converter) endStages(jobID string, err error) {
+	c.stages.mu.Lock()
+	defer c.stages.mu.Unlock()
+	s, ok := c.stages.jobs[jobID]
+	if !ok {
+		return
+	}
+	delete(c.stages.jobs, jobID)
+	for i := range s.stages {
+		switch s.stages[i].Status {
+		case models.StageStatusProcessing:
+			if err != nil {
+				s.stages[i].Status = models.StageStatusFailed
+			} else {
+				s.stages[i].Status = models.StageStatusCompleted
+				s.stages[i].Progress = 100
+			}
+		case models.StageStatusPending:
+			if err == nil {
+				s.stages[i].Status = models.StageStatusSkipped
+			}
+		}
+	}
+	c.publishStagesLocked(jobID, s)
+}
+
+func (c *Converter) publishStagesLocked(jobID string, s *jobStages) {
+	current := ""
+	if s.current >= 0 {
+		current = s.stages[s.current].Key
+	}
+	_ = c.jobManager.ReplaceStages(jobID, append([]models.TranscodeJobStage(nil), s.stages...), current)
+	if p := s.overall(); p > 0 {
+		_ = c.jobManager.UpdateJobProgress(jobID, p)
+	}
+}
+
+// overall is the weighted progress of all stages; finished and skipped
+// stages count as done.
+func (s *jobStages) overall() int {
+	total, done := 0, 0
+	for i, st := range s.stages {
+		total += s.weights[i]
+		switch st.Status {
+		case models.StageStatusCompleted, models.StageStatusSkipped:
+			done += 100 * s.weights[i]
+		default:
+			done += st.Progress * s.weights[i]
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return done / total
+}
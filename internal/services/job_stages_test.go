@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestStageProgressIsWeighted(t *testing.T) {
+	jm := NewJobManager()
+	c := &Converter{jobManager: jm}
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+
+	c.beginStages(job.ID, []stagePlan{
+		{"loudness_analysis", "Measuring loudness", 10},
+		{"encode", "Encoding", 50},
+		{"quality_metrics", "Scoring quality", 40},
+	})
+	c.startStage(job.ID, "encode")
+	c.reportProgress(job.ID, 50)
+	c.reportProgress(job.ID, 20) // a later ffmpeg run restarting at 0 must not move it back
+
+	got, _ := jm.GetJob(job.ID)
+	if got.CurrentStage != "encode" || got.Stages[1].Progress != 50 {
+		t.Fatalf("current = %q, encode progress = %d", got.CurrentStage, got.Stages[1].Progress)
+	}
+	// loudness_analysis never ran, so it is still pending and counts as 0.
+	if got.Progress != 25 {
+		t.Fatalf("progress = %d, want 25", got.Progress)
+	}
+
+	c.endStages(job.ID, nil)
+	got, _ = jm.GetJob(job.ID)
+	want := []models.TranscodeStageStatus{models.StageStatusSkipped, models.StageStatusCompleted, models.StageStatusSkipped}
+	for i, st := range got.Stages {
+		if st.Status != want[i] {
+			t.Errorf("stage %s = %s, want %s", st.Key, st.Status, want[i])
+		}
+	}
+	if got.Progress != 100 {
+		t.Errorf("progress = %d, want 100", got.Progress)
+	}
+}
+
+func TestStageFailure(t *testing.T) {
+	jm := NewJobManager()
+	c := &Converter{jobManager: jm}
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+
+	c.beginStages(job.ID, []stagePlan{{"render", "Rendering GIF", 70}, {"optimize", "Optimizing GIF", 30}})
+	c.startStage(job.ID, "render")
+	c.startStage(job.ID, "optimize")
+	c.endStages(job.ID, errors.New("gifsicle failed"))
+
+	got, _ := jm.GetJob(job.ID)
+	if got.Stages[0].Status != models.StageStatusCompleted || got.Stages[1].Status != models.StageStatusFailed {
+		t.Fatalf("stages = %+v", got.Stages)
+	}
+	// Tracking ended, so later progress goes to the job as before.
+	c.reportProgress(job.ID, 10)
+	if len(c.stages.jobs) != 0 {
+		t.Fatalf("job still tracked after endStages")
+	}
+}
+
+func TestSingleStepJobsAreNotTracked(t *testing.T) {
+	if plan := videoStagePlan(&models.VideoConversionOptions{Format: "mp4"}); len(plan) != 1 {
+		t.Fatalf("plain encode plan = %+v", plan)
+	}
+	if plan := audioStagePlan(&models.AudioConversionOptions{Format: "mp3"}); plan != nil {
+		t.Fatalf("plain audio plan = %+v", plan)
+	}
+	jm := NewJobManager()
+	c := &Converter{jobManager: jm}
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "clip.mp4"}, nil)
+	c.beginStages(job.ID, []stagePlan{{"encode", "Encoding", 50}})
+	if got, _ := jm.GetJob(job.ID); len(got.Stages) != 0 {
+		t.Fatalf("single-step job published stages %+v", got.Stages)
+	}
+}
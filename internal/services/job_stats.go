@@ -0,0 +1,241 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// JobStats is an aggregate snapshot of the in-memory job table for the
+// operator dashboard. It only covers jobs the JobManager still holds, so
+// finished jobs drop out once the cleanup worker purges them after
+// JOB_RETENTION_SECONDS.
+type JobStats struct {
+	GeneratedAt time.Time                  `json:"generatedAt"`
+	Total       int                        `json:"total"`
+	ByStatus    map[models.JobStatus]int   `json:"byStatus"`
+	QueueDepth  JobQueueDepth              `json:"queueDepth"`
+	Throughput  []JobThroughputWindow      `json:"throughput"`
+	Durations   map[string]JobDurationStat `json:"durations"`
+}
+
+// JobQueueDepth counts jobs that have not finished yet.
+type JobQueueDepth struct {
+	Pending    int `json:"pending"`
+	Processing int `json:"processing"`
+}
+
+// JobThroughputWindow counts jobs that finished inside a trailing window.
+type JobThroughputWindow struct {
+	Window    string  `json:"window"`
+	Completed int     `json:"completed"`
+	Failed    int     `json:"failed"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+// JobDurationStat summarizes wall-clock time (created → completed) for the
+// successful jobs of one type.
+type JobDurationStat struct {
+	Count      int     `json:"count"`
+	AvgSeconds float64 `json:"avgSeconds"`
+	MaxSeconds float64 `json:"maxSeconds"`
+}
+
+// jobStatsWindows are the trailing throughput windows reported by Stats.
+var jobStatsWindows = []struct {
+	name string
+	d    time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// Stats aggregates the current job table as of now.
+func (jm *JobManager) Stats(now time.Time) JobStats {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return aggregateJobStats(jm.jobs, now)
+}
+
+func aggregateJobStats(jobs map[string]*models.ConversionJob, now time.Time) JobStats {
+	stats := JobStats{
+		GeneratedAt: now.UTC(),
+		Total:       len(jobs),
+		ByStatus: map[models.JobStatus]int{
+			models.StatusPending:    0,
+			models.StatusProcessing: 0,
+			models.StatusCompleted:  0,
+			models.StatusFailed:     0,
+			models.StatusInfected:   0,
+		},
+		Durations: map[string]JobDurationStat{},
+	}
+	windows := make([]JobThroughputWindow, len(jobStatsWindows))
+	for i, w := range jobStatsWindows {
+		windows[i].Window = w.name
+	}
+	totals := map[string]float64{}
+
+	for _, job := range jobs {
+		stats.ByStatus[job.Status]++
+		switch job.Status {
+		case models.StatusPending:
+			stats.QueueDepth.Pending++
+		case models.StatusProcessing:
+			stats.QueueDepth.Processing++
+		}
+		if job.CompletedAt == nil {
+			continue
+		}
+		age := now.Sub(*job.CompletedAt)
+		for i, w := range jobStatsWindows {
+			if age < 0 || age > w.d {
+				continue
+			}
+			if job.Status == models.StatusCompleted {
+				windows[i].Completed++
+			} else if job.Status == models.StatusFailed || job.Status == models.StatusInfected {
+				windows[i].Failed++
+			}
+		}
+		if job.Status != models.StatusCompleted {
+			continue
+		}
+		secs := job.CompletedAt.Sub(job.CreatedAt).Seconds()
+		if secs < 0 {
+			continue
+		}
+		kind := jobStatsType(job)
+		d := stats.Durations[kind]
+		d.Count++
+		if secs > d.MaxSeconds {
+			d.MaxSeconds = secs
+		}
+		totals[kind] += secs
+		stats.Durations[kind] = d
+	}
+	for kind, d := range stats.Durations {
+		d.AvgSeconds = totals[kind] / float64(d.Count)
+		stats.Durations[kind] = d
+	}
+	for i, w := range jobStatsWindows {
+		windows[i].PerMinute = float64(windows[i].Completed+windows[i].Failed) / w.d.Minutes()
+	}
+	stats.Throughput = windows
+	return stats
+}
+
+// jobStatsType buckets a job for duration stats: the pipeline mode when one
+// is set (tools, transcode, transcribe, ...), otherwise the input file type.
+func jobStatsType(job *models.ConversionJob) string {
+	if job.Mode != "" {
+		return strings.ToLower(job.Mode)
+	}
+	if mode, _ := job.Options["mode"].(string); strings.TrimSpace(mode) != "" {
+		return strings.ToLower(strings.TrimSpace(mode))
+	}
+	return string(models.GetFileType(job.OriginalFile.Type))
+}
+
+// ActiveJob is a pending or processing job as listed to operators.
+type ActiveJob struct {
+	ID           string           `json:"id"`
+	Status       models.JobStatus `json:"status"`
+	Type         string           `json:"type"`
+	Progress     int              `json:"progress"`
+	CurrentStage string           `json:"currentStage,omitempty"`
+	FileName     string           `json:"fileName"`
+	SizeBytes    int64            `json:"sizeBytes"`
+	Client       string           `json:"client,omitempty"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	StartedAt    *time.Time       `json:"startedAt,omitempty"`
+	Attempt      int              `json:"attempt,omitempty"`
+}
+
+// JobFailure is a failed job with the tail of the error, where converter
+// errors carry the failing command's stderr.
+type JobFailure struct {
+	ID       string           `json:"id"`
+	Status   models.JobStatus `json:"status"`
+	Type     string           `json:"type"`
+	FileName string           `json:"fileName"`
+	FailedAt time.Time        `json:"failedAt"`
+	Error    string           `json:"error"`
+	Stderr   string           `json:"stderr,omitempty"`
+	Attempts int              `json:"attempts,omitempty"`
+}
+
+// failureSnippetBytes bounds the error and stderr of a listed failure.
+const failureSnippetBytes = 2000
+
+// ActiveJobs lists the pending and processing jobs, oldest first.
+func (jm *JobManager) ActiveJobs() []ActiveJob {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	active := []ActiveJob{}
+	for _, job := range jm.jobs {
+		if job.Status != models.StatusPending && job.Status != models.StatusProcessing {
+			continue
+		}
+		entry := ActiveJob{
+			ID:           job.ID,
+			Status:       job.Status,
+			Type:         jobStatsType(job),
+			Progress:     job.Progress,
+			CurrentStage: job.CurrentStage,
+			FileName:     job.OriginalFile.Name,
+			SizeBytes:    job.OriginalFile.Size,
+			Client:       job.Client,
+			CreatedAt:    job.CreatedAt,
+		}
+		if n := len(job.Attempts); n > 0 {
+			started := job.Attempts[n-1].StartedAt
+			entry.StartedAt, entry.Attempt = &started, job.Attempts[n-1].Number
+		}
+		active = append(active, entry)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
+	return active
+}
+
+// RecentFailures lists up to limit failed or infected jobs, newest first.
+func (jm *JobManager) RecentFailures(limit int) []JobFailure {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	failures := []JobFailure{}
+	for _, job := range jm.jobs {
+		if (job.Status != models.StatusFailed && job.Status != models.StatusInfected) || job.CompletedAt == nil {
+			continue
+		}
+		message, stderr := splitCommandStderr(job.Error)
+		failures = append(failures, JobFailure{
+			ID:       job.ID,
+			Status:   job.Status,
+			Type:     jobStatsType(job),
+			FileName: job.OriginalFile.Name,
+			FailedAt: *job.CompletedAt,
+			Error:    commandTail(message, failureSnippetBytes),
+			Stderr:   commandTail(stderr, failureSnippetBytes),
+			Attempts: len(job.Attempts),
+		})
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].FailedAt.After(failures[j].FailedAt) })
+	if limit > 0 && len(failures) > limit {
+		failures = failures[:limit]
+	}
+	return failures
+}
+
+// splitCommandStderr separates the stderr runFFmpegWithProgress and
+// runImageMagickWithProgress append to a command's error.
+func splitCommandStderr(message string) (string, string) {
+	for _, marker := range []string{". FFmpeg stderr: ", ". ImageMagick stderr: "} {
+		if before, after, ok := strings.Cut(message, marker); ok {
+			return before, after
+		}
+	}
+	return message, ""
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestAggregateJobStats(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	jobs := map[string]*models.ConversionJob{
+		"a": {Status: models.StatusPending, OriginalFile: models.OriginalFileInfo{Type: "video/mp4"}},
+		"b": {Status: models.StatusProcessing, OriginalFile: models.OriginalFileInfo{Type: "video/mp4"}},
+		"c": {Status: models.StatusCompleted, OriginalFile: models.OriginalFileInfo{Type: "image/png"},
+			CreatedAt: now.Add(-3 * time.Minute), CompletedAt: at(2 * time.Minute)},
+		"d": {Status: models.StatusCompleted, OriginalFile: models.OriginalFileInfo{Type: "image/jpeg"},
+			CreatedAt: now.Add(-2 * time.Hour), CompletedAt: at(2*time.Hour - 3*time.Minute)},
+		"e": {Status: models.StatusFailed, OriginalFile: models.OriginalFileInfo{Type: "audio/mpeg"},
+			CreatedAt: now.Add(-40 * time.Minute), CompletedAt: at(30 * time.Minute)},
+		"f": {Status: models.StatusCompleted, Options: map[string]interface{}{"mode": "Video_Grid"},
+			CreatedAt: now.Add(-10 * time.Minute), CompletedAt: at(9 * time.Minute)},
+	}
+	stats := aggregateJobStats(jobs, now)
+
+	if stats.Total != 6 || stats.ByStatus[models.StatusCompleted] != 3 || stats.ByStatus[models.StatusFailed] != 1 {
+		t.Fatalf("status counts = %+v", stats.ByStatus)
+	}
+	if stats.QueueDepth.Pending != 1 || stats.QueueDepth.Processing != 1 {
+		t.Fatalf("queue depth = %+v", stats.QueueDepth)
+	}
+	want := map[string][2]int{"5m": {1, 0}, "1h": {2, 1}, "24h": {3, 1}}
+	for _, w := range stats.Throughput {
+		if got := [2]int{w.Completed, w.Failed}; got != want[w.Window] {
+			t.Errorf("window %s = %v, want %v", w.Window, got, want[w.Window])
+		}
+	}
+	img := stats.Durations["image"]
+	if img.Count != 2 || img.AvgSeconds != 120 || img.MaxSeconds != 180 {
+		t.Errorf("image durations = %+v", img)
+	}
+	if stats.Durations["video_grid"].Count != 1 {
+		t.Errorf("mode bucket missing: %+v", stats.Durations)
+	}
+	if _, ok := stats.Durations["audio"]; ok {
+		t.Errorf("failed jobs must not count toward durations: %+v", stats.Durations)
+	}
+}
+
+func TestActiveJobsAndRecentFailures(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	jm := NewJobManager()
+	jm.jobs = map[string]*models.ConversionJob{
+		"new": {ID: "new", Status: models.StatusPending, CreatedAt: now.Add(-time.Minute)},
+		"old": {ID: "old", Status: models.StatusProcessing, CreatedAt: now.Add(-time.Hour), Progress: 40,
+			Attempts: []models.JobAttempt{{Number: 2, StartedAt: now.Add(-10 * time.Minute)}}},
+		"done": {ID: "done", Status: models.StatusCompleted, CreatedAt: now.Add(-time.Hour), CompletedAt: at(time.Minute)},
+		"ffmpeg": {ID: "ffmpeg", Status: models.StatusFailed, CompletedAt: at(time.Hour),
+			Error: "exit status 1. FFmpeg stderr: Invalid data found when processing input"},
+		"infected": {ID: "infected", Status: models.StatusInfected, CompletedAt: at(time.Minute), Error: "infected"},
+	}
+
+	active := jm.ActiveJobs()
+	if len(active) != 2 || active[0].ID != "old" || active[1].ID != "new" {
+		t.Fatalf("active = %+v", active)
+	}
+	if active[0].Attempt != 2 || active[0].StartedAt == nil || active[0].Progress != 40 {
+		t.Errorf("processing job = %+v", active[0])
+	}
+
+	failures := jm.RecentFailures(10)
+	if len(failures) != 2 || failures[0].ID != "infected" || failures[1].ID != "ffmpeg" {
+		t.Fatalf("failures = %+v", failures)
+	}
+	if f := failures[1]; f.Error != "exit status 1" || f.Stderr != "Invalid data found when processing input" {
+		t.Errorf("stderr not split out: %+v", f)
+	}
+	if got := jm.RecentFailures(1); len(got) != 1 || got[0].ID != "infected" {
+		t.Errorf("limit 1 = %+v", got)
+	}
+}
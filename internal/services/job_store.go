@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// jobStore keeps one JSON record per job in dir so jobs outlive the
+// process. Records are rewritten when a job changes status, result or
+// attempts, not on every progress tick.
+type jobStore struct {
+	dir string
+
+	// pending holds the latest record of each job not yet written. save
+	// only queues it, so JobManager's lock isn't held across disk writes.
+	mu      sync.Mutex
+	pending map[string][]byte
+	// writeMu orders flushes and removals, so an older record is never
+	// written over a newer one or after the job's removal.
+	writeMu sync.Mutex
+}
+
+// storedJob is a job's record: the job as the API shows it, plus the
+// fields the API hides.
+type storedJob struct {
+	*models.ConversionJob
+	Client    string `json:"client,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+func (s *jobStore) path(jobID string) string {
+	return filepath.Join(s.dir, jobID+".json")
+}
+
+// save encodes the job's record and writes it in the background. The
+// caller may hold JobManager's lock: the job is read here, but the file is
+// written by flush.
+func (s *jobStore) save(job *models.ConversionJob) {
+	data, err := json.Marshal(storedJob{ConversionJob: job, Client: job.Client, RequestID: job.RequestID})
+	if err != nil {
+		slog.Error("job store: failed to encode job", "jobId", job.ID, "error", err)
+		return
+	}
+	s.mu.Lock()
+	if s.pending == nil {
+		s.pending = map[string][]byte{}
+	}
+	s.pending[job.ID] = data
+	s.mu.Unlock()
+	go s.flush()
+}
+
+// flush writes the queued records. A failed write is logged: the job
+// itself carries on, it just won't survive a restart in its latest state.
+func (s *jobStore) flush() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	for jobID, data := range pending {
+		s.write(jobID, data)
+	}
+}
+
+func (s *jobStore) write(jobID string, data []byte) {
+	tmp := s.path(jobID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		slog.Error("job store: failed to write job", "jobId", jobID, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path(jobID)); err != nil {
+		_ = os.Remove(tmp)
+		slog.Error("job store: failed to write job", "jobId", jobID, "error", err)
+	}
+}
+
+func (s *jobStore) remove(jobID string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	delete(s.pending, jobID)
+	s.mu.Unlock()
+	if err := os.Remove(s.path(jobID)); err != nil && !os.IsNotExist(err) {
+		slog.Error("job store: failed to remove job", "jobId", jobID, "error", err)
+	}
+}
+
+// load reads every record in dir. Finished jobs older than maxAge are
+// dropped, since the cleanup worker has removed their files by then.
+// Unreadable records are logged and skipped.
+func (s *jobStore) load(maxAge time.Duration) ([]*models.ConversionJob, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store directory: %w", err)
+	}
+	cutoff := time.Now().UTC().Add(-maxAge)
+	var jobs []*models.ConversionJob
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			if strings.HasSuffix(name, ".json.tmp") {
+				_ = os.Remove(filepath.Join(s.dir, name))
+			}
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			slog.Error("job store: failed to read record", "file", name, "error", err)
+			continue
+		}
+		var job models.ConversionJob
+		record := storedJob{ConversionJob: &job}
+		if err := json.Unmarshal(data, &record); err != nil || job.ID == "" {
+			slog.Warn("job store: skipping unreadable record", "file", name, "error", err)
+			continue
+		}
+		if job.CompletedAt != nil && maxAge > 0 && job.CompletedAt.Before(cutoff) {
+			s.remove(job.ID)
+			continue
+		}
+		job.Client, job.RequestID = record.Client, record.RequestID
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
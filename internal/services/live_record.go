@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// LiveRecordService captures a bounded window of a live source (RTMP, SRT or
+// an HLS playlist) to a local Matroska file. The capture is a stream copy —
+// no decode — so the recording itself is cheap; the caller then feeds the
+// file through the normal conversion pipeline.
+type LiveRecordService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewLiveRecordService(cfg *config.Config, jm *JobManager) *LiveRecordService {
+	return &LiveRecordService{cfg: cfg, jobManager: jm}
+}
+
+// liveRecordGrace is how long past the requested duration we let FFmpeg run
+// before killing it (connect + buffering + trailer write).
+const liveRecordGrace = 60 * time.Second
+
+// liveProtocolWhitelists restricts which FFmpeg protocols each source scheme
+// may touch, so a crafted playlist can't pivot to file:// or another scheme.
+var liveProtocolWhitelists = map[string]string{
+	"rtmp":  "rtmp,tcp",
+	"rtmps": "rtmps,rtmp,tcp,tls",
+	"srt":   "srt,udp",
+	"http":  "http,tcp,hls,crypto",
+	"https": "https,http,tcp,tls,hls,crypto",
+}
+
+// ValidateLiveSourceURL checks the scheme and host of a live source. HTTP(S)
+// sources must be HLS playlists (.m3u8). Unless allowPrivate is set, hosts
+// that resolve to loopback, private, link-local or unspecified addresses are
+// refused so the endpoint can't be used to probe the internal network, and
+// HLS is refused outright: the segment and key URIs in the playlist are
+// fetched by FFmpeg without this check. The host is resolved here and again
+// when FFmpeg dials, so a name that re-resolves (DNS rebinding) can still
+// reach a private address; allowPrivate deployments should egress through a
+// filtering proxy or network policy.
+func ValidateLiveSourceURL(ctx context.Context, raw string, allowPrivate bool) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, errors.New("invalid live source URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := liveProtocolWhitelists[u.Scheme]; !ok {
+		return nil, fmt.Errorf("unsupported live source scheme %q (expected rtmp, rtmps, srt, or http(s) HLS)", u.Scheme)
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && !strings.HasSuffix(strings.ToLower(u.Path), ".m3u8") {
+		return nil, errors.New("http(s) live sources must be HLS playlists (.m3u8)")
+	}
+	if allowPrivate {
+		return u, nil
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return nil, errors.New("http(s) HLS live sources require LIVE_RECORD_ALLOW_PRIVATE_HOSTS (playlist segments can't be checked)")
+	}
+	if err := requirePublicHost(ctx, u.Hostname()); err != nil {
+		return nil, fmt.Errorf("live source %w", err)
+	}
+	return u, nil
+}
+
+// requirePublicHost resolves host and fails if any address it maps to is
+// loopback, private, link-local, multicast or unspecified. Shared by every
+// feature that makes the server fetch a caller-supplied URL.
+func requirePublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("host %q could not be resolved", host)
+	}
+	for _, a := range addrs {
+		if isNonPublicIP(a.IP) {
+			return fmt.Errorf("host %q resolves to a non-public address", host)
+		}
+	}
+	return nil
+}
+
+func isNonPublicIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// RedactLiveSourceURL drops credentials, query and stream-key path segments
+// so the source can be logged or stored on the job without leaking secrets.
+func RedactLiveSourceURL(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// buildLiveRecordArgs assembles the capture command. -rw_timeout (µs) makes a
+// stalled RTMP/HLS connection fail instead of hanging; SRT has its own
+// timeout option.
+func buildLiveRecordArgs(u *url.URL, durationSec int, outputPath string) []string {
+	args := []string{"-hide_banner", "-nostdin", "-y", "-protocol_whitelist", liveProtocolWhitelists[u.Scheme]}
+	if u.Scheme == "srt" {
+		args = append(args, "-timeout", "15000000")
+	} else {
+		args = append(args, "-rw_timeout", "15000000")
+	}
+	args = append(args,
+		"-i", u.String(),
+		"-t", strconv.Itoa(durationSec),
+		"-map", "0:v?", "-map", "0:a?",
+		"-c", "copy",
+		"-f", "matroska",
+		outputPath,
+	)
+	return args
+}
+
+// Record captures durationSec seconds of the source into outputPath.
+func (s *LiveRecordService) Record(ctx context.Context, job *models.ConversionJob, source *url.URL, durationSec int, outputPath string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(durationSec)*time.Second+liveRecordGrace)
+	defer cancel()
+	s.progress(job.ID, 1)
+	_, stderr, err := runCommand(withNetwork(ctx), "ffmpeg", buildLiveRecordArgs(source, durationSec, outputPath)...)
+	if err != nil {
+		return fmt.Errorf("live capture from %s failed: %w (%s)", RedactLiveSourceURL(source), err, tail(stderr, 1000))
+	}
+	info, statErr := os.Stat(outputPath)
+	if statErr != nil || info.Size() == 0 {
+		return fmt.Errorf("live capture from %s produced no data", RedactLiveSourceURL(source))
+	}
+	if !ffprobeHasStream(ctx, outputPath, "v") {
+		return errors.New("live source has no video stream")
+	}
+	return nil
+}
+
+func (s *LiveRecordService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
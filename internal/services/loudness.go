@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// EBU R128 two-pass loudness normalization.
+//
+// The single-pass `loudnorm` insert used by BasicProcessing.Normalize runs in
+// dynamic mode: it has no idea how loud the whole programme is, so it rides
+// the gain in real time and routinely misses the target by a few LU. The
+// two-pass form first measures the programme (integrated loudness, true
+// peak, loudness range, gating threshold) and then feeds those measurements
+// back so the second pass can apply a single linear gain — hitting the target
+// within a fraction of a LU without pumping.
+
+// loudnessTarget is the resolved, validated target for one job.
+type loudnessTarget struct {
+	I   float64 // integrated loudness, LUFS
+	TP  float64 // maximum true peak, dBTP
+	LRA float64 // loudness range target, LU
+}
+
+// loudnessPresets are the common deliverable specs. Explicit I/TP/LRA values
+// on the request override the preset's.
+var loudnessPresets = map[string]loudnessTarget{
+	"podcast":   {I: -16, TP: -1.5, LRA: 11}, // Apple Podcasts / AES TD1004
+	"broadcast": {I: -23, TP: -1, LRA: 15},   // EBU R128
+	"streaming": {I: -14, TP: -1, LRA: 11},   // Spotify / YouTube reference
+}
+
+// resolveLoudnessTarget applies preset defaults and range checks. It returns
+// ok=false when normalization isn't requested.
+func resolveLoudnessTarget(opts *models.LoudnessNormalizeOptions) (loudnessTarget, bool, error) {
+	if opts == nil || !opts.Enabled {
+		return loudnessTarget{}, false, nil
+	}
+	preset := strings.ToLower(strings.TrimSpace(opts.Preset))
+	if preset == "" {
+		preset = "podcast"
+	}
+	t, ok := loudnessPresets[preset]
+	if !ok {
+		return loudnessTarget{}, false, fmt.Errorf("unsupported loudness preset: %s (expected podcast|broadcast|streaming)", opts.Preset)
+	}
+	if opts.IntegratedLUFS != nil {
+		t.I = *opts.IntegratedLUFS
+	}
+	if opts.TruePeak != nil {
+		t.TP = *opts.TruePeak
+	}
+	if opts.LRA != nil {
+		t.LRA = *opts.LRA
+	}
+	// Ranges are the ones the loudnorm filter itself accepts.
+	if math.IsNaN(t.I) || t.I < -70 || t.I > -5 {
+		return loudnessTarget{}, false, fmt.Errorf("integrated loudness must be between -70 and -5 LUFS, got %.1f", t.I)
+	}
+	if math.IsNaN(t.TP) || t.TP < -9 || t.TP > 0 {
+		return loudnessTarget{}, false, fmt.Errorf("true peak must be between -9 and 0 dBTP, got %.1f", t.TP)
+	}
+	if math.IsNaN(t.LRA) || t.LRA < 1 || t.LRA > 50 {
+		return loudnessTarget{}, false, fmt.Errorf("loudness range must be between 1 and 50 LU, got %.1f", t.LRA)
+	}
+	return t, true, nil
+}
+
+// loudnormStats is the subset of loudnorm's print_format=json output the
+// second pass needs. loudnorm prints the numbers as JSON strings.
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+func (t loudnessTarget) baseFilter() string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s",
+		strconv.FormatFloat(t.I, 'f', 1, 64),
+		strconv.FormatFloat(t.TP, 'f', 1, 64),
+		strconv.FormatFloat(t.LRA, 'f', 1, 64))
+}
+
+// measureLoudness runs the analysis pass. inputArgs are the arguments that
+// follow "-i input" in the real encode (trim seeks), and preFilters is the
+// audio chain that will run ahead of loudnorm, so the measurement describes
+// exactly the signal the second pass will see.
+func measureLoudness(ctx context.Context, inputPath string, inputArgs, preFilters []string, t loudnessTarget) (*loudnormStats, error) {
+	chain := append(append([]string{}, preFilters...), t.baseFilter()+":print_format=json")
+	args := []string{"-hide_banner", "-nostats", "-i", inputPath}
+	args = append(args, inputArgs...)
+	args = append(args, "-vn", "-af", strings.Join(chain, ","), "-f", "null", "-")
+	_, stderr, err := runCommand(ctx, "ffmpeg", args...)
+	if err != nil {
+		return nil, fmt.Errorf("loudness analysis failed: %w (%s)", err, commandTail(stderr, 800))
+	}
+	return parseLoudnormStats(stderr)
+}
+
+// parseLoudnormStats extracts the JSON block loudnorm prints at the end of
+// the analysis pass. It is the last {...} in stderr.
+func parseLoudnormStats(stderr string) (*loudnormStats, error) {
+	end := strings.LastIndex(stderr, "}")
+	start := strings.LastIndex(stderr[:max(end, 0)], "{")
+	if start < 0 || end < start {
+		return nil, errors.New("loudness analysis produced no measurement")
+	}
+	var stats loudnormStats
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &stats); err != nil {
+		return nil, fmt.Errorf("parse loudness measurement: %w", err)
+	}
+	for _, v := range []string{stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset} {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			// Pure digital silence measures as -inf; there's nothing to
+			// normalize and loudnorm would reject the values.
+			return nil, errors.New("loudness analysis found no measurable audio (silent input?)")
+		}
+	}
+	return &stats, nil
+}
+
+// loudnormSecondPassFilter builds the measured, linear-mode loudnorm filter.
+// Every interpolated value came out of strconv.ParseFloat in
+// parseLoudnormStats, so nothing from the ffmpeg output reaches the filter
+// graph unvalidated.
+func loudnormSecondPassFilter(t loudnessTarget, m *loudnormStats) string {
+	return fmt.Sprintf("%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		t.baseFilter(), m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+}
+
+// loudnessNormalizeFilter runs the analysis pass and returns the filter to
+// append to the audio chain.
+func (c *Converter) loudnessNormalizeFilter(jobID, inputPath string, inputArgs, preFilters []string, t loudnessTarget) (string, error) {
+	ctx, cancel := c.commandContext(jobID)
+	defer cancel()
+	stats, err := measureLoudness(ctx, inputPath, inputArgs, preFilters, t)
+	if err != nil {
+		return "", err
+	}
+	c.jobLogger(jobID).Debug("loudness analysis", "integratedLUFS", stats.InputI, "truePeakDBTP", stats.InputTP, "rangeLU", stats.InputLRA)
+	return loudnormSecondPassFilter(t, stats), nil
+}
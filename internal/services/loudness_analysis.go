@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Loudness analysis for POST /api/analyze/loudness: the ebur128 filter
+// measures the programme once and reports integrated loudness, loudness
+// range and true peak in its summary, plus a momentary/short-term reading
+// every 100 ms, which is thinned to one per second for the response.
+
+// LoudnessAnalysis is the measurement of one audio stream.
+type LoudnessAnalysis struct {
+	IntegratedLUFS  float64 `json:"integratedLufs"`
+	LoudnessRangeLU float64 `json:"loudnessRangeLu"`
+	// TruePeakDBTP is nil for digital silence, whose peak is -inf.
+	TruePeakDBTP    *float64         `json:"truePeakDbtp"`
+	DurationSeconds float64          `json:"durationSeconds"`
+	Momentary       []LoudnessSample `json:"momentary"`
+	Compliance      []LoudnessCheck  `json:"compliance"`
+}
+
+// LoudnessSample is the reading at Time seconds: momentary (400 ms window)
+// and short-term (3 s window) loudness in LUFS.
+type LoudnessSample struct {
+	Time      float64 `json:"time"`
+	Momentary float64 `json:"momentary"`
+	ShortTerm float64 `json:"shortTerm"`
+}
+
+// LoudnessCheck compares the measurement with one loudnessPresets spec.
+// Integrated loudness passes within ±1 LU of the target (the EBU R128
+// tolerance); the true peak must not exceed the ceiling.
+type LoudnessCheck struct {
+	Preset         string  `json:"preset"`
+	TargetLUFS     float64 `json:"targetLufs"`
+	MaxTruePeak    float64 `json:"maxTruePeak"`
+	LoudnessOK     bool    `json:"loudnessOk"`
+	TruePeakOK     bool    `json:"truePeakOk"`
+	Compliant      bool    `json:"compliant"`
+	GainToTargetLU float64 `json:"gainToTargetLu"`
+}
+
+const loudnessTolerance = 1.0
+
+var (
+	ebur128FrameLine = regexp.MustCompile(`t:\s*([0-9.]+)\s+TARGET:.*?M:\s*(\S+)\s+S:\s*(\S+)`)
+	ebur128Integ     = regexp.MustCompile(`(?m)^\s*I:\s*(\S+)\s+LUFS`)
+	ebur128LRA       = regexp.MustCompile(`(?m)^\s*LRA:\s*(\S+)\s+LU`)
+	ebur128Peak      = regexp.MustCompile(`(?m)^\s*Peak:\s*(\S+)\s+dBFS`)
+)
+
+// AnalyzeLoudness measures the first audio stream of inputPath.
+func AnalyzeLoudness(ctx context.Context, inputPath string) (*LoudnessAnalysis, error) {
+	args := []string{"-hide_banner", "-nostats", "-i", inputPath,
+		"-map", "0:a:0", "-af", "ebur128=peak=true:framelog=info", "-f", "null", "-"}
+	_, stderr, err := runCommand(ctx, "ffmpeg", args...)
+	if err != nil {
+		return nil, fmt.Errorf("loudness analysis failed: %w (%s)", err, commandTail(stderr, 800))
+	}
+	return parseEBUR128(stderr)
+}
+
+// parseEBUR128 reads the filter's per-frame log and final summary.
+func parseEBUR128(stderr string) (*LoudnessAnalysis, error) {
+	idx := strings.LastIndex(stderr, "Summary:")
+	if idx < 0 {
+		return nil, errors.New("loudness analysis produced no summary")
+	}
+	summary := stderr[idx:]
+	a := &LoudnessAnalysis{Momentary: []LoudnessSample{}}
+
+	m := ebur128Integ.FindStringSubmatch(summary)
+	lra := ebur128LRA.FindStringSubmatch(summary)
+	if m == nil || lra == nil {
+		return nil, errors.New("loudness summary is missing integrated loudness or range")
+	}
+	var err error
+	if a.IntegratedLUFS, err = parseLoudnessValue(m[1]); err != nil {
+		return nil, err
+	}
+	if a.LoudnessRangeLU, err = parseLoudnessValue(lra[1]); err != nil {
+		return nil, err
+	}
+	if p := ebur128Peak.FindStringSubmatch(summary); p != nil {
+		if v, err := parseLoudnessValue(p[1]); err == nil {
+			a.TruePeakDBTP = &v
+		}
+	}
+
+	next := 1.0
+	for _, fm := range ebur128FrameLine.FindAllStringSubmatch(stderr[:idx], -1) {
+		t, err := strconv.ParseFloat(fm[1], 64)
+		if err != nil {
+			continue
+		}
+		a.DurationSeconds = t
+		if t+1e-6 < next {
+			continue
+		}
+		next = math.Floor(t) + 1
+		mom, err1 := parseLoudnessValue(fm[2])
+		short, err2 := parseLoudnessValue(fm[3])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		a.Momentary = append(a.Momentary, LoudnessSample{Time: math.Round(t*10) / 10, Momentary: mom, ShortTerm: short})
+	}
+	a.Compliance = loudnessCompliance(a)
+	return a, nil
+}
+
+func parseLoudnessValue(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("unmeasurable loudness value %q", s)
+	}
+	return v, nil
+}
+
+// loudnessCompliance checks the measurement against every preset, in name
+// order so the response is stable.
+func loudnessCompliance(a *LoudnessAnalysis) []LoudnessCheck {
+	names := make([]string, 0, len(loudnessPresets))
+	for name := range loudnessPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]LoudnessCheck, 0, len(names))
+	for _, name := range names {
+		t := loudnessPresets[name]
+		c := LoudnessCheck{
+			Preset:         name,
+			TargetLUFS:     t.I,
+			MaxTruePeak:    t.TP,
+			LoudnessOK:     math.Abs(a.IntegratedLUFS-t.I) <= loudnessTolerance,
+			TruePeakOK:     a.TruePeakDBTP == nil || *a.TruePeakDBTP <= t.TP,
+			GainToTargetLU: math.Round((t.I-a.IntegratedLUFS)*10) / 10,
+		}
+		c.Compliant = c.LoudnessOK && c.TruePeakOK
+		checks = append(checks, c)
+	}
+	return checks
+}
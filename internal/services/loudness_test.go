@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestResolveLoudnessTarget_Presets(t *testing.T) {
+	if _, ok, err := resolveLoudnessTarget(nil); ok || err != nil {
+		t.Fatalf("nil options should be a no-op, got ok=%v err=%v", ok, err)
+	}
+	tgt, ok, err := resolveLoudnessTarget(&models.LoudnessNormalizeOptions{Enabled: true})
+	if err != nil || !ok {
+		t.Fatalf("default preset: ok=%v err=%v", ok, err)
+	}
+	if tgt.I != -16 {
+		t.Errorf("default integrated = %v, want -16 (podcast)", tgt.I)
+	}
+	tgt, _, _ = resolveLoudnessTarget(&models.LoudnessNormalizeOptions{Enabled: true, Preset: "broadcast", TruePeak: floatPtr(-2)})
+	if tgt.I != -23 || tgt.TP != -2 {
+		t.Errorf("broadcast override = %+v, want I=-23 TP=-2", tgt)
+	}
+}
+
+func TestResolveLoudnessTarget_RejectsOutOfRange(t *testing.T) {
+	cases := []*models.LoudnessNormalizeOptions{
+		{Enabled: true, Preset: "cinema"},
+		{Enabled: true, IntegratedLUFS: floatPtr(0)},
+		{Enabled: true, TruePeak: floatPtr(1)},
+		{Enabled: true, LRA: floatPtr(60)},
+	}
+	for _, o := range cases {
+		if _, _, err := resolveLoudnessTarget(o); err == nil {
+			t.Errorf("expected rejection of %+v", o)
+		}
+	}
+}
+
+func TestParseLoudnormStats(t *testing.T) {
+	stderr := `[Parsed_loudnorm_0 @ 0x55] 
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"target_offset" : "0.58"
+}
+`
+	stats, err := parseLoudnormStats(stderr)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	f := loudnormSecondPassFilter(loudnessTarget{I: -16, TP: -1.5, LRA: 11}, stats)
+	want := "loudnorm=I=-16.0:TP=-1.5:LRA=11.0:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.20:offset=0.58:linear=true"
+	if f != want {
+		t.Errorf("second pass filter =\n%s\nwant\n%s", f, want)
+	}
+}
+
+func TestParseLoudnormStats_SilentOrMissing(t *testing.T) {
+	if _, err := parseLoudnormStats("no json here"); err == nil {
+		t.Error("expected error when no measurement block is present")
+	}
+	silent := `{"input_i" : "-inf", "input_tp" : "-inf", "input_lra" : "0.00", "input_thresh" : "-70.00", "target_offset" : "inf"}`
+	if _, err := parseLoudnormStats(silent); err == nil || !strings.Contains(err.Error(), "silent") {
+		t.Errorf("expected silent-input error, got %v", err)
+	}
+}
+
+func TestParseEBUR128(t *testing.T) {
+	var log strings.Builder
+	for i := 1; i <= 25; i++ {
+		ts := float64(i) / 10
+		fmt.Fprintf(&log, "[Parsed_ebur128_0 @ 0x55] t: %-8.1f TARGET:-23 LUFS    M: -%4.1f S: -%4.1f     I: -16.0 LUFS       LRA:   0.0 LU  FTPK: -5.0 dBFS  TPK: -5.0 dBFS\n", ts, 15+ts, 20+ts)
+	}
+	log.WriteString(`[Parsed_ebur128_0 @ 0x55] Summary:
+
+  Integrated loudness:
+    I:         -16.4 LUFS
+    Threshold: -26.6 LUFS
+
+  Loudness range:
+    LRA:         5.2 LU
+    Threshold:  -36.6 LUFS
+    LRA low:   -19.9 LUFS
+    LRA high:  -14.7 LUFS
+
+  True peak:
+    Peak:       -0.4 dBFS
+`)
+	a, err := parseEBUR128(log.String())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if a.IntegratedLUFS != -16.4 || a.LoudnessRangeLU != 5.2 || a.TruePeakDBTP == nil || *a.TruePeakDBTP != -0.4 || a.DurationSeconds != 2.5 {
+		t.Fatalf("summary = %+v", a)
+	}
+	want := []LoudnessSample{{Time: 1, Momentary: -16, ShortTerm: -21}, {Time: 2, Momentary: -17, ShortTerm: -22}}
+	if !reflect.DeepEqual(a.Momentary, want) {
+		t.Errorf("momentary = %+v, want %+v", a.Momentary, want)
+	}
+	for _, c := range a.Compliance {
+		switch c.Preset {
+		case "podcast":
+			if !c.LoudnessOK || c.TruePeakOK || c.Compliant || c.GainToTargetLU != 0.4 {
+				t.Errorf("podcast check = %+v", c)
+			}
+		case "broadcast":
+			if c.LoudnessOK || c.GainToTargetLU != -6.6 {
+				t.Errorf("broadcast check = %+v", c)
+			}
+		}
+	}
+}
+
+func TestParseEBUR128_Silence(t *testing.T) {
+	a, err := parseEBUR128("Summary:\n  Integrated loudness:\n    I:         -70.0 LUFS\n  Loudness range:\n    LRA:         0.0 LU\n  True peak:\n    Peak:       -inf dBFS\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if a.TruePeakDBTP != nil || len(a.Momentary) != 0 {
+		t.Fatalf("silence = %+v", a)
+	}
+	if _, err := parseEBUR128("no summary here"); err == nil {
+		t.Fatal("expected an error without a summary")
+	}
+}
@@ -27,8 +27,14 @@ type MediaMetadata struct {
 	Tool          string                          `json:"tool"`
 	Details       map[string]any                  `json:"details"`
 	ImageMetadata *models.StructuredImageMetadata `json:"imageMetadata,omitempty"`
-	Raw           string                          `json:"raw,omitempty"`
-	Error         string                          `json:"error,omitempty"`
+	// AudioTracks lists the audio streams of a video or audio file.
+	AudioTracks []models.AudioTrack `json:"audioTracks,omitempty"`
+	Raw         string              `json:"raw,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	// AudioTags are the title/artist/album… tags of a video or audio file.
+	AudioTags *models.AudioTags `json:"audioTags,omitempty"`
+	// Identify is the typed ImageMagick description of an image.
+	Identify *models.ImageIdentification `json:"identify,omitempty"`
 }
 
 func NewMediaInspector(commandTimeout time.Duration) *MediaInspector {
@@ -38,43 +44,108 @@ func NewMediaInspector(commandTimeout time.Duration) *MediaInspector {
 	return &MediaInspector{commandTimeout: commandTimeout}
 }
 
+// DetectFile sniffs the file type from the content of path. The declared
+// Content-Type is not trusted; see CheckDeclaredType.
 func (m *MediaInspector) DetectFile(ctx context.Context, path string, declaredMime string) (models.FileType, string) {
-	mimeType := strings.TrimSpace(declaredMime)
+	var mimeType string
 	if detected, err := mimetype.DetectFile(path); err == nil && detected != nil {
 		mimeType = detected.String()
-	}
-	if mimeType == "" {
+	} else {
 		mimeType = http.DetectContentType(readFilePrefix(path, 512))
 	}
-	fileType := models.GetFileType(mimeType)
-	if fileType != models.FileTypeUnknown {
-		return fileType, mimeType
+	// Some HEIF brands sniff as generic ISO media (video/mp4, quicktime).
+	if heif := sniffHEIF(readFilePrefix(path, 64)); heif != "" {
+		mimeType = heif
+	}
+	return classifySniffed(mimeType, path), mimeType
+}
+
+// DetectBytes is DetectFile for an upload held in memory; name supplies the
+// extension of formats that can't be sniffed.
+func (m *MediaInspector) DetectBytes(data []byte, name string, declaredMime string) (models.FileType, string) {
+	mimeType := mimetype.Detect(data).String()
+	if heif := sniffHEIF(data); heif != "" {
+		mimeType = heif
 	}
-	return detectTypeByExtension(path), mimeType
+	return classifySniffed(mimeType, name), mimeType
 }
 
 func (m *MediaInspector) ProbeFile(ctx context.Context, path string, fileType models.FileType) (*MediaMetadata, error) {
+	return m.probe(ctx, probeSource{path: path}, fileType)
+}
+
+// ProbeBytes probes an upload held in memory by piping it to the tools'
+// stdin, so small files never touch the disk. ok is false when the file
+// can't be probed from a pipe and the caller should write it out and use
+// ProbeFile instead: documents (pdfinfo needs a seekable file), and any
+// tool that failed on stdin, such as ffprobe on an MP4 whose index comes
+// after the media data.
+func (m *MediaInspector) ProbeBytes(ctx context.Context, data []byte, fileType models.FileType) (*MediaMetadata, bool) {
+	if fileType == models.FileTypeDocument {
+		return nil, false
+	}
+	metadata, err := m.probe(ctx, probeSource{data: data}, fileType)
+	if err != nil || metadata.Error != "" {
+		return nil, false
+	}
+	return metadata, true
+}
+
+// probeSource is a file on disk or, when data is set, bytes to feed the
+// probe tools on stdin.
+type probeSource struct {
+	path string
+	data []byte
+}
+
+// arg is the input argument for tools that read stdin as "-".
+func (s probeSource) arg() string {
+	if s.data != nil {
+		return "-"
+	}
+	return s.path
+}
+
+func (s probeSource) ffmpegArg() string {
+	if s.data != nil {
+		return "pipe:0"
+	}
+	return s.path
+}
+
+func (m *MediaInspector) probe(ctx context.Context, src probeSource, fileType models.FileType) (*MediaMetadata, error) {
 	ctx, cancel := context.WithTimeout(ctx, m.commandTimeout)
 	defer cancel()
 
 	metadata := &MediaMetadata{FileType: fileType, Details: map[string]any{}}
-	_, mimeType := m.DetectFile(ctx, path, "")
-	metadata.MimeType = mimeType
+	if src.data != nil {
+		metadata.MimeType = mimetype.Detect(src.data).String()
+	} else {
+		_, metadata.MimeType = m.DetectFile(ctx, src.path, "")
+	}
+	path := src.path
 
 	switch fileType {
 	case models.FileTypeImage:
-		tool, args := imageMagickIdentifyCommand(path)
-		stdout, stderr, err := runCommand(ctx, tool, args...)
-		metadata.Tool = strings.Join(append([]string{tool}, args[:len(args)-1]...), " ")
+		tool, args := imageMagickJSONCommand(src.arg())
+		stdout, stderr, err := runCommandInput(ctx, src.data, tool, args...)
+		metadata.Tool = tool + " json:"
 		metadata.Raw = stdout
 		if err != nil {
 			metadata.Error = strings.TrimSpace(stderr)
 			return metadata, fmt.Errorf("identify image: %w", err)
 		}
-		container := parseIdentifyVerbose(stdout)
+		frames, err := parseImageMagickJSON(stdout)
+		if err != nil {
+			return metadata, err
+		}
+		metadata.Identify = identificationFromJSON(frames)
+		container := frames[0]
 		metadata.Details = cloneAnyMap(container)
+		metadata.Details["width"], metadata.Details["height"] = metadata.Identify.Width, metadata.Identify.Height
+		metadata.Details["displayWidth"], metadata.Details["displayHeight"] = metadata.Identify.DisplayWidth, metadata.Identify.DisplayHeight
 		metadata.ImageMetadata = &models.StructuredImageMetadata{Container: cloneAnyMap(container)}
-		if exifMetadata, raw, exifErr := probeExiftool(ctx, path, container); exifErr != nil {
+		if exifMetadata, raw, exifErr := probeExiftool(ctx, src, container); exifErr != nil {
 			metadata.Details["exiftool_error"] = strings.TrimSpace(exifErr.Error())
 		} else {
 			metadata.Tool += " + exiftool"
@@ -86,7 +157,7 @@ func (m *MediaInspector) ProbeFile(ctx context.Context, path string, fileType mo
 			metadata.Details["advancedDeviceMetadata"] = exifMetadata.AdvancedDeviceMetadata
 		}
 	case models.FileTypeVideo, models.FileTypeAudio:
-		stdout, stderr, err := runCommand(ctx, "ffprobe", "-v", "error", "-print_format", "json", "-show_streams", "-show_format", path)
+		stdout, stderr, err := runCommandInput(ctx, src.data, "ffprobe", "-v", "error", "-print_format", "json", "-show_streams", "-show_format", src.ffmpegArg())
 		metadata.Tool = "ffprobe"
 		metadata.Raw = stdout
 		if err != nil {
@@ -98,6 +169,8 @@ func (m *MediaInspector) ProbeFile(ctx context.Context, path string, fileType mo
 			return metadata, fmt.Errorf("parse ffprobe json: %w", err)
 		}
 		metadata.Details = details
+		metadata.AudioTracks = audioTracksFromProbe(details)
+		metadata.AudioTags = audioTagsFromProbe(details)
 	case models.FileTypeDocument:
 		// PDFs are inspected with pdfinfo (poppler-utils) when available. We
 		// never feed PDFs to ImageMagick's identify probe — the deployment's
@@ -134,7 +207,7 @@ func (m *MediaInspector) ProbeFile(ctx context.Context, path string, fileType mo
 			}
 		}
 	default:
-		stdout, stderr, err := runCommand(ctx, "file", "-b", "--mime-all", path)
+		stdout, stderr, err := runCommandInput(ctx, src.data, "file", "-b", "--mime-all", src.arg())
 		metadata.Tool = "file"
 		metadata.Raw = stdout
 		if err != nil {
@@ -144,7 +217,9 @@ func (m *MediaInspector) ProbeFile(ctx context.Context, path string, fileType mo
 		metadata.Details["file_command_output"] = strings.TrimSpace(stdout)
 	}
 
-	if stat, err := os.Stat(path); err == nil {
+	if src.data != nil {
+		metadata.Details["size_bytes"] = int64(len(src.data))
+	} else if stat, err := os.Stat(path); err == nil {
 		metadata.Details["size_bytes"] = stat.Size()
 		metadata.Details["modification_time"] = stat.ModTime().UTC()
 	}
@@ -167,32 +242,8 @@ func WriteMetadata(path string, metadata *MediaMetadata) error {
 	return os.WriteFile(path, body, 0644)
 }
 
-func imageMagickIdentifyCommand(path string) (string, []string) {
-	if _, err := exec.LookPath("magick"); err == nil {
-		return "magick", []string{"identify", "-verbose", path}
-	}
-	return "identify", []string{"-verbose", path}
-}
-
-func parseIdentifyVerbose(raw string) map[string]any {
-	details := make(map[string]any)
-	for _, line := range strings.Split(raw, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || !strings.Contains(line, ":") {
-			continue
-		}
-		parts := strings.SplitN(line, ":", 2)
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if key != "" {
-			details[key] = value
-		}
-	}
-	return details
-}
-
-func probeExiftool(ctx context.Context, path string, container map[string]any) (*models.StructuredImageMetadata, string, error) {
-	stdout, stderr, err := runCommand(ctx, "exiftool", "-json", "-G1", "-s", "-a", path)
+func probeExiftool(ctx context.Context, src probeSource, container map[string]any) (*models.StructuredImageMetadata, string, error) {
+	stdout, stderr, err := runCommandInput(ctx, src.data, "exiftool", "-json", "-G1", "-s", "-a", src.arg())
 	if err != nil {
 		if strings.TrimSpace(stderr) != "" {
 			return nil, stdout, fmt.Errorf("%s", strings.TrimSpace(stderr))
@@ -324,7 +375,7 @@ func gpsLocationTags() map[string]bool {
 
 func detectTypeByExtension(path string) models.FileType {
 	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")) {
-	case "jpg", "jpeg", "png", "gif", "webp", "bmp", "tiff", "heic", "heif", "avif", "svg", "ico":
+	case "jpg", "jpeg", "png", "gif", "webp", "bmp", "tiff", "heic", "heif", "hif", "avif", "jxl", "svg", "ico":
 		return models.FileTypeImage
 	case "mp4", "mov", "m4v", "webm", "mkv", "avi", "flv", "wmv", "mpeg", "mpg":
 		return models.FileTypeVideo
@@ -374,14 +425,49 @@ func readFilePrefix(path string, n int) []byte {
 }
 
 func runCommand(ctx context.Context, name string, args ...string) (string, string, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
+	return runCommandInput(ctx, nil, name, args...)
+}
+
+// runCommandInput is runCommand with stdin fed from input (none when nil).
+func runCommandInput(ctx context.Context, input []byte, name string, args ...string) (string, string, error) {
+	cmd := limitedCommand(ctx, name, args...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
 	if ctx.Err() != nil {
-		return stdout.String(), stderr.String(), ctx.Err()
+		return stdout.String(), stderr.String(), context.Cause(ctx)
 	}
 	return stdout.String(), stderr.String(), err
 }
+
+// Dimensions returns the pixel size of a probed image, or of the largest
+// video stream of a probed video; 0, 0 when unknown.
+func (m *MediaMetadata) Dimensions() (int, int) {
+	if m == nil {
+		return 0, 0
+	}
+	if m.Identify != nil {
+		return m.Identify.Width, m.Identify.Height
+	}
+	width, height := 0, 0
+	streams, _ := m.Details["streams"].([]any)
+	for _, s := range streams {
+		stream, _ := s.(map[string]any)
+		// Cover art of audio files is a video stream too.
+		disposition, _ := stream["disposition"].(map[string]any)
+		if stream["codec_type"] != "video" || disposition["attached_pic"] == float64(1) {
+			continue
+		}
+		w, _ := stream["width"].(float64)
+		h, _ := stream["height"].(float64)
+		if int(w)*int(h) > width*height {
+			width, height = int(w), int(h)
+		}
+	}
+	return width, height
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestRunCommandInputFeedsStdin(t *testing.T) {
+	stdout, _, err := runCommandInput(context.Background(), []byte("probe me"), "cat")
+	if err != nil || stdout != "probe me" {
+		t.Fatalf("runCommandInput = %q, %v", stdout, err)
+	}
+}
+
+func TestProbeSourceArgs(t *testing.T) {
+	disk := probeSource{path: "/tmp/in.mp4"}
+	mem := probeSource{data: []byte{}}
+	if disk.arg() != "/tmp/in.mp4" || disk.ffmpegArg() != "/tmp/in.mp4" {
+		t.Fatalf("disk source args = %q, %q", disk.arg(), disk.ffmpegArg())
+	}
+	if mem.arg() != "-" || mem.ffmpegArg() != "pipe:0" {
+		t.Fatalf("memory source args = %q, %q", mem.arg(), mem.ffmpegArg())
+	}
+}
+
+func TestProbeBytesDefersDocumentsToDisk(t *testing.T) {
+	if _, ok := NewMediaInspector(0).ProbeBytes(context.Background(), []byte("%PDF-1.7"), models.FileTypeDocument); ok {
+		t.Fatal("documents must be probed from a file")
+	}
+}
+
+func TestMediaMetadataDimensions(t *testing.T) {
+	video := &MediaMetadata{Details: map[string]any{"streams": []any{
+		map[string]any{"codec_type": "audio"},
+		map[string]any{"codec_type": "video", "width": float64(640), "height": float64(360)},
+		map[string]any{"codec_type": "video", "width": float64(3840), "height": float64(2160)},
+	}}}
+	if w, h := video.Dimensions(); w != 3840 || h != 2160 {
+		t.Fatalf("video = %dx%d", w, h)
+	}
+	coverArt := &MediaMetadata{Details: map[string]any{"streams": []any{
+		map[string]any{"codec_type": "video", "width": float64(6000), "height": float64(6000), "disposition": map[string]any{"attached_pic": float64(1)}},
+	}}}
+	if w, h := coverArt.Dimensions(); w != 0 || h != 0 {
+		t.Fatalf("cover art counted: %dx%d", w, h)
+	}
+	var none *MediaMetadata
+	if w, h := none.Dimensions(); w != 0 || h != 0 {
+		t.Fatalf("nil metadata = %dx%d", w, h)
+	}
+}
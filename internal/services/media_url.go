@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// MediaURLService downloads a platform link (YouTube, Vimeo, SoundCloud) with
+// yt-dlp. Like a live capture, the download becomes the job's original file
+// and then goes through the normal conversion pipeline.
+type MediaURLService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewMediaURLService(cfg *config.Config, jm *JobManager) *MediaURLService {
+	return &MediaURLService{cfg: cfg, jobManager: jm}
+}
+
+// mediaURLDownloadTimeout bounds one yt-dlp run, including the merge of
+// separate video and audio streams.
+const mediaURLDownloadTimeout = 30 * time.Minute
+
+// ValidateMediaURL accepts http(s) links on an allowed host or one of its
+// subdomains. The host must also resolve to public addresses: the allowlist
+// is the real guard, this catches a hostile DNS entry under it.
+func ValidateMediaURL(ctx context.Context, raw string, allowedHosts []string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, errors.New("invalid media URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported media URL scheme %q (expected http or https)", u.Scheme)
+	}
+	if u.User != nil {
+		return nil, errors.New("media URLs must not carry credentials")
+	}
+	if !mediaURLHostAllowed(u.Hostname(), allowedHosts) {
+		return nil, fmt.Errorf("media URL host %q is not supported", u.Hostname())
+	}
+	if err := requirePublicHost(ctx, u.Hostname()); err != nil {
+		return nil, fmt.Errorf("media URL %w", err)
+	}
+	return u, nil
+}
+
+func mediaURLHostAllowed(host string, allowed []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, a := range allowed {
+		if host == a || strings.HasSuffix(host, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAudioOutputFormat reports whether format is an audio-only output, for
+// which only the source's audio stream is downloaded.
+func IsAudioOutputFormat(format string) bool {
+	return audioCodecArgs(strings.ToLower(strings.TrimSpace(format))) != nil
+}
+
+// ytDlpArgs builds the download command. User and system yt-dlp config files
+// are ignored so the server's behaviour doesn't depend on them, playlists are
+// cut to the single linked item, and live streams or media longer than
+// maxSeconds are filtered out before anything is downloaded. The final path
+// is printed on stdout.
+func ytDlpArgs(u *url.URL, audioOnly bool, maxBytes int64, maxSeconds int, outputDir string) []string {
+	args := []string{
+		"--ignore-config", "--no-cache-dir", "--no-playlist", "--no-progress", "--no-part",
+		"--restrict-filenames",
+		"--max-filesize", strconv.FormatInt(maxBytes, 10),
+		"--match-filter", fmt.Sprintf("!is_live & duration <=? %d", maxSeconds),
+	}
+	if audioOnly {
+		// -x remuxes the audio stream out of a WebM/MP4 container so the
+		// file is detected as audio.
+		args = append(args, "-f", "bestaudio/best", "-x")
+	} else {
+		args = append(args, "-f", "bv*+ba/b", "--merge-output-format", "mkv")
+	}
+	return append(args,
+		"-o", filepath.Join(outputDir, "%(id)s.%(ext)s"),
+		"--print", "after_move:filepath", "--no-simulate",
+		"--", u.String(),
+	)
+}
+
+// Download fetches the linked media into dir and returns its path.
+func (s *MediaURLService) Download(ctx context.Context, job *models.ConversionJob, u *url.URL, audioOnly bool, dir string) (string, error) {
+	if _, err := exec.LookPath(s.cfg.YtDlpPath); err != nil {
+		return "", fmt.Errorf("%s not found in PATH", s.cfg.YtDlpPath)
+	}
+	ctx, cancel := context.WithTimeout(ctx, mediaURLDownloadTimeout)
+	defer cancel()
+	s.progress(job.ID, 1)
+	stdout, stderr, err := runCommand(withNetwork(ctx), s.cfg.YtDlpPath, ytDlpArgs(u, audioOnly, s.cfg.MaxFileSize, s.cfg.MediaURLImportMaxSeconds, dir)...)
+	if err != nil {
+		return "", fmt.Errorf("download from %s failed: %w (%s)", u.Hostname(), err, tail(stderr, 1000))
+	}
+	path := downloadedPath(stdout)
+	if path == "" {
+		// yt-dlp exits cleanly when --match-filter or --max-filesize skips
+		// the media.
+		return "", fmt.Errorf("media at %s is live, longer than %d seconds, or larger than the upload limit", u.Hostname(), s.cfg.MediaURLImportMaxSeconds)
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("yt-dlp wrote outside the job directory")
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return "", fmt.Errorf("download from %s produced no data", u.Hostname())
+	}
+	s.progress(job.ID, 10)
+	return path, nil
+}
+
+// downloadedPath is the last non-empty line yt-dlp printed.
+func downloadedPath(stdout string) string {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (s *MediaURLService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
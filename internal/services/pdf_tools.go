@@ -49,7 +49,7 @@ func (c *Converter) convertImageToPDF(job *models.ConversionJob, options *models
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+	ctx, cancel := c.commandContext(job.ID)
 	defer cancel()
 
 	pdfBytes, err := imageToPDFBytes(ctx, inputPath, options.Quality)
@@ -223,7 +223,7 @@ func imageMagickToPNG(ctx context.Context, inputPath string) ([]byte, error) {
 	if _, err := exec.LookPath(bin); err != nil {
 		bin = "convert"
 	}
-	cmd := exec.CommandContext(ctx, bin, inputPath, "-auto-orient", "png:-")
+	cmd := limitedCommand(ctx, bin, inputPath, "-auto-orient", "png:-")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -298,7 +298,7 @@ func (c *Converter) convertPDFToImages(job *models.ConversionJob, inputPath, out
 	}
 	opts := parsePDFRenderOptions(job.Options)
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.CommandTimeout)
+	ctx, cancel := c.commandContext(job.ID)
 	defer cancel()
 
 	pageCount, err := pdfPageCount(ctx, inputPath)
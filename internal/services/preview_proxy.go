@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// PreviewProxyHeight caps the height of a preview proxy; smaller videos keep
+// their size.
+const PreviewProxyHeight = 480
+
+// previewProxyArgs encodes a small H.264/AAC MP4 for in-browser playback:
+// at most 480p, capped at about 1 Mbit/s, with the moov atom up front so it
+// starts playing before it has fully loaded.
+func previewProxyArgs(inputPath, outputPath string) []string {
+	return []string{
+		"-y", "-i", inputPath,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", PreviewProxyHeight),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "30",
+		"-maxrate", "1M", "-bufsize", "2M", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "96k", "-ac", "2",
+		"-movflags", "+faststart",
+		"-f", "mp4", outputPath,
+	}
+}
+
+// BuildPreviewProxy writes a low-bitrate preview of the video at inputPath
+// to outputPath. The file only appears once it is complete, so a concurrent
+// reader never sees a partial proxy.
+func BuildPreviewProxy(ctx context.Context, inputPath, outputPath string) error {
+	tmp := outputPath + ".part"
+	defer os.Remove(tmp)
+	if _, stderr, err := runCommand(ctx, "ffmpeg", previewProxyArgs(inputPath, tmp)...); err != nil {
+		return fmt.Errorf("preview proxy failed: %w (%s)", err, tail(stderr, 1000))
+	}
+	return os.Rename(tmp, outputPath)
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewProxyArgs(t *testing.T) {
+	args := strings.Join(previewProxyArgs("in.mkv", "out.part"), " ")
+	for _, want := range []string{
+		"-i in.mkv",
+		"-map 0:a:0?",
+		"scale=-2:'min(480,ih)'",
+		"-movflags +faststart",
+		"-f mp4 out.part",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args %q missing %q", args, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// QR codes are generated with qrencode and read with zbarimg (zbar-tools),
+// which also reads EAN/UPC, Code 128, Code 39 and the other 1D symbologies
+// zbar supports.
+
+const (
+	QRCodeDefaultSize = 512
+	QRCodeMinSize     = 64
+	QRCodeMaxSize     = 4096
+	// QRCodeMaxText is the binary capacity of a version 40 code at level L.
+	QRCodeMaxText = 2953
+)
+
+var qrErrorCorrectionLevels = map[string]bool{"L": true, "M": true, "Q": true, "H": true}
+
+var qrHexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// QRCodeRequest is the body of POST /api/tools/qr-code. Colors are
+// #rrggbb or #rrggbbaa; Margin is the quiet zone in modules.
+type QRCodeRequest struct {
+	Text            string `json:"text"`
+	Format          string `json:"format,omitempty"`
+	Size            int    `json:"size,omitempty"`
+	ErrorCorrection string `json:"errorCorrection,omitempty"`
+	Foreground      string `json:"foreground,omitempty"`
+	Background      string `json:"background,omitempty"`
+	Margin          *int   `json:"margin,omitempty"`
+}
+
+// ValidateQRCodeRequest fills in defaults and rejects unusable options.
+func ValidateQRCodeRequest(r *QRCodeRequest) error {
+	if r.Text == "" {
+		return fmt.Errorf("text is required")
+	}
+	if len(r.Text) > QRCodeMaxText {
+		return fmt.Errorf("text is too long for a QR code (%d bytes, max %d)", len(r.Text), QRCodeMaxText)
+	}
+	r.Format = strings.ToLower(strings.TrimSpace(r.Format))
+	if r.Format == "" {
+		r.Format = "png"
+	}
+	if r.Format != "png" && r.Format != "svg" {
+		return fmt.Errorf("invalid format: %q (expected png|svg)", r.Format)
+	}
+	if r.Size == 0 {
+		r.Size = QRCodeDefaultSize
+	}
+	if r.Size < QRCodeMinSize || r.Size > QRCodeMaxSize {
+		return fmt.Errorf("size must be between %d and %d, got %d", QRCodeMinSize, QRCodeMaxSize, r.Size)
+	}
+	r.ErrorCorrection = strings.ToUpper(strings.TrimSpace(r.ErrorCorrection))
+	if r.ErrorCorrection == "" {
+		r.ErrorCorrection = "M"
+	}
+	if !qrErrorCorrectionLevels[r.ErrorCorrection] {
+		return fmt.Errorf("errorCorrection must be L, M, Q or H, got %q", r.ErrorCorrection)
+	}
+	for _, c := range []struct {
+		name  string
+		value *string
+		def   string
+	}{{"foreground", &r.Foreground, "#000000"}, {"background", &r.Background, "#ffffff"}} {
+		if *c.value == "" {
+			*c.value = c.def
+		}
+		if !qrHexColor.MatchString(*c.value) {
+			return fmt.Errorf("%s must be a hex color like #000000, got %q", c.name, *c.value)
+		}
+	}
+	if r.Margin == nil {
+		margin := 4
+		r.Margin = &margin
+	}
+	if *r.Margin < 0 || *r.Margin > 20 {
+		return fmt.Errorf("margin must be between 0 and 20, got %d", *r.Margin)
+	}
+	return nil
+}
+
+// qrencodeArgs returns the qrencode arguments; the text itself is fed on
+// stdin so it can never be read as a flag.
+func qrencodeArgs(r *QRCodeRequest, outputPath string, moduleSize int) []string {
+	return []string{
+		"-o", outputPath,
+		"-t", strings.ToUpper(r.Format),
+		"-s", strconv.Itoa(moduleSize),
+		"-m", strconv.Itoa(*r.Margin),
+		"-l", r.ErrorCorrection,
+		"-8",
+		"--foreground=" + strings.TrimPrefix(r.Foreground, "#"),
+		"--background=" + strings.TrimPrefix(r.Background, "#"),
+	}
+}
+
+// GenerateQRCode writes the code to outputPath. PNGs are drawn one pixel
+// per module and then sampled up to exactly Size x Size, so modules stay
+// sharp-edged; SVGs keep their module viewBox and get Size as their width
+// and height.
+func GenerateQRCode(ctx context.Context, r *QRCodeRequest, outputPath string) error {
+	if _, err := exec.LookPath("qrencode"); err != nil {
+		return errors.New("qrencode not found in PATH")
+	}
+	if r.Format == "svg" {
+		svg, stderr, err := runCommandInput(ctx, []byte(r.Text), "qrencode", qrencodeArgs(r, "-", 1)...)
+		if err != nil {
+			return fmt.Errorf("qrencode failed: %w (%s)", err, strings.TrimSpace(stderr))
+		}
+		return os.WriteFile(outputPath, []byte(sizeQRCodeSVG(svg, r.Size)), 0o644)
+	}
+
+	rawPath := outputPath + ".raw.png"
+	defer os.Remove(rawPath)
+	if _, stderr, err := runCommandInput(ctx, []byte(r.Text), "qrencode", qrencodeArgs(r, rawPath, 1)...); err != nil {
+		return fmt.Errorf("qrencode failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	name, args := resolveImageMagickConvertCommand("convert", []string{rawPath, "-sample", fmt.Sprintf("%dx%d!", r.Size, r.Size), "PNG:" + outputPath})
+	if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+		return fmt.Errorf("QR code resize failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+var svgRootSize = regexp.MustCompile(`\s(width|height)="[^"]*"`)
+
+// sizeQRCodeSVG sets the root element's width and height to size pixels;
+// qrencode writes them in centimetres.
+func sizeQRCodeSVG(svg string, size int) string {
+	start := strings.Index(svg, "<svg")
+	if start < 0 {
+		return svg
+	}
+	end := strings.Index(svg[start:], ">")
+	if end < 0 {
+		return svg
+	}
+	root := svgRootSize.ReplaceAllString(svg[start:start+end], fmt.Sprintf(` $1="%d"`, size))
+	return svg[:start] + root + svg[start+end:]
+}
+
+// DecodedBarcode is one symbol zbarimg found. Polygon is the outline as
+// x,y points when zbar reports it.
+type DecodedBarcode struct {
+	Type    string   `json:"type"`
+	Data    string   `json:"data"`
+	Binary  bool     `json:"binary,omitempty"`
+	Quality int      `json:"quality,omitempty"`
+	Polygon [][2]int `json:"polygon,omitempty"`
+	Page    int      `json:"page"`
+}
+
+// BarcodeDecodeResult is the response of POST /api/tools/qr-decode.
+type BarcodeDecodeResult struct {
+	Codes []DecodedBarcode `json:"codes"`
+}
+
+type zbarXML struct {
+	Sources []struct {
+		Indexes []struct {
+			Num     int `xml:"num,attr"`
+			Symbols []struct {
+				Type    string `xml:"type,attr"`
+				Quality int    `xml:"quality,attr"`
+				Polygon struct {
+					Points string `xml:"points,attr"`
+				} `xml:"polygon"`
+				Data struct {
+					Format string `xml:"format,attr"`
+					Value  string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"symbol"`
+		} `xml:"index"`
+	} `xml:"source"`
+}
+
+// parseZbarXML reads zbarimg --xml output. Binary payloads, which newer
+// zbar versions base64-encode, are returned as base64 with Binary set.
+func parseZbarXML(raw string) ([]DecodedBarcode, error) {
+	var doc zbarXML
+	if err := xml.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("parse zbarimg xml: %w", err)
+	}
+	codes := []DecodedBarcode{}
+	for _, source := range doc.Sources {
+		for _, index := range source.Indexes {
+			for _, symbol := range index.Symbols {
+				code := DecodedBarcode{Type: symbol.Type, Data: symbol.Data.Value, Quality: symbol.Quality, Page: index.Num}
+				if symbol.Data.Format == "base64" {
+					decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(symbol.Data.Value))
+					if err == nil && isPrintableText(decoded) {
+						code.Data = string(decoded)
+					} else {
+						code.Data, code.Binary = strings.TrimSpace(symbol.Data.Value), true
+					}
+				}
+				for _, point := range strings.Fields(symbol.Polygon.Points) {
+					x, y, ok := strings.Cut(point, ",")
+					xi, errX := strconv.Atoi(strings.TrimPrefix(x, "+"))
+					yi, errY := strconv.Atoi(strings.TrimPrefix(y, "+"))
+					if ok && errX == nil && errY == nil {
+						code.Polygon = append(code.Polygon, [2]int{xi, yi})
+					}
+				}
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+func isPrintableText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeBarcodes finds every QR code and barcode in an image.
+func DecodeBarcodes(ctx context.Context, inputPath string) (*BarcodeDecodeResult, error) {
+	if _, err := exec.LookPath("zbarimg"); err != nil {
+		return nil, errors.New("zbarimg not found in PATH (install zbar-tools)")
+	}
+	stdout, stderr, err := runCommand(ctx, "zbarimg", "--quiet", "--xml", inputPath)
+	// zbarimg exits 4 when the image holds no symbols.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 4) {
+		return nil, fmt.Errorf("zbarimg failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	if strings.TrimSpace(stdout) == "" {
+		return &BarcodeDecodeResult{Codes: []DecodedBarcode{}}, nil
+	}
+	codes, err := parseZbarXML(stdout)
+	if err != nil {
+		return nil, err
+	}
+	return &BarcodeDecodeResult{Codes: codes}, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateQRCodeRequest(t *testing.T) {
+	r := QRCodeRequest{Text: "https://example.com", ErrorCorrection: "h"}
+	if err := ValidateQRCodeRequest(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Format != "png" || r.Size != QRCodeDefaultSize || r.ErrorCorrection != "H" || r.Foreground != "#000000" || *r.Margin != 4 {
+		t.Fatalf("defaults = %+v", r)
+	}
+	want := []string{"-o", "out.png", "-t", "PNG", "-s", "1", "-m", "4", "-l", "H", "-8", "--foreground=000000", "--background=ffffff"}
+	if got := qrencodeArgs(&r, "out.png", 1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("qrencodeArgs = %q", got)
+	}
+
+	for _, bad := range []QRCodeRequest{
+		{},
+		{Text: strings.Repeat("x", QRCodeMaxText+1)},
+		{Text: "x", Format: "jpg"},
+		{Text: "x", Size: 10},
+		{Text: "x", ErrorCorrection: "X"},
+		{Text: "x", Foreground: "red"},
+		{Text: "x", Background: "#fff;rm"},
+	} {
+		if err := ValidateQRCodeRequest(&bad); err == nil {
+			t.Errorf("ValidateQRCodeRequest(%+v) = nil, want error", bad)
+		}
+	}
+}
+
+func TestSizeQRCodeSVG(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<svg width="0.82cm" height="0.82cm" viewBox="0 0 29 29" version="1.1" xmlns="http://www.w3.org/2000/svg">
+	<rect x="0" y="0" width="29" height="29" fill="#ffffff"/>
+</svg>`
+	got := sizeQRCodeSVG(in, 512)
+	if !strings.Contains(got, `<svg width="512" height="512" viewBox="0 0 29 29"`) || !strings.Contains(got, `<rect x="0" y="0" width="29" height="29"`) {
+		t.Fatalf("sized svg = %s", got)
+	}
+}
+
+func TestParseZbarXML(t *testing.T) {
+	raw := `<barcodes xmlns='http://zbar.sourceforge.net/2008/barcode'>
+<source href='in.png'>
+<index num='0'>
+<symbol type='QR-Code' quality='1' orientation='UP'><polygon points='+40,40 +40,471 +471,471 +471,40'/><data><![CDATA[https://example.com]]></data></symbol>
+<symbol type='EAN-13' quality='87'><data format='base64' length='13'><![CDATA[NDAwNjM4MTMzMzkzMQ==]]></data></symbol>
+<symbol type='QR-Code' quality='1'><data format='base64' length='3'><![CDATA[AP8B]]></data></symbol>
+</index>
+</source>
+</barcodes>`
+	codes, err := parseZbarXML(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DecodedBarcode{
+		{Type: "QR-Code", Data: "https://example.com", Quality: 1, Polygon: [][2]int{{40, 40}, {40, 471}, {471, 471}, {471, 40}}},
+		{Type: "EAN-13", Data: "4006381333931", Quality: 87},
+		{Type: "QR-Code", Data: "AP8B", Binary: true, Quality: 1},
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("codes = %+v", codes)
+	}
+}
+
+func TestQRCodeRoundTrip(t *testing.T) {
+	for _, tool := range []string{"qrencode", "zbarimg", "convert"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+	r := QRCodeRequest{Text: "-not a flag", Size: 256}
+	if err := ValidateQRCodeRequest(&r); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "qr.png")
+	if err := GenerateQRCode(context.Background(), &r, out); err != nil {
+		t.Fatal(err)
+	}
+	res, err := DecodeBarcodes(context.Background(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Codes) != 1 || res.Codes[0].Data != r.Text {
+		t.Fatalf("decoded = %+v", res.Codes)
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Full-reference quality metrics (VMAF / PSNR / SSIM).
+//
+// After a video encode finishes, the output is decoded side by side with the
+// source and scored. The distorted stream is scaled to the reference's frame
+// size first (the usual VMAF convention), and a trimmed encode is compared
+// against the same span of the source. Encodes that change timing — speed,
+// reverse, variable speed, frame-rate conversion — can't be aligned frame for
+// frame and are reported as skipped rather than producing a meaningless score.
+
+var qualityMetricNames = []string{"vmaf", "psnr", "ssim"}
+
+var (
+	libvmafOnce   sync.Once
+	libvmafCached bool
+
+	vmafScoreRegexp = regexp.MustCompile(`VMAF score[:=]\s*([0-9.]+)`)
+	psnrScoreRegexp = regexp.MustCompile(`PSNR .*?average:([0-9.]+|inf)`)
+	ssimScoreRegexp = regexp.MustCompile(`SSIM .*?All:([0-9.]+)`)
+)
+
+// resolveQualityMetrics validates the requested metric list. It returns
+// ok=false when metrics aren't requested; an empty list means all three.
+func resolveQualityMetrics(opts *models.QualityMetricsOptions) ([]string, bool, error) {
+	if opts == nil || !opts.Enabled {
+		return nil, false, nil
+	}
+	if len(opts.Metrics) == 0 {
+		return append([]string{}, qualityMetricNames...), true, nil
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range opts.Metrics {
+		name := strings.ToLower(strings.TrimSpace(m))
+		switch name {
+		case "vmaf", "psnr", "ssim":
+		default:
+			return nil, false, fmt.Errorf("unsupported quality metric: %s (expected vmaf|psnr|ssim)", m)
+		}
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out, true, nil
+}
+
+// ffmpegSupportsLibVMAF reports whether this FFmpeg build has the libvmaf
+// filter (it's an optional --enable-libvmaf dependency). Probed once.
+func ffmpegSupportsLibVMAF() bool {
+	libvmafOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		stdout, _, err := runCommand(ctx, "ffmpeg", "-hide_banner", "-filters")
+		libvmafCached = err == nil && strings.Contains(stdout, " libvmaf ")
+	})
+	return libvmafCached
+}
+
+// qualityMetricsTimingSkip explains why an encode can't be compared frame for
+// frame with its source, or returns "" when it can.
+func qualityMetricsTimingSkip(options *models.VideoConversionOptions) string {
+	if options.Speed != 0 && options.Speed != 1 {
+		return "output speed differs from source"
+	}
+	if len(options.TrimSegments) > 0 {
+		return "output joins trimmed segments"
+	}
+	if t := options.Temporal; t != nil {
+		if len(t.VariableSpeed) > 0 || (t.Reverse != nil && *t.Reverse) || (t.PingPong != nil && *t.PingPong) {
+			return "output timing differs from source"
+		}
+		if t.FrameRate != nil {
+			return "output frame rate differs from source"
+		}
+	}
+	return ""
+}
+
+// buildQualityMetricsArgs assembles the scoring command. Input 0 is the
+// encode (distorted), input 1 the source (reference) — libvmaf expects that
+// order. Each metric gets its own labelled output so the null muxer consumes
+// all of them.
+func buildQualityMetricsArgs(outputPath, sourcePath string, trim *models.TrimRange, metrics []string) []string {
+	refChain := ""
+	if trim != nil {
+		refChain = fmt.Sprintf("trim=start=%.3f:end=%.3f,", trim.StartTime, trim.EndTime)
+	}
+	var graph []string
+	graph = append(graph,
+		"[0:v]setpts=PTS-STARTPTS,format=yuv420p[d0]",
+		fmt.Sprintf("[1:v]%ssetpts=PTS-STARTPTS,format=yuv420p[r0]", refChain),
+		"[d0][r0]scale2ref=flags=bicubic[dist][ref]",
+	)
+	n := len(metrics)
+	distLabels := make([]string, n)
+	refLabels := make([]string, n)
+	for i := range metrics {
+		distLabels[i] = fmt.Sprintf("[d%d]", i+1)
+		refLabels[i] = fmt.Sprintf("[r%d]", i+1)
+	}
+	if n == 1 {
+		distLabels[0], refLabels[0] = "[dist]", "[ref]"
+	} else {
+		graph = append(graph,
+			fmt.Sprintf("[dist]split=%d%s", n, strings.Join(distLabels, "")),
+			fmt.Sprintf("[ref]split=%d%s", n, strings.Join(refLabels, "")),
+		)
+	}
+	args := []string{"-hide_banner", "-nostats", "-i", outputPath, "-i", sourcePath}
+	var maps []string
+	for i, m := range metrics {
+		filter := m
+		if m == "vmaf" {
+			filter = "libvmaf=n_threads=4"
+		}
+		label := fmt.Sprintf("[q%d]", i+1)
+		graph = append(graph, distLabels[i]+refLabels[i]+filter+label)
+		maps = append(maps, "-map", label)
+	}
+	args = append(args, "-filter_complex", strings.Join(graph, ";"))
+	args = append(args, maps...)
+	return append(args, "-f", "null", "-")
+}
+
+// parseQualityMetrics pulls the pooled scores out of FFmpeg's stderr summary.
+func parseQualityMetrics(stderr string, metrics []string) *models.QualityMetricsResult {
+	res := &models.QualityMetricsResult{}
+	last := func(re *regexp.Regexp) (string, bool) {
+		all := re.FindAllStringSubmatch(stderr, -1)
+		if len(all) == 0 {
+			return "", false
+		}
+		return all[len(all)-1][1], true
+	}
+	for _, m := range metrics {
+		var re *regexp.Regexp
+		switch m {
+		case "vmaf":
+			re = vmafScoreRegexp
+		case "psnr":
+			re = psnrScoreRegexp
+		case "ssim":
+			re = ssimScoreRegexp
+		}
+		raw, ok := last(re)
+		if !ok {
+			skipQualityMetric(res, m, "no score in ffmpeg output")
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) {
+			skipQualityMetric(res, m, "unparseable score")
+			continue
+		}
+		if math.IsInf(v, 0) {
+			// PSNR of a bit-identical encode is infinite, which JSON can't carry.
+			skipQualityMetric(res, m, "output is identical to source")
+			continue
+		}
+		v = math.Round(v*10000) / 10000
+		switch m {
+		case "vmaf":
+			res.VMAF = &v
+		case "psnr":
+			res.PSNR = &v
+		case "ssim":
+			res.SSIM = &v
+		}
+	}
+	return res
+}
+
+// computeQualityMetrics scores outputPath against sourcePath. It never fails
+// the job: problems are recorded per metric in Skipped.
+func (c *Converter) computeQualityMetrics(jobID, sourcePath, outputPath string, options *models.VideoConversionOptions, metrics []string) *models.QualityMetricsResult {
+	if reason := qualityMetricsTimingSkip(options); reason != "" {
+		return skippedQualityMetrics(metrics, reason)
+	}
+	run := metrics[:0:0]
+	skipped := map[string]string{}
+	for _, m := range metrics {
+		if m == "vmaf" && !ffmpegSupportsLibVMAF() {
+			skipped[m] = "ffmpeg was built without libvmaf"
+			continue
+		}
+		run = append(run, m)
+	}
+	res := &models.QualityMetricsResult{}
+	if len(run) > 0 {
+		ctx, cancel := c.commandContext(jobID)
+		defer cancel()
+		args := buildQualityMetricsArgs(outputPath, sourcePath, options.Trim, run)
+		done := c.recordCommand(jobID, "ffmpeg", args)
+		_, stderr, err := runCommand(ctx, "ffmpeg", args...)
+		done(stderr, err)
+		if err != nil {
+			c.jobLogger(jobID).Debug("quality metrics failed", "error", err, "stderr", commandTail(stderr, 500))
+			for _, m := range run {
+				skipped[m] = "metric computation failed"
+			}
+		} else {
+			res = parseQualityMetrics(stderr, run)
+		}
+	}
+	for m, reason := range skipped {
+		skipQualityMetric(res, m, reason)
+	}
+	c.jobLogger(jobID).Debug("quality metrics", "result", res)
+	return res
+}
+
+func skipQualityMetric(res *models.QualityMetricsResult, metric, reason string) {
+	if res.Skipped == nil {
+		res.Skipped = map[string]string{}
+	}
+	res.Skipped[metric] = reason
+}
+
+func skippedQualityMetrics(metrics []string, reason string) *models.QualityMetricsResult {
+	res := &models.QualityMetricsResult{}
+	for _, m := range metrics {
+		skipQualityMetric(res, m, reason)
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestResolveQualityMetrics(t *testing.T) {
+	if _, ok, err := resolveQualityMetrics(nil); ok || err != nil {
+		t.Fatalf("nil options: ok=%v err=%v", ok, err)
+	}
+	got, ok, err := resolveQualityMetrics(&models.QualityMetricsOptions{Enabled: true})
+	if !ok || err != nil || strings.Join(got, ",") != "vmaf,psnr,ssim" {
+		t.Fatalf("default metrics = %v ok=%v err=%v", got, ok, err)
+	}
+	got, _, err = resolveQualityMetrics(&models.QualityMetricsOptions{Enabled: true, Metrics: []string{"SSIM", "psnr", "ssim"}})
+	if err != nil || strings.Join(got, ",") != "ssim,psnr" {
+		t.Fatalf("deduped metrics = %v err=%v", got, err)
+	}
+	if _, _, err := resolveQualityMetrics(&models.QualityMetricsOptions{Enabled: true, Metrics: []string{"msssim"}}); err == nil {
+		t.Fatal("expected error for unsupported metric")
+	}
+}
+
+func TestBuildQualityMetricsArgs(t *testing.T) {
+	args := buildQualityMetricsArgs("/out.mp4", "/src.mov", &models.TrimRange{StartTime: 2, EndTime: 7.5}, []string{"vmaf", "psnr"})
+	if args[3] != "/out.mp4" || args[5] != "/src.mov" {
+		t.Fatalf("distorted must be input 0 and reference input 1: %v", args)
+	}
+	graph := valueAfter(args, "-filter_complex")
+	for _, want := range []string{
+		"[1:v]trim=start=2.000:end=7.500,setpts=PTS-STARTPTS",
+		"[d0][r0]scale2ref",
+		"split=2",
+		"[d1][r1]libvmaf",
+		"[d2][r2]psnr[q2]",
+	} {
+		if !strings.Contains(graph, want) {
+			t.Fatalf("filter graph missing %q: %s", want, graph)
+		}
+	}
+	if countFlag(args, "-map") != 2 {
+		t.Fatalf("expected one -map per metric: %v", args)
+	}
+
+	args = buildQualityMetricsArgs("/out.mp4", "/src.mov", nil, []string{"ssim"})
+	graph = valueAfter(args, "-filter_complex")
+	if strings.Contains(graph, "split") || !strings.Contains(graph, "[dist][ref]ssim[q1]") {
+		t.Fatalf("single metric should not split: %s", graph)
+	}
+}
+
+func TestParseQualityMetrics(t *testing.T) {
+	stderr := `[Parsed_psnr_5 @ 0x1] PSNR y:41.20 u:45.01 v:45.93 average:42.386712 min:38.10 max:49.00
+[Parsed_ssim_6 @ 0x2] SSIM Y:0.981 (17.2) U:0.990 (20.1) V:0.991 (20.4) All:0.984321 (18.0)
+[Parsed_libvmaf_4 @ 0x3] VMAF score: 93.512345`
+	res := parseQualityMetrics(stderr, []string{"vmaf", "psnr", "ssim"})
+	if res.VMAF == nil || *res.VMAF != 93.5123 {
+		t.Fatalf("vmaf = %v", res.VMAF)
+	}
+	if res.PSNR == nil || *res.PSNR != 42.3867 {
+		t.Fatalf("psnr = %v", res.PSNR)
+	}
+	if res.SSIM == nil || *res.SSIM != 0.9843 {
+		t.Fatalf("ssim = %v", res.SSIM)
+	}
+	if len(res.Skipped) != 0 {
+		t.Fatalf("unexpected skips: %v", res.Skipped)
+	}
+
+	res = parseQualityMetrics("PSNR y:inf u:inf v:inf average:inf min:inf max:inf", []string{"psnr", "vmaf"})
+	if res.PSNR != nil || res.Skipped["psnr"] == "" || res.Skipped["vmaf"] == "" {
+		t.Fatalf("expected psnr and vmaf skipped: %+v", res)
+	}
+}
+
+func TestQualityMetricsTimingSkip(t *testing.T) {
+	reverse := true
+	if r := qualityMetricsTimingSkip(&models.VideoConversionOptions{Speed: 1}); r != "" {
+		t.Fatalf("plain encode skipped: %s", r)
+	}
+	if r := qualityMetricsTimingSkip(&models.VideoConversionOptions{Speed: 2}); r == "" {
+		t.Fatal("speed change should skip")
+	}
+	if r := qualityMetricsTimingSkip(&models.VideoConversionOptions{Speed: 1, Temporal: &models.TemporalEffects{Reverse: &reverse}}); r == "" {
+		t.Fatal("reverse should skip")
+	}
+}
@@ -47,6 +47,7 @@ func NewRestoreService(cfg *config.Config, jm *JobManager, s3Client *s3.Client,
 	}
 	if runner == nil {
 		runner = cmdaudit.NewRunner(nil, nil)
+		runner.Command = limitedCommand
 	}
 	n := cfg.RestoreMaxConcurrentJobs
 	if n <= 0 {
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ResultCacheKey identifies a conversion by the SHA-256 of its input and its
+// options. encoding/json writes map keys in sorted order, so options that
+// differ only in key order share a key. The version prefix changes whenever
+// the same options would produce a different output.
+func ResultCacheKey(inputSHA256 string, options map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	sum.Write([]byte("v1\n" + inputSHA256 + "\n"))
+	sum.Write(encoded)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
@@ -0,0 +1,20 @@
+package services
+
+import "testing"
+
+func TestResultCacheKey(t *testing.T) {
+	a, err := ResultCacheKey("abc", map[string]interface{}{"format": "webm", "quality": "high", "trim": map[string]interface{}{"startTime": 1.0, "endTime": 2.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ResultCacheKey("abc", map[string]interface{}{"trim": map[string]interface{}{"endTime": 2.0, "startTime": 1.0}, "quality": "high", "format": "webm"})
+	if a != b {
+		t.Fatal("key depends on option order")
+	}
+	if c, _ := ResultCacheKey("abd", map[string]interface{}{"format": "webm", "quality": "high", "trim": map[string]interface{}{"startTime": 1.0, "endTime": 2.0}}); c == a {
+		t.Fatal("different inputs share a key")
+	}
+	if d, _ := ResultCacheKey("abc", map[string]interface{}{"format": "webm", "quality": "low", "trim": map[string]interface{}{"startTime": 1.0, "endTime": 2.0}}); d == a {
+		t.Fatal("different options share a key")
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"syscall"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+// sandbox confines the commands of a conversion (SANDBOX), since the
+// decoders in ffmpeg and ImageMagick are where untrusted input gets parsed.
+// Commands get no network and a read-only filesystem apart from the upload,
+// output and temp directories, and can run as a separate user.
+type sandbox struct {
+	// kind is "firejail" or "bwrap"; "" runs commands unconfined.
+	kind string
+	// network keeps the host's network for commands that download their
+	// input (see withNetwork).
+	network bool
+	// writable are the absolute directories a command may write to.
+	writable []string
+	uid, gid int
+}
+
+// newSandbox returns the sandbox cfg asks for.
+func newSandbox(cfg *config.Config) sandbox {
+	s := sandbox{kind: cfg.Sandbox, uid: cfg.SandboxUID, gid: cfg.SandboxGID}
+	if s.kind == "" {
+		return s
+	}
+	for _, dir := range []string{cfg.UploadDir, cfg.OutputDir, cfg.TempDir, os.TempDir()} {
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			s.writable = append(s.writable, abs)
+		}
+	}
+	return s
+}
+
+// CheckSandbox reports a SANDBOX setting that commands could not run
+// under, so a misconfigured server fails at startup rather than on every
+// job.
+func CheckSandbox(cfg *config.Config) error {
+	switch cfg.Sandbox {
+	case "":
+		if cfg.SandboxUID > 0 || cfg.SandboxGID > 0 {
+			return fmt.Errorf("SANDBOX_UID and SANDBOX_GID need SANDBOX to be set")
+		}
+		return nil
+	case "firejail", "bwrap":
+	default:
+		return fmt.Errorf("unsupported SANDBOX %q (want firejail or bwrap)", cfg.Sandbox)
+	}
+	if _, err := exec.LookPath(cfg.Sandbox); err != nil {
+		return fmt.Errorf("SANDBOX=%s: %w", cfg.Sandbox, err)
+	}
+	if (cfg.SandboxUID > 0 || cfg.SandboxGID > 0) && os.Geteuid() != 0 {
+		return fmt.Errorf("SANDBOX_UID and SANDBOX_GID need the server to run as root")
+	}
+	return nil
+}
+
+// wrap prefixes the command with the sandbox.
+func (s sandbox) wrap(name string, args []string) (string, []string) {
+	var prefix []string
+	switch s.kind {
+	case "firejail":
+		// --seccomp applies firejail's default syscall blacklist.
+		prefix = []string{"--quiet", "--noprofile", "--net=none", "--seccomp", "--caps.drop=all",
+			"--nonewprivs", "--nogroups", "--read-only=/"}
+		if s.network {
+			prefix = slices.Delete(prefix, 2, 3)
+		}
+		for _, dir := range s.writable {
+			prefix = append(prefix, "--read-write="+dir)
+		}
+	case "bwrap":
+		prefix = []string{"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc"}
+		for _, dir := range s.writable {
+			prefix = append(prefix, "--bind", dir, dir)
+		}
+		prefix = append(prefix, "--unshare-all", "--die-with-parent", "--new-session", "--cap-drop", "ALL")
+		if s.network {
+			prefix = append(prefix, "--share-net")
+		}
+	default:
+		return name, args
+	}
+	wrapped := append(prefix, "--", name)
+	return s.kind, append(wrapped, args...)
+}
+
+// credential is the user commands run as; nil keeps the server's.
+func (s sandbox) credential() *syscall.Credential {
+	if s.kind == "" || (s.uid <= 0 && s.gid <= 0) {
+		return nil
+	}
+	uid, gid := s.uid, s.gid
+	if uid <= 0 {
+		uid = os.Getuid()
+	}
+	if gid <= 0 {
+		gid = os.Getgid()
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func TestSandboxWrap(t *testing.T) {
+	args := []string{"-i", "in.mp4", "out.webm"}
+	if name, got := (sandbox{}).wrap("ffmpeg", args); name != "ffmpeg" || !reflect.DeepEqual(got, args) {
+		t.Fatalf("no sandbox: got %s %v", name, got)
+	}
+
+	s := sandbox{kind: "firejail", writable: []string{"/srv/uploads", "/tmp"}}
+	name, got := s.wrap("ffmpeg", args)
+	want := []string{"--quiet", "--noprofile", "--net=none", "--seccomp", "--caps.drop=all", "--nonewprivs", "--nogroups",
+		"--read-only=/", "--read-write=/srv/uploads", "--read-write=/tmp", "--", "ffmpeg", "-i", "in.mp4", "out.webm"}
+	if name != "firejail" || !reflect.DeepEqual(got, want) {
+		t.Fatalf("firejail: got %s %v", name, got)
+	}
+
+	s.kind = "bwrap"
+	name, got = s.wrap("ffmpeg", args)
+	want = []string{"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--bind", "/srv/uploads", "/srv/uploads",
+		"--bind", "/tmp", "/tmp", "--unshare-all", "--die-with-parent", "--new-session", "--cap-drop", "ALL",
+		"--", "ffmpeg", "-i", "in.mp4", "out.webm"}
+	if name != "bwrap" || !reflect.DeepEqual(got, want) {
+		t.Fatalf("bwrap: got %s %v", name, got)
+	}
+
+	s.network = true
+	if _, got = s.wrap("ffmpeg", args); !slices.Contains(got, "--share-net") {
+		t.Fatalf("bwrap with network: got %v", got)
+	}
+	fj := sandbox{kind: "firejail", network: true}
+	if _, got = fj.wrap("ffmpeg", args); slices.Contains(got, "--net=none") {
+		t.Fatalf("firejail with network: got %v", got)
+	}
+	s.network = false
+
+	// The limits run inside the sandbox, so they apply to the converter
+	// rather than to the sandbox tool.
+	name, got = processLimits{nice: 5, sandbox: s}.wrap("ffmpeg", args)
+	if i := slices.Index(got, "--"); name != "bwrap" || i < 0 || !reflect.DeepEqual(got[i+1:], []string{"nice", "-n", "5", "ffmpeg", "-i", "in.mp4", "out.webm"}) {
+		t.Fatalf("limits in sandbox: got %s %v", name, got)
+	}
+}
+
+func TestNewSandbox(t *testing.T) {
+	root := t.TempDir()
+	cfg := &config.Config{UploadDir: filepath.Join(root, "uploads"), OutputDir: "outputs", TempDir: filepath.Join(root, "temp")}
+	if s := newSandbox(cfg); s.kind != "" || s.writable != nil {
+		t.Fatalf("no SANDBOX: %+v", s)
+	}
+	cfg.Sandbox = "bwrap"
+	s := newSandbox(cfg)
+	outputs, _ := filepath.Abs("outputs")
+	for _, dir := range []string{cfg.UploadDir, outputs, cfg.TempDir, os.TempDir()} {
+		if !slices.Contains(s.writable, dir) {
+			t.Errorf("writable %v is missing %s", s.writable, dir)
+		}
+	}
+	if s.credential() != nil {
+		t.Error("no SANDBOX_UID should keep the server's user")
+	}
+	s.uid = 1234
+	if c := s.credential(); c == nil || c.Uid != 1234 || c.Gid != uint32(os.Getgid()) {
+		t.Errorf("credential = %+v", c)
+	}
+}
+
+func TestCheckSandbox(t *testing.T) {
+	if err := CheckSandbox(&config.Config{}); err != nil {
+		t.Fatalf("no sandbox: %v", err)
+	}
+	for _, cfg := range []*config.Config{{Sandbox: "docker"}, {SandboxUID: 1000}} {
+		if CheckSandbox(cfg) == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+	if os.Geteuid() != 0 {
+		if err := CheckSandbox(&config.Config{Sandbox: "firejail", SandboxUID: 1000}); err == nil {
+			t.Error("SANDBOX_UID without root should be refused")
+		}
+	}
+}
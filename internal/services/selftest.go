@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+// SelfTestService runs a battery of tiny conversions against synthetic
+// inputs (FFmpeg lavfi test sources, an ImageMagick gradient) so an operator
+// can confirm every encoder path works after a dependency upgrade or on a
+// freshly provisioned worker image. Each case reuses the production argument
+// builders — buildVideoCodecArgs, audioEncoderArgs, the ImageMagick command
+// resolver — so a pass here means the real pipeline's flags are accepted.
+type SelfTestService struct {
+	cfg *config.Config
+
+	// mu serializes runs; the suite is cheap but there's no value in letting
+	// two admins hammer the encoders concurrently.
+	mu sync.Mutex
+}
+
+func NewSelfTestService(cfg *config.Config) *SelfTestService {
+	return &SelfTestService{cfg: cfg}
+}
+
+// SelfTestResult is the outcome of one capability check.
+type SelfTestResult struct {
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	Passed     bool   `json:"passed"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SelfTestReport aggregates every check. Passed is true only when no
+// non-skipped case failed.
+type SelfTestReport struct {
+	Passed     bool             `json:"passed"`
+	PassCount  int              `json:"passCount"`
+	FailCount  int              `json:"failCount"`
+	SkipCount  int              `json:"skipCount"`
+	DurationMs int64            `json:"durationMs"`
+	StartedAt  time.Time        `json:"startedAt"`
+	Results    []SelfTestResult `json:"results"`
+}
+
+// selfTestCaseTimeout bounds each individual conversion. The samples are a
+// second long at thumbnail resolution, so anything slower is a hang.
+const selfTestCaseTimeout = 60 * time.Second
+
+var (
+	selfTestVideoFormats = []string{"mp4", "webm", "mov", "mkv", "avi", "flv", "wmv", "prores", "dnxhd"}
+	selfTestAudioFormats = []string{"mp3", "wav", "aac", "ogg", "flac", "opus", "ac3"}
+	selfTestImageFormats = []string{"jpg", "png", "webp", "gif", "avif", "jxl", "pdf", "ico"}
+	selfTestTools        = []string{"ffmpeg", "ffprobe", "exiftool", "gifsicle", "potrace", "pdfinfo"}
+)
+
+// Run executes the whole suite in a scratch directory under TempDir and
+// returns the per-capability results. It never returns an error for a failed
+// case; only setup failures (no scratch dir) abort the run.
+func (s *SelfTestService) Run(ctx context.Context) (*SelfTestReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	baseDir := os.TempDir()
+	if s.cfg != nil && s.cfg.TempDir != "" {
+		baseDir = s.cfg.TempDir
+	}
+	workDir, err := os.MkdirTemp(baseDir, "selftest-")
+	if err != nil {
+		return nil, fmt.Errorf("create self-test workdir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	report := &SelfTestReport{StartedAt: time.Now().UTC()}
+	record := func(name, category string, fn func(ctx context.Context) error) {
+		caseCtx, cancel := context.WithTimeout(ctx, selfTestCaseTimeout)
+		defer cancel()
+		start := time.Now()
+		err := fn(caseCtx)
+		res := SelfTestResult{Name: name, Category: category, DurationMs: time.Since(start).Milliseconds()}
+		var skip *selfTestSkip
+		switch {
+		case errors.As(err, &skip):
+			res.Skipped = true
+			res.Error = skip.reason
+		case err != nil:
+			res.Error = err.Error()
+		default:
+			res.Passed = true
+		}
+		report.Results = append(report.Results, res)
+	}
+
+	for _, tool := range selfTestTools {
+		tool := tool
+		record(tool, "tool", func(context.Context) error {
+			if _, err := exec.LookPath(tool); err != nil {
+				return fmt.Errorf("%s not found on PATH", tool)
+			}
+			return nil
+		})
+	}
+
+	videoSample := filepath.Join(workDir, "sample_video.mkv")
+	audioSample := filepath.Join(workDir, "sample_audio.wav")
+	imageSample := filepath.Join(workDir, "sample_image.png")
+
+	record("sample_video", "sample", func(ctx context.Context) error {
+		// 256x144 keeps DNxHR happy (it needs 16-aligned dimensions) while
+		// staying tiny. FFV1 is lossless and always built into FFmpeg.
+		return selfTestFFmpeg(ctx,
+			"-f", "lavfi", "-i", "testsrc2=size=256x144:rate=24:duration=1",
+			"-f", "lavfi", "-i", "sine=frequency=440:sample_rate=48000:duration=1",
+			"-c:v", "ffv1", "-c:a", "pcm_s16le", "-shortest", videoSample)
+	})
+	record("sample_audio", "sample", func(ctx context.Context) error {
+		return selfTestFFmpeg(ctx, "-f", "lavfi", "-i", "sine=frequency=440:sample_rate=48000:duration=1", "-ac", "2", audioSample)
+	})
+	record("sample_image", "sample", func(ctx context.Context) error {
+		name, args := resolveImageMagickConvertCommand("convert", []string{"-size", "64x64", "gradient:#336699-#ffcc00", imageSample})
+		if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+			return fmt.Errorf("%w (%s)", err, commandTail(stderr, 500))
+		}
+		return nil
+	})
+
+	for _, format := range selfTestVideoFormats {
+		format := format
+		record("video_"+format, "video", func(ctx context.Context) error {
+			if !fileExists(videoSample) {
+				return &selfTestSkip{reason: "sample video unavailable"}
+			}
+			webmVP9 := format == "webm" && ffmpegSupportsWebMVP9()
+			args := []string{"-i", videoSample}
+			args = append(args, buildVideoCodecArgs(videoEncodeSettings{Format: format, Quality: "low", WebMVP9: webmVP9})...)
+			out := filepath.Join(workDir, "out_video"+videoFormatExtension(format))
+			args = append(args, out)
+			return selfTestFFmpegOutput(ctx, out, args...)
+		})
+	}
+
+	for _, format := range selfTestAudioFormats {
+		format := format
+		record("audio_"+format, "audio", func(ctx context.Context) error {
+			if !fileExists(audioSample) {
+				return &selfTestSkip{reason: "sample audio unavailable"}
+			}
+			out := filepath.Join(workDir, "out_audio."+format)
+			args := append([]string{"-i", audioSample}, audioEncoderArgs(out)...)
+			args = append(args, out)
+			return selfTestFFmpegOutput(ctx, out, args...)
+		})
+	}
+
+	for _, format := range selfTestImageFormats {
+		format := format
+		record("image_"+format, "image", func(ctx context.Context) error {
+			if !fileExists(imageSample) {
+				return &selfTestSkip{reason: "sample image unavailable"}
+			}
+			out := filepath.Join(workDir, "out_image."+format)
+			name, args := resolveImageMagickConvertCommand("convert", []string{imageSample, out})
+			if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+				return fmt.Errorf("%w (%s)", err, commandTail(stderr, 500))
+			}
+			return requireNonEmpty(out)
+		})
+	}
+
+	record("probe", "inspect", func(ctx context.Context) error {
+		if !fileExists(videoSample) {
+			return &selfTestSkip{reason: "sample video unavailable"}
+		}
+		if !ffprobeHasStream(ctx, videoSample, "v") || !ffprobeHasStream(ctx, videoSample, "a") {
+			return errors.New("ffprobe did not report the sample's audio and video streams")
+		}
+		return nil
+	})
+
+	for _, r := range report.Results {
+		switch {
+		case r.Skipped:
+			report.SkipCount++
+		case r.Passed:
+			report.PassCount++
+		default:
+			report.FailCount++
+		}
+	}
+	report.Passed = report.FailCount == 0
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// selfTestSkip marks a case that could not run because a prerequisite
+// (usually a sample) failed; the prerequisite's own failure is what counts.
+type selfTestSkip struct {
+	reason string
+}
+
+func (s *selfTestSkip) Error() string { return s.reason }
+
+func selfTestFFmpeg(ctx context.Context, args ...string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+	full := append([]string{"-y", "-hide_banner", "-loglevel", "error"}, args...)
+	if _, stderr, err := runCommand(ctx, "ffmpeg", full...); err != nil {
+		return fmt.Errorf("%w (%s)", err, commandTail(stderr, 500))
+	}
+	return nil
+}
+
+func selfTestFFmpegOutput(ctx context.Context, out string, args ...string) error {
+	if err := selfTestFFmpeg(ctx, args...); err != nil {
+		return err
+	}
+	return requireNonEmpty(out)
+}
+
+func requireNonEmpty(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("output missing: %w", err)
+	}
+	if info.Size() == 0 {
+		return errors.New("output is empty")
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// videoFormatExtension maps a video format option onto a container extension
+// FFmpeg can infer a muxer from. The editing intermediates ride in .mov.
+func videoFormatExtension(format string) string {
+	switch format {
+	case "prores", "dnxhd":
+		return ".mov"
+	default:
+		return "." + format
+	}
+}
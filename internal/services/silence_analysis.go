@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Silence analysis for POST /api/analyze/silence: the silencedetect filter
+// logs where each quiet stretch starts and ends, so editors can review the
+// cuts the restoration.silenceDetection option would make before making
+// them.
+
+const (
+	DefaultSilenceThresholdDB = -50.0
+	DefaultSilenceMinDuration = 0.5
+)
+
+// SilenceAnalysis lists the silent intervals of one audio stream.
+type SilenceAnalysis struct {
+	ThresholdDB         float64           `json:"thresholdDb"`
+	MinDuration         float64           `json:"minDuration"`
+	DurationSeconds     float64           `json:"durationSeconds"`
+	TotalSilenceSeconds float64           `json:"totalSilenceSeconds"`
+	SilencePercent      float64           `json:"silencePercent"`
+	Intervals           []SilenceInterval `json:"intervals"`
+}
+
+// SilenceInterval is one stretch below the threshold, in seconds.
+type SilenceInterval struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+}
+
+var (
+	silenceDetectLine = regexp.MustCompile(`silence_(start|end):\s*(-?[0-9.]+)`)
+	ffmpegDuration    = regexp.MustCompile(`Duration:\s*(\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+)
+
+// AnalyzeSilence finds the stretches of the first audio stream of inputPath
+// quieter than thresholdDB for at least minDuration seconds.
+func AnalyzeSilence(ctx context.Context, inputPath string, thresholdDB, minDuration float64) (*SilenceAnalysis, error) {
+	filter := fmt.Sprintf("silencedetect=noise=%.1fdB:d=%.2f", thresholdDB, minDuration)
+	args := []string{"-hide_banner", "-nostats", "-i", inputPath,
+		"-map", "0:a:0", "-af", filter, "-f", "null", "-"}
+	_, stderr, err := runCommand(ctx, "ffmpeg", args...)
+	if err != nil {
+		return nil, fmt.Errorf("silence analysis failed: %w (%s)", err, commandTail(stderr, 800))
+	}
+	a := parseSilenceDetect(stderr)
+	a.ThresholdDB, a.MinDuration = thresholdDB, minDuration
+	return a, nil
+}
+
+// parseSilenceDetect pairs the filter's silence_start / silence_end lines.
+// Silence that runs to the end of the file has no end line in older FFmpeg
+// builds, so it is closed at the input duration.
+func parseSilenceDetect(stderr string) *SilenceAnalysis {
+	a := &SilenceAnalysis{Intervals: []SilenceInterval{}}
+	if m := ffmpegDuration.FindStringSubmatch(stderr); m != nil {
+		hours, _ := strconv.ParseFloat(m[1], 64)
+		minutes, _ := strconv.ParseFloat(m[2], 64)
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		a.DurationSeconds = hours*3600 + minutes*60 + seconds
+	}
+
+	open := math.NaN()
+	closeAt := func(end float64) {
+		start := math.Max(open, 0)
+		if end > start {
+			a.Intervals = append(a.Intervals, SilenceInterval{
+				Start:    roundMillis(start),
+				End:      roundMillis(end),
+				Duration: roundMillis(end - start),
+			})
+		}
+		open = math.NaN()
+	}
+	for _, m := range silenceDetectLine.FindAllStringSubmatch(stderr, -1) {
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		if m[1] == "start" {
+			open = v
+		} else if !math.IsNaN(open) {
+			closeAt(v)
+		}
+	}
+	if !math.IsNaN(open) && a.DurationSeconds > 0 {
+		closeAt(a.DurationSeconds)
+	}
+
+	for _, iv := range a.Intervals {
+		a.TotalSilenceSeconds += iv.Duration
+	}
+	a.TotalSilenceSeconds = roundMillis(a.TotalSilenceSeconds)
+	if a.DurationSeconds > 0 {
+		a.SilencePercent = math.Round(a.TotalSilenceSeconds/a.DurationSeconds*1000) / 10
+	}
+	return a
+}
+
+func roundMillis(v float64) float64 { return math.Round(v*1000) / 1000 }
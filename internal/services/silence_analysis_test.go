@@ -0,0 +1,36 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSilenceDetect(t *testing.T) {
+	stderr := `Input #0, mp3, from 'episode.mp3':
+  Duration: 00:01:00.50, start: 0.025057, bitrate: 128 kb/s
+[silencedetect @ 0x5581] silence_start: -0.0123
+[silencedetect @ 0x5581] silence_end: 1.5 | silence_duration: 1.5123
+[silencedetect @ 0x5581] silence_start: 20.25
+[silencedetect @ 0x5581] silence_end: 23.75 | silence_duration: 3.5
+[silencedetect @ 0x5581] silence_start: 58.5
+size=N/A time=00:01:00.50 bitrate=N/A speed= 512x`
+	a := parseSilenceDetect(stderr)
+	want := []SilenceInterval{
+		{Start: 0, End: 1.5, Duration: 1.5},
+		{Start: 20.25, End: 23.75, Duration: 3.5},
+		{Start: 58.5, End: 60.5, Duration: 2},
+	}
+	if !reflect.DeepEqual(a.Intervals, want) {
+		t.Fatalf("intervals = %+v, want %+v", a.Intervals, want)
+	}
+	if a.DurationSeconds != 60.5 || a.TotalSilenceSeconds != 7 || a.SilencePercent != 11.6 {
+		t.Fatalf("totals = %+v", a)
+	}
+}
+
+func TestParseSilenceDetectNoSilence(t *testing.T) {
+	a := parseSilenceDetect("  Duration: 00:00:05.00, start: 0.000000, bitrate: 1411 kb/s\n")
+	if len(a.Intervals) != 0 || a.Intervals == nil || a.SilencePercent != 0 {
+		t.Fatalf("analysis = %+v", a)
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Smart crop cuts an image to an aspect ratio and decides where the window
+// sits. The window is resolved into a plain crop rectangle before the
+// ImageMagick chain runs, so the rest of the pipeline treats it like a
+// user-drawn crop. Content-aware modes score a small grayscale preview.
+
+// smartCropPreviewSize bounds the preview the content-aware modes score.
+const smartCropPreviewSize = 256
+
+var smartCropModes = map[string]bool{"center": true, "gravity": true, "entropy": true, "attention": true, "face": true}
+
+// validateSmartCrop checks the smartCrop options.
+func validateSmartCrop(options *models.ImageConversionOptions) error {
+	sc := options.SmartCrop
+	if sc == nil {
+		return nil
+	}
+	if options.Crop != nil {
+		return fmt.Errorf("smartCrop cannot be combined with crop")
+	}
+	if _, err := parseAspectRatio(sc.AspectRatio); err != nil {
+		return err
+	}
+	if sc.Mode != "" && !smartCropModes[sc.Mode] {
+		return fmt.Errorf("unsupported smartCrop mode: %s", sc.Mode)
+	}
+	if sc.Gravity != "" && !validImageGravities()[sc.Gravity] {
+		return fmt.Errorf("unsupported smartCrop gravity: %s", sc.Gravity)
+	}
+	return nil
+}
+
+// parseAspectRatio parses "W:H" (e.g. "1:1", "4:5", "16:9") into W/H.
+func parseAspectRatio(s string) (float64, error) {
+	w, h, ok := strings.Cut(strings.TrimSpace(s), ":")
+	wf, err1 := strconv.ParseFloat(w, 64)
+	hf, err2 := strconv.ParseFloat(h, 64)
+	if !ok || err1 != nil || err2 != nil || wf <= 0 || hf <= 0 {
+		return 0, fmt.Errorf("aspectRatio must look like 1:1 or 16:9, got %q", s)
+	}
+	return wf / hf, nil
+}
+
+// smartCropSize is the largest width x height window of the given ratio
+// that fits in the image.
+func smartCropSize(imgW, imgH int, ratio float64) (int, int) {
+	cw, ch := imgW, int(math.Round(float64(imgW)/ratio))
+	if ch > imgH {
+		cw, ch = int(math.Round(float64(imgH)*ratio)), imgH
+	}
+	return max(cw, 1), max(ch, 1)
+}
+
+// gravityCropOffset places the window by ImageMagick gravity name.
+func gravityCropOffset(imgW, imgH, cw, ch int, gravity string) image.Point {
+	x, y := (imgW-cw)/2, (imgH-ch)/2
+	if strings.Contains(gravity, "West") {
+		x = 0
+	} else if strings.Contains(gravity, "East") {
+		x = imgW - cw
+	}
+	if strings.HasPrefix(gravity, "North") {
+		y = 0
+	} else if strings.HasPrefix(gravity, "South") {
+		y = imgH - ch
+	}
+	return image.Pt(x, y)
+}
+
+// faceCropOffset centres the window horizontally on the faces and puts them
+// in the upper part of the frame, where portrait crops keep the head and
+// leave room for the shoulders. Boxes are normalized to the image.
+func faceCropOffset(imgW, imgH, cw, ch int, faces []models.FaceBox) image.Point {
+	minX, minY, maxX, maxY := 1.0, 1.0, 0.0, 0.0
+	for _, f := range faces {
+		minX, minY = math.Min(minX, f.X), math.Min(minY, f.Y)
+		maxX, maxY = math.Max(maxX, f.X+f.Width), math.Max(maxY, f.Y+f.Height)
+	}
+	cx := (minX + maxX) / 2 * float64(imgW)
+	cy := (minY + maxY) / 2 * float64(imgH)
+	x := int(math.Round(cx - float64(cw)/2))
+	y := int(math.Round(cy - float64(ch)*0.4))
+	// Never cut the top of the highest face when the window is tall enough.
+	if top := int(minY * float64(imgH)); y > top && int(maxY*float64(imgH))-top <= ch {
+		y = top
+	}
+	return image.Pt(clampInt(x, 0, imgW-cw), clampInt(y, 0, imgH-ch))
+}
+
+func clampInt(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
+
+// windowEntropy is the Shannon entropy of the luminance histogram in r.
+func windowEntropy(g *image.Gray, r image.Rectangle) float64 {
+	var hist [256]int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			hist[g.GrayAt(x, y).Y]++
+		}
+	}
+	total := float64(r.Dx() * r.Dy())
+	entropy := 0.0
+	for _, n := range hist {
+		if n > 0 {
+			p := float64(n) / total
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// windowAttention is the mean gradient magnitude in r: detailed, in-focus
+// subjects score higher than smooth backgrounds and bokeh.
+func windowAttention(g *image.Gray, r image.Rectangle) float64 {
+	b := g.Bounds()
+	sum := 0.0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if x+1 >= b.Max.X || y+1 >= b.Max.Y {
+				continue
+			}
+			v := float64(g.GrayAt(x, y).Y)
+			dx := float64(g.GrayAt(x+1, y).Y) - v
+			dy := float64(g.GrayAt(x, y+1).Y) - v
+			sum += math.Hypot(dx, dy)
+		}
+	}
+	return sum / float64(r.Dx()*r.Dy())
+}
+
+// bestCropOffset slides a cw x ch window over g along its free axis and
+// returns the offset of the best-scoring position. Ties keep the one nearest
+// the centre.
+func bestCropOffset(g *image.Gray, cw, ch int, score func(*image.Gray, image.Rectangle) float64) image.Point {
+	b := g.Bounds()
+	best, bestScore, bestDist := image.Pt((b.Dx()-cw)/2, (b.Dy()-ch)/2), math.Inf(-1), math.Inf(1)
+	center := image.Pt((b.Dx()-cw)/2, (b.Dy()-ch)/2)
+	for y := 0; y+ch <= b.Dy(); y++ {
+		for x := 0; x+cw <= b.Dx(); x++ {
+			s := score(g, image.Rect(x, y, x+cw, y+ch).Add(b.Min))
+			d := math.Hypot(float64(x-center.X), float64(y-center.Y))
+			if s > bestScore+1e-9 || (math.Abs(s-bestScore) <= 1e-9 && d < bestDist) {
+				best, bestScore, bestDist = image.Pt(x, y), s, d
+			}
+		}
+	}
+	return best
+}
+
+// smartCropPreview renders inputPath as seen after -auto-orient into a small
+// grayscale image and reports the full-size oriented dimensions.
+func smartCropPreview(ctx context.Context, inputPath, workDir string, autoOrient bool) (*image.Gray, int, int, error) {
+	src := []string{inputPath + "[0]"}
+	if autoOrient {
+		src = append(src, "-auto-orient")
+	}
+	name, args := resolveImageMagickConvertCommand("convert", append(append([]string{}, src...), "-format", "%w %h", "info:"))
+	stdout, stderr, err := runCommand(ctx, name, args...)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("smart crop probe failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	var w, h int
+	if _, err := fmt.Sscanf(strings.TrimSpace(stdout), "%d %d", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return nil, 0, 0, fmt.Errorf("smart crop probe returned %q", stdout)
+	}
+
+	previewPath := filepath.Join(workDir, "smart_crop_preview.png")
+	size := strconv.Itoa(smartCropPreviewSize)
+	name, args = resolveImageMagickConvertCommand("convert", append(src, "-resize", size+"x"+size+">", "-colorspace", "Gray", "PNG:"+previewPath))
+	if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+		return nil, 0, 0, fmt.Errorf("smart crop preview failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	f, err := os.Open(previewPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("smart crop preview decode failed: %w", err)
+	}
+	gray := image.NewGray(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(x, y)))
+		}
+	}
+	return gray, w, h, nil
+}
+
+// resolveSmartCrop turns options.SmartCrop into options.Crop.
+func (c *Converter) resolveSmartCrop(ctx context.Context, inputPath string, options *models.ImageConversionOptions) error {
+	sc := options.SmartCrop
+	ratio, err := parseAspectRatio(sc.AspectRatio)
+	if err != nil {
+		return err
+	}
+	workDir, err := os.MkdirTemp(c.cfg.TempDir, "smart-crop-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	autoOrient := options.AutoOrient == nil || *options.AutoOrient
+	preview, w, h, err := smartCropPreview(ctx, inputPath, workDir, autoOrient)
+	if err != nil {
+		return err
+	}
+	cw, ch := smartCropSize(w, h, ratio)
+
+	mode := sc.Mode
+	if mode == "face" {
+		// With no detector or no faces found, fall back to attention.
+		var faces []models.FaceBox
+		if c.faceDetectionAvailable() {
+			if res, err := c.detectOrientedFaces(ctx, inputPath, workDir, autoOrient); err == nil && res.ImageWidth == w && res.ImageHeight == h {
+				faces = res.Faces
+			}
+		}
+		if len(faces) > 0 {
+			options.Crop = cropArea(faceCropOffset(w, h, cw, ch, faces), cw, ch)
+			options.SmartCrop = nil
+			return nil
+		}
+		mode = "attention"
+	}
+
+	var offset image.Point
+	switch mode {
+	case "entropy", "attention":
+		score := windowAttention
+		if mode == "entropy" {
+			score = windowEntropy
+		}
+		scale := float64(preview.Bounds().Dx()) / float64(w)
+		pw := clampInt(int(math.Round(float64(cw)*scale)), 1, preview.Bounds().Dx())
+		ph := clampInt(int(math.Round(float64(ch)*scale)), 1, preview.Bounds().Dy())
+		p := bestCropOffset(preview, pw, ph, score)
+		offset = image.Pt(clampInt(int(math.Round(float64(p.X)/scale)), 0, w-cw), clampInt(int(math.Round(float64(p.Y)/scale)), 0, h-ch))
+	default:
+		gravity := sc.Gravity
+		if mode == "center" {
+			gravity = "Center"
+		}
+		offset = gravityCropOffset(w, h, cw, ch, gravity)
+	}
+	options.Crop = cropArea(offset, cw, ch)
+	options.SmartCrop = nil
+	return nil
+}
+
+// detectOrientedFaces runs face detection on the picture as it will be
+// cropped: the detector reads pixels as stored and ignores EXIF orientation.
+func (c *Converter) detectOrientedFaces(ctx context.Context, inputPath, workDir string, autoOrient bool) (*models.FaceDetectionResponse, error) {
+	if !autoOrient {
+		return c.ai.DetectFaces(ctx, inputPath)
+	}
+	oriented := filepath.Join(workDir, "smart_crop_oriented.png")
+	name, args := resolveImageMagickConvertCommand("convert", []string{inputPath + "[0]", "-auto-orient", "PNG:" + oriented})
+	if _, stderr, err := runCommand(ctx, name, args...); err != nil {
+		return nil, fmt.Errorf("smart crop render failed: %w (%s)", err, strings.TrimSpace(stderr))
+	}
+	return c.ai.DetectFaces(ctx, oriented)
+}
+
+func cropArea(p image.Point, w, h int) *models.CropArea {
+	return &models.CropArea{X: p.X, Y: p.Y, Width: w, Height: h}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestSmartCropSize(t *testing.T) {
+	cases := []struct {
+		w, h   int
+		ratio  string
+		cw, ch int
+	}{
+		{4000, 3000, "1:1", 3000, 3000},
+		{3000, 4000, "1:1", 3000, 3000},
+		{1920, 1080, "4:5", 864, 1080},
+		{1080, 1920, "16:9", 1080, 608},
+	}
+	for _, tc := range cases {
+		ratio, err := parseAspectRatio(tc.ratio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cw, ch := smartCropSize(tc.w, tc.h, ratio); cw != tc.cw || ch != tc.ch {
+			t.Errorf("smartCropSize(%d, %d, %s) = %dx%d, want %dx%d", tc.w, tc.h, tc.ratio, cw, ch, tc.cw, tc.ch)
+		}
+	}
+	if _, err := parseAspectRatio("square"); err == nil {
+		t.Error("expected an invalid aspect ratio to fail")
+	}
+}
+
+func TestGravityCropOffset(t *testing.T) {
+	if got := gravityCropOffset(400, 300, 300, 300, "Center"); got != image.Pt(50, 0) {
+		t.Errorf("Center = %v", got)
+	}
+	if got := gravityCropOffset(300, 400, 300, 300, "North"); got != image.Pt(0, 0) {
+		t.Errorf("North = %v", got)
+	}
+	if got := gravityCropOffset(400, 300, 300, 300, "SouthEast"); got != image.Pt(100, 0) {
+		t.Errorf("SouthEast = %v", got)
+	}
+}
+
+func TestFaceCropOffsetKeepsHead(t *testing.T) {
+	// Portrait 3000x4000 with a face near the top: a square crop must start
+	// at or above the top of the face rather than in the middle.
+	faces := []models.FaceBox{{X: 0.4, Y: 0.1, Width: 0.2, Height: 0.15}}
+	got := faceCropOffset(3000, 4000, 3000, 3000, faces)
+	if got.Y > 400 {
+		t.Fatalf("offset %v cuts the head (face top at y=400)", got)
+	}
+}
+
+func TestBestCropOffsetFindsDetail(t *testing.T) {
+	// Flat image with a checkerboard patch on the right third.
+	g := image.NewGray(image.Rect(0, 0, 90, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 90; x++ {
+			v := uint8(128)
+			if x >= 60 && (x+y)%2 == 0 {
+				v = 255
+			}
+			g.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	for name, score := range map[string]func(*image.Gray, image.Rectangle) float64{"attention": windowAttention, "entropy": windowEntropy} {
+		if got := bestCropOffset(g, 30, 30, score); got.X < 55 {
+			t.Errorf("%s picked x=%d, want the detailed right side", name, got.X)
+		}
+	}
+}
+
+func TestValidateSmartCrop(t *testing.T) {
+	ok := &models.ImageConversionOptions{SmartCrop: &models.SmartCropOptions{AspectRatio: "1:1", Mode: "face"}}
+	if err := validateSmartCrop(ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	withCrop := &models.ImageConversionOptions{Crop: &models.CropArea{Width: 10, Height: 10}, SmartCrop: &models.SmartCropOptions{AspectRatio: "1:1"}}
+	if err := validateSmartCrop(withCrop); err == nil {
+		t.Fatal("expected smartCrop with crop to fail")
+	}
+	badMode := &models.ImageConversionOptions{SmartCrop: &models.SmartCropOptions{AspectRatio: "1:1", Mode: "magic"}}
+	if err := validateSmartCrop(badMode); err == nil {
+		t.Fatal("expected an unknown mode to fail")
+	}
+}
@@ -586,9 +586,19 @@ type TrimVideoOptions struct {
 	// CopyMode: auto (default — stream-copy then re-encode fallback),
 	// stream_copy (copy only), or reencode (always re-encode for frame accuracy).
 	CopyMode string `json:"copyMode"`
+	// AccurateSeek cuts on the exact requested frames: the stream-copy
+	// attempt (which can only start on a keyframe) is skipped and the final
+	// seek happens after the input is decoded. Not combinable with
+	// stream_copy.
+	AccurateSeek bool `json:"accurateSeek"`
 }
 
-const trimVideoMaxDurationSec = 6 * 60 * 60 // 6h, matches the command timeout.
+const (
+	trimVideoMaxDurationSec = 6 * 60 * 60 // 6h, matches the command timeout.
+	// trimAccuratePreroll is how far before the cut the fast input-side seek
+	// lands; the output-side seek decodes the rest.
+	trimAccuratePreroll = 5.0
+)
 
 func parseTrimVideoOptions(raw map[string]any) TrimVideoOptions {
 	o := TrimVideoOptions{}
@@ -601,6 +611,9 @@ func parseTrimVideoOptions(raw map[string]any) TrimVideoOptions {
 	if v, ok := raw["copyMode"].(string); ok {
 		o.CopyMode = strings.ToLower(strings.TrimSpace(v))
 	}
+	if v, ok := raw["accurateSeek"].(bool); ok {
+		o.AccurateSeek = v
+	}
 	o.StartTime = floatFromAny(raw["startTime"])
 	o.EndTime = floatFromAny(raw["endTime"])
 	return o
@@ -623,6 +636,9 @@ func (o *TrimVideoOptions) applyDefaults() error {
 	default:
 		return fmt.Errorf("invalid copyMode: %q (expected auto|stream_copy|reencode)", o.CopyMode)
 	}
+	if o.AccurateSeek && o.CopyMode == "stream_copy" {
+		return errors.New("accurateSeek needs a re-encode; a stream copy can only start on a keyframe (use copyMode auto or reencode)")
+	}
 	if math.IsNaN(o.StartTime) || math.IsInf(o.StartTime, 0) || o.StartTime < 0 {
 		return fmt.Errorf("invalid start time: %v", o.StartTime)
 	}
@@ -677,8 +693,8 @@ func (s *SpecializedToolsService) runTrimVideo(ctx context.Context, job *models.
 		if opts.Format == "webm" {
 			webmVP9 = ffmpegSupportsWebMVP9()
 		}
-		// -ss before -i is decode-accurate for re-encode in modern FFmpeg.
-		args := []string{"-y", "-ss", start, "-i", inputPath, "-t", duration, "-map", "0:v:0", "-map", "0:a?"}
+		args := append([]string{"-y"}, trimReencodeSeekArgs(opts, inputPath)...)
+		args = append(args, "-map", "0:v:0", "-map", "0:a?")
 		args = append(args, buildVideoCodecArgs(videoEncodeSettings{Format: opts.Format, Quality: "high", WebMVP9: webmVP9})...)
 		args = append(args, outputPath)
 		if _, stderr, err := runCommand(ctx, "ffmpeg", args...); err != nil {
@@ -687,12 +703,12 @@ func (s *SpecializedToolsService) runTrimVideo(ctx context.Context, job *models.
 		return nil
 	}
 
-	switch opts.CopyMode {
-	case "reencode":
+	switch {
+	case opts.CopyMode == "reencode" || opts.AccurateSeek:
 		if err := reencode(); err != nil {
 			return err
 		}
-	case "stream_copy":
+	case opts.CopyMode == "stream_copy":
 		if _, stderr, err := runCommand(ctx, "ffmpeg", copyArgs...); err != nil {
 			return fmt.Errorf("ffmpeg trim (stream-copy) failed: %w (%s)", err, tail(stderr, 1500))
 		}
@@ -708,6 +724,23 @@ func (s *SpecializedToolsService) runTrimVideo(ctx context.Context, job *models.
 	return nil
 }
 
+// trimReencodeSeekArgs selects the clip for a re-encode. -ss before -i is
+// decode-accurate in modern FFmpeg; with AccurateSeek the input-side seek only
+// gets within trimAccuratePreroll seconds of the cut and an output-side -ss
+// decodes up to the exact frame, so the cut does not depend on how precisely
+// the demuxer can seek.
+func trimReencodeSeekArgs(opts TrimVideoOptions, inputPath string) []string {
+	duration := fmt.Sprintf("%.3f", opts.EndTime-opts.StartTime)
+	if !opts.AccurateSeek {
+		return []string{"-ss", fmt.Sprintf("%.3f", opts.StartTime), "-i", inputPath, "-t", duration}
+	}
+	preroll := math.Min(opts.StartTime, trimAccuratePreroll)
+	return []string{
+		"-ss", fmt.Sprintf("%.3f", opts.StartTime-preroll), "-i", inputPath,
+		"-ss", fmt.Sprintf("%.3f", preroll), "-t", duration,
+	}
+}
+
 // floatFromAny coerces a JSON-decoded value (float64/int/int64/string) into a
 // float64, returning 0 when it can't.
 func floatFromAny(v any) float64 {
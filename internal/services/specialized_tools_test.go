@@ -1,6 +1,9 @@
 package services
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestAudioWaveformOptions_Defaults(t *testing.T) {
 	opts := &AudioWaveformOptions{}
@@ -137,3 +140,24 @@ func TestTrimVideoOptions_RejectsBadFormatAndMode(t *testing.T) {
 		t.Fatalf("expected rejection of bad copyMode")
 	}
 }
+
+func TestTrimVideoOptions_AccurateSeek(t *testing.T) {
+	o := parseTrimVideoOptions(map[string]any{"startTime": 62.5, "endTime": 70.0, "accurateSeek": true})
+	if err := o.applyDefaults(); err != nil {
+		t.Fatalf("applyDefaults: %v", err)
+	}
+	got := strings.Join(trimReencodeSeekArgs(o, "in.mp4"), " ")
+	if want := "-ss 57.500 -i in.mp4 -ss 5.000 -t 7.500"; got != want {
+		t.Fatalf("seek args = %q, want %q", got, want)
+	}
+	// Near the start there is less to pre-roll.
+	o.StartTime = 2
+	if got := strings.Join(trimReencodeSeekArgs(o, "in.mp4"), " "); got != "-ss 0.000 -i in.mp4 -ss 2.000 -t 68.000" {
+		t.Fatalf("early seek args = %q", got)
+	}
+
+	o = TrimVideoOptions{CopyMode: "stream_copy", AccurateSeek: true, StartTime: 1, EndTime: 5}
+	if err := o.applyDefaults(); err == nil {
+		t.Fatalf("expected accurateSeek with stream_copy to be rejected")
+	}
+}
@@ -14,14 +14,18 @@ import (
 
 // StitchAudioToVideoService mixes one base video with up to a handful of
 // additional audio tracks — voiceovers, music, narration — and emits a single
-// MP4. It supports two top-level modes:
+// MP4. It supports three top-level modes:
 //
-//   - "mix":     keep the original audio (if any) and overlay the new tracks
+//   - "mix":     keep the original audio (if any) at OriginalVolume and
+//     overlay the new tracks
 //   - "replace": drop the original audio and use only the new tracks
+//   - "mute":    drop the audio entirely; no tracks are taken
 //
 // Each added audio track has its own volume scalar (0..4), start offset in
 // seconds, and an optional "loop" flag for short backing tracks that should
-// extend to the video duration.
+// extend to the video duration. Tracks flagged "duck" are music beds: with
+// Ducking set they are sidechain-compressed by the speech (the original
+// audio plus every unflagged track) so they dip whenever someone talks.
 type StitchAudioToVideoService struct {
 	cfg        *config.Config
 	jobManager *JobManager
@@ -39,13 +43,21 @@ type StitchAudioTrack struct {
 	Volume   float64
 	DelaySec float64
 	Loop     bool
+	Duck     bool
 }
 
 // StitchAudioRequest captures the validated, ready-to-run job parameters.
 type StitchAudioRequest struct {
-	Mode                string // "mix" | "replace"
+	Mode                string // "mix" | "replace" | "mute"
 	TrimToVideoDuration bool
-	Tracks              []StitchAudioTrack
+	// OriginalVolume scales the video's own audio in "mix" mode (0..4), so
+	// new audio can sit over a ducked original or music can sit under
+	// untouched dialogue. Zero means unchanged.
+	OriginalVolume float64
+	// Ducking, when set, ducks the Duck tracks under the speech. It uses the
+	// same sidechaincompress settings as Studio export auto-ducking.
+	Ducking *StudioDucking
+	Tracks  []StitchAudioTrack
 }
 
 // Stitch runs FFmpeg with a programmatically-built filter_complex graph. We
@@ -57,6 +69,9 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
 		return errors.New("ffmpeg not found in PATH")
 	}
+	if req.Mode == "mute" {
+		return s.mute(ctx, job, videoPath, outputPath)
+	}
 	if len(req.Tracks) == 0 {
 		return errors.New("at least one audio track is required")
 	}
@@ -76,20 +91,52 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 	//     [a_1][a_2]...[a_N] amix=inputs=N:duration=longest [aout]
 	//   or if includeOriginal:
 	//     [0:a] amix into the same chain.
-	args := []string{"-y"}
-	for i, track := range req.Tracks {
-		// -stream_loop -1 makes a short backing track loop until the video ends.
-		// We apply it pre-input, mirroring FFmpeg's documented usage.
-		if track.Loop {
-			args = append(args, "-stream_loop", "-1")
+	args := buildStitchAudioArgs(videoPath, outputPath, req, includeOriginal)
+
+	s.progress(job.ID, 25)
+
+	if _, stderr, err := runCommand(ctx, "ffmpeg", args...); err != nil {
+		// Re-encode video as a fallback if -c:v copy was rejected (e.g.,
+		// HEVC-in-MOV → MP4 container with stricter codec compatibility).
+		fallbackArgs := append([]string{}, args...)
+		for i, v := range fallbackArgs {
+			if v == "-c:v" && i+1 < len(fallbackArgs) {
+				fallbackArgs[i+1] = "libx264"
+			}
+		}
+		fallbackArgs = appendArgIfMissing(fallbackArgs, "-pix_fmt", "yuv420p")
+		fallbackArgs = appendArgIfMissing(fallbackArgs, "-preset", "medium")
+		fallbackArgs = appendArgIfMissing(fallbackArgs, "-crf", "20")
+		if _, stderr2, err2 := runCommand(ctx, "ffmpeg", fallbackArgs...); err2 != nil {
+			return fmt.Errorf("ffmpeg stitch failed: %w (copy: %s | reencode: %s)", err2, tail(stderr, 1000), tail(stderr2, 1000))
 		}
-		args = append(args, "-i", track.Path)
-		_ = i
 	}
-	// Video input goes first conceptually, but FFmpeg requires inputs in argv
-	// order; we re-jigger so input 0 is the video, then audio tracks follow.
-	// Rebuild args with the proper input order:
-	args = []string{"-y", "-i", videoPath}
+
+	s.progress(job.ID, 100)
+	return nil
+}
+
+// mute copies the video stream and drops every audio stream.
+func (s *StitchAudioToVideoService) mute(ctx context.Context, job *models.ConversionJob, videoPath, outputPath string) error {
+	s.progress(job.ID, 25)
+	args := []string{"-y", "-i", videoPath, "-map", "0:v:0", "-an", "-c:v", "copy", "-movflags", "+faststart", outputPath}
+	if _, stderr, err := runCommand(ctx, "ffmpeg", args...); err != nil {
+		fallbackArgs := []string{"-y", "-i", videoPath, "-map", "0:v:0", "-an", "-c:v", "libx264", "-pix_fmt", "yuv420p",
+			"-preset", "medium", "-crf", "20", "-movflags", "+faststart", outputPath}
+		if _, stderr2, err2 := runCommand(ctx, "ffmpeg", fallbackArgs...); err2 != nil {
+			return fmt.Errorf("ffmpeg mute failed: %w (copy: %s | reencode: %s)", err2, tail(stderr, 1000), tail(stderr2, 1000))
+		}
+	}
+	s.progress(job.ID, 100)
+	return nil
+}
+
+// buildStitchAudioArgs assembles the ffmpeg argv for a mix/replace run.
+func buildStitchAudioArgs(videoPath, outputPath string, req StitchAudioRequest, includeOriginal bool) []string {
+	// -stream_loop -1 makes a short backing track loop until the video ends;
+	// it applies to the input that follows it, so input 0 is the video and
+	// the audio tracks follow in order.
+	args := []string{"-y", "-i", videoPath}
 	for _, track := range req.Tracks {
 		if track.Loop {
 			args = append(args, "-stream_loop", "-1")
@@ -99,6 +146,7 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 
 	filterParts := make([]string, 0, len(req.Tracks)+2)
 	mixLabels := make([]string, 0, len(req.Tracks)+1)
+	var bedLabels []string
 	for i, track := range req.Tracks {
 		inputIdx := i + 1 // input 0 is the video
 		label := fmt.Sprintf("a%d", i)
@@ -114,19 +162,54 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 		}
 		fmt.Fprintf(&sb, "volume=%s[%s]", formatVolumeArg(track.Volume), label)
 		filterParts = append(filterParts, sb.String())
-		mixLabels = append(mixLabels, "["+label+"]")
+		if track.Duck && req.Ducking != nil {
+			bedLabels = append(bedLabels, "["+label+"]")
+		} else {
+			mixLabels = append(mixLabels, "["+label+"]")
+		}
 	}
 
-	mixInputs := len(req.Tracks)
 	if includeOriginal {
-		mixInputs++
-		mixLabels = append([]string{"[0:a]"}, mixLabels...)
+		original := "[0:a]"
+		if req.OriginalVolume > 0 && req.OriginalVolume != 1 {
+			filterParts = append(filterParts, fmt.Sprintf("[0:a]volume=%s[orig]", formatVolumeArg(req.OriginalVolume)))
+			original = "[orig]"
+		}
+		mixLabels = append([]string{original}, mixLabels...)
+	}
+	mixInputs := len(mixLabels) + len(bedLabels)
+
+	switch {
+	case len(bedLabels) > 0 && len(mixLabels) > 0:
+		// The speech is split: one copy is heard, the other keys the
+		// compressor on the bed. Voice goes first so duration=first still
+		// follows the video's own audio in mix mode.
+		voice := groupAudioLabels(&filterParts, mixLabels, "voice")
+		bed := groupAudioLabels(&filterParts, bedLabels, "bed")
+		filterParts = append(filterParts,
+			fmt.Sprintf("%sasplit[voicemix][voicesc]", voice),
+			fmt.Sprintf("%s[voicesc]sidechaincompress=threshold=0.02:ratio=%s:attack=%s:release=%s:makeup=1[duckedbed]",
+				bed, formatGain(duckRatio(req.Ducking.AmountDb)), formatGain(req.Ducking.AttackMs), formatGain(req.Ducking.ReleaseMs)),
+		)
+		mixLabels = []string{"[voicemix]", "[duckedbed]"}
+		mixInputs = 2
+	case len(bedLabels) > 0:
+		// Nothing to duck under (replace mode with only beds, or a silent
+		// video): the beds are mixed as ordinary tracks.
+		mixLabels = bedLabels
 	}
 
+	// When fitting to the video, pad the mix with silence so a replacement
+	// track shorter than the video doesn't make -shortest cut the picture;
+	// -shortest then trims the padded audio at the video's end.
+	pad := ""
+	if req.TrimToVideoDuration {
+		pad = ",apad"
+	}
 	if mixInputs == 1 {
 		// Pass-through: rename the single track to [aout] so the mapping
 		// below works whether or not we ran amix.
-		filterParts = append(filterParts, fmt.Sprintf("%sanull[aout]", mixLabels[0]))
+		filterParts = append(filterParts, fmt.Sprintf("%sanull%s[aout]", mixLabels[0], pad))
 	} else {
 		duration := "longest"
 		if req.TrimToVideoDuration {
@@ -134,8 +217,8 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 		}
 		// dropout_transition reduces popping when one input ends early.
 		filterParts = append(filterParts, fmt.Sprintf(
-			"%samix=inputs=%d:duration=%s:dropout_transition=2[aout]",
-			strings.Join(mixLabels, ""), mixInputs, duration,
+			"%samix=inputs=%d:duration=%s:dropout_transition=2%s[aout]",
+			strings.Join(mixLabels, ""), mixInputs, duration, pad,
 		))
 	}
 
@@ -148,34 +231,9 @@ func (s *StitchAudioToVideoService) Stitch(ctx context.Context, job *models.Conv
 		"-c:a", "aac",
 		"-b:a", "192k",
 		"-shortest",
+		"-movflags", "+faststart",
 	)
-	if req.TrimToVideoDuration {
-		// `-shortest` already trims to the shortest input; the explicit flag
-		// keeps intent obvious in logs.
-	}
-	args = append(args, outputPath)
-
-	s.progress(job.ID, 25)
-
-	if _, stderr, err := runCommand(ctx, "ffmpeg", args...); err != nil {
-		// Re-encode video as a fallback if -c:v copy was rejected (e.g.,
-		// HEVC-in-MOV → MP4 container with stricter codec compatibility).
-		fallbackArgs := append([]string{}, args...)
-		for i, v := range fallbackArgs {
-			if v == "-c:v" && i+1 < len(fallbackArgs) {
-				fallbackArgs[i+1] = "libx264"
-			}
-		}
-		fallbackArgs = appendArgIfMissing(fallbackArgs, "-pix_fmt", "yuv420p")
-		fallbackArgs = appendArgIfMissing(fallbackArgs, "-preset", "medium")
-		fallbackArgs = appendArgIfMissing(fallbackArgs, "-crf", "20")
-		if _, stderr2, err2 := runCommand(ctx, "ffmpeg", fallbackArgs...); err2 != nil {
-			return fmt.Errorf("ffmpeg stitch failed: %w (copy: %s | reencode: %s)", err2, tail(stderr, 1000), tail(stderr2, 1000))
-		}
-	}
-
-	s.progress(job.ID, 100)
-	return nil
+	return append(args, outputPath)
 }
 
 func (s *StitchAudioToVideoService) progress(jobID string, percent int) {
@@ -185,6 +243,17 @@ func (s *StitchAudioToVideoService) progress(jobID string, percent int) {
 	s.jobManager.SendProgressUpdate(jobID, percent)
 }
 
+// groupAudioLabels amixes several labels into one named group, or returns a
+// lone label as is.
+func groupAudioLabels(filterParts *[]string, labels []string, name string) string {
+	if len(labels) == 1 {
+		return labels[0]
+	}
+	*filterParts = append(*filterParts, fmt.Sprintf("%samix=inputs=%d:duration=longest:dropout_transition=2[%s]",
+		strings.Join(labels, ""), len(labels), name))
+	return "[" + name + "]"
+}
+
 // formatVolumeArg renders a float volume into FFmpeg-friendly syntax. We cap
 // to two decimal places so the resulting filter string is predictable and
 // reads cleanly in logs.
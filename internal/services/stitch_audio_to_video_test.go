@@ -0,0 +1,82 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildStitchAudioArgs_MixUnderOriginal(t *testing.T) {
+	req := StitchAudioRequest{
+		Mode:           "mix",
+		OriginalVolume: 0.5,
+		Tracks:         []StitchAudioTrack{{Path: "music.mp3", Volume: 0.3, DelaySec: 1.5}},
+	}
+	args := buildStitchAudioArgs("in.mp4", "out.mp4", req, true)
+	filter := valueAfter(args, "-filter_complex")
+	for _, want := range []string{
+		"[0:a]volume=0.50[orig]",
+		"[1:a]adelay=1500|1500,volume=0.30[a0]",
+		"[orig][a0]amix=inputs=2:duration=longest:dropout_transition=2[aout]",
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("filter %q missing %q", filter, want)
+		}
+	}
+	if args[len(args)-1] != "out.mp4" {
+		t.Fatalf("output path not last: %v", args)
+	}
+}
+
+func TestBuildStitchAudioArgs_ReplaceFitsVideo(t *testing.T) {
+	req := StitchAudioRequest{
+		Mode:                "replace",
+		TrimToVideoDuration: true,
+		OriginalVolume:      0.5,
+		Tracks:              []StitchAudioTrack{{Path: "voice.wav", Volume: 1, Loop: true}},
+	}
+	args := buildStitchAudioArgs("in.mp4", "out.mp4", req, false)
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "-stream_loop -1 -i voice.wav") {
+		t.Errorf("looped track not set up: %s", joined)
+	}
+	filter := valueAfter(args, "-filter_complex")
+	if strings.Contains(filter, "[0:a]") {
+		t.Errorf("replace mode must not use the original audio: %q", filter)
+	}
+	if !strings.Contains(filter, "[a0]anull,apad[aout]") {
+		t.Errorf("short replacement audio should be padded to the video: %q", filter)
+	}
+	if countFlag(args, "-shortest") != 1 {
+		t.Errorf("expected -shortest once: %s", joined)
+	}
+}
+
+func TestBuildStitchAudioArgs_DucksMusicUnderSpeech(t *testing.T) {
+	req := StitchAudioRequest{
+		Mode:    "mix",
+		Ducking: &StudioDucking{AmountDb: 9, AttackMs: 20, ReleaseMs: 300},
+		Tracks: []StitchAudioTrack{
+			{Path: "music.mp3", Volume: 0.8, Loop: true, Duck: true},
+			{Path: "voiceover.wav", Volume: 1},
+		},
+	}
+	filter := valueAfter(buildStitchAudioArgs("in.mp4", "out.mp4", req, true), "-filter_complex")
+	for _, want := range []string{
+		"[0:a][a1]amix=inputs=2:duration=longest:dropout_transition=2[voice]",
+		"[voice]asplit[voicemix][voicesc]",
+		"[a0][voicesc]sidechaincompress=threshold=0.02:ratio=10.000:attack=20.000:release=300.000:makeup=1[duckedbed]",
+		"[voicemix][duckedbed]amix=inputs=2",
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("filter %q missing %q", filter, want)
+		}
+	}
+
+	// Without any speech to key on, a ducked bed is mixed like any track.
+	req.Mode = "replace"
+	req.Tracks = req.Tracks[:1]
+	filter = valueAfter(buildStitchAudioArgs("in.mp4", "out.mp4", req, false), "-filter_complex")
+	if strings.Contains(filter, "sidechaincompress") || !strings.Contains(filter, "[a0]anull[aout]") {
+		t.Errorf("unexpected filter without speech: %q", filter)
+	}
+}
@@ -652,10 +652,16 @@ func drawtextArg(ov models.StudioTextOverlay, fontFile string) string {
 	)
 }
 
-// drawtextEscape escapes a value for use inside a single-quoted drawtext field.
+// drawtextEscape escapes a value for use inside a single-quoted drawtext
+// field. A quote can't be escaped there: the filtergraph parser ends the
+// value at any "'", backslash or not, and would read the rest as further
+// options or filters. Quotes become typographic apostrophes instead. ':' is
+// escaped for the option parser, which sees the value after the quotes are
+// gone.
 func drawtextEscape(s string) string {
 	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "'", "\\'")
+	s = strings.ReplaceAll(s, "'", "\u2019")
+	s = strings.ReplaceAll(s, ":", "\\:")
 	s = strings.ReplaceAll(s, "%", "\\%")
 	s = strings.ReplaceAll(s, "\n", " ")
 	s = strings.ReplaceAll(s, "\r", " ")
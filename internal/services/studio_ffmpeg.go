@@ -122,7 +122,7 @@ func runStudioFFmpeg(ctx context.Context, jm *JobManager, jobID string, gpuIndex
 		return fmt.Errorf("ffmpeg is required for Content Studio but was not found on PATH — install FFmpeg or see https://ffmpeg.org/download.html")
 	}
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd := limitedCommand(ctx, "ffmpeg", args...)
 	cmd.Env = studioFFmpegEnv(gpuIndex)
 
 	stderr, err := cmd.StderrPipe()
@@ -186,7 +186,7 @@ func runStudioFFmpegCapture(ctx context.Context, jm *JobManager, jobID string, g
 
 	runCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	cmd := exec.CommandContext(runCtx, "ffmpeg", args...)
+	cmd := limitedCommand(runCtx, "ffmpeg", args...)
 	cmd.Env = studioFFmpegEnv(gpuIndex)
 
 	stdout, err := cmd.StdoutPipe()
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Timecode burn-in for review copies and dailies: drawtext's timecode mode
+// counts output frames from a start timecode at a given base rate, so it is
+// appended last in the filter chain and always reflects the delivered file,
+// after trims, speed changes and frame-rate conversion.
+
+const (
+	timecodeDefaultStart    = "00:00:00:00"
+	timecodeDefaultFont     = "DejaVu-Sans-Mono"
+	timecodeDefaultPosition = "bottom-center"
+	timecodeFallbackRate    = "25/1"
+)
+
+// timecodeRates maps the accepted frameRate values to drawtext rationals.
+var timecodeRates = map[string]string{
+	"23.976": "24000/1001", "24": "24/1", "25": "25/1",
+	"29.97": "30000/1001", "30": "30/1", "48": "48/1", "50": "50/1",
+	"59.94": "60000/1001", "60": "60/1",
+}
+
+// timecodeFontFiles resolves the image text-overlay font names to the TTFs
+// shipped by the fonts-dejavu / fonts-liberation packages.
+var timecodeFontFiles = map[string]string{
+	"DejaVu-Sans":      "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"DejaVu-Serif":     "/usr/share/fonts/truetype/dejavu/DejaVuSerif.ttf",
+	"DejaVu-Sans-Mono": "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf",
+	"Liberation-Sans":  "/usr/share/fonts/truetype/liberation/LiberationSans-Regular.ttf",
+	"Liberation-Serif": "/usr/share/fonts/truetype/liberation/LiberationSerif-Regular.ttf",
+	"Liberation-Mono":  "/usr/share/fonts/truetype/liberation/LiberationMono-Regular.ttf",
+}
+
+// timecodePositions gives the drawtext x/y expressions for each anchor. The
+// margin scales with the frame so it looks the same at 480p and 4K.
+var timecodePositions = map[string][2]string{
+	"top-left":      {"h*0.03", "h*0.03"},
+	"top-center":    {"(w-text_w)/2", "h*0.03"},
+	"top-right":     {"w-text_w-h*0.03", "h*0.03"},
+	"bottom-left":   {"h*0.03", "h-text_h-h*0.03"},
+	"bottom-center": {"(w-text_w)/2", "h-text_h-h*0.03"},
+	"bottom-right":  {"w-text_w-h*0.03", "h-text_h-h*0.03"},
+}
+
+var timecodeStartPattern = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})([:;])(\d{2})$`)
+
+// validateTimecodeBurnIn checks the timecode options for a video job.
+func validateTimecodeBurnIn(tc *models.TimecodeBurnInOptions, format string) error {
+	if tc == nil || !tc.Enabled {
+		return nil
+	}
+	if strings.EqualFold(format, "gif") {
+		return fmt.Errorf("timecode burn-in is not supported for GIF output")
+	}
+	rate := ""
+	if tc.FrameRate != "" {
+		r, ok := timecodeRates[strings.TrimSpace(tc.FrameRate)]
+		if !ok {
+			return fmt.Errorf("unsupported timecode frame rate: %s (expected 23.976|24|25|29.97|30|48|50|59.94|60)", tc.FrameRate)
+		}
+		rate = r
+	}
+	if start := strings.TrimSpace(tc.Start); start != "" {
+		m := timecodeStartPattern.FindStringSubmatch(start)
+		if m == nil {
+			return fmt.Errorf("timecode start must be HH:MM:SS:FF, got %q", tc.Start)
+		}
+		hh, _ := strconv.Atoi(m[1])
+		mm, _ := strconv.Atoi(m[2])
+		ss, _ := strconv.Atoi(m[3])
+		ff, _ := strconv.Atoi(m[5])
+		if hh > 23 || mm > 59 || ss > 59 {
+			return fmt.Errorf("timecode start is out of range: %s", start)
+		}
+		if m[4] == ";" && rate != "30000/1001" && rate != "60000/1001" {
+			return fmt.Errorf("drop-frame timecode (;) requires frameRate 29.97 or 59.94")
+		}
+		if rate != "" && ff >= timecodeNominalFPS(rate) {
+			return fmt.Errorf("timecode start frame %02d is out of range at %s fps", ff, tc.FrameRate)
+		}
+	}
+	if tc.Position != "" {
+		if _, ok := timecodePositions[tc.Position]; !ok {
+			return fmt.Errorf("unsupported timecode position: %s", tc.Position)
+		}
+	}
+	if tc.Font != "" && tc.Font != "default" {
+		if _, ok := timecodeFontFiles[tc.Font]; !ok {
+			return fmt.Errorf("unsupported timecode font: %s", tc.Font)
+		}
+	}
+	if tc.FontSize != 0 && (tc.FontSize < 8 || tc.FontSize > 512) {
+		return fmt.Errorf("timecode font size must be between 8 and 512")
+	}
+	if tc.FontColor != "" && !isSafeImageColor(tc.FontColor) {
+		return fmt.Errorf("invalid timecode font color")
+	}
+	return nil
+}
+
+// timecodeNominalFPS is the frame count per timecode second for a rate
+// (30 for 29.97, 24 for 23.976).
+func timecodeNominalFPS(rate string) int {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		return 0
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return int(math.Round(n / d))
+}
+
+// timecodeBurnInFilter builds the drawtext filter. rate is the drawtext
+// rational actually used (the explicit frameRate, or the output rate).
+func timecodeBurnInFilter(tc *models.TimecodeBurnInOptions, rate string) string {
+	start := strings.TrimSpace(tc.Start)
+	if start == "" {
+		start = timecodeDefaultStart
+	}
+	font := tc.Font
+	if font == "" || font == "default" {
+		font = timecodeDefaultFont
+	}
+	pos := timecodePositions[tc.Position]
+	if tc.Position == "" {
+		pos = timecodePositions[timecodeDefaultPosition]
+	}
+	size := "h/20"
+	if tc.FontSize > 0 {
+		size = strconv.Itoa(tc.FontSize)
+	}
+	color := "white"
+	if tc.FontColor != "" {
+		color = hexToFFColor(tc.FontColor)
+	}
+	// The timecode is quoted for the filtergraph parser and its colons are
+	// escaped again for drawtext's own option parser.
+	return fmt.Sprintf(
+		"drawtext=fontfile='%s':timecode='%s':rate=%s:fontsize=%s:fontcolor=%s:box=1:boxcolor=black@0.5:boxborderw=8:x=%s:y=%s",
+		drawtextEscape(timecodeFontFiles[font]), strings.ReplaceAll(start, ":", `\:`), rate, size, color, pos[0], pos[1],
+	)
+}
+
+// resolveTimecodeRate picks the timecode base: the explicit frameRate, else
+// the fps target of a frame-rate conversion, else 23.976 after inverse
+// telecine, else the source's average rate.
+func resolveTimecodeRate(tc *models.TimecodeBurnInOptions, options *models.VideoConversionOptions, inputPath string) string {
+	if r, ok := timecodeRates[strings.TrimSpace(tc.FrameRate)]; ok {
+		return r
+	}
+	if te := options.Temporal; te != nil && te.FrameRate != nil && te.FrameRate.Target != nil {
+		return fmt.Sprintf("%d/1", *te.FrameRate.Target)
+	}
+	if detelecineFilter(options.Advanced) != "" {
+		return detelecineRate
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stdout, _, err := runCommand(ctx, "ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=avg_frame_rate", "-of", "default=nw=1:nk=1", inputPath)
+	if err != nil {
+		return timecodeFallbackRate
+	}
+	rate := strings.TrimSpace(stdout)
+	if timecodeNominalFPS(rate) <= 0 {
+		return timecodeFallbackRate
+	}
+	return rate
+}
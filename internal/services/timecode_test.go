@@ -0,0 +1,82 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateTimecodeBurnIn(t *testing.T) {
+	ok := []*models.TimecodeBurnInOptions{
+		nil,
+		{Enabled: false, Start: "garbage"},
+		{Enabled: true},
+		{Enabled: true, Start: "01:00:00:00", FrameRate: "23.976", Position: "top-right", Font: "Liberation-Mono", FontSize: 36, FontColor: "#ffcc00"},
+		{Enabled: true, Start: "00:59:59;29", FrameRate: "29.97"},
+	}
+	for _, tc := range ok {
+		if err := validateTimecodeBurnIn(tc, "mp4"); err != nil {
+			t.Errorf("%+v rejected: %v", tc, err)
+		}
+	}
+	bad := []*models.TimecodeBurnInOptions{
+		{Enabled: true, Start: "1:00:00:00"},
+		{Enabled: true, Start: "24:00:00:00"},
+		{Enabled: true, Start: "00:00:00:25", FrameRate: "25"},
+		{Enabled: true, Start: "00:00:00;00", FrameRate: "25"},
+		{Enabled: true, Start: "00:00:00;00"},
+		{Enabled: true, FrameRate: "12"},
+		{Enabled: true, Position: "middle"},
+		{Enabled: true, Font: "Comic-Sans"},
+		{Enabled: true, FontSize: 4},
+		{Enabled: true, FontColor: "red'"},
+	}
+	for _, tc := range bad {
+		if err := validateTimecodeBurnIn(tc, "mp4"); err == nil {
+			t.Errorf("%+v: expected error", tc)
+		}
+	}
+	if err := validateTimecodeBurnIn(&models.TimecodeBurnInOptions{Enabled: true}, "gif"); err == nil {
+		t.Error("gif output accepted")
+	}
+}
+
+func TestTimecodeBurnInFilter(t *testing.T) {
+	got := timecodeBurnInFilter(&models.TimecodeBurnInOptions{Enabled: true, Start: "10:00:00:00"}, "24000/1001")
+	for _, want := range []string{
+		"drawtext=fontfile='/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf'",
+		`timecode='10\:00\:00\:00'`,
+		":rate=24000/1001:",
+		":fontsize=h/20:fontcolor=white:",
+		":x=(w-text_w)/2:y=h-text_h-h*0.03",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("filter missing %q: %s", want, got)
+		}
+	}
+
+	got = timecodeBurnInFilter(&models.TimecodeBurnInOptions{Enabled: true, Start: "00:00:00;00", Position: "top-left", FontSize: 40, FontColor: "#00ff00"}, "30000/1001")
+	if !strings.Contains(got, `timecode='00\:00\:00;00'`) || !strings.Contains(got, "fontsize=40:fontcolor=0x00FF00") || !strings.Contains(got, "x=h*0.03:y=h*0.03") {
+		t.Errorf("unexpected filter: %s", got)
+	}
+}
+
+func TestResolveTimecodeRate(t *testing.T) {
+	tc := &models.TimecodeBurnInOptions{Enabled: true, FrameRate: "59.94"}
+	if got := resolveTimecodeRate(tc, &models.VideoConversionOptions{}, "/nonexistent.mp4"); got != "60000/1001" {
+		t.Fatalf("explicit rate = %s", got)
+	}
+	target := 50
+	opts := &models.VideoConversionOptions{Temporal: &models.TemporalEffects{FrameRate: &models.FrameRateConfig{Target: &target}}}
+	if got := resolveTimecodeRate(&models.TimecodeBurnInOptions{Enabled: true}, opts, "/nonexistent.mp4"); got != "50/1" {
+		t.Fatalf("fps target rate = %s", got)
+	}
+	ivtc := &models.VideoConversionOptions{Advanced: &models.AdvancedProcessing{Detelecine: "pullup"}}
+	if got := resolveTimecodeRate(&models.TimecodeBurnInOptions{Enabled: true}, ivtc, "/nonexistent.mp4"); got != "24000/1001" {
+		t.Fatalf("detelecine rate = %s", got)
+	}
+	if got := resolveTimecodeRate(&models.TimecodeBurnInOptions{Enabled: true}, &models.VideoConversionOptions{}, "/nonexistent.mp4"); got != timecodeFallbackRate {
+		t.Fatalf("fallback rate = %s", got)
+	}
+}
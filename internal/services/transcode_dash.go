@@ -162,7 +162,7 @@ func transcodeToDASH(ctx context.Context, inputPath string, profiles []QualityPr
 			"-media_seg_name", "seg-$Number%05d$.m4s",
 			"manifest.mpd",
 		)
-		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		cmd := limitedCommand(ctx, "ffmpeg", args...)
 		cmd.Dir = variantDir
 		var stderr strings.Builder
 		cmd.Stderr = &stderr
@@ -219,7 +219,7 @@ func transcodeToDASH(ctx context.Context, inputPath string, profiles []QualityPr
 			"-media_seg_name", dashAudioSegment,
 			"manifest.mpd",
 		}
-		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		cmd := limitedCommand(ctx, "ffmpeg", args...)
 		cmd.Dir = audioDir
 		var stderr strings.Builder
 		cmd.Stderr = &stderr
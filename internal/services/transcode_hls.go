@@ -105,7 +105,7 @@ func transcodeToHLS(ctx context.Context, inputPath string, profiles []QualityPro
 			"-hls_flags", "independent_segments",
 			"index.m3u8",
 		)
-		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		cmd := limitedCommand(ctx, "ffmpeg", args...)
 		cmd.Dir = variantDir
 		var stderr strings.Builder
 		cmd.Stderr = &stderr
@@ -64,7 +64,7 @@ func generateHLSIFramePlaylist(ctx context.Context, inputPath string, sourceHeig
 		"-hls_segment_filename", segmentPattern,
 		"iframes.m3u8",
 	}
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd := limitedCommand(ctx, "ffmpeg", args...)
 	cmd.Dir = dir
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
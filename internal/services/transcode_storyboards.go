@@ -76,7 +76,7 @@ func generateStoryboards(ctx context.Context, inputPath string, durationSeconds
 		cfg.Cols, cfg.Rows,
 	)
 	args := []string{"-y", "-i", inputPath, "-vf", vf, "-q:v", fmt.Sprintf("%d", cfg.JPEGQuality), outPattern}
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd := limitedCommand(ctx, "ffmpeg", args...)
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -619,7 +619,7 @@ func whisperSubprocessEnv() []string {
 // discarded stdout entirely and truncated stderr to 2000 bytes, which made
 // real failures invisible when huggingface_hub emitted a long preamble.
 func runWhisperCommand(ctx context.Context, bin string, args ...string) (stdout, stderr string, err error) {
-	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd := limitedCommand(ctx, bin, args...)
 	cmd.Env = whisperSubprocessEnv()
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io): the
+// client creates an upload with its total length, then PATCHes the bytes in
+// as many pieces as its connection allows, asking with HEAD where to resume
+// after a drop. The partial file lives in the upload directory, so the
+// cleanup worker sweeps uploads abandoned for longer than UploadRetention.
+
+var (
+	ErrTusUploadNotFound = errors.New("upload not found")
+	ErrTusOffsetMismatch = errors.New("Upload-Offset does not match the current offset")
+	ErrTusUploadBusy     = errors.New("upload is already receiving data")
+	ErrTusUploadComplete = errors.New("upload is already complete")
+	ErrTusUploadTooLarge = errors.New("data exceeds Upload-Length")
+)
+
+// TusUpload is one resumable upload. JobID is set once the upload is
+// complete and its conversion has started.
+type TusUpload struct {
+	ID        string
+	Length    int64
+	Offset    int64
+	Metadata  map[string]string
+	JobID     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	path string
+	busy bool
+}
+
+// Complete reports whether every byte has been received.
+func (u *TusUpload) Complete() bool { return u.Offset == u.Length }
+
+// TusStore tracks resumable uploads in memory, like the job manager tracks
+// jobs, with each upload's bytes in a file under dir.
+type TusStore struct {
+	mu      sync.Mutex
+	dir     string
+	ttl     time.Duration
+	uploads map[string]*TusUpload
+}
+
+func NewTusStore(dir string, ttl time.Duration) *TusStore {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &TusStore{dir: dir, ttl: ttl, uploads: make(map[string]*TusUpload)}
+}
+
+// Create starts an upload of length bytes with an empty file behind it.
+func (s *TusStore) Create(length int64, metadata map[string]string) (*TusUpload, error) {
+	now := time.Now().UTC()
+	u := &TusUpload{
+		ID:        uuid.New().String(),
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	u.path = filepath.Join(s.dir, "tus_"+u.ID)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(u.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(u.path)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupExpiredLocked()
+	s.uploads[u.ID] = u
+	snapshot := *u
+	return &snapshot, nil
+}
+
+// Get returns a snapshot of the upload. Expired uploads are treated as
+// missing.
+func (s *TusStore) Get(id string) (*TusUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.liveLocked(id)
+	if !ok {
+		return nil, false
+	}
+	snapshot := *u
+	return &snapshot, true
+}
+
+// Append writes r at offset, which must be the upload's current offset.
+// Bytes that arrive before r fails are kept, so a client whose connection
+// drops mid-PATCH resumes from wherever the data stopped. Only one Append
+// per upload runs at a time.
+func (s *TusStore) Append(id string, offset int64, r io.Reader) (*TusUpload, error) {
+	s.mu.Lock()
+	u, ok := s.liveLocked(id)
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return nil, ErrTusUploadNotFound
+	case u.busy:
+		s.mu.Unlock()
+		return nil, ErrTusUploadBusy
+	case u.Complete():
+		s.mu.Unlock()
+		return nil, ErrTusUploadComplete
+	case offset != u.Offset:
+		s.mu.Unlock()
+		return nil, ErrTusOffsetMismatch
+	}
+	u.busy = true
+	path, remaining := u.path, u.Length-u.Offset
+	s.mu.Unlock()
+
+	written, err := appendAt(path, offset, r, remaining)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.busy = false
+	u.Offset += written
+	u.ExpiresAt = time.Now().UTC().Add(s.ttl)
+	snapshot := *u
+	return &snapshot, err
+}
+
+// appendAt writes up to limit bytes of r at offset. A body longer than limit
+// is rejected whole and the file truncated back to offset.
+func appendAt(path string, offset int64, r io.Reader, limit int64) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	written, copyErr := io.Copy(f, io.LimitReader(r, limit))
+	if copyErr == nil && written == limit {
+		// Anything still unread is more than the upload declared.
+		var probe [1]byte
+		if n, _ := r.Read(probe[:]); n > 0 {
+			written, copyErr = 0, ErrTusUploadTooLarge
+		}
+	}
+	if err := f.Truncate(offset + written); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	return written, copyErr
+}
+
+// Take hands a complete upload's file over to the caller, who moves it to
+// dest. The upload stays known, without a file, until it expires so HEAD
+// can still report it and, once set with SetJob, its job.
+func (s *TusStore) Take(id, dest string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.liveLocked(id)
+	if !ok || u.path == "" {
+		return ErrTusUploadNotFound
+	}
+	if !u.Complete() || u.busy {
+		return fmt.Errorf("upload %s is not complete", id)
+	}
+	if err := os.Rename(u.path, dest); err != nil {
+		return err
+	}
+	u.path = ""
+	return nil
+}
+
+// SetJob records the conversion job a complete upload started.
+func (s *TusStore) SetJob(id, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.uploads[id]; ok {
+		u.JobID = jobID
+	}
+}
+
+// Delete forgets an upload and removes its partial file. Uploads that are
+// receiving data can't be deleted.
+func (s *TusStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.liveLocked(id)
+	if !ok {
+		return ErrTusUploadNotFound
+	}
+	if u.busy {
+		return ErrTusUploadBusy
+	}
+	s.removeLocked(u)
+	return nil
+}
+
+// CleanupExpired drops every upload that hasn't received data within the
+// TTL.
+func (s *TusStore) CleanupExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupExpiredLocked()
+}
+
+func (s *TusStore) liveLocked(id string) (*TusUpload, bool) {
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, false
+	}
+	if !u.busy && time.Now().UTC().After(u.ExpiresAt) {
+		s.removeLocked(u)
+		return nil, false
+	}
+	return u, true
+}
+
+func (s *TusStore) cleanupExpiredLocked() {
+	now := time.Now().UTC()
+	for _, u := range s.uploads {
+		if !u.busy && now.After(u.ExpiresAt) {
+			s.removeLocked(u)
+		}
+	}
+}
+
+func (s *TusStore) removeLocked(u *TusUpload) {
+	if u.path != "" {
+		_ = os.Remove(u.path)
+	}
+	delete(s.uploads, u.ID)
+}
+
+// ParseTusMetadata decodes an Upload-Metadata header: comma-separated
+// "key base64value" pairs, where the value may be omitted.
+func ParseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid Upload-Metadata pair %q", strings.TrimSpace(pair))
+		}
+		key := fields[0]
+		if _, dup := metadata[key]; dup {
+			return nil, fmt.Errorf("duplicate Upload-Metadata key %q", key)
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("Upload-Metadata value for %q is not base64", key)
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTusStoreResumesAfterInterruptedAppend(t *testing.T) {
+	dir := t.TempDir()
+	store := NewTusStore(dir, 0)
+	upload, err := store.Create(10, map[string]string{"filename": "clip.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection drops after four bytes.
+	broken := io.MultiReader(strings.NewReader("abcd"), iotest.ErrReader(errors.New("connection reset")))
+	got, err := store.Append(upload.ID, 0, broken)
+	if err == nil {
+		t.Fatal("expected the interrupted append to fail")
+	}
+	if got.Offset != 4 {
+		t.Fatalf("offset after interruption = %d, want 4", got.Offset)
+	}
+
+	if _, err := store.Append(upload.ID, 0, strings.NewReader("abcdefghij")); !errors.Is(err, ErrTusOffsetMismatch) {
+		t.Fatalf("stale offset: err = %v, want ErrTusOffsetMismatch", err)
+	}
+	if _, err := store.Append(upload.ID, 4, strings.NewReader("efghijXX")); !errors.Is(err, ErrTusUploadTooLarge) {
+		t.Fatalf("oversized body: err = %v, want ErrTusUploadTooLarge", err)
+	}
+	got, err = store.Append(upload.ID, 4, strings.NewReader("efghij"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Complete() {
+		t.Fatalf("offset = %d, want complete at 10", got.Offset)
+	}
+	if _, err := store.Append(upload.ID, 10, strings.NewReader("x")); !errors.Is(err, ErrTusUploadComplete) {
+		t.Fatalf("append after completion: err = %v, want ErrTusUploadComplete", err)
+	}
+
+	dest := filepath.Join(dir, "assembled")
+	if err := store.Take(upload.ID, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abcdefghij" {
+		t.Fatalf("assembled file = %q", data)
+	}
+	store.SetJob(upload.ID, "job-1")
+	if got, ok := store.Get(upload.ID); !ok || got.JobID != "job-1" {
+		t.Fatalf("Get after completion = %+v, %v", got, ok)
+	}
+}
+
+func TestTusStoreDeleteRemovesPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewTusStore(dir, 0)
+	upload, err := store.Create(5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(upload.ID, 0, strings.NewReader("ab")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(upload.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get(upload.ID); ok {
+		t.Fatal("upload still known after Delete")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("partial file left behind: %v", entries)
+	}
+	if err := store.Delete(upload.ID); !errors.Is(err, ErrTusUploadNotFound) {
+		t.Fatalf("second Delete: err = %v, want ErrTusUploadNotFound", err)
+	}
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	got, err := ParseTusMetadata("filename Y2xpcC5tcDQ=, filetype dmlkZW8vbXA0,is_confidential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"filename": "clip.mp4", "filetype": "video/mp4", "is_confidential": ""}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	for _, bad := range []string{"filename not-base64!", "a YQ==,a Yg==", "a b c"} {
+		if _, err := ParseTusMetadata(bad); err == nil {
+			t.Errorf("ParseTusMetadata(%q) succeeded, want error", bad)
+		}
+	}
+}
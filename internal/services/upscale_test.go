@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestValidateImageUpscale(t *testing.T) {
+	width := 800
+	cases := []struct {
+		name    string
+		options models.ImageConversionOptions
+		wantErr bool
+	}{
+		{name: "off", options: models.ImageConversionOptions{Format: "png"}},
+		{name: "2x", options: models.ImageConversionOptions{Format: "png", Upscale: 2}},
+		{name: "4x", options: models.ImageConversionOptions{Format: "webp", Upscale: 4}},
+		{name: "3x", options: models.ImageConversionOptions{Format: "png", Upscale: 3}, wantErr: true},
+		{name: "with width", options: models.ImageConversionOptions{Format: "png", Upscale: 2, Width: &width}, wantErr: true},
+		{name: "svg output", options: models.ImageConversionOptions{Format: "svg", Upscale: 2}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateImageUpscale(&tc.options); (err != nil) != tc.wantErr {
+				t.Fatalf("validateImageUpscale error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// usageAccountPrefix marks the job clients that are accounted: API key
+// tenants. Anonymous clients are limited by IP but not billed.
+const usageAccountPrefix = "key:"
+
+// UsageTracker adds up what each API key tenant used per calendar month
+// (UTC): jobs started, input bytes and encode seconds. It is kept in one
+// JSON file, rewritten after every change, so the month's totals survive a
+// restart.
+type UsageTracker struct {
+	mu   sync.Mutex
+	path string
+	// usage is keyed by tenant, then month ("2006-01").
+	usage map[string]map[string]*models.Usage
+}
+
+// NewUsageTracker loads the usage recorded in path. An empty path keeps
+// usage in memory only.
+func NewUsageTracker(path string) (*UsageTracker, error) {
+	u := &UsageTracker{path: path, usage: map[string]map[string]*models.Usage{}}
+	if path == "" {
+		return u, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &u.usage); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return u, nil
+}
+
+// UsageMonth returns the accounting period t falls in.
+func UsageMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// UsageAccount returns the tenant a job client is billed to, or "" when it
+// isn't accounted.
+func UsageAccount(client string) string {
+	account, ok := strings.CutPrefix(client, usageAccountPrefix)
+	if !ok {
+		return ""
+	}
+	return account
+}
+
+// Get returns account's usage in month; zero when there is none.
+func (u *UsageTracker) Get(account, month string) models.Usage {
+	if u == nil {
+		return models.Usage{}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if usage := u.usage[account][month]; usage != nil {
+		return *usage
+	}
+	return models.Usage{}
+}
+
+// AddJob counts a job started by client with an input of size bytes.
+func (u *UsageTracker) AddJob(client string, size int64, at time.Time) {
+	u.add(client, at, func(usage *models.Usage) {
+		usage.Jobs++
+		usage.Bytes += max(0, size)
+	})
+}
+
+// AddEncodeTime counts d of processing for client's job, in the month the
+// processing finished.
+func (u *UsageTracker) AddEncodeTime(client string, d time.Duration, at time.Time) {
+	if d <= 0 {
+		return
+	}
+	u.add(client, at, func(usage *models.Usage) {
+		usage.EncodeSeconds += d.Seconds()
+	})
+}
+
+func (u *UsageTracker) add(client string, at time.Time, update func(*models.Usage)) {
+	account := UsageAccount(client)
+	if u == nil || account == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	month := UsageMonth(at)
+	if u.usage[account] == nil {
+		u.usage[account] = map[string]*models.Usage{}
+	}
+	usage := u.usage[account][month]
+	if usage == nil {
+		usage = &models.Usage{}
+		u.usage[account][month] = usage
+	}
+	update(usage)
+	u.saveLocked()
+}
+
+// saveLocked rewrites the usage file. Like the job store, a failed write
+// is logged and the totals stay correct in memory.
+func (u *UsageTracker) saveLocked() {
+	if u.path == "" {
+		return
+	}
+	data, err := json.Marshal(u.usage)
+	if err != nil {
+		slog.Error("usage: failed to encode usage", "error", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o755); err != nil {
+		slog.Error("usage: failed to write usage", "path", u.path, "error", err)
+		return
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		slog.Error("usage: failed to write usage", "path", u.path, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, u.path); err != nil {
+		_ = os.Remove(tmp)
+		slog.Error("usage: failed to write usage", "path", u.path, "error", err)
+	}
+}
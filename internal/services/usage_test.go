@@ -0,0 +1,70 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestUsageTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	u, err := NewUsageTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oct := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+	u.AddJob("key:acme", 1000, oct)
+	u.AddJob("key:acme", 500, oct)
+	u.AddEncodeTime("key:acme", 90*time.Second, oct)
+	u.AddEncodeTime("key:acme", 30*time.Second, oct.Add(2*time.Hour))
+	u.AddJob("ip:203.0.113.7", 1000, oct)
+
+	reloaded, err := NewUsageTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reloaded.Get("acme", "2026-10"), (models.Usage{Jobs: 2, Bytes: 1500, EncodeSeconds: 90}); got != want {
+		t.Fatalf("October = %+v, want %+v", got, want)
+	}
+	if got := reloaded.Get("acme", "2026-11"); got.EncodeSeconds != 30 || got.Jobs != 0 {
+		t.Fatalf("November = %+v", got)
+	}
+	if got := reloaded.Get("203.0.113.7", "2026-10"); got != (models.Usage{}) {
+		t.Fatalf("anonymous clients must not be accounted: %+v", got)
+	}
+}
+
+func TestJobManagerAccountsUsage(t *testing.T) {
+	u, _ := NewUsageTracker("")
+	jm := NewJobManager()
+	jm.TrackUsage(u)
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "a.mp4", Size: 2048}, nil)
+	_ = jm.SetClient(job.ID, "key:acme")
+	_ = jm.StartAttempt(job.ID, models.AttemptInitial)
+	_ = jm.UpdateJobStatus(job.ID, models.StatusCompleted)
+	got := u.Get("acme", UsageMonth(time.Now()))
+	if got.Jobs != 1 || got.Bytes != 2048 || got.EncodeSeconds <= 0 {
+		t.Fatalf("usage = %+v", got)
+	}
+}
+
+func TestJobManagerAccountsToolJobEncodeTime(t *testing.T) {
+	u, _ := NewUsageTracker("")
+	jm := NewJobManager()
+	jm.TrackUsage(u)
+	job := jm.CreateJob(models.OriginalFileInfo{Name: "a.png", Size: 512, Type: "image/batch"}, map[string]interface{}{"mode": "batch_images"})
+	_ = jm.SetClient(job.ID, "key:acme")
+	_ = jm.UpdateJobStatus(job.ID, models.StatusProcessing)
+	time.Sleep(10 * time.Millisecond)
+	_ = jm.UpdateJobStatus(job.ID, models.StatusCompleted)
+	got := u.Get("acme", UsageMonth(time.Now()))
+	if got.EncodeSeconds < 0.01 {
+		t.Fatalf("tool job encode seconds = %v, want at least 0.01", got.EncodeSeconds)
+	}
+	_ = jm.UpdateJobError(job.ID, "late failure")
+	if again := u.Get("acme", UsageMonth(time.Now())); again.EncodeSeconds != got.EncodeSeconds {
+		t.Fatalf("finishing the job again counted %v more encode seconds", again.EncodeSeconds-got.EncodeSeconds)
+	}
+}
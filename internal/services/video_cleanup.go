@@ -0,0 +1,42 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cleanup post-filters for heavily compressed sources (old web rips, low
+// bitrate streams). Deblock runs on the decoded frame before any scaling so
+// it sees the codec's 8x8 grid where it actually is; deband runs after the
+// color work, since eq/curves tend to widen the bands it has to smooth.
+
+var deblockLevels = map[string]string{
+	"light":  "deblock=filter=weak:block=8",
+	"medium": "deblock=filter=strong:block=8",
+	"strong": "deblock=filter=strong:block=8:alpha=0.12:beta=0.07:gamma=0.07:delta=0.07",
+}
+
+var debandLevels = map[string]string{
+	"light":  "deband=1thr=0.015:2thr=0.015:3thr=0.015:4thr=0.015:range=12",
+	"medium": "deband=1thr=0.02:2thr=0.02:3thr=0.02:4thr=0.02:range=16",
+	"strong": "deband=1thr=0.04:2thr=0.04:3thr=0.04:4thr=0.04:range=24:blur=1",
+}
+
+// cleanupFilter returns the filter for a strength level, "" for none.
+func cleanupFilter(levels map[string]string, level *string) string {
+	if level == nil {
+		return ""
+	}
+	return levels[strings.ToLower(strings.TrimSpace(*level))]
+}
+
+func validateCleanupLevel(name string, level *string) error {
+	if level == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(*level)) {
+	case "", "none", "light", "medium", "strong":
+		return nil
+	}
+	return fmt.Errorf("unsupported %s strength: %s (expected none|light|medium|strong)", name, *level)
+}
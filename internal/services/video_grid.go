@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// VideoGridService composes 2–4 videos into one frame — side by side,
+// stacked, or a 2x2 grid — for before/after comparisons and multi-cam
+// review. Every input is conformed to the same cell size, frame rate and
+// pixel format first so the stack filters accept them, and their audio is
+// mixed (or one track picked) on the same timeline.
+type VideoGridService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewVideoGridService(cfg *config.Config, jm *JobManager) *VideoGridService {
+	return &VideoGridService{cfg: cfg, jobManager: jm}
+}
+
+const (
+	VideoGridMinInputs = 2
+	VideoGridMaxInputs = 4
+
+	videoGridDefaultCellWidth  = 960
+	videoGridDefaultCellHeight = 540
+	videoGridMaxCanvas         = 3840
+	videoGridFPS               = 30
+)
+
+// VideoGridOptions are the validated composition parameters.
+type VideoGridOptions struct {
+	// Layout: hstack (side by side, default), vstack (top to bottom) or grid
+	// (2x2; a 3-input grid leaves the last cell black).
+	Layout string `json:"layout"`
+	// CellWidth / CellHeight is the size each input is fitted into.
+	CellWidth  int `json:"cellWidth"`
+	CellHeight int `json:"cellHeight"`
+	// Audio: mix (default) mixes every input that has audio, first keeps
+	// only the first input's track, none drops audio.
+	Audio string `json:"audio"`
+	// Duration: shortest (default) ends with the shortest input; longest
+	// runs to the longest, holding the last frame of finished inputs.
+	Duration string `json:"duration"`
+	// Quality is the usual low/medium/high encode preset.
+	Quality string `json:"quality"`
+}
+
+func (o *VideoGridOptions) applyDefaults(inputs int) error {
+	if inputs < VideoGridMinInputs || inputs > VideoGridMaxInputs {
+		return fmt.Errorf("provide between %d and %d videos", VideoGridMinInputs, VideoGridMaxInputs)
+	}
+	o.Layout = strings.ToLower(strings.TrimSpace(o.Layout))
+	switch o.Layout {
+	case "":
+		o.Layout = "hstack"
+	case "hstack", "vstack", "grid":
+	default:
+		return fmt.Errorf("unsupported layout: %s (expected hstack|vstack|grid)", o.Layout)
+	}
+	if o.Layout == "grid" && inputs < 3 {
+		return errors.New("grid layout needs 3 or 4 videos; use hstack or vstack for 2")
+	}
+	o.Audio = strings.ToLower(strings.TrimSpace(o.Audio))
+	switch o.Audio {
+	case "":
+		o.Audio = "mix"
+	case "mix", "first", "none":
+	default:
+		return fmt.Errorf("unsupported audio mode: %s (expected mix|first|none)", o.Audio)
+	}
+	o.Duration = strings.ToLower(strings.TrimSpace(o.Duration))
+	switch o.Duration {
+	case "":
+		o.Duration = "shortest"
+	case "shortest", "longest":
+	default:
+		return fmt.Errorf("unsupported duration mode: %s (expected shortest|longest)", o.Duration)
+	}
+	switch o.Quality {
+	case "":
+		o.Quality = "medium"
+	case "low", "medium", "high":
+	default:
+		return fmt.Errorf("unsupported quality: %s", o.Quality)
+	}
+	if o.CellWidth == 0 {
+		o.CellWidth = videoGridDefaultCellWidth
+	}
+	if o.CellHeight == 0 {
+		o.CellHeight = videoGridDefaultCellHeight
+	}
+	if o.CellWidth < 16 || o.CellHeight < 16 {
+		return errors.New("cell dimensions must be at least 16 pixels")
+	}
+	// yuv420p needs even dimensions.
+	o.CellWidth += o.CellWidth % 2
+	o.CellHeight += o.CellHeight % 2
+	cols, rows := videoGridShape(o.Layout, inputs)
+	if o.CellWidth*cols > videoGridMaxCanvas || o.CellHeight*rows > videoGridMaxCanvas {
+		return fmt.Errorf("composed frame would exceed %dpx; reduce the cell size", videoGridMaxCanvas)
+	}
+	return nil
+}
+
+// ValidateVideoGridOptions normalizes opts for the given input count.
+func ValidateVideoGridOptions(opts *VideoGridOptions, inputs int) error {
+	return opts.applyDefaults(inputs)
+}
+
+// videoGridShape returns the column/row count of the composed frame.
+func videoGridShape(layout string, inputs int) (cols, rows int) {
+	switch layout {
+	case "vstack":
+		return 1, inputs
+	case "grid":
+		return 2, 2
+	default:
+		return inputs, 1
+	}
+}
+
+// buildVideoGridFilter builds the -filter_complex graph. hasAudio lists which
+// inputs carry an audio stream; the returned audio label is "" when the
+// output has no audio.
+func buildVideoGridFilter(opts VideoGridOptions, hasAudio []bool) (graph string, audioLabel string) {
+	n := len(hasAudio)
+	var parts []string
+	var cells strings.Builder
+	for i := 0; i < n; i++ {
+		parts = append(parts, fmt.Sprintf(
+			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=black,setsar=1,fps=%d,format=yuv420p[v%d]",
+			i, opts.CellWidth, opts.CellHeight, opts.CellWidth, opts.CellHeight, videoGridFPS, i))
+		fmt.Fprintf(&cells, "[v%d]", i)
+	}
+	shortest := 0
+	if opts.Duration == "shortest" {
+		shortest = 1
+	}
+	switch opts.Layout {
+	case "vstack":
+		parts = append(parts, fmt.Sprintf("%svstack=inputs=%d:shortest=%d[vout]", cells.String(), n, shortest))
+	case "grid":
+		layout := "0_0|w0_0|0_h0|w0_h0"
+		if n == 3 {
+			layout = "0_0|w0_0|0_h0"
+		}
+		parts = append(parts, fmt.Sprintf("%sxstack=inputs=%d:layout=%s:fill=black:shortest=%d[vout]", cells.String(), n, layout, shortest))
+	default:
+		parts = append(parts, fmt.Sprintf("%shstack=inputs=%d:shortest=%d[vout]", cells.String(), n, shortest))
+	}
+
+	switch opts.Audio {
+	case "first":
+		if hasAudio[0] {
+			parts = append(parts, "[0:a]aresample=48000[aout]")
+			audioLabel = "[aout]"
+		}
+	case "mix":
+		var ins []string
+		for i, ok := range hasAudio {
+			if ok {
+				ins = append(ins, fmt.Sprintf("[%d:a]", i))
+			}
+		}
+		switch len(ins) {
+		case 0:
+		case 1:
+			parts = append(parts, ins[0]+"aresample=48000[aout]")
+			audioLabel = "[aout]"
+		default:
+			// normalize=0 keeps each track at its own level instead of
+			// dividing by the input count; the limiter catches the sum.
+			parts = append(parts, fmt.Sprintf("%samix=inputs=%d:duration=%s:normalize=0,alimiter=limit=0.95,aresample=48000[aout]",
+				strings.Join(ins, ""), len(ins), opts.Duration))
+			audioLabel = "[aout]"
+		}
+	}
+	return strings.Join(parts, ";"), audioLabel
+}
+
+// buildVideoGridArgs assembles the full ffmpeg command.
+func buildVideoGridArgs(inputs []string, opts VideoGridOptions, hasAudio []bool, outputPath string) []string {
+	args := []string{"-hide_banner", "-y"}
+	for _, in := range inputs {
+		args = append(args, "-i", in)
+	}
+	graph, audioLabel := buildVideoGridFilter(opts, hasAudio)
+	args = append(args, "-filter_complex", graph, "-map", "[vout]")
+	settings := videoEncodeSettings{Format: "mp4", Quality: opts.Quality}
+	if audioLabel != "" {
+		args = append(args, "-map", audioLabel)
+	} else {
+		settings.StripAudio = true
+	}
+	if opts.Duration == "shortest" {
+		args = append(args, "-shortest")
+	}
+	args = append(args, buildVideoCodecArgs(settings)...)
+	return append(args, outputPath)
+}
+
+// Compose renders the grid to outputPath.
+func (s *VideoGridService) Compose(ctx context.Context, job *models.ConversionJob, inputs []string, opts VideoGridOptions, outputPath string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found in PATH")
+	}
+	if err := opts.applyDefaults(len(inputs)); err != nil {
+		return err
+	}
+	hasAudio := make([]bool, len(inputs))
+	for i, in := range inputs {
+		if !ffprobeHasStream(ctx, in, "v") {
+			return fmt.Errorf("input %d has no video stream", i+1)
+		}
+		hasAudio[i] = ffprobeHasStream(ctx, in, "a")
+	}
+	s.progress(job.ID, 10)
+	_, stderr, err := runCommand(ctx, "ffmpeg", buildVideoGridArgs(inputs, opts, hasAudio, outputPath)...)
+	if err != nil {
+		return fmt.Errorf("video grid composition failed: %w (%s)", err, commandTail(stderr, 1000))
+	}
+	if err := requireNonEmpty(outputPath); err != nil {
+		return fmt.Errorf("video grid composition produced no output: %w", err)
+	}
+	s.progress(job.ID, 95)
+	return nil
+}
+
+func (s *VideoGridService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVideoGridOptions(t *testing.T) {
+	opts := VideoGridOptions{}
+	if err := ValidateVideoGridOptions(&opts, 2); err != nil {
+		t.Fatalf("defaults rejected: %v", err)
+	}
+	if opts.Layout != "hstack" || opts.Audio != "mix" || opts.Duration != "shortest" || opts.CellWidth != 960 || opts.CellHeight != 540 {
+		t.Fatalf("defaults not applied: %+v", opts)
+	}
+
+	opts = VideoGridOptions{Layout: "grid", CellWidth: 641, CellHeight: 361}
+	if err := ValidateVideoGridOptions(&opts, 3); err != nil {
+		t.Fatalf("grid rejected: %v", err)
+	}
+	if opts.CellWidth != 642 || opts.CellHeight != 362 {
+		t.Fatalf("odd cell size not rounded to even: %+v", opts)
+	}
+
+	for name, tc := range map[string]struct {
+		opts   VideoGridOptions
+		inputs int
+	}{
+		"one input":    {VideoGridOptions{}, 1},
+		"five inputs":  {VideoGridOptions{}, 5},
+		"grid of two":  {VideoGridOptions{Layout: "grid"}, 2},
+		"bad layout":   {VideoGridOptions{Layout: "mosaic"}, 2},
+		"bad audio":    {VideoGridOptions{Audio: "loudest"}, 2},
+		"too wide":     {VideoGridOptions{CellWidth: 1920}, 3},
+		"tiny cell":    {VideoGridOptions{CellWidth: 8}, 2},
+		"bad duration": {VideoGridOptions{Duration: "first"}, 2},
+	} {
+		o := tc.opts
+		if err := ValidateVideoGridOptions(&o, tc.inputs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBuildVideoGridFilter(t *testing.T) {
+	opts := VideoGridOptions{Layout: "grid"}
+	if err := opts.applyDefaults(3); err != nil {
+		t.Fatal(err)
+	}
+	graph, audio := buildVideoGridFilter(opts, []bool{true, false, true})
+	for _, want := range []string{
+		"[2:v]scale=960:540:force_original_aspect_ratio=decrease",
+		"xstack=inputs=3:layout=0_0|w0_0|0_h0:fill=black:shortest=1[vout]",
+		"[0:a][2:a]amix=inputs=2:duration=shortest",
+	} {
+		if !strings.Contains(graph, want) {
+			t.Errorf("graph missing %q:\n%s", want, graph)
+		}
+	}
+	if strings.Contains(graph, "[1:a]") || audio != "[aout]" {
+		t.Fatalf("silent input must not be mixed: %s (%s)", graph, audio)
+	}
+
+	opts = VideoGridOptions{Layout: "vstack", Audio: "first", Duration: "longest"}
+	if err := opts.applyDefaults(2); err != nil {
+		t.Fatal(err)
+	}
+	graph, audio = buildVideoGridFilter(opts, []bool{false, true})
+	if !strings.Contains(graph, "vstack=inputs=2:shortest=0[vout]") || audio != "" {
+		t.Fatalf("vstack/first-without-audio: %s (%q)", graph, audio)
+	}
+}
+
+func TestBuildVideoGridArgs(t *testing.T) {
+	opts := VideoGridOptions{Audio: "none"}
+	if err := opts.applyDefaults(2); err != nil {
+		t.Fatal(err)
+	}
+	args := buildVideoGridArgs([]string{"/a.mp4", "/b.mp4"}, opts, []bool{true, true}, "/out.mp4")
+	if countFlag(args, "-i") != 2 || valueAfter(args, "-map") != "[vout]" || countFlag(args, "-an") != 1 {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if countFlag(args, "-shortest") != 1 || args[len(args)-1] != "/out.mp4" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
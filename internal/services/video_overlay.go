@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// Video counterparts of the image text overlay and size limit, so an API-key
+// policy can force a watermark and a maximum resolution on any output.
+
+// videoOverlayPositions maps the ImageMagick gravities to drawtext x/y
+// expressions. The overlay's X/Y offsets are added as margins from the
+// anchored edges, matching -annotate.
+var videoOverlayPositions = map[string][2]string{
+	"NorthWest": {"%[1]d", "%[2]d"},
+	"North":     {"(w-text_w)/2+%[1]d", "%[2]d"},
+	"NorthEast": {"w-text_w-%[1]d", "%[2]d"},
+	"West":      {"%[1]d", "(h-text_h)/2+%[2]d"},
+	"Center":    {"(w-text_w)/2+%[1]d", "(h-text_h)/2+%[2]d"},
+	"East":      {"w-text_w-%[1]d", "(h-text_h)/2+%[2]d"},
+	"SouthWest": {"%[1]d", "h-text_h-%[2]d"},
+	"South":     {"(w-text_w)/2+%[1]d", "h-text_h-%[2]d"},
+	"SouthEast": {"w-text_w-%[1]d", "h-text_h-%[2]d"},
+}
+
+// videoTextOverlayFilter renders a validated ImageTextOverlay with drawtext.
+// Defaults follow imageTextOverlayArgs: 48px white DejaVu Sans at South.
+func videoTextOverlayFilter(overlay *models.ImageTextOverlay) string {
+	// drawtext has no "@file" or %[...] forms, so the text only needs
+	// drawtextEscape, not sanitizeAnnotationText.
+	text := drawtextEscape(strings.ReplaceAll(strings.TrimSpace(overlay.Text), "\x00", ""))
+	size := overlay.Size
+	if size == 0 {
+		size = 48
+	}
+	font := overlay.Font
+	if font == "" || font == "default" {
+		font = "DejaVu-Sans"
+	}
+	gravity := overlay.Gravity
+	if gravity == "" {
+		gravity = "South"
+	}
+	pos := videoOverlayPositions[gravity]
+	color := "white"
+	if overlay.Color != "" {
+		color = ffmpegOverlayColor(overlay.Color)
+	}
+	filter := fmt.Sprintf("drawtext=fontfile='%s':text='%s':fontsize=%d:fontcolor=%s:x=%s:y=%s",
+		drawtextEscape(timecodeFontFiles[font]), text, size, color,
+		fmt.Sprintf(pos[0], overlay.X, overlay.Y), fmt.Sprintf(pos[1], overlay.X, overlay.Y))
+	if overlay.StrokeWidth > 0 {
+		stroke := "black"
+		if overlay.StrokeColor != "" {
+			stroke = ffmpegOverlayColor(overlay.StrokeColor)
+		}
+		filter += fmt.Sprintf(":borderw=%d:bordercolor=%s", overlay.StrokeWidth, stroke)
+	}
+	return filter
+}
+
+// ffmpegOverlayColor converts the #RGB, #RRGGBB and #RRGGBBAA forms that
+// isSafeImageColor accepts into FFmpeg color syntax.
+func ffmpegOverlayColor(hex string) string {
+	h := strings.TrimPrefix(hex, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	return "0x" + strings.ToUpper(h)
+}
+
+// videoMaxSizeFilter shrinks the frame to fit maxWidth x maxHeight without
+// upscaling, keeping the aspect ratio and even dimensions. Either bound may
+// be nil.
+func videoMaxSizeFilter(maxWidth, maxHeight *int) string {
+	switch {
+	case maxWidth != nil && maxHeight != nil:
+		return fmt.Sprintf(`scale=w=min(iw\,%d):h=min(ih\,%d):force_original_aspect_ratio=decrease:force_divisible_by=2`, *maxWidth, *maxHeight)
+	case maxWidth != nil:
+		return fmt.Sprintf(`scale=w=min(iw\,%d):h=-2`, *maxWidth)
+	case maxHeight != nil:
+		return fmt.Sprintf(`scale=w=-2:h=min(ih\,%d)`, *maxHeight)
+	}
+	return ""
+}
+
+// imageMaxSizeArg is the ImageMagick shrink-only geometry for the same
+// bounds ("1920x1080>").
+func imageMaxSizeArg(maxWidth, maxHeight *int) string {
+	w, h := "", ""
+	if maxWidth != nil {
+		w = strconv.Itoa(*maxWidth)
+	}
+	if maxHeight != nil {
+		h = strconv.Itoa(*maxHeight)
+	}
+	if w == "" && h == "" {
+		return ""
+	}
+	return w + "x" + h + ">"
+}
+
+func validateMaxSize(maxWidth, maxHeight *int) error {
+	if maxWidth != nil && (*maxWidth < 16 || *maxWidth > 10000) {
+		return fmt.Errorf("maxWidth must be between 16 and 10000, got %d", *maxWidth)
+	}
+	if maxHeight != nil && (*maxHeight < 16 || *maxHeight > 10000) {
+		return fmt.Errorf("maxHeight must be between 16 and 10000, got %d", *maxHeight)
+	}
+	return nil
+}
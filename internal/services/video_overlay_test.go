@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+func TestVideoTextOverlayFilter(t *testing.T) {
+	got := videoTextOverlayFilter(&models.ImageTextOverlay{Text: "ACME: 100%", Gravity: "SouthEast", X: 12, Y: 8, Color: "#fc0", StrokeWidth: 2})
+	for _, want := range []string{
+		`text='ACME\: 100\%'`,
+		"fontsize=48",
+		"fontcolor=0xFFCC00",
+		"x=w-text_w-12:y=h-text_h-8",
+		"borderw=2:bordercolor=black",
+		"DejaVuSans.ttf",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("filter %q missing %q", got, want)
+		}
+	}
+}
+
+func TestVideoTextOverlayFilterKeepsTextQuoted(t *testing.T) {
+	got := videoTextOverlayFilter(&models.ImageTextOverlay{Text: `it's',drawtext=textfile=/etc/passwd`})
+	if !strings.Contains(got, "text='it’s’,drawtext=textfile=/etc/passwd'") {
+		t.Errorf("quote not neutralized: %q", got)
+	}
+	if strings.Count(got, "'") != 4 {
+		t.Errorf("filter %q has stray quotes", got)
+	}
+}
+
+func TestVideoMaxSizeFilter(t *testing.T) {
+	w, h := 1920, 1080
+	if got := videoMaxSizeFilter(&w, &h); !strings.Contains(got, `w=min(iw\,1920):h=min(ih\,1080):force_original_aspect_ratio=decrease`) {
+		t.Errorf("both bounds: %q", got)
+	}
+	if got := videoMaxSizeFilter(nil, &h); got != `scale=w=-2:h=min(ih\,1080)` {
+		t.Errorf("height bound: %q", got)
+	}
+	if videoMaxSizeFilter(nil, nil) != "" {
+		t.Error("no bounds should add no filter")
+	}
+	if got := imageMaxSizeArg(&w, nil); got != "1920x>" {
+		t.Errorf("imageMaxSizeArg = %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is how much of the file goes into each INSTREAM chunk.
+// clamd only bounds the total (StreamMaxLength), not the chunks.
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner checks files with a ClamAV daemon. Files are streamed over
+// the socket (INSTREAM), so clamd needs no access to the upload directory.
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner returns a scanner for the clamd at address: a unix socket
+// path ("/run/clamav/clamd.ctl" or "unix:/run/clamav/clamd.ctl") or a TCP
+// "host:port" ("tcp://host:port" also works). timeout bounds each scan.
+func NewClamdScanner(address string, timeout time.Duration) *ClamdScanner {
+	s := &ClamdScanner{network: "tcp", address: strings.TrimSpace(address), timeout: timeout}
+	switch {
+	case strings.HasPrefix(s.address, "unix:"):
+		s.network, s.address = "unix", strings.TrimPrefix(s.address, "unix:")
+	case strings.HasPrefix(s.address, "tcp://"):
+		s.address = strings.TrimPrefix(s.address, "tcp://")
+	case strings.HasPrefix(s.address, "/"):
+		s.network = "unix"
+	}
+	return s
+}
+
+// Scan streams the file at path to clamd. It returns the name of the
+// signature that matched, or "" when the file is clean. An error means the
+// file could not be checked, including when it is larger than clamd's
+// StreamMaxLength.
+func (s *ClamdScanner) Scan(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for scanning: %w", err)
+	}
+	defer f.Close()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := f.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd hangs up once the stream passes StreamMaxLength;
+				// its reply says so.
+				break
+			}
+		}
+		if readErr == io.EOF {
+			binary.BigEndian.PutUint32(buf[:4], 0)
+			if _, err := conn.Write(buf[:4]); err != nil {
+				return "", fmt.Errorf("clamd: %w", err)
+			}
+			break
+		}
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read file for scanning: %w", readErr)
+		}
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("clamd: no reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// Ping checks that clamd is up.
+func (s *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "zPING\x00"); err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if strings.TrimRight(reply, "\x00") != "PONG" {
+		return fmt.Errorf("clamd: unexpected ping reply %q: %v", reply, err)
+	}
+	return nil
+}
+
+func (s *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach clamd: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// parseClamdReply reads an INSTREAM reply: "stream: OK",
+// "stream: <signature> FOUND" or "<reason> ERROR".
+func parseClamdReply(reply string) (string, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		threat := strings.TrimSuffix(reply, " FOUND")
+		if _, after, ok := strings.Cut(threat, ": "); ok {
+			threat = after
+		}
+		return strings.TrimSpace(threat), nil
+	case strings.HasSuffix(reply, ": OK"):
+		return "", nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return "", fmt.Errorf("clamd: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+	return "", fmt.Errorf("clamd: unexpected reply %q", reply)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd answers INSTREAM like clamd, flagging streams that contain the
+// EICAR marker, and PING with PONG.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, _ := r.ReadString(0)
+				if cmd == "zPING\x00" {
+					io.WriteString(conn, "PONG\x00")
+					return
+				}
+				var data bytes.Buffer
+				for {
+					var size uint32
+					if binary.Read(r, binary.BigEndian, &size) != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+						return
+					}
+				}
+				if strings.Contains(data.String(), "EICAR") {
+					io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return sock
+}
+
+func TestClamdScanner(t *testing.T) {
+	scanner := NewClamdScanner("unix:"+fakeClamd(t), 5*time.Second)
+	if err := scanner.Ping(context.Background()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.bin")
+	infected := filepath.Join(dir, "infected.bin")
+	_ = os.WriteFile(clean, bytes.Repeat([]byte("x"), 3*clamdChunkSize+17), 0o644)
+	_ = os.WriteFile(infected, append(bytes.Repeat([]byte("x"), clamdChunkSize), "EICAR"...), 0o644)
+
+	if threat, err := scanner.Scan(context.Background(), clean); err != nil || threat != "" {
+		t.Fatalf("clean file: threat %q, err %v", threat, err)
+	}
+	if threat, err := scanner.Scan(context.Background(), infected); err != nil || threat != "Eicar-Test-Signature" {
+		t.Fatalf("infected file: threat %q, err %v", threat, err)
+	}
+}
+
+func TestClamdScannerUnreachable(t *testing.T) {
+	scanner := NewClamdScanner(filepath.Join(t.TempDir(), "missing.sock"), time.Second)
+	if _, err := scanner.Scan(context.Background(), "virus_scan.go"); err == nil {
+		t.Fatal("expected an error without clamd")
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	cases := []struct {
+		reply, threat string
+		wantErr       bool
+	}{
+		{reply: "stream: OK\x00"},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND\x00", threat: "Win.Test.EICAR_HDB-1"},
+		{reply: "INSTREAM size limit exceeded. ERROR\x00", wantErr: true},
+		{reply: "garbage", wantErr: true},
+	}
+	for _, tc := range cases {
+		threat, err := parseClamdReply(tc.reply)
+		if threat != tc.threat || (err != nil) != tc.wantErr {
+			t.Errorf("%q: threat %q, err %v", tc.reply, threat, err)
+		}
+	}
+}
+
+func TestNewClamdScannerAddress(t *testing.T) {
+	cases := map[string][2]string{
+		"/run/clamav/clamd.ctl":      {"unix", "/run/clamav/clamd.ctl"},
+		"unix:/run/clamav/clamd.ctl": {"unix", "/run/clamav/clamd.ctl"},
+		"clamav:3310":                {"tcp", "clamav:3310"},
+		"tcp://clamav:3310":          {"tcp", "clamav:3310"},
+	}
+	for in, want := range cases {
+		s := NewClamdScanner(in, 0)
+		if s.network != want[0] || s.address != want[1] {
+			t.Errorf("%s: got %s %s", in, s.network, s.address)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+)
+
+// Synchronous waveforms for POST /api/waveform. JSON peaks are decoded as
+// mono 8 kHz PCM and bucketed on the fly into 100 min/max pairs per second,
+// then merged down to the requested point count, so memory stays flat no
+// matter how long the file is. PNGs reuse the audio_waveform mode's
+// showwavespic renderer and its option validation.
+
+const (
+	WaveformDefaultPoints = 1000
+	WaveformMaxPoints     = 20000
+
+	waveformPeaksSampleRate     = 8000
+	waveformFineBucketsPerSec   = 100
+	waveformPeaksSamplesPerFine = waveformPeaksSampleRate / waveformFineBucketsPerSec
+)
+
+// WaveformRequest configures one waveform render. Image is only used for
+// png output; its OutputSelection and ImageFormat are forced.
+type WaveformRequest struct {
+	Format string
+	Points int
+	Image  AudioWaveformOptions
+}
+
+// WaveformPeaks is the JSON response. Peaks is a flat [min0,max0,min1,max1,…]
+// list normalized to -1..1; Points is the number of pairs, which is lower
+// than requested for clips shorter than Points/100 seconds.
+type WaveformPeaks struct {
+	Duration   float64   `json:"duration"`
+	SampleRate int       `json:"sampleRate"`
+	Points     int       `json:"points"`
+	Peaks      []float64 `json:"peaks"`
+}
+
+// ValidateWaveformRequest fills in defaults and rejects unusable options.
+func ValidateWaveformRequest(r *WaveformRequest) error {
+	r.Format = strings.ToLower(strings.TrimSpace(r.Format))
+	if r.Format == "" {
+		r.Format = "json"
+	}
+	switch r.Format {
+	case "json":
+		if r.Points == 0 {
+			r.Points = WaveformDefaultPoints
+		}
+		if r.Points < 10 || r.Points > WaveformMaxPoints {
+			return fmt.Errorf("invalid points: %d (allowed range 10-%d)", r.Points, WaveformMaxPoints)
+		}
+	case "png":
+		r.Image.OutputSelection = "image"
+		r.Image.ImageFormat = "png"
+		return r.Image.applyDefaults()
+	default:
+		return fmt.Errorf("invalid format: %q (expected json|png)", r.Format)
+	}
+	return nil
+}
+
+// RenderWaveformPNG draws the whole file into one PNG.
+func RenderWaveformPNG(ctx context.Context, inputPath, outputPath string, r *WaveformRequest) error {
+	return renderWaveformImage(ctx, inputPath, outputPath, &r.Image)
+}
+
+// GenerateWaveformPeaks streams the decoded audio through bucketPCM and
+// reduces it to r.Points min/max pairs.
+func GenerateWaveformPeaks(ctx context.Context, inputPath string, r *WaveformRequest) (*WaveformPeaks, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, errors.New("ffmpeg not found in PATH")
+	}
+	cmd := limitedCommand(ctx, "ffmpeg",
+		"-v", "error", "-i", inputPath, "-vn",
+		"-ac", "1", "-ar", fmt.Sprint(waveformPeaksSampleRate),
+		"-f", "s16le", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+	fine, samples, readErr := bucketPCM(stdout, waveformPeaksSamplesPerFine)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("decode audio for waveform: %w (%s)", err, tail(stderr.String(), 1500))
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("read decoded audio: %w", readErr)
+	}
+	if samples == 0 {
+		return nil, errors.New("no audio samples decoded")
+	}
+	peaks := downsamplePeaks(fine, r.Points)
+	return &WaveformPeaks{
+		Duration:   math.Round(float64(samples)/waveformPeaksSampleRate*1000) / 1000,
+		SampleRate: waveformPeaksSampleRate,
+		Points:     len(peaks) / 2,
+		Peaks:      peaks,
+	}, nil
+}
+
+// bucketPCM reads little-endian mono s16le PCM and returns a flat min/max
+// list with one pair per samplesPerBucket samples, plus the sample count.
+func bucketPCM(r io.Reader, samplesPerBucket int) ([]int16, int, error) {
+	var (
+		out      []int16
+		samples  int
+		mn, mx   int16
+		inBucket int
+		carry    []byte
+	)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		data := append(carry, buf[:n]...)
+		even := len(data) &^ 1
+		for i := 0; i < even; i += 2 {
+			s := int16(uint16(data[i]) | uint16(data[i+1])<<8)
+			if inBucket == 0 || s < mn {
+				mn = s
+			}
+			if inBucket == 0 || s > mx {
+				mx = s
+			}
+			inBucket++
+			samples++
+			if inBucket == samplesPerBucket {
+				out = append(out, mn, mx)
+				inBucket = 0
+			}
+		}
+		carry = append(carry[:0], data[even:]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if inBucket > 0 {
+		out = append(out, mn, mx)
+	}
+	return out, samples, nil
+}
+
+// downsamplePeaks merges a flat min/max list into at most points pairs,
+// normalized to -1..1 and rounded to four decimals to keep the JSON small.
+func downsamplePeaks(fine []int16, points int) []float64 {
+	pairs := len(fine) / 2
+	if points > pairs {
+		points = pairs
+	}
+	norm := func(s int16) float64 {
+		return math.Round(float64(s)/32768*10000) / 10000
+	}
+	out := make([]float64, 0, points*2)
+	for i := 0; i < points; i++ {
+		start, end := i*pairs/points, (i+1)*pairs/points
+		mn, mx := fine[start*2], fine[start*2+1]
+		for j := start + 1; j < end; j++ {
+			mn = min(mn, fine[j*2])
+			mx = max(mx, fine[j*2+1])
+		}
+		out = append(out, norm(mn), norm(mx))
+	}
+	return out
+}
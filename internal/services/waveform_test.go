@@ -0,0 +1,58 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBucketPCM(t *testing.T) {
+	samples := []int16{100, -200, 300, 32767, -32768, 5, 7}
+	var pcm bytes.Buffer
+	_ = binary.Write(&pcm, binary.LittleEndian, samples)
+	// OneByteReader splits every sample across reads.
+	got, n, err := bucketPCM(iotest.OneByteReader(&pcm), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int16{-200, 300, -32768, 32767, 7, 7}
+	if n != len(samples) || !reflect.DeepEqual(got, want) {
+		t.Fatalf("bucketPCM = %v (%d samples), want %v (%d)", got, n, want, len(samples))
+	}
+}
+
+func TestDownsamplePeaks(t *testing.T) {
+	fine := []int16{-16384, 8192, -100, 100, 0, 32767, -32768, 0}
+	got := downsamplePeaks(fine, 2)
+	want := []float64{-0.5, 0.25, -1, 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("downsamplePeaks = %v, want %v", got, want)
+	}
+	if got := downsamplePeaks(fine, 100); len(got) != len(fine) {
+		t.Fatalf("asking for more points than buckets should return every bucket, got %d values", len(got))
+	}
+}
+
+func TestValidateWaveformRequest(t *testing.T) {
+	r := WaveformRequest{}
+	if err := ValidateWaveformRequest(&r); err != nil || r.Format != "json" || r.Points != WaveformDefaultPoints {
+		t.Fatalf("unexpected defaults: %+v, %v", r, err)
+	}
+	r = WaveformRequest{Format: "PNG"}
+	if err := ValidateWaveformRequest(&r); err != nil || r.Image.ImageFormat != "png" || r.Image.Width != waveformDefaultWidth {
+		t.Fatalf("unexpected png defaults: %+v, %v", r, err)
+	}
+	for _, bad := range []WaveformRequest{
+		{Format: "svg"},
+		{Points: 5},
+		{Points: WaveformMaxPoints + 1},
+		{Format: "png", Image: AudioWaveformOptions{ColorPrimary: "red"}},
+	} {
+		bad := bad
+		if err := ValidateWaveformRequest(&bad); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+// WebpageScreenshotService renders a URL to PNG, WebP or PDF with a headless
+// Chrome/Chromium. Chrome is driven through its own --headless command line
+// (--screenshot / --print-to-pdf) like every other tool in this package, so
+// the integration needs a browser binary on the worker but no extra Go
+// dependency. WebP is produced by converting Chrome's PNG with ImageMagick.
+type WebpageScreenshotService struct {
+	cfg        *config.Config
+	jobManager *JobManager
+}
+
+func NewWebpageScreenshotService(cfg *config.Config, jm *JobManager) *WebpageScreenshotService {
+	return &WebpageScreenshotService{cfg: cfg, jobManager: jm}
+}
+
+const (
+	webpageScreenshotDefaultWidth  = 1280
+	webpageScreenshotDefaultHeight = 800
+	webpageScreenshotMinDimension  = 320
+	webpageScreenshotMaxDimension  = 3840
+	webpageScreenshotDefaultDelay  = 1000
+	webpageScreenshotMaxDelay      = 15000
+)
+
+// chromeBinaryCandidates are tried in order when SCREENSHOT_CHROME_PATH is
+// unset.
+var chromeBinaryCandidates = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// WebpageScreenshotOptions are the render parameters for one capture.
+type WebpageScreenshotOptions struct {
+	// Format: png (default), webp or pdf.
+	Format string `json:"format"`
+	// Width / Height of the browser viewport in CSS pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// DelayMs is how long the page may run (scripts, network, animations)
+	// before the capture. Default 1000.
+	DelayMs *int `json:"delayMs,omitempty"`
+}
+
+func (o *WebpageScreenshotOptions) applyDefaults() error {
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	switch o.Format {
+	case "":
+		o.Format = "png"
+	case "png", "webp", "pdf":
+	default:
+		return fmt.Errorf("unsupported screenshot format: %s (expected png|webp|pdf)", o.Format)
+	}
+	if o.Width == 0 {
+		o.Width = webpageScreenshotDefaultWidth
+	}
+	if o.Height == 0 {
+		o.Height = webpageScreenshotDefaultHeight
+	}
+	for _, d := range []int{o.Width, o.Height} {
+		if d < webpageScreenshotMinDimension || d > webpageScreenshotMaxDimension {
+			return fmt.Errorf("viewport dimensions must be between %d and %d", webpageScreenshotMinDimension, webpageScreenshotMaxDimension)
+		}
+	}
+	if o.DelayMs == nil {
+		d := webpageScreenshotDefaultDelay
+		o.DelayMs = &d
+	}
+	if *o.DelayMs < 0 || *o.DelayMs > webpageScreenshotMaxDelay {
+		return fmt.Errorf("delayMs must be between 0 and %d", webpageScreenshotMaxDelay)
+	}
+	return nil
+}
+
+// ValidateWebpageScreenshotRequest checks the target URL and normalizes the
+// options in place. Only http(s) is accepted, and unless allowPrivate is set
+// the host must resolve to public addresses. The check covers the top-level
+// URL only; subresources the page loads are not filtered, which is why the
+// feature is off by default.
+func ValidateWebpageScreenshotRequest(ctx context.Context, raw string, opts *WebpageScreenshotOptions, allowPrivate bool) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, errors.New("invalid URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q (expected http or https)", u.Scheme)
+	}
+	if u.User != nil {
+		return nil, errors.New("URLs with embedded credentials are not supported")
+	}
+	if err := opts.applyDefaults(); err != nil {
+		return nil, err
+	}
+	if !allowPrivate {
+		if err := requirePublicHost(ctx, u.Hostname()); err != nil {
+			return nil, fmt.Errorf("screenshot target %w", err)
+		}
+	}
+	return u, nil
+}
+
+// resolveChromeBinary returns the configured browser, or the first candidate
+// found on PATH.
+func resolveChromeBinary(configured string) (string, error) {
+	if configured = strings.TrimSpace(configured); configured != "" {
+		if path, err := exec.LookPath(configured); err == nil {
+			return path, nil
+		}
+		return "", fmt.Errorf("configured Chrome binary %q not found", configured)
+	}
+	for _, name := range chromeBinaryCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no headless Chrome/Chromium found on PATH (set SCREENSHOT_CHROME_PATH)")
+}
+
+// buildChromeCaptureArgs assembles the headless invocation. A throwaway
+// profile directory keeps cookies and cache from leaking between jobs, and
+// --virtual-time-budget lets the page settle for DelayMs before capture.
+// Chrome refuses to start as root without --no-sandbox, which is how most
+// containers run it.
+func buildChromeCaptureArgs(u *url.URL, opts WebpageScreenshotOptions, profileDir, capturePath string, noSandbox bool) []string {
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--mute-audio",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-extensions",
+		"--disable-background-networking",
+		"--user-data-dir=" + profileDir,
+		fmt.Sprintf("--window-size=%d,%d", opts.Width, opts.Height),
+		"--virtual-time-budget=" + strconv.Itoa(*opts.DelayMs),
+	}
+	if noSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	if opts.Format == "pdf" {
+		args = append(args, "--no-pdf-header-footer", "--print-to-pdf="+capturePath)
+	} else {
+		args = append(args, "--screenshot="+capturePath)
+	}
+	return append(args, u.String())
+}
+
+// Capture renders target into outputPath.
+func (s *WebpageScreenshotService) Capture(ctx context.Context, job *models.ConversionJob, target *url.URL, opts WebpageScreenshotOptions, outputPath string) error {
+	chrome, err := resolveChromeBinary(s.cfg.ScreenshotChromePath)
+	if err != nil {
+		return err
+	}
+	workDir, err := os.MkdirTemp(s.cfg.TempDir, "screenshot-")
+	if err != nil {
+		return fmt.Errorf("create screenshot workdir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	s.progress(job.ID, 10)
+	capturePath := outputPath
+	if opts.Format == "webp" {
+		capturePath = filepath.Join(workDir, "capture.png")
+	}
+	args := buildChromeCaptureArgs(target, opts, filepath.Join(workDir, "profile"), capturePath, os.Geteuid() == 0)
+	if _, stderr, err := runCommand(withNetwork(ctx), chrome, args...); err != nil {
+		return fmt.Errorf("page render failed: %w (%s)", err, commandTail(stderr, 800))
+	}
+	if err := requireNonEmpty(capturePath); err != nil {
+		return fmt.Errorf("page render produced no output: %w", err)
+	}
+	s.progress(job.ID, 80)
+
+	if opts.Format == "webp" {
+		name, imArgs := resolveImageMagickConvertCommand("convert", []string{capturePath, "-quality", "90", outputPath})
+		if _, stderr, err := runCommand(ctx, name, imArgs...); err != nil {
+			return fmt.Errorf("webp conversion failed: %w (%s)", err, commandTail(stderr, 500))
+		}
+	}
+	s.progress(job.ID, 95)
+	return nil
+}
+
+func (s *WebpageScreenshotService) progress(jobID string, percent int) {
+	if s.jobManager == nil {
+		return
+	}
+	s.jobManager.SendProgressUpdate(jobID, percent)
+}
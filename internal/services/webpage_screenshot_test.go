@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateWebpageScreenshotRequest(t *testing.T) {
+	ctx := context.Background()
+
+	opts := WebpageScreenshotOptions{}
+	u, err := ValidateWebpageScreenshotRequest(ctx, "https://203.0.113.9/pricing", &opts, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Host != "203.0.113.9" || opts.Format != "png" || opts.Width != 1280 || opts.Height != 800 || *opts.DelayMs != 1000 {
+		t.Fatalf("defaults not applied: url=%v opts=%+v", u, opts)
+	}
+
+	cases := []struct {
+		name string
+		raw  string
+		opts WebpageScreenshotOptions
+		want string
+	}{
+		{name: "scheme", raw: "file:///etc/hosts", want: "invalid URL"},
+		{name: "ftp", raw: "ftp://203.0.113.9/x", want: "unsupported URL scheme"},
+		{name: "credentials", raw: "https://u:p@203.0.113.9/", want: "credentials"},
+		{name: "format", raw: "https://203.0.113.9/", opts: WebpageScreenshotOptions{Format: "gif"}, want: "unsupported screenshot format"},
+		{name: "width", raw: "https://203.0.113.9/", opts: WebpageScreenshotOptions{Width: 10000}, want: "viewport"},
+		{name: "loopback", raw: "http://127.0.0.1:8080/admin", want: "non-public"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := tc.opts
+			if _, err := ValidateWebpageScreenshotRequest(ctx, tc.raw, &o, false); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want substring %q", err, tc.want)
+			}
+		})
+	}
+
+	o := WebpageScreenshotOptions{}
+	if _, err := ValidateWebpageScreenshotRequest(ctx, "http://127.0.0.1:8080/", &o, true); err != nil {
+		t.Fatalf("allowPrivate should accept loopback: %v", err)
+	}
+}
+
+func TestBuildChromeCaptureArgs(t *testing.T) {
+	u, _ := url.Parse("https://example.com/page")
+	delay := 2500
+	opts := WebpageScreenshotOptions{Format: "png", Width: 1440, Height: 900, DelayMs: &delay}
+
+	args := buildChromeCaptureArgs(u, opts, "/tmp/profile", "/out/shot.png", false)
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--window-size=1440,900", "--virtual-time-budget=2500", "--screenshot=/out/shot.png", "--user-data-dir=/tmp/profile"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing %s in %v", want, args)
+		}
+	}
+	if strings.Contains(joined, "--no-sandbox") {
+		t.Fatalf("sandbox disabled unexpectedly: %v", args)
+	}
+	if args[len(args)-1] != "https://example.com/page" {
+		t.Fatalf("URL must be the last argument: %v", args)
+	}
+
+	opts.Format = "pdf"
+	args = buildChromeCaptureArgs(u, opts, "/tmp/profile", "/out/page.pdf", true)
+	joined = strings.Join(args, " ")
+	if !strings.Contains(joined, "--print-to-pdf=/out/page.pdf") || strings.Contains(joined, "--screenshot") || !strings.Contains(joined, "--no-sandbox") {
+		t.Fatalf("unexpected pdf args: %v", args)
+	}
+}
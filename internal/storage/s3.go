@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// minPartSize is S3's smallest allowed multipart part (except the last).
+	minPartSize     = 5 << 20
+	defaultPartSize = 64 << 20
+	// maxParts is S3's limit on parts per multipart upload.
+	maxParts = 10000
+)
+
+// S3 stores job files in a bucket under prefix. Files larger than one part
+// are sent as a multipart upload, so results past S3's 5GB single-PUT limit
+// work and a failed part is retried on its own by the SDK.
+type S3 struct {
+	client   *s3.Client
+	presign  *s3.PresignClient
+	bucket   string
+	prefix   string
+	partSize int64
+	ttl      time.Duration
+}
+
+func NewS3(client *s3.Client, bucket, prefix string, partSize int64, ttl time.Duration) *S3 {
+	if partSize < minPartSize {
+		partSize = defaultPartSize
+	}
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	return &S3{
+		client:   client,
+		presign:  s3.NewPresignClient(client),
+		bucket:   bucket,
+		prefix:   strings.Trim(prefix, "/"),
+		partSize: partSize,
+		ttl:      ttl,
+	}
+}
+
+func (s *S3) Name() string { return "s3" }
+
+func (s *S3) Remote() bool { return true }
+
+func (s *S3) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return path.Join(s.prefix, key)
+}
+
+func (s *S3) Save(ctx context.Context, key, src, contentType string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if info.Size() <= s.partSize {
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(s.objectKey(key)),
+			Body:          f,
+			ContentLength: aws.Int64(info.Size()),
+			ContentType:   aws.String(contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("s3 put %s: %w", key, err)
+		}
+		return nil
+	}
+	return s.multipartUpload(ctx, s.objectKey(key), f, info.Size(), contentType)
+}
+
+// partSizeFor grows the part size for files that would otherwise need more
+// than maxParts parts.
+func partSizeFor(size, partSize int64) int64 {
+	if min := (size + maxParts - 1) / maxParts; partSize < min {
+		return min
+	}
+	return partSize
+}
+
+func (s *S3) multipartUpload(ctx context.Context, objectKey string, f *os.File, size int64, contentType string) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 create multipart upload %s: %w", objectKey, err)
+	}
+	uploadID := created.UploadId
+	abort := func(cause error) error {
+		// A fresh context: the upload's own may be what was cancelled.
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = s.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(objectKey),
+			UploadId: uploadID,
+		})
+		return cause
+	}
+
+	partSize := partSizeFor(size, s.partSize)
+	var parts []types.CompletedPart
+	for offset, number := int64(0), int32(1); offset < size; offset, number = offset+partSize, number+1 {
+		length := min(partSize, size-offset)
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(objectKey),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          io.NewSectionReader(f, offset, length),
+			ContentLength: aws.Int64(length),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("s3 upload part %d of %s: %w", number, objectKey, err))
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+	}
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(objectKey),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("s3 complete multipart upload %s: %w", objectKey, err))
+	}
+	return nil
+}
+
+func (s *S3) Fetch(ctx context.Context, key, dest string) error {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 get %s: %w", key, err)
+	}
+	defer out.Body.Close()
+	return writeFile(dest, out.Body)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	var notFound *types.NoSuchKey
+	if err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("s3 delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// maxDeleteBatch is S3's limit on keys per DeleteObjects call.
+const maxDeleteBatch = 1000
+
+func (s *S3) DeleteJob(ctx context.Context, jobID string) error {
+	if jobID == "" || strings.Contains(jobID, "/") {
+		return fmt.Errorf("invalid job ID %q", jobID)
+	}
+	for _, area := range []string{AreaUploads, AreaOutputs} {
+		prefix := s.objectKey(path.Join(area, jobID)) + "/"
+		pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(s.bucket),
+			Prefix: aws.String(prefix),
+		})
+		var batch []types.ObjectIdentifier
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("s3 list %s: %w", prefix, err)
+			}
+			for _, obj := range page.Contents {
+				batch = append(batch, types.ObjectIdentifier{Key: obj.Key})
+			}
+		}
+		for len(batch) > 0 {
+			n := min(len(batch), maxDeleteBatch)
+			out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(s.bucket),
+				Delete: &types.Delete{Objects: batch[:n], Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return fmt.Errorf("s3 delete %s: %w", prefix, err)
+			}
+			if len(out.Errors) > 0 {
+				return fmt.Errorf("s3 delete %s: %s: %s", prefix, aws.ToString(out.Errors[0].Key), aws.ToString(out.Errors[0].Message))
+			}
+			batch = batch[n:]
+		}
+	}
+	return nil
+}
+
+func (s *S3) PresignGet(ctx context.Context, key, fileName string) (string, time.Time, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	}
+	if fileName != "" {
+		in.ResponseContentDisposition = aws.String(fmt.Sprintf("attachment; filename=%q", fileName))
+	}
+	out, err := s.presign.PresignGetObject(ctx, in, func(o *s3.PresignOptions) { o.Expires = s.ttl })
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("s3 presign %s: %w", key, err)
+	}
+	return out.URL, time.Now().UTC().Add(s.ttl), nil
+}
@@ -0,0 +1,189 @@
+// Package storage is where job files end up once a job is done: the original
+// upload and the converted output. Conversions always run on local files in
+// UPLOAD_DIR / OUTPUT_DIR; a backend then keeps them there (the default) or
+// moves them to an S3-compatible bucket such as AWS S3 or MinIO.
+//
+// Keys are "<area>/<jobID>/<file name>", where area is uploads or outputs.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+const (
+	AreaUploads = "uploads"
+	AreaOutputs = "outputs"
+)
+
+// ErrNoPresign is returned by backends whose files are served by the API
+// itself rather than through a direct URL.
+var ErrNoPresign = errors.New("storage backend has no presigned URLs")
+
+// Backend stores finished job files.
+type Backend interface {
+	// Name is the STORAGE_BACKEND value, for logs.
+	Name() string
+	// Remote reports whether Save moves files off this machine; the local
+	// copy may then be deleted.
+	Remote() bool
+	// Save stores the local file at path under key.
+	Save(ctx context.Context, key, path, contentType string) error
+	// Fetch copies key to the local file at path.
+	Fetch(ctx context.Context, key, path string) error
+	// Delete removes key. A missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeleteJob removes every file stored for jobID in both areas.
+	DeleteJob(ctx context.Context, jobID string) error
+	// PresignGet returns a time-limited URL that downloads key as an
+	// attachment named fileName, and when it expires. With no fileName the
+	// URL serves the file inline.
+	PresignGet(ctx context.Context, key, fileName string) (string, time.Time, error)
+}
+
+// Key builds the key of a job file.
+func Key(area, jobID, name string) string {
+	return path.Join(area, jobID, filepath.Base(name))
+}
+
+// New returns the backend STORAGE_BACKEND selects. s3Client may be nil when
+// the local backend is used.
+func New(cfg *config.Config, s3Client *s3.Client) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.StorageBackend)) {
+	case "", "local":
+		return &Local{UploadDir: cfg.UploadDir, OutputDir: cfg.OutputDir}, nil
+	case "s3":
+		if s3Client == nil {
+			return nil, errors.New("STORAGE_BACKEND=s3 needs an S3 client")
+		}
+		if cfg.StorageS3Bucket == "" {
+			return nil, errors.New("STORAGE_BACKEND=s3 needs STORAGE_S3_BUCKET or S3_BUCKET")
+		}
+		return NewS3(s3Client, cfg.StorageS3Bucket, cfg.StorageS3Prefix, cfg.StorageMultipartPartSize, cfg.S3ResultPresignTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (expected local or s3)", cfg.StorageBackend)
+	}
+}
+
+// Local keeps job files where the pipeline wrote them.
+type Local struct {
+	UploadDir string
+	OutputDir string
+}
+
+func (l *Local) Name() string { return "local" }
+
+func (l *Local) Remote() bool { return false }
+
+func (l *Local) path(key string) (string, error) {
+	area, rest, ok := strings.Cut(path.Clean(key), "/")
+	if !ok || rest == "" || strings.HasPrefix(rest, "../") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	switch area {
+	case AreaUploads:
+		return filepath.Join(l.UploadDir, filepath.FromSlash(rest)), nil
+	case AreaOutputs:
+		return filepath.Join(l.OutputDir, filepath.FromSlash(rest)), nil
+	}
+	return "", fmt.Errorf("invalid storage key %q", key)
+}
+
+// Save moves the file into place, which for files the pipeline wrote is
+// where they already are.
+func (l *Local) Save(_ context.Context, key, src, _ string) error {
+	dest, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if same, _ := samePath(src, dest); same {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dest)
+}
+
+func (l *Local) Fetch(_ context.Context, key, dest string) error {
+	src, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if same, _ := samePath(src, dest); same {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dest, in)
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) DeleteJob(_ context.Context, jobID string) error {
+	if jobID == "" || filepath.Base(jobID) != jobID || jobID == "." || jobID == ".." {
+		return fmt.Errorf("invalid job ID %q", jobID)
+	}
+	for _, dir := range []string{l.UploadDir, l.OutputDir} {
+		if err := os.RemoveAll(filepath.Join(dir, jobID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Local) PresignGet(context.Context, string, string) (string, time.Time, error) {
+	return "", time.Time{}, ErrNoPresign
+}
+
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
+
+// writeFile copies r to path, creating its directory.
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	if got := Key(AreaOutputs, "job-1", "/srv/outputs/job-1/converted.mp4"); got != "outputs/job-1/converted.mp4" {
+		t.Fatalf("Key = %q", got)
+	}
+}
+
+func TestLocalSaveFetchDelete(t *testing.T) {
+	root := t.TempDir()
+	l := &Local{UploadDir: filepath.Join(root, "uploads"), OutputDir: filepath.Join(root, "outputs")}
+	ctx := context.Background()
+
+	// A file already in place is left alone.
+	inPlace := filepath.Join(l.OutputDir, "job-1", "out.mp4")
+	if err := os.MkdirAll(filepath.Dir(inPlace), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(inPlace, []byte("output"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(ctx, Key(AreaOutputs, "job-1", inPlace), inPlace, "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(inPlace); err != nil {
+		t.Fatalf("in-place save moved the file: %v", err)
+	}
+
+	// Anything else is moved under its area.
+	elsewhere := filepath.Join(root, "scratch.bin")
+	if err := os.WriteFile(elsewhere, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := Key(AreaUploads, "job-1", "original_clip.mov")
+	if err := l.Save(ctx, key, elsewhere, ""); err != nil {
+		t.Fatal(err)
+	}
+	fetched := filepath.Join(root, "fetched.mov")
+	if err := l.Fetch(ctx, key, fetched); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(fetched); string(data) != "original" {
+		t.Fatalf("fetched %q", data)
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing key: %v", err)
+	}
+	if _, _, err := l.PresignGet(ctx, key, "clip.mov"); !errors.Is(err, ErrNoPresign) {
+		t.Fatalf("PresignGet err = %v, want ErrNoPresign", err)
+	}
+	for _, bad := range []string{"elsewhere/job-1/x", "uploads", "uploads/../../etc/passwd"} {
+		if err := l.Delete(ctx, bad); err == nil {
+			t.Errorf("Delete(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestLocalDeleteJob(t *testing.T) {
+	root := t.TempDir()
+	l := &Local{UploadDir: filepath.Join(root, "uploads"), OutputDir: filepath.Join(root, "outputs")}
+	for _, f := range []string{"uploads/job-1/original_a.mov", "outputs/job-1/converted.mp4", "outputs/job-2/converted.mp4"} {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.DeleteJob(context.Background(), "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"uploads/job-1", "outputs/job-1"} {
+		if _, err := os.Stat(filepath.Join(root, f)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", f)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "outputs/job-2/converted.mp4")); err != nil {
+		t.Errorf("other job's files removed: %v", err)
+	}
+	for _, bad := range []string{"", "..", "job-1/../job-2"} {
+		if err := l.DeleteJob(context.Background(), bad); err == nil {
+			t.Errorf("DeleteJob(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestPartSizeFor(t *testing.T) {
+	if got := partSizeFor(100<<20, defaultPartSize); got != defaultPartSize {
+		t.Fatalf("small file part size = %d", got)
+	}
+	huge := int64(1 << 40) // 1 TiB needs parts over 100 MiB to fit in 10000
+	if got := partSizeFor(huge, defaultPartSize); got*maxParts < huge {
+		t.Fatalf("part size %d can't cover %d bytes in %d parts", got, huge, maxParts)
+	}
+}
@@ -0,0 +1,316 @@
+// Package tenant holds the per-API-key integrator policies of a shared
+// deployment. Admins list tenants in a JSON file (TENANT_POLICIES_FILE);
+// each has one or more API keys and a policy that is merged over the
+// options of every /api/upload conversion the key makes.
+//
+//	{
+//	  "tenants": [{
+//	    "name": "acme",
+//	    "keys": ["sha256:9f86d081…"],
+//	    "policy": {
+//	      "defaults": {"quality": "medium"},
+//	      "maxWidth": 1920, "maxHeight": 1080,
+//	      "allowedFormats": ["mp4", "webm", "jpg", "webp"],
+//	      "watermark": {"text": "ACME preview", "gravity": "SouthEast"},
+//	      "stripMetadata": true,
+//	      "requestsPerSecond": 5, "burst": 50, "maxConcurrentJobs": 4,
+//	      "quota": {"jobsPerMonth": 10000, "bytesPerMonth": 500000000000}
+//	    }
+//	  }]
+//	}
+//
+// Keys may be listed in plain text or as "sha256:<hex>" of the key, so the
+// file doesn't have to hold usable secrets.
+package tenant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+const hashPrefix = "sha256:"
+
+// Tenant is one integrator.
+type Tenant struct {
+	Name   string   `json:"name"`
+	Keys   []string `json:"keys"`
+	Policy Policy   `json:"policy"`
+}
+
+// Policy is what a tenant may produce. Defaults fill in options the request
+// leaves out; everything else is enforced over whatever the request says.
+type Policy struct {
+	Defaults       map[string]any           `json:"defaults,omitempty"`
+	MaxWidth       int                      `json:"maxWidth,omitempty"`
+	MaxHeight      int                      `json:"maxHeight,omitempty"`
+	AllowedFormats []string                 `json:"allowedFormats,omitempty"`
+	Watermark      *models.ImageTextOverlay `json:"watermark,omitempty"`
+	StripMetadata  bool                     `json:"stripMetadata,omitempty"`
+	// RequestsPerSecond, Burst and MaxConcurrentJobs replace the server's
+	// per-client limits (CLIENT_RATE_LIMIT_RPS, CLIENT_RATE_LIMIT_BURST,
+	// MAX_CONCURRENT_JOBS_PER_CLIENT) for the tenant's keys; 0 keeps them.
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+	MaxConcurrentJobs int     `json:"maxConcurrentJobs,omitempty"`
+	// Quota caps the tenant's monthly usage across all its jobs.
+	Quota *Quota `json:"quota,omitempty"`
+}
+
+// Quota caps a tenant's usage per calendar month (UTC); 0 leaves an item
+// uncapped.
+type Quota struct {
+	JobsPerMonth          int     `json:"jobsPerMonth,omitempty"`
+	BytesPerMonth         int64   `json:"bytesPerMonth,omitempty"`
+	EncodeSecondsPerMonth float64 `json:"encodeSecondsPerMonth,omitempty"`
+}
+
+// Check returns an error naming the quota a new job with an input of size
+// bytes would exceed, given the month's usage so far. Encode time is only
+// known once a job has run, so that quota stops new jobs once reached.
+func (q *Quota) Check(usage models.Usage, size int64) error {
+	switch {
+	case q == nil:
+		return nil
+	case q.JobsPerMonth > 0 && usage.Jobs >= q.JobsPerMonth:
+		return fmt.Errorf("monthly quota of %d jobs reached", q.JobsPerMonth)
+	case q.BytesPerMonth > 0 && usage.Bytes+size > q.BytesPerMonth:
+		return fmt.Errorf("monthly quota of %d bytes would be exceeded (%d used)", q.BytesPerMonth, usage.Bytes)
+	case q.EncodeSecondsPerMonth > 0 && usage.EncodeSeconds >= q.EncodeSecondsPerMonth:
+		return fmt.Errorf("monthly quota of %g encode seconds reached", q.EncodeSecondsPerMonth)
+	}
+	return nil
+}
+
+// Registry resolves API keys to tenants. A nil Registry has no tenants.
+type Registry struct {
+	byHash  map[string]*Tenant
+	tenants []*Tenant
+}
+
+// Load reads the policy file. An empty path returns a nil Registry.
+func Load(path string) (*Registry, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tenant policies: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse validates a policy file and indexes its keys.
+func Parse(data []byte) (*Registry, error) {
+	var file struct {
+		Tenants []*Tenant `json:"tenants"`
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse tenant policies: %w", err)
+	}
+	r := &Registry{byHash: map[string]*Tenant{}, tenants: file.Tenants}
+	names := map[string]bool{}
+	for _, t := range file.Tenants {
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return nil, errors.New("tenant policies: every tenant needs a name")
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenant policies: duplicate tenant %q", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Keys) == 0 {
+			return nil, fmt.Errorf("tenant policies: tenant %q has no keys", t.Name)
+		}
+		for _, key := range t.Keys {
+			hash, err := normalizeKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("tenant policies: tenant %q: %w", t.Name, err)
+			}
+			if _, dup := r.byHash[hash]; dup {
+				return nil, fmt.Errorf("tenant policies: tenant %q reuses a key", t.Name)
+			}
+			r.byHash[hash] = t
+		}
+		if err := t.Policy.validate(); err != nil {
+			return nil, fmt.Errorf("tenant policies: tenant %q: %w", t.Name, err)
+		}
+	}
+	return r, nil
+}
+
+// HashKey returns the "sha256:<hex>" form of an API key for the policy file.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+func normalizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if hexPart, ok := strings.CutPrefix(key, hashPrefix); ok {
+		if b, err := hex.DecodeString(hexPart); err != nil || len(b) != sha256.Size {
+			return "", errors.New("hashed keys must be sha256: followed by 64 hex digits")
+		}
+		return hashPrefix + strings.ToLower(hexPart), nil
+	}
+	if len(key) < 16 {
+		return "", errors.New("API keys must be at least 16 characters")
+	}
+	return HashKey(key), nil
+}
+
+// Lookup returns the tenant owning key, or nil.
+func (r *Registry) Lookup(key string) *Tenant {
+	if r == nil || key == "" {
+		return nil
+	}
+	return r.byHash[HashKey(key)]
+}
+
+// Len reports the number of tenants.
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.tenants)
+}
+
+func (p *Policy) validate() error {
+	if p.MaxWidth < 0 || p.MaxHeight < 0 || p.MaxWidth > 10000 || p.MaxHeight > 10000 {
+		return errors.New("maxWidth and maxHeight must be between 0 and 10000")
+	}
+	for i, f := range p.AllowedFormats {
+		p.AllowedFormats[i] = strings.ToLower(strings.TrimSpace(f))
+	}
+	if p.Watermark != nil && strings.TrimSpace(p.Watermark.Text) == "" {
+		return errors.New("watermark needs text")
+	}
+	if p.RequestsPerSecond < 0 || p.Burst < 0 || p.MaxConcurrentJobs < 0 {
+		return errors.New("requestsPerSecond, burst and maxConcurrentJobs must not be negative")
+	}
+	if q := p.Quota; q != nil && (q.JobsPerMonth < 0 || q.BytesPerMonth < 0 || q.EncodeSecondsPerMonth < 0) {
+		return errors.New("quota limits must not be negative")
+	}
+	return nil
+}
+
+// Restricted reports whether the policy constrains output at all. Keys with
+// a restricted policy are limited to the endpoints Apply covers.
+func (p *Policy) Restricted() bool {
+	return p.MaxWidth > 0 || p.MaxHeight > 0 || len(p.AllowedFormats) > 0 || p.Watermark != nil || p.StripMetadata
+}
+
+// Apply merges the policy into the options of an /api/upload conversion of
+// a file of the given type. Defaults are deep-merged under the request;
+// constraints override it. Options asking for more than the policy allows
+// are rejected rather than silently changed.
+func (p *Policy) Apply(fileType models.FileType, options map[string]any) error {
+	mergeDefaults(options, p.Defaults)
+
+	if mode, _ := options["mode"].(string); strings.TrimSpace(mode) != "" && p.Restricted() {
+		return fmt.Errorf("mode %s is not permitted for this API key", mode)
+	}
+
+	format, _ := options["format"].(string)
+	format = strings.ToLower(strings.TrimSpace(format))
+	if len(p.AllowedFormats) > 0 && !contains(p.AllowedFormats, format) {
+		if format == "" {
+			return errors.New("format is required for this API key")
+		}
+		return fmt.Errorf("output format %s is not permitted for this API key", format)
+	}
+
+	visual := fileType == models.FileTypeImage || fileType == models.FileTypeVideo
+	if visual {
+		for _, dim := range []struct {
+			name, maxName string
+			limit         int
+		}{{"width", "maxWidth", p.MaxWidth}, {"height", "maxHeight", p.MaxHeight}} {
+			if dim.limit == 0 {
+				continue
+			}
+			if v, ok := number(options[dim.name]); ok && v > float64(dim.limit) {
+				return fmt.Errorf("%s %v exceeds this API key's limit of %d", dim.name, v, dim.limit)
+			}
+			if v, ok := number(options[dim.maxName]); !ok || v > float64(dim.limit) {
+				options[dim.maxName] = dim.limit
+			}
+		}
+		if p.Watermark != nil {
+			options["textOverlay"] = *p.Watermark
+		}
+	}
+
+	if p.StripMetadata {
+		switch fileType {
+		case models.FileTypeImage:
+			options["removeMetadata"] = true
+			options["metadataMode"] = "strip"
+			delete(options, "metadata")
+			delete(options, "gpsOptions")
+			delete(options, "advancedTags")
+		case models.FileTypeVideo, models.FileTypeAudio:
+			options["stripMetadata"] = true
+		}
+	}
+	return nil
+}
+
+// mergeDefaults copies defaults into options where the request has no
+// value, recursing into nested objects.
+func mergeDefaults(options, defaults map[string]any) {
+	for k, dv := range defaults {
+		ov, ok := options[k]
+		if !ok || ov == nil {
+			options[k] = cloneValue(dv)
+			continue
+		}
+		om, oIsMap := ov.(map[string]any)
+		dm, dIsMap := dv.(map[string]any)
+		if oIsMap && dIsMap {
+			mergeDefaults(om, dm)
+		}
+	}
+}
+
+func cloneValue(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	out := make(map[string]any, len(m))
+	for k, mv := range m {
+		out[k] = cloneValue(mv)
+	}
+	return out
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
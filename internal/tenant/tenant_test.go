@@ -0,0 +1,130 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/models"
+)
+
+const testPolicies = `{
+  "tenants": [
+    {
+      "name": "acme",
+      "keys": ["acme-test-key-0001"],
+      "policy": {
+        "defaults": {"quality": "medium", "gif": {"fps": 10}},
+        "maxWidth": 1920,
+        "maxHeight": 1080,
+        "allowedFormats": ["MP4", "webp"],
+        "watermark": {"text": "ACME", "gravity": "SouthEast"},
+        "stripMetadata": true
+      }
+    },
+    {"name": "open", "keys": ["` + "sha256:" + `%s"]}
+  ]
+}`
+
+func loadTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	hash := strings.TrimPrefix(HashKey("open-test-key-0001"), "sha256:")
+	reg, err := Parse([]byte(strings.Replace(testPolicies, "%s", hash, 1)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return reg
+}
+
+func TestLookup(t *testing.T) {
+	reg := loadTestRegistry(t)
+	if got := reg.Lookup("acme-test-key-0001"); got == nil || got.Name != "acme" {
+		t.Fatalf("plain key lookup = %+v", got)
+	}
+	if got := reg.Lookup("open-test-key-0001"); got == nil || got.Name != "open" || got.Policy.Restricted() {
+		t.Fatalf("hashed key lookup = %+v", got)
+	}
+	if reg.Lookup("nope") != nil || (*Registry)(nil).Lookup("acme-test-key-0001") != nil {
+		t.Fatal("unknown keys must not resolve")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"short key":     `{"tenants":[{"name":"a","keys":["short"]}]}`,
+		"no keys":       `{"tenants":[{"name":"a","keys":[]}]}`,
+		"bad hash":      `{"tenants":[{"name":"a","keys":["sha256:abc"]}]}`,
+		"duplicate":     `{"tenants":[{"name":"a","keys":["0123456789abcdef"]},{"name":"a","keys":["fedcba9876543210"]}]}`,
+		"shared key":    `{"tenants":[{"name":"a","keys":["0123456789abcdef"]},{"name":"b","keys":["0123456789abcdef"]}]}`,
+		"unknown field": `{"tenants":[{"name":"a","keys":["0123456789abcdef"],"policy":{"maxWidht":10}}]}`,
+		"empty mark":    `{"tenants":[{"name":"a","keys":["0123456789abcdef"],"policy":{"watermark":{"text":" "}}}]}`,
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	policy := loadTestRegistry(t).Lookup("acme-test-key-0001").Policy
+
+	options := map[string]any{"format": "mp4", "width": float64(1280), "gif": map[string]any{"colors": float64(64)}, "quality": "high"}
+	if err := policy.Apply(models.FileTypeVideo, options); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if options["quality"] != "high" {
+		t.Errorf("request values must win over defaults: %v", options["quality"])
+	}
+	gif := options["gif"].(map[string]any)
+	if gif["fps"] != float64(10) || gif["colors"] != float64(64) {
+		t.Errorf("nested defaults not merged: %v", gif)
+	}
+	if options["maxWidth"] != 1920 || options["maxHeight"] != 1080 || options["stripMetadata"] != true {
+		t.Errorf("limits not enforced: %v", options)
+	}
+	if overlay, ok := options["textOverlay"].(models.ImageTextOverlay); !ok || overlay.Text != "ACME" {
+		t.Errorf("watermark not forced: %v", options["textOverlay"])
+	}
+
+	image := map[string]any{"format": "webp", "metadataMode": "keep", "metadata": map[string]any{"title": "x"}}
+	if err := policy.Apply(models.FileTypeImage, image); err != nil {
+		t.Fatalf("Apply image: %v", err)
+	}
+	if image["metadataMode"] != "strip" || image["removeMetadata"] != true || image["metadata"] != nil {
+		t.Errorf("image metadata not stripped: %v", image)
+	}
+
+	for name, opts := range map[string]map[string]any{
+		"format":  {"format": "avi"},
+		"missing": {},
+		"width":   {"format": "mp4", "width": float64(3840)},
+		"mode":    {"format": "mp4", "mode": "transcribe"},
+	} {
+		if err := policy.Apply(models.FileTypeVideo, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestQuotaCheck(t *testing.T) {
+	q := &Quota{JobsPerMonth: 2, BytesPerMonth: 1000, EncodeSecondsPerMonth: 60}
+	cases := []struct {
+		name    string
+		usage   models.Usage
+		size    int64
+		wantErr bool
+	}{
+		{name: "within", usage: models.Usage{Jobs: 1, Bytes: 400, EncodeSeconds: 10}, size: 600},
+		{name: "jobs", usage: models.Usage{Jobs: 2}, size: 1, wantErr: true},
+		{name: "bytes", usage: models.Usage{Bytes: 400}, size: 601, wantErr: true},
+		{name: "encode", usage: models.Usage{EncodeSeconds: 60}, size: 1, wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := q.Check(tc.usage, tc.size); (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+	var none *Quota
+	if err := none.Check(models.Usage{Jobs: 1 << 20}, 1<<40); err != nil {
+		t.Fatalf("nil quota: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+// formatAliases maps alternative spellings onto the names the policy lists
+// are compared in, so "jpeg" in DENIED_INPUT_FORMATS also catches "jpg".
+var formatAliases = map[string]string{
+	"jpeg": "jpg",
+	"tif":  "tiff",
+	"asf":  "wmv",
+	"mpg":  "mpeg",
+	"hif":  "heif",
+}
+
+func normalizeFormat(format string) string {
+	format = strings.ToLower(strings.TrimLeft(strings.TrimSpace(format), "."))
+	if alias, ok := formatAliases[format]; ok {
+		return alias
+	}
+	return format
+}
+
+// formatPermitted applies an allow list (when non-empty) and a deny list.
+func formatPermitted(format string, allowed, denied []string) bool {
+	matches := func(list []string) bool {
+		return slices.ContainsFunc(list, func(f string) bool { return normalizeFormat(f) == format })
+	}
+	if len(allowed) > 0 && !matches(allowed) {
+		return false
+	}
+	return !matches(denied)
+}
+
+// CheckInputFormat enforces ALLOWED_INPUT_FORMATS and DENIED_INPUT_FORMATS
+// against the sniffed format of an upload.
+func CheckInputFormat(cfg *config.Config, format string) error {
+	if cfg == nil {
+		return nil
+	}
+	format = normalizeFormat(format)
+	if formatPermitted(format, cfg.AllowedInputFormats, cfg.DeniedInputFormats) {
+		return nil
+	}
+	return &Rejection{
+		Reason:      "input_format",
+		UserMessage: fmt.Sprintf("%s files are not accepted by this server.", strings.ToUpper(format)),
+		HTTPStatus:  415,
+	}
+}
+
+// CheckOutputFormat enforces ALLOWED_OUTPUT_FORMATS and
+// DENIED_OUTPUT_FORMATS against a requested output format. An empty format
+// (the converter's default) is not checked.
+func CheckOutputFormat(cfg *config.Config, format string) error {
+	format = normalizeFormat(format)
+	if cfg == nil || format == "" {
+		return nil
+	}
+	if formatPermitted(format, cfg.AllowedOutputFormats, cfg.DeniedOutputFormats) {
+		return nil
+	}
+	return &Rejection{
+		Reason:      "output_format",
+		UserMessage: fmt.Sprintf("Converting to %s is not allowed on this server.", strings.ToUpper(format)),
+		HTTPStatus:  400,
+	}
+}
+
+// CheckResolution enforces MAX_RESOLUTION. The limit holds in either
+// orientation: with 3840x2160, a 2160x3840 portrait video passes and a
+// 4096x2160 one doesn't. output says whether width x height is a requested
+// output size rather than a probed input. A zero side, as in a request
+// for a width only, is free.
+func CheckResolution(cfg *config.Config, width, height int, output bool) error {
+	if cfg == nil || cfg.MaxResolutionWidth <= 0 || cfg.MaxResolutionHeight <= 0 {
+		return nil
+	}
+	long, short := max(cfg.MaxResolutionWidth, cfg.MaxResolutionHeight), min(cfg.MaxResolutionWidth, cfg.MaxResolutionHeight)
+	if max(width, height) <= long && min(width, height) <= short {
+		return nil
+	}
+	if output {
+		return &Rejection{
+			Reason:      "output_resolution",
+			UserMessage: fmt.Sprintf("Requested size %dx%d exceeds the limit of %dx%d.", width, height, cfg.MaxResolutionWidth, cfg.MaxResolutionHeight),
+			HTTPStatus:  400,
+			Width:       width,
+			Height:      height,
+		}
+	}
+	return &Rejection{
+		Reason:      "input_resolution",
+		UserMessage: fmt.Sprintf("Resolution %dx%d exceeds the limit of %dx%d.", width, height, cfg.MaxResolutionWidth, cfg.MaxResolutionHeight),
+		HTTPStatus:  413,
+		Width:       width,
+		Height:      height,
+	}
+}
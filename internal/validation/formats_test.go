@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mrrobotisreal/media_manipulator_api/internal/config"
+)
+
+func TestCheckInputFormat(t *testing.T) {
+	c := &config.Config{DeniedInputFormats: []string{"flv", "asf"}}
+	for format, wantErr := range map[string]bool{"mp4": false, "flv": true, "wmv": true, "jpg": false} {
+		if err := CheckInputFormat(c, format); (err != nil) != wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", format, err, wantErr)
+		}
+	}
+
+	c = &config.Config{AllowedInputFormats: []string{"mp4", "jpeg"}}
+	for format, wantErr := range map[string]bool{"mp4": false, "jpg": false, "mov": true, "": true} {
+		if err := CheckInputFormat(c, format); (err != nil) != wantErr {
+			t.Errorf("allow list, %q: err = %v, wantErr %v", format, err, wantErr)
+		}
+	}
+	var rej *Rejection
+	if err := CheckInputFormat(c, "mov"); !errors.As(err, &rej) || rej.Reason != "input_format" || rej.HTTPStatus != 415 {
+		t.Fatalf("expected an input_format rejection, got %v", err)
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	c := &config.Config{AllowedOutputFormats: []string{"mp4", "webp"}, DeniedOutputFormats: []string{"webp"}}
+	for format, wantErr := range map[string]bool{"": false, "MP4": false, "webp": true, "gif": true} {
+		if err := CheckOutputFormat(c, format); (err != nil) != wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", format, err, wantErr)
+		}
+	}
+}
+
+func TestCheckResolution(t *testing.T) {
+	c := &config.Config{MaxResolutionWidth: 3840, MaxResolutionHeight: 2160}
+	cases := []struct {
+		w, h    int
+		wantErr bool
+	}{
+		{w: 3840, h: 2160},
+		{w: 2160, h: 3840},
+		{w: 1920},
+		{w: 4096, h: 2160, wantErr: true},
+		{w: 2200, h: 2200, wantErr: true},
+		{h: 4000, wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := CheckResolution(c, tc.w, tc.h, false); (err != nil) != tc.wantErr {
+			t.Errorf("%dx%d: err = %v, wantErr %v", tc.w, tc.h, err, tc.wantErr)
+		}
+	}
+	var rej *Rejection
+	if err := CheckResolution(c, 7680, 4320, true); !errors.As(err, &rej) || rej.Reason != "output_resolution" {
+		t.Fatalf("expected an output_resolution rejection, got %v", err)
+	}
+	if err := CheckResolution(&config.Config{}, 7680, 4320, false); err != nil {
+		t.Fatalf("no limit configured: %v", err)
+	}
+}
@@ -10,15 +10,20 @@
 #   sudo cp scripts/server/face_privacy.py \
 #     /opt/media-manipulator-ai/scripts/face_privacy.py
 #
-# The script supports two modes used by the API:
+# The script supports three modes used by the API:
 #   1. --detect-only --json-out <path>     (POST /api/ai/faces/detect)
 #   2. --selection-json <path> --output ...(POST /api/upload final job)
+#   3. --video --output <path.mkv>         (blurFaces on a video job)
 #
 # In mode 2 the JSON contains the face boxes the user saw in the preview
 # overlay plus their selectionMode / selectedFaceIds, so the runtime reuses
 # the same boxes instead of redetecting.
+#
+# In mode 3 every frame is scanned and redacted, and the frames are piped to
+# ffmpeg, which writes near-lossless H.264 plus the source's audio.
 import argparse
 import json
+import subprocess
 from pathlib import Path
 from typing import Any, Dict, List, Optional, Sequence, Tuple
 
@@ -49,22 +54,28 @@ def clamp_box(x1: int, y1: int, x2: int, y2: int, width: int, height: int) -> Tu
     return max(0, x1), max(0, y1), min(width, x2), min(height, y2)
 
 
+def create_detector(model_path: str, confidence: float, width: int, height: int) -> Any:
+    return cv2.FaceDetectorYN.create(
+        model=model_path,
+        config="",
+        input_size=(width, height),
+        score_threshold=confidence,
+        nms_threshold=0.3,
+        top_k=5000,
+    )
+
+
 def detect_faces(
     image: np.ndarray,
     model_path: str,
     confidence: float,
     padding: float,
+    detector: Optional[Any] = None,
 ) -> List[FaceBox]:
     height, width = image.shape[:2]
 
-    detector = cv2.FaceDetectorYN.create(
-        model=model_path,
-        config="",
-        input_size=(width, height),
-        score_threshold=confidence,
-        nms_threshold=0.3,
-        top_k=5000,
-    )
+    if detector is None:
+        detector = create_detector(model_path, confidence, width, height)
 
     _, faces = detector.detect(image)
     if faces is None:
@@ -188,6 +199,60 @@ def apply_effect(image: np.ndarray, face: FaceBox, mode: str, pixel_blocks: int)
     return True
 
 
+def blur_video(args: argparse.Namespace) -> None:
+    capture = cv2.VideoCapture(args.input)
+    if not capture.isOpened():
+        raise SystemExit(f"Could not read input video: {args.input}")
+    fps = capture.get(cv2.CAP_PROP_FPS) or 30.0
+
+    ok, frame = capture.read()
+    if not ok:
+        raise SystemExit(f"Input video has no frames: {args.input}")
+    # OpenCV applies the rotation tag, so size the encoder from a real frame.
+    height, width = frame.shape[:2]
+    detector = create_detector(args.model, args.confidence, width, height)
+
+    Path(args.output).parent.mkdir(parents=True, exist_ok=True)
+    encoder = subprocess.Popen(
+        [
+            "ffmpeg", "-hide_banner", "-loglevel", "error", "-y",
+            "-f", "rawvideo", "-pix_fmt", "bgr24", "-s", f"{width}x{height}", "-r", f"{fps:.6f}", "-i", "-",
+            "-i", args.input,
+            "-map", "0:v", "-map", "1:a?",
+            "-c:v", "libx264", "-preset", "veryfast", "-crf", "12", "-pix_fmt", "yuv420p",
+            "-c:a", "copy",
+            args.output,
+        ],
+        stdin=subprocess.PIPE,
+    )
+
+    frames = 0
+    frames_with_faces = 0
+    faces: List[FaceBox] = []
+    try:
+        while ok:
+            if frames % max(1, args.detect_every) == 0:
+                faces = detect_faces(frame, args.model, args.confidence, args.padding, detector)
+            if faces:
+                frames_with_faces += 1
+            for face in faces:
+                apply_effect(frame, face, args.mode, args.pixel_blocks)
+            encoder.stdin.write(frame.tobytes())
+            frames += 1
+            ok, frame = capture.read()
+    finally:
+        capture.release()
+        encoder.stdin.close()
+    if encoder.wait() != 0:
+        raise SystemExit(f"ffmpeg failed to encode {args.output}")
+
+    print(json.dumps({
+        "frames": frames,
+        "framesWithFaces": frames_with_faces,
+        "output": args.output,
+    }))
+
+
 def main():
     parser = argparse.ArgumentParser()
     parser.add_argument("--input", required=True)
@@ -204,9 +269,17 @@ def main():
     parser.add_argument("--selection-json", help="JSON file with selectionMode, selectedFaceIds, and optionally faces")
     parser.add_argument("--selection-mode", choices=["all", "only_selected", "all_except_selected"], default="all")
     parser.add_argument("--selected-face-ids", default="", help="Comma-separated face IDs such as face_1,face_3")
+    parser.add_argument("--video", action="store_true", help="Redact every frame of a video input")
+    parser.add_argument("--detect-every", type=int, default=1, help="In --video mode, rerun detection every N frames")
 
     args = parser.parse_args()
 
+    if args.video:
+        if not args.output:
+            raise SystemExit("--output is required with --video")
+        blur_video(args)
+        return
+
     image = cv2.imread(args.input, cv2.IMREAD_COLOR)
     if image is None:
         raise SystemExit(f"Could not read input image: {args.input}")